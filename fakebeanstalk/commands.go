@@ -0,0 +1,558 @@
+package fakebeanstalk
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	badFormat      string = "BAD_FORMAT\r\n"
+	unknownCommand string = "UNKNOWN_COMMAND\r\n"
+	notFound       string = "NOT_FOUND\r\n"
+
+	// jobs with the priority below are urgent
+	urgentPriority uint32 = 1024
+	maxTubeName    int    = 200
+	tubeNameChars  string = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-+/;.$_()"
+	version        string = "1.12"
+)
+
+type command struct {
+	args int
+	fn   func(c *conn, args []string) string
+}
+
+var commands = map[string]command{ //nolint:gochecknoglobals
+	"put":                  {4, put},
+	"use":                  {1, use},
+	"watch":                {1, watch},
+	"ignore":               {1, ignore},
+	"reserve":              {0, reserve},
+	"reserve-with-timeout": {1, reserveWithTimeout},
+	"reserve-job":          {1, reserveJob},
+	"delete":               {1, deleteJob},
+	"release":              {3, release},
+	"bury":                 {2, bury},
+	"touch":                {1, touch},
+	"kick":                 {1, kick},
+	"kick-job":             {1, kickJob},
+	"peek":                 {1, peek},
+	"peek-ready":           {0, peekState(stateReady)},
+	"peek-delayed":         {0, peekState(stateDelayed)},
+	"peek-buried":          {0, peekState(stateBuried)},
+	"stats-job":            {1, statsJob},
+	"stats-tube":           {1, statsTube},
+	"stats":                {0, stats},
+	"list-tubes":           {0, listTubes},
+	"list-tube-used":       {0, listTubeUsed},
+	"list-tubes-watched":   {0, listTubesWatched},
+	"pause-tube":           {2, pauseTube},
+}
+
+func put(c *conn, args []string) string {
+	pri, err1 := strconv.ParseUint(args[0], 10, 32)
+	delay, err2 := strconv.ParseUint(args[1], 10, 32)
+	ttr, err3 := strconv.ParseUint(args[2], 10, 32)
+	size, err4 := strconv.Atoi(args[3])
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil || size < 0 {
+		return badFormat
+	}
+
+	body, crlf, err := c.readBody(size)
+	if err != nil {
+		return ""
+	}
+	if !crlf {
+		return "EXPECTED_CRLF\r\n"
+	}
+	if size > maxJobSize {
+		return "JOB_TOO_BIG\r\n"
+	}
+
+	// the minimal TTR is one second
+	if ttr == 0 {
+		ttr = 1
+	}
+
+	s := c.srv
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.seq++
+	j := &job{
+		id:        s.seq,
+		tube:      c.use,
+		body:      body,
+		pri:       uint32(pri),
+		delay:     seconds(delay),
+		ttr:       seconds(ttr),
+		state:     stateReady,
+		createdAt: now,
+	}
+	if delay > 0 {
+		j.state = stateDelayed
+		j.until = now.Add(j.delay)
+	}
+
+	s.jobs[j.id] = j
+	s.tubeFor(j.tube).totalJobs++
+
+	return "INSERTED " + strconv.FormatUint(j.id, 10) + "\r\n"
+}
+
+func use(c *conn, args []string) string {
+	if !validTubeName(args[0]) {
+		return badFormat
+	}
+
+	c.srv.mu.Lock()
+	c.srv.tubeFor(args[0])
+	c.use = args[0]
+	c.srv.mu.Unlock()
+
+	return "USING " + args[0] + "\r\n"
+}
+
+func watch(c *conn, args []string) string {
+	if !validTubeName(args[0]) {
+		return badFormat
+	}
+
+	c.srv.mu.Lock()
+	c.srv.tubeFor(args[0])
+	c.watched[args[0]] = struct{}{}
+	n := len(c.watched)
+	c.srv.mu.Unlock()
+
+	return "WATCHING " + strconv.Itoa(n) + "\r\n"
+}
+
+func ignore(c *conn, args []string) string {
+	c.srv.mu.Lock()
+	defer c.srv.mu.Unlock()
+
+	if _, ok := c.watched[args[0]]; ok {
+		if len(c.watched) == 1 {
+			return "NOT_IGNORED\r\n"
+		}
+		delete(c.watched, args[0])
+	}
+
+	return "WATCHING " + strconv.Itoa(len(c.watched)) + "\r\n"
+}
+
+func reserve(c *conn, _ []string) string {
+	return c.reserve(-1)
+}
+
+func reserveWithTimeout(c *conn, args []string) string {
+	timeout, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil {
+		return badFormat
+	}
+
+	return c.reserve(seconds(timeout))
+}
+
+func reserveJob(c *conn, args []string) string {
+	return withJob(c, args[0], func(j *job, now time.Time) string {
+		if j.state == stateReserved {
+			return notFound
+		}
+
+		c.srv.reserve(j, c, now)
+		return withBody("RESERVED", j)
+	})
+}
+
+func deleteJob(c *conn, args []string) string {
+	return withJob(c, args[0], func(j *job, _ time.Time) string {
+		if j.state == stateReserved && j.owner != c {
+			return notFound
+		}
+
+		delete(c.srv.jobs, j.id)
+		c.srv.tubes[j.tube].cmdDelete++
+		return "DELETED\r\n"
+	})
+}
+
+func release(c *conn, args []string) string {
+	pri, err1 := strconv.ParseUint(args[1], 10, 32)
+	delay, err2 := strconv.ParseUint(args[2], 10, 32)
+	if err1 != nil || err2 != nil {
+		return badFormat
+	}
+
+	return withJob(c, args[0], func(j *job, now time.Time) string {
+		if j.state != stateReserved || j.owner != c {
+			return notFound
+		}
+
+		j.pri = uint32(pri)
+		j.owner = nil
+		j.releases++
+		j.delay = seconds(delay)
+		j.state = stateReady
+		j.until = time.Time{}
+		if delay > 0 {
+			j.state = stateDelayed
+			j.until = now.Add(j.delay)
+		}
+
+		return "RELEASED\r\n"
+	})
+}
+
+func bury(c *conn, args []string) string {
+	pri, err := strconv.ParseUint(args[1], 10, 32)
+	if err != nil {
+		return badFormat
+	}
+
+	return withJob(c, args[0], func(j *job, _ time.Time) string {
+		if j.state != stateReserved || j.owner != c {
+			return notFound
+		}
+
+		j.pri = uint32(pri)
+		j.owner = nil
+		j.buries++
+		j.state = stateBuried
+		j.until = time.Time{}
+
+		return "BURIED\r\n"
+	})
+}
+
+func touch(c *conn, args []string) string {
+	return withJob(c, args[0], func(j *job, now time.Time) string {
+		if j.state != stateReserved || j.owner != c {
+			return notFound
+		}
+
+		j.until = now.Add(j.ttr)
+		return "TOUCHED\r\n"
+	})
+}
+
+// kick kicks the buried jobs of the used tube, the delayed ones if there are no buried jobs
+func kick(c *conn, args []string) string {
+	bound, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil {
+		return badFormat
+	}
+
+	s := c.srv
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tick(time.Now())
+
+	st := stateBuried
+	if s.count(c.use, stateBuried) == 0 {
+		st = stateDelayed
+	}
+
+	kicked := uint64(0)
+	for kicked < bound {
+		j := s.first(c.use, st, (*job).less)
+		if j == nil {
+			break
+		}
+
+		kickOne(j)
+		kicked++
+	}
+
+	return "KICKED " + strconv.FormatUint(kicked, 10) + "\r\n"
+}
+
+func kickJob(c *conn, args []string) string {
+	return withJob(c, args[0], func(j *job, _ time.Time) string {
+		if j.state != stateBuried && j.state != stateDelayed {
+			return notFound
+		}
+
+		kickOne(j)
+		return "KICKED\r\n"
+	})
+}
+
+func kickOne(j *job) {
+	j.state = stateReady
+	j.until = time.Time{}
+	j.kicks++
+}
+
+func peek(c *conn, args []string) string {
+	return withJob(c, args[0], func(j *job, _ time.Time) string {
+		return withBody("FOUND", j)
+	})
+}
+
+func peekState(st state) func(c *conn, args []string) string {
+	less := (*job).less
+	switch st {
+	case stateDelayed:
+		less = func(a, b *job) bool {
+			return a.until.Before(b.until)
+		}
+	case stateBuried:
+		less = func(a, b *job) bool {
+			return a.id < b.id
+		}
+	}
+
+	return func(c *conn, _ []string) string {
+		s := c.srv
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.tick(time.Now())
+
+		j := s.first(c.use, st, less)
+		if j == nil {
+			return notFound
+		}
+
+		return withBody("FOUND", j)
+	}
+}
+
+func statsJob(c *conn, args []string) string {
+	return withJob(c, args[0], func(j *job, now time.Time) string {
+		left := time.Duration(0)
+		if j.state == stateReserved || j.state == stateDelayed {
+			left = j.until.Sub(now)
+		}
+
+		return yamlDict([][2]string{
+			{"id", strconv.FormatUint(j.id, 10)},
+			{"tube", j.tube},
+			{"state", j.state.String()},
+			{"pri", strconv.FormatUint(uint64(j.pri), 10)},
+			{"age", secondsString(now.Sub(j.createdAt))},
+			{"delay", secondsString(j.delay)},
+			{"ttr", secondsString(j.ttr)},
+			{"time-left", secondsString(left)},
+			{"file", "0"},
+			{"reserves", strconv.Itoa(j.reserves)},
+			{"timeouts", strconv.Itoa(j.timeouts)},
+			{"releases", strconv.Itoa(j.releases)},
+			{"buries", strconv.Itoa(j.buries)},
+			{"kicks", strconv.Itoa(j.kicks)},
+		})
+	})
+}
+
+func statsTube(c *conn, args []string) string {
+	s := c.srv
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tubes[args[0]]
+	if !ok {
+		return notFound
+	}
+
+	now := time.Now()
+	s.tick(now)
+
+	urgent := 0
+	for _, j := range s.jobs {
+		if j.tube == t.name && j.state == stateReady && j.pri < urgentPriority {
+			urgent++
+		}
+	}
+
+	using, watching, waiting := 0, 0, 0
+	for cn := range s.conns {
+		if cn.use == t.name {
+			using++
+		}
+		if _, ok := cn.watched[t.name]; ok {
+			watching++
+			if cn.waiting {
+				waiting++
+			}
+		}
+	}
+
+	left := time.Duration(0)
+	if t.paused(now) {
+		left = t.pausedTill.Sub(now)
+	}
+
+	return yamlDict([][2]string{
+		{"name", t.name},
+		{"current-jobs-urgent", strconv.Itoa(urgent)},
+		{"current-jobs-ready", strconv.Itoa(s.count(t.name, stateReady))},
+		{"current-jobs-reserved", strconv.Itoa(s.count(t.name, stateReserved))},
+		{"current-jobs-delayed", strconv.Itoa(s.count(t.name, stateDelayed))},
+		{"current-jobs-buried", strconv.Itoa(s.count(t.name, stateBuried))},
+		{"total-jobs", strconv.FormatUint(t.totalJobs, 10)},
+		{"current-using", strconv.Itoa(using)},
+		{"current-watching", strconv.Itoa(watching)},
+		{"current-waiting", strconv.Itoa(waiting)},
+		{"cmd-delete", strconv.FormatUint(t.cmdDelete, 10)},
+		{"cmd-pause-tube", strconv.FormatUint(t.cmdPause, 10)},
+		{"pause", secondsString(t.pause)},
+		{"pause-time-left", secondsString(left)},
+	})
+}
+
+// stats returns the subset of the server stats
+func stats(c *conn, _ []string) string {
+	s := c.srv
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.tick(now)
+
+	counts := make(map[state]int)
+	urgent, timeouts, waiting := 0, 0, 0
+	for _, j := range s.jobs {
+		counts[j.state]++
+		timeouts += j.timeouts
+		if j.state == stateReady && j.pri < urgentPriority {
+			urgent++
+		}
+	}
+	for cn := range s.conns {
+		if cn.waiting {
+			waiting++
+		}
+	}
+
+	return yamlDict([][2]string{
+		{"current-jobs-urgent", strconv.Itoa(urgent)},
+		{"current-jobs-ready", strconv.Itoa(counts[stateReady])},
+		{"current-jobs-reserved", strconv.Itoa(counts[stateReserved])},
+		{"current-jobs-delayed", strconv.Itoa(counts[stateDelayed])},
+		{"current-jobs-buried", strconv.Itoa(counts[stateBuried])},
+		{"job-timeouts", strconv.Itoa(timeouts)},
+		{"total-jobs", strconv.FormatUint(s.seq, 10)},
+		{"max-job-size", strconv.Itoa(maxJobSize)},
+		{"current-tubes", strconv.Itoa(len(s.tubes))},
+		{"current-connections", strconv.Itoa(len(s.conns))},
+		{"current-waiting", strconv.Itoa(waiting)},
+		{"pid", strconv.Itoa(os.Getpid())},
+		{"version", version},
+		{"uptime", secondsString(now.Sub(s.startedAt))},
+	})
+}
+
+func listTubes(c *conn, _ []string) string {
+	return yamlList(c.srv.Tubes())
+}
+
+func listTubeUsed(c *conn, _ []string) string {
+	return "USING " + c.use + "\r\n"
+}
+
+func listTubesWatched(c *conn, _ []string) string {
+	names := make([]string, 0, len(c.watched))
+	for name := range c.watched {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return yamlList(names)
+}
+
+func pauseTube(c *conn, args []string) string {
+	delay, err := strconv.ParseUint(args[1], 10, 32)
+	if err != nil {
+		return badFormat
+	}
+
+	s := c.srv
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tubes[args[0]]
+	if !ok {
+		return notFound
+	}
+
+	t.cmdPause++
+	t.pause = seconds(delay)
+	t.pausedTill = time.Now().Add(t.pause)
+
+	return "PAUSED\r\n"
+}
+
+// withJob looks up the job by the id argument and calls fn under the lock
+func withJob(c *conn, id string, fn func(j *job, now time.Time) string) string {
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return badFormat
+	}
+
+	s := c.srv
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.tick(now)
+
+	j, ok := s.jobs[n]
+	if !ok {
+		return notFound
+	}
+
+	return fn(j, now)
+}
+
+func validTubeName(name string) bool {
+	if name == "" || len(name) > maxTubeName || name[0] == '-' {
+		return false
+	}
+
+	for i := 0; i < len(name); i++ {
+		if strings.IndexByte(tubeNameChars, name[i]) < 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func yamlDict(kv [][2]string) string {
+	b := &strings.Builder{}
+	b.WriteString("---\n")
+	for i := 0; i < len(kv); i++ {
+		_, _ = fmt.Fprintf(b, "%s: %s\n", kv[i][0], kv[i][1])
+	}
+
+	return okData(b.String())
+}
+
+func yamlList(items []string) string {
+	b := &strings.Builder{}
+	b.WriteString("---\n")
+	for i := 0; i < len(items); i++ {
+		_, _ = fmt.Fprintf(b, "- %s\n", items[i])
+	}
+
+	return okData(b.String())
+}
+
+func okData(data string) string {
+	return "OK " + strconv.Itoa(len(data)) + "\r\n" + data + "\r\n"
+}
+
+func seconds(n uint64) time.Duration {
+	return time.Duration(n) * time.Second
+}
+
+func secondsString(d time.Duration) string {
+	return strconv.FormatInt(int64(d/time.Second), 10)
+}
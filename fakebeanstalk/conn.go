@@ -0,0 +1,200 @@
+package fakebeanstalk
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// max command line length, the beanstalkd limit
+	maxLine int = 224
+	// resolution of the blocking reserve
+	pollInterval time.Duration = time.Millisecond * 10
+)
+
+var errLineTooLong = errors.New("line too long")
+
+type conn struct {
+	net.Conn
+	srv *Server
+
+	r *bufio.Reader
+	w *bufio.Writer
+
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// the fields below are written by the connection goroutine only, read by the server under srv.mu
+	use     string
+	watched map[string]struct{}
+	waiting bool
+}
+
+func (c *conn) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		_ = c.Close()
+	})
+}
+
+func (c *conn) serve() {
+	defer c.close()
+
+	for {
+		line, err := readLine(c.r)
+		if err != nil {
+			if errors.Is(err, errLineTooLong) {
+				_ = c.write(badFormat)
+				continue
+			}
+			return
+		}
+
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			_ = c.write(badFormat)
+			continue
+		}
+
+		if args[0] == "quit" {
+			return
+		}
+
+		rep := c.dispatch(args[0], args[1:])
+		if rep == "" {
+			// the connection was closed while waiting
+			return
+		}
+
+		err = c.write(rep)
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (c *conn) dispatch(name string, args []string) string {
+	cmd, ok := commands[name]
+	if !ok {
+		return unknownCommand
+	}
+
+	if len(args) != cmd.args {
+		return badFormat
+	}
+
+	return cmd.fn(c, args)
+}
+
+func (c *conn) write(rep string) error {
+	_, err := c.w.WriteString(rep)
+	if err != nil {
+		return err
+	}
+
+	return c.w.Flush()
+}
+
+// readBody reads the job body of n bytes followed by CRLF
+func (c *conn) readBody(n int) ([]byte, bool, error) {
+	buf := make([]byte, n+2)
+	_, err := io.ReadFull(c.r, buf)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return buf[:n], buf[n] == '\r' && buf[n+1] == '\n', nil
+}
+
+// reserve waits for the ready job in the watched tubes, forever if timeout is negative
+func (c *conn) reserve(timeout time.Duration) string {
+	s := c.srv
+
+	s.mu.Lock()
+	delay := s.reserveDelay
+	s.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-c.done:
+			return ""
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	s.mu.Lock()
+	c.waiting = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		c.waiting = false
+		s.mu.Unlock()
+	}()
+
+	for {
+		s.mu.Lock()
+		now := time.Now()
+		s.tick(now)
+
+		if j := s.nextReady(c.watched, now); j != nil {
+			s.reserve(j, c, now)
+			rep := withBody("RESERVED", j)
+			s.mu.Unlock()
+			return rep
+		}
+
+		soon := c.deadlineSoon(now)
+		s.mu.Unlock()
+
+		if soon {
+			return "DEADLINE_SOON\r\n"
+		}
+
+		if timeout >= 0 && !now.Before(deadline) {
+			return "TIMED_OUT\r\n"
+		}
+
+		select {
+		case <-c.done:
+			return ""
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// deadlineSoon reports whether the job reserved by the connection is about to expire, called under srv.mu
+func (c *conn) deadlineSoon(now time.Time) bool {
+	for _, j := range c.srv.jobs {
+		if j.owner == c && j.state == stateReserved && j.until.Sub(now) <= deadlineSoon {
+			return true
+		}
+	}
+
+	return false
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	if len(line) > maxLine {
+		return "", errLineTooLong
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func withBody(status string, j *job) string {
+	return status + " " + strconv.FormatUint(j.id, 10) + " " + strconv.Itoa(len(j.body)) + "\r\n" + string(j.body) + "\r\n"
+}
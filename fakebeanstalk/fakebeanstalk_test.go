@@ -0,0 +1,253 @@
+package fakebeanstalk
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dial(t *testing.T, s *Server) *client {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", s.Addr())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return &client{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+// cmd sends the command and returns the status line and the body (if the reply has one)
+func (c *client) cmd(line string, body ...string) (string, string) {
+	c.t.Helper()
+
+	msg := line + "\r\n"
+	if len(body) > 0 {
+		msg += body[0] + "\r\n"
+	}
+	_, err := c.conn.Write([]byte(msg))
+	require.NoError(c.t, err)
+
+	status, err := c.r.ReadString('\n')
+	require.NoError(c.t, err)
+	status = strings.TrimSuffix(status, "\r\n")
+
+	fields := strings.Fields(status)
+	switch fields[0] {
+	case "RESERVED", "FOUND", "OK":
+		n, err := strconv.Atoi(fields[len(fields)-1])
+		require.NoError(c.t, err)
+
+		data := make([]byte, n+2)
+		_, err = io.ReadFull(c.r, data)
+		require.NoError(c.t, err)
+
+		return status, string(data[:n])
+	}
+
+	return status, ""
+}
+
+func (c *client) put(pri, delay, ttr int, body string) string {
+	c.t.Helper()
+
+	status, _ := c.cmd("put "+strconv.Itoa(pri)+" "+strconv.Itoa(delay)+" "+strconv.Itoa(ttr)+" "+strconv.Itoa(len(body)), body)
+	require.True(c.t, strings.HasPrefix(status, "INSERTED "), status)
+
+	return strings.TrimPrefix(status, "INSERTED ")
+}
+
+func TestPutReserveDelete(t *testing.T) {
+	s := Start(t)
+	c := dial(t, s)
+
+	status, _ := c.cmd("use jobs")
+	assert.Equal(t, "USING jobs", status)
+
+	low := c.put(100, 0, 10, "low")
+	high := c.put(1, 0, 10, "high")
+
+	status, _ = c.cmd("watch jobs")
+	assert.Equal(t, "WATCHING 2", status)
+	status, _ = c.cmd("ignore default")
+	assert.Equal(t, "WATCHING 1", status)
+	status, _ = c.cmd("ignore jobs")
+	assert.Equal(t, "NOT_IGNORED", status)
+
+	// the higher priority (lower value) goes first
+	status, body := c.cmd("reserve-with-timeout 0")
+	assert.Equal(t, "RESERVED "+high+" 4", status)
+	assert.Equal(t, "high", body)
+
+	status, body = c.cmd("reserve")
+	assert.Equal(t, "RESERVED "+low+" 3", status)
+	assert.Equal(t, "low", body)
+
+	status, _ = c.cmd("reserve-with-timeout 0")
+	assert.Equal(t, "TIMED_OUT", status)
+
+	jobs := s.Jobs("jobs")
+	require.Len(t, jobs, 2)
+	assert.Equal(t, "reserved", jobs[0].State)
+	assert.Equal(t, 1, jobs[0].Reserves)
+
+	// reserved by the other connection
+	other := dial(t, s)
+	status, _ = other.cmd("delete " + low)
+	assert.Equal(t, "NOT_FOUND", status)
+
+	status, _ = c.cmd("delete " + low)
+	assert.Equal(t, "DELETED", status)
+	status, _ = c.cmd("delete " + high)
+	assert.Equal(t, "DELETED", status)
+	status, _ = c.cmd("delete " + high)
+	assert.Equal(t, "NOT_FOUND", status)
+
+	assert.Empty(t, s.Jobs("jobs"))
+	assert.Equal(t, []string{"default", "jobs"}, s.Tubes())
+}
+
+func TestReleaseBuryKickTouch(t *testing.T) {
+	s := Start(t)
+	c := dial(t, s)
+
+	id := c.put(10, 0, 1, "job")
+
+	status, _ := c.cmd("reserve")
+	require.Equal(t, "RESERVED "+id+" 3", status)
+
+	status, _ = c.cmd("release " + id + " 5 1")
+	assert.Equal(t, "RELEASED", status)
+	status, _ = c.cmd("peek-delayed")
+	assert.Equal(t, "FOUND "+id+" 3", status)
+
+	// ready again after the delay
+	status, _ = c.cmd("reserve-with-timeout 3")
+	require.Equal(t, "RESERVED "+id+" 3", status)
+
+	status, _ = c.cmd("touch " + id)
+	assert.Equal(t, "TOUCHED", status)
+
+	status, _ = c.cmd("bury " + id + " 20")
+	assert.Equal(t, "BURIED", status)
+	status, _ = c.cmd("peek-buried")
+	assert.Equal(t, "FOUND "+id+" 3", status)
+
+	status, _ = c.cmd("kick 10")
+	assert.Equal(t, "KICKED 1", status)
+
+	status, body := c.cmd("stats-job " + id)
+	assert.Equal(t, "OK "+strconv.Itoa(len(body)), status)
+	assert.Contains(t, body, "state: ready\n")
+	assert.Contains(t, body, "pri: 20\n")
+	assert.Contains(t, body, "releases: 1\n")
+	assert.Contains(t, body, "buries: 1\n")
+	assert.Contains(t, body, "kicks: 1\n")
+}
+
+func TestTTR(t *testing.T) {
+	s := Start(t)
+	c := dial(t, s)
+
+	id := c.put(0, 0, 1, "job")
+
+	status, _ := c.cmd("reserve")
+	require.Equal(t, "RESERVED "+id+" 3", status)
+
+	// the only reserved job is about to expire
+	status, _ = c.cmd("reserve-with-timeout 5")
+	assert.Equal(t, "DEADLINE_SOON", status)
+
+	time.Sleep(time.Millisecond * 1100)
+
+	jobs := s.Jobs("default")
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "ready", jobs[0].State)
+	assert.Equal(t, 1, jobs[0].Timeouts)
+
+	// the reserved jobs of the closed connection are returned
+	other := dial(t, s)
+	status, _ = other.cmd("reserve")
+	require.Equal(t, "RESERVED "+id+" 3", status)
+	_ = other.conn.Close()
+
+	require.Eventually(t, func() bool {
+		return s.Jobs("default")[0].State == "ready"
+	}, time.Second, time.Millisecond*10)
+}
+
+func TestStatsTube(t *testing.T) {
+	s := Start(t)
+	c := dial(t, s)
+
+	c.cmd("use stats")
+	c.put(0, 0, 10, "urgent")
+	c.put(2000, 100, 10, "delayed")
+
+	status, body := c.cmd("stats-tube stats")
+	require.True(t, strings.HasPrefix(status, "OK "))
+	assert.True(t, strings.HasPrefix(body, "---\n"))
+	assert.Contains(t, body, "name: stats\n")
+	assert.Contains(t, body, "current-jobs-urgent: 1\n")
+	assert.Contains(t, body, "current-jobs-ready: 1\n")
+	assert.Contains(t, body, "current-jobs-delayed: 1\n")
+	assert.Contains(t, body, "total-jobs: 2\n")
+	assert.Contains(t, body, "current-using: 1\n")
+
+	status, _ = c.cmd("stats-tube missing")
+	assert.Equal(t, "NOT_FOUND", status)
+
+	_, body = c.cmd("list-tubes")
+	assert.Equal(t, "---\n- default\n- stats\n", body)
+
+	status, _ = c.cmd("list-tube-used")
+	assert.Equal(t, "USING stats", status)
+
+	status, _ = c.cmd("pause-tube stats 0")
+	assert.Equal(t, "PAUSED", status)
+
+	status, _ = c.cmd("bogus")
+	assert.Equal(t, "UNKNOWN_COMMAND", status)
+	status, _ = c.cmd("put 1 2")
+	assert.Equal(t, "BAD_FORMAT", status)
+}
+
+func TestFaults(t *testing.T) {
+	s := Start(t)
+	c := dial(t, s)
+	c.put(0, 0, 10, "job")
+	assert.Equal(t, 1, s.Connections())
+
+	s.DropConnections()
+	_, err := c.r.ReadString('\n')
+	assert.Error(t, err)
+
+	require.NoError(t, s.Down())
+	_, err = net.Dial("tcp", s.Addr())
+	assert.Error(t, err)
+
+	require.NoError(t, s.Up())
+	c = dial(t, s)
+
+	// the jobs survive the restart
+	s.SetReserveDelay(time.Millisecond * 300)
+	start := time.Now()
+	status, body := c.cmd("reserve-with-timeout 1")
+	assert.Equal(t, "job", body)
+	assert.True(t, strings.HasPrefix(status, "RESERVED "))
+	assert.GreaterOrEqual(t, time.Since(start), time.Millisecond*300)
+}
@@ -0,0 +1,172 @@
+package fakebeanstalk
+
+import (
+	"time"
+)
+
+const (
+	defaultTube string = "default"
+	// max job body size, the beanstalkd default (-z)
+	maxJobSize int = 65535
+	// reserve returns DEADLINE_SOON when the reserved job of the connection expires in less than this
+	deadlineSoon time.Duration = time.Second
+)
+
+type state int
+
+const (
+	stateReady state = iota
+	stateDelayed
+	stateReserved
+	stateBuried
+)
+
+func (s state) String() string {
+	switch s {
+	case stateReady:
+		return "ready"
+	case stateDelayed:
+		return "delayed"
+	case stateReserved:
+		return "reserved"
+	case stateBuried:
+		return "buried"
+	default:
+		return "invalid"
+	}
+}
+
+type job struct {
+	id   uint64
+	tube string
+	body []byte
+	pri  uint32
+
+	delay time.Duration
+	ttr   time.Duration
+
+	state     state
+	createdAt time.Time
+	// delayed: the time the job becomes ready, reserved: the TTR deadline
+	until time.Time
+	// connection that reserved the job
+	owner *conn
+
+	reserves int
+	timeouts int
+	releases int
+	buries   int
+	kicks    int
+}
+
+// less orders the ready jobs: lower priority value first, then FIFO
+func (j *job) less(o *job) bool {
+	if j.pri != o.pri {
+		return j.pri < o.pri
+	}
+
+	return j.id < o.id
+}
+
+// tube is created on the first use and never removed
+type tube struct {
+	name string
+
+	totalJobs uint64
+	cmdDelete uint64
+	cmdPause  uint64
+
+	pause      time.Duration
+	pausedTill time.Time
+}
+
+func (t *tube) paused(now time.Time) bool {
+	return now.Before(t.pausedTill)
+}
+
+// tick moves the delayed jobs with the passed delay and the reserved jobs with the passed TTR to the ready state
+func (s *Server) tick(now time.Time) {
+	for _, j := range s.jobs {
+		if j.state == stateReserved || j.state == stateDelayed {
+			if now.Before(j.until) {
+				continue
+			}
+
+			if j.state == stateReserved {
+				j.timeouts++
+				j.owner = nil
+			}
+
+			j.state = stateReady
+			j.until = time.Time{}
+		}
+	}
+}
+
+// tubeFor returns the tube with the name, creates it if needed
+func (s *Server) tubeFor(name string) *tube {
+	t, ok := s.tubes[name]
+	if !ok {
+		t = &tube{name: name}
+		s.tubes[name] = t
+	}
+
+	return t
+}
+
+// nextReady returns the ready job from the watched (not paused) tubes with the highest priority
+func (s *Server) nextReady(watched map[string]struct{}, now time.Time) *job {
+	var next *job
+	for _, j := range s.jobs {
+		if j.state != stateReady {
+			continue
+		}
+		if _, ok := watched[j.tube]; !ok {
+			continue
+		}
+		if s.tubes[j.tube].paused(now) {
+			continue
+		}
+
+		if next == nil || j.less(next) {
+			next = j
+		}
+	}
+
+	return next
+}
+
+func (s *Server) reserve(j *job, c *conn, now time.Time) {
+	j.state = stateReserved
+	j.owner = c
+	j.until = now.Add(j.ttr)
+	j.reserves++
+}
+
+// count returns the number of jobs in the tube in the state
+func (s *Server) count(tube string, st state) int {
+	n := 0
+	for _, j := range s.jobs {
+		if j.tube == tube && j.state == st {
+			n++
+		}
+	}
+
+	return n
+}
+
+// first returns the job in the tube and the state selected by the less func
+func (s *Server) first(tube string, st state, less func(a, b *job) bool) *job {
+	var out *job
+	for _, j := range s.jobs {
+		if j.tube != tube || j.state != st {
+			continue
+		}
+
+		if out == nil || less(j, out) {
+			out = j
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,264 @@
+// Package fakebeanstalk is the in-process stand-in for beanstalkd. It speaks the beanstalkd text protocol (tubes, put
+// with priority/delay/TTR, reserve with timeout, delete, release, bury, kick, touch, peek and stats) and supports
+// injected faults: dropped connections, the server going down and up on the same address and slow reserves.
+package fakebeanstalk
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Server is the beanstalkd stand-in
+type Server struct {
+	addr      string
+	startedAt time.Time
+
+	mu    sync.Mutex
+	ls    net.Listener
+	conns map[*conn]struct{}
+	jobs  map[uint64]*job
+	tubes map[string]*tube
+	seq   uint64
+
+	// faults
+	reserveDelay time.Duration
+
+	wg sync.WaitGroup
+}
+
+// Job is the snapshot of the job stored in the server
+type Job struct {
+	ID       uint64
+	Tube     string
+	Body     []byte
+	Priority uint32
+	TTR      time.Duration
+	// ready, delayed, reserved or buried
+	State    string
+	Reserves int
+	Timeouts int
+	Releases int
+	Buries   int
+	Kicks    int
+}
+
+// Listen starts the server on the addr (host:port), use port 0 for the random one
+func Listen(addr string) (*Server, error) {
+	ls, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		addr:      ls.Addr().String(),
+		startedAt: time.Now(),
+		ls:        ls,
+		conns:     make(map[*conn]struct{}),
+		jobs:      make(map[uint64]*job),
+		tubes:     make(map[string]*tube),
+	}
+	s.tubeFor(defaultTube)
+
+	s.wg.Add(1)
+	go s.serve(ls)
+
+	return s, nil
+}
+
+// Start starts the server on the random port, the server is closed when the test finishes
+func Start(t testing.TB) *Server {
+	t.Helper()
+
+	s, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start the beanstalk stand-in: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = s.Close()
+	})
+
+	return s
+}
+
+// Addr returns host:port the server listens on, it is kept across Down/Up
+func (s *Server) Addr() string {
+	return s.addr
+}
+
+// Close stops the server and closes all client connections
+func (s *Server) Close() error {
+	err := s.Down()
+	s.wg.Wait()
+
+	return err
+}
+
+// Down closes the listener and all client connections, new connections are refused until Up.
+// The jobs are kept, the jobs reserved by the closed connections are returned to the ready state.
+func (s *Server) Down() error {
+	s.mu.Lock()
+	ls := s.ls
+	s.ls = nil
+	for c := range s.conns {
+		c.close()
+	}
+	s.mu.Unlock()
+
+	if ls == nil {
+		return nil
+	}
+
+	return ls.Close()
+}
+
+// Up starts listening on the same address after Down
+func (s *Server) Up() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ls != nil {
+		return nil
+	}
+
+	ls, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	s.ls = ls
+	s.wg.Add(1)
+	go s.serve(ls)
+
+	return nil
+}
+
+// DropConnections closes all current client connections, the server keeps accepting the new ones
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	for c := range s.conns {
+		c.close()
+	}
+	s.mu.Unlock()
+}
+
+// SetReserveDelay delays every reserve command by d before it looks for the job, zero disables the delay
+func (s *Server) SetReserveDelay(d time.Duration) {
+	s.mu.Lock()
+	s.reserveDelay = d
+	s.mu.Unlock()
+}
+
+// Connections returns the number of the connected clients
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.conns)
+}
+
+// Tubes returns the sorted names of the tubes
+func (s *Server) Tubes() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.tubes))
+	for name := range s.tubes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Jobs returns the jobs of the tube ordered by id
+func (s *Server) Jobs(tube string) []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tick(time.Now())
+
+	out := make([]Job, 0)
+	for _, j := range s.jobs {
+		if j.tube != tube {
+			continue
+		}
+
+		out = append(out, Job{
+			ID:       j.id,
+			Tube:     j.tube,
+			Body:     append([]byte(nil), j.body...),
+			Priority: j.pri,
+			TTR:      j.ttr,
+			State:    j.state.String(),
+			Reserves: j.reserves,
+			Timeouts: j.timeouts,
+			Releases: j.releases,
+			Buries:   j.buries,
+			Kicks:    j.kicks,
+		})
+	}
+
+	sort.Slice(out, func(i, k int) bool {
+		return out[i].ID < out[k].ID
+	})
+
+	return out
+}
+
+func (s *Server) serve(ls net.Listener) {
+	defer s.wg.Done()
+
+	for {
+		nc, err := ls.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		c := &conn{
+			Conn:    nc,
+			srv:     s,
+			r:       bufio.NewReader(nc),
+			w:       bufio.NewWriter(nc),
+			done:    make(chan struct{}),
+			use:     defaultTube,
+			watched: map[string]struct{}{defaultTube: {}},
+		}
+
+		s.mu.Lock()
+		if s.ls != ls {
+			// went down between Accept and here
+			s.mu.Unlock()
+			_ = nc.Close()
+			continue
+		}
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			c.serve()
+
+			s.mu.Lock()
+			delete(s.conns, c)
+			// beanstalkd returns the reserved jobs of the closed connection to the ready queue
+			for _, j := range s.jobs {
+				if j.owner == c {
+					j.state = stateReady
+					j.owner = nil
+					j.until = time.Time{}
+				}
+			}
+			s.mu.Unlock()
+		}()
+	}
+}
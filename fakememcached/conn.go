@@ -0,0 +1,418 @@
+package fakememcached
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxKeyLength int    = 250
+	version      string = "1.6.21"
+	noreply      string = "noreply"
+
+	stored    string = "STORED\r\n"
+	notStored string = "NOT_STORED\r\n"
+	exists    string = "EXISTS\r\n"
+	notFound  string = "NOT_FOUND\r\n"
+	errorResp string = "ERROR\r\n"
+)
+
+type conn struct {
+	net.Conn
+	srv *Server
+
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+func (c *conn) serve() {
+	defer func() {
+		_ = c.Close()
+	}()
+
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			_ = c.write(errorResp)
+			continue
+		}
+
+		if args[0] == "quit" {
+			return
+		}
+
+		rep, ok := c.dispatch(args[0], args[1:])
+		if !ok {
+			// the data block could not be read
+			return
+		}
+
+		if rep == "" {
+			// noreply
+			continue
+		}
+
+		err = c.write(rep)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// dispatch executes the command, false if the connection is broken
+func (c *conn) dispatch(name string, args []string) (string, bool) {
+	switch name {
+	case "set", "add", "replace", "append", "prepend", "cas":
+		return c.storage(name, args)
+	case "get", "gets":
+		return c.retrieve(args, nil, name == "gets"), true
+	case "gat", "gats":
+		if len(args) < 2 {
+			return errorResp, true
+		}
+		exptime, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return clientError("invalid exptime argument"), true
+		}
+		return c.retrieve(args[1:], &exptime, name == "gats"), true
+	case "delete":
+		return withNoreply(args, 1, c.delete), true
+	case "incr", "decr":
+		return withNoreply(args, 2, func(args []string) string {
+			return c.incr(args, name == "incr")
+		}), true
+	case "touch":
+		return withNoreply(args, 2, c.touch), true
+	case "flush_all":
+		return c.flushAll(args), true
+	case "version":
+		return "VERSION " + version + "\r\n", true
+	case "verbosity":
+		return withNoreply(args, 1, func([]string) string { return "OK\r\n" }), true
+	case "stats":
+		return c.stats(), true
+	default:
+		return errorResp, true
+	}
+}
+
+func (c *conn) write(rep string) error {
+	_, err := c.w.WriteString(rep)
+	if err != nil {
+		return err
+	}
+
+	return c.w.Flush()
+}
+
+// storage handles set, add, replace, append, prepend: <cmd> <key> <flags> <exptime> <bytes> [noreply]
+// and cas: cas <key> <flags> <exptime> <bytes> <cas unique> [noreply]
+func (c *conn) storage(name string, args []string) (string, bool) {
+	n := 4
+	if name == "cas" {
+		n = 5
+	}
+
+	quiet := len(args) == n+1 && args[n] == noreply
+	if len(args) != n && !quiet {
+		return errorResp, true
+	}
+
+	key := args[0]
+	flags, err1 := strconv.ParseUint(args[1], 10, 32)
+	exptime, err2 := strconv.ParseInt(args[2], 10, 64)
+	size, err3 := strconv.Atoi(args[3])
+	if err1 != nil || err2 != nil || err3 != nil || size < 0 {
+		return clientError("bad command line format"), true
+	}
+
+	var unique uint64
+	if name == "cas" {
+		var err error
+		unique, err = strconv.ParseUint(args[4], 10, 64)
+		if err != nil {
+			return clientError("bad command line format"), true
+		}
+	}
+
+	buf := make([]byte, size+2)
+	_, err := io.ReadFull(c.r, buf)
+	if err != nil {
+		return "", false
+	}
+	if buf[size] != '\r' || buf[size+1] != '\n' {
+		// swallow the rest of the data line as memcached does
+		if buf[size+1] != '\n' {
+			_, err = c.r.ReadString('\n')
+			if err != nil {
+				return "", false
+			}
+		}
+		return clientError("bad data chunk"), true
+	}
+
+	if !validKey(key) {
+		return clientError("bad command line format"), true
+	}
+
+	rep := c.store(name, key, buf[:size], uint32(flags), exptime, unique)
+	if quiet {
+		return "", true
+	}
+
+	return rep, true
+}
+
+func (c *conn) store(name, key string, value []byte, flags uint32, exptime int64, unique uint64) string {
+	s := c.srv
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	cur := s.get(key, now)
+	it := &item{value: value, flags: flags, expireAt: expiration(exptime, now)}
+
+	switch name {
+	case "add":
+		if cur != nil {
+			return notStored
+		}
+	case "replace":
+		if cur == nil {
+			return notStored
+		}
+	case "append", "prepend":
+		if cur == nil {
+			return notStored
+		}
+		// flags and exptime are ignored
+		it.flags = cur.flags
+		it.expireAt = cur.expireAt
+		if name == "append" {
+			it.value = append(append([]byte(nil), cur.value...), value...)
+		} else {
+			it.value = append(append([]byte(nil), value...), cur.value...)
+		}
+	case "cas":
+		if cur == nil {
+			return notFound
+		}
+		if cur.cas != unique {
+			return exists
+		}
+	}
+
+	s.store(key, it)
+
+	return stored
+}
+
+// retrieve handles get, gets and (with the exptime) gat, gats
+func (c *conn) retrieve(keys []string, exptime *int64, withCAS bool) string {
+	if len(keys) == 0 {
+		return errorResp
+	}
+
+	s := c.srv
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	b := &strings.Builder{}
+	for i := 0; i < len(keys); i++ {
+		it := s.get(keys[i], now)
+		if it == nil {
+			continue
+		}
+
+		if exptime != nil {
+			it.expireAt = expiration(*exptime, now)
+		}
+
+		b.WriteString("VALUE " + keys[i] + " " + strconv.FormatUint(uint64(it.flags), 10) + " " + strconv.Itoa(len(it.value)))
+		if withCAS {
+			b.WriteString(" " + strconv.FormatUint(it.cas, 10))
+		}
+		b.WriteString("\r\n")
+		b.Write(it.value)
+		b.WriteString("\r\n")
+	}
+	b.WriteString("END\r\n")
+
+	return b.String()
+}
+
+func (c *conn) delete(args []string) string {
+	s := c.srv
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.get(args[0], time.Now()) == nil {
+		return notFound
+	}
+
+	delete(s.data, args[0])
+	return "DELETED\r\n"
+}
+
+func (c *conn) incr(args []string, incr bool) string {
+	delta, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return clientError("invalid numeric delta argument")
+	}
+
+	s := c.srv
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	it := s.get(args[0], time.Now())
+	if it == nil {
+		return notFound
+	}
+
+	v, err := strconv.ParseUint(string(it.value), 10, 64)
+	if err != nil {
+		return clientError("cannot increment or decrement non-numeric value")
+	}
+
+	switch {
+	case incr:
+		// wraps around 64 bits as the real one
+		v += delta
+	case delta > v:
+		v = 0
+	default:
+		v -= delta
+	}
+
+	it.value = []byte(strconv.FormatUint(v, 10))
+	s.cas++
+	it.cas = s.cas
+
+	return string(it.value) + "\r\n"
+}
+
+func (c *conn) touch(args []string) string {
+	exptime, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return clientError("invalid exptime argument")
+	}
+
+	s := c.srv
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	it := s.get(args[0], now)
+	if it == nil {
+		return notFound
+	}
+
+	it.expireAt = expiration(exptime, now)
+	return "TOUCHED\r\n"
+}
+
+// flushAll handles flush_all [delay] [noreply], with the delay the current items expire after it
+func (c *conn) flushAll(args []string) string {
+	quiet := len(args) > 0 && args[len(args)-1] == noreply
+	if quiet {
+		args = args[:len(args)-1]
+	}
+	if len(args) > 1 {
+		return errorResp
+	}
+
+	delay := int64(0)
+	if len(args) == 1 {
+		var err error
+		delay, err = strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return clientError("bad command line format")
+		}
+	}
+
+	s := c.srv
+	s.mu.Lock()
+	if delay <= 0 {
+		s.data = make(map[string]*item)
+	} else {
+		now := time.Now()
+		deadline := expiration(delay, now)
+		for _, it := range s.data {
+			if it.expireAt.IsZero() || it.expireAt.After(deadline) {
+				it.expireAt = deadline
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	if quiet {
+		return ""
+	}
+
+	return "OK\r\n"
+}
+
+// stats returns the subset of the general-purpose statistics
+func (c *conn) stats() string {
+	s := c.srv
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	items := 0
+	for _, it := range s.data {
+		if !it.expired(now) {
+			items++
+		}
+	}
+
+	return "STAT pid " + strconv.Itoa(os.Getpid()) + "\r\n" +
+		"STAT version " + version + "\r\n" +
+		"STAT curr_connections " + strconv.Itoa(len(s.conns)) + "\r\n" +
+		"STAT curr_items " + strconv.Itoa(items) + "\r\n" +
+		"END\r\n"
+}
+
+// withNoreply checks the number of arguments (plus optional noreply) and calls fn, empty reply for noreply
+func withNoreply(args []string, n int, fn func(args []string) string) string {
+	quiet := len(args) == n+1 && args[n] == noreply
+	if len(args) != n && !quiet {
+		return errorResp
+	}
+
+	rep := fn(args[:n])
+	if quiet {
+		return ""
+	}
+
+	return rep
+}
+
+func validKey(key string) bool {
+	if key == "" || len(key) > maxKeyLength {
+		return false
+	}
+
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+
+	return true
+}
+
+func clientError(msg string) string {
+	return "CLIENT_ERROR " + msg + "\r\n"
+}
@@ -0,0 +1,165 @@
+package fakememcached
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dial(t *testing.T, s *Server) *client {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", s.Addr())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return &client{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+// cmd sends the raw request and reads the reply lines until one of the terminators
+func (c *client) cmd(req string, until ...string) []string {
+	c.t.Helper()
+
+	_, err := c.conn.Write([]byte(req))
+	require.NoError(c.t, err)
+
+	var out []string
+	for {
+		line, err := c.r.ReadString('\n')
+		require.NoError(c.t, err)
+		line = strings.TrimSuffix(line, "\r\n")
+		out = append(out, line)
+
+		if len(until) == 0 {
+			return out
+		}
+		for i := 0; i < len(until); i++ {
+			if line == until[i] {
+				return out
+			}
+		}
+	}
+}
+
+func (c *client) line(req string) string {
+	c.t.Helper()
+
+	return c.cmd(req)[0]
+}
+
+func TestStorage(t *testing.T) {
+	s := Start(t)
+	c := dial(t, s)
+
+	assert.Equal(t, "STORED", c.line("set a 5 0 2\r\naa\r\n"))
+	assert.Equal(t, "NOT_STORED", c.line("add a 0 0 1\r\nx\r\n"))
+	assert.Equal(t, "STORED", c.line("add b 0 0 2\r\nbb\r\n"))
+	assert.Equal(t, "NOT_STORED", c.line("replace c 0 0 1\r\nx\r\n"))
+	assert.Equal(t, "STORED", c.line("append a 0 0 1\r\n+\r\n"))
+	assert.Equal(t, "STORED", c.line("prepend a 0 0 1\r\n-\r\n"))
+	assert.Equal(t, "CLIENT_ERROR bad data chunk", c.line("set a 0 0 1\r\nxx\r\n"))
+
+	assert.Equal(t, []string{"VALUE a 5 4", "-aa+", "VALUE b 0 2", "bb", "END"}, c.cmd("get a b missing\r\n", "END"))
+
+	reply := c.cmd("gets a\r\n", "END")
+	fields := strings.Fields(reply[0])
+	require.Len(t, fields, 5)
+	unique := fields[4]
+
+	assert.Equal(t, "EXISTS", c.line("cas a 0 0 1 "+unique+"0\r\nx\r\n"))
+	assert.Equal(t, "STORED", c.line("cas a 0 0 1 "+unique+"\r\nx\r\n"))
+	assert.Equal(t, "NOT_FOUND", c.line("cas missing 0 0 1 1\r\nx\r\n"))
+
+	v, ok := s.Get("a")
+	require.True(t, ok)
+	assert.Equal(t, "x", string(v))
+
+	assert.Equal(t, "STORED", c.line("set n 0 0 2\r\n10\r\n"))
+	assert.Equal(t, "15", c.line("incr n 5\r\n"))
+	assert.Equal(t, "0", c.line("decr n 100\r\n"))
+	assert.Equal(t, "CLIENT_ERROR cannot increment or decrement non-numeric value", c.line("incr a 1\r\n"))
+
+	assert.Equal(t, "DELETED", c.line("delete b\r\n"))
+	assert.Equal(t, "NOT_FOUND", c.line("delete b\r\n"))
+
+	// noreply: the next reply belongs to the next command
+	assert.Equal(t, "VERSION "+version, c.line("set q 0 0 1 noreply\r\nq\r\nversion\r\n"))
+	assert.Equal(t, []string{"a", "n", "q"}, s.Keys())
+
+	assert.Equal(t, "ERROR", c.line("bogus\r\n"))
+
+	assert.Equal(t, "OK", c.line("flush_all\r\n"))
+	assert.Empty(t, s.Keys())
+}
+
+func TestExpiration(t *testing.T) {
+	s := Start(t)
+	c := dial(t, s)
+
+	assert.Equal(t, "STORED", c.line("set rel 0 1 1\r\nr\r\n"))
+	abs := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	assert.Equal(t, "STORED", c.line("set abs 0 "+abs+" 1\r\na\r\n"))
+	assert.Equal(t, "STORED", c.line("set neg 0 -1 1\r\nn\r\n"))
+	assert.Equal(t, "STORED", c.line("set forever 0 0 1\r\nf\r\n"))
+
+	ttl, ok := s.TTL("abs")
+	require.True(t, ok)
+	assert.InDelta(t, time.Hour.Seconds(), ttl.Seconds(), 2)
+
+	ttl, ok = s.TTL("forever")
+	require.True(t, ok)
+	assert.Zero(t, ttl)
+
+	_, ok = s.TTL("neg")
+	assert.False(t, ok)
+
+	time.Sleep(time.Millisecond * 1100)
+	assert.Equal(t, []string{"END"}, c.cmd("get rel\r\n", "END"))
+	assert.Equal(t, []string{"abs", "forever"}, s.Keys())
+
+	assert.Equal(t, "TOUCHED", c.line("touch forever 1\r\n"))
+	assert.Equal(t, "NOT_FOUND", c.line("touch rel 1\r\n"))
+
+	ttl, _ = s.TTL("forever")
+	assert.Greater(t, ttl, time.Duration(0))
+
+	// gat updates the expiration
+	assert.Equal(t, []string{"VALUE abs 0 1", "a", "END"}, c.cmd("gat 0 abs\r\n", "END"))
+	ttl, _ = s.TTL("abs")
+	assert.Zero(t, ttl)
+
+	assert.Equal(t, "OK", c.line("flush_all 1\r\n"))
+	assert.Len(t, s.Keys(), 2)
+	time.Sleep(time.Millisecond * 1100)
+	assert.Empty(t, s.Keys())
+}
+
+func TestInspection(t *testing.T) {
+	s := Start(t)
+	c := dial(t, s)
+
+	s.Set("k", []byte("v"), time.Minute)
+	assert.Equal(t, []string{"VALUE k 0 1", "v", "END"}, c.cmd("get k\r\n", "END"))
+
+	ttl, ok := s.TTL("k")
+	require.True(t, ok)
+	assert.InDelta(t, time.Minute.Seconds(), ttl.Seconds(), 1)
+
+	s.FlushAll()
+	_, ok = s.Get("k")
+	assert.False(t, ok)
+}
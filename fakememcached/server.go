@@ -0,0 +1,235 @@
+// Package fakememcached is the in-process stand-in for memcached. It speaks the memcached text protocol (storage
+// commands, get/gets/gat, delete, incr/decr, touch, flush_all) with the real expiration semantics: zero means no
+// expiration, up to 30 days is relative, above is the unix time, negative expires immediately.
+package fakememcached
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// relative expiration times are limited by 30 days, the bigger values are the unix timestamps
+const maxRelativeExpiration int64 = 60 * 60 * 24 * 30
+
+type item struct {
+	value []byte
+	flags uint32
+	cas   uint64
+	// zero means no expiration
+	expireAt time.Time
+}
+
+func (i *item) expired(now time.Time) bool {
+	return !i.expireAt.IsZero() && !now.Before(i.expireAt)
+}
+
+// Server is the memcached stand-in
+type Server struct {
+	ls net.Listener
+
+	mu    sync.Mutex
+	data  map[string]*item
+	conns map[*conn]struct{}
+	cas   uint64
+
+	wg sync.WaitGroup
+}
+
+// Listen starts the server on the addr (host:port), use port 0 for the random one
+func Listen(addr string) (*Server, error) {
+	ls, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		ls:    ls,
+		data:  make(map[string]*item),
+		conns: make(map[*conn]struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+
+	return s, nil
+}
+
+// Start starts the server on the random port, the server is closed when the test finishes
+func Start(t testing.TB) *Server {
+	t.Helper()
+
+	s, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start the memcached stand-in: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = s.Close()
+	})
+
+	return s
+}
+
+// Addr returns host:port the server listens on
+func (s *Server) Addr() string {
+	return s.ls.Addr().String()
+}
+
+// Close stops the server and closes all client connections
+func (s *Server) Close() error {
+	err := s.ls.Close()
+
+	s.mu.Lock()
+	for c := range s.conns {
+		_ = c.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+// Get returns the value of the key (if it exists and is not expired)
+func (s *Server) Get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	it := s.get(key, time.Now())
+	if it == nil {
+		return nil, false
+	}
+
+	return append([]byte(nil), it.value...), true
+}
+
+// Set stores the value with zero flags, zero ttl means no expiration
+func (s *Server) Set(key string, value []byte, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	it := &item{value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		it.expireAt = time.Now().Add(ttl)
+	}
+
+	s.store(key, it)
+}
+
+// TTL returns the remaining time to live of the key, zero if the key has no expiration. False if there is no key.
+func (s *Server) TTL(key string) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	it := s.get(key, now)
+	if it == nil {
+		return 0, false
+	}
+
+	if it.expireAt.IsZero() {
+		return 0, true
+	}
+
+	return it.expireAt.Sub(now), true
+}
+
+// Keys returns the sorted not expired keys
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	keys := make([]string, 0, len(s.data))
+	for k, it := range s.data {
+		if !it.expired(now) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// FlushAll removes all keys
+func (s *Server) FlushAll() {
+	s.mu.Lock()
+	s.data = make(map[string]*item)
+	s.mu.Unlock()
+}
+
+// get returns the live item, the expired one is removed, called under the lock
+func (s *Server) get(key string, now time.Time) *item {
+	it, ok := s.data[key]
+	if !ok {
+		return nil
+	}
+
+	if it.expired(now) {
+		delete(s.data, key)
+		return nil
+	}
+
+	return it
+}
+
+// store assigns the new CAS unique and stores the item, called under the lock
+func (s *Server) store(key string, it *item) {
+	s.cas++
+	it.cas = s.cas
+	s.data[key] = it
+}
+
+// expiration converts the protocol exptime to the deadline, zero time means no expiration
+func expiration(exptime int64, now time.Time) time.Time {
+	switch {
+	case exptime == 0:
+		return time.Time{}
+	case exptime < 0:
+		// already expired
+		return now
+	case exptime <= maxRelativeExpiration:
+		return now.Add(time.Duration(exptime) * time.Second)
+	default:
+		return time.Unix(exptime, 0)
+	}
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		nc, err := s.ls.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		c := &conn{
+			Conn: nc,
+			srv:  s,
+			r:    bufio.NewReader(nc),
+			w:    bufio.NewWriter(nc),
+		}
+
+		s.mu.Lock()
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			c.serve()
+
+			s.mu.Lock()
+			delete(s.conns, c)
+			s.mu.Unlock()
+		}()
+	}
+}
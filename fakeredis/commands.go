@@ -0,0 +1,367 @@
+package fakeredis
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+type command struct {
+	// number of arguments without the command name, negative maxArgs means unlimited
+	minArgs int
+	maxArgs int
+	// fn runs the command taking the server lock itself
+	fn func(c *conn, args [][]byte) reply
+	// locked runs the command under the already taken lock (inside EXEC), nil for the commands not allowed in MULTI
+	locked func(c *conn, args [][]byte) reply
+}
+
+func (cmd command) arity(n int) bool {
+	return n >= cmd.minArgs && (cmd.maxArgs < 0 || n <= cmd.maxArgs)
+}
+
+// data command, the same function is used in and outside of the transaction
+func data(minArgs, maxArgs int, fn func(s *Server, args [][]byte) reply) command {
+	return command{
+		minArgs: minArgs,
+		maxArgs: maxArgs,
+		fn: func(c *conn, args [][]byte) reply {
+			c.srv.mu.Lock()
+			defer c.srv.mu.Unlock()
+			return fn(c.srv, args)
+		},
+		locked: func(c *conn, args [][]byte) reply {
+			return fn(c.srv, args)
+		},
+	}
+}
+
+// connection command, doesn't touch the data
+func connection(minArgs, maxArgs int, fn func(c *conn, args [][]byte) reply) command {
+	return command{minArgs: minArgs, maxArgs: maxArgs, fn: fn, locked: fn}
+}
+
+var commands map[string]command //nolint:gochecknoglobals
+
+// commands which are allowed in the subscribed state
+var pubsubAllowed = map[string]bool{ //nolint:gochecknoglobals
+	"subscribe":    true,
+	"unsubscribe":  true,
+	"psubscribe":   true,
+	"punsubscribe": true,
+	"ping":         true,
+	"quit":         true,
+}
+
+func init() { //nolint:gochecknoinits
+	commands = map[string]command{
+		// connection
+		"ping": {minArgs: 0, maxArgs: 1, fn: ping, locked: pong},
+		"echo": connection(1, 1, func(_ *conn, args [][]byte) reply { return args[0] }),
+		"select": connection(1, 1, func(_ *conn, args [][]byte) reply {
+			if _, err := strconv.Atoi(string(args[0])); err != nil {
+				return errorf("ERR invalid DB index")
+			}
+			return ok
+		}),
+		"auth":    connection(1, 2, func(_ *conn, _ [][]byte) reply { return ok }),
+		"client":  connection(1, -1, clientCmd),
+		"command": connection(0, -1, func(_ *conn, _ [][]byte) reply { return []interface{}{} }),
+		"info": connection(0, -1, func(_ *conn, _ [][]byte) reply {
+			return []byte("# Server\r\nredis_version:6.2.6\r\nredis_mode:standalone\r\n")
+		}),
+
+		// keys
+		"get": data(1, 1, get),
+		"set": data(2, -1, set),
+		"setnx": data(2, 2, func(s *Server, args [][]byte) reply {
+			if s.get(string(args[0])) != nil {
+				return int64(0)
+			}
+			s.data[string(args[0])] = &item{value: args[1]}
+			return int64(1)
+		}),
+		"setex":     data(3, 3, setex(time.Second)),
+		"psetex":    data(3, 3, setex(time.Millisecond)),
+		"mget":      data(1, -1, mget),
+		"mset":      data(2, -1, mset),
+		"del":       data(1, -1, del),
+		"unlink":    data(1, -1, del),
+		"exists":    data(1, -1, exists),
+		"expire":    data(2, 2, expire(relative(time.Second))),
+		"pexpire":   data(2, 2, expire(relative(time.Millisecond))),
+		"expireat":  data(2, 2, expire(absolute(time.Second))),
+		"pexpireat": data(2, 2, expire(absolute(time.Millisecond))),
+		"persist":   data(1, 1, persist),
+		"ttl":       data(1, 1, ttl(time.Second)),
+		"pttl":      data(1, 1, ttl(time.Millisecond)),
+		"type":      data(1, 1, typ),
+		"keys":      data(1, 1, func(s *Server, args [][]byte) reply { return strings2array(s.keys(string(args[0]))) }),
+		"dbsize":    data(0, 0, func(s *Server, _ [][]byte) reply { return int64(len(s.keys("*"))) }),
+		"flushall": data(0, 1, func(s *Server, _ [][]byte) reply {
+			s.data = make(map[string]*item)
+			return ok
+		}),
+		"flushdb": data(0, 1, func(s *Server, _ [][]byte) reply {
+			s.data = make(map[string]*item)
+			return ok
+		}),
+
+		// pub/sub
+		"publish":      data(2, 2, publish),
+		"subscribe":    {minArgs: 1, maxArgs: -1, fn: subscribe},
+		"psubscribe":   {minArgs: 1, maxArgs: -1, fn: psubscribe},
+		"unsubscribe":  {minArgs: 0, maxArgs: -1, fn: unsubscribe},
+		"punsubscribe": {minArgs: 0, maxArgs: -1, fn: punsubscribe},
+		"pubsub":       data(1, -1, pubsub),
+	}
+}
+
+func ping(c *conn, args [][]byte) reply {
+	// in the subscribed state the reply is the array, go-redis health checks rely on it
+	if c.subscribed() {
+		msg := []byte{}
+		if len(args) == 1 {
+			msg = args[0]
+		}
+		return []interface{}{[]byte("pong"), msg}
+	}
+
+	return pong(c, args)
+}
+
+func pong(_ *conn, args [][]byte) reply {
+	if len(args) == 1 {
+		return args[0]
+	}
+
+	return simple("PONG")
+}
+
+func clientCmd(_ *conn, args [][]byte) reply {
+	switch strings.ToLower(string(args[0])) {
+	case "getname":
+		return nil
+	case "id":
+		return int64(1)
+	default:
+		return ok
+	}
+}
+
+func get(s *Server, args [][]byte) reply {
+	it := s.get(string(args[0]))
+	if it == nil {
+		return nil
+	}
+
+	return it.value
+}
+
+// SET key value [EX seconds|PX milliseconds|KEEPTTL] [NX|XX] [GET]
+func set(s *Server, args [][]byte) reply {
+	key := string(args[0])
+	var exp time.Duration
+	var nx, xx, keepTTL, getOld bool
+
+	for i := 2; i < len(args); i++ {
+		switch strings.ToLower(string(args[i])) {
+		case "ex", "px":
+			if i+1 >= len(args) {
+				return errorf("ERR syntax error")
+			}
+			n, err := strconv.ParseInt(string(args[i+1]), 10, 64)
+			if err != nil || n <= 0 {
+				return errorf("ERR invalid expire time in 'set' command")
+			}
+			unit := time.Second
+			if strings.EqualFold(string(args[i]), "px") {
+				unit = time.Millisecond
+			}
+			exp = time.Duration(n) * unit
+			i++
+		case "nx":
+			nx = true
+		case "xx":
+			xx = true
+		case "keepttl":
+			keepTTL = true
+		case "get":
+			getOld = true
+		default:
+			return errorf("ERR syntax error")
+		}
+	}
+
+	if nx && xx {
+		return errorf("ERR syntax error")
+	}
+
+	old := s.get(key)
+	var oldValue reply
+	if old != nil {
+		oldValue = old.value
+	}
+
+	if (nx && old != nil) || (xx && old == nil) {
+		if getOld {
+			return oldValue
+		}
+		return nil
+	}
+
+	it := &item{value: args[1]}
+	switch {
+	case exp > 0:
+		it.expireAt = time.Now().Add(exp)
+	case keepTTL && old != nil:
+		it.expireAt = old.expireAt
+	}
+	s.data[key] = it
+
+	if getOld {
+		return oldValue
+	}
+
+	return ok
+}
+
+func setex(unit time.Duration) func(s *Server, args [][]byte) reply {
+	return func(s *Server, args [][]byte) reply {
+		n, err := strconv.ParseInt(string(args[1]), 10, 64)
+		if err != nil || n <= 0 {
+			return errorf("ERR invalid expire time in 'setex' command")
+		}
+
+		s.data[string(args[0])] = &item{value: args[2], expireAt: time.Now().Add(time.Duration(n) * unit)}
+		return ok
+	}
+}
+
+func mget(s *Server, args [][]byte) reply {
+	out := make([]interface{}, len(args))
+	for i := 0; i < len(args); i++ {
+		if it := s.get(string(args[i])); it != nil {
+			out[i] = it.value
+		}
+	}
+
+	return out
+}
+
+func mset(s *Server, args [][]byte) reply {
+	if len(args)%2 != 0 {
+		return errorf("ERR wrong number of arguments for 'mset' command")
+	}
+
+	for i := 0; i < len(args); i += 2 {
+		s.data[string(args[i])] = &item{value: args[i+1]}
+	}
+
+	return ok
+}
+
+func del(s *Server, args [][]byte) reply {
+	var n int64
+	for i := 0; i < len(args); i++ {
+		if s.get(string(args[i])) != nil {
+			delete(s.data, string(args[i]))
+			n++
+		}
+	}
+
+	return n
+}
+
+func exists(s *Server, args [][]byte) reply {
+	var n int64
+	for i := 0; i < len(args); i++ {
+		if s.get(string(args[i])) != nil {
+			n++
+		}
+	}
+
+	return n
+}
+
+// relative ttl in the unit
+func relative(unit time.Duration) func(n int64) time.Time {
+	return func(n int64) time.Time {
+		return time.Now().Add(time.Duration(n) * unit)
+	}
+}
+
+// absolute unix time in the unit
+func absolute(unit time.Duration) func(n int64) time.Time {
+	return func(n int64) time.Time {
+		return time.Unix(0, 0).Add(time.Duration(n) * unit)
+	}
+}
+
+func expire(deadline func(n int64) time.Time) func(s *Server, args [][]byte) reply {
+	return func(s *Server, args [][]byte) reply {
+		n, err := strconv.ParseInt(string(args[1]), 10, 64)
+		if err != nil {
+			return errorf("ERR value is not an integer or out of range")
+		}
+
+		it := s.get(string(args[0]))
+		if it == nil {
+			return int64(0)
+		}
+
+		// the deadline in the past deletes the key
+		at := deadline(n)
+		if !at.After(time.Now()) {
+			delete(s.data, string(args[0]))
+			return int64(1)
+		}
+
+		it.expireAt = at
+		return int64(1)
+	}
+}
+
+func persist(s *Server, args [][]byte) reply {
+	it := s.get(string(args[0]))
+	if it == nil || it.expireAt.IsZero() {
+		return int64(0)
+	}
+
+	it.expireAt = time.Time{}
+	return int64(1)
+}
+
+func ttl(unit time.Duration) func(s *Server, args [][]byte) reply {
+	return func(s *Server, args [][]byte) reply {
+		it := s.get(string(args[0]))
+		if it == nil {
+			return int64(-2)
+		}
+
+		if it.expireAt.IsZero() {
+			return int64(-1)
+		}
+
+		// round to the nearest the same way redis does ((ttl+500)/1000), a key with 1.4s left has the ttl 1
+		left := time.Until(it.expireAt)
+		return int64((left + unit/2) / unit)
+	}
+}
+
+func typ(s *Server, args [][]byte) reply {
+	if s.get(string(args[0])) == nil {
+		return simple("none")
+	}
+
+	return simple("string")
+}
+
+func strings2array(ss []string) []interface{} {
+	out := make([]interface{}, len(ss))
+	for i := 0; i < len(ss); i++ {
+		out[i] = []byte(ss[i])
+	}
+
+	return out
+}
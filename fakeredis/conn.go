@@ -0,0 +1,214 @@
+package fakeredis
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+)
+
+// size of the per-connection queue of the published messages
+const outboxSize int = 1024
+
+type conn struct {
+	net.Conn
+	srv *Server
+
+	r *bufio.Reader
+	// w is shared with the outbox writer
+	wmu sync.Mutex
+	w   *bufio.Writer
+
+	// published messages, written in order by the separate goroutine to not block the publisher
+	outbox chan reply
+	done   chan struct{}
+
+	// protected by srv.mu
+	channels map[string]struct{}
+	patterns map[string]struct{}
+
+	// MULTI queue, nil when not in the transaction
+	multi [][][]byte
+	// error in the queued command discards the whole transaction
+	multiErr bool
+}
+
+func (c *conn) serve() {
+	c.outbox = make(chan reply, outboxSize)
+	c.done = make(chan struct{})
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.writeOutbox()
+	}()
+
+	defer func() {
+		c.srv.mu.Lock()
+		c.channels = make(map[string]struct{})
+		c.patterns = make(map[string]struct{})
+		c.srv.mu.Unlock()
+
+		close(c.done)
+		wg.Wait()
+		_ = c.Close()
+	}()
+
+	for {
+		args, err := readCommand(c.r)
+		if err != nil {
+			if errors.Is(err, errProtocol) {
+				_ = c.write(errorf("ERR %v", err))
+			}
+			return
+		}
+
+		if len(args) == 0 {
+			continue
+		}
+
+		name := strings.ToLower(string(args[0]))
+		if name == "quit" {
+			_ = c.write(ok)
+			return
+		}
+
+		rep := c.dispatch(name, args[1:])
+		if _, skip := rep.(noReply); skip {
+			continue
+		}
+
+		err = c.write(rep)
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (c *conn) write(rep reply) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	err := writeReply(c.w, rep)
+	if err != nil {
+		return err
+	}
+
+	return c.w.Flush()
+}
+
+// push queues the published message, called under the server lock
+func (c *conn) push(msg reply) {
+	select {
+	case c.outbox <- msg:
+	case <-c.done:
+	}
+}
+
+func (c *conn) writeOutbox() {
+	for {
+		select {
+		case msg := <-c.outbox:
+			if c.write(msg) != nil {
+				// drop the rest, the connection is broken
+				_ = c.Close()
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *conn) dispatch(name string, args [][]byte) reply {
+	if c.multi != nil {
+		switch name {
+		case "exec":
+			return c.exec()
+		case "discard":
+			c.multi = nil
+			c.multiErr = false
+			return ok
+		case "multi":
+			return errorf("ERR MULTI calls can not be nested")
+		}
+
+		cmd, found := commands[name]
+		if !found || !cmd.arity(len(args)) {
+			c.multiErr = true
+			return unknownOrArity(name, found)
+		}
+
+		if cmd.locked == nil {
+			c.multiErr = true
+			return errorf("ERR Command '%s' is not allowed inside a transaction", name)
+		}
+
+		c.multi = append(c.multi, append([][]byte{[]byte(name)}, args...))
+		return queued
+	}
+
+	switch name {
+	case "multi":
+		if c.subscribed() {
+			break
+		}
+		c.multi = make([][][]byte, 0, 4)
+		return ok
+	case "exec":
+		return errorf("ERR EXEC without MULTI")
+	case "discard":
+		return errorf("ERR DISCARD without MULTI")
+	}
+
+	if c.subscribed() && !pubsubAllowed[name] {
+		return errorf("ERR Can't execute '%s': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT are allowed in this context", name)
+	}
+
+	cmd, found := commands[name]
+	if !found || !cmd.arity(len(args)) {
+		return unknownOrArity(name, found)
+	}
+
+	return cmd.fn(c, args)
+}
+
+func unknownOrArity(name string, found bool) reply {
+	if !found {
+		return errorf("ERR unknown command '%s'", name)
+	}
+
+	return errorf("ERR wrong number of arguments for '%s' command", name)
+}
+
+func (c *conn) exec() reply {
+	queue := c.multi
+	failed := c.multiErr
+	c.multi = nil
+	c.multiErr = false
+
+	if failed {
+		return errorf("EXECABORT Transaction discarded because of previous errors.")
+	}
+
+	// the commands are executed without the interleaving with the other clients
+	c.srv.mu.Lock()
+	defer c.srv.mu.Unlock()
+
+	replies := make([]interface{}, 0, len(queue))
+	for i := 0; i < len(queue); i++ {
+		cmd := commands[string(queue[i][0])]
+		replies = append(replies, cmd.locked(c, queue[i][1:]))
+	}
+
+	return replies
+}
+
+// subscribed reports whether the connection is in the pub/sub mode
+func (c *conn) subscribed() bool {
+	c.srv.mu.Lock()
+	defer c.srv.mu.Unlock()
+
+	return len(c.channels)+len(c.patterns) > 0
+}
@@ -0,0 +1,196 @@
+package fakeredis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func client(t *testing.T, s *Server) *redis.Client {
+	c := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	t.Cleanup(func() {
+		_ = c.Close()
+	})
+
+	return c
+}
+
+func TestStrings(t *testing.T) {
+	s := Start(t)
+	c := client(t, s)
+	ctx := context.Background()
+
+	require.NoError(t, c.Ping(ctx).Err())
+	require.NoError(t, c.Set(ctx, "a", "1", 0).Err())
+	require.NoError(t, c.Set(ctx, "b", "2", time.Minute).Err())
+
+	v, err := c.Get(ctx, "a").Result()
+	require.NoError(t, err)
+	assert.Equal(t, "1", v)
+
+	_, err = c.Get(ctx, "none").Result()
+	assert.Equal(t, redis.Nil, err)
+
+	vals, err := c.MGet(ctx, "a", "none", "b").Result()
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"1", nil, "2"}, vals)
+
+	ttl, err := c.TTL(ctx, "b").Result()
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, ttl)
+
+	ttl, err = c.TTL(ctx, "a").Result()
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(-1), ttl)
+
+	// rounded to the nearest second
+	require.NoError(t, c.Set(ctx, "short", "1", time.Millisecond*1400).Err())
+	require.NoError(t, c.Set(ctx, "long", "1", time.Millisecond*1700).Err())
+	ttl, err = c.TTL(ctx, "short").Result()
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, ttl)
+	ttl, err = c.TTL(ctx, "long").Result()
+	require.NoError(t, err)
+	assert.Equal(t, time.Second*2, ttl)
+	require.NoError(t, c.Del(ctx, "short", "long").Err())
+
+	set, err := c.SetNX(ctx, "a", "3", 0).Result()
+	require.NoError(t, err)
+	assert.False(t, set)
+
+	n, err := c.Exists(ctx, "a", "b", "none").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	n, err = c.Del(ctx, "a", "none").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	keys, err := c.Keys(ctx, "*").Result()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, keys)
+
+	require.NoError(t, c.FlushAll(ctx).Err())
+	assert.Empty(t, s.Keys())
+}
+
+func TestExpiration(t *testing.T) {
+	s := Start(t)
+	c := client(t, s)
+	ctx := context.Background()
+
+	require.NoError(t, c.Set(ctx, "short", "v", time.Millisecond*100).Err())
+	require.NoError(t, c.Set(ctx, "long", "v", 0).Err())
+
+	ok, err := c.Expire(ctx, "long", time.Hour).Result()
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ttl, found := s.TTL("long")
+	require.True(t, found)
+	assert.InDelta(t, time.Hour, ttl, float64(time.Second))
+
+	time.Sleep(time.Millisecond * 200)
+
+	_, err = c.Get(ctx, "short").Result()
+	assert.Equal(t, redis.Nil, err)
+
+	_, found = s.Get("short")
+	assert.False(t, found)
+
+	ok, err = c.ExpireAt(ctx, "long", time.Now().Add(time.Minute)).Result()
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ttl, found = s.TTL("long")
+	require.True(t, found)
+	assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+
+	ok, err = c.Persist(ctx, "long").Result()
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ttl, found = s.TTL("long")
+	require.True(t, found)
+	assert.Zero(t, ttl)
+}
+
+func TestTransaction(t *testing.T) {
+	s := Start(t)
+	c := client(t, s)
+	ctx := context.Background()
+
+	s.Set("counter", []byte("seeded"), 0)
+
+	cmds, err := c.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.Get(ctx, "counter")
+		p.Set(ctx, "k1", "v1", time.Minute)
+		p.MGet(ctx, "k1", "counter")
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, cmds, 3)
+	assert.Equal(t, "seeded", cmds[0].(*redis.StringCmd).Val())
+	assert.Equal(t, []interface{}{"v1", "seeded"}, cmds[2].(*redis.SliceCmd).Val())
+
+	_, err = c.Pipelined(ctx, func(p redis.Pipeliner) error {
+		p.Set(ctx, "k2", "v2", 0)
+		p.Expire(ctx, "k2", time.Minute)
+		return nil
+	})
+	require.NoError(t, err)
+
+	v, found := s.Get("k2")
+	require.True(t, found)
+	assert.Equal(t, []byte("v2"), v)
+}
+
+func TestPubSub(t *testing.T) {
+	s := Start(t)
+	sub := client(t, s)
+	pub := client(t, s)
+	ctx := context.Background()
+
+	ps := sub.Subscribe(ctx, "foo")
+	defer func() {
+		_ = ps.Close()
+	}()
+
+	_, err := ps.Receive(ctx)
+	require.NoError(t, err)
+	require.NoError(t, ps.PSubscribe(ctx, "ba*"))
+	require.Equal(t, 1, s.Subscribers("foo"))
+
+	n, err := pub.Publish(ctx, "foo", "hello").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	require.NoError(t, pub.Publish(ctx, "bar", "world").Err())
+	require.NoError(t, pub.Publish(ctx, "other", "lost").Err())
+	require.NoError(t, ps.Ping(ctx))
+
+	ch := ps.Channel()
+	for _, expected := range []*redis.Message{
+		{Channel: "foo", Payload: "hello"},
+		{Channel: "bar", Pattern: "ba*", Payload: "world"},
+	} {
+		select {
+		case msg := <-ch:
+			assert.Equal(t, expected.Channel, msg.Channel)
+			assert.Equal(t, expected.Pattern, msg.Pattern)
+			assert.Equal(t, expected.Payload, msg.Payload)
+		case <-time.After(time.Second * 5):
+			t.Fatal("message was not received")
+		}
+	}
+
+	require.NoError(t, ps.Unsubscribe(ctx, "foo"))
+	require.Eventually(t, func() bool { return s.Subscribers("foo") == 0 }, time.Second*5, time.Millisecond*10)
+
+	n, err = pub.Publish(ctx, "foo", "nobody").Result()
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
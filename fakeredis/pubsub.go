@@ -0,0 +1,146 @@
+package fakeredis
+
+import (
+	"sort"
+	"strings"
+)
+
+// publish delivers the message to the subscribers, called under the server lock
+func publish(s *Server, args [][]byte) reply {
+	channel := string(args[0])
+	var n int64
+
+	for c := range s.conns {
+		if _, ok := c.channels[channel]; ok {
+			c.push([]interface{}{[]byte("message"), args[0], args[1]})
+			n++
+		}
+
+		for p := range c.patterns {
+			if match(p, channel) {
+				c.push([]interface{}{[]byte("pmessage"), []byte(p), args[0], args[1]})
+				n++
+			}
+		}
+	}
+
+	return n
+}
+
+func subscribe(c *conn, args [][]byte) reply {
+	return c.subscribe("subscribe", args, func() map[string]struct{} { return c.channels })
+}
+
+func psubscribe(c *conn, args [][]byte) reply {
+	return c.subscribe("psubscribe", args, func() map[string]struct{} { return c.patterns })
+}
+
+func unsubscribe(c *conn, args [][]byte) reply {
+	return c.unsubscribe("unsubscribe", args, func() map[string]struct{} { return c.channels })
+}
+
+func punsubscribe(c *conn, args [][]byte) reply {
+	return c.unsubscribe("punsubscribe", args, func() map[string]struct{} { return c.patterns })
+}
+
+// subscribe writes the confirmation per channel by itself, the returned reply is empty
+func (c *conn) subscribe(kind string, args [][]byte, set func() map[string]struct{}) reply {
+	for i := 0; i < len(args); i++ {
+		c.srv.mu.Lock()
+		set()[string(args[i])] = struct{}{}
+		count := int64(len(c.channels) + len(c.patterns))
+		c.srv.mu.Unlock()
+
+		err := c.write([]interface{}{[]byte(kind), args[i], count})
+		if err != nil {
+			return noReply{}
+		}
+	}
+
+	return noReply{}
+}
+
+func (c *conn) unsubscribe(kind string, args [][]byte, set func() map[string]struct{}) reply {
+	c.srv.mu.Lock()
+	if len(args) == 0 {
+		names := make([]string, 0, len(set()))
+		for name := range set() {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for i := 0; i < len(names); i++ {
+			args = append(args, []byte(names[i]))
+		}
+	}
+	c.srv.mu.Unlock()
+
+	if len(args) == 0 {
+		_ = c.write([]interface{}{[]byte(kind), nil, int64(0)})
+		return noReply{}
+	}
+
+	for i := 0; i < len(args); i++ {
+		c.srv.mu.Lock()
+		delete(set(), string(args[i]))
+		count := int64(len(c.channels) + len(c.patterns))
+		c.srv.mu.Unlock()
+
+		err := c.write([]interface{}{[]byte(kind), args[i], count})
+		if err != nil {
+			return noReply{}
+		}
+	}
+
+	return noReply{}
+}
+
+// PUBSUB CHANNELS [pattern] | NUMSUB [channel...] | NUMPAT
+func pubsub(s *Server, args [][]byte) reply {
+	switch strings.ToLower(string(args[0])) {
+	case "channels":
+		pattern := "*"
+		if len(args) > 1 {
+			pattern = string(args[1])
+		}
+
+		set := make(map[string]struct{})
+		for c := range s.conns {
+			for ch := range c.channels {
+				if match(pattern, ch) {
+					set[ch] = struct{}{}
+				}
+			}
+		}
+
+		names := make([]string, 0, len(set))
+		for ch := range set {
+			names = append(names, ch)
+		}
+		sort.Strings(names)
+
+		return strings2array(names)
+	case "numsub":
+		out := make([]interface{}, 0, (len(args)-1)*2)
+		for i := 1; i < len(args); i++ {
+			var n int64
+			for c := range s.conns {
+				if _, ok := c.channels[string(args[i])]; ok {
+					n++
+				}
+			}
+			out = append(out, args[i], n)
+		}
+
+		return out
+	case "numpat":
+		var n int64
+		for c := range s.conns {
+			n += int64(len(c.patterns))
+		}
+
+		return n
+	default:
+		return errorf("ERR unknown subcommand '%s'", args[0])
+	}
+}
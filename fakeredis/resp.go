@@ -0,0 +1,153 @@
+package fakeredis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maximum size of the bulk string accepted from the clients (the same as the default proto-max-bulk-len)
+const maxBulkLen int = 512 * 1024 * 1024
+
+// reply is one of: simple, errReply, int64, string, []byte (nil is the null bulk), []interface{} (array) or nil
+type reply interface{}
+
+type simple string
+
+type errReply string
+
+// noReply is returned by the commands which write the replies by themselves (SUBSCRIBE and friends)
+type noReply struct{}
+
+// errProtocol is returned for the malformed client input, the connection is closed after the error reply
+var errProtocol = errors.New("protocol error")
+
+var (
+	ok     reply = simple("OK")
+	queued reply = simple("QUEUED")
+)
+
+func errorf(format string, args ...interface{}) reply {
+	return errReply(fmt.Sprintf(format, args...))
+}
+
+// readCommand reads the command either in the RESP array form or the inline one (telnet)
+func readCommand(r *bufio.Reader) ([][]byte, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) == 0 {
+		return nil, nil
+	}
+
+	if line[0] != '*' {
+		fields := strings.Fields(line)
+		args := make([][]byte, len(fields))
+		for i := 0; i < len(fields); i++ {
+			args[i] = []byte(fields[i])
+		}
+		return args, nil
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid multibulk length: %s", errProtocol, line)
+	}
+
+	args := make([][]byte, 0, n)
+	for i := 0; i < n; i++ {
+		line, err = readLine(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(line) == 0 || line[0] != '$' {
+			return nil, fmt.Errorf("%w: expected '$', got: %q", errProtocol, line)
+		}
+
+		l, err := strconv.Atoi(line[1:])
+		if err != nil || l < 0 || l > maxBulkLen {
+			return nil, fmt.Errorf("%w: invalid bulk length: %s", errProtocol, line)
+		}
+
+		buf := make([]byte, l+2)
+		_, err = io.ReadFull(r, buf)
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, buf[:l])
+	}
+
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.HasSuffix(line, "\r\n") {
+		// inline commands might be terminated with \n only
+		return strings.TrimSuffix(line, "\n"), nil
+	}
+
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
+
+func writeReply(w *bufio.Writer, rep reply) error {
+	var err error
+	switch v := rep.(type) {
+	case simple:
+		_, err = fmt.Fprintf(w, "+%s\r\n", v)
+	case errReply:
+		_, err = fmt.Fprintf(w, "-%s\r\n", v)
+	case int64:
+		_, err = fmt.Fprintf(w, ":%d\r\n", v)
+	case int:
+		_, err = fmt.Fprintf(w, ":%d\r\n", v)
+	case string:
+		_, err = fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+	case []byte:
+		if v == nil {
+			_, err = w.WriteString("$-1\r\n")
+			break
+		}
+		_, err = fmt.Fprintf(w, "$%d\r\n", len(v))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.WriteString("\r\n")
+	case []interface{}:
+		if v == nil {
+			_, err = w.WriteString("*-1\r\n")
+			break
+		}
+		_, err = fmt.Fprintf(w, "*%d\r\n", len(v))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < len(v); i++ {
+			err = writeReply(w, v[i])
+			if err != nil {
+				return err
+			}
+		}
+	case nil:
+		_, err = w.WriteString("$-1\r\n")
+	default:
+		return errors.New("unknown reply type")
+	}
+
+	return err
+}
@@ -0,0 +1,243 @@
+// Package fakeredis is the in-process stand-in for Redis. It speaks RESP2 and supports the commands used by the RR
+// redis kv and pub/sub drivers (strings with expiration, transactions and pub/sub), so the suites can run without
+// the redis containers.
+package fakeredis
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type item struct {
+	value []byte
+	// zero means no expiration
+	expireAt time.Time
+}
+
+func (i *item) expired(now time.Time) bool {
+	return !i.expireAt.IsZero() && !now.Before(i.expireAt)
+}
+
+// Server is the Redis stand-in, a single database shared by all connections (SELECT is accepted and ignored)
+type Server struct {
+	ls net.Listener
+
+	mu    sync.Mutex
+	data  map[string]*item
+	conns map[*conn]struct{}
+
+	wg sync.WaitGroup
+}
+
+// Listen starts the server on the addr (host:port), use port 0 for the random one
+func Listen(addr string) (*Server, error) {
+	ls, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		ls:    ls,
+		data:  make(map[string]*item),
+		conns: make(map[*conn]struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+
+	return s, nil
+}
+
+// Start starts the server on the random port, the server is closed when the test finishes
+func Start(t testing.TB) *Server {
+	t.Helper()
+
+	s, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start the redis stand-in: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = s.Close()
+	})
+
+	return s
+}
+
+// Addr returns host:port the server listens on
+func (s *Server) Addr() string {
+	return s.ls.Addr().String()
+}
+
+// Close stops the server and closes all client connections
+func (s *Server) Close() error {
+	err := s.ls.Close()
+
+	s.mu.Lock()
+	for c := range s.conns {
+		_ = c.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+// Get returns the value of the key (if it exists and is not expired)
+func (s *Server) Get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	it := s.get(key)
+	if it == nil {
+		return nil, false
+	}
+
+	return append([]byte(nil), it.value...), true
+}
+
+// Set sets the value of the key, zero ttl means no expiration
+func (s *Server) Set(key string, value []byte, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	it := &item{value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		it.expireAt = time.Now().Add(ttl)
+	}
+
+	s.data[key] = it
+}
+
+// TTL returns the remaining time to live of the key, zero if the key has no expiration. False if there is no key.
+func (s *Server) TTL(key string) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	it := s.get(key)
+	if it == nil {
+		return 0, false
+	}
+
+	if it.expireAt.IsZero() {
+		return 0, true
+	}
+
+	return time.Until(it.expireAt), true
+}
+
+// Keys returns the sorted not expired keys
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.keys("*")
+}
+
+// FlushAll removes all keys
+func (s *Server) FlushAll() {
+	s.mu.Lock()
+	s.data = make(map[string]*item)
+	s.mu.Unlock()
+}
+
+// Subscribers returns the number of connections subscribed to the channel (patterns are not counted)
+func (s *Server) Subscribers(channel string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for c := range s.conns {
+		if _, ok := c.channels[channel]; ok {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		nc, err := s.ls.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		c := &conn{
+			Conn:     nc,
+			srv:      s,
+			r:        bufio.NewReader(nc),
+			w:        bufio.NewWriter(nc),
+			channels: make(map[string]struct{}),
+			patterns: make(map[string]struct{}),
+		}
+
+		s.mu.Lock()
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			c.serve()
+
+			s.mu.Lock()
+			delete(s.conns, c)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// get returns the item removing the expired one, must be called under the lock
+func (s *Server) get(key string) *item {
+	it, ok := s.data[key]
+	if !ok {
+		return nil
+	}
+
+	if it.expired(time.Now()) {
+		delete(s.data, key)
+		return nil
+	}
+
+	return it
+}
+
+// keys returns the sorted keys matching the glob pattern, must be called under the lock
+func (s *Server) keys(pattern string) []string {
+	keys := make([]string, 0, len(s.data))
+	for k := range s.data {
+		if s.get(k) == nil {
+			continue
+		}
+
+		if match(pattern, k) {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// match is the redis glob-style match, path.Match is close enough except the separators
+func match(pattern, s string) bool {
+	if pattern == "*" {
+		return true
+	}
+
+	ok, err := filepath.Match(strings.ReplaceAll(pattern, "/", "\x00"), strings.ReplaceAll(s, "/", "\x00"))
+	return err == nil && ok
+}
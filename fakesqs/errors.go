@@ -0,0 +1,50 @@
+package fakesqs
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// apiError is the SQS error, code is used in the query protocol, jsonType in the JSON one
+type apiError struct {
+	status   int
+	code     string
+	jsonType string
+	message  string
+}
+
+func (e *apiError) Error() string {
+	return e.code + ": " + e.message
+}
+
+func newError(code, jsonType, msg string) *apiError {
+	return &apiError{status: http.StatusBadRequest, code: code, jsonType: "com.amazonaws.sqs#" + jsonType, message: msg}
+}
+
+func errNonExistentQueue() error {
+	return newError("AWS.SimpleQueueService.NonExistentQueue", "QueueDoesNotExist", "The specified queue does not exist for this wsdl version.")
+}
+
+func errQueueAlreadyExists(name string) error {
+	return newError("QueueAlreadyExists", "QueueNameExists", fmt.Sprintf("A queue already exists with the same name and a different value for attribute(s): %s", name))
+}
+
+func errReceiptHandle(receipt string) error {
+	return newError("ReceiptHandleIsInvalid", "ReceiptHandleIsInvalid", fmt.Sprintf("The input receipt handle \"%s\" is not a valid receipt handle.", receipt))
+}
+
+func errMessageNotInflight() error {
+	return newError("AWS.SimpleQueueService.MessageNotInflight", "MessageNotInflight", "The message referred to isn't in flight.")
+}
+
+func errMissingParameter(msg string) error {
+	return newError("MissingParameter", "MissingParameter", msg)
+}
+
+func errInvalidParameter(msg string) error {
+	return newError("InvalidParameterValue", "InvalidParameterValue", msg)
+}
+
+func errInvalidAction(action string) error {
+	return newError("InvalidAction", "InvalidAction", fmt.Sprintf("The action %s is not valid for this endpoint.", action))
+}
@@ -0,0 +1,269 @@
+package fakesqs
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testResult struct {
+	QueueURL       []string       `xml:"QueueUrl"`
+	Attributes     []xmlAttribute `xml:"Attribute"`
+	Tags           []xmlTag       `xml:"Tag"`
+	MessageID      string         `xml:"MessageId"`
+	MD5OfBody      string         `xml:"MD5OfMessageBody"`
+	SequenceNumber string         `xml:"SequenceNumber"`
+	Messages       []xmlMessage   `xml:"Message"`
+}
+
+type testResponse struct {
+	Result testResult `xml:",any"`
+	Error  xmlError   `xml:"Error"`
+}
+
+// do sends the query protocol request, returns the result and the error code (if any)
+func do(t *testing.T, s *Server, action string, params ...string) (*testResult, string) {
+	t.Helper()
+
+	form := url.Values{"Action": {action}, "Version": {"2012-11-05"}}
+	for i := 0; i+1 < len(params); i += 2 {
+		form.Set(params[i], params[i+1])
+	}
+
+	resp, err := http.PostForm(s.URL(), form) //nolint:gosec,noctx
+	require.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	out := &testResponse{}
+	require.NoError(t, xml.NewDecoder(resp.Body).Decode(out))
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, out.Error.Code
+	}
+
+	return &out.Result, ""
+}
+
+func mustDo(t *testing.T, s *Server, action string, params ...string) *testResult {
+	t.Helper()
+
+	res, code := do(t, s, action, params...)
+	require.Empty(t, code)
+
+	return res
+}
+
+func TestQueues(t *testing.T) {
+	s := Start(t)
+
+	res := mustDo(t, s, "CreateQueue", "QueueName", "default",
+		"Attribute.1.Name", "VisibilityTimeout", "Attribute.1.Value", "10",
+		"Tag.1.Key", "env", "Tag.1.Value", "test")
+	require.Equal(t, []string{s.QueueURL("default")}, res.QueueURL)
+
+	// same attributes - the same queue, different - error
+	mustDo(t, s, "CreateQueue", "QueueName", "default", "Attribute.1.Name", "VisibilityTimeout", "Attribute.1.Value", "10")
+	_, code := do(t, s, "CreateQueue", "QueueName", "default", "Attribute.1.Name", "VisibilityTimeout", "Attribute.1.Value", "20")
+	assert.Equal(t, "QueueAlreadyExists", code)
+
+	mustDo(t, s, "CreateQueue", "QueueName", "other")
+	assert.Equal(t, []string{"default", "other"}, s.Queues())
+
+	res = mustDo(t, s, "ListQueues", "QueueNamePrefix", "def")
+	assert.Equal(t, []string{s.QueueURL("default")}, res.QueueURL)
+
+	res = mustDo(t, s, "GetQueueUrl", "QueueName", "other")
+	assert.Equal(t, []string{s.QueueURL("other")}, res.QueueURL)
+
+	_, code = do(t, s, "GetQueueUrl", "QueueName", "missing")
+	assert.Equal(t, "AWS.SimpleQueueService.NonExistentQueue", code)
+
+	mustDo(t, s, "TagQueue", "QueueUrl", s.QueueURL("default"), "Tag.1.Key", "team", "Tag.1.Value", "rr")
+	mustDo(t, s, "UntagQueue", "QueueUrl", s.QueueURL("default"), "TagKey.1", "env")
+	res = mustDo(t, s, "ListQueueTags", "QueueUrl", s.QueueURL("default"))
+	assert.Equal(t, []xmlTag{{Key: "team", Value: "rr"}}, res.Tags)
+
+	tags, ok := s.Tags("default")
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{"team": "rr"}, tags)
+
+	mustDo(t, s, "SetQueueAttributes", "QueueUrl", s.QueueURL("default"), "Attribute.1.Name", "DelaySeconds", "Attribute.1.Value", "5")
+	res = mustDo(t, s, "GetQueueAttributes", "QueueUrl", s.QueueURL("default"),
+		"AttributeName.1", "DelaySeconds", "AttributeName.2", "VisibilityTimeout")
+	assert.Equal(t, []xmlAttribute{{Name: "DelaySeconds", Value: "5"}, {Name: "VisibilityTimeout", Value: "10"}}, res.Attributes)
+
+	mustDo(t, s, "DeleteQueue", "QueueUrl", s.QueueURL("other"))
+	assert.Equal(t, []string{"default"}, s.Queues())
+}
+
+func TestSendReceiveDelete(t *testing.T) {
+	s := Start(t)
+	qu := mustDo(t, s, "CreateQueue", "QueueName", "default").QueueURL[0]
+
+	res := mustDo(t, s, "SendMessage", "QueueUrl", qu, "MessageBody", "hello",
+		"MessageAttribute.1.Name", "rr_job", "MessageAttribute.1.Value.DataType", "String",
+		"MessageAttribute.1.Value.StringValue", "test")
+	assert.NotEmpty(t, res.MessageID)
+	assert.Equal(t, md5Hex("hello"), res.MD5OfBody)
+
+	res = mustDo(t, s, "ReceiveMessage", "QueueUrl", qu, "VisibilityTimeout", "1",
+		"AttributeName.1", "All", "MessageAttributeName.1", "All")
+	require.Len(t, res.Messages, 1)
+	m := res.Messages[0]
+	assert.Equal(t, "hello", m.Body)
+	assert.Equal(t, []xmlMessageAttribute{{Name: "rr_job", Value: xmlAttributeValue{DataType: "String", StringValue: "test"}}}, m.MessageAttributes)
+	assert.Contains(t, m.Attributes, xmlAttribute{Name: "ApproximateReceiveCount", Value: "1"})
+
+	msgs, ok := s.Messages("default")
+	require.True(t, ok)
+	require.Len(t, msgs, 1)
+	assert.True(t, msgs[0].InFlight)
+	assert.Equal(t, "test", msgs[0].Attributes["rr_job"].StringValue)
+
+	// invisible for now
+	res = mustDo(t, s, "ReceiveMessage", "QueueUrl", qu)
+	assert.Empty(t, res.Messages)
+
+	// long polling returns the message when it becomes visible again
+	res = mustDo(t, s, "ReceiveMessage", "QueueUrl", qu, "WaitTimeSeconds", "3")
+	require.Len(t, res.Messages, 1)
+	assert.Equal(t, m.MessageID, res.Messages[0].MessageID)
+
+	// the old receipt handle is no longer valid for the visibility change
+	_, code := do(t, s, "ChangeMessageVisibility", "QueueUrl", qu, "ReceiptHandle", "bogus", "VisibilityTimeout", "0")
+	assert.Equal(t, "ReceiptHandleIsInvalid", code)
+
+	mustDo(t, s, "ChangeMessageVisibility", "QueueUrl", qu, "ReceiptHandle", res.Messages[0].ReceiptHandle, "VisibilityTimeout", "0")
+	res = mustDo(t, s, "ReceiveMessage", "QueueUrl", qu)
+	require.Len(t, res.Messages, 1)
+
+	mustDo(t, s, "DeleteMessage", "QueueUrl", qu, "ReceiptHandle", res.Messages[0].ReceiptHandle)
+	msgs, _ = s.Messages("default")
+	assert.Empty(t, msgs)
+}
+
+func TestDelay(t *testing.T) {
+	s := Start(t)
+	qu := mustDo(t, s, "CreateQueue", "QueueName", "default").QueueURL[0]
+
+	mustDo(t, s, "SendMessage", "QueueUrl", qu, "MessageBody", "later", "DelaySeconds", "1")
+
+	msgs, _ := s.Messages("default")
+	require.Len(t, msgs, 1)
+	assert.True(t, msgs[0].Delayed)
+
+	attrs, _ := s.Attributes("default")
+	assert.Equal(t, "1", attrs["ApproximateNumberOfMessagesDelayed"])
+
+	res := mustDo(t, s, "ReceiveMessage", "QueueUrl", qu)
+	assert.Empty(t, res.Messages)
+
+	start := time.Now()
+	res = mustDo(t, s, "ReceiveMessage", "QueueUrl", qu, "WaitTimeSeconds", "5")
+	require.Len(t, res.Messages, 1)
+	assert.Less(t, time.Since(start), time.Second*3)
+}
+
+func TestFIFO(t *testing.T) {
+	s := Start(t)
+
+	_, code := do(t, s, "CreateQueue", "QueueName", "default")
+	require.Empty(t, code)
+	_, code = do(t, s, "CreateQueue", "QueueName", "nofifo", "Attribute.1.Name", "FifoQueue", "Attribute.1.Value", "true")
+	assert.Equal(t, "InvalidParameterValue", code)
+
+	qu := mustDo(t, s, "CreateQueue", "QueueName", "default.fifo",
+		"Attribute.1.Name", "FifoQueue", "Attribute.1.Value", "true",
+		"Attribute.2.Name", "ContentBasedDeduplication", "Attribute.2.Value", "true").QueueURL[0]
+
+	_, code = do(t, s, "SendMessage", "QueueUrl", qu, "MessageBody", "no group")
+	assert.Equal(t, "MissingParameter", code)
+
+	for i := 0; i < 3; i++ {
+		res := mustDo(t, s, "SendMessage", "QueueUrl", qu, "MessageBody", "a"+strconv.Itoa(i), "MessageGroupId", "a")
+		assert.NotEmpty(t, res.SequenceNumber)
+	}
+	mustDo(t, s, "SendMessage", "QueueUrl", qu, "MessageBody", "b0", "MessageGroupId", "b")
+
+	// content based deduplication
+	first := mustDo(t, s, "SendMessage", "QueueUrl", qu, "MessageBody", "a0", "MessageGroupId", "a")
+	msgs, _ := s.Messages("default.fifo")
+	require.Len(t, msgs, 4)
+	assert.Equal(t, msgs[0].ID, first.MessageID)
+
+	// the group is locked while its message is in flight
+	res := mustDo(t, s, "ReceiveMessage", "QueueUrl", qu, "MaxNumberOfMessages", "10")
+	require.Len(t, res.Messages, 4)
+	bodies := make([]string, 0, 4)
+	for _, m := range res.Messages {
+		bodies = append(bodies, m.Body)
+	}
+	assert.Equal(t, "a0,a1,a2,b0", strings.Join(bodies, ","))
+
+	res = mustDo(t, s, "ReceiveMessage", "QueueUrl", qu, "MaxNumberOfMessages", "10")
+	assert.Empty(t, res.Messages)
+
+	mustDo(t, s, "SendMessage", "QueueUrl", qu, "MessageBody", "c0", "MessageGroupId", "c")
+	res = mustDo(t, s, "ReceiveMessage", "QueueUrl", qu, "MaxNumberOfMessages", "10")
+	require.Len(t, res.Messages, 1)
+	assert.Equal(t, "c0", res.Messages[0].Body)
+}
+
+func TestJSONProtocol(t *testing.T) {
+	s := Start(t)
+	qu := mustDo(t, s, "CreateQueue", "QueueName", "default").QueueURL[0]
+
+	post := func(action, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, s.URL(), strings.NewReader(body)) //nolint:noctx
+		require.NoError(t, err)
+		req.Header.Set("X-Amz-Target", "AmazonSQS."+action)
+		req.Header.Set("Content-Type", jsonContentType)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_ = resp.Body.Close()
+		})
+
+		return resp
+	}
+
+	resp := post("SendMessage", `{"QueueUrl":"`+qu+`","MessageBody":"json","MessageAttributes":{"rr_job":{"DataType":"String","StringValue":"x"}}}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, jsonContentType, resp.Header.Get("Content-Type"))
+
+	msgs, _ := s.Messages("default")
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "json", msgs[0].Body)
+	assert.Equal(t, "x", msgs[0].Attributes["rr_job"].StringValue)
+
+	resp = post("GetQueueUrl", `{"QueueName":"missing"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "AWS.SimpleQueueService.NonExistentQueue;Sender", resp.Header.Get("X-Amzn-Query-Error"))
+
+	s.Purge()
+	msgs, _ = s.Messages("default")
+	assert.Empty(t, msgs)
+}
+
+func TestMD5OfAttributes(t *testing.T) {
+	// the type is the part of the digest
+	attrs := map[string]MessageAttribute{
+		"attr": {DataType: "String", StringValue: "value"},
+	}
+
+	assert.Len(t, md5OfAttributes(attrs), 32)
+	assert.Empty(t, md5OfAttributes(nil))
+	assert.NotEqual(t, md5OfAttributes(attrs), md5OfAttributes(map[string]MessageAttribute{
+		"attr": {DataType: "Binary", BinaryValue: []byte("value")},
+	}))
+}
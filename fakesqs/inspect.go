@@ -0,0 +1,105 @@
+package fakesqs
+
+import (
+	"sort"
+	"time"
+)
+
+// Message is the snapshot of the message stored in the queue
+type Message struct {
+	ID              string
+	Body            string
+	Attributes      map[string]MessageAttribute
+	GroupID         string
+	DeduplicationID string
+	ReceiveCount    int
+	SentAt          time.Time
+	// InFlight is true for the received and not yet visible messages, Delayed for the never received invisible ones
+	InFlight bool
+	Delayed  bool
+}
+
+// Queues returns the sorted names of the queues
+func (s *Server) Queues() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.queues))
+	for name := range s.queues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Messages returns the messages of the queue in the send order, false if there is no such queue
+func (s *Server) Messages(queue string) ([]Message, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.queues[queue]
+	if !ok {
+		return nil, false
+	}
+
+	now := time.Now()
+	out := make([]Message, 0, len(q.messages))
+	for i := 0; i < len(q.messages); i++ {
+		m := q.messages[i]
+		attrs := make(map[string]MessageAttribute, len(m.attrs))
+		for k, v := range m.attrs {
+			attrs[k] = v
+		}
+
+		out = append(out, Message{
+			ID:              m.id,
+			Body:            m.body,
+			Attributes:      attrs,
+			GroupID:         m.groupID,
+			DeduplicationID: m.dedupID,
+			ReceiveCount:    m.receiveCount,
+			SentAt:          m.sentAt,
+			InFlight:        m.inFlight(now),
+			Delayed:         !m.inFlight(now) && m.visibleAt.After(now),
+		})
+	}
+
+	return out, true
+}
+
+// Attributes returns the queue attributes including the computed ones (ApproximateNumberOfMessages, etc)
+func (s *Server) Attributes(queue string) (map[string]string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.queues[queue]
+	if !ok {
+		return nil, false
+	}
+
+	return q.attributesFor([]string{"All"}), true
+}
+
+// Tags returns the queue tags
+func (s *Server) Tags(queue string) (map[string]string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.queues[queue]
+	if !ok {
+		return nil, false
+	}
+
+	return copyMap(q.tags), true
+}
+
+// Purge removes all messages from all queues, the queues are kept
+func (s *Server) Purge() {
+	s.mu.Lock()
+	for _, q := range s.queues {
+		q.messages = nil
+		q.dedup = make(map[string]dedupEntry)
+	}
+	s.mu.Unlock()
+}
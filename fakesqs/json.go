@@ -0,0 +1,173 @@
+package fakesqs
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+const (
+	headerTarget string = "X-Amz-Target"
+	targetPrefix string = "AmazonSQS."
+
+	jsonContentType string = "application/x-amz-json-1.0"
+)
+
+type jsonAttributeValue struct {
+	DataType    string `json:"DataType"`
+	StringValue string `json:"StringValue,omitempty"`
+	BinaryValue []byte `json:"BinaryValue,omitempty"`
+}
+
+type jsonRequest struct {
+	QueueName              string                        `json:"QueueName"`
+	QueueURL               string                        `json:"QueueUrl"`
+	QueueNamePrefix        string                        `json:"QueueNamePrefix"`
+	Attributes             map[string]string             `json:"Attributes"`
+	Tags                   map[string]string             `json:"tags"`
+	TagsAlt                map[string]string             `json:"Tags"`
+	TagKeys                []string                      `json:"TagKeys"`
+	MessageBody            string                        `json:"MessageBody"`
+	DelaySeconds           *int                          `json:"DelaySeconds"`
+	MessageAttributes      map[string]jsonAttributeValue `json:"MessageAttributes"`
+	MessageGroupID         string                        `json:"MessageGroupId"`
+	MessageDeduplicationID string                        `json:"MessageDeduplicationId"`
+	MaxNumberOfMessages    int                           `json:"MaxNumberOfMessages"`
+	VisibilityTimeout      *int                          `json:"VisibilityTimeout"`
+	WaitTimeSeconds        *int                          `json:"WaitTimeSeconds"`
+	AttributeNames         []string                      `json:"AttributeNames"`
+	MessageAttributeNames  []string                      `json:"MessageAttributeNames"`
+	ReceiptHandle          string                        `json:"ReceiptHandle"`
+}
+
+type jsonMessage struct {
+	MessageID              string                        `json:"MessageId"`
+	ReceiptHandle          string                        `json:"ReceiptHandle"`
+	MD5OfBody              string                        `json:"MD5OfBody"`
+	Body                   string                        `json:"Body"`
+	Attributes             map[string]string             `json:"Attributes,omitempty"`
+	MD5OfMessageAttributes string                        `json:"MD5OfMessageAttributes,omitempty"`
+	MessageAttributes      map[string]jsonAttributeValue `json:"MessageAttributes,omitempty"`
+}
+
+type jsonResult struct {
+	QueueURL               string            `json:"QueueUrl,omitempty"`
+	QueueURLs              []string          `json:"QueueUrls,omitempty"`
+	Attributes             map[string]string `json:"Attributes,omitempty"`
+	Tags                   map[string]string `json:"Tags,omitempty"`
+	MessageID              string            `json:"MessageId,omitempty"`
+	MD5OfMessageBody       string            `json:"MD5OfMessageBody,omitempty"`
+	MD5OfMessageAttributes string            `json:"MD5OfMessageAttributes,omitempty"`
+	SequenceNumber         string            `json:"SequenceNumber,omitempty"`
+	Messages               []jsonMessage     `json:"Messages,omitempty"`
+}
+
+type jsonError struct {
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+func (s *Server) serveJSON(w http.ResponseWriter, r *http.Request, action string) {
+	in := &jsonRequest{}
+	err := json.NewDecoder(r.Body).Decode(in)
+	if err != nil {
+		writeJSONError(w, errInvalidParameter(err.Error()))
+		return
+	}
+
+	req := &request{
+		QueueName:              in.QueueName,
+		QueueURL:               in.QueueURL,
+		QueueNamePrefix:        in.QueueNamePrefix,
+		Attributes:             in.Attributes,
+		Tags:                   in.Tags,
+		TagKeys:                in.TagKeys,
+		MessageBody:            in.MessageBody,
+		DelaySeconds:           in.DelaySeconds,
+		MessageGroupID:         in.MessageGroupID,
+		MessageDeduplicationID: in.MessageDeduplicationID,
+		MaxNumberOfMessages:    in.MaxNumberOfMessages,
+		VisibilityTimeout:      in.VisibilityTimeout,
+		WaitTimeSeconds:        in.WaitTimeSeconds,
+		AttributeNames:         in.AttributeNames,
+		MessageAttributeNames:  in.MessageAttributeNames,
+		ReceiptHandle:          in.ReceiptHandle,
+		MessageAttributes:      make(map[string]MessageAttribute, len(in.MessageAttributes)),
+	}
+
+	// CreateQueue uses "tags", TagQueue uses "Tags"
+	if req.Tags == nil {
+		req.Tags = in.TagsAlt
+	}
+
+	for name, v := range in.MessageAttributes {
+		req.MessageAttributes[name] = MessageAttribute(v)
+	}
+
+	res, err := s.call(r.Context(), action, req)
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, encodeJSONResult(res))
+}
+
+func writeJSONError(w http.ResponseWriter, err error) {
+	apiErr := &apiError{}
+	if !errors.As(err, &apiErr) {
+		apiErr = &apiError{status: http.StatusInternalServerError, code: "InternalError", jsonType: "com.amazonaws.sqs#InternalError", message: err.Error()}
+	}
+
+	// the SDKs map the JSON errors to the legacy query codes by this header
+	w.Header().Set("X-Amzn-Query-Error", apiErr.code+";Sender")
+	writeJSON(w, apiErr.status, &jsonError{Type: apiErr.jsonType, Message: apiErr.message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", jsonContentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func encodeJSONResult(res *result) *jsonResult {
+	out := &jsonResult{
+		QueueURL:   res.QueueURL,
+		QueueURLs:  res.QueueURLs,
+		Attributes: res.Attributes,
+		Tags:       res.Tags,
+	}
+
+	if res.Sent != nil {
+		out.MessageID = res.Sent.MessageID
+		out.MD5OfMessageBody = res.Sent.MD5OfMessageBody
+		out.MD5OfMessageAttributes = res.Sent.MD5OfMessageAttributes
+		if res.Sent.SequenceNumber > 0 {
+			out.SequenceNumber = strconv.FormatUint(res.Sent.SequenceNumber, 10)
+		}
+	}
+
+	for i := 0; i < len(res.Messages); i++ {
+		m := res.Messages[i]
+		jm := jsonMessage{
+			MessageID:              m.MessageID,
+			ReceiptHandle:          m.ReceiptHandle,
+			MD5OfBody:              m.MD5OfBody,
+			Body:                   m.Body,
+			Attributes:             m.Attributes,
+			MD5OfMessageAttributes: m.MD5OfMessageAttributes,
+		}
+
+		if len(m.MessageAttributes) > 0 {
+			jm.MessageAttributes = make(map[string]jsonAttributeValue, len(m.MessageAttributes))
+			for name, attr := range m.MessageAttributes {
+				jm.MessageAttributes[name] = jsonAttributeValue(attr)
+			}
+		}
+
+		out.Messages = append(out.Messages, jm)
+	}
+
+	return out
+}
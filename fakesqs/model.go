@@ -0,0 +1,120 @@
+package fakesqs
+
+import (
+	"crypto/md5" //nolint:gosec
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	transportString byte = 1
+	transportBinary byte = 2
+)
+
+// request holds the parameters of any action, decoded from either protocol
+type request struct {
+	QueueName       string
+	QueueURL        string
+	QueueNamePrefix string
+
+	Attributes map[string]string
+	Tags       map[string]string
+	TagKeys    []string
+
+	MessageBody            string
+	DelaySeconds           *int
+	MessageAttributes      map[string]MessageAttribute
+	MessageGroupID         string
+	MessageDeduplicationID string
+
+	MaxNumberOfMessages   int
+	VisibilityTimeout     *int
+	WaitTimeSeconds       *int
+	AttributeNames        []string
+	MessageAttributeNames []string
+	ReceiptHandle         string
+}
+
+// result holds the output of any action, encoded by the protocol
+type result struct {
+	QueueURL   string
+	QueueURLs  []string
+	Attributes map[string]string
+	Tags       map[string]string
+	Sent       *sentMessage
+	Messages   []receivedMessage
+}
+
+type sentMessage struct {
+	MessageID              string
+	MD5OfMessageBody       string
+	MD5OfMessageAttributes string
+	// FIFO only
+	SequenceNumber uint64
+}
+
+type receivedMessage struct {
+	MessageID              string
+	ReceiptHandle          string
+	Body                   string
+	MD5OfBody              string
+	Attributes             map[string]string
+	MessageAttributes      map[string]MessageAttribute
+	MD5OfMessageAttributes string
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s)) //nolint:gosec
+	return hex.EncodeToString(sum[:])
+}
+
+// md5OfAttributes calculates the digest the same way SQS does, the SDKs validate it. Empty for no attributes.
+func md5OfAttributes(attrs map[string]MessageAttribute) string {
+	if len(attrs) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := md5.New() //nolint:gosec
+	for i := 0; i < len(names); i++ {
+		attr := attrs[names[i]]
+		writeLengthPrefixed(h, []byte(names[i]))
+		writeLengthPrefixed(h, []byte(attr.DataType))
+
+		if strings.HasPrefix(attr.DataType, "Binary") {
+			_, _ = h.Write([]byte{transportBinary})
+			writeLengthPrefixed(h, attr.BinaryValue)
+			continue
+		}
+
+		_, _ = h.Write([]byte{transportString})
+		writeLengthPrefixed(h, []byte(attr.StringValue))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeLengthPrefixed(h io.Writer, b []byte) {
+	l := make([]byte, 4)
+	binary.BigEndian.PutUint32(l, uint32(len(b)))
+	_, _ = h.Write(l)
+	_, _ = h.Write(b)
+}
+
+func sortedAttributeNames(m map[string]MessageAttribute) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,313 @@
+package fakesqs
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+const queryNamespace string = "http://queue.amazonaws.com/doc/2012-11-05/"
+
+type xmlAttribute struct {
+	Name  string `xml:"Name"`
+	Value string `xml:"Value"`
+}
+
+type xmlTag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+type xmlAttributeValue struct {
+	StringValue string `xml:"StringValue,omitempty"`
+	BinaryValue string `xml:"BinaryValue,omitempty"`
+	DataType    string `xml:"DataType"`
+}
+
+type xmlMessageAttribute struct {
+	Name  string            `xml:"Name"`
+	Value xmlAttributeValue `xml:"Value"`
+}
+
+type xmlMessage struct {
+	MessageID              string                `xml:"MessageId"`
+	ReceiptHandle          string                `xml:"ReceiptHandle"`
+	MD5OfBody              string                `xml:"MD5OfBody"`
+	Body                   string                `xml:"Body"`
+	Attributes             []xmlAttribute        `xml:"Attribute"`
+	MD5OfMessageAttributes string                `xml:"MD5OfMessageAttributes,omitempty"`
+	MessageAttributes      []xmlMessageAttribute `xml:"MessageAttribute"`
+}
+
+// xmlResult is the union of the <ActionResult> contents, the empty elements are omitted
+type xmlResult struct {
+	XMLName                xml.Name
+	QueueURL               []string       `xml:"QueueUrl"`
+	Attributes             []xmlAttribute `xml:"Attribute"`
+	Tags                   []xmlTag       `xml:"Tag"`
+	MessageID              string         `xml:"MessageId,omitempty"`
+	MD5OfMessageBody       string         `xml:"MD5OfMessageBody,omitempty"`
+	MD5OfMessageAttributes string         `xml:"MD5OfMessageAttributes,omitempty"`
+	SequenceNumber         string         `xml:"SequenceNumber,omitempty"`
+	Messages               []xmlMessage   `xml:"Message"`
+}
+
+type xmlResponseMetadata struct {
+	RequestID string `xml:"RequestId"`
+}
+
+type xmlResponse struct {
+	XMLName  xml.Name
+	Xmlns    string              `xml:"xmlns,attr"`
+	Result   *xmlResult          `xml:",omitempty"`
+	Metadata xmlResponseMetadata `xml:"ResponseMetadata"`
+}
+
+type xmlError struct {
+	Type    string `xml:"Type"`
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+	Detail  string `xml:"Detail"`
+}
+
+type xmlErrorResponse struct {
+	XMLName   xml.Name `xml:"ErrorResponse"`
+	Error     xmlError `xml:"Error"`
+	RequestID string   `xml:"RequestId"`
+}
+
+// actions without the <ActionResult> element
+var emptyResults = map[string]bool{ //nolint:gochecknoglobals
+	"DeleteQueue":             true,
+	"PurgeQueue":              true,
+	"SetQueueAttributes":      true,
+	"TagQueue":                true,
+	"UntagQueue":              true,
+	"DeleteMessage":           true,
+	"ChangeMessageVisibility": true,
+}
+
+func (s *Server) serveQuery(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		writeQueryError(w, errInvalidParameter(err.Error()))
+		return
+	}
+
+	action := r.Form.Get("Action")
+	req, err := decodeQuery(r.Form)
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	// the SDKs might send the request to the queue URL instead of the QueueUrl parameter
+	if req.QueueURL == "" && r.URL.Path != "/" && r.URL.Path != "" {
+		req.QueueURL = r.URL.Path
+	}
+
+	res, err := s.call(r.Context(), action, req)
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	resp := &xmlResponse{
+		XMLName:  xml.Name{Local: action + "Response"},
+		Xmlns:    queryNamespace,
+		Metadata: xmlResponseMetadata{RequestID: uuid.NewString()},
+	}
+	if !emptyResults[action] {
+		resp.Result = encodeQueryResult(res)
+		resp.Result.XMLName = xml.Name{Local: action + "Result"}
+	}
+
+	writeXML(w, http.StatusOK, resp)
+}
+
+func writeQueryError(w http.ResponseWriter, err error) {
+	apiErr := &apiError{}
+	if !errors.As(err, &apiErr) {
+		apiErr = &apiError{status: http.StatusInternalServerError, code: "InternalError", message: err.Error()}
+	}
+
+	resp := &xmlErrorResponse{
+		Error: xmlError{
+			Type:    "Sender",
+			Code:    apiErr.code,
+			Message: apiErr.message,
+		},
+		RequestID: uuid.NewString(),
+	}
+
+	writeXML(w, apiErr.status, resp)
+}
+
+func writeXML(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "text/xml")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(xml.Header))
+	_ = xml.NewEncoder(w).Encode(v)
+}
+
+// decodeQuery decodes the form values, the maps and the lists are flattened: Attribute.1.Name=...&Attribute.1.Value=...
+func decodeQuery(form url.Values) (*request, error) {
+	req := &request{
+		QueueName:              form.Get("QueueName"),
+		QueueURL:               form.Get("QueueUrl"),
+		QueueNamePrefix:        form.Get("QueueNamePrefix"),
+		Attributes:             queryMap(form, "Attribute", "Name", "Value"),
+		Tags:                   queryMap(form, "Tag", "Key", "Value"),
+		TagKeys:                queryList(form, "TagKey"),
+		MessageBody:            form.Get("MessageBody"),
+		MessageGroupID:         form.Get("MessageGroupId"),
+		MessageDeduplicationID: form.Get("MessageDeduplicationId"),
+		AttributeNames:         queryList(form, "AttributeName"),
+		MessageAttributeNames:  queryList(form, "MessageAttributeName"),
+		ReceiptHandle:          form.Get("ReceiptHandle"),
+	}
+
+	var err error
+	req.DelaySeconds, err = queryInt(form, "DelaySeconds")
+	if err != nil {
+		return nil, err
+	}
+	req.VisibilityTimeout, err = queryInt(form, "VisibilityTimeout")
+	if err != nil {
+		return nil, err
+	}
+	req.WaitTimeSeconds, err = queryInt(form, "WaitTimeSeconds")
+	if err != nil {
+		return nil, err
+	}
+
+	max, err := queryInt(form, "MaxNumberOfMessages")
+	if err != nil {
+		return nil, err
+	}
+	if max != nil {
+		req.MaxNumberOfMessages = *max
+	}
+
+	req.MessageAttributes = make(map[string]MessageAttribute)
+	for i := 1; ; i++ {
+		prefix := "MessageAttribute." + strconv.Itoa(i) + "."
+		name := form.Get(prefix + "Name")
+		if name == "" {
+			break
+		}
+
+		attr := MessageAttribute{
+			DataType:    form.Get(prefix + "Value.DataType"),
+			StringValue: form.Get(prefix + "Value.StringValue"),
+		}
+
+		if bin := form.Get(prefix + "Value.BinaryValue"); bin != "" {
+			attr.BinaryValue, err = base64.StdEncoding.DecodeString(bin)
+			if err != nil {
+				return nil, errInvalidParameter("invalid binary value of the message attribute " + name)
+			}
+		}
+
+		req.MessageAttributes[name] = attr
+	}
+
+	return req, nil
+}
+
+func queryMap(form url.Values, prefix, key, value string) map[string]string {
+	out := make(map[string]string)
+	for i := 1; ; i++ {
+		p := prefix + "." + strconv.Itoa(i) + "."
+		k := form.Get(p + key)
+		if k == "" {
+			return out
+		}
+		out[k] = form.Get(p + value)
+	}
+}
+
+func queryList(form url.Values, prefix string) []string {
+	var out []string
+	for i := 1; ; i++ {
+		v := form.Get(prefix + "." + strconv.Itoa(i))
+		if v == "" {
+			return out
+		}
+		out = append(out, v)
+	}
+}
+
+func queryInt(form url.Values, name string) (*int, error) {
+	v := form.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, errInvalidParameter("Value " + v + " for parameter " + name + " is invalid.")
+	}
+
+	return &n, nil
+}
+
+func encodeQueryResult(res *result) *xmlResult {
+	out := &xmlResult{
+		QueueURL: res.QueueURLs,
+	}
+
+	if res.QueueURL != "" {
+		out.QueueURL = []string{res.QueueURL}
+	}
+
+	for _, k := range sortedKeys(res.Attributes) {
+		out.Attributes = append(out.Attributes, xmlAttribute{Name: k, Value: res.Attributes[k]})
+	}
+
+	for _, k := range sortedKeys(res.Tags) {
+		out.Tags = append(out.Tags, xmlTag{Key: k, Value: res.Tags[k]})
+	}
+
+	if res.Sent != nil {
+		out.MessageID = res.Sent.MessageID
+		out.MD5OfMessageBody = res.Sent.MD5OfMessageBody
+		out.MD5OfMessageAttributes = res.Sent.MD5OfMessageAttributes
+		if res.Sent.SequenceNumber > 0 {
+			out.SequenceNumber = strconv.FormatUint(res.Sent.SequenceNumber, 10)
+		}
+	}
+
+	for i := 0; i < len(res.Messages); i++ {
+		m := res.Messages[i]
+		xm := xmlMessage{
+			MessageID:              m.MessageID,
+			ReceiptHandle:          m.ReceiptHandle,
+			MD5OfBody:              m.MD5OfBody,
+			Body:                   m.Body,
+			MD5OfMessageAttributes: m.MD5OfMessageAttributes,
+		}
+
+		for _, k := range sortedKeys(m.Attributes) {
+			xm.Attributes = append(xm.Attributes, xmlAttribute{Name: k, Value: m.Attributes[k]})
+		}
+
+		for _, k := range sortedAttributeNames(m.MessageAttributes) {
+			attr := m.MessageAttributes[k]
+			xa := xmlMessageAttribute{Name: k, Value: xmlAttributeValue{DataType: attr.DataType, StringValue: attr.StringValue}}
+			if attr.BinaryValue != nil {
+				xa.Value.BinaryValue = base64.StdEncoding.EncodeToString(attr.BinaryValue)
+			}
+			xm.MessageAttributes = append(xm.MessageAttributes, xa)
+		}
+
+		out.Messages = append(out.Messages, xm)
+	}
+
+	return out
+}
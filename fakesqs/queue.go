@@ -0,0 +1,367 @@
+package fakesqs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	fifoSuffix string = ".fifo"
+
+	// defaults of the real SQS
+	defaultVisibilityTimeout time.Duration = time.Second * 30
+	dedupInterval            time.Duration = time.Minute * 5
+	maxReceiveMessages       int           = 10
+
+	attrDelaySeconds              string = "DelaySeconds"
+	attrVisibilityTimeout         string = "VisibilityTimeout"
+	attrReceiveWaitTimeSeconds    string = "ReceiveMessageWaitTimeSeconds"
+	attrFifoQueue                 string = "FifoQueue"
+	attrContentBasedDeduplication string = "ContentBasedDeduplication"
+	attrApproximateNumber         string = "ApproximateNumberOfMessages"
+	attrApproximateNotVisible     string = "ApproximateNumberOfMessagesNotVisible"
+	attrApproximateDelayed        string = "ApproximateNumberOfMessagesDelayed"
+	attrCreatedTimestamp          string = "CreatedTimestamp"
+	attrQueueArn                  string = "QueueArn"
+
+	attrApproximateReceiveCount string = "ApproximateReceiveCount"
+	attrSentTimestamp           string = "SentTimestamp"
+	attrFirstReceiveTimestamp   string = "ApproximateFirstReceiveTimestamp"
+	attrSenderID                string = "SenderId"
+	attrMessageGroupID          string = "MessageGroupId"
+	attrMessageDeduplicationID  string = "MessageDeduplicationId"
+	attrSequenceNumber          string = "SequenceNumber"
+
+	// account id used in the queue URLs and ARNs
+	accountID string = "000000000000"
+	region    string = "us-east-1"
+)
+
+// MessageAttribute is the user defined attribute of the message
+type MessageAttribute struct {
+	DataType    string
+	StringValue string
+	BinaryValue []byte
+}
+
+type message struct {
+	id      string
+	body    string
+	attrs   map[string]MessageAttribute
+	groupID string
+	dedupID string
+	seq     uint64
+
+	sentAt       time.Time
+	firstReceive time.Time
+	visibleAt    time.Time
+	receiveCount int
+	// receipt handle of the last receive, empty if the message was never received
+	receipt string
+}
+
+type queue struct {
+	name      string
+	url       string
+	fifo      bool
+	createdAt time.Time
+
+	attributes map[string]string
+	tags       map[string]string
+
+	// messages in the send order
+	messages []*message
+	// deduplication id -> message id and the time of the send, FIFO only
+	dedup map[string]dedupEntry
+	seq   uint64
+}
+
+type dedupEntry struct {
+	messageID string
+	sentAt    time.Time
+}
+
+func newQueue(name, url string, attributes, tags map[string]string) *queue {
+	q := &queue{
+		name:       name,
+		url:        url,
+		fifo:       strings.HasSuffix(name, fifoSuffix),
+		createdAt:  time.Now(),
+		attributes: make(map[string]string, len(attributes)),
+		tags:       make(map[string]string, len(tags)),
+		dedup:      make(map[string]dedupEntry),
+	}
+
+	for k, v := range attributes {
+		q.attributes[k] = v
+	}
+	for k, v := range tags {
+		q.tags[k] = v
+	}
+
+	return q
+}
+
+func (q *queue) duration(attr string, def time.Duration) time.Duration {
+	v, ok := q.attributes[attr]
+	if !ok {
+		return def
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return def
+	}
+
+	return time.Duration(n) * time.Second
+}
+
+func (q *queue) send(body string, attrs map[string]MessageAttribute, delay *time.Duration, groupID, dedupID string) (*message, error) {
+	if q.fifo {
+		if groupID == "" {
+			return nil, errMissingParameter("The request must contain the parameter MessageGroupId.")
+		}
+
+		if dedupID == "" {
+			if q.attributes[attrContentBasedDeduplication] != "true" {
+				return nil, errInvalidParameter("The queue should either have ContentBasedDeduplication enabled or MessageDeduplicationId provided explicitly")
+			}
+			sum := sha256.Sum256([]byte(body))
+			dedupID = hex.EncodeToString(sum[:])
+		}
+
+		if prev, ok := q.dedup[dedupID]; ok && time.Since(prev.sentAt) < dedupInterval {
+			// accepted, but not delivered the second time
+			for i := 0; i < len(q.messages); i++ {
+				if q.messages[i].id == prev.messageID {
+					return q.messages[i], nil
+				}
+			}
+			return &message{id: prev.messageID, body: body, attrs: attrs, groupID: groupID, dedupID: dedupID}, nil
+		}
+	} else if groupID != "" {
+		return nil, errInvalidParameter("The request include parameter that is not valid for this queue type")
+	}
+
+	now := time.Now()
+	d := q.duration(attrDelaySeconds, 0)
+	if delay != nil {
+		if q.fifo {
+			return nil, errInvalidParameter("Value for parameter DelaySeconds is invalid. Reason: The request include parameter that is not valid for this queue type.")
+		}
+		d = *delay
+	}
+
+	q.seq++
+	m := &message{
+		id:        uuid.NewString(),
+		body:      body,
+		attrs:     attrs,
+		groupID:   groupID,
+		dedupID:   dedupID,
+		seq:       q.seq,
+		sentAt:    now,
+		visibleAt: now.Add(d),
+	}
+
+	q.messages = append(q.messages, m)
+	if q.fifo {
+		q.dedup[dedupID] = dedupEntry{messageID: m.id, sentAt: now}
+	}
+
+	return m, nil
+}
+
+// receive marks up to max visible messages as in flight
+func (q *queue) receive(max int, visibility *time.Duration) []*message {
+	now := time.Now()
+	vt := q.duration(attrVisibilityTimeout, defaultVisibilityTimeout)
+	if visibility != nil {
+		vt = *visibility
+	}
+
+	// FIFO: the group is locked while any of its messages is in flight
+	locked := make(map[string]bool)
+	if q.fifo {
+		for i := 0; i < len(q.messages); i++ {
+			if q.messages[i].inFlight(now) {
+				locked[q.messages[i].groupID] = true
+			}
+		}
+	}
+
+	out := make([]*message, 0, max)
+	for i := 0; i < len(q.messages) && len(out) < max; i++ {
+		m := q.messages[i]
+		if m.visibleAt.After(now) {
+			if q.fifo {
+				// the order within the group is strict, the delayed or in flight message blocks the rest
+				locked[m.groupID] = true
+			}
+			continue
+		}
+
+		if q.fifo && locked[m.groupID] {
+			continue
+		}
+
+		m.receiveCount++
+		if m.firstReceive.IsZero() {
+			m.firstReceive = now
+		}
+		m.visibleAt = now.Add(vt)
+		m.receipt = uuid.NewString()
+		out = append(out, m)
+	}
+
+	return out
+}
+
+func (q *queue) byReceipt(receipt string) (int, *message) {
+	for i := 0; i < len(q.messages); i++ {
+		if q.messages[i].receipt == receipt {
+			return i, q.messages[i]
+		}
+	}
+
+	return -1, nil
+}
+
+func (q *queue) delete(receipt string) error {
+	i, m := q.byReceipt(receipt)
+	if m == nil {
+		return errReceiptHandle(receipt)
+	}
+
+	q.messages = append(q.messages[:i], q.messages[i+1:]...)
+	return nil
+}
+
+func (q *queue) changeVisibility(receipt string, timeout time.Duration) error {
+	_, m := q.byReceipt(receipt)
+	if m == nil {
+		return errReceiptHandle(receipt)
+	}
+
+	if !m.inFlight(time.Now()) {
+		return errMessageNotInflight()
+	}
+
+	m.visibleAt = time.Now().Add(timeout)
+	return nil
+}
+
+// counters returns the number of visible, in flight and delayed messages
+func (q *queue) counters() (int, int, int) {
+	now := time.Now()
+	visible, inFlight, delayed := 0, 0, 0
+	for i := 0; i < len(q.messages); i++ {
+		switch {
+		case q.messages[i].inFlight(now):
+			inFlight++
+		case q.messages[i].visibleAt.After(now):
+			delayed++
+		default:
+			visible++
+		}
+	}
+
+	return visible, inFlight, delayed
+}
+
+// attributesFor returns the stored and computed attributes filtered by the names ("All" for everything)
+func (q *queue) attributesFor(names []string) map[string]string {
+	visible, inFlight, delayed := q.counters()
+
+	all := make(map[string]string, len(q.attributes)+5)
+	for k, v := range q.attributes {
+		all[k] = v
+	}
+
+	all[attrApproximateNumber] = strconv.Itoa(visible)
+	all[attrApproximateNotVisible] = strconv.Itoa(inFlight)
+	all[attrApproximateDelayed] = strconv.Itoa(delayed)
+	all[attrCreatedTimestamp] = strconv.FormatInt(q.createdAt.Unix(), 10)
+	all[attrQueueArn] = "arn:aws:sqs:" + region + ":" + accountID + ":" + q.name
+	if _, ok := all[attrVisibilityTimeout]; !ok {
+		all[attrVisibilityTimeout] = strconv.Itoa(int(defaultVisibilityTimeout / time.Second))
+	}
+	if q.fifo {
+		all[attrFifoQueue] = "true"
+	}
+
+	return filter(all, names)
+}
+
+// inFlight reports whether the message was received and is not visible yet
+func (m *message) inFlight(now time.Time) bool {
+	return m.receipt != "" && m.visibleAt.After(now)
+}
+
+// systemAttributes returns the message system attributes filtered by the names
+func (m *message) systemAttributes(names []string) map[string]string {
+	all := map[string]string{
+		attrApproximateReceiveCount: strconv.Itoa(m.receiveCount),
+		attrSentTimestamp:           strconv.FormatInt(m.sentAt.UnixNano()/int64(time.Millisecond), 10),
+		attrSenderID:                accountID,
+	}
+
+	if !m.firstReceive.IsZero() {
+		all[attrFirstReceiveTimestamp] = strconv.FormatInt(m.firstReceive.UnixNano()/int64(time.Millisecond), 10)
+	}
+
+	if m.groupID != "" {
+		all[attrMessageGroupID] = m.groupID
+		all[attrMessageDeduplicationID] = m.dedupID
+		all[attrSequenceNumber] = strconv.FormatUint(m.seq, 10)
+	}
+
+	return filter(all, names)
+}
+
+// messageAttributes returns the user attributes filtered by the names, "All", ".*" and "prefix.*" are supported
+func (m *message) messageAttributes(names []string) map[string]MessageAttribute {
+	out := make(map[string]MessageAttribute)
+	for name, attr := range m.attrs {
+		for i := 0; i < len(names); i++ {
+			if names[i] == "All" || names[i] == ".*" || names[i] == name ||
+				(strings.HasSuffix(names[i], ".*") && strings.HasPrefix(name, strings.TrimSuffix(names[i], "*"))) {
+				out[name] = attr
+				break
+			}
+		}
+	}
+
+	return out
+}
+
+func filter(all map[string]string, names []string) map[string]string {
+	out := make(map[string]string)
+	for i := 0; i < len(names); i++ {
+		if names[i] == "All" {
+			return all
+		}
+
+		if v, ok := all[names[i]]; ok {
+			out[names[i]] = v
+		}
+	}
+
+	return out
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,347 @@
+// Package fakesqs is the in-process stand-in for Amazon SQS. It implements the subset of the query (XML) and the JSON
+// protocols used by the RR sqs driver: queues with attributes and tags, send, receive with the visibility timeout and
+// long polling, delete, change visibility, FIFO message groups and deduplication. Queue contents can be inspected
+// directly via the Server methods.
+package fakesqs
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	// long polling resolution
+	pollInterval time.Duration = time.Millisecond * 20
+	maxWaitTime  time.Duration = time.Second * 20
+)
+
+// Server is the SQS stand-in
+type Server struct {
+	ls  net.Listener
+	srv *http.Server
+
+	mu     sync.Mutex
+	queues map[string]*queue
+}
+
+// Listen starts the server on the addr (host:port), use port 0 for the random one
+func Listen(addr string) (*Server, error) {
+	ls, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		ls:     ls,
+		queues: make(map[string]*queue),
+	}
+
+	s.srv = &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: time.Minute,
+	}
+
+	go func() {
+		_ = s.srv.Serve(ls)
+	}()
+
+	return s, nil
+}
+
+// Start starts the server on the random port, the server is closed when the test finishes
+func Start(t testing.TB) *Server {
+	t.Helper()
+
+	s, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start the sqs stand-in: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = s.Close()
+	})
+
+	return s
+}
+
+// Addr returns host:port the server listens on
+func (s *Server) Addr() string {
+	return s.ls.Addr().String()
+}
+
+// URL returns the endpoint for the SQS clients, http://host:port
+func (s *Server) URL() string {
+	return "http://" + s.Addr()
+}
+
+// QueueURL returns the URL of the queue with the name (the queue might not exist)
+func (s *Server) QueueURL(name string) string {
+	return s.URL() + "/" + accountID + "/" + name
+}
+
+// Close stops the server
+func (s *Server) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	err := s.srv.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		// long polling requests
+		return s.srv.Close()
+	}
+
+	return err
+}
+
+// ServeHTTP dispatches the request by the protocol: JSON if X-Amz-Target is set, query otherwise
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if target := r.Header.Get(headerTarget); target != "" {
+		s.serveJSON(w, r, strings.TrimPrefix(target, targetPrefix))
+		return
+	}
+
+	s.serveQuery(w, r)
+}
+
+// call executes the action, the same for both protocols
+func (s *Server) call(ctx context.Context, action string, req *request) (*result, error) {
+	switch action {
+	case "CreateQueue":
+		return s.createQueue(req)
+	case "GetQueueUrl":
+		return s.withQueue(req, func(q *queue) (*result, error) {
+			return &result{QueueURL: q.url}, nil
+		})
+	case "DeleteQueue":
+		return s.withQueue(req, func(q *queue) (*result, error) {
+			delete(s.queues, q.name)
+			return &result{}, nil
+		})
+	case "ListQueues":
+		return s.listQueues(req), nil
+	case "PurgeQueue":
+		return s.withQueue(req, func(q *queue) (*result, error) {
+			q.messages = nil
+			return &result{}, nil
+		})
+	case "GetQueueAttributes":
+		return s.withQueue(req, func(q *queue) (*result, error) {
+			names := req.AttributeNames
+			if len(names) == 0 {
+				names = []string{"All"}
+			}
+			return &result{Attributes: q.attributesFor(names)}, nil
+		})
+	case "SetQueueAttributes":
+		return s.withQueue(req, func(q *queue) (*result, error) {
+			for k, v := range req.Attributes {
+				q.attributes[k] = v
+			}
+			return &result{}, nil
+		})
+	case "TagQueue":
+		return s.withQueue(req, func(q *queue) (*result, error) {
+			for k, v := range req.Tags {
+				q.tags[k] = v
+			}
+			return &result{}, nil
+		})
+	case "UntagQueue":
+		return s.withQueue(req, func(q *queue) (*result, error) {
+			for i := 0; i < len(req.TagKeys); i++ {
+				delete(q.tags, req.TagKeys[i])
+			}
+			return &result{}, nil
+		})
+	case "ListQueueTags":
+		return s.withQueue(req, func(q *queue) (*result, error) {
+			return &result{Tags: copyMap(q.tags)}, nil
+		})
+	case "SendMessage":
+		return s.withQueue(req, func(q *queue) (*result, error) {
+			return s.sendMessage(q, req)
+		})
+	case "ReceiveMessage":
+		return s.receiveMessage(ctx, req)
+	case "DeleteMessage":
+		return s.withQueue(req, func(q *queue) (*result, error) {
+			return &result{}, q.delete(req.ReceiptHandle)
+		})
+	case "ChangeMessageVisibility":
+		return s.withQueue(req, func(q *queue) (*result, error) {
+			if req.VisibilityTimeout == nil {
+				return nil, errMissingParameter("The request must contain the parameter VisibilityTimeout.")
+			}
+			return &result{}, q.changeVisibility(req.ReceiptHandle, seconds(*req.VisibilityTimeout))
+		})
+	default:
+		return nil, errInvalidAction(action)
+	}
+}
+
+func (s *Server) createQueue(req *request) (*result, error) {
+	if req.QueueName == "" {
+		return nil, errMissingParameter("The request must contain the parameter QueueName.")
+	}
+
+	fifo := strings.HasSuffix(req.QueueName, fifoSuffix)
+	if fifo != (req.Attributes[attrFifoQueue] == "true") && (fifo || req.Attributes[attrFifoQueue] != "") {
+		return nil, errInvalidParameter("The name of a FIFO queue can only include alphanumeric characters, hyphens, or underscores, must end with .fifo suffix")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if q, ok := s.queues[req.QueueName]; ok {
+		for k, v := range req.Attributes {
+			if cur, exists := q.attributes[k]; exists && cur != v {
+				return nil, errQueueAlreadyExists(k)
+			}
+		}
+		return &result{QueueURL: q.url}, nil
+	}
+
+	q := newQueue(req.QueueName, s.QueueURL(req.QueueName), req.Attributes, req.Tags)
+	s.queues[q.name] = q
+
+	return &result{QueueURL: q.url}, nil
+}
+
+func (s *Server) listQueues(req *request) *result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := &result{}
+	for name, q := range s.queues {
+		if strings.HasPrefix(name, req.QueueNamePrefix) {
+			res.QueueURLs = append(res.QueueURLs, q.url)
+		}
+	}
+	sort.Strings(res.QueueURLs)
+
+	return res
+}
+
+func (s *Server) sendMessage(q *queue, req *request) (*result, error) {
+	if req.MessageBody == "" {
+		return nil, errMissingParameter("The request must contain the parameter MessageBody.")
+	}
+
+	var delay *time.Duration
+	if req.DelaySeconds != nil {
+		d := seconds(*req.DelaySeconds)
+		delay = &d
+	}
+
+	m, err := q.send(req.MessageBody, req.MessageAttributes, delay, req.MessageGroupID, req.MessageDeduplicationID)
+	if err != nil {
+		return nil, err
+	}
+
+	sent := &sentMessage{
+		MessageID:              m.id,
+		MD5OfMessageBody:       md5Hex(req.MessageBody),
+		MD5OfMessageAttributes: md5OfAttributes(req.MessageAttributes),
+	}
+	if q.fifo {
+		sent.SequenceNumber = m.seq
+	}
+
+	return &result{Sent: sent}, nil
+}
+
+func (s *Server) receiveMessage(ctx context.Context, req *request) (*result, error) {
+	max := 1
+	if req.MaxNumberOfMessages > 0 {
+		max = req.MaxNumberOfMessages
+	}
+	if max > maxReceiveMessages {
+		return nil, errInvalidParameter("Value for parameter MaxNumberOfMessages is invalid. Reason: Must be between 1 and 10.")
+	}
+
+	var visibility *time.Duration
+	if req.VisibilityTimeout != nil {
+		v := seconds(*req.VisibilityTimeout)
+		visibility = &v
+	}
+
+	var deadline time.Time
+	for {
+		res, err := s.withQueue(req, func(q *queue) (*result, error) {
+			if deadline.IsZero() {
+				wait := q.duration(attrReceiveWaitTimeSeconds, 0)
+				if req.WaitTimeSeconds != nil {
+					wait = seconds(*req.WaitTimeSeconds)
+				}
+				if wait > maxWaitTime {
+					return nil, errInvalidParameter("Value for parameter WaitTimeSeconds is invalid. Reason: Must be >= 0 and <= 20.")
+				}
+				deadline = time.Now().Add(wait)
+			}
+
+			msgs := q.receive(max, visibility)
+			res := &result{Messages: make([]receivedMessage, 0, len(msgs))}
+			for i := 0; i < len(msgs); i++ {
+				attrs := msgs[i].messageAttributes(req.MessageAttributeNames)
+				res.Messages = append(res.Messages, receivedMessage{
+					MessageID:              msgs[i].id,
+					ReceiptHandle:          msgs[i].receipt,
+					Body:                   msgs[i].body,
+					MD5OfBody:              md5Hex(msgs[i].body),
+					Attributes:             msgs[i].systemAttributes(req.AttributeNames),
+					MessageAttributes:      attrs,
+					MD5OfMessageAttributes: md5OfAttributes(attrs),
+				})
+			}
+
+			return res, nil
+		})
+		if err != nil || len(res.Messages) > 0 || !time.Now().Before(deadline) {
+			return res, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return res, nil
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// withQueue looks up the queue by QueueUrl (or QueueName for GetQueueUrl) and calls fn under the lock
+func (s *Server) withQueue(req *request, fn func(q *queue) (*result, error)) (*result, error) {
+	name := req.QueueName
+	if req.QueueURL != "" {
+		name = req.QueueURL[strings.LastIndex(req.QueueURL, "/")+1:]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.queues[name]
+	if !ok {
+		return nil, errNonExistentQueue()
+	}
+
+	return fn(q)
+}
+
+func seconds(n int) time.Duration {
+	return time.Duration(n) * time.Second
+}
+
+func copyMap(m map[string]string) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+
+	return out
+}
@@ -0,0 +1,31 @@
+// Command fakeworker is the standalone Go worker with the builtin handlers:
+//
+//	server:
+//	  command: "fakeworker echo"
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/roadrunner-server/rr-e2e-tests/fakeworker"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: fakeworker <echo|error|pid|http|jobs>")
+		os.Exit(2)
+	}
+
+	h, ok := fakeworker.Lookup(os.Args[1])
+	if !ok {
+		_, _ = fmt.Fprintf(os.Stderr, "fakeworker: unknown handler %s\n", os.Args[1])
+		os.Exit(2)
+	}
+
+	err := fakeworker.Run(h)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "fakeworker: %v\n", err)
+		os.Exit(1)
+	}
+}
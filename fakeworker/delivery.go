@@ -0,0 +1,64 @@
+package fakeworker
+
+import (
+	"encoding/json"
+	"net"
+	"sync"
+	"time"
+)
+
+// Delivery is sent by the jobs worker to the address in the fakeworker_report header for every acked job, as the
+// JSON line over TCP, so the pusher can verify that every job was consumed exactly once
+type Delivery struct {
+	ID       string `json:"id"`
+	Pipeline string `json:"pipeline"`
+	Driver   string `json:"driver"`
+}
+
+// reporter is the connection to the delivery listener, kept open between the jobs. The mutex serializes the dial and
+// the writes to one address only, the other addresses aren't blocked by the slow listener.
+type reporter struct {
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+var (
+	reportersMu sync.Mutex
+	reporters   = make(map[string]*reporter)
+)
+
+// reportDelivery sends the delivery of the job to the addr, the connection is redialed after the error
+func reportDelivery(addr string, job *JobRequest) error {
+	data, err := json.Marshal(&Delivery{ID: job.ID, Pipeline: job.Pipeline, Driver: job.Driver})
+	if err != nil {
+		return err
+	}
+
+	reportersMu.Lock()
+	r, ok := reporters[addr]
+	if !ok {
+		r = &reporter{}
+		reporters[addr] = r
+	}
+	reportersMu.Unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn == nil {
+		c, err := net.DialTimeout("tcp", addr, time.Second*5)
+		if err != nil {
+			return err
+		}
+		r.conn = c
+	}
+
+	_, err = r.conn.Write(append(data, '\n'))
+	if err != nil {
+		_ = r.conn.Close()
+		r.conn = nil
+		return err
+	}
+
+	return nil
+}
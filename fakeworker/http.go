@@ -0,0 +1,323 @@
+package fakeworker
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// BehaviourHeader selects the HTTP behaviour per request
+	BehaviourHeader string = "X-Fakeworker-Behaviour"
+	// envBehaviour selects the default HTTP behaviour of the worker
+	envBehaviour string = "FAKEWORKER_HTTP_BEHAVIOUR"
+
+	defaultBehaviour string = "echo"
+	bigResponseSize  int    = 1024 * 1024
+)
+
+// HTTPRequest is the request context sent by the http plugin
+type HTTPRequest struct {
+	RemoteAddr string                 `json:"remoteAddr"`
+	Protocol   string                 `json:"protocol"`
+	Method     string                 `json:"method"`
+	URI        string                 `json:"uri"`
+	Headers    http.Header            `json:"headers"`
+	Cookies    map[string]string      `json:"cookies"`
+	RawQuery   string                 `json:"rawQuery"`
+	Parsed     bool                   `json:"parsed"`
+	Uploads    map[string]interface{} `json:"uploads"`
+	Attributes map[string]interface{} `json:"attributes"`
+
+	// Body is the raw request body or, when Parsed is true, the JSON of the parsed form
+	Body []byte `json:"-"`
+}
+
+// Query parses RawQuery
+func (r *HTTPRequest) Query() url.Values {
+	q, _ := url.ParseQuery(r.RawQuery)
+	return q
+}
+
+// HTTPResponse is the response for the http plugin
+type HTTPResponse struct {
+	Status  int                 `json:"status"`
+	Headers map[string][]string `json:"headers"`
+
+	Body []byte `json:"-"`
+}
+
+// DecodeHTTPRequest decodes the payload sent by the http plugin
+func DecodeHTTPRequest(p *Payload) (*HTTPRequest, error) {
+	req := &HTTPRequest{}
+	err := json.Unmarshal(p.Context, req)
+	if err != nil {
+		return nil, fmt.Errorf("bad http request context %q: %w", p.Context, err)
+	}
+
+	req.Body = p.Body
+	return req, nil
+}
+
+// HTTP adapts the func working with the decoded requests to the Handler interface
+func HTTP(fn func(req *HTTPRequest) (*HTTPResponse, error)) Handler {
+	return HandlerFunc(func(p *Payload) (*Payload, error) {
+		req, err := DecodeHTTPRequest(p)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := fn(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.Status == 0 {
+			resp.Status = http.StatusOK
+		}
+
+		if resp.Headers == nil {
+			resp.Headers = make(map[string][]string)
+		}
+
+		ctx, err := json.Marshal(resp)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Payload{Context: ctx, Body: resp.Body}, nil
+	})
+}
+
+// HTTPBehaviours is the HTTP worker equivalent to php_test_files/http/*.php. The behaviour is selected by the
+// X-Fakeworker-Behaviour request header, then by the FAKEWORKER_HTTP_BEHAVIOUR env variable, echo by default.
+// Registered as "http".
+func HTTPBehaviours() Handler {
+	return HTTP(func(req *HTTPRequest) (*HTTPResponse, error) {
+		name := req.Headers.Get(BehaviourHeader)
+		if name == "" {
+			name = os.Getenv(envBehaviour)
+		}
+		if name == "" {
+			name = defaultBehaviour
+		}
+
+		b, ok := behaviours[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown http behaviour: %s", name)
+		}
+
+		return b(req)
+	})
+}
+
+var behaviours = map[string]func(req *HTTPRequest) (*HTTPResponse, error){ //nolint:gochecknoglobals
+	// echo.php
+	"echo": echo,
+	// echoDelay.php
+	"delay": func(req *HTTPRequest) (*HTTPResponse, error) {
+		time.Sleep(time.Second)
+		return echo(req)
+	},
+	// stuck.php
+	"stuck": func(req *HTTPRequest) (*HTTPResponse, error) {
+		time.Sleep(time.Second * 10)
+		return echo(req)
+	},
+	// sleeps for the ?sleep= duration (1s by default)
+	"sleep": func(req *HTTPRequest) (*HTTPResponse, error) {
+		d, err := time.ParseDuration(req.Query().Get("sleep"))
+		if err != nil {
+			d = time.Second
+		}
+
+		time.Sleep(d)
+		return echo(req)
+	},
+	// header.php, server.php
+	"header": func(req *HTTPRequest) (*HTTPResponse, error) {
+		return &HTTPResponse{
+			Headers: map[string][]string{"Header": {req.Query().Get("hello")}},
+			Body:    []byte(strings.ToUpper(req.Headers.Get("input"))),
+		}, nil
+	},
+	// headers.php
+	"headers": func(req *HTTPRequest) (*HTTPResponse, error) {
+		return jsonResponse(req.Headers)
+	},
+	// cookie.php
+	"cookie": func(req *HTTPRequest) (*HTTPResponse, error) {
+		return &HTTPResponse{
+			Headers: map[string][]string{"Set-Cookie": {"output=cookie-output"}},
+			Body:    []byte(strings.ToUpper(req.Cookies["input"])),
+		}, nil
+	},
+	// data.php, the keys are sorted by the encoder
+	"data": func(req *HTTPRequest) (*HTTPResponse, error) {
+		var data interface{}
+		err := json.Unmarshal(req.Body, &data)
+		if err != nil {
+			return nil, err
+		}
+
+		return jsonResponse(data)
+	},
+	// payload.php
+	"payload": func(req *HTTPRequest) (*HTTPResponse, error) {
+		if req.Headers.Get("Content-Type") != "application/json" {
+			return &HTTPResponse{Body: []byte("invalid content-type")}, nil
+		}
+
+		p := make(map[string]interface{})
+		err := json.Unmarshal(req.Body, &p)
+		if err != nil {
+			return nil, err
+		}
+
+		flipped := make(map[string]string, len(p))
+		for k, v := range p {
+			flipped[fmt.Sprint(v)] = k
+		}
+
+		return jsonResponse(flipped)
+	},
+	// upload.php
+	"upload": func(req *HTTPRequest) (*HTTPResponse, error) {
+		tree, err := dumpUploads(req.Uploads)
+		if err != nil {
+			return nil, err
+		}
+
+		return jsonResponse(tree)
+	},
+	// pid.php
+	"pid": func(_ *HTTPRequest) (*HTTPResponse, error) {
+		return &HTTPResponse{Body: []byte(strconv.Itoa(os.Getpid()))}, nil
+	},
+	// ip.php
+	"ip": func(req *HTTPRequest) (*HTTPResponse, error) {
+		return &HTTPResponse{Body: []byte(req.RemoteAddr)}, nil
+	},
+	// user-agent.php
+	"user-agent": func(req *HTTPRequest) (*HTTPResponse, error) {
+		return &HTTPResponse{Body: []byte(req.Headers.Get("User-Agent"))}, nil
+	},
+	// request-uri.php
+	"request-uri": func(req *HTTPRequest) (*HTTPResponse, error) {
+		return &HTTPResponse{Body: []byte(req.URI)}, nil
+	},
+	// env.php
+	"env": func(_ *HTTPRequest) (*HTTPResponse, error) {
+		return &HTTPResponse{Body: []byte(os.Getenv("ENV_KEY"))}, nil
+	},
+	// error.php
+	"error": func(_ *HTTPRequest) (*HTTPResponse, error) {
+		return nil, errors.New("error")
+	},
+	// error2.php, the worker exits in the middle of the request
+	"exit": func(_ *HTTPRequest) (*HTTPResponse, error) {
+		os.Exit(0)
+		return nil, nil
+	},
+	// the response of the ?size= bytes (1mb by default)
+	"big": func(req *HTTPRequest) (*HTTPResponse, error) {
+		size, err := strconv.Atoi(req.Query().Get("size"))
+		if err != nil {
+			size = bigResponseSize
+		}
+
+		return &HTTPResponse{Body: []byte(strings.Repeat("a", size))}, nil
+	},
+	// responds with the request context as is, to assert on what RR sends
+	"context": func(req *HTTPRequest) (*HTTPResponse, error) {
+		return jsonResponse(req)
+	},
+}
+
+func echo(req *HTTPRequest) (*HTTPResponse, error) {
+	return &HTTPResponse{
+		Status: http.StatusCreated,
+		Body:   []byte(strings.ToUpper(req.Query().Get("hello"))),
+	}, nil
+}
+
+func jsonResponse(v interface{}) (*HTTPResponse, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HTTPResponse{Body: data}, nil
+}
+
+// dumpUploads replaces the uploaded files in the tree with their description, the same as upload.php does
+func dumpUploads(node interface{}) (interface{}, error) {
+	switch n := node.(type) {
+	case []interface{}:
+		out := make([]interface{}, len(n))
+		for i := 0; i < len(n); i++ {
+			v, err := dumpUploads(n[i])
+			if err != nil {
+				return nil, err
+			}
+			out[i] = v
+		}
+		return out, nil
+	case map[string]interface{}:
+		// leaf, FileUpload
+		if tmp, ok := n["tmpName"]; ok {
+			return dumpFile(n, fmt.Sprint(tmp))
+		}
+
+		out := make(map[string]interface{}, len(n))
+		for k := range n {
+			v, err := dumpUploads(n[k])
+			if err != nil {
+				return nil, err
+			}
+			out[k] = v
+		}
+		return out, nil
+	default:
+		return n, nil
+	}
+}
+
+func dumpFile(f map[string]interface{}, tmpName string) (interface{}, error) {
+	out := map[string]interface{}{
+		"name":  f["name"],
+		"size":  f["size"],
+		"mime":  f["mime"],
+		"error": f["error"],
+	}
+
+	if e, ok := f["error"].(float64); ok && e != 0 {
+		return out, nil
+	}
+
+	file, err := os.Open(tmpName)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	h := sha512.New()
+	_, err = io.Copy(h, file)
+	if err != nil {
+		return nil, err
+	}
+
+	out["sha512"] = hex.EncodeToString(h.Sum(nil))
+	return out, nil
+}
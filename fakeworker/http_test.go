@@ -0,0 +1,121 @@
+package fakeworker
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/roadrunner-server/api/v2/payload"
+	"github.com/roadrunner-server/sdk/v2/ipc/pipe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func httpPayload(t *testing.T, req *HTTPRequest) *Payload {
+	ctx, err := json.Marshal(req)
+	require.NoError(t, err)
+
+	return &Payload{Context: ctx, Body: req.Body}
+}
+
+func httpResponse(t *testing.T, p *Payload) *HTTPResponse {
+	resp := &HTTPResponse{}
+	require.NoError(t, json.Unmarshal(p.Context, resp))
+	resp.Body = p.Body
+
+	return resp
+}
+
+func TestHTTPBehaviours(t *testing.T) {
+	h := HTTPBehaviours()
+
+	res, err := h.Handle(httpPayload(t, &HTTPRequest{RawQuery: "hello=world"}))
+	require.NoError(t, err)
+	resp := httpResponse(t, res)
+	assert.Equal(t, http.StatusCreated, resp.Status)
+	assert.Equal(t, "WORLD", string(resp.Body))
+
+	res, err = h.Handle(httpPayload(t, &HTTPRequest{
+		RawQuery: "hello=value",
+		Headers:  http.Header{BehaviourHeader: {"header"}, "Input": {"sample"}},
+	}))
+	require.NoError(t, err)
+	resp = httpResponse(t, res)
+	assert.Equal(t, http.StatusOK, resp.Status)
+	assert.Equal(t, []string{"value"}, resp.Headers["Header"])
+	assert.Equal(t, "SAMPLE", string(resp.Body))
+
+	_, err = h.Handle(httpPayload(t, &HTTPRequest{Headers: http.Header{BehaviourHeader: {"error"}}}))
+	assert.EqualError(t, err, "error")
+
+	_, err = h.Handle(httpPayload(t, &HTTPRequest{Headers: http.Header{BehaviourHeader: {"foo"}}}))
+	assert.Error(t, err)
+
+	_, err = h.Handle(&Payload{Context: []byte("not a json")})
+	assert.Error(t, err)
+}
+
+func TestHTTPUpload(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "upload")
+	require.NoError(t, os.WriteFile(tmp, []byte("content"), 0600))
+
+	res, err := HTTPBehaviours().Handle(httpPayload(t, &HTTPRequest{
+		Headers: http.Header{BehaviourHeader: {"upload"}},
+		Uploads: map[string]interface{}{
+			"upload": map[string]interface{}{"name": "a.txt", "mime": "text/plain", "size": 7, "error": 0, "tmpName": tmp},
+			"arr": []interface{}{
+				map[string]interface{}{"name": "b.txt", "mime": "", "size": 0, "error": 4, "tmpName": ""},
+			},
+		},
+	}))
+	require.NoError(t, err)
+
+	sum := sha512.Sum512([]byte("content"))
+	expected := map[string]interface{}{
+		"upload": map[string]interface{}{"name": "a.txt", "mime": "text/plain", "size": 7, "error": 0, "sha512": hex.EncodeToString(sum[:])},
+		"arr": []interface{}{
+			map[string]interface{}{"name": "b.txt", "mime": "", "size": 0, "error": 4},
+		},
+	}
+	exp, err := json.Marshal(expected)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(exp), string(httpResponse(t, res).Body))
+}
+
+func TestHTTPRecord(t *testing.T) {
+	rec := filepath.Join(t.TempDir(), "records")
+
+	w := spawn(t, pipe.NewPipeFactory(zap.NewNop()), "http", pipes, envRecord+"="+rec, envBehaviour+"=context")
+
+	req := &HTTPRequest{
+		RemoteAddr: "127.0.0.1",
+		Protocol:   "HTTP/1.1",
+		Method:     http.MethodPost,
+		URI:        "http://127.0.0.1/foo?hello=world",
+		Headers:    http.Header{"Content-Type": {"application/json"}},
+		Cookies:    map[string]string{"input": "cookie"},
+		RawQuery:   "hello=world",
+		Attributes: map[string]interface{}{"foo": "bar"},
+		Body:       []byte(`{"key":"value"}`),
+	}
+	p := httpPayload(t, req)
+
+	res, err := w.Exec(&payload.Payload{Context: p.Context, Body: p.Body})
+	require.NoError(t, err)
+	assert.JSONEq(t, string(p.Context), string(res.Body))
+	require.NoError(t, w.Stop())
+
+	records, err := ReadRecords(rec)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, int(w.Pid()), records[0].Pid)
+
+	got, err := records[0].HTTPRequest()
+	require.NoError(t, err)
+	assert.Equal(t, req, got)
+}
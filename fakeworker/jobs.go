@@ -0,0 +1,254 @@
+package fakeworker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Job headers which script the jobs worker, they take precedence over the script in the payload
+const (
+	HeaderAction      string = "fakeworker_action"
+	HeaderDelay       string = "fakeworker_delay"
+	HeaderMaxAttempts string = "fakeworker_max_attempts"
+	HeaderSleep       string = "fakeworker_sleep"
+	HeaderQueue       string = "fakeworker_queue"
+	HeaderReport      string = "fakeworker_report"
+
+	// envAction is the default action of the jobs worker
+	envAction string = "FAKEWORKER_JOBS_ACTION"
+	// attempts is the header incremented on every requeue, the same one jobs_err.php uses
+	attempts string = "attempts"
+)
+
+// Jobs worker actions
+const (
+	// ActionAck acknowledges the job (jobs_ok.php)
+	ActionAck string = "ack"
+	// ActionNack fails the job without the requeue
+	ActionNack string = "nack"
+	// ActionRequeue fails the job and requeues it with the delay, acks it after the max attempts (jobs_err.php)
+	ActionRequeue string = "requeue"
+	// ActionRespond responds to the queue (jobs_respond.php)
+	ActionRespond string = "respond"
+	// ActionCrash kills the worker in the middle of the job
+	ActionCrash string = "crash"
+	// ActionMalformed responds with not a JSON (jobs_bad_resp.php)
+	ActionMalformed string = "malformed"
+	// ActionError responds with the worker error frame
+	ActionError string = "error"
+)
+
+// response types understood by the jobs plugin
+const (
+	respNoError int = iota
+	respError
+	respQueue
+)
+
+// JobRequest is the job sent by the jobs plugin
+type JobRequest struct {
+	ID       string              `json:"id"`
+	Job      string              `json:"job"`
+	Driver   string              `json:"driver"`
+	Queue    string              `json:"queue"`
+	Pipeline string              `json:"pipeline"`
+	Headers  map[string][]string `json:"headers"`
+
+	Payload []byte `json:"-"`
+}
+
+// DecodeJobRequest decodes the payload sent by the jobs plugin
+func DecodeJobRequest(p *Payload) (*JobRequest, error) {
+	req := &JobRequest{}
+	err := json.Unmarshal(p.Context, req)
+	if err != nil {
+		return nil, fmt.Errorf("bad job context %q: %w", p.Context, err)
+	}
+
+	req.Payload = p.Body
+	return req, nil
+}
+
+// JobScript tells the jobs worker what to do with the job. It's read from the "fakeworker" key of the JSON
+// payload, then overridden by the fakeworker_* headers.
+type JobScript struct {
+	Action string `json:"action,omitempty"`
+	// Delay of the requeue in seconds
+	Delay int64 `json:"delay,omitempty"`
+	// MaxAttempts of the requeue, the job is acked after, 0 means requeue forever
+	MaxAttempts int `json:"max_attempts,omitempty"`
+	// Sleep before the action, time.Duration string
+	Sleep string `json:"sleep,omitempty"`
+	// Queue to respond to
+	Queue string `json:"queue,omitempty"`
+	// Report the acked job to the host:port
+	Report string `json:"report,omitempty"`
+}
+
+// Payload returns the job payload carrying the script
+func (s JobScript) Payload() string {
+	data, _ := json.Marshal(map[string]JobScript{"fakeworker": s})
+	return string(data)
+}
+
+func (s *JobScript) fromHeaders(h map[string][]string) error {
+	var err error
+	if v := first(h, HeaderAction); v != "" {
+		s.Action = v
+	}
+	if v := first(h, HeaderDelay); v != "" {
+		s.Delay, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return err
+		}
+	}
+	if v := first(h, HeaderMaxAttempts); v != "" {
+		s.MaxAttempts, err = strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+	}
+	if v := first(h, HeaderSleep); v != "" {
+		s.Sleep = v
+	}
+	if v := first(h, HeaderQueue); v != "" {
+		s.Queue = v
+	}
+	if v := first(h, HeaderReport); v != "" {
+		s.Report = v
+	}
+
+	return nil
+}
+
+type jobsResponse struct {
+	Type int         `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+type jobsError struct {
+	Message string              `json:"message"`
+	Requeue bool                `json:"requeue"`
+	Delay   int64               `json:"delay_seconds"`
+	Headers map[string][]string `json:"headers"`
+}
+
+type jobsRespond struct {
+	Queue   string `json:"queue"`
+	Payload string `json:"payload"`
+}
+
+// Jobs adapts the func working with the decoded jobs to the Handler interface, the returned payload is sent as is
+func Jobs(fn func(job *JobRequest) (*Payload, error)) Handler {
+	return HandlerFunc(func(p *Payload) (*Payload, error) {
+		job, err := DecodeJobRequest(p)
+		if err != nil {
+			return nil, err
+		}
+
+		return fn(job)
+	})
+}
+
+// JobsBehaviours is the scriptable jobs worker, see JobScript. The default action is taken from the
+// FAKEWORKER_JOBS_ACTION env variable, ack if not set. Registered as "jobs".
+func JobsBehaviours() Handler {
+	return Jobs(func(job *JobRequest) (*Payload, error) {
+		script := JobScript{Action: os.Getenv(envAction)}
+
+		// the payload is not necessarily a JSON
+		wrapper := struct {
+			Script *JobScript `json:"fakeworker"`
+		}{Script: &script}
+		_ = json.Unmarshal(job.Payload, &wrapper)
+
+		err := script.fromHeaders(job.Headers)
+		if err != nil {
+			return nil, err
+		}
+
+		if script.Sleep != "" {
+			d, err := time.ParseDuration(script.Sleep)
+			if err != nil {
+				return nil, err
+			}
+			time.Sleep(d)
+		}
+
+		switch script.Action {
+		case ActionAck, "":
+			return ack(job, &script)
+		case ActionNack:
+			return jobsPayload(respError, &jobsError{Message: "nack", Headers: job.Headers})
+		case ActionRequeue:
+			headers := copyHeaders(job.Headers)
+			n, _ := strconv.Atoi(first(headers, attempts))
+			n++
+			headers[attempts] = []string{strconv.Itoa(n)}
+
+			if script.MaxAttempts > 0 && n > script.MaxAttempts {
+				return ack(job, &script)
+			}
+
+			return jobsPayload(respError, &jobsError{
+				Message: "requeue",
+				Requeue: true,
+				Delay:   script.Delay,
+				Headers: headers,
+			})
+		case ActionRespond:
+			return jobsPayload(respQueue, &jobsRespond{Queue: script.Queue, Payload: string(job.Payload)})
+		case ActionCrash:
+			os.Exit(1)
+			return nil, nil
+		case ActionMalformed:
+			return &Payload{Body: []byte("foo")}, nil
+		case ActionError:
+			return nil, errors.New("job error")
+		default:
+			return nil, fmt.Errorf("unknown jobs action: %s", script.Action)
+		}
+	})
+}
+
+// ack acknowledges the job, reporting the delivery first if requested
+func ack(job *JobRequest, script *JobScript) (*Payload, error) {
+	if script.Report != "" {
+		err := reportDelivery(script.Report, job)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return jobsPayload(respNoError, struct{}{})
+}
+
+func jobsPayload(tp int, data interface{}) (*Payload, error) {
+	body, err := json.Marshal(&jobsResponse{Type: tp, Data: data})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Payload{Body: body}, nil
+}
+
+func first(h map[string][]string, key string) string {
+	if len(h[key]) == 0 {
+		return ""
+	}
+
+	return h[key][0]
+}
+
+func copyHeaders(h map[string][]string) map[string][]string {
+	out := make(map[string][]string, len(h)+1)
+	for k, v := range h {
+		out[k] = append([]string(nil), v...)
+	}
+
+	return out
+}
@@ -0,0 +1,130 @@
+package fakeworker
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func jobPayload(t *testing.T, headers map[string][]string, body string) *Payload {
+	ctx, err := json.Marshal(&JobRequest{
+		ID:       "1",
+		Job:      "some/php/namespace",
+		Driver:   "memory",
+		Queue:    "test-1",
+		Pipeline: "test-1",
+		Headers:  headers,
+	})
+	require.NoError(t, err)
+
+	return &Payload{Context: ctx, Body: []byte(body)}
+}
+
+func TestJobsBehaviours(t *testing.T) {
+	h := JobsBehaviours()
+
+	tests := []struct {
+		name     string
+		headers  map[string][]string
+		payload  string
+		expected string
+	}{
+		{"default", nil, `{"hello":"world"}`, `{"type":0,"data":{}}`},
+		{"ack", map[string][]string{HeaderAction: {ActionAck}}, "", `{"type":0,"data":{}}`},
+		{"nack", map[string][]string{HeaderAction: {ActionNack}}, "", `{"type":1,"data":{"message":"nack","requeue":false,"delay_seconds":0,"headers":{"fakeworker_action":["nack"]}}}`},
+		{
+			"requeue",
+			map[string][]string{HeaderAction: {ActionRequeue}, HeaderDelay: {"5"}},
+			"",
+			`{"type":1,"data":{"message":"requeue","requeue":true,"delay_seconds":5,"headers":{"attempts":["1"],"fakeworker_action":["requeue"],"fakeworker_delay":["5"]}}}`,
+		},
+		{
+			"requeue exhausted",
+			map[string][]string{HeaderAction: {ActionRequeue}, HeaderMaxAttempts: {"2"}, "attempts": {"2"}},
+			"",
+			`{"type":0,"data":{}}`,
+		},
+		{"respond", map[string][]string{HeaderAction: {ActionRespond}, HeaderQueue: {"test-2"}}, "foo", `{"type":2,"data":{"queue":"test-2","payload":"foo"}}`},
+		{"payload script", nil, JobScript{Action: ActionRespond, Queue: "q"}.Payload(), `{"type":2,"data":{"queue":"q","payload":"{\"fakeworker\":{\"action\":\"respond\",\"queue\":\"q\"}}"}}`},
+		{"headers override payload", map[string][]string{HeaderAction: {ActionAck}}, JobScript{Action: ActionNack}.Payload(), `{"type":0,"data":{}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := h.Handle(jobPayload(t, tt.headers, tt.payload))
+			require.NoError(t, err)
+			assert.Empty(t, res.Context)
+			assert.JSONEq(t, tt.expected, string(res.Body))
+		})
+	}
+
+	res, err := h.Handle(jobPayload(t, map[string][]string{HeaderAction: {ActionMalformed}}, ""))
+	require.NoError(t, err)
+	assert.Equal(t, "foo", string(res.Body))
+
+	_, err = h.Handle(jobPayload(t, map[string][]string{HeaderAction: {ActionError}}, ""))
+	assert.Error(t, err)
+
+	_, err = h.Handle(jobPayload(t, map[string][]string{HeaderAction: {"foo"}}, ""))
+	assert.Error(t, err)
+}
+
+func TestJobRecord(t *testing.T) {
+	p := jobPayload(t, map[string][]string{"foo": {"bar"}}, "payload")
+	r := Record{Context: p.Context, Body: p.Body}
+
+	job, err := r.JobRequest()
+	require.NoError(t, err)
+	assert.Equal(t, &JobRequest{
+		ID:       "1",
+		Job:      "some/php/namespace",
+		Driver:   "memory",
+		Queue:    "test-1",
+		Pipeline: "test-1",
+		Headers:  map[string][]string{"foo": {"bar"}},
+		Payload:  []byte("payload"),
+	}, job)
+}
+
+func TestJobsReport(t *testing.T) {
+	ls, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() {
+		_ = ls.Close()
+	}()
+
+	h := JobsBehaviours()
+
+	// only the acked jobs are reported
+	_, err = h.Handle(jobPayload(t, map[string][]string{HeaderAction: {ActionNack}, HeaderReport: {ls.Addr().String()}}, ""))
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		res, err := h.Handle(jobPayload(t, map[string][]string{HeaderReport: {ls.Addr().String()}}, ""))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"type":0,"data":{}}`, string(res.Body))
+	}
+
+	// the connection is reused
+	c, err := ls.Accept()
+	require.NoError(t, err)
+	defer func() {
+		_ = c.Close()
+	}()
+
+	r := bufio.NewScanner(c)
+	for i := 0; i < 2; i++ {
+		require.True(t, r.Scan())
+
+		d := Delivery{}
+		require.NoError(t, json.Unmarshal(r.Bytes(), &d))
+		assert.Equal(t, Delivery{ID: "1", Pipeline: "test-1", Driver: "memory"}, d)
+	}
+
+	_, err = h.Handle(jobPayload(t, map[string][]string{HeaderReport: {"127.0.0.1:1"}}, ""))
+	assert.Error(t, err)
+}
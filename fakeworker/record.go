@@ -0,0 +1,88 @@
+package fakeworker
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+)
+
+// envRecord is the path of the file where the worker appends every received payload
+const envRecord string = "FAKEWORKER_RECORD"
+
+// Record is the payload received by the worker
+type Record struct {
+	Pid     int    `json:"pid"`
+	Context []byte `json:"context"`
+	Body    []byte `json:"body"`
+}
+
+// HTTPRequest decodes the recorded http plugin request
+func (r *Record) HTTPRequest() (*HTTPRequest, error) {
+	return DecodeHTTPRequest(&Payload{Context: r.Context, Body: r.Body})
+}
+
+// JobRequest decodes the recorded jobs plugin job
+func (r *Record) JobRequest() (*JobRequest, error) {
+	return DecodeJobRequest(&Payload{Context: r.Context, Body: r.Body})
+}
+
+// Recording wraps the handler to append every received payload to the file (JSON lines). The file is shared by
+// all workers of the pool, each record is written with the single append.
+func Recording(path string, h Handler) Handler {
+	return HandlerFunc(func(p *Payload) (*Payload, error) {
+		data, err := json.Marshal(&Record{
+			Pid:     os.Getpid(),
+			Context: p.Context,
+			Body:    p.Body,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = f.Write(append(data, '\n'))
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+
+		err = f.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		return h.Handle(p)
+	})
+}
+
+// ReadRecords reads the payloads recorded by the workers, a missing file means no records
+func ReadRecords(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var records []Record
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for sc.Scan() {
+		r := Record{}
+		err = json.Unmarshal(sc.Bytes(), &r)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+
+	return records, sc.Err()
+}
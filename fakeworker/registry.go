@@ -0,0 +1,116 @@
+package fakeworker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// arg is the first argument of the command which turns the binary into the worker
+const arg string = "fakeworker"
+
+var (
+	mu       sync.RWMutex
+	handlers = map[string]Handler{
+		"echo":  Echo(),
+		"error": Error(),
+		"pid":   Pid(),
+		"http":  HTTPBehaviours(),
+		"jobs":  JobsBehaviours(),
+	}
+)
+
+// Register makes the handler available by name for Command and Main. Handlers are registered in the test
+// package (init or TestMain), so the re-executed test binary has the same set.
+func Register(name string, h Handler) {
+	mu.Lock()
+	handlers[name] = h
+	mu.Unlock()
+}
+
+// Lookup returns the registered handler
+func Lookup(name string) (Handler, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	h, ok := handlers[name]
+	return h, ok
+}
+
+// Args returns the argv which runs the current binary as the worker with the named handler.
+// Main should be called at the beginning of the binary's TestMain (or main).
+func Args(name string) []string {
+	exe, err := os.Executable()
+	if err != nil {
+		exe = os.Args[0]
+	}
+
+	return []string{exe, arg, name}
+}
+
+// Command returns the server.command value which runs the current binary as the worker with the named handler.
+// RR splits the command by spaces, so the path of the binary should not contain them.
+func Command(name string) string {
+	return strings.Join(Args(name), " ")
+}
+
+// Cmd is the same as Command, but returns the command ready to be started by the worker factory (pools in tests)
+func Cmd(name string, env ...string) *exec.Cmd {
+	args := Args(name)
+	cmd := exec.Command(args[0], args[1:]...) //nolint:gosec
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stderr = os.Stderr
+
+	return cmd
+}
+
+// Main runs the worker and exits if the binary was started via Command, otherwise it returns immediately:
+//
+//	func TestMain(m *testing.M) {
+//		fakeworker.Main()
+//		os.Exit(m.Run())
+//	}
+func Main() {
+	if len(os.Args) < 3 || os.Args[1] != arg {
+		return
+	}
+
+	h, ok := Lookup(os.Args[2])
+	if !ok {
+		_, _ = fmt.Fprintf(os.Stderr, "fakeworker: unknown handler %s\n", os.Args[2])
+		os.Exit(2)
+	}
+
+	err := Run(h)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "fakeworker: %v\n", err)
+		os.Exit(1)
+	}
+
+	os.Exit(0)
+}
+
+// Echo responds with the received body
+func Echo() Handler {
+	return HandlerFunc(func(p *Payload) (*Payload, error) {
+		return &Payload{Body: p.Body}, nil
+	})
+}
+
+// Error responds with the error, the body is used as the message
+func Error() Handler {
+	return HandlerFunc(func(p *Payload) (*Payload, error) {
+		return nil, errors.New(string(p.Body))
+	})
+}
+
+// Pid responds with the pid of the worker
+func Pid() Handler {
+	return HandlerFunc(func(_ *Payload) (*Payload, error) {
+		return &Payload{Body: []byte(strconv.Itoa(os.Getpid()))}, nil
+	})
+}
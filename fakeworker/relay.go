@@ -0,0 +1,43 @@
+package fakeworker
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/roadrunner-server/goridge/v3/pkg/pipe"
+	"github.com/roadrunner-server/goridge/v3/pkg/relay"
+	"github.com/roadrunner-server/goridge/v3/pkg/socket"
+)
+
+const (
+	// envRelay is set by the server plugin from the server.relay option
+	envRelay string = "RR_RELAY"
+
+	pipes      string = "pipes"
+	tcpPrefix  string = "tcp://"
+	unixPrefix string = "unix://"
+)
+
+// NewRelay creates the relay from the server.relay value: pipes (or empty), tcp://host:port or unix://path
+func NewRelay(addr string) (relay.Relay, error) {
+	switch {
+	case addr == "" || addr == pipes:
+		return pipe.NewPipeRelay(os.Stdin, os.Stdout), nil
+	case strings.HasPrefix(addr, tcpPrefix):
+		conn, err := net.Dial("tcp", strings.TrimPrefix(addr, tcpPrefix))
+		if err != nil {
+			return nil, err
+		}
+		return socket.NewSocketRelay(conn), nil
+	case strings.HasPrefix(addr, unixPrefix):
+		conn, err := net.Dial("unix", strings.TrimPrefix(addr, unixPrefix))
+		if err != nil {
+			return nil, err
+		}
+		return socket.NewSocketRelay(conn), nil
+	default:
+		return nil, fmt.Errorf("unknown relay: %s", addr)
+	}
+}
@@ -0,0 +1,174 @@
+// Package fakeworker implements the RoadRunner worker side of the goridge protocol in Go, so the tests can
+// define the worker logic without PHP. The worker is either the standalone binary (cmd/fakeworker) or the test
+// binary itself, re-executed via Command and Main.
+package fakeworker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/roadrunner-server/goridge/v3/pkg/frame"
+	"github.com/roadrunner-server/goridge/v3/pkg/relay"
+)
+
+// Payload is a single request or response, Context holds the plugin specific metadata (usually JSON)
+type Payload struct {
+	Context []byte
+	Body    []byte
+}
+
+// Handler processes the payloads sent to the worker. An error is sent back as the ERROR frame, which RR treats
+// as a soft job error. Handlers must not write to the stdout, it's used as the relay in the pipes mode.
+type Handler interface {
+	Handle(p *Payload) (*Payload, error)
+}
+
+// HandlerFunc adapts the func to the Handler interface
+type HandlerFunc func(p *Payload) (*Payload, error)
+
+// Handle calls f(p)
+func (f HandlerFunc) Handle(p *Payload) (*Payload, error) {
+	return f(p)
+}
+
+// control is the union of the commands RR sends in the CONTROL frames
+type control struct {
+	Pid  int  `json:"pid,omitempty"`
+	Stop bool `json:"stop,omitempty"`
+}
+
+// Run connects to RR using the RR_RELAY env variable and serves the handler. When FAKEWORKER_RECORD is set,
+// the received payloads are recorded to that file, see ReadRecords.
+func Run(h Handler) error {
+	if path := os.Getenv(envRecord); path != "" {
+		h = Recording(path, h)
+	}
+
+	rl, err := NewRelay(os.Getenv(envRelay))
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = rl.Close()
+	}()
+
+	return Serve(rl, h)
+}
+
+// Serve serves the handler over the relay until the stop command is received or the relay is closed by RR
+func Serve(rl relay.Relay, h Handler) error {
+	for {
+		fr := frame.NewFrame()
+		err := rl.Receive(fr)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		if fr.ReadFlags()&frame.CONTROL != 0 {
+			stop, err := handleControl(rl, fr)
+			if err != nil {
+				return err
+			}
+
+			if stop {
+				return nil
+			}
+
+			continue
+		}
+
+		options := fr.ReadOptions(fr.Header())
+		if len(options) != 1 || int(options[0]) > len(fr.Payload()) {
+			return fmt.Errorf("bad payload frame, options: %v, payload length: %d", options, len(fr.Payload()))
+		}
+
+		pld := fr.Payload()
+		resp, err := h.Handle(&Payload{
+			Context: pld[:options[0]],
+			Body:    pld[options[0]:],
+		})
+		if err != nil {
+			err = sendError(rl, err)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err = send(rl, resp)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func handleControl(rl relay.Relay, fr *frame.Frame) (bool, error) {
+	cmd := &control{}
+	err := json.Unmarshal(fr.Payload(), cmd)
+	if err != nil {
+		return false, fmt.Errorf("bad control frame %q: %w", fr.Payload(), err)
+	}
+
+	if cmd.Stop {
+		return true, nil
+	}
+
+	if cmd.Pid == 0 {
+		return false, fmt.Errorf("unknown control command: %s", fr.Payload())
+	}
+
+	// RR sends its pid and expects the worker's pid in response
+	data, err := json.Marshal(&control{Pid: os.Getpid()})
+	if err != nil {
+		return false, err
+	}
+
+	out := frame.NewFrame()
+	out.WriteVersion(out.Header(), frame.Version1)
+	out.WriteFlags(out.Header(), frame.CONTROL, frame.CodecJSON)
+	out.WritePayloadLen(out.Header(), uint32(len(data)))
+	out.WritePayload(data)
+	out.WriteCRC(out.Header())
+
+	return false, rl.Send(out)
+}
+
+func send(rl relay.Relay, p *Payload) error {
+	if p == nil {
+		p = &Payload{}
+	}
+
+	data := make([]byte, 0, len(p.Context)+len(p.Body))
+	data = append(data, p.Context...)
+	data = append(data, p.Body...)
+
+	fr := frame.NewFrame()
+	fr.WriteVersion(fr.Header(), frame.Version1)
+	fr.WriteFlags(fr.Header(), frame.CodecRaw)
+	fr.WriteOptions(fr.HeaderPtr(), uint32(len(p.Context)))
+	fr.WritePayloadLen(fr.Header(), uint32(len(data)))
+	fr.WritePayload(data)
+	fr.WriteCRC(fr.Header())
+
+	return rl.Send(fr)
+}
+
+func sendError(rl relay.Relay, e error) error {
+	data := []byte(e.Error())
+
+	fr := frame.NewFrame()
+	fr.WriteVersion(fr.Header(), frame.Version1)
+	fr.WriteFlags(fr.Header(), frame.ERROR)
+	fr.WritePayloadLen(fr.Header(), uint32(len(data)))
+	fr.WritePayload(data)
+	fr.WriteCRC(fr.Header())
+
+	return rl.Send(fr)
+}
@@ -0,0 +1,124 @@
+package fakeworker
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/api/v2/payload"
+	workerApi "github.com/roadrunner-server/api/v2/worker"
+	"github.com/roadrunner-server/sdk/v2/ipc/pipe"
+	"github.com/roadrunner-server/sdk/v2/ipc/socket"
+	"github.com/roadrunner-server/sdk/v2/worker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	Register("upper", HandlerFunc(func(p *Payload) (*Payload, error) {
+		return &Payload{Context: p.Context, Body: []byte(strings.ToUpper(string(p.Body)))}, nil
+	}))
+
+	Main()
+	os.Exit(m.Run())
+}
+
+type factory interface {
+	SpawnWorkerWithTimeout(ctx context.Context, cmd *exec.Cmd) (workerApi.BaseProcess, error)
+}
+
+// spawn starts the current test binary as the worker, the same way the server plugin does
+func spawn(t *testing.T, f factory, name, relay string, env ...string) *worker.Worker {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	w, err := f.SpawnWorkerWithTimeout(ctx, Cmd(name, append(env, envRelay+"="+relay)...))
+	require.NoError(t, err)
+
+	go func() {
+		assert.NoError(t, w.Wait())
+	}()
+
+	return worker.From(w)
+}
+
+func exercise(t *testing.T, w *worker.Worker) {
+	res, err := w.Exec(&payload.Payload{Context: []byte(`{"foo":"bar"}`), Body: []byte("hello")})
+	require.NoError(t, err)
+	assert.Equal(t, []byte(`{"foo":"bar"}`), res.Context)
+	assert.Equal(t, []byte("HELLO"), res.Body)
+
+	res, err = w.Exec(&payload.Payload{Body: []byte("world")})
+	require.NoError(t, err)
+	assert.Empty(t, res.Context)
+	assert.Equal(t, []byte("WORLD"), res.Body)
+
+	require.NoError(t, w.Stop())
+}
+
+func TestPipes(t *testing.T) {
+	exercise(t, spawn(t, pipe.NewPipeFactory(zap.NewNop()), "upper", pipes))
+}
+
+func TestTCP(t *testing.T) {
+	ls, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() {
+		_ = ls.Close()
+	}()
+
+	f := socket.NewSocketServer(ls, time.Second*10, zap.NewNop())
+	exercise(t, spawn(t, f, "upper", tcpPrefix+ls.Addr().String()))
+}
+
+func TestUnix(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "rr.sock")
+	ls, err := net.Listen("unix", sock)
+	require.NoError(t, err)
+	defer func() {
+		_ = ls.Close()
+	}()
+
+	f := socket.NewSocketServer(ls, time.Second*10, zap.NewNop())
+	exercise(t, spawn(t, f, "upper", unixPrefix+sock))
+}
+
+func TestBuiltins(t *testing.T) {
+	f := pipe.NewPipeFactory(zap.NewNop())
+
+	w := spawn(t, f, "echo", pipes)
+	res, err := w.Exec(&payload.Payload{Context: []byte("ctx"), Body: []byte("hello")})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hello"), res.Body)
+	require.NoError(t, w.Stop())
+
+	w = spawn(t, f, "pid", pipes)
+	res, err = w.Exec(&payload.Payload{Body: []byte("hello")})
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(int(w.Pid())), string(res.Body))
+	require.NoError(t, w.Stop())
+
+	w = spawn(t, f, "error", pipes)
+	_, err = w.Exec(&payload.Payload{Body: []byte("boom")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "boom")
+	require.NoError(t, w.Stop())
+}
+
+func TestArgs(t *testing.T) {
+	args := Args("echo")
+	require.Len(t, args, 3)
+	assert.Equal(t, []string{arg, "echo"}, args[1:])
+
+	// the argv is passed as is, the path of the binary may contain spaces
+	cmd := Cmd("echo")
+	assert.Equal(t, args[0], cmd.Path)
+	assert.Equal(t, args, cmd.Args)
+}
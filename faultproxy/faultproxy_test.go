@@ -0,0 +1,276 @@
+package faultproxy
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echo starts the line echo server
+func echo(t *testing.T) string {
+	t.Helper()
+
+	ls, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = ls.Close()
+	})
+
+	go func() {
+		for {
+			c, err := ls.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer func() {
+					_ = c.Close()
+				}()
+				_, _ = io.Copy(c, c)
+			}()
+		}
+	}()
+
+	return ls.Addr().String()
+}
+
+func dial(t *testing.T, p *Proxy) (net.Conn, *bufio.Reader) {
+	t.Helper()
+
+	c, err := net.Dial("tcp", p.Addr())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = c.Close()
+	})
+
+	return c, bufio.NewReader(c)
+}
+
+func roundTrip(t *testing.T, c net.Conn, r *bufio.Reader, msg string) (string, error) {
+	t.Helper()
+
+	_, err := c.Write([]byte(msg + "\n"))
+	if err != nil {
+		return "", err
+	}
+
+	line, err := r.ReadString('\n')
+	return strings.TrimSuffix(line, "\n"), err
+}
+
+func TestForward(t *testing.T) {
+	p := Start(t, echo(t))
+	c, r := dial(t, p)
+
+	out, err := roundTrip(t, c, r, "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", out)
+
+	require.Eventually(t, func() bool { return p.Connections() == 1 }, time.Second, time.Millisecond*10)
+}
+
+func TestDisableEnable(t *testing.T) {
+	p := Start(t, echo(t))
+	c, r := dial(t, p)
+
+	require.NoError(t, p.Disable())
+	assert.False(t, p.Enabled())
+
+	_, err := roundTrip(t, c, r, "hello")
+	assert.Error(t, err)
+
+	_, err = net.Dial("tcp", p.Addr())
+	assert.Error(t, err)
+
+	require.NoError(t, p.Enable())
+	c, r = dial(t, p)
+	out, err := roundTrip(t, c, r, "again")
+	require.NoError(t, err)
+	assert.Equal(t, "again", out)
+}
+
+func TestDropConnections(t *testing.T) {
+	p := Start(t, echo(t))
+	c, r := dial(t, p)
+
+	_, err := roundTrip(t, c, r, "hello")
+	require.NoError(t, err)
+
+	p.DropConnections()
+	_, err = r.ReadString('\n')
+	assert.Error(t, err)
+
+	// still accepting
+	c, r = dial(t, p)
+	_, err = roundTrip(t, c, r, "hello")
+	assert.NoError(t, err)
+}
+
+func TestLatency(t *testing.T) {
+	p := Start(t, echo(t))
+	c, r := dial(t, p)
+
+	require.NoError(t, p.AddToxic("latency", Downstream, Latency{Latency: time.Millisecond * 200, Jitter: time.Millisecond * 50}))
+	assert.Error(t, p.AddToxic("latency", Upstream, Latency{}))
+
+	start := time.Now()
+	_, err := roundTrip(t, c, r, "hello")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), time.Millisecond*150)
+
+	require.NoError(t, p.RemoveToxic("latency"))
+	assert.Error(t, p.RemoveToxic("latency"))
+
+	start = time.Now()
+	_, err = roundTrip(t, c, r, "hello")
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), time.Millisecond*100)
+}
+
+func TestBandwidth(t *testing.T) {
+	p := Start(t, echo(t))
+	c, r := dial(t, p)
+
+	// 10 KB/s, 5 KB take ~0.5s
+	require.NoError(t, p.AddToxic("bw", Upstream, Bandwidth{Rate: 10}))
+
+	start := time.Now()
+	out, err := roundTrip(t, c, r, strings.Repeat("a", 5*1024))
+	require.NoError(t, err)
+	assert.Len(t, out, 5*1024)
+	assert.GreaterOrEqual(t, time.Since(start), time.Millisecond*400)
+}
+
+func TestTimeout(t *testing.T) {
+	p := Start(t, echo(t))
+	c, r := dial(t, p)
+
+	// zero timeout holds the data until removed
+	require.NoError(t, p.AddToxic("hold", Upstream, Timeout{}))
+	_, err := c.Write([]byte("held\n"))
+	require.NoError(t, err)
+
+	time.AfterFunc(time.Millisecond*200, func() {
+		_ = p.RemoveToxic("hold")
+	})
+
+	start := time.Now()
+	line, err := r.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "held\n", line)
+	assert.GreaterOrEqual(t, time.Since(start), time.Millisecond*150)
+
+	// with the timeout the connection is closed
+	require.NoError(t, p.AddToxic("timeout", Upstream, Timeout{Timeout: time.Millisecond * 100}))
+	_, err = roundTrip(t, c, r, "lost")
+	assert.Error(t, err)
+}
+
+func TestResetPeer(t *testing.T) {
+	p := Start(t, echo(t))
+	require.NoError(t, p.AddToxic("reset", Downstream, ResetPeer{Timeout: time.Millisecond * 100}))
+
+	c, r := dial(t, p)
+	_, err := roundTrip(t, c, r, "hello")
+	require.NoError(t, err)
+
+	_, err = r.ReadString('\n')
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, syscall.ECONNRESET), err)
+}
+
+func TestLimitData(t *testing.T) {
+	p := Start(t, echo(t))
+	require.NoError(t, p.AddToxic("limit", Downstream, LimitData{Bytes: 4}))
+
+	c, r := dial(t, p)
+	_, err := c.Write([]byte("hello\n"))
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "hell", string(data))
+}
+
+func TestLimitDataAddedLater(t *testing.T) {
+	p := Start(t, echo(t))
+	c, r := dial(t, p)
+
+	// the link carries more than the limit before the toxic is added
+	msg := strings.Repeat("a", 99)
+	out, err := roundTrip(t, c, r, msg)
+	require.NoError(t, err)
+	assert.Equal(t, msg, out)
+
+	// the bytes are counted from the moment the toxic is added
+	require.NoError(t, p.AddToxic("limit", Downstream, LimitData{Bytes: 10}))
+
+	_, err = c.Write([]byte(msg + "\n"))
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("a", 10), string(data))
+}
+
+func TestSlowClose(t *testing.T) {
+	p := Start(t, echo(t))
+	require.NoError(t, p.AddToxic("slow", Upstream, SlowClose{Delay: time.Millisecond * 300}))
+
+	c, r := dial(t, p)
+	_, err := roundTrip(t, c, r, "hello")
+	require.NoError(t, err)
+
+	// half-close the client side, the proxy keeps the link for the delay
+	require.NoError(t, c.(*net.TCPConn).CloseWrite())
+
+	start := time.Now()
+	_, err = r.ReadString('\n')
+	assert.ErrorIs(t, err, io.EOF)
+	assert.GreaterOrEqual(t, time.Since(start), time.Millisecond*250)
+}
+
+func TestSlicer(t *testing.T) {
+	p := Start(t, echo(t))
+	c, r := dial(t, p)
+
+	// 1 KB in ~64 byte slices with 10ms between them take at least ~0.1s
+	require.NoError(t, p.AddToxic("slicer", Downstream, Slicer{AverageSize: 64, SizeVariation: 16, Delay: time.Millisecond * 10}))
+
+	start := time.Now()
+	out, err := roundTrip(t, c, r, strings.Repeat("a", 1024))
+	require.NoError(t, err)
+	assert.Len(t, out, 1024)
+	assert.GreaterOrEqual(t, time.Since(start), time.Millisecond*100)
+}
+
+func TestUpdateToxic(t *testing.T) {
+	p := Start(t, echo(t))
+	c, r := dial(t, p)
+
+	assert.Error(t, p.UpdateToxic("latency", Latency{}))
+	require.NoError(t, p.AddToxic("latency", Upstream, Latency{Latency: time.Millisecond * 300}))
+	require.NoError(t, p.UpdateToxic("latency", Latency{Latency: time.Millisecond * 10}))
+
+	start := time.Now()
+	_, err := roundTrip(t, c, r, "hello")
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), time.Millisecond*200)
+
+	// the timer is restarted with the new timeout
+	require.NoError(t, p.AddToxic("reset", Downstream, ResetPeer{Timeout: time.Hour}))
+	require.NoError(t, p.UpdateToxic("reset", ResetPeer{Timeout: time.Millisecond * 100}))
+
+	_, err = r.ReadString('\n')
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, syscall.ECONNRESET), err)
+}
@@ -0,0 +1,295 @@
+package faultproxy
+
+import (
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	bufSize int = 32 * 1024
+	// chunks read ahead of the writer, the latency is counted from the read time
+	chunksBuffer int = 32
+	// the bandwidth limited data is written in the pieces of rate/bandwidthSteps
+	bandwidthSteps int64 = 10
+)
+
+type chunk struct {
+	data []byte
+	at   time.Time
+}
+
+// link is the proxied connection: the client and the upstream sides with the pipe in each direction
+type link struct {
+	p        *Proxy
+	client   net.Conn
+	upstream net.Conn
+	started  time.Time
+
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// timers of the timeout and reset_peer toxics, protected by p.mu
+	timers map[string]*time.Timer
+}
+
+func newLink(p *Proxy, client, upstream net.Conn) *link {
+	return &link{
+		p:        p,
+		client:   client,
+		upstream: upstream,
+		started:  time.Now(),
+		done:     make(chan struct{}),
+		timers:   make(map[string]*time.Timer),
+	}
+}
+
+func (l *link) serve() {
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		l.pipe(Upstream, l.client, l.upstream)
+	}()
+
+	go func() {
+		defer wg.Done()
+		l.pipe(Downstream, l.upstream, l.client)
+	}()
+
+	wg.Wait()
+}
+
+func (l *link) close() {
+	l.closeOnce.Do(func() {
+		close(l.done)
+		_ = l.client.Close()
+		_ = l.upstream.Close()
+	})
+}
+
+// reset closes both sides with TCP RST instead of FIN
+func (l *link) reset() {
+	for _, c := range []net.Conn{l.client, l.upstream} {
+		if tc, ok := c.(*net.TCPConn); ok {
+			_ = tc.SetLinger(0)
+		}
+	}
+
+	l.close()
+}
+
+// arm starts the link level timer of the toxic, called under p.mu
+func (l *link) arm(nt namedToxic) {
+	var (
+		after time.Duration
+		fn    func()
+	)
+
+	switch t := nt.toxic.(type) {
+	case Timeout:
+		if t.Timeout <= 0 {
+			// hold forever, the data is stopped in the pipe
+			return
+		}
+		after, fn = t.Timeout, l.close
+	case ResetPeer:
+		after, fn = t.Timeout, l.reset
+	default:
+		return
+	}
+
+	start := nt.added
+	if l.started.After(start) {
+		start = l.started
+	}
+
+	l.timers[nt.name] = time.AfterFunc(time.Until(start.Add(after)), fn)
+}
+
+// disarm stops the timer of the toxic, called under p.mu
+func (l *link) disarm(name string) {
+	if t, ok := l.timers[name]; ok {
+		t.Stop()
+		delete(l.timers, name)
+	}
+}
+
+func (l *link) disarmAll() {
+	for name := range l.timers {
+		l.disarm(name)
+	}
+}
+
+// pipe copies the data from src to dst applying the toxics of the direction, closes the link when src is closed
+func (l *link) pipe(dir Direction, src, dst net.Conn) {
+	chunks := make(chan chunk, chunksBuffer)
+
+	go func() {
+		defer close(chunks)
+
+		for {
+			buf := make([]byte, bufSize)
+			n, err := src.Read(buf)
+			if n > 0 {
+				select {
+				case chunks <- chunk{data: buf[:n], at: time.Now()}:
+				case <-l.done:
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	counted := make(limits)
+	for {
+		var (
+			c  chunk
+			ok bool
+		)
+
+		select {
+		case c, ok = <-chunks:
+		case <-l.done:
+			return
+		}
+
+		if !ok {
+			break
+		}
+
+		// the Timeout toxic stops the data until it is removed (or the link is closed by its timer)
+		ts, changed := l.p.snapshot()
+		for ts.blocked(dir) {
+			select {
+			case <-changed:
+			case <-l.done:
+				return
+			}
+			ts, changed = l.p.snapshot()
+		}
+
+		if d := ts.delay(dir, jitter); d > 0 {
+			if !l.sleep(time.Until(c.at.Add(d))) {
+				return
+			}
+		}
+
+		data := c.data
+		limited := false
+		if remaining := counted.remaining(ts, dir); remaining >= 0 && int64(len(data)) >= remaining {
+			// the limit was already reached, close the link right away
+			if remaining == 0 {
+				l.close()
+				return
+			}
+			data = data[:remaining]
+			limited = true
+		}
+
+		var slicer *Slicer
+		if sl, ok := ts.slicer(dir); ok {
+			slicer = &sl
+		}
+
+		if !l.write(dst, data, ts.rate(dir), slicer) {
+			return
+		}
+		counted.add(ts, dir, int64(len(data)))
+
+		if limited {
+			l.close()
+			return
+		}
+	}
+
+	ts, _ := l.p.snapshot()
+	if d := ts.slowClose(dir); d > 0 {
+		if !l.sleep(d) {
+			return
+		}
+	}
+
+	l.close()
+}
+
+// write writes the data, with the slicer in the pieces of its size and with the rate (bytes per second)
+// in the pieces of rate/bandwidthSteps, pausing between them, false if the link is closed
+func (l *link) write(dst net.Conn, data []byte, rate int64, slicer *Slicer) bool {
+	if rate <= 0 && slicer == nil {
+		_, err := dst.Write(data)
+		if err != nil {
+			l.close()
+			return false
+		}
+		return true
+	}
+
+	piece := int64(len(data))
+	if rate > 0 {
+		piece = rate / bandwidthSteps
+		if piece == 0 {
+			piece = 1
+		}
+	}
+
+	for len(data) > 0 {
+		n := int64(len(data))
+		if n > piece {
+			n = piece
+		}
+		if slicer != nil {
+			if size := int64(slicer.size(jitter)); n > size {
+				n = size
+			}
+		}
+
+		_, err := dst.Write(data[:n])
+		if err != nil {
+			l.close()
+			return false
+		}
+		data = data[n:]
+
+		pause := time.Duration(0)
+		if rate > 0 {
+			pause = time.Duration(n) * time.Second / time.Duration(rate)
+		}
+		if slicer != nil && len(data) > 0 {
+			pause += slicer.Delay
+		}
+
+		if !l.sleep(pause) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sleep waits for d, false if the link was closed meanwhile
+func (l *link) sleep(d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-l.done:
+		return false
+	}
+}
+
+// jitter returns the random value in [-1, 1)
+func jitter() float64 {
+	return rand.Float64()*2 - 1 //nolint:gosec
+}
@@ -0,0 +1,311 @@
+// Package faultproxy is the in-process TCP proxy with fault injection, the replacement of the toxiproxy container.
+// It supports the toxiproxy toxics (latency with jitter, bandwidth, slow_close, timeout, reset_peer, limit_data, slicer),
+// disabling and enabling the proxy on the same address and dropping the current connections, all via the Go API,
+// so the tests can script precise failure timelines.
+package faultproxy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// dialTimeout is the timeout of the upstream dial for every accepted connection
+const dialTimeout time.Duration = time.Second * 5
+
+// Proxy forwards the connections accepted on Addr to the upstream address
+type Proxy struct {
+	addr     string
+	upstream string
+
+	mu     sync.Mutex
+	ls     net.Listener
+	links  map[*link]struct{}
+	toxics toxics
+	// closed and replaced on every change of the toxics
+	changed chan struct{}
+
+	wg sync.WaitGroup
+}
+
+// Listen starts the proxy on the addr (host:port, use port 0 for the random one) forwarding to the upstream
+func Listen(addr, upstream string) (*Proxy, error) {
+	ls, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Proxy{
+		addr:     ls.Addr().String(),
+		upstream: upstream,
+		ls:       ls,
+		links:    make(map[*link]struct{}),
+		changed:  make(chan struct{}),
+	}
+
+	p.wg.Add(1)
+	go p.serve(ls)
+
+	return p, nil
+}
+
+// Start starts the proxy on the random port, the proxy is closed when the test finishes
+func Start(t testing.TB, upstream string) *Proxy {
+	t.Helper()
+
+	p, err := Listen("127.0.0.1:0", upstream)
+	if err != nil {
+		t.Fatalf("failed to start the proxy: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = p.Close()
+	})
+
+	return p
+}
+
+// Addr returns host:port the proxy listens on, it is kept across Disable/Enable
+func (p *Proxy) Addr() string {
+	return p.addr
+}
+
+// Upstream returns the address the proxy forwards to
+func (p *Proxy) Upstream() string {
+	return p.upstream
+}
+
+// Close stops the proxy and closes all connections
+func (p *Proxy) Close() error {
+	err := p.Disable()
+	p.wg.Wait()
+
+	return err
+}
+
+// Disable closes the listener and all connections, the new connections are refused until Enable
+func (p *Proxy) Disable() error {
+	p.mu.Lock()
+	ls := p.ls
+	p.ls = nil
+	for l := range p.links {
+		l.close()
+	}
+	p.mu.Unlock()
+
+	if ls == nil {
+		return nil
+	}
+
+	return ls.Close()
+}
+
+// Enable starts listening on the same address after Disable
+func (p *Proxy) Enable() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ls != nil {
+		return nil
+	}
+
+	ls, err := net.Listen("tcp", p.addr)
+	if err != nil {
+		return err
+	}
+
+	p.ls = ls
+	p.wg.Add(1)
+	go p.serve(ls)
+
+	return nil
+}
+
+// Enabled reports whether the proxy accepts the connections
+func (p *Proxy) Enabled() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.ls != nil
+}
+
+// DropConnections closes all current connections now, the proxy keeps accepting the new ones
+func (p *Proxy) DropConnections() {
+	p.mu.Lock()
+	for l := range p.links {
+		l.close()
+	}
+	p.mu.Unlock()
+}
+
+// Connections returns the number of the proxied connections
+func (p *Proxy) Connections() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.links)
+}
+
+// AddToxic adds the named toxic to the direction, it is applied to the current and the new connections
+func (p *Proxy) AddToxic(name string, dir Direction, toxic Toxic) error {
+	if toxic == nil {
+		return errors.New("toxic should not be nil")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.toxics.find(name); ok {
+		return fmt.Errorf("toxic already exists: %s", name)
+	}
+
+	nt := namedToxic{name: name, dir: dir, toxic: toxic, added: time.Now()}
+	p.setToxics(append(append(toxics(nil), p.toxics...), nt))
+
+	for l := range p.links {
+		l.arm(nt)
+	}
+
+	return nil
+}
+
+// UpdateToxic replaces the named toxic keeping its direction, the timers (timeout, reset_peer) are restarted
+func (p *Proxy) UpdateToxic(name string, toxic Toxic) error {
+	if toxic == nil {
+		return errors.New("toxic should not be nil")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.toxics.find(name); !ok {
+		return fmt.Errorf("no such toxic: %s", name)
+	}
+
+	var nt namedToxic
+	ts := make(toxics, 0, len(p.toxics))
+	for i := 0; i < len(p.toxics); i++ {
+		if p.toxics[i].name == name {
+			nt = namedToxic{name: name, dir: p.toxics[i].dir, toxic: toxic, added: time.Now()}
+			ts = append(ts, nt)
+			continue
+		}
+		ts = append(ts, p.toxics[i])
+	}
+	p.setToxics(ts)
+
+	for l := range p.links {
+		l.disarm(name)
+		l.arm(nt)
+	}
+
+	return nil
+}
+
+// RemoveToxic removes the named toxic, the stopped data (Timeout) flows again
+func (p *Proxy) RemoveToxic(name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.toxics.find(name); !ok {
+		return fmt.Errorf("no such toxic: %s", name)
+	}
+
+	ts := make(toxics, 0, len(p.toxics))
+	for i := 0; i < len(p.toxics); i++ {
+		if p.toxics[i].name != name {
+			ts = append(ts, p.toxics[i])
+		}
+	}
+	p.setToxics(ts)
+
+	for l := range p.links {
+		l.disarm(name)
+	}
+
+	return nil
+}
+
+// RemoveToxics removes all toxics
+func (p *Proxy) RemoveToxics() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i := 0; i < len(p.toxics); i++ {
+		for l := range p.links {
+			l.disarm(p.toxics[i].name)
+		}
+	}
+
+	p.setToxics(nil)
+}
+
+// setToxics replaces the toxics and notifies the links, called under the lock
+func (p *Proxy) setToxics(ts toxics) {
+	p.toxics = ts
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// snapshot returns the current toxics and the channel closed on their next change
+func (p *Proxy) snapshot() (toxics, <-chan struct{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.toxics, p.changed
+}
+
+func (p *Proxy) serve(ls net.Listener) {
+	defer p.wg.Done()
+
+	for {
+		client, err := ls.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.handle(ls, client)
+		}()
+	}
+}
+
+// handle dials the upstream for the accepted client and proxies the data until one of the sides is closed.
+// Dialing here doesn't block the accept loop when the upstream is slow or blackholed.
+func (p *Proxy) handle(ls net.Listener, client net.Conn) {
+	upstream, err := net.DialTimeout("tcp", p.upstream, dialTimeout)
+	if err != nil {
+		_ = client.Close()
+		return
+	}
+
+	l := newLink(p, client, upstream)
+
+	p.mu.Lock()
+	if p.ls != ls {
+		// disabled while dialing
+		p.mu.Unlock()
+		l.close()
+		return
+	}
+	p.links[l] = struct{}{}
+	for i := 0; i < len(p.toxics); i++ {
+		l.arm(p.toxics[i])
+	}
+	p.mu.Unlock()
+
+	l.serve()
+
+	p.mu.Lock()
+	delete(p.links, l)
+	l.disarmAll()
+	p.mu.Unlock()
+}
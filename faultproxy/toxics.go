@@ -0,0 +1,217 @@
+package faultproxy
+
+import (
+	"time"
+)
+
+// Direction of the data the toxic is applied to
+type Direction int
+
+const (
+	// Upstream is the client -> upstream direction
+	Upstream Direction = iota
+	// Downstream is the upstream -> client direction
+	Downstream
+)
+
+func (d Direction) String() string {
+	if d == Upstream {
+		return "upstream"
+	}
+
+	return "downstream"
+}
+
+// Toxic is one of the toxics below, the same set as in toxiproxy
+type Toxic interface {
+	toxic()
+}
+
+// Latency delays every chunk of data by Latency +- Jitter
+type Latency struct {
+	Latency time.Duration
+	Jitter  time.Duration
+}
+
+// Bandwidth limits the throughput to Rate KB/s
+type Bandwidth struct {
+	Rate int64
+}
+
+// SlowClose delays closing the connection by Delay after the source side is closed
+type SlowClose struct {
+	Delay time.Duration
+}
+
+// Timeout stops all data and closes the connection after Timeout, zero keeps the connection open until the toxic is removed
+type Timeout struct {
+	Timeout time.Duration
+}
+
+// ResetPeer resets the connection (TCP RST) after Timeout
+type ResetPeer struct {
+	Timeout time.Duration
+}
+
+// LimitData closes the connection after Bytes were transmitted since the toxic was added
+type LimitData struct {
+	Bytes int64
+}
+
+// Slicer slices the data into the pieces of AverageSize +- SizeVariation bytes with Delay between them
+type Slicer struct {
+	AverageSize   int
+	SizeVariation int
+	Delay         time.Duration
+}
+
+func (Latency) toxic()   {}
+func (Bandwidth) toxic() {}
+func (SlowClose) toxic() {}
+func (Timeout) toxic()   {}
+func (ResetPeer) toxic() {}
+func (LimitData) toxic() {}
+func (Slicer) toxic()    {}
+
+type namedToxic struct {
+	name  string
+	dir   Direction
+	toxic Toxic
+	// the time the toxic was added, the link level timers (timeout, reset_peer) start from max(added, link start)
+	added time.Time
+}
+
+// toxics is the immutable snapshot of the proxy toxics, replaced on every change
+type toxics []namedToxic
+
+func (ts toxics) find(name string) (namedToxic, bool) {
+	for i := 0; i < len(ts); i++ {
+		if ts[i].name == name {
+			return ts[i], true
+		}
+	}
+
+	return namedToxic{}, false
+}
+
+// blocked reports whether the data in the direction is stopped by the Timeout toxic
+func (ts toxics) blocked(dir Direction) bool {
+	for i := 0; i < len(ts); i++ {
+		if _, ok := ts[i].toxic.(Timeout); ok && ts[i].dir == dir {
+			return true
+		}
+	}
+
+	return false
+}
+
+// delay returns the latency of the direction, the jitter is applied with rnd in [-1, 1)
+func (ts toxics) delay(dir Direction, rnd func() float64) time.Duration {
+	d := time.Duration(0)
+	for i := 0; i < len(ts); i++ {
+		if l, ok := ts[i].toxic.(Latency); ok && ts[i].dir == dir {
+			d += l.Latency + time.Duration(float64(l.Jitter)*rnd())
+		}
+	}
+
+	if d < 0 {
+		return 0
+	}
+
+	return d
+}
+
+// rate returns the lowest bandwidth limit of the direction in bytes per second, zero means no limit
+func (ts toxics) rate(dir Direction) int64 {
+	rate := int64(0)
+	for i := 0; i < len(ts); i++ {
+		if b, ok := ts[i].toxic.(Bandwidth); ok && ts[i].dir == dir {
+			if r := b.Rate * 1024; rate == 0 || r < rate {
+				rate = r
+			}
+		}
+	}
+
+	return rate
+}
+
+// limits counts the bytes passed through every LimitData toxic of the link direction since the toxic was added,
+// as the toxiproxy toxic state is created when the toxic is added, by the toxic name
+type limits map[string]*limitState
+
+type limitState struct {
+	added time.Time
+	sent  int64
+}
+
+// remaining returns the bytes left until the lowest data limit of the direction is reached, negative means no limit.
+// The count restarts when the toxic is added again or updated.
+func (ls limits) remaining(ts toxics, dir Direction) int64 {
+	remaining := int64(-1)
+	for i := 0; i < len(ts); i++ {
+		l, ok := ts[i].toxic.(LimitData)
+		if !ok || ts[i].dir != dir {
+			continue
+		}
+
+		st, ok := ls[ts[i].name]
+		if !ok || !st.added.Equal(ts[i].added) {
+			st = &limitState{added: ts[i].added}
+			ls[ts[i].name] = st
+		}
+
+		r := l.Bytes - st.sent
+		if r < 0 {
+			r = 0
+		}
+		if remaining < 0 || r < remaining {
+			remaining = r
+		}
+	}
+
+	return remaining
+}
+
+// add counts n bytes passed through the LimitData toxics of the direction
+func (ls limits) add(ts toxics, dir Direction, n int64) {
+	for i := 0; i < len(ts); i++ {
+		if _, ok := ts[i].toxic.(LimitData); ok && ts[i].dir == dir {
+			if st, ok := ls[ts[i].name]; ok {
+				st.sent += n
+			}
+		}
+	}
+}
+
+// slowClose returns the longest close delay of the direction
+func (ts toxics) slowClose(dir Direction) time.Duration {
+	d := time.Duration(0)
+	for i := 0; i < len(ts); i++ {
+		if s, ok := ts[i].toxic.(SlowClose); ok && ts[i].dir == dir && s.Delay > d {
+			d = s.Delay
+		}
+	}
+
+	return d
+}
+
+// slicer returns the first slicer of the direction
+func (ts toxics) slicer(dir Direction) (Slicer, bool) {
+	for i := 0; i < len(ts); i++ {
+		if s, ok := ts[i].toxic.(Slicer); ok && ts[i].dir == dir {
+			return s, true
+		}
+	}
+
+	return Slicer{}, false
+}
+
+// size returns the random piece size, at least one byte
+func (s Slicer) size(rnd func() float64) int {
+	n := s.AverageSize + int(float64(s.SizeVariation)*rnd())
+	if n < 1 {
+		return 1
+	}
+
+	return n
+}
@@ -0,0 +1,196 @@
+package harness
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"text/template"
+
+	"github.com/roadrunner-server/config/v2"
+	endure "github.com/roadrunner-server/endure/pkg/container"
+)
+
+const (
+	localhost string = "127.0.0.1"
+	rpcPort   string = "rpc"
+)
+
+// ports allocated in this test binary, shared between all Envs to not hand out the same port twice
+var (
+	allocatedMu sync.Mutex
+	allocated   = make(map[int]struct{})
+)
+
+// Env holds the resources allocated for a single test: ports, temp dirs and variables used in the config templates.
+// Config files are rendered with text/template, the following functions are available:
+//
+//	{{ port "http" }}  - free port, the same one for the same name
+//	{{ addr "http" }}  - 127.0.0.1:{{ port "http" }}
+//	{{ tempdir }}      - temporary directory, removed after the test
+//	{{ var "redis" }}  - variable set via Env.Set (addresses of the started stand-ins, etc.)
+type Env struct {
+	t testing.TB
+
+	mu    sync.Mutex
+	ports map[string]int
+	vars  map[string]string
+	dir   string
+	opts  []endure.Options
+}
+
+// NewEnv creates an empty environment bound to the test
+func NewEnv(t testing.TB) *Env {
+	return &Env{
+		t:     t,
+		ports: make(map[string]int),
+		vars:  make(map[string]string),
+		dir:   t.TempDir(),
+	}
+}
+
+// Port returns the free port allocated for the name
+func (e *Env) Port(name string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if p, ok := e.ports[name]; ok {
+		return p
+	}
+
+	p, err := freePort()
+	if err != nil {
+		e.t.Fatalf("failed to allocate a port for %s: %v", name, err)
+	}
+
+	e.ports[name] = p
+	return p
+}
+
+// Addr returns 127.0.0.1:port for the name
+func (e *Env) Addr(name string) string {
+	return net.JoinHostPort(localhost, strconv.Itoa(e.Port(name)))
+}
+
+// RPCAddr returns the address for the RPC plugin ({{ addr "rpc" }})
+func (e *Env) RPCAddr() string {
+	return e.Addr(rpcPort)
+}
+
+// TempDir returns the temporary directory of the environment
+func (e *Env) TempDir() string {
+	return e.dir
+}
+
+// Set sets the variable available in templates as {{ var "name" }}
+func (e *Env) Set(name, value string) {
+	e.mu.Lock()
+	e.vars[name] = value
+	e.mu.Unlock()
+}
+
+// Var returns the variable set via Set
+func (e *Env) Var(name string) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	v, ok := e.vars[name]
+	if !ok {
+		e.t.Fatalf("variable %s is not set", name)
+	}
+
+	return v
+}
+
+// Render renders the config template and returns the path to the rendered file
+func (e *Env) Render(path string) string {
+	e.t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		e.t.Fatalf("failed to read the config %s: %v", path, err)
+	}
+
+	tmpl, err := template.New(filepath.Base(path)).Funcs(template.FuncMap{
+		"port":    e.Port,
+		"addr":    e.Addr,
+		"tempdir": e.TempDir,
+		"var":     e.Var,
+	}).Parse(string(data))
+	if err != nil {
+		e.t.Fatalf("failed to parse the config template %s: %v", path, err)
+	}
+
+	buf := new(bytes.Buffer)
+	err = tmpl.Execute(buf, nil)
+	if err != nil {
+		e.t.Fatalf("failed to render the config template %s: %v", path, err)
+	}
+
+	// keep the extension, the config plugin detects the format by it
+	out := filepath.Join(e.dir, filepath.Base(path))
+	err = os.WriteFile(out, buf.Bytes(), 0600)
+	if err != nil {
+		e.t.Fatalf("failed to write the rendered config: %v", err)
+	}
+
+	return out
+}
+
+// Options sets the endure options (endure.GracefulShutdownTimeout, etc) of the containers started by this Env
+func (e *Env) Options(opts ...endure.Options) *Env {
+	e.opts = append(e.opts, opts...)
+
+	return e
+}
+
+// Start renders the config and starts the container, see Start
+func (e *Env) Start(cfgPath string, plugins ...interface{}) *Harness {
+	e.t.Helper()
+
+	return e.StartConfig(&config.Plugin{
+		Path:   cfgPath,
+		Prefix: prefix,
+	}, plugins...)
+}
+
+// StartConfig renders cfg.Path and starts the container, see StartConfig. The inline configs (ReadInCfg) are used as is.
+func (e *Env) StartConfig(cfg *config.Plugin, plugins ...interface{}) *Harness {
+	e.t.Helper()
+
+	if cfg.Path != "" {
+		cfg.Path = e.Render(cfg.Path)
+	}
+	h := start(e.t, cfg, e.opts, plugins...)
+	h.env = e
+
+	return h
+}
+
+func freePort() (int, error) {
+	allocatedMu.Lock()
+	defer allocatedMu.Unlock()
+
+	for {
+		l, err := net.Listen("tcp", net.JoinHostPort(localhost, "0"))
+		if err != nil {
+			return 0, err
+		}
+
+		p := l.Addr().(*net.TCPAddr).Port
+		err = l.Close()
+		if err != nil {
+			return 0, err
+		}
+
+		if _, ok := allocated[p]; ok {
+			continue
+		}
+
+		allocated[p] = struct{}{}
+		return p, nil
+	}
+}
@@ -0,0 +1,49 @@
+package harness
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvRender(t *testing.T) {
+	env := NewEnv(t)
+	env.Set("redis", "127.0.0.1:6379")
+
+	tmpl := filepath.Join(t.TempDir(), ".rr-test.yaml")
+	require.NoError(t, os.WriteFile(tmpl, []byte(`rpc:
+  listen: tcp://{{ addr "rpc" }}
+http:
+  address: 127.0.0.1:{{ port "http" }}
+boltdb:
+  file: {{ tempdir }}/rr.db
+redis:
+  addrs: [ "{{ var "redis" }}" ]
+`), 0600))
+
+	out := env.Render(tmpl)
+	assert.Equal(t, ".rr-test.yaml", filepath.Base(out))
+
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(data), "tcp://"+env.RPCAddr())
+	assert.Contains(t, string(data), "127.0.0.1:"+strconv.Itoa(env.Port("http")))
+	assert.Contains(t, string(data), env.TempDir()+"/rr.db")
+	assert.Contains(t, string(data), `[ "127.0.0.1:6379" ]`)
+	assert.NotEqual(t, env.Port("rpc"), env.Port("http"))
+}
+
+func TestEnvPortsAreUnique(t *testing.T) {
+	seen := make(map[int]struct{})
+	for i := 0; i < 10; i++ {
+		p := NewEnv(t).Port("rpc")
+		_, ok := seen[p]
+		require.False(t, ok, "port %d allocated twice", p)
+		seen[p] = struct{}{}
+	}
+}
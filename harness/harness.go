@@ -0,0 +1,160 @@
+package harness
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/config/v2"
+	endure "github.com/roadrunner-server/endure/pkg/container"
+)
+
+const (
+	// DefaultStopTimeout is the time given to the container to stop all vertices
+	DefaultStopTimeout time.Duration = time.Second * 30
+	prefix             string        = "rr"
+)
+
+// Harness owns the lifecycle of the endure container used in a test
+type Harness struct {
+	t    testing.TB
+	env  *Env
+	cont *endure.Endure
+
+	stopTimeout time.Duration
+	stopCh      chan struct{}
+	stopOnce    sync.Once
+	wg          sync.WaitGroup
+}
+
+// Start renders the config template (located at cfgPath) with the new Env, registers it and the plugins in the new
+// container, initializes and serves it. The container is stopped automatically when the test finishes, or earlier via Stop.
+func Start(t testing.TB, cfgPath string, plugins ...interface{}) *Harness {
+	t.Helper()
+
+	return NewEnv(t).Start(cfgPath, plugins...)
+}
+
+// StartConfig is the same as Start, but accepts the already configured config plugin (version, timeout, etc)
+func StartConfig(t testing.TB, cfg *config.Plugin, plugins ...interface{}) *Harness {
+	t.Helper()
+
+	return NewEnv(t).StartConfig(cfg, plugins...)
+}
+
+func start(t testing.TB, cfg *config.Plugin, opts []endure.Options, plugins ...interface{}) *Harness {
+	t.Helper()
+
+	if cfg.Prefix == "" {
+		cfg.Prefix = prefix
+	}
+
+	// the passed options go last to override the defaults
+	cont, err := endure.NewContainer(nil, append([]endure.Options{endure.SetLogLevel(endure.ErrorLevel)}, opts...)...)
+	if err != nil {
+		t.Fatalf("failed to create the container: %v", err)
+	}
+
+	err = cont.RegisterAll(append([]interface{}{cfg}, plugins...)...)
+	if err != nil {
+		t.Fatalf("failed to register plugins: %v", err)
+	}
+
+	err = cont.Init()
+	if err != nil {
+		_ = cont.Stop()
+		t.Fatalf("failed to init the container: %v", err)
+	}
+
+	errCh, err := cont.Serve()
+	if err != nil {
+		// stop the vertices served before the failed one
+		_ = cont.Stop()
+		t.Fatalf("failed to serve the container: %v", err)
+	}
+
+	h := &Harness{
+		t:           t,
+		cont:        cont,
+		stopTimeout: DefaultStopTimeout,
+		stopCh:      make(chan struct{}),
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		defer signal.Stop(sig)
+
+		// endure stops the whole graph on the vertex error (unless it retries), the following stop may fail then
+		failed := false
+		for {
+			select {
+			case e := <-errCh:
+				// don't stop here, the test decides what to do with the failed vertex
+				t.Errorf("got error from vertex: %s, error: %v", e.VertexID, e.Error)
+				failed = true
+			case <-sig:
+				h.stop(failed)
+				return
+			case <-h.stopCh:
+				h.stop(failed)
+				return
+			}
+		}
+	}()
+
+	t.Cleanup(h.Stop)
+
+	return h
+}
+
+// SetStopTimeout overrides DefaultStopTimeout for this harness
+func (h *Harness) SetStopTimeout(timeout time.Duration) {
+	h.stopTimeout = timeout
+}
+
+// Env returns the environment used to render the config
+func (h *Harness) Env() *Env {
+	return h.env
+}
+
+// RPCAddr returns the address of the RPC plugin, shortcut for Env().RPCAddr()
+func (h *Harness) RPCAddr() string {
+	return h.env.RPCAddr()
+}
+
+// Container returns the underlying endure container
+func (h *Harness) Container() *endure.Endure {
+	return h.cont
+}
+
+// Stop stops the container and waits for it. Safe to call multiple times.
+func (h *Harness) Stop() {
+	h.stopOnce.Do(func() {
+		close(h.stopCh)
+	})
+	h.wg.Wait()
+}
+
+// stop stops the container, the stop error is not reported after the vertex error (failed)
+func (h *Harness) stop(failed bool) {
+	done := make(chan error, 1)
+	go func() {
+		done <- h.cont.Stop()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil && !failed {
+			h.t.Errorf("failed to stop the container: %v", err)
+		}
+	case <-time.After(h.stopTimeout):
+		h.t.Errorf("container was not stopped in %s", h.stopTimeout)
+	}
+}
@@ -0,0 +1,120 @@
+package harness
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/config/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingT records the errors reported by the harness instead of failing the test, Fatalf still fails it
+type recordingT struct {
+	testing.TB
+
+	mu     sync.Mutex
+	errors []string
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.mu.Lock()
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+	r.mu.Unlock()
+}
+
+func (r *recordingT) failures() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.errors...)
+}
+
+// failingPlugin reports the error shortly after it is served, the error returned right away fails the serve instead
+type failingPlugin struct{}
+
+func (p *failingPlugin) Init() error {
+	return nil
+}
+
+func (p *failingPlugin) Serve() chan error {
+	errCh := make(chan error, 1)
+	go func() {
+		time.Sleep(time.Millisecond * 100)
+		errCh <- errors.New("failed to serve")
+	}()
+	return errCh
+}
+
+func (p *failingPlugin) Stop() error {
+	return nil
+}
+
+// stuckPlugin doesn't stop until released
+type stuckPlugin struct {
+	release chan struct{}
+}
+
+func (p *stuckPlugin) Init() error {
+	return nil
+}
+
+func (p *stuckPlugin) Serve() chan error {
+	return make(chan error, 1)
+}
+
+func (p *stuckPlugin) Stop() error {
+	<-p.release
+	return nil
+}
+
+func testConfig(t *testing.T) *config.Plugin {
+	path := filepath.Join(t.TempDir(), ".rr-harness.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("version: '2.7'\n"), 0600))
+
+	return &config.Plugin{
+		Path:    path,
+		Prefix:  "rr",
+		Version: "2.9.0",
+	}
+}
+
+func TestHarnessVertexError(t *testing.T) {
+	rt := &recordingT{TB: t}
+	h := StartConfig(rt, testConfig(t), &failingPlugin{})
+
+	require.Eventually(t, func() bool {
+		return len(rt.failures()) > 0
+	}, time.Second*10, time.Millisecond*10)
+	h.Stop()
+
+	failures := rt.failures()
+	require.Len(t, failures, 1, failures)
+	assert.Contains(t, failures[0], "got error from vertex")
+	assert.Contains(t, failures[0], "failed to serve")
+}
+
+func TestHarnessStopTimeout(t *testing.T) {
+	rt := &recordingT{TB: t}
+	p := &stuckPlugin{release: make(chan struct{})}
+	defer close(p.release)
+
+	h := StartConfig(rt, testConfig(t), p)
+	h.SetStopTimeout(time.Millisecond * 200)
+
+	start := time.Now()
+	h.Stop()
+	assert.Less(t, time.Since(start), time.Second*5)
+	assert.Equal(t, []string{"container was not stopped in 200ms"}, rt.failures())
+
+	// the second stop doesn't wait again
+	h.Stop()
+	assert.Len(t, rt.failures(), 1)
+}
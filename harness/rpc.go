@@ -0,0 +1,30 @@
+package harness
+
+import (
+	"testing"
+
+	"github.com/roadrunner-server/rr-e2e-tests/rpcclient"
+)
+
+// Dial connects to the RPC plugin listening on the addr, the connection is closed when the test finishes
+func Dial(t testing.TB, addr string) *rpcclient.Client {
+	t.Helper()
+
+	client, err := rpcclient.Dial(addr)
+	if err != nil {
+		t.Fatalf("failed to connect to the rpc server %s: %v", addr, err)
+	}
+
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	return client
+}
+
+// RPC connects to the RPC plugin of the harness, see Dial
+func (h *Harness) RPC() *rpcclient.Client {
+	h.t.Helper()
+
+	return Dial(h.t, h.RPCAddr())
+}
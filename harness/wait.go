@@ -0,0 +1,202 @@
+package harness
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	jobsv1beta "github.com/roadrunner-server/api/v2/proto/jobs/v1"
+	"github.com/roadrunner-server/api/v2/state/process"
+	"github.com/roadrunner-server/rr-e2e-tests/rpcclient"
+)
+
+const (
+	// DefaultWaitTimeout is a reasonable deadline for the most of the readiness checks
+	DefaultWaitTimeout time.Duration = time.Second * 30
+
+	pollInterval time.Duration = time.Millisecond * 50
+	dialTimeout  time.Duration = time.Second
+	// callTimeout limits every RPC attempt of the polls, so the hung server doesn't block them past the timeout
+	callTimeout time.Duration = time.Second * 2
+)
+
+// Eventually polls cond until it returns true or the timeout is reached. The last error returned by cond is
+// included into the failure message together with the description.
+func Eventually(t testing.TB, timeout time.Duration, desc string, cond func() (bool, error)) {
+	t.Helper()
+
+	var lastErr error
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := cond()
+		if ok {
+			return
+		}
+		lastErr = err
+
+		if time.Now().After(deadline) {
+			if lastErr != nil {
+				t.Fatalf("%s: not satisfied in %s, last error: %v", desc, timeout, lastErr)
+			}
+			t.Fatalf("%s: not satisfied in %s", desc, timeout)
+		}
+
+		time.Sleep(pollInterval)
+	}
+}
+
+// WaitForTCP waits until the addr accepts TCP connections
+func WaitForTCP(t testing.TB, addr string, timeout time.Duration) {
+	t.Helper()
+
+	Eventually(t, timeout, fmt.Sprintf("tcp endpoint %s", addr), func() (bool, error) {
+		conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+		if err != nil {
+			return false, err
+		}
+		_ = conn.Close()
+		return true, nil
+	})
+}
+
+// WaitForHTTP waits until the url responds with any status code
+func WaitForHTTP(t testing.TB, url string, timeout time.Duration) {
+	t.Helper()
+
+	client := &http.Client{Timeout: dialTimeout}
+	Eventually(t, timeout, fmt.Sprintf("http endpoint %s", url), func() (bool, error) {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+		if err != nil {
+			return false, err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return false, err
+		}
+		_ = resp.Body.Close()
+		return true, nil
+	})
+}
+
+// WaitForRPC waits until the RPC server on the addr serves the requests (informer.List), the informer plugin should
+// be registered
+func WaitForRPC(t testing.TB, addr string, timeout time.Duration) {
+	t.Helper()
+
+	Eventually(t, timeout, fmt.Sprintf("rpc endpoint %s", addr), func() (bool, error) {
+		client, err := dialRPC(addr)
+		if err != nil {
+			return false, err
+		}
+		defer func() {
+			_ = client.Close()
+		}()
+
+		_, err = client.Informer.List()
+		if err != nil {
+			return false, err
+		}
+
+		return true, nil
+	})
+}
+
+// WaitForWorkers waits until the plugin reports (via informer.Workers) exactly n workers in the ready state
+func WaitForWorkers(t testing.TB, rpcAddr, plugin string, n int, timeout time.Duration) {
+	t.Helper()
+
+	Eventually(t, timeout, fmt.Sprintf("%d ready workers in the %s plugin", n, plugin), func() (bool, error) {
+		workers, err := informerWorkers(rpcAddr, plugin)
+		if err != nil {
+			return false, err
+		}
+
+		ready := 0
+		for i := 0; i < len(workers); i++ {
+			if workers[i].Status == "ready" {
+				ready++
+			}
+		}
+
+		if ready != n {
+			return false, fmt.Errorf("%d of %d workers are ready", ready, len(workers))
+		}
+
+		return true, nil
+	})
+}
+
+// WaitForJobsStat waits until the cond returns true for the jobs.Stat response
+func WaitForJobsStat(t testing.TB, rpcAddr string, timeout time.Duration, cond func(stats []*jobsv1beta.Stat) bool) {
+	t.Helper()
+
+	Eventually(t, timeout, "jobs.Stat condition", func() (bool, error) {
+		stats, err := jobsStat(rpcAddr)
+		if err != nil {
+			return false, err
+		}
+
+		if !cond(stats) {
+			return false, fmt.Errorf("last stat: %v", stats)
+		}
+
+		return true, nil
+	})
+}
+
+// PipelineStat returns a condition for WaitForJobsStat which checks the state of the particular pipeline
+func PipelineStat(pipeline string, cond func(st *jobsv1beta.Stat) bool) func(stats []*jobsv1beta.Stat) bool {
+	return func(stats []*jobsv1beta.Stat) bool {
+		for i := 0; i < len(stats); i++ {
+			if stats[i].GetPipeline() == pipeline {
+				return cond(stats[i])
+			}
+		}
+
+		return false
+	}
+}
+
+func informerWorkers(addr, plugin string) ([]*process.State, error) {
+	client, err := dialRPC(addr)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	return client.Informer.Workers(plugin)
+}
+
+func jobsStat(addr string) ([]*jobsv1beta.Stat, error) {
+	client, err := dialRPC(addr)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	return client.Jobs.Stat()
+}
+
+// dialRPC connects to the RPC server, the calls over the connection fail after callTimeout
+func dialRPC(addr string) (*rpcclient.Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	err = conn.SetDeadline(time.Now().Add(callTimeout))
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return rpcclient.New(conn), nil
+}
@@ -0,0 +1,70 @@
+package harness
+
+import (
+	"net"
+	"net/rpc"
+	"testing"
+	"time"
+
+	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeInformer struct{}
+
+func (fakeInformer) List(_ bool, out *[]string) error {
+	*out = []string{"http"}
+	return nil
+}
+
+func listen(t *testing.T, serve func(conn net.Conn)) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func TestWaitForRPC(t *testing.T) {
+	srv := rpc.NewServer()
+	require.NoError(t, srv.RegisterName("informer", fakeInformer{}))
+
+	addr := listen(t, func(conn net.Conn) {
+		srv.ServeCodec(goridgeRpc.NewCodec(conn))
+	})
+
+	WaitForRPC(t, addr, time.Second*5)
+}
+
+func TestDialRPCDeadline(t *testing.T) {
+	// accepts the connections and never responds
+	addr := listen(t, func(conn net.Conn) {
+		t.Cleanup(func() {
+			_ = conn.Close()
+		})
+	})
+
+	client, err := dialRPC(addr)
+	require.NoError(t, err)
+	defer func() {
+		_ = client.Close()
+	}()
+
+	start := time.Now()
+	_, err = client.Informer.List()
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), callTimeout+time.Second)
+}
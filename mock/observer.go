@@ -21,6 +21,8 @@ package mock_logger //nolint:stylecheck
 // THE SOFTWARE.
 
 import (
+	"context"
+	"fmt"
 	"strings"
 	"sync"
 	"time"
@@ -48,6 +50,11 @@ func (e LoggedEntry) ContextMap() map[string]interface{} {
 type ObservedLogs struct {
 	mu   sync.RWMutex
 	logs []LoggedEntry
+	// taken is the number of entries removed by TakeAll, the sequence number of logs[i] is taken+i
+	taken uint64
+	// notify is closed (and reset) every time a new entry is added
+	notify chan struct{}
+	subs   map[chan LoggedEntry]struct{}
 }
 
 // Len returns the number of items in the collection.
@@ -74,6 +81,7 @@ func (o *ObservedLogs) All() []LoggedEntry {
 func (o *ObservedLogs) TakeAll() []LoggedEntry {
 	o.mu.Lock()
 	ret := o.logs
+	o.taken += uint64(len(o.logs))
 	o.logs = nil
 	o.mu.Unlock()
 	return ret
@@ -99,28 +107,17 @@ func (o *ObservedLogs) FilterLevelExact(level zapcore.Level) *ObservedLogs {
 
 // FilterMessage filters entries to those that have the specified message.
 func (o *ObservedLogs) FilterMessage(msg string) *ObservedLogs {
-	return o.Filter(func(e LoggedEntry) bool {
-		return e.Message == msg
-	})
+	return o.Filter(Message(msg))
 }
 
 // FilterMessageSnippet filters entries to those that have a message containing the specified snippet.
 func (o *ObservedLogs) FilterMessageSnippet(snippet string) *ObservedLogs {
-	return o.Filter(func(e LoggedEntry) bool {
-		return strings.Contains(e.Message, snippet)
-	})
+	return o.Filter(MessageSnippet(snippet))
 }
 
 // FilterField filters entries to those that have the specified field.
 func (o *ObservedLogs) FilterField(field zapcore.Field) *ObservedLogs {
-	return o.Filter(func(e LoggedEntry) bool {
-		for _, ctxField := range e.Context {
-			if ctxField.Equals(field) {
-				return true
-			}
-		}
-		return false
-	})
+	return o.Filter(Field(field))
 }
 
 // FilterFieldKey filters entries to those that have the specified key.
@@ -150,12 +147,150 @@ func (o *ObservedLogs) Filter(keep func(LoggedEntry) bool) *ObservedLogs {
 	return &ObservedLogs{logs: filtered}
 }
 
+// Subscribe returns a channel which receives every entry logged after the call, and a function to cancel the
+// subscription (it also closes the channel). The logger never blocks on a subscriber, entries are dropped
+// when the channel buffer of the provided size is full.
+func (o *ObservedLogs) Subscribe(size int) (<-chan LoggedEntry, func()) {
+	ch := make(chan LoggedEntry, size)
+
+	o.mu.Lock()
+	if o.subs == nil {
+		o.subs = make(map[chan LoggedEntry]struct{})
+	}
+	o.subs[ch] = struct{}{}
+	o.mu.Unlock()
+
+	once := sync.Once{}
+	return ch, func() {
+		once.Do(func() {
+			o.mu.Lock()
+			delete(o.subs, ch)
+			close(ch)
+			o.mu.Unlock()
+		})
+	}
+}
+
+// WaitFor blocks until an entry matching the predicate is observed (including already observed entries)
+// or the context is canceled. Returns the first matched entry.
+func (o *ObservedLogs) WaitFor(ctx context.Context, predicate func(LoggedEntry) bool) (LoggedEntry, error) {
+	// sequence number of the next entry to check
+	next := uint64(0)
+	for {
+		o.mu.Lock()
+		for _, entry := range o.unseen(&next) {
+			if predicate(entry) {
+				o.mu.Unlock()
+				return entry, nil
+			}
+		}
+		changed := o.changed()
+		o.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return LoggedEntry{}, ctx.Err()
+		}
+	}
+}
+
+// WaitForCount blocks until at least n entries matching the filter are observed or the timeout is reached.
+func (o *ObservedLogs) WaitForCount(filter func(LoggedEntry) bool, n int, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	found := 0
+	// sequence number of the next entry to check
+	next := uint64(0)
+	for {
+		o.mu.Lock()
+		for _, entry := range o.unseen(&next) {
+			if filter(entry) {
+				found++
+			}
+		}
+		changed := o.changed()
+		o.mu.Unlock()
+
+		if found >= n {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return fmt.Errorf("observed %d of %d expected entries in %s", found, n, timeout)
+		}
+	}
+}
+
+// unseen returns the entries starting from the sequence number next and moves next past them, the entries removed
+// by TakeAll are skipped. Should be called under the lock.
+func (o *ObservedLogs) unseen(next *uint64) []LoggedEntry {
+	if *next < o.taken {
+		*next = o.taken
+	}
+
+	entries := o.logs[*next-o.taken:]
+	*next = o.taken + uint64(len(o.logs))
+
+	return entries
+}
+
+// changed returns the channel closed on the next added entry, should be called under the lock
+func (o *ObservedLogs) changed() chan struct{} {
+	if o.notify == nil {
+		o.notify = make(chan struct{})
+	}
+
+	return o.notify
+}
+
 func (o *ObservedLogs) add(log LoggedEntry) {
 	o.mu.Lock()
 	o.logs = append(o.logs, log)
+
+	if o.notify != nil {
+		close(o.notify)
+		o.notify = nil
+	}
+
+	for ch := range o.subs {
+		select {
+		case ch <- log:
+		default:
+		}
+	}
 	o.mu.Unlock()
 }
 
+// MessageSnippet returns a filter matching the entries with a message containing the snippet.
+func MessageSnippet(snippet string) func(LoggedEntry) bool {
+	return func(e LoggedEntry) bool {
+		return strings.Contains(e.Message, snippet)
+	}
+}
+
+// Message returns a filter matching the entries with exactly the same message.
+func Message(msg string) func(LoggedEntry) bool {
+	return func(e LoggedEntry) bool {
+		return e.Message == msg
+	}
+}
+
+// Field returns a filter matching the entries having the field.
+func Field(field zapcore.Field) func(LoggedEntry) bool {
+	return func(e LoggedEntry) bool {
+		for _, ctxField := range e.Context {
+			if ctxField.Equals(field) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
 // New creates a new Core that buffers logs in memory (without any encoding).
 // It's particularly useful in tests.
 func New(enab zapcore.LevelEnabler) (zapcore.Core, *ObservedLogs) {
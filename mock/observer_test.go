@@ -0,0 +1,101 @@
+package mock_logger //nolint:stylecheck
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestWaitFor(t *testing.T) {
+	l, logs := ZapTestLogger(zap.DebugLevel)
+
+	go func() {
+		time.Sleep(time.Millisecond * 100)
+		l.ProvideZapLogger().Info("plugin was started", zap.String("pipeline", "test-1"))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	entry, err := logs.WaitFor(ctx, MessageSnippet("was started"))
+	require.NoError(t, err)
+	assert.Equal(t, "test-1", entry.ContextMap()["pipeline"])
+
+	// already observed entries should be matched immediately
+	entry, err = logs.WaitFor(ctx, Message("plugin was started"))
+	require.NoError(t, err)
+	assert.Equal(t, "plugin was started", entry.Message)
+}
+
+func TestWaitForTimeout(t *testing.T) {
+	_, logs := ZapTestLogger(zap.DebugLevel)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+
+	_, err := logs.WaitFor(ctx, MessageSnippet("never"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestWaitForCount(t *testing.T) {
+	l, logs := ZapTestLogger(zap.DebugLevel)
+
+	go func() {
+		for i := 0; i < 10; i++ {
+			l.ProvideZapLogger().Debug("job processing was started")
+			l.ProvideZapLogger().Debug("job was processed successfully")
+		}
+	}()
+
+	require.NoError(t, logs.WaitForCount(MessageSnippet("job processing was started"), 10, time.Second*5))
+	require.Error(t, logs.WaitForCount(MessageSnippet("job processing was started"), 11, time.Millisecond*100))
+}
+
+func TestWaitForCountTakeAll(t *testing.T) {
+	l, logs := ZapTestLogger(zap.DebugLevel)
+
+	for i := 0; i < 3; i++ {
+		l.ProvideZapLogger().Debug("before")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- logs.WaitForCount(MessageSnippet("after"), 3, time.Second*5)
+	}()
+
+	// the entries logged after the truncation should not be skipped even when there are as many as before it
+	time.Sleep(time.Millisecond * 100)
+	logs.TakeAll()
+	for i := 0; i < 3; i++ {
+		l.ProvideZapLogger().Debug("after")
+	}
+
+	require.NoError(t, <-done)
+}
+
+func TestSubscribe(t *testing.T) {
+	l, logs := ZapTestLogger(zap.DebugLevel)
+
+	l.ProvideZapLogger().Info("before subscription")
+
+	ch, unsubscribe := logs.Subscribe(10)
+	l.ProvideZapLogger().Info("after subscription")
+
+	select {
+	case e := <-ch:
+		assert.Equal(t, "after subscription", e.Message)
+	case <-time.After(time.Second):
+		t.Fatal("entry was not delivered")
+	}
+
+	unsubscribe()
+	unsubscribe()
+
+	l.ProvideZapLogger().Info("after unsubscribe")
+	_, ok := <-ch
+	assert.False(t, ok)
+}
@@ -0,0 +1,154 @@
+package mock_logger //nolint:stylecheck
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// how many entries after the closest partial match are shown in the error
+const shownEntries int = 5
+
+// EntryMatcher matches an entry by the message snippet and the subset of the context fields.
+type EntryMatcher struct {
+	Snippet string
+	Fields  map[string]interface{}
+}
+
+// Entry creates EntryMatcher from the message snippet and key-value pairs of the expected fields.
+func Entry(snippet string, keyvals ...interface{}) EntryMatcher {
+	m := EntryMatcher{
+		Snippet: snippet,
+		Fields:  make(map[string]interface{}, len(keyvals)/2),
+	}
+
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		m.Fields[fmt.Sprint(keyvals[i])] = keyvals[i+1]
+	}
+
+	return m
+}
+
+// Match checks the entry against the matcher.
+func (m EntryMatcher) Match(e LoggedEntry) bool {
+	if !strings.Contains(e.Message, m.Snippet) {
+		return false
+	}
+
+	if len(m.Fields) == 0 {
+		return true
+	}
+
+	ctx := e.ContextMap()
+	for k, expected := range m.Fields {
+		actual, ok := ctx[k]
+		if !ok {
+			return false
+		}
+
+		// ints are stored as int64, durations as strings, etc. so compare the string representations as well
+		if !reflect.DeepEqual(expected, actual) && fmt.Sprint(expected) != fmt.Sprint(actual) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (m EntryMatcher) String() string {
+	if len(m.Fields) == 0 {
+		return fmt.Sprintf("%q", m.Snippet)
+	}
+
+	keys := make([]string, 0, len(m.Fields))
+	for k := range m.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fields := make([]string, 0, len(keys))
+	for _, k := range keys {
+		fields = append(fields, fmt.Sprintf("%s=%v", k, m.Fields[k]))
+	}
+
+	return fmt.Sprintf("%q {%s}", m.Snippet, strings.Join(fields, ", "))
+}
+
+// Sequence checks that the entries matching the matchers were logged in the same order. Any other entries
+// are allowed between the matched ones. The error describes the closest partial match.
+func (o *ObservedLogs) Sequence(matchers ...EntryMatcher) error {
+	logs := o.All()
+
+	matched := 0
+	last := -1
+	for i := 0; i < len(logs) && matched < len(matchers); i++ {
+		if matchers[matched].Match(logs[i]) {
+			matched++
+			last = i
+		}
+	}
+
+	if matched == len(matchers) {
+		return nil
+	}
+
+	return sequenceError(logs, matchers, matched, last)
+}
+
+// StrictSequence checks that the entries matching the matchers were logged one after another without any
+// other entries between them. Usually used on the filtered logs.
+func (o *ObservedLogs) StrictSequence(matchers ...EntryMatcher) error {
+	logs := o.All()
+
+	if len(matchers) == 0 {
+		return nil
+	}
+
+	best := 0
+	bestLast := -1
+	for start := 0; start < len(logs); start++ {
+		matched := 0
+		for matched < len(matchers) && start+matched < len(logs) && matchers[matched].Match(logs[start+matched]) {
+			matched++
+		}
+
+		if matched == len(matchers) {
+			return nil
+		}
+
+		if matched > best {
+			best = matched
+			bestLast = start + matched - 1
+		}
+	}
+
+	return sequenceError(logs, matchers, best, bestLast)
+}
+
+func sequenceError(logs []LoggedEntry, matchers []EntryMatcher, matched, last int) error {
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("log sequence is not matched: matched %d of %d entries", matched, len(matchers)))
+	if last >= 0 {
+		sb.WriteString(fmt.Sprintf(", last matched entry #%d %q", last, logs[last].Message))
+	}
+	sb.WriteString(fmt.Sprintf(", next expected: %s", matchers[matched]))
+
+	next := logs[last+1:]
+	if len(next) > shownEntries {
+		next = next[:shownEntries]
+	}
+
+	if len(next) == 0 {
+		sb.WriteString(", no entries were observed after")
+		return errors.New(sb.String())
+	}
+
+	sb.WriteString(", observed after:")
+	for i := 0; i < len(next); i++ {
+		sb.WriteString(fmt.Sprintf(" #%d %q %v;", last+1+i, next[i].Message, next[i].ContextMap()))
+	}
+
+	return errors.New(sb.String())
+}
@@ -0,0 +1,56 @@
+package mock_logger //nolint:stylecheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSequence(t *testing.T) {
+	l, logs := ZapTestLogger(zap.DebugLevel)
+	zl := l.ProvideZapLogger()
+
+	zl.Info("pipeline was started", zap.String("pipeline", "test-1"))
+	zl.Info("job was pushed successfully", zap.String("pipeline", "test-1"))
+	zl.Info("pipeline was paused", zap.String("pipeline", "test-1"))
+	zl.Info("pipeline was paused", zap.String("pipeline", "test-2"))
+	zl.Info("pipeline was destroyed", zap.String("pipeline", "test-1"), zap.Int("attempt", 1))
+
+	require.NoError(t, logs.Sequence(
+		Entry("was started"),
+		Entry("was paused", "pipeline", "test-1"),
+		Entry("was destroyed", "pipeline", "test-1", "attempt", 1),
+	))
+
+	err := logs.Sequence(
+		Entry("was started"),
+		Entry("was destroyed"),
+		Entry("was paused", "pipeline", "test-1"),
+	)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "matched 2 of 3 entries")
+	assert.Contains(t, err.Error(), `next expected: "was paused" {pipeline=test-1}`)
+}
+
+func TestStrictSequence(t *testing.T) {
+	l, logs := ZapTestLogger(zap.DebugLevel)
+	zl := l.ProvideZapLogger()
+
+	zl.Info("worker stopped")
+	zl.Info("worker destructed")
+	zl.Info("worker respawned")
+
+	require.NoError(t, logs.StrictSequence(Entry("destructed"), Entry("respawned")))
+
+	err := logs.StrictSequence(Entry("stopped"), Entry("respawned"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "matched 1 of 2 entries")
+	assert.Contains(t, err.Error(), `#1 "worker destructed"`)
+
+	// with the filter applied, the gap disappears
+	require.NoError(t, logs.Filter(func(e LoggedEntry) bool {
+		return e.Message != "worker destructed"
+	}).StrictSequence(Entry("stopped"), Entry("respawned")))
+}
@@ -0,0 +1,132 @@
+// Package natsserver runs the embedded NATS server with JetStream enabled, so the nats jobs suites don't need the
+// nats container.
+package natsserver
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+// time to wait for the server to accept the connections
+const readyTimeout time.Duration = time.Second * 10
+
+// Server is the embedded NATS server with JetStream
+type Server struct {
+	srv *server.Server
+	dir string
+}
+
+// Listen starts the server on the addr (host:port, port 0 for the random one) with the JetStream store in the dir
+func Listen(addr, dir string) (*Server, error) {
+	host, port, err := hostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	srv, err := server.NewServer(&server.Options{
+		Host:      host,
+		Port:      port,
+		JetStream: true,
+		StoreDir:  dir,
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go srv.Start()
+
+	if !srv.ReadyForConnections(readyTimeout) {
+		srv.Shutdown()
+		return nil, errors.New("nats server is not ready for connections")
+	}
+
+	if !srv.JetStreamEnabled() {
+		srv.Shutdown()
+		return nil, errors.New("jetstream is not enabled")
+	}
+
+	return &Server{srv: srv, dir: dir}, nil
+}
+
+// Start starts the server on the random port with the temporary store, the server is stopped when the test finishes
+func Start(t testing.TB) *Server {
+	t.Helper()
+
+	s, err := Listen("127.0.0.1:0", t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to start the nats server: %v", err)
+	}
+
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// Addr returns host:port the server listens on
+func (s *Server) Addr() string {
+	return s.srv.Addr().String()
+}
+
+// URL returns the client URL, nats://host:port
+func (s *Server) URL() string {
+	return s.srv.ClientURL()
+}
+
+// StoreDir returns the JetStream store directory
+func (s *Server) StoreDir() string {
+	return s.dir
+}
+
+// Close stops the server and waits for the shutdown
+func (s *Server) Close() {
+	s.srv.Shutdown()
+	s.srv.WaitForShutdown()
+}
+
+// Streams returns the number of messages per stream in the default account
+func (s *Server) Streams() (map[string]uint64, error) {
+	info, err := s.srv.Jsz(&server.JSzOptions{Accounts: true, Streams: true})
+	if err != nil {
+		return nil, err
+	}
+
+	streams := make(map[string]uint64)
+	for i := 0; i < len(info.AccountDetails); i++ {
+		for j := 0; j < len(info.AccountDetails[i].Streams); j++ {
+			st := info.AccountDetails[i].Streams[j]
+			streams[st.Name] = st.State.Msgs
+		}
+	}
+
+	return streams, nil
+}
+
+// Connections returns the number of the connected clients
+func (s *Server) Connections() int {
+	return s.srv.NumClients()
+}
+
+func hostPort(addr string) (string, int, error) {
+	host, p, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", 0, err
+	}
+
+	port, err := net.LookupPort("tcp", p)
+	if err != nil {
+		return "", 0, err
+	}
+
+	// the nats server treats 0 as the default port (4222)
+	if port == 0 {
+		port = server.RANDOM_PORT
+	}
+
+	return host, port, nil
+}
@@ -0,0 +1,34 @@
+package natsserver
+
+import (
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJetStream(t *testing.T) {
+	s := Start(t)
+
+	conn, err := nats.Connect(s.URL())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	assert.Equal(t, 1, s.Connections())
+
+	js, err := conn.JetStream()
+	require.NoError(t, err)
+
+	_, err = js.AddStream(&nats.StreamConfig{Name: "foo", Subjects: []string{"default"}})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err = js.Publish("default", []byte("hello"))
+		require.NoError(t, err)
+	}
+
+	streams, err := s.Streams()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]uint64{"foo": 3}, streams)
+}
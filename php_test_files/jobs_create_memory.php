@@ -9,7 +9,7 @@ require __DIR__ . "/vendor/autoload.php";
 
 $jobs = new Spiral\RoadRunner\Jobs\Jobs(
     // Expects RPC connection
-    Spiral\Goridge\RPC\RPC::create('tcp://127.0.0.1:6001')
+    Spiral\Goridge\RPC\RPC::create(Spiral\RoadRunner\Environment::fromGlobals()->getRPCAddress())
 );
 
 //
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/rpc"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+	websocketsv1 "github.com/roadrunner-server/api/v2/proto/websockets/v1beta"
+	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hub is the fake websockets middleware with the broadcast RPC, registered as "broadcast"
+type hub struct {
+	mu     sync.Mutex
+	topics map[string][]*wsConn
+	// drop skips the deliveries of the messages with the payload prefix
+	drop func(payload string) bool
+}
+
+type wsConn struct {
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func (c *wsConn) write(v interface{}) {
+	d, _ := json.Marshal(v)
+	c.mu.Lock()
+	_ = wsutil.WriteServerText(c.conn, d)
+	c.mu.Unlock()
+}
+
+func (h *hub) Publish(in *websocketsv1.Request, out *websocketsv1.Response) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, msg := range in.GetMessages() {
+		if h.drop != nil && h.drop(string(msg.GetPayload())) {
+			continue
+		}
+		for _, topic := range msg.GetTopics() {
+			for _, c := range h.topics[topic] {
+				c.write(map[string]string{"topic": topic, "payload": string(msg.GetPayload())})
+			}
+		}
+	}
+	out.Ok = true
+
+	return nil
+}
+
+func (h *hub) PublishAsync(in *websocketsv1.Request, out *websocketsv1.Response) error {
+	return h.Publish(in, out)
+}
+
+func (h *hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, _, _, err := ws.UpgradeHTTP(r, w)
+	if err != nil {
+		return
+	}
+	c := &wsConn{conn: conn}
+
+	go func() {
+		defer func() {
+			_ = conn.Close()
+		}()
+		for {
+			d, err := wsutil.ReadClientText(conn)
+			if err != nil {
+				return
+			}
+
+			msg := &websocketsv1.Message{}
+			if json.Unmarshal(d, msg) != nil || msg.Command != "join" {
+				continue
+			}
+
+			h.mu.Lock()
+			for _, topic := range msg.Topics {
+				h.topics[topic] = append(h.topics[topic], c)
+			}
+			h.mu.Unlock()
+			c.write(map[string]interface{}{"topic": "@join", "payload": msg.Topics})
+		}
+	}()
+}
+
+// serveHub starts the fake websockets server and the goridge RPC server, returns the target
+func serveHub(t *testing.T, h *hub) target {
+	h.topics = make(map[string][]*wsConn)
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	rs := rpc.NewServer()
+	require.NoError(t, rs.RegisterName("broadcast", h))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go rs.ServeCodec(goridgeRpc.NewCodec(conn))
+		}
+	}()
+
+	return target{Name: "memory", RPCAddr: l.Addr().String(), URL: "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"}
+}
+
+func testConfig(t *testing.T, tg target, args ...string) *config {
+	cfg, err := parseFlags(append([]string{
+		"-target", tg.Name + "," + tg.RPCAddr + "," + tg.URL,
+		"-clients", "50",
+		"-topics", "5",
+		"-topics-per-client", "2",
+		"-rate", "200",
+		"-duration", "300ms",
+		"-grace", "2s",
+	}, args...))
+	require.NoError(t, err)
+
+	return cfg
+}
+
+func TestFanout(t *testing.T) {
+	tg := serveHub(t, &hub{})
+
+	for _, mode := range []string{modeSync, modeAsync} {
+		rep, err := run(context.Background(), testConfig(t, tg, "-mode", mode))
+		require.NoError(t, err)
+
+		tr := rep.Targets["memory"]
+		require.NotNil(t, tr)
+		assert.Equal(t, 50, tr.Connected)
+		// the arrivals are dropped when all publishers are busy
+		assert.Equal(t, uint64(60), tr.Publishes.Started+tr.Publishes.Dropped)
+		assert.Equal(t, tr.Publishes.Started, tr.Published)
+		assert.Greater(t, tr.Expected, uint64(0))
+		assert.Equal(t, tr.Expected, tr.Delivered)
+		assert.Zero(t, tr.Lost)
+		assert.Zero(t, tr.Duplicated)
+		assert.Zero(t, tr.Unexpected)
+		assert.Equal(t, float64(1), tr.Completeness)
+		assert.Equal(t, 50, tr.CompleteSubscribers)
+		assert.Equal(t, uint64(50), tr.Methods[opJoin].Calls)
+		assert.Equal(t, tr.Delivered, tr.Methods[opDelivery].Calls)
+		assert.LessOrEqual(t, tr.Methods[opFanout].Calls, tr.Published)
+		assert.Greater(t, tr.Methods[opFanout].Calls, uint64(0))
+		assert.GreaterOrEqual(t, tr.Methods[opFanout].Max, tr.Methods[opDelivery].P50)
+	}
+}
+
+func TestFanoutLost(t *testing.T) {
+	// every message with the sequence number ending with 0 is lost
+	tg := serveHub(t, &hub{drop: func(p string) bool {
+		num, _, _ := strings.Cut(p, "|")
+		return strings.HasSuffix(num, "0")
+	}})
+
+	rep, err := run(context.Background(), testConfig(t, tg, "-grace", "200ms"))
+	require.NoError(t, err)
+
+	tr := rep.Targets["memory"]
+	assert.Greater(t, tr.Lost, uint64(0))
+	assert.Equal(t, tr.Expected-tr.Delivered, tr.Lost)
+	assert.Less(t, tr.Completeness, float64(1))
+	assert.Less(t, tr.MinSubscriberCompleteness, float64(1))
+	assert.Less(t, tr.CompleteSubscribers, 50)
+}
+
+func TestParseFlags(t *testing.T) {
+	cfg, err := parseFlags(nil)
+	require.NoError(t, err)
+	require.Len(t, cfg.Targets, 1)
+	assert.Equal(t, "memory", cfg.Targets[0].Name)
+
+	cfg, err = parseFlags([]string{"-target", "memory,127.0.0.1:6001,ws://127.0.0.1:11111/ws", "-target", "redis,127.0.0.1:6002,ws://127.0.0.1:13235/ws"})
+	require.NoError(t, err)
+	assert.Len(t, cfg.Targets, 2)
+
+	for _, args := range [][]string{
+		{"-target", "memory,127.0.0.1:6001"},
+		{"-target", "memory,127.0.0.1:6001,http://127.0.0.1/ws"},
+		{"-target", "a,1,ws://x", "-target", "a,2,ws://y"},
+		{"-topics", "2", "-topics-per-client", "3"},
+		{"-mode", "fast"},
+		{"-rate", "0"},
+	} {
+		_, err = parseFlags(args)
+		assert.Error(t, err, strings.Join(args, " "))
+	}
+
+	assert.Equal(t, "12|aaa", string(payload(12, 6)))
+	seq, ok := parsePayload(json.RawMessage(`"12|aaa"`))
+	assert.True(t, ok)
+	assert.Equal(t, uint64(12), seq)
+	_, ok = parsePayload(json.RawMessage(`["foo"]`))
+	assert.False(t, ok)
+}
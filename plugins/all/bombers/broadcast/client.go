@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+	websocketsv1 "github.com/roadrunner-server/api/v2/proto/websockets/v1beta"
+	"github.com/roadrunner-server/rr-e2e-tests/plugins/all/bombers/load"
+)
+
+const (
+	// joinTimeout is how long the client waits for the @join confirmation
+	joinTimeout time.Duration = time.Second * 10
+	opJoin      string        = "join"
+	opDelivery  string        = "delivery"
+)
+
+// serverMessage is the message sent by the websockets middleware, the payload is the string for the published
+// messages and the list of the topics for @join
+type serverMessage struct {
+	Topic   string          `json:"topic"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// client is the websocket subscriber of the topics
+type client struct {
+	conn   net.Conn
+	topics []int
+	doneCh chan struct{}
+
+	// owned by the read loop until doneCh is closed
+	received   map[uint64]struct{}
+	duplicated uint64
+	unexpected uint64
+}
+
+func topicName(t int) string {
+	return "topic-" + strconv.Itoa(t)
+}
+
+// connect dials the websocket and joins the topics, the join latency includes the handshake
+func connect(ctx context.Context, cfg *config, name, addr string, topics []int, rec *load.Recorder) (*client, error) {
+	start := time.Now()
+	dialer := ws.Dialer{
+		Header:  ws.HandshakeHeaderHTTP(http.Header{"Origin": []string{cfg.Origin}}),
+		Timeout: joinTimeout,
+	}
+
+	conn, _, _, err := dialer.Dial(ctx, addr)
+	if err != nil {
+		rec.Record(name, opJoin, time.Since(start), err)
+		return nil, err
+	}
+
+	err = join(conn, topics)
+	rec.Record(name, opJoin, time.Since(start), err)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return &client{conn: conn, topics: topics, doneCh: make(chan struct{}), received: make(map[uint64]struct{})}, nil
+}
+
+// join sends the join command and waits for the confirmation
+func join(conn net.Conn, topics []int) error {
+	names := make([]string, len(topics))
+	for i := 0; i < len(topics); i++ {
+		names[i] = topicName(topics[i])
+	}
+
+	d, err := json.Marshal(&websocketsv1.Message{Command: "join", Topics: names})
+	if err != nil {
+		return err
+	}
+
+	_ = conn.SetDeadline(time.Now().Add(joinTimeout))
+	defer func() {
+		_ = conn.SetDeadline(time.Time{})
+	}()
+
+	err = wsutil.WriteClientText(conn, d)
+	if err != nil {
+		return err
+	}
+
+	for {
+		msg, err := wsutil.ReadServerText(conn)
+		if err != nil {
+			return err
+		}
+
+		sm := &serverMessage{}
+		err = json.Unmarshal(msg, sm)
+		if err != nil {
+			return err
+		}
+
+		switch sm.Topic {
+		case "@join":
+			return nil
+		case "#join":
+			return errJoinDenied
+		}
+	}
+}
+
+// read receives the messages until the connection is closed
+func (c *client) read(name string, f *fanout, rec *load.Recorder) {
+	defer close(c.doneCh)
+
+	for {
+		msg, err := wsutil.ReadServerText(c.conn)
+		if err != nil {
+			return
+		}
+		at := time.Now()
+
+		sm := &serverMessage{}
+		if json.Unmarshal(msg, sm) != nil || strings.HasPrefix(sm.Topic, "@") || strings.HasPrefix(sm.Topic, "#") {
+			continue
+		}
+
+		seq, ok := parsePayload(sm.Payload)
+		if !ok {
+			c.unexpected++
+			continue
+		}
+
+		if _, ok := c.received[seq]; ok {
+			c.duplicated++
+			continue
+		}
+
+		sent, ok := f.deliver(seq, at)
+		if !ok {
+			c.unexpected++
+			continue
+		}
+		c.received[seq] = struct{}{}
+		rec.Record(name, opDelivery, at.Sub(sent), nil)
+	}
+}
+
+// close closes the connection and waits for the read loop, safe to call more than once
+func (c *client) close() {
+	_ = c.conn.Close()
+	<-c.doneCh
+}
+
+// payload returns the message payload: the sequence number padded up to the size
+func payload(seq uint64, size int) []byte {
+	p := strconv.AppendUint(nil, seq, 10)
+	p = append(p, '|')
+	for len(p) < size {
+		p = append(p, 'a')
+	}
+
+	return p
+}
+
+func parsePayload(raw json.RawMessage) (uint64, bool) {
+	s := ""
+	if json.Unmarshal(raw, &s) != nil {
+		return 0, false
+	}
+
+	num, _, _ := strings.Cut(s, "|")
+	seq, err := strconv.ParseUint(num, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return seq, true
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	modeSync  string = "sync"
+	modeAsync string = "async"
+)
+
+// target is the RR instance with the websockets middleware, its broker is named by the target name
+type target struct {
+	Name    string `json:"name"`
+	RPCAddr string `json:"rpc_addr"`
+	URL     string `json:"url"`
+}
+
+// targets is the repeatable -target flag
+type targets []target
+
+func (ts *targets) String() string {
+	out := make([]string, 0, len(*ts))
+	for _, t := range *ts {
+		out = append(out, t.Name+","+t.RPCAddr+","+t.URL)
+	}
+
+	return strings.Join(out, " ")
+}
+
+// Set parses "name,rpc-addr,ws-url"
+func (ts *targets) Set(v string) error {
+	parts := strings.Split(v, ",")
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid target, want name,rpc-addr,ws-url: %s", v)
+	}
+
+	t := target{Name: strings.TrimSpace(parts[0]), RPCAddr: strings.TrimSpace(parts[1]), URL: strings.TrimSpace(parts[2])}
+	if t.Name == "" || t.RPCAddr == "" {
+		return fmt.Errorf("invalid target, want name,rpc-addr,ws-url: %s", v)
+	}
+
+	u, err := url.Parse(t.URL)
+	if err != nil || (u.Scheme != "ws" && u.Scheme != "wss") {
+		return fmt.Errorf("invalid websocket url: %s", t.URL)
+	}
+
+	for _, known := range *ts {
+		if known.Name == t.Name {
+			return fmt.Errorf("duplicate target: %s", t.Name)
+		}
+	}
+
+	*ts = append(*ts, t)
+	return nil
+}
+
+// config is the bomber configuration set via the flags
+type config struct {
+	Targets []target `json:"targets"`
+	Clients int      `json:"clients"`
+	// Topics is the number of the topics, every client joins TopicsPerClient random ones
+	Topics          int           `json:"topics"`
+	TopicsPerClient int           `json:"topics_per_client"`
+	Origin          string        `json:"origin"`
+	DialConcurrency int           `json:"dial_concurrency"`
+	Mode            string        `json:"mode"`
+	Rate            float64       `json:"rate"`
+	Duration        time.Duration `json:"duration_ns"`
+	Publishers      int           `json:"publishers"`
+	PayloadSize     int           `json:"payload_size"`
+	Grace           time.Duration `json:"grace_ns"`
+	Report          string        `json:"-"`
+}
+
+func parseFlags(args []string) (*config, error) {
+	cfg := &config{}
+	ts := targets{}
+
+	fs := flag.NewFlagSet("broadcast-bomber", flag.ContinueOnError)
+	fs.Var(&ts, "target", "RR instance \"name,rpc-addr,ws-url\", repeatable, the name labels its broker (default memory,127.0.0.1:6001,ws://127.0.0.1:11111/ws)")
+	fs.IntVar(&cfg.Clients, "clients", 1000, "websocket clients per target")
+	fs.IntVar(&cfg.Topics, "topics", 10, "number of the topics")
+	fs.IntVar(&cfg.TopicsPerClient, "topics-per-client", 1, "random topics joined by every client")
+	fs.StringVar(&cfg.Origin, "origin", "127.0.0.1", "Origin header of the websocket handshake")
+	fs.IntVar(&cfg.DialConcurrency, "dial-concurrency", 100, "clients connected and joined at the same time")
+	fs.StringVar(&cfg.Mode, "mode", modeSync, "sync (broadcast.Publish) or async (broadcast.PublishAsync)")
+	fs.Float64Var(&cfg.Rate, "rate", 100, "published messages per second per target (open loop)")
+	fs.DurationVar(&cfg.Duration, "duration", time.Second*30, "publish for the duration")
+	fs.IntVar(&cfg.Publishers, "publishers", 4, "RPC connections used to publish per target, limits the publishes in flight")
+	fs.IntVar(&cfg.PayloadSize, "payload", 64, "message payload size in bytes, at least the size of the message header")
+	fs.DurationVar(&cfg.Grace, "grace", time.Second*10, "wait up to this for the remaining deliveries at the end")
+	fs.StringVar(&cfg.Report, "report", "broadcast-report.json", "path of the JSON report, - for stdout")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ts) == 0 {
+		_ = ts.Set("memory,127.0.0.1:6001,ws://127.0.0.1:11111/ws")
+	}
+	cfg.Targets = ts
+
+	if cfg.Clients <= 0 || cfg.Topics <= 0 || cfg.TopicsPerClient <= 0 || cfg.DialConcurrency <= 0 || cfg.Publishers <= 0 {
+		return nil, errors.New("clients, topics, topics-per-client, dial-concurrency and publishers should be positive")
+	}
+	if cfg.TopicsPerClient > cfg.Topics {
+		return nil, errors.New("topics-per-client should not be greater than topics")
+	}
+	if cfg.Rate <= 0 || cfg.Duration <= 0 || cfg.Grace < 0 || cfg.PayloadSize < 0 {
+		return nil, errors.New("rate and duration should be positive, grace and payload should not be negative")
+	}
+
+	switch cfg.Mode {
+	case modeSync, modeAsync:
+	default:
+		return nil, fmt.Errorf("unknown mode: %s", cfg.Mode)
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// message is the published message waiting for the deliveries
+type message struct {
+	sent      time.Time
+	expected  int
+	delivered int
+	last      time.Time
+}
+
+// fanout tracks the published messages of a target and their deliveries to the subscribers
+type fanout struct {
+	mu       sync.Mutex
+	messages map[uint64]*message
+	// subscribers and the successfully published messages per topic
+	subscribers []int
+	published   []uint64
+	expected    uint64
+	delivered   uint64
+	doneCh      chan struct{}
+	publishing  bool
+}
+
+func newFanout(topics int) *fanout {
+	return &fanout{
+		messages:    make(map[uint64]*message),
+		subscribers: make([]int, topics),
+		published:   make([]uint64, topics),
+		doneCh:      make(chan struct{}),
+		publishing:  true,
+	}
+}
+
+// subscribe adds the subscriber of the topics, should be called before the publishing
+func (f *fanout) subscribe(topics []int) {
+	f.mu.Lock()
+	for _, t := range topics {
+		f.subscribers[t]++
+	}
+	f.mu.Unlock()
+}
+
+// publish registers the message before it is published, so the fast deliveries are not missed
+func (f *fanout) publish(seq uint64, topic int, sent time.Time) {
+	f.mu.Lock()
+	f.messages[seq] = &message{sent: sent, expected: f.subscribers[topic]}
+	f.published[topic]++
+	f.expected += uint64(f.subscribers[topic])
+	f.mu.Unlock()
+}
+
+// failed forgets the message which was not published, its deliveries are reported as unexpected
+func (f *fanout) failed(seq uint64, topic int) {
+	f.mu.Lock()
+	if m, ok := f.messages[seq]; ok {
+		delete(f.messages, seq)
+		f.published[topic]--
+		f.expected -= uint64(m.expected)
+		f.delivered -= uint64(m.delivered)
+	}
+	f.mu.Unlock()
+}
+
+// deliver records the first delivery of the message to a subscriber and returns the time it was sent,
+// false for the unknown messages
+func (f *fanout) deliver(seq uint64, at time.Time) (time.Time, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	m, ok := f.messages[seq]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	m.delivered++
+	if at.After(m.last) {
+		m.last = at
+	}
+	f.delivered++
+	f.checkDone()
+
+	return m.sent, true
+}
+
+// stopped marks the end of the publishing, wait returns once everything published is delivered
+func (f *fanout) stopped() {
+	f.mu.Lock()
+	f.publishing = false
+	f.checkDone()
+	f.mu.Unlock()
+}
+
+func (f *fanout) checkDone() {
+	if !f.publishing && f.delivered >= f.expected {
+		select {
+		case <-f.doneCh:
+		default:
+			close(f.doneCh)
+		}
+	}
+}
+
+// wait waits for all deliveries up to the timeout, false if some are still missing
+func (f *fanout) wait(timeout time.Duration) bool {
+	tt := time.NewTimer(timeout)
+	defer tt.Stop()
+
+	select {
+	case <-f.doneCh:
+		return true
+	case <-tt.C:
+		return false
+	}
+}
+
+// expectedFor returns the number of the messages published to the topics
+func (f *fanout) expectedFor(topics []int) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n := uint64(0)
+	for _, t := range topics {
+		n += f.published[t]
+	}
+
+	return n
+}
+
+// complete returns the time it took for every message to be delivered to all its subscribers,
+// the messages not delivered to everyone are skipped
+func (f *fanout) complete() []time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := make([]time.Duration, 0, len(f.messages))
+	for _, m := range f.messages {
+		if m.expected > 0 && m.delivered >= m.expected {
+			out = append(out, m.last.Sub(m.sent))
+		}
+	}
+
+	return out
+}
+
+// count returns the number of the received messages which were published
+func (f *fanout) count(received map[uint64]struct{}) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n := uint64(0)
+	for seq := range received {
+		if _, ok := f.messages[seq]; ok {
+			n++
+		}
+	}
+
+	return n
+}
+
+// deliveries returns the number of the deliveries so far
+func (f *fanout) deliveries() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.delivered
+}
@@ -0,0 +1,250 @@
+// Command broadcast is the load generator for the broadcast plugin and the websockets middleware. The websocket
+// clients join the random topics, then the messages are published via broadcast.Publish (or PublishAsync) to the
+// random topics at the open-loop rate. Every delivery is matched to the published message, so the report has the
+// delivery latency (publish to a subscriber), the fan-out latency (publish to the last subscriber) and the delivery
+// completeness per target and per subscriber. The targets are RR instances with different brokers (memory, redis),
+// they run one after another.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/roadrunner-server/rr-e2e-tests/plugins/all/bombers/load"
+	"github.com/roadrunner-server/rr-e2e-tests/rpcclient"
+)
+
+const (
+	publish      string = "broadcast.Publish"
+	publishAsync string = "broadcast.PublishAsync"
+	opFanout     string = "fanout"
+)
+
+var (
+	errJoinDenied   = errors.New("join denied")
+	errNotPublished = errors.New("not published")
+)
+
+func main() {
+	cfg, err := parseFlags(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	rep, err := run(ctx, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = load.WriteJSON(cfg.Report, rep)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context, cfg *config) (*Report, error) {
+	rec := load.NewRecorder()
+	rep := &Report{
+		Config:  cfg,
+		Started: time.Now(),
+		Targets: make(map[string]*TargetReport, len(cfg.Targets)),
+	}
+
+	for i := 0; i < len(cfg.Targets); i++ {
+		tr, err := runTarget(ctx, cfg, cfg.Targets[i], rec)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", cfg.Targets[i].Name, err)
+		}
+		rep.Targets[cfg.Targets[i].Name] = tr
+	}
+
+	rep.Finished = time.Now()
+	rep.Elapsed = rep.Finished.Sub(rep.Started).Seconds()
+
+	return rep, nil
+}
+
+// runTarget connects the clients, publishes for the duration and waits for the deliveries
+func runTarget(ctx context.Context, cfg *config, t target, rec *load.Recorder) (*TargetReport, error) {
+	f := newFanout(cfg.Topics)
+	clients := subscribe(ctx, cfg, t, f, rec)
+	defer func() {
+		for i := 0; i < len(clients); i++ {
+			clients[i].close()
+		}
+	}()
+
+	if len(clients) == 0 {
+		return nil, errors.New("no clients connected")
+	}
+
+	pool := make(chan *rpcclient.Client, cfg.Publishers)
+	for i := 0; i < cfg.Publishers; i++ {
+		client, err := rpcclient.Dial(t.RPCAddr)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = client.Close()
+		}()
+		pool <- client
+	}
+
+	method := publish
+	if cfg.Mode == modeAsync {
+		method = publishAsync
+	}
+
+	stopCh := make(chan struct{})
+	go load.Progress(t.Name+" DELIVERED", f.deliveries, stopCh)
+
+	seq := uint64(0)
+	started := time.Now()
+	paced := load.Pace(ctx, []load.Phase{load.Constant(cfg.Duration, cfg.Rate)}, cfg.Publishers, func(scheduled time.Time) {
+		client := <-pool
+		defer func() {
+			pool <- client
+		}()
+
+		n := atomic.AddUint64(&seq, 1)
+		topic := rand.Intn(cfg.Topics) //nolint:gosec
+		msg := rpcclient.Message(payload(n, cfg.PayloadSize), topicName(topic))
+
+		// registered before the publish, the deliveries may come before it returns
+		f.publish(n, topic, scheduled)
+
+		var ok bool
+		var err error
+		if cfg.Mode == modeAsync {
+			ok, err = client.Broadcast.PublishAsync(msg)
+		} else {
+			ok, err = client.Broadcast.Publish(msg)
+		}
+		if err == nil && !ok {
+			err = errNotPublished
+		}
+		rec.Record(t.Name, method, time.Since(scheduled), err)
+
+		if err != nil {
+			f.failed(n, topic)
+		}
+	})
+
+	rec.Drop(t.Name, method, paced.Dropped)
+
+	f.stopped()
+	if !f.wait(cfg.Grace) {
+		log.Printf("%s: not all messages were delivered in %s", t.Name, cfg.Grace)
+	}
+	close(stopCh)
+	elapsed := time.Since(started).Seconds()
+
+	for i := 0; i < len(clients); i++ {
+		clients[i].close()
+	}
+
+	for _, d := range f.complete() {
+		rec.Record(t.Name, opFanout, d, nil)
+	}
+
+	tr := &TargetReport{
+		Clients:                   cfg.Clients,
+		Connected:                 len(clients),
+		Publishes:                 paced,
+		MinSubscriberCompleteness: 1,
+	}
+
+	f.mu.Lock()
+	for _, p := range f.published {
+		tr.Published += p
+	}
+	tr.Expected = f.expected
+	tr.Delivered = f.delivered
+	f.mu.Unlock()
+
+	for i := 0; i < len(clients); i++ {
+		c := clients[i]
+		tr.Duplicated += c.duplicated
+		tr.Unexpected += c.unexpected
+
+		expected := f.expectedFor(c.topics)
+		completeness := float64(1)
+		if expected > 0 {
+			completeness = float64(f.count(c.received)) / float64(expected)
+		}
+		if completeness >= 1 {
+			tr.CompleteSubscribers++
+		}
+		if completeness < tr.MinSubscriberCompleteness {
+			tr.MinSubscriberCompleteness = completeness
+		}
+	}
+
+	if tr.Expected > tr.Delivered {
+		tr.Lost = tr.Expected - tr.Delivered
+	}
+	tr.Completeness = 1
+	if tr.Expected > 0 {
+		tr.Completeness = float64(tr.Delivered) / float64(tr.Expected)
+	}
+	tr.Throughput = float64(tr.Delivered) / elapsed
+	tr.Methods = rec.Reports(t.Name)
+
+	return tr, nil
+}
+
+// subscribe connects the clients, at most cfg.DialConcurrency at once, the failed clients are skipped
+func subscribe(ctx context.Context, cfg *config, t target, f *fanout, rec *load.Recorder) []*client {
+	mu := &sync.Mutex{}
+	clients := make([]*client, 0, cfg.Clients)
+	failed := 0
+
+	sem := make(chan struct{}, cfg.DialConcurrency)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < cfg.Clients; i++ {
+		if ctx.Err() != nil {
+			break
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			topics := rand.Perm(cfg.Topics)[:cfg.TopicsPerClient] //nolint:gosec
+			c, err := connect(ctx, cfg, t.Name, t.URL, topics, rec)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed++
+				return
+			}
+
+			f.subscribe(topics)
+			go c.read(t.Name, f, rec)
+			clients = append(clients, c)
+		}()
+	}
+	wg.Wait()
+
+	if failed > 0 {
+		log.Printf("%s: %d of %d clients failed to connect", t.Name, failed, cfg.Clients)
+	}
+
+	return clients
+}
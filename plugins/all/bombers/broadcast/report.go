@@ -0,0 +1,40 @@
+package main
+
+import (
+	"time"
+
+	"github.com/roadrunner-server/rr-e2e-tests/plugins/all/bombers/load"
+)
+
+// Report is the machine-readable result of the run
+type Report struct {
+	Config   *config                  `json:"config"`
+	Started  time.Time                `json:"started"`
+	Finished time.Time                `json:"finished"`
+	Elapsed  float64                  `json:"elapsed_seconds"`
+	Targets  map[string]*TargetReport `json:"targets"`
+}
+
+// TargetReport is the per target (broker) summary
+type TargetReport struct {
+	Clients   int `json:"clients"`
+	Connected int `json:"connected"`
+	// Publishes are the started and the dropped publish calls
+	Publishes load.Paced `json:"publishes"`
+	Published uint64     `json:"published"`
+	// Expected is the sum of the subscribers of the published messages
+	Expected   uint64 `json:"expected"`
+	Delivered  uint64 `json:"delivered"`
+	Lost       uint64 `json:"lost"`
+	Duplicated uint64 `json:"duplicated"`
+	Unexpected uint64 `json:"unexpected"`
+	// Completeness is delivered to expected, the subscriber completeness is the same per subscriber
+	Completeness              float64 `json:"completeness"`
+	CompleteSubscribers       int     `json:"complete_subscribers"`
+	MinSubscriberCompleteness float64 `json:"min_subscriber_completeness"`
+	// Throughput is the deliveries per second
+	Throughput float64 `json:"throughput"`
+	// Methods are the join, the publish RPC, the delivery (publish to a subscriber) and the fanout (publish to the
+	// last subscriber) latencies
+	Methods map[string]*load.OpReport `json:"methods"`
+}
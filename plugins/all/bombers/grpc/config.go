@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/roadrunner-server/rr-e2e-tests/plugins/all/bombers/load"
+)
+
+// config is the bomber configuration set via the flags
+type config struct {
+	Addr        string `json:"addr"`
+	Methods     string `json:"methods"`
+	PayloadSize int    `json:"payload_size"`
+	// TLS settings, as in TestGrpcRqRsTLS (CA is the server certificate) and TestGrpcRqRsTLSRootCA (client certificate)
+	TLS        bool   `json:"tls"`
+	CA         string `json:"ca,omitempty"`
+	Cert       string `json:"cert,omitempty"`
+	Key        string `json:"key,omitempty"`
+	ServerName string `json:"server_name,omitempty"`
+	Insecure   bool   `json:"insecure"`
+	// Gzip compresses the requests, as in TestGrpcRqRsGzip
+	Gzip        bool          `json:"gzip"`
+	Rate        float64       `json:"rate"`
+	Duration    time.Duration `json:"duration_ns"`
+	RampUp      time.Duration `json:"ramp_up_ns"`
+	MaxInFlight int           `json:"max_in_flight"`
+	Connections int           `json:"connections"`
+	Timeout     time.Duration `json:"timeout_ns"`
+	Report      string        `json:"-"`
+
+	mix *load.Mix
+}
+
+func parseFlags(args []string) (*config, error) {
+	cfg := &config{}
+
+	fs := flag.NewFlagSet("grpc-bomber", flag.ContinueOnError)
+	fs.StringVar(&cfg.Addr, "addr", "127.0.0.1:9001", "gRPC address of the RoadRunner")
+	fs.StringVar(&cfg.Methods, "methods", echoPing, "weighted methods, e.g. echo.Ping:80,test.Echo:20: "+strings.Join(knownMethods(), ", "))
+	fs.IntVar(&cfg.PayloadSize, "payload", 16, "message size in bytes")
+	fs.BoolVar(&cfg.TLS, "tls", false, "connect with TLS")
+	fs.StringVar(&cfg.CA, "ca", "", "PEM file with the server certificate or the CA, the system roots when empty")
+	fs.StringVar(&cfg.Cert, "cert", "", "PEM file with the client certificate (mutual TLS)")
+	fs.StringVar(&cfg.Key, "key", "", "PEM file with the client key (mutual TLS)")
+	fs.StringVar(&cfg.ServerName, "server-name", "", "server name to verify, the host of the address when empty")
+	fs.BoolVar(&cfg.Insecure, "insecure", false, "skip the TLS certificate verification")
+	fs.BoolVar(&cfg.Gzip, "gzip", false, "compress the requests with gzip")
+	fs.Float64Var(&cfg.Rate, "rate", 100, "calls per second, sent regardless of the responses (open loop)")
+	fs.DurationVar(&cfg.Duration, "duration", time.Minute, "duration at the full rate")
+	fs.DurationVar(&cfg.RampUp, "ramp-up", 0, "ramp the rate up from zero for the duration before the full rate")
+	fs.IntVar(&cfg.MaxInFlight, "max-in-flight", 1000, "calls in flight, the arrivals above are dropped and reported")
+	fs.IntVar(&cfg.Connections, "connections", 1, "client connections, the calls are spread over them")
+	fs.DurationVar(&cfg.Timeout, "timeout", time.Second*30, "call timeout")
+	fs.StringVar(&cfg.Report, "report", "grpc-report.json", "path of the JSON report, - for stdout")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Rate <= 0 || cfg.Duration <= 0 || cfg.MaxInFlight <= 0 || cfg.Connections <= 0 || cfg.Timeout <= 0 {
+		return nil, errors.New("rate, duration, max-in-flight, connections and timeout should be positive")
+	}
+	if cfg.RampUp < 0 || cfg.PayloadSize < 0 {
+		return nil, errors.New("ramp-up and payload should not be negative")
+	}
+	if (cfg.Cert == "") != (cfg.Key == "") {
+		return nil, errors.New("cert and key should be set together")
+	}
+	if !cfg.TLS && (cfg.CA != "" || cfg.Cert != "" || cfg.Insecure) {
+		return nil, errors.New("ca, cert, key and insecure require tls")
+	}
+
+	cfg.mix, err = load.ParseMix(cfg.Methods, "method", func(name string) bool {
+		_, ok := calls[name]
+		return ok
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func knownMethods() []string {
+	names := make([]string, 0, len(calls))
+	for name := range calls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
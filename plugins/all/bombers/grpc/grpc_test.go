@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/roadrunner-server/rr-e2e-tests/plugins/all/bombers/load"
+	"github.com/roadrunner-server/rr-e2e-tests/plugins/grpc/proto/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const (
+	certs  string = "../../../grpc/configs/test-certs/"
+	caCert string = certs + "ca.cert"
+	pem    string = certs + "test.pem"
+	key    string = certs + "test.key"
+)
+
+type echoServer struct {
+	service.UnimplementedEchoServer
+}
+
+func (echoServer) Ping(_ context.Context, in *service.Message) (*service.Message, error) {
+	return in, nil
+}
+
+// testServiceDesc is the Test service of proto/test with the wire compatible messages, Throw fails
+var testServiceDesc = grpc.ServiceDesc{ //nolint:gochecknoglobals
+	ServiceName: "service.Test",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Echo", Handler: testHandler(func(in *service.Message) (*service.Message, error) { return in, nil })},
+		{MethodName: "Throw", Handler: testHandler(func(*service.Message) (*service.Message, error) {
+			return nil, status.Error(codes.Internal, "thrown")
+		})},
+		{MethodName: "Ping", Handler: func(_ interface{}, _ context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := &emptypb.Empty{}
+			return in, dec(in)
+		}},
+	},
+}
+
+func testHandler(fn func(*service.Message) (*service.Message, error)) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(_ interface{}, _ context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+		in := &service.Message{}
+		err := dec(in)
+		if err != nil {
+			return nil, err
+		}
+
+		return fn(in)
+	}
+}
+
+// gzipCounter counts the requests compressed with gzip
+type gzipCounter struct {
+	n int64
+}
+
+func (g *gzipCounter) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (g *gzipCounter) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (g *gzipCounter) HandleConn(context.Context, stats.ConnStats) {}
+
+func (g *gzipCounter) HandleRPC(_ context.Context, s stats.RPCStats) {
+	if h, ok := s.(*stats.InHeader); ok && h.Compression == "gzip" {
+		atomic.AddInt64(&g.n, 1)
+	}
+}
+
+func serve(t *testing.T, opts ...grpc.ServerOption) string {
+	srv := grpc.NewServer(opts...)
+	service.RegisterEchoServer(srv, echoServer{})
+	srv.RegisterService(&testServiceDesc, nil)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() {
+		_ = srv.Serve(l)
+	}()
+	t.Cleanup(srv.Stop)
+
+	return l.Addr().String()
+}
+
+func testConfig(t *testing.T, addr string, args ...string) *config {
+	cfg, err := parseFlags(append([]string{"-addr", addr, "-rate", "100", "-duration", "300ms", "-connections", "2"}, args...))
+	require.NoError(t, err)
+
+	return cfg
+}
+
+func TestPlaintext(t *testing.T) {
+	addr := serve(t)
+
+	rep, err := run(context.Background(), testConfig(t, addr, "-methods", "echo.Ping:2,test.Echo:1,test.Ping:1,test.Throw:1"))
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(30), rep.Calls.Started+rep.Calls.Dropped)
+	require.NotNil(t, rep.Latency)
+	assert.Equal(t, rep.Calls.Started, rep.Latency.Calls)
+	assert.Equal(t, rep.Calls.Dropped, rep.Latency.Dropped)
+	assert.Greater(t, rep.RPS, float64(0))
+
+	// Throw fails with Internal
+	throw := rep.Methods[testThrow]
+	require.NotNil(t, throw)
+	assert.Equal(t, throw.Calls, throw.Errors)
+	assert.Equal(t, map[string]uint64{"Internal": throw.Calls}, throw.Codes)
+	assert.Equal(t, throw.Calls, rep.Latency.Errors)
+	assert.Equal(t, rep.Latency.Calls-throw.Calls, rep.Latency.Codes["OK"])
+
+	for _, method := range []string{echoPing, testEcho, testPing} {
+		require.NotNil(t, rep.Methods[method], method)
+		assert.Zero(t, rep.Methods[method].Errors, method)
+	}
+
+	// Info is not implemented by the server
+	rep, err = run(context.Background(), testConfig(t, addr, "-methods", "test.Info"))
+	require.NoError(t, err)
+	assert.Equal(t, rep.Latency.Calls, rep.Latency.Codes["Unimplemented"])
+}
+
+func TestTLSGzip(t *testing.T) {
+	cert, err := tls.LoadX509KeyPair(pem, key)
+	require.NoError(t, err)
+
+	counter := &gzipCounter{}
+	addr := serve(t, grpc.Creds(credentials.NewServerTLSFromCert(&cert)), grpc.StatsHandler(counter))
+
+	// the server certificate is the root, as in TestGrpcRqRsTLS
+	rep, err := run(context.Background(), testConfig(t, addr, "-tls", "-ca", pem, "-gzip"))
+	require.NoError(t, err)
+	assert.Zero(t, rep.Latency.Errors)
+	assert.Equal(t, rep.Latency.Calls, uint64(atomic.LoadInt64(&counter.n)))
+
+	// the server is not trusted without the CA
+	rep, err = run(context.Background(), testConfig(t, addr, "-tls"))
+	require.NoError(t, err)
+	assert.Equal(t, rep.Latency.Calls, rep.Latency.Codes["Unavailable"])
+
+	// plaintext to the TLS server
+	rep, err = run(context.Background(), testConfig(t, addr))
+	require.NoError(t, err)
+	assert.Equal(t, rep.Latency.Calls, rep.Latency.Errors)
+}
+
+func TestMutualTLS(t *testing.T) {
+	cert, err := tls.LoadX509KeyPair(pem, key)
+	require.NoError(t, err)
+
+	ca, err := os.ReadFile(caCert)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(ca))
+
+	addr := serve(t, grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	})))
+
+	rep, err := run(context.Background(), testConfig(t, addr, "-tls", "-ca", caCert, "-cert", pem, "-key", key))
+	require.NoError(t, err)
+	assert.Zero(t, rep.Latency.Errors)
+
+	// no client certificate
+	rep, err = run(context.Background(), testConfig(t, addr, "-tls", "-ca", caCert))
+	require.NoError(t, err)
+	assert.Equal(t, rep.Latency.Calls, rep.Latency.Errors)
+}
+
+func TestParseFlags(t *testing.T) {
+	cfg, err := parseFlags([]string{"-methods", "echo.Ping:3, test.Echo, test.Die:0"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{echoPing, testEcho}, cfg.mix.Names())
+	assert.Len(t, load.RampUp(cfg.RampUp, cfg.Duration, cfg.Rate), 1)
+
+	for _, args := range [][]string{
+		{"-methods", "echo.Echo"},
+		{"-methods", "echo.Ping:0"},
+		{"-cert", pem, "-tls"},
+		{"-ca", pem},
+		{"-connections", "0"},
+	} {
+		_, err = parseFlags(args)
+		assert.Error(t, err, strings.Join(args, " "))
+	}
+
+	_, err = run(context.Background(), testConfig(t, "127.0.0.1:1", "-tls", "-ca", key))
+	assert.Error(t, err)
+}
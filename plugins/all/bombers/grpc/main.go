@@ -0,0 +1,218 @@
+// Command grpc is the open-loop gRPC load generator for the Echo service (plugins/grpc/proto/service) and the Test
+// service (plugins/grpc/proto/test): the calls are sent at the fixed arrival rate regardless of how fast the
+// responses come, and the latency is measured from the time the call was scheduled (no coordinated omission). It
+// supports plaintext, TLS, mutual TLS and gzip and writes the JSON report with the calls per second, the status
+// codes and the latency percentiles per method.
+//
+// The proto/service and proto/test packages both register service.Message and can't be linked into one binary, and
+// there is no generated client for the Test service, so its methods are invoked with the wire compatible
+// service.Message and google.protobuf.Empty.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/roadrunner-server/rr-e2e-tests/plugins/all/bombers/load"
+	"github.com/roadrunner-server/rr-e2e-tests/plugins/grpc/proto/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const (
+	echoPing  string = "echo.Ping"
+	testEcho  string = "test.Echo"
+	testInfo  string = "test.Info"
+	testThrow string = "test.Throw"
+	testDie   string = "test.Die"
+	testPing  string = "test.Ping"
+
+	groupMethods string = "methods"
+	groupTotal   string = "total"
+	opLatency    string = "latency"
+)
+
+// call calls the method over the connection
+type call func(ctx context.Context, c *conn, msg *service.Message) error
+
+// calls are the supported methods
+var calls = map[string]call{ //nolint:gochecknoglobals
+	echoPing: func(ctx context.Context, c *conn, msg *service.Message) error {
+		_, err := c.echo.Ping(ctx, msg)
+		return err
+	},
+	testEcho:  invoke("/service.Test/Echo"),
+	testInfo:  invoke("/service.Test/Info"),
+	testThrow: invoke("/service.Test/Throw"),
+	testDie:   invoke("/service.Test/Die"),
+	testPing: func(ctx context.Context, c *conn, _ *service.Message) error {
+		return c.cc.Invoke(ctx, "/service.Test/Ping", &emptypb.Empty{}, &emptypb.Empty{})
+	},
+}
+
+// invoke calls the Test service method, its Message has the same wire format as service.Message
+func invoke(method string) call {
+	return func(ctx context.Context, c *conn, msg *service.Message) error {
+		return c.cc.Invoke(ctx, method, msg, &service.Message{})
+	}
+}
+
+// conn is the client connection with the generated Echo client
+type conn struct {
+	cc   *grpc.ClientConn
+	echo service.EchoClient
+}
+
+// Report is the machine-readable result of the run
+type Report struct {
+	Config   *config   `json:"config"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Elapsed  float64   `json:"elapsed_seconds"`
+	// Calls are the started and the dropped arrivals
+	Calls load.Paced `json:"calls"`
+	// RPS is the achieved responses per second
+	RPS float64 `json:"rps"`
+	// Latency of all methods with the status codes and the errors
+	Latency *load.OpReport            `json:"latency"`
+	Methods map[string]*load.OpReport `json:"methods"`
+}
+
+func main() {
+	cfg, err := parseFlags(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the interrupted run still writes the report
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	rep, err := run(ctx, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = load.WriteJSON(cfg.Report, rep)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context, cfg *config) (*Report, error) {
+	opts, err := dialOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	conns := make([]*conn, 0, cfg.Connections)
+	for i := 0; i < cfg.Connections; i++ {
+		cc, err := grpc.Dial(cfg.Addr, opts...)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = cc.Close()
+		}()
+
+		conns = append(conns, &conn{cc: cc, echo: service.NewEchoClient(cc)})
+	}
+
+	msg := &service.Message{Msg: strings.Repeat("a", cfg.PayloadSize)}
+	rec := load.NewRecorder()
+
+	// the arrivals run concurrently, the random source is shared
+	mu := &sync.Mutex{}
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano())) //nolint:gosec
+	next := uint64(0)
+
+	started := time.Now()
+	paced := load.Pace(ctx, load.RampUp(cfg.RampUp, cfg.Duration, cfg.Rate), cfg.MaxInFlight, func(scheduled time.Time) {
+		mu.Lock()
+		method := cfg.mix.Pick(rnd)
+		mu.Unlock()
+		c := conns[atomic.AddUint64(&next, 1)%uint64(len(conns))]
+
+		cctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+		err := calls[method](cctx, c, msg)
+		cancel()
+
+		d := time.Since(scheduled)
+		code := status.Code(err).String()
+		rec.RecordCode(groupMethods, method, d, code, err)
+		rec.RecordCode(groupTotal, opLatency, d, code, err)
+	})
+	finished := time.Now()
+	// the method of the dropped arrival is not picked
+	rec.Drop(groupTotal, opLatency, paced.Dropped)
+
+	elapsed := finished.Sub(started).Seconds()
+	rep := &Report{
+		Config:   cfg,
+		Started:  started,
+		Finished: finished,
+		Elapsed:  elapsed,
+		Calls:    paced,
+		Latency:  rec.Reports(groupTotal)[opLatency],
+		Methods:  rec.Reports(groupMethods),
+	}
+	if rep.Latency != nil {
+		rep.RPS = float64(rep.Latency.Calls) / elapsed
+	}
+
+	return rep, nil
+}
+
+// dialOptions returns the transport credentials and the compressor options
+func dialOptions(cfg *config) ([]grpc.DialOption, error) {
+	opts := make([]grpc.DialOption, 0, 2)
+	if cfg.Gzip {
+		opts = append(opts, grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)))
+	}
+
+	if !cfg.TLS {
+		return append(opts, grpc.WithTransportCredentials(insecure.NewCredentials())), nil
+	}
+
+	tlsCfg := &tls.Config{
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.Insecure, //nolint:gosec
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if cfg.CA != "" {
+		ca, err := os.ReadFile(cfg.CA)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsCfg.RootCAs = x509.NewCertPool()
+		if !tlsCfg.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates in %s", cfg.CA)
+		}
+	}
+
+	if cfg.Cert != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.Cert, cfg.Key)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg))), nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// protocols supported by the bomber
+const (
+	protoHTTP1 string = "http1"
+	// HTTP/2 over TLS, as in TestHTTP2Req
+	protoH2 string = "h2"
+	// HTTP/2 without TLS (prior knowledge), as in TestH2C
+	protoH2C string = "h2c"
+)
+
+// config is the bomber configuration set via the flags
+type config struct {
+	URL         string        `json:"url"`
+	Method      string        `json:"method"`
+	Headers     http.Header   `json:"headers,omitempty"`
+	Body        []byte        `json:"-"`
+	Proto       string        `json:"proto"`
+	Rate        float64       `json:"rate"`
+	Duration    time.Duration `json:"duration_ns"`
+	RampUp      time.Duration `json:"ramp_up_ns"`
+	MaxInFlight int           `json:"max_in_flight"`
+	Connections int           `json:"connections"`
+	KeepAlive   bool          `json:"keep_alive"`
+	Insecure    bool          `json:"insecure"`
+	Timeout     time.Duration `json:"timeout_ns"`
+	Report      string        `json:"-"`
+}
+
+// headers is the repeatable -header "Name: value" flag
+type headers http.Header
+
+func (h headers) String() string {
+	return fmt.Sprint(http.Header(h))
+}
+
+func (h headers) Set(v string) error {
+	name, value, ok := strings.Cut(v, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("header should be \"Name: value\": %s", v)
+	}
+
+	http.Header(h).Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	return nil
+}
+
+func parseFlags(args []string) (*config, error) {
+	cfg := &config{Headers: make(http.Header)}
+	body, bodyFile := "", ""
+
+	fs := flag.NewFlagSet("http-bomber", flag.ContinueOnError)
+	fs.StringVar(&cfg.URL, "url", "http://127.0.0.1:8080", "target URL")
+	fs.StringVar(&cfg.Method, "method", http.MethodGet, "request method")
+	fs.Var(headers(cfg.Headers), "header", "request header \"Name: value\", repeatable")
+	fs.StringVar(&body, "body", "", "request body")
+	fs.StringVar(&bodyFile, "body-file", "", "read the request body from the file")
+	fs.StringVar(&cfg.Proto, "proto", protoHTTP1, "protocol: http1, h2 (TLS) or h2c")
+	fs.Float64Var(&cfg.Rate, "rate", 100, "requests per second, sent regardless of the responses (open loop)")
+	fs.DurationVar(&cfg.Duration, "duration", time.Minute, "duration at the full rate")
+	fs.DurationVar(&cfg.RampUp, "ramp-up", 0, "ramp the rate up from zero for the duration before the full rate")
+	fs.IntVar(&cfg.MaxInFlight, "max-in-flight", 1000, "requests in flight, the arrivals above are dropped and reported")
+	fs.IntVar(&cfg.Connections, "connections", 0, "max connections for http1, 0 means no limit")
+	fs.BoolVar(&cfg.KeepAlive, "keep-alive", true, "reuse the http1 connections")
+	fs.BoolVar(&cfg.Insecure, "insecure", false, "skip the TLS certificate verification")
+	fs.DurationVar(&cfg.Timeout, "timeout", time.Second*30, "request timeout")
+	fs.StringVar(&cfg.Report, "report", "http-report.json", "path of the JSON report, - for stdout")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.Proto {
+	case protoHTTP1, protoH2, protoH2C:
+	default:
+		return nil, fmt.Errorf("unknown proto: %s", cfg.Proto)
+	}
+
+	if cfg.Rate <= 0 || cfg.Duration <= 0 || cfg.MaxInFlight <= 0 || cfg.RampUp < 0 || cfg.Connections < 0 {
+		return nil, errors.New("rate, duration and max-in-flight should be positive, ramp-up and connections should not be negative")
+	}
+
+	cfg.Body = []byte(body)
+	if bodyFile != "" {
+		cfg.Body, err = os.ReadFile(bodyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/rr-e2e-tests/plugins/all/bombers/load"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// handler responds with 201 and every fourth request with 503, the protocol is in the header
+func handler(hits *int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Proto", r.Proto)
+		if r.Header.Get("X-Test") != "yes" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if atomic.AddInt64(hits, 1)%4 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+}
+
+func testConfig(t *testing.T, url, proto string, args ...string) *config {
+	cfg, err := parseFlags(append([]string{
+		"-url", url,
+		"-proto", proto,
+		"-header", "X-Test: yes",
+		"-rate", "40",
+		"-duration", "500ms",
+	}, args...))
+	require.NoError(t, err)
+
+	return cfg
+}
+
+func assertReport(t *testing.T, rep *Report) {
+	assert.Equal(t, uint64(20), rep.Requests.Started)
+	assert.Equal(t, uint64(0), rep.Requests.Dropped)
+	require.NotNil(t, rep.Latency)
+	assert.Equal(t, uint64(20), rep.Latency.Calls)
+	assert.Equal(t, uint64(5), rep.Latency.Errors)
+	assert.Equal(t, map[string]uint64{"201": 15, "503": 5}, rep.Latency.Codes)
+	assert.Equal(t, uint64(20), rep.Service.Calls)
+	assert.GreaterOrEqual(t, rep.Latency.Max, rep.Service.Max)
+}
+
+func TestHTTP1(t *testing.T) {
+	hits := int64(0)
+	srv := httptest.NewServer(handler(&hits))
+	defer srv.Close()
+
+	assertReport(t, run(context.Background(), testConfig(t, srv.URL, protoHTTP1)))
+}
+
+func TestH2(t *testing.T) {
+	hits := int64(0)
+	srv := httptest.NewUnstartedServer(handler(&hits))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	// the test server certificate is self-signed
+	assertReport(t, run(context.Background(), testConfig(t, srv.URL, protoH2, "-insecure")))
+
+	rep := run(context.Background(), testConfig(t, srv.URL, protoH2))
+	assert.Equal(t, rep.Latency.Calls, rep.Latency.Errors)
+}
+
+func TestH2C(t *testing.T) {
+	hits := int64(0)
+	srv := httptest.NewServer(h2c.NewHandler(handler(&hits), &http2.Server{}))
+	defer srv.Close()
+
+	assertReport(t, run(context.Background(), testConfig(t, srv.URL, protoH2C)))
+
+	// the http1 server is not accepted as h2
+	plain := httptest.NewServer(handler(&hits))
+	defer plain.Close()
+
+	rep := run(context.Background(), testConfig(t, plain.URL, protoH2C))
+	assert.Equal(t, rep.Latency.Calls, rep.Latency.Errors)
+}
+
+func TestPhases(t *testing.T) {
+	cfg, err := parseFlags([]string{"-rate", "100", "-duration", "1m", "-ramp-up", "10s", "-header", "A: b", "-header", "A: c"})
+	require.NoError(t, err)
+	assert.Len(t, load.RampUp(cfg.RampUp, cfg.Duration, cfg.Rate), 2)
+	assert.Equal(t, []string{"b", "c"}, cfg.Headers["A"])
+
+	_, err = parseFlags([]string{"-proto", "h3"})
+	assert.Error(t, err)
+	_, err = parseFlags([]string{"-header", "nocolon"})
+	assert.Error(t, err)
+	_, err = parseFlags([]string{"-rate", "0"})
+	assert.Error(t, err)
+
+	// the run is stopped with the context
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	cfg, err = parseFlags([]string{"-url", "http://127.0.0.1:1", "-rate", "10", "-duration", "1h"})
+	require.NoError(t, err)
+	rep := run(ctx, cfg)
+	assert.LessOrEqual(t, rep.Requests.Started, uint64(2))
+}
@@ -0,0 +1,163 @@
+// Command http is the open-loop HTTP load generator: the requests are sent at the fixed arrival rate regardless of
+// how fast the responses come, and the latency is measured from the time the request was scheduled, so the stalls of
+// the server are not hidden (no coordinated omission). It supports the keep-alive http1, h2 and h2c targets and writes
+// the JSON report with the status codes, the errors and the latency percentiles.
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/roadrunner-server/rr-e2e-tests/plugins/all/bombers/load"
+	"golang.org/x/net/http2"
+)
+
+const (
+	group string = "http"
+	// from the scheduled time, includes the time the request waited for the connection
+	opLatency string = "latency"
+	// from the time the request was sent
+	opService string = "service"
+)
+
+// Report is the machine-readable result of the run
+type Report struct {
+	Config   *config   `json:"config"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Elapsed  float64   `json:"elapsed_seconds"`
+	// Requests are the started and the dropped arrivals
+	Requests load.Paced `json:"requests"`
+	// Rate is the achieved responses per second
+	Rate float64 `json:"rate"`
+	// Latency is measured from the scheduled time and has the status codes and the errors
+	Latency *load.OpReport `json:"latency"`
+	// Service is measured from the time the request was sent
+	Service *load.OpReport `json:"service"`
+}
+
+func main() {
+	cfg, err := parseFlags(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the interrupted run still writes the report
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	rep := run(ctx, cfg)
+
+	err = load.WriteJSON(cfg.Report, rep)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context, cfg *config) *Report {
+	client := newClient(cfg)
+	defer client.CloseIdleConnections()
+
+	rec := load.NewRecorder()
+
+	started := time.Now()
+	paced := load.Pace(ctx, load.RampUp(cfg.RampUp, cfg.Duration, cfg.Rate), cfg.MaxInFlight, func(scheduled time.Time) {
+		sent := time.Now()
+		code, err := do(ctx, client, cfg)
+		rec.RecordCode(group, opLatency, time.Since(scheduled), code, err)
+		rec.Record(group, opService, time.Since(sent), nil)
+	})
+	finished := time.Now()
+	rec.Drop(group, opLatency, paced.Dropped)
+
+	elapsed := finished.Sub(started).Seconds()
+	ops := rec.Reports(group)
+
+	rep := &Report{
+		Config:   cfg,
+		Started:  started,
+		Finished: finished,
+		Elapsed:  elapsed,
+		Requests: paced,
+		Latency:  ops[opLatency],
+		Service:  ops[opService],
+	}
+	if rep.Latency != nil {
+		rep.Rate = float64(rep.Latency.Calls) / elapsed
+	}
+
+	return rep
+}
+
+// do sends the request and returns the status code, the statuses >= 400 are the errors
+func do(ctx context.Context, client *http.Client, cfg *config) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, cfg.Method, cfg.URL, bytes.NewReader(cfg.Body))
+	if err != nil {
+		return "", err
+	}
+	for name, values := range cfg.Headers {
+		req.Header[name] = values
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	// read the body to reuse the connection
+	_, err = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	code := strconv.Itoa(resp.StatusCode)
+	switch {
+	case err != nil:
+		return code, err
+	case cfg.Proto != protoHTTP1 && resp.ProtoMajor != 2:
+		return code, fmt.Errorf("unexpected protocol: %s", resp.Proto)
+	case resp.StatusCode >= http.StatusBadRequest:
+		return code, errors.New(resp.Status)
+	}
+
+	return code, nil
+}
+
+func newClient(cfg *config) *http.Client {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.Insecure} //nolint:gosec
+
+	var tr http.RoundTripper
+	switch cfg.Proto {
+	case protoH2:
+		tr = &http2.Transport{TLSClientConfig: tlsConfig}
+	case protoH2C:
+		tr = &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+				// use the http dial (w/o tls)
+				return net.Dial(network, addr)
+			},
+		}
+	default:
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.TLSClientConfig = tlsConfig
+		// http1 only, even for the https targets
+		t.ForceAttemptHTTP2 = false
+		t.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+		t.DisableKeepAlives = !cfg.KeepAlive
+		t.MaxIdleConns = cfg.MaxInFlight
+		t.MaxIdleConnsPerHost = cfg.MaxInFlight
+		t.MaxConnsPerHost = cfg.Connections
+		tr = t
+	}
+
+	return &http.Client{Transport: tr, Timeout: cfg.Timeout}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/roadrunner-server/rr-e2e-tests/plugins/all/bombers/load"
+)
+
+// config is the bomber configuration set via the flags
+type config struct {
+	RPCAddr     string        `json:"rpc_addr"`
+	Drivers     []string      `json:"drivers"`
+	Concurrency int           `json:"concurrency"`
+	Duration    time.Duration `json:"duration_ns"`
+	Iterations  int           `json:"iterations"`
+	Jobs        int           `json:"jobs_per_iteration"`
+	PayloadSize int           `json:"payload_size"`
+	Priorities  string        `json:"priorities"`
+	Report      string        `json:"-"`
+
+	// delivery verification, the workers should be the fakeworker jobs worker
+	Verify     bool          `json:"verify"`
+	VerifyAddr string        `json:"verify_addr,omitempty"`
+	Late       time.Duration `json:"late_ns,omitempty"`
+	Drain      time.Duration `json:"drain_ns,omitempty"`
+	Grace      time.Duration `json:"grace_ns,omitempty"`
+
+	priorities priorities
+}
+
+func parseFlags(args []string) (*config, error) {
+	cfg := &config{}
+	drivers := ""
+
+	fs := flag.NewFlagSet("jobs-bomber", flag.ContinueOnError)
+	fs.StringVar(&cfg.RPCAddr, "rpc", "127.0.0.1:6001", "RPC address of the RoadRunner")
+	fs.StringVar(&drivers, "drivers", "memory", "comma separated drivers: "+strings.Join(knownDrivers(), ", "))
+	fs.IntVar(&cfg.Concurrency, "concurrency", 10, "workers per driver")
+	fs.DurationVar(&cfg.Duration, "duration", 0, "run for the duration, 0 means until the iterations are done")
+	fs.IntVar(&cfg.Iterations, "iterations", 0, "iterations (declare, resume, push, pause, destroy) per driver, 0 means until the duration ends")
+	fs.IntVar(&cfg.Jobs, "jobs", 100, "jobs pushed per iteration")
+	fs.IntVar(&cfg.PayloadSize, "payload", 512, "job payload size in bytes")
+	fs.StringVar(&cfg.Priorities, "priorities", "1-100", "job priorities: comma separated priority or lo-hi range with the optional :weight, e.g. 1:70,10:20,50-100:10")
+	fs.StringVar(&cfg.Report, "report", "jobs-report.json", "path of the JSON report, - for stdout")
+	fs.BoolVar(&cfg.Verify, "verify", false, "verify the delivery of every job, RR should run the fakeworker jobs worker")
+	fs.StringVar(&cfg.VerifyAddr, "verify-listen", "127.0.0.1:0", "address the workers report the delivered jobs to")
+	fs.DurationVar(&cfg.Late, "late", time.Second*5, "jobs delivered later than this after the push are reported as late")
+	fs.DurationVar(&cfg.Drain, "drain", time.Second*5, "wait up to this for the pipeline jobs to be delivered before destroying it, the not delivered jobs are lost, 0 destroys right away and reports them as discarded")
+	fs.DurationVar(&cfg.Grace, "grace", time.Second*30, "wait up to this at the end for the deliveries of the pipelines which failed to be destroyed")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Drivers = load.SplitList(drivers)
+	if len(cfg.Drivers) == 0 {
+		return nil, errors.New("no drivers")
+	}
+	for i := 0; i < len(cfg.Drivers); i++ {
+		if _, ok := declarers[cfg.Drivers[i]]; !ok {
+			return nil, fmt.Errorf("unknown driver: %s", cfg.Drivers[i])
+		}
+	}
+
+	if cfg.Concurrency <= 0 || cfg.Jobs <= 0 || cfg.PayloadSize < 0 || cfg.Iterations < 0 || cfg.Duration < 0 {
+		return nil, errors.New("concurrency and jobs should be positive, payload, iterations and duration should not be negative")
+	}
+	if cfg.Late < 0 || cfg.Drain < 0 || cfg.Grace < 0 {
+		return nil, errors.New("late, drain and grace should not be negative")
+	}
+	if cfg.Duration == 0 && cfg.Iterations == 0 {
+		return nil, errors.New("either duration or iterations should be set")
+	}
+
+	cfg.priorities, err = parsePriorities(cfg.Priorities)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// priority is the lo-hi range of the priorities
+type priority struct {
+	lo, hi int64
+}
+
+// priorities is the weighted distribution of the job priorities
+type priorities struct {
+	ranges  []priority
+	weights load.Weights
+}
+
+// parsePriorities parses "1:70,10:20,50-100:10", the weight defaults to 1
+func parsePriorities(s string) (priorities, error) {
+	ps := priorities{}
+	for _, entry := range load.SplitList(s) {
+		rng, weight, ok := strings.Cut(entry, ":")
+		w := 1
+		if ok {
+			var err error
+			w, err = strconv.Atoi(weight)
+			if err != nil || w <= 0 {
+				return priorities{}, fmt.Errorf("invalid priority weight: %s", entry)
+			}
+		}
+
+		lo, hi, ok := strings.Cut(rng, "-")
+		if !ok {
+			hi = lo
+		}
+
+		p := priority{}
+		var err1, err2 error
+		p.lo, err1 = strconv.ParseInt(lo, 10, 64)
+		p.hi, err2 = strconv.ParseInt(hi, 10, 64)
+		if err1 != nil || err2 != nil || p.lo <= 0 || p.hi < p.lo {
+			return priorities{}, fmt.Errorf("invalid priority: %s", entry)
+		}
+
+		ps.ranges = append(ps.ranges, p)
+		ps.weights.Add(w)
+	}
+
+	if len(ps.ranges) == 0 {
+		return priorities{}, errors.New("no priorities")
+	}
+
+	return ps, nil
+}
+
+// pick returns the random priority according to the weights
+func (ps priorities) pick(rnd *rand.Rand) int64 {
+	p := ps.ranges[ps.weights.Pick(rnd)]
+	return p.lo + rnd.Int63n(p.hi-p.lo+1)
+}
+
+func knownDrivers() []string {
+	names := make([]string, 0, len(declarers))
+	for name := range declarers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePriorities(t *testing.T) {
+	ps, err := parsePriorities("1:70, 10:20,50-100:10")
+	require.NoError(t, err)
+	assert.Equal(t, 100, ps.weights.Total())
+
+	rnd := rand.New(rand.NewSource(1)) //nolint:gosec
+	counts := make(map[int64]int)
+	for i := 0; i < 10000; i++ {
+		p := ps.pick(rnd)
+		if p >= 50 {
+			assert.LessOrEqual(t, p, int64(100))
+			p = 50
+		}
+		counts[p]++
+	}
+
+	assert.InDelta(t, 7000, counts[1], 300)
+	assert.InDelta(t, 2000, counts[10], 300)
+	assert.InDelta(t, 1000, counts[50], 300)
+
+	for _, bad := range []string{"", "0", "5-1", "1:0", "a"} {
+		_, err = parsePriorities(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	cfg, err := parseFlags([]string{"-drivers", "memory,boltdb", "-iterations", "3", "-report", "-"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"memory", "boltdb"}, cfg.Drivers)
+
+	_, err = parseFlags([]string{"-drivers", "kafka", "-iterations", "3"})
+	assert.Error(t, err)
+
+	_, err = parseFlags([]string{"-iterations", "0"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+)
+
+// declarer returns the pipeline config for the pipeline name
+type declarer func(name string) map[string]string
+
+var declarers = map[string]declarer{
+	"amqp":      amqpPipe,
+	"beanstalk": beanstalkPipe,
+	"boltdb":    boltdbPipe,
+	"memory":    memoryPipe,
+	"sqs":       sqsPipe,
+}
+
+// cleanup removes the driver leftovers of the destroyed pipeline
+func cleanup(driver, name string) {
+	if driver == "boltdb" {
+		// the file is created in the RR working directory, the bomber is usually started from the same one
+		_ = os.Remove(name)
+	}
+}
+
+func sqsPipe(n string) map[string]string {
+	return map[string]string{
+		"driver":             "sqs",
+		"name":               n,
+		"queue":              n,
+		"prefetch":           "10",
+		"priority":           "3",
+		"visibility_timeout": "0",
+		"wait_time_seconds":  "3",
+		"tags":               `{"key":"value"}`,
+	}
+}
+
+func amqpPipe(n string) map[string]string {
+	return map[string]string{
+		"driver":          "amqp",
+		"name":            n,
+		"routing_key":     "test-3",
+		"queue":           "default",
+		"exchange_type":   "direct",
+		"exchange":        "amqp.default",
+		"prefetch":        "100",
+		"priority":        "4",
+		"exclusive":       "false",
+		"multiple_ask":    "false",
+		"requeue_on_fail": "false",
+	}
+}
+
+func beanstalkPipe(n string) map[string]string {
+	return map[string]string{
+		"driver":          "beanstalk",
+		"name":            n,
+		"tube":            n,
+		"reserve_timeout": "1",
+		"priority":        "3",
+		"tube_priority":   "10",
+	}
+}
+
+func boltdbPipe(n string) map[string]string {
+	return map[string]string{
+		"driver":   "boltdb",
+		"name":     n,
+		"prefetch": "100",
+		"priority": "2",
+		"file":     n,
+	}
+}
+
+func memoryPipe(n string) map[string]string {
+	return map[string]string{
+		"driver":   "memory",
+		"name":     n,
+		"prefetch": "10000",
+		"priority": "1",
+	}
+}
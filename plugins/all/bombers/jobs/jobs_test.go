@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	jobsv1beta "github.com/roadrunner-server/api/v2/proto/jobs/v1"
+	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
+	"github.com/roadrunner-server/rr-e2e-tests/plugins/all/bombers/load"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// errBusy is returned by the first destroy of every pipeline, the bomber should retry it
+const errBusy string = "pipeline is busy"
+
+// fakeJobs is the in-memory jobs plugin RPC, registered as "jobs"
+type fakeJobs struct {
+	mu sync.Mutex
+	// pipeline -> driver
+	pipelines map[string]string
+	busy      map[string]bool
+	// per driver pushed jobs
+	pushed map[string]int
+}
+
+func (j *fakeJobs) Declare(in *jobsv1beta.DeclareRequest, _ *jobsv1beta.Empty) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	name := in.GetPipeline()["name"]
+	if _, ok := j.pipelines[name]; ok {
+		return errors.New("pipeline already exists")
+	}
+	j.pipelines[name] = in.GetPipeline()["driver"]
+	j.busy[name] = true
+
+	return nil
+}
+
+func (j *fakeJobs) Resume(in *jobsv1beta.Pipelines, _ *jobsv1beta.Empty) error {
+	return j.lookup(in.GetPipelines())
+}
+
+func (j *fakeJobs) Pause(in *jobsv1beta.Pipelines, _ *jobsv1beta.Empty) error {
+	return j.lookup(in.GetPipelines())
+}
+
+func (j *fakeJobs) Push(in *jobsv1beta.PushRequest, _ *jobsv1beta.Empty) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	driver, ok := j.pipelines[in.GetJob().GetOptions().GetPipeline()]
+	if !ok {
+		return errors.New("no such pipeline")
+	}
+	j.pushed[driver]++
+
+	return nil
+}
+
+func (j *fakeJobs) Destroy(in *jobsv1beta.Pipelines, _ *jobsv1beta.Empty) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, name := range in.GetPipelines() {
+		if _, ok := j.pipelines[name]; !ok {
+			return errors.New("no such pipeline")
+		}
+		if j.busy[name] {
+			j.busy[name] = false
+			return errors.New(errBusy)
+		}
+
+		delete(j.pipelines, name)
+		delete(j.busy, name)
+	}
+
+	return nil
+}
+
+func (j *fakeJobs) lookup(pipelines []string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, name := range pipelines {
+		if _, ok := j.pipelines[name]; !ok {
+			return errors.New("no such pipeline")
+		}
+	}
+
+	return nil
+}
+
+func serveJobs(t *testing.T) (*fakeJobs, string) {
+	jobs := &fakeJobs{
+		pipelines: make(map[string]string),
+		busy:      make(map[string]bool),
+		pushed:    make(map[string]int),
+	}
+	srv := rpc.NewServer()
+	require.NoError(t, srv.RegisterName("jobs", jobs))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go srv.ServeCodec(goridgeRpc.NewCodec(conn))
+		}
+	}()
+
+	return jobs, l.Addr().String()
+}
+
+func TestRun(t *testing.T) {
+	jobs, addr := serveJobs(t)
+	report := filepath.Join(t.TempDir(), "report.json")
+
+	cfg, err := parseFlags([]string{
+		"-rpc", addr,
+		"-drivers", "memory,boltdb",
+		"-iterations", "3",
+		"-concurrency", "2",
+		"-jobs", "5",
+		"-payload", "16",
+		"-report", report,
+	})
+	require.NoError(t, err)
+
+	rep, err := run(cfg)
+	require.NoError(t, err)
+	require.NoError(t, load.WriteJSON(cfg.Report, rep))
+
+	// all pipelines were destroyed after the retry
+	jobs.mu.Lock()
+	assert.Empty(t, jobs.pipelines)
+	assert.Equal(t, map[string]int{"memory": 15, "boltdb": 15}, jobs.pushed)
+	jobs.mu.Unlock()
+
+	data, err := os.ReadFile(report)
+	require.NoError(t, err)
+
+	written := &Report{}
+	require.NoError(t, json.Unmarshal(data, written))
+	assert.Equal(t, []string{"memory", "boltdb"}, written.Config.Drivers)
+	assert.Greater(t, written.Throughput, float64(0))
+	assert.Nil(t, written.Deliveries)
+
+	require.Len(t, written.Drivers, 2)
+	for _, driver := range []string{"memory", "boltdb"} {
+		dr := written.Drivers[driver]
+		require.NotNil(t, dr, driver)
+		assert.Equal(t, uint64(3), dr.Iterations, driver)
+		assert.Equal(t, uint64(15), dr.Pushed, driver)
+
+		for method, calls := range map[string]uint64{declare: 3, resume: 3, push: 15, pause: 3} {
+			require.Contains(t, dr.Methods, method, driver)
+			assert.Equal(t, calls, dr.Methods[method].Calls, method)
+			assert.Zero(t, dr.Methods[method].Errors, method)
+		}
+
+		// the first destroy of every pipeline fails
+		require.Contains(t, dr.Methods, destroy, driver)
+		assert.Equal(t, uint64(6), dr.Methods[destroy].Calls, driver)
+		assert.Equal(t, uint64(3), dr.Methods[destroy].Errors, driver)
+		assert.Equal(t, map[string]uint64{errBusy: 3}, dr.Methods[destroy].ErrorCounts, driver)
+	}
+
+	require.Contains(t, written.Methods, push)
+	assert.Equal(t, uint64(30), written.Methods[push].Calls)
+	assert.Equal(t, uint64(12), written.Methods[destroy].Calls)
+}
@@ -1,13 +1,14 @@
+// Command jobs is the load generator for the jobs plugin. Every worker repeats the iteration: declare the pipeline,
+// resume it, push the jobs, pause and destroy it. At the end the JSON report with the throughput, the errors and the
+// latency percentiles (HDR histograms) per driver and RPC method is written, so the runs can be compared between the releases.
+// With -verify every pushed job is tracked and the fakeworker jobs worker reports the acked jobs back
+// (fakeworker_report header), the report then has the lost, discarded, duplicated and late jobs per driver.
 package main
 
 import (
-	"fmt"
 	"log"
-	rand2 "math/rand"
-	"net"
-	"net/rpc"
+	"math/rand"
 	"os"
-	"path"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -15,7 +16,9 @@ import (
 
 	"github.com/google/uuid"
 	jobsv1beta "github.com/roadrunner-server/api/v2/proto/jobs/v1"
-	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
+	"github.com/roadrunner-server/rr-e2e-tests/fakeworker"
+	"github.com/roadrunner-server/rr-e2e-tests/plugins/all/bombers/load"
+	"github.com/roadrunner-server/rr-e2e-tests/rpcclient"
 )
 
 const (
@@ -24,329 +27,219 @@ const (
 	destroy string = "jobs.Destroy"
 	declare string = "jobs.Declare"
 	resume  string = "jobs.Resume"
-	stat    string = "jobs.Stat" //nolint:unused,deadcode,varcheck
+
+	// destroy is retried while the pipeline is still busy
+	destroyAttempts int = 10
 )
 
 func main() {
-	wg := &sync.WaitGroup{}
-	wg.Add(33)
+	cfg, err := parseFlags(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	rate := uint64(0)
-	delayedCloseCh := make(chan string, 1000000)
+	rep, err := run(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	go func() {
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
-		if err != nil {
-			log.Fatal(err)
-		}
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-		for i := 0; i < 10; i++ {
-			go func() {
-				for j := 0; j < 10000; j++ {
-					n := uuid.NewString()
-					declareAMQPPipe(client, n)
-					startPipelines(client, n)
-					push100(client, n)
-					atomic.AddUint64(&rate, 1)
-					delayedCloseCh <- n
-				}
-				wg.Done()
-			}()
-		}
-	}()
+	err = load.WriteJSON(cfg.Report, rep)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
 
-	go func() {
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
-		if err != nil {
-			log.Fatal(err)
-		}
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-		for i := 0; i < 10; i++ {
-			go func() {
-				for j := 0; j < 10; j++ {
-					n := uuid.NewString()
-					declareBeanstalkPipe(client, n)
-					startPipelines(client, n)
-					push100(client, n)
-					atomic.AddUint64(&rate, 1)
-					delayedCloseCh <- n
-				}
-				wg.Done()
-			}()
-		}
-	}()
+// bomber runs the workers of a single driver
+type bomber struct {
+	cfg    *config
+	driver string
+	client *rpcclient.Client
+	rec    *load.Recorder
+	// nil unless cfg.Verify
+	tr *tracker
+
+	// iterations started, used to stop after cfg.Iterations
+	started    uint64
+	iterations uint64
+	pushed     uint64
+}
 
-	go func() {
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
-		if err != nil {
-			log.Fatal(err)
-		}
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-		for i := 0; i < 3; i++ {
-			go func() {
-				for j := 0; j < 10; j++ {
-					n := uuid.NewString()
-					declareBoltDBPipe(client, n, n)
-					startPipelines(client, n)
-					push100(client, n)
-					atomic.AddUint64(&rate, 1)
-					delayedCloseCh <- n
-
-					cur, err := os.Getwd()
-					if err != nil {
-						panic(err)
-					}
-					_ = os.Remove(path.Join(cur, n))
-				}
-				wg.Done()
-			}()
-		}
-	}()
+func run(cfg *config) (*Report, error) {
+	rec := load.NewRecorder()
+	bombers := make([]*bomber, 0, len(cfg.Drivers))
 
-	go func() {
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
+	var tr *tracker
+	if cfg.Verify {
+		var err error
+		tr, err = listen(cfg.VerifyAddr, cfg.Late)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-		for i := 0; i < 10; i++ {
-			go func() {
-				for j := 0; j < 1000; j++ {
-					n := uuid.NewString()
-					declareMemoryPipe(client, n)
-					startPipelines(client, n)
-					push100(client, n)
-					atomic.AddUint64(&rate, 1)
-					delayedCloseCh <- n
-				}
-				wg.Done()
-			}()
-		}
-	}()
+		defer tr.close()
+	}
 
-	go func() {
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
+	for i := 0; i < len(cfg.Drivers); i++ {
+		client, err := rpcclient.Dial(cfg.RPCAddr)
 		if err != nil {
-			log.Fatal(err)
-		}
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-		for i := 0; i < 10; i++ {
-			go func() {
-				for j := 0; j < 1000; j++ {
-					n := uuid.NewString()
-					declareSQSPipe(client, n)
-					startPipelines(client, n)
-					push100(client, n)
-					atomic.AddUint64(&rate, 1)
-					delayedCloseCh <- n
-				}
-				wg.Done()
-			}()
-		}
-	}()
-
-	go func() {
-		tt := time.NewTicker(time.Second)
-		for { //nolint:gosimple
-			select {
-			case <-tt.C:
-				fmt.Println(fmt.Sprintf("-- RATE: %d --", atomic.LoadUint64(&rate)*100)) //nolint:gosimple
-				atomic.StoreUint64(&rate, 0)
-			}
+			return nil, err
 		}
-	}()
+		defer func() {
+			_ = client.Close()
+		}()
 
-	stopCh := make(chan struct{})
-
-	go func() {
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
-		if err != nil {
-			log.Fatal(err)
-		}
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-		for {
-			select {
-			case k := <-delayedCloseCh:
-				pausePipelines(client, k)
-				destroyPipelines(delayedCloseCh, client, k)
-			case <-stopCh:
-				return
-			}
-		}
-	}()
+		bombers = append(bombers, &bomber{cfg: cfg, driver: cfg.Drivers[i], client: client, rec: rec, tr: tr})
+	}
 
-	wg.Wait()
-	stopCh <- struct{}{}
-}
+	var deadline time.Time
+	if cfg.Duration > 0 {
+		deadline = time.Now().Add(cfg.Duration)
+	}
 
-func push100(client *rpc.Client, pipe string) {
-	for j := 0; j < 100; j++ {
-		payloads := &jobsv1beta.PushRequest{
-			Job: &jobsv1beta.Job{
-				Job:     "Some/Super/PHP/Class",
-				Id:      uuid.NewString(),
-				Payload: "Lorem Ipsum is simply dummy text of the printing and typesetting industry. Lorem Ipsum has been the industry's standard dummy text ever since the 1500s, when an unknown printer took a galley of type and scrambled it to make a type specimen book. It has survived not only five centuries, but also the leap into electronic typesetting, remaining essentially unchanged. It was popularized in the 1960s with the release of Letraset sheets containing Lorem Ipsum passages, and more recently with desktop publishing software like Aldus PageMaker including versions of Lorem Ipsum.",
-				Headers: map[string]*jobsv1beta.HeaderValue{"test": {Value: []string{"hello"}}},
-				Options: &jobsv1beta.Options{
-					Priority: int64(rand2.Intn(100) + 1), //nolint:gosec
-					Pipeline: pipe,
-				},
-			},
+	stopCh := make(chan struct{})
+	go load.Progress("RATE", func() uint64 {
+		cur := uint64(0)
+		for i := 0; i < len(bombers); i++ {
+			cur += atomic.LoadUint64(&bombers[i].pushed)
 		}
+		return cur
+	}, stopCh)
 
-		resp := jobsv1beta.Empty{}
-		err := client.Call(push, payloads, &resp)
-		if err != nil {
-			log.Println(err)
+	started := time.Now()
+	wg := &sync.WaitGroup{}
+	for i := 0; i < len(bombers); i++ {
+		for j := 0; j < cfg.Concurrency; j++ {
+			wg.Add(1)
+			go func(b *bomber, seed int64) {
+				defer wg.Done()
+				b.work(deadline, rand.New(rand.NewSource(seed))) //nolint:gosec
+			}(bombers[i], time.Now().UnixNano()+int64(i*cfg.Concurrency+j))
 		}
 	}
-}
 
-func startPipelines(client *rpc.Client, pipes ...string) {
-	pipe := &jobsv1beta.Pipelines{Pipelines: make([]string, len(pipes))}
+	wg.Wait()
+	close(stopCh)
+	finished := time.Now()
 
-	for i := 0; i < len(pipes); i++ {
-		pipe.GetPipelines()[i] = pipes[i]
+	// only the pipelines which failed to be destroyed are still waited for
+	if tr != nil && !tr.wait("", cfg.Grace) {
+		log.Printf("not all jobs were delivered in %s", cfg.Grace)
 	}
 
-	er := &jobsv1beta.Empty{}
-	err := client.Call(resume, pipe, er)
-	if err != nil {
-		log.Println(err)
+	elapsed := finished.Sub(started).Seconds()
+	rep := &Report{
+		Config:   cfg,
+		Started:  started,
+		Finished: finished,
+		Elapsed:  elapsed,
+		Drivers:  make(map[string]*DriverReport, len(bombers)),
+		Methods:  rec.TotalReports(),
 	}
-}
 
-func pausePipelines(client *rpc.Client, pipes ...string) {
-	pipe := &jobsv1beta.Pipelines{Pipelines: make([]string, len(pipes))}
-
-	for i := 0; i < len(pipes); i++ {
-		pipe.GetPipelines()[i] = pipes[i]
+	total := uint64(0)
+	for i := 0; i < len(bombers); i++ {
+		b := bombers[i]
+		pushed := atomic.LoadUint64(&b.pushed)
+		total += pushed
+		rep.Drivers[b.driver] = &DriverReport{
+			Iterations: atomic.LoadUint64(&b.iterations),
+			Pushed:     pushed,
+			Throughput: float64(pushed) / elapsed,
+			Methods:    rec.Reports(b.driver),
+		}
 	}
+	rep.Throughput = float64(total) / elapsed
 
-	er := &jobsv1beta.Empty{}
-	err := client.Call(pause, pipe, er)
-	if err != nil {
-		log.Println(err)
+	if tr != nil {
+		rep.Deliveries = tr.reports()
 	}
-}
 
-func destroyPipelines(rest chan<- string, client *rpc.Client, p string) {
-	pipe := &jobsv1beta.Pipelines{Pipelines: make([]string, 1)}
+	return rep, nil
+}
 
-	pipe.GetPipelines()[0] = p
+// work runs the iterations until the deadline (if set) or until all iterations (if set) are started
+func (b *bomber) work(deadline time.Time, rnd *rand.Rand) {
+	payload := strings.Repeat("a", b.cfg.PayloadSize)
 
-	er := &jobsv1beta.Empty{}
-	err := client.Call(destroy, pipe, er)
-	if err != nil {
-		log.Println(err)
-		if !strings.Contains(err.Error(), "no such pipeline") {
-			rest <- p
+	for {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return
+		}
+		if b.cfg.Iterations > 0 && atomic.AddUint64(&b.started, 1) > uint64(b.cfg.Iterations) {
+			return
 		}
+
+		b.iteration(rnd, payload)
+		atomic.AddUint64(&b.iterations, 1)
 	}
 }
 
-func list(client *rpc.Client) []string { //nolint:unused,deadcode
-	resp := &jobsv1beta.Pipelines{}
-	er := &jobsv1beta.Empty{}
-	err := client.Call("jobs.List", er, resp)
+func (b *bomber) iteration(rnd *rand.Rand, payload string) {
+	name := uuid.NewString()
+
+	err := b.call(declare, func() error { return b.client.Jobs.Declare(declarers[b.driver](name)) })
 	if err != nil {
-		log.Println(err)
+		return
 	}
+	defer cleanup(b.driver, name)
 
-	l := make([]string, len(resp.GetPipelines()))
+	_ = b.call(resume, func() error { return b.client.Jobs.Resume(name) })
 
-	for i := 0; i < len(resp.GetPipelines()); i++ {
-		l[i] = resp.GetPipelines()[i]
+	headers := map[string]*jobsv1beta.HeaderValue{"test": {Value: []string{"hello"}}}
+	if b.tr != nil {
+		headers[fakeworker.HeaderReport] = &jobsv1beta.HeaderValue{Value: []string{b.tr.addr()}}
 	}
-	return l
-}
 
-func declareSQSPipe(client *rpc.Client, n string) {
-	pipe := &jobsv1beta.DeclareRequest{Pipeline: map[string]string{
-		"driver":             "sqs",
-		"name":               n,
-		"queue":              n,
-		"prefetch":           "10",
-		"priority":           "3",
-		"visibility_timeout": "0",
-		"wait_time_seconds":  "3",
-		"tags":               `{"key":"value"}`,
-	}}
-
-	er := &jobsv1beta.Empty{}
-	err := client.Call(declare, pipe, er)
-	if err != nil {
-		log.Println(err)
-	}
-}
+	for i := 0; i < b.cfg.Jobs; i++ {
+		job := &jobsv1beta.Job{
+			Job:     "Some/Super/PHP/Class",
+			Id:      uuid.NewString(),
+			Payload: payload,
+			Headers: headers,
+			Options: &jobsv1beta.Options{
+				Priority: b.cfg.priorities.pick(rnd),
+				Pipeline: name,
+			},
+		}
 
-func declareAMQPPipe(client *rpc.Client, p string) {
-	pipe := &jobsv1beta.DeclareRequest{Pipeline: map[string]string{
-		"driver":          "amqp",
-		"name":            p,
-		"routing_key":     "test-3",
-		"queue":           "default",
-		"exchange_type":   "direct",
-		"exchange":        "amqp.default",
-		"prefetch":        "100",
-		"priority":        "4",
-		"exclusive":       "false",
-		"multiple_ask":    "false",
-		"requeue_on_fail": "false",
-	}}
-
-	er := &jobsv1beta.Empty{}
-	err := client.Call(declare, pipe, er)
-	if err != nil {
-		log.Println(err)
+		if b.tr != nil {
+			b.tr.track(b.driver, name, job.Id)
+		}
+
+		err = b.call(push, func() error { return b.client.Jobs.Push(job) })
+		if err != nil {
+			if b.tr != nil {
+				b.tr.untrack(job.Id)
+			}
+			continue
+		}
+
+		atomic.AddUint64(&b.pushed, 1)
 	}
-}
 
-func declareBeanstalkPipe(client *rpc.Client, n string) {
-	pipe := &jobsv1beta.DeclareRequest{Pipeline: map[string]string{
-		"driver":          "beanstalk",
-		"name":            n,
-		"tube":            n,
-		"reserve_timeout": "1",
-		"priority":        "3",
-		"tube_priority":   "10",
-	}}
-
-	er := &jobsv1beta.Empty{}
-	err := client.Call(declare, pipe, er)
-	if err != nil {
-		log.Println(err)
+	if b.tr != nil && b.cfg.Drain > 0 {
+		b.tr.wait(name, b.cfg.Drain)
 	}
-}
 
-func declareBoltDBPipe(client *rpc.Client, n, file string) {
-	pipe := &jobsv1beta.DeclareRequest{Pipeline: map[string]string{
-		"driver":   "boltdb",
-		"name":     n,
-		"prefetch": "100",
-		"priority": "2",
-		"file":     file,
-	}}
-
-	er := &jobsv1beta.Empty{}
-	err := client.Call(declare, pipe, er)
-	if err != nil {
-		log.Println(err)
+	_ = b.call(pause, func() error { return b.client.Jobs.Pause(name) })
+
+	for i := 0; i < destroyAttempts; i++ {
+		err = b.call(destroy, func() error { return b.client.Jobs.Destroy(name) })
+		if err == nil || strings.Contains(err.Error(), "no such pipeline") {
+			if b.tr != nil {
+				// the queued jobs are destroyed with the pipeline
+				b.tr.destroyed(name, b.cfg.Drain == 0)
+			}
+			return
+		}
+		time.Sleep(time.Millisecond * 100)
 	}
 }
 
-func declareMemoryPipe(client *rpc.Client, p string) {
-	pipe := &jobsv1beta.DeclareRequest{Pipeline: map[string]string{
-		"driver":   "memory",
-		"name":     p,
-		"prefetch": "10000",
-		"priority": "1",
-	}}
+// call records the latency and the result of the RPC call
+func (b *bomber) call(method string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	b.rec.Record(b.driver, method, time.Since(start), err)
 
-	er := &jobsv1beta.Empty{}
-	err := client.Call(declare, pipe, er)
-	if err != nil {
-		log.Println(err)
-	}
+	return err
 }
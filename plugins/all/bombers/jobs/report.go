@@ -0,0 +1,29 @@
+package main
+
+import (
+	"time"
+
+	"github.com/roadrunner-server/rr-e2e-tests/plugins/all/bombers/load"
+)
+
+// Report is the machine-readable result of the run
+type Report struct {
+	Config   *config                  `json:"config"`
+	Started  time.Time                `json:"started"`
+	Finished time.Time                `json:"finished"`
+	Elapsed  float64                  `json:"elapsed_seconds"`
+	Drivers  map[string]*DriverReport `json:"drivers"`
+	// Throughput is the pushed jobs per second for all drivers
+	Throughput float64                   `json:"throughput"`
+	Methods    map[string]*load.OpReport `json:"methods"`
+	// Deliveries is the per driver delivery verification result, only with -verify
+	Deliveries map[string]*DeliveryReport `json:"deliveries,omitempty"`
+}
+
+// DriverReport is the per driver summary
+type DriverReport struct {
+	Iterations uint64                    `json:"iterations"`
+	Pushed     uint64                    `json:"pushed"`
+	Throughput float64                   `json:"throughput"`
+	Methods    map[string]*load.OpReport `json:"methods"`
+}
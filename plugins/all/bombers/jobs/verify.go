@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/roadrunner-server/rr-e2e-tests/fakeworker"
+	"github.com/roadrunner-server/rr-e2e-tests/plugins/all/bombers/load"
+)
+
+// lostSample is the number of the lost job IDs kept in the report per driver
+const lostSample int = 100
+
+// tracker tracks every pushed job and the deliveries reported by the fakeworker jobs worker (fakeworker_report
+// header), safe for concurrent use
+type tracker struct {
+	ls   net.Listener
+	late time.Duration
+	done chan struct{}
+	wg   sync.WaitGroup
+
+	mu   sync.Mutex
+	jobs map[string]*trackedJob
+	// not delivered jobs per pipeline, and the jobs of the pipelines with the pending ones
+	pending   map[string]int
+	pipelines map[string][]*trackedJob
+	// per driver counters and the latencies from the push to the first delivery
+	unexpected map[string]uint64
+	lateJobs   map[string]uint64
+	latencies  map[string]*load.Histogram
+}
+
+type trackedJob struct {
+	driver     string
+	pipeline   string
+	pushed     time.Time
+	deliveries int
+	// the pipeline was destroyed before the delivery
+	discarded bool
+}
+
+// DeliveryReport is the per driver result of the delivery verification, the latencies are from the push to the
+// first delivery in milliseconds. Discarded are the jobs destroyed with the pipeline without the drain, lost are the
+// jobs not delivered in the drain (or in the grace at the end of the run if the pipeline failed to be destroyed).
+type DeliveryReport struct {
+	Tracked    uint64   `json:"tracked"`
+	Delivered  uint64   `json:"delivered"`
+	Lost       uint64   `json:"lost"`
+	Discarded  uint64   `json:"discarded"`
+	Duplicated uint64   `json:"duplicated"`
+	Late       uint64   `json:"late"`
+	Unexpected uint64   `json:"unexpected"`
+	LostIDs    []string `json:"lost_ids,omitempty"`
+	P50        float64  `json:"p50_ms"`
+	P99        float64  `json:"p99_ms"`
+	Max        float64  `json:"max_ms"`
+}
+
+// listen starts the listener for the deliveries on the addr, the jobs delivered later than late are reported as late
+func listen(addr string, late time.Duration) (*tracker, error) {
+	ls, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	tr := &tracker{
+		ls:         ls,
+		late:       late,
+		done:       make(chan struct{}),
+		jobs:       make(map[string]*trackedJob),
+		pending:    make(map[string]int),
+		pipelines:  make(map[string][]*trackedJob),
+		unexpected: make(map[string]uint64),
+		lateJobs:   make(map[string]uint64),
+		latencies:  make(map[string]*load.Histogram),
+	}
+
+	tr.wg.Add(1)
+	go tr.serve()
+
+	return tr, nil
+}
+
+// addr returns host:port the workers report to
+func (tr *tracker) addr() string {
+	return tr.ls.Addr().String()
+}
+
+// close stops the listener and closes the worker connections
+func (tr *tracker) close() {
+	close(tr.done)
+	_ = tr.ls.Close()
+	tr.wg.Wait()
+}
+
+func (tr *tracker) serve() {
+	defer tr.wg.Done()
+
+	for {
+		c, err := tr.ls.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		tr.wg.Add(2)
+		go func() {
+			defer tr.wg.Done()
+			tr.read(c)
+		}()
+
+		// the workers keep the connections open
+		go func() {
+			defer tr.wg.Done()
+			<-tr.done
+			_ = c.Close()
+		}()
+	}
+}
+
+func (tr *tracker) read(c net.Conn) {
+	sc := bufio.NewScanner(c)
+	for sc.Scan() {
+		d := &fakeworker.Delivery{}
+		if json.Unmarshal(sc.Bytes(), d) != nil {
+			continue
+		}
+		tr.delivered(d, time.Now())
+	}
+}
+
+// track registers the job before it is pushed, the delivery may come before the push returns
+func (tr *tracker) track(driver, pipeline, id string) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	j := &trackedJob{driver: driver, pipeline: pipeline, pushed: time.Now()}
+	tr.jobs[id] = j
+	tr.pending[pipeline]++
+	tr.pipelines[pipeline] = append(tr.pipelines[pipeline], j)
+}
+
+// untrack forgets the job which failed to push, unless it was delivered anyway
+func (tr *tracker) untrack(id string) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	j, ok := tr.jobs[id]
+	if !ok || j.deliveries > 0 {
+		return
+	}
+
+	delete(tr.jobs, id)
+	tr.settle(j.pipeline)
+}
+
+func (tr *tracker) delivered(d *fakeworker.Delivery, at time.Time) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	j, ok := tr.jobs[d.ID]
+	if !ok {
+		tr.unexpected[d.Driver]++
+		return
+	}
+
+	j.deliveries++
+	if j.deliveries > 1 {
+		return
+	}
+
+	tr.settle(j.pipeline)
+
+	lat := at.Sub(j.pushed)
+	if lat > tr.late {
+		tr.lateJobs[j.driver]++
+	}
+
+	h, ok := tr.latencies[j.driver]
+	if !ok {
+		h = load.NewHistogram()
+		tr.latencies[j.driver] = h
+	}
+	h.Record(lat)
+}
+
+// settle decrements the pending jobs of the pipeline, called under the lock
+func (tr *tracker) settle(pipeline string) {
+	tr.pending[pipeline]--
+	if tr.pending[pipeline] <= 0 {
+		delete(tr.pending, pipeline)
+		delete(tr.pipelines, pipeline)
+	}
+}
+
+// destroyed stops waiting for the jobs of the destroyed pipeline, they can't be delivered anymore. The not delivered
+// jobs are reported as discarded if the pipeline was destroyed without the drain, and as lost otherwise.
+func (tr *tracker) destroyed(pipeline string, discard bool) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	if discard {
+		for _, j := range tr.pipelines[pipeline] {
+			if j.deliveries == 0 {
+				j.discarded = true
+			}
+		}
+	}
+
+	delete(tr.pending, pipeline)
+	delete(tr.pipelines, pipeline)
+}
+
+// wait waits until all jobs of the pipeline (or of all pipelines for the empty name) are delivered, false on timeout
+func (tr *tracker) wait(pipeline string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		tr.mu.Lock()
+		n := len(tr.pending)
+		if pipeline != "" {
+			n = tr.pending[pipeline]
+		}
+		tr.mu.Unlock()
+
+		if n == 0 {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+// reports builds the per driver delivery reports
+func (tr *tracker) reports() map[string]*DeliveryReport {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	out := make(map[string]*DeliveryReport)
+	get := func(driver string) *DeliveryReport {
+		r, ok := out[driver]
+		if !ok {
+			r = &DeliveryReport{}
+			out[driver] = r
+		}
+		return r
+	}
+
+	for id, j := range tr.jobs {
+		r := get(j.driver)
+		r.Tracked++
+
+		if j.deliveries == 0 && j.discarded {
+			r.Discarded++
+			continue
+		}
+
+		if j.deliveries == 0 {
+			r.Lost++
+			if len(r.LostIDs) < lostSample {
+				r.LostIDs = append(r.LostIDs, id)
+			}
+			continue
+		}
+
+		r.Delivered++
+		if j.deliveries > 1 {
+			r.Duplicated++
+		}
+	}
+
+	for driver, h := range tr.latencies {
+		r := get(driver)
+		r.Late = tr.lateJobs[driver]
+		r.P50 = load.Ms(h.Percentile(50))
+		r.P99 = load.Ms(h.Percentile(99))
+		r.Max = load.Ms(h.Max())
+	}
+
+	for driver, n := range tr.unexpected {
+		get(driver).Unexpected = n
+	}
+
+	return out
+}
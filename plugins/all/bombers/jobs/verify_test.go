@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/rr-e2e-tests/fakeworker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deliver handles the job with the fakeworker jobs worker reporting to the tracker
+func deliver(t *testing.T, tr *tracker, driver, pipeline, id string) {
+	ctx, err := json.Marshal(&fakeworker.JobRequest{
+		ID:       id,
+		Driver:   driver,
+		Pipeline: pipeline,
+		Headers:  map[string][]string{fakeworker.HeaderReport: {tr.addr()}},
+	})
+	require.NoError(t, err)
+
+	_, err = fakeworker.JobsBehaviours().Handle(&fakeworker.Payload{Context: ctx})
+	require.NoError(t, err)
+}
+
+func TestTracker(t *testing.T) {
+	tr, err := listen("127.0.0.1:0", time.Millisecond*100)
+	require.NoError(t, err)
+
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		tr.track("memory", "p1", id)
+	}
+	tr.track("boltdb", "p2", "6")
+
+	// failed to push
+	tr.untrack("5")
+
+	deliver(t, tr, "memory", "p1", "1")
+	deliver(t, tr, "memory", "p1", "2")
+	deliver(t, tr, "memory", "p1", "2")
+	deliver(t, tr, "boltdb", "p2", "unknown")
+
+	assert.False(t, tr.wait("p2", time.Millisecond*10))
+	assert.False(t, tr.wait("p1", time.Millisecond*100))
+
+	time.Sleep(time.Millisecond * 150)
+	deliver(t, tr, "memory", "p1", "3")
+	assert.False(t, tr.wait("p1", time.Millisecond*100))
+
+	// 4 is lost
+	require.Eventually(t, func() bool {
+		reps := tr.reports()
+		return reps["memory"] != nil && reps["memory"].Delivered == 3
+	}, time.Second, time.Millisecond*10)
+	tr.close()
+
+	reps := tr.reports()
+	mem := reps["memory"]
+	assert.Equal(t, uint64(4), mem.Tracked)
+	assert.Equal(t, uint64(3), mem.Delivered)
+	assert.Equal(t, uint64(1), mem.Lost)
+	assert.Equal(t, []string{"4"}, mem.LostIDs)
+	assert.Equal(t, uint64(1), mem.Duplicated)
+	assert.Equal(t, uint64(1), mem.Late)
+	assert.GreaterOrEqual(t, mem.Max, 150.0)
+
+	bolt := reps["boltdb"]
+	assert.Equal(t, uint64(1), bolt.Tracked)
+	assert.Equal(t, uint64(1), bolt.Lost)
+	assert.Equal(t, uint64(1), bolt.Unexpected)
+}
+
+func TestTrackerDiscard(t *testing.T) {
+	tr, err := listen("127.0.0.1:0", time.Second)
+	require.NoError(t, err)
+
+	for _, id := range []string{"1", "2", "3"} {
+		tr.track("memory", "p1", id)
+	}
+	tr.track("memory", "p2", "4")
+
+	deliver(t, tr, "memory", "p1", "1")
+	require.False(t, tr.wait("", time.Millisecond*10))
+	require.Eventually(t, func() bool {
+		return tr.reports()["memory"].Delivered == 1
+	}, time.Second, time.Millisecond*10)
+
+	// destroyed without the drain, p2 is still waited for
+	tr.destroyed("p1", true)
+	assert.True(t, tr.wait("p1", time.Millisecond*10))
+	assert.False(t, tr.wait("", time.Millisecond*10))
+
+	// delivered after the discard
+	deliver(t, tr, "memory", "p1", "2")
+	require.Eventually(t, func() bool {
+		return tr.reports()["memory"].Delivered == 2
+	}, time.Second, time.Millisecond*10)
+	tr.close()
+
+	mem := tr.reports()["memory"]
+	assert.Equal(t, uint64(4), mem.Tracked)
+	assert.Equal(t, uint64(2), mem.Delivered)
+	assert.Equal(t, uint64(1), mem.Discarded)
+	assert.Equal(t, uint64(1), mem.Lost)
+	assert.Equal(t, []string{"4"}, mem.LostIDs)
+}
+
+func TestTrackerDestroyedAfterDrain(t *testing.T) {
+	tr, err := listen("127.0.0.1:0", time.Second)
+	require.NoError(t, err)
+
+	tr.track("memory", "p1", "1")
+	tr.track("memory", "p1", "2")
+	tr.track("memory", "p2", "3")
+
+	deliver(t, tr, "memory", "p1", "1")
+	assert.False(t, tr.wait("p1", time.Millisecond*100))
+
+	// destroyed after the drain, 2 is lost and not waited for, p2 failed to be destroyed
+	tr.destroyed("p1", false)
+	assert.False(t, tr.wait("", time.Millisecond*10))
+
+	deliver(t, tr, "memory", "p2", "3")
+
+	// the grace ends as soon as nothing is in flight
+	start := time.Now()
+	assert.True(t, tr.wait("", time.Second*5))
+	assert.Less(t, time.Since(start), time.Second)
+	tr.close()
+
+	mem := tr.reports()["memory"]
+	assert.Equal(t, uint64(3), mem.Tracked)
+	assert.Equal(t, uint64(2), mem.Delivered)
+	assert.Equal(t, uint64(0), mem.Discarded)
+	assert.Equal(t, uint64(1), mem.Lost)
+	assert.Equal(t, []string{"2"}, mem.LostIDs)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/roadrunner-server/rr-e2e-tests/plugins/all/bombers/load"
+)
+
+const (
+	distUniform string = "uniform"
+	distZipf    string = "zipf"
+)
+
+// config is the bomber configuration set via the flags
+type config struct {
+	RPCAddr     string        `json:"rpc_addr"`
+	Storages    []string      `json:"storages"`
+	Concurrency int           `json:"concurrency"`
+	Duration    time.Duration `json:"duration_ns"`
+	Calls       int           `json:"calls"`
+	// Keys is the size of the key space, the keys are shared by the storages
+	Keys         int           `json:"keys"`
+	Distribution string        `json:"distribution"`
+	Batch        int           `json:"batch"`
+	ValueSize    string        `json:"value_size"`
+	Mix          string        `json:"mix"`
+	TTL          time.Duration `json:"ttl_ns"`
+	Preload      bool          `json:"preload"`
+	Cleanup      bool          `json:"cleanup"`
+	Report       string        `json:"-"`
+
+	valueMin, valueMax int
+	mix                *load.Mix
+}
+
+func parseFlags(args []string) (*config, error) {
+	cfg := &config{}
+	storages := ""
+
+	fs := flag.NewFlagSet("kv-bomber", flag.ContinueOnError)
+	fs.StringVar(&cfg.RPCAddr, "rpc", "127.0.0.1:6001", "RPC address of the RoadRunner")
+	fs.StringVar(&storages, "storages", "memory", "comma separated storages from the kv section of the RR config, .rr-kv-bomber.yaml names them after the drivers")
+	fs.IntVar(&cfg.Concurrency, "concurrency", 10, "workers per storage")
+	fs.DurationVar(&cfg.Duration, "duration", 0, "run for the duration, 0 means until the calls are done")
+	fs.IntVar(&cfg.Calls, "calls", 0, "calls per storage, 0 means until the duration ends")
+	fs.IntVar(&cfg.Keys, "keys", 10000, "size of the key space")
+	fs.StringVar(&cfg.Distribution, "distribution", distUniform, "key distribution: uniform or zipf (a few hot keys)")
+	fs.IntVar(&cfg.Batch, "batch", 1, "keys per call")
+	fs.StringVar(&cfg.ValueSize, "value-size", "128", "value size in bytes or the min-max range")
+	fs.StringVar(&cfg.Mix, "mix", "set:20,mget:50,has:10,ttl:10,mexpire:5,delete:5", "weighted operations: "+strings.Join(knownOps(), ", "))
+	fs.DurationVar(&cfg.TTL, "ttl", 0, "timeout of the set keys, 0 means no timeout, mexpire uses 1h when 0")
+	fs.BoolVar(&cfg.Preload, "preload", true, "set every key of the key space before the run")
+	fs.BoolVar(&cfg.Cleanup, "cleanup", true, "delete the key space after the run")
+	fs.StringVar(&cfg.Report, "report", "kv-report.json", "path of the JSON report, - for stdout")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Storages = load.SplitList(storages)
+	if len(cfg.Storages) == 0 {
+		return nil, errors.New("no storages")
+	}
+
+	if cfg.Concurrency <= 0 || cfg.Keys <= 0 || cfg.Batch <= 0 || cfg.Calls < 0 || cfg.Duration < 0 || cfg.TTL < 0 {
+		return nil, errors.New("concurrency, keys and batch should be positive, calls, duration and ttl should not be negative")
+	}
+	if cfg.Batch > cfg.Keys {
+		return nil, errors.New("batch should not be greater than the key space")
+	}
+	if cfg.Duration == 0 && cfg.Calls == 0 {
+		return nil, errors.New("either duration or calls should be set")
+	}
+	if cfg.TTL > 0 && cfg.TTL < time.Second {
+		// the timeouts are RFC3339 strings, the precision is a second
+		return nil, errors.New("ttl should be at least 1s")
+	}
+
+	switch cfg.Distribution {
+	case distUniform, distZipf:
+	default:
+		return nil, fmt.Errorf("unknown distribution: %s", cfg.Distribution)
+	}
+
+	cfg.valueMin, cfg.valueMax, err = parseRange(cfg.ValueSize)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.mix, err = load.ParseMix(cfg.Mix, "operation", func(name string) bool {
+		_, ok := methods[name]
+		return ok
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// parseRange parses "n" or "lo-hi"
+func parseRange(s string) (int, int, error) {
+	lo, hi, ok := strings.Cut(s, "-")
+	if !ok {
+		hi = lo
+	}
+
+	l, err1 := strconv.Atoi(strings.TrimSpace(lo))
+	h, err2 := strconv.Atoi(strings.TrimSpace(hi))
+	if err1 != nil || err2 != nil || l < 0 || h < l {
+		return 0, 0, fmt.Errorf("invalid value size: %s", s)
+	}
+
+	return l, h, nil
+}
+
+func knownOps() []string {
+	names := make([]string, 0, len(methods))
+	for name := range methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"net"
+	"net/rpc"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	kvv1 "github.com/roadrunner-server/api/v2/proto/kv/v1"
+	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKV is the in-memory kv plugin RPC, registered as "kv"
+type fakeKV struct {
+	mu    sync.Mutex
+	items map[string]*kvv1.Item
+}
+
+func (k *fakeKV) Has(in *kvv1.Request, out *kvv1.Response) error {
+	return k.lookup(in, out, false)
+}
+
+func (k *fakeKV) MGet(in *kvv1.Request, out *kvv1.Response) error {
+	return k.lookup(in, out, true)
+}
+
+func (k *fakeKV) TTL(in *kvv1.Request, out *kvv1.Response) error {
+	return k.lookup(in, out, false)
+}
+
+func (k *fakeKV) Set(in *kvv1.Request, _ *kvv1.Response) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for _, item := range in.GetItems() {
+		k.items[in.GetStorage()+item.GetKey()] = &kvv1.Item{Key: item.GetKey(), Value: item.GetValue(), Timeout: item.GetTimeout()}
+	}
+
+	return nil
+}
+
+func (k *fakeKV) MExpire(in *kvv1.Request, _ *kvv1.Response) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for _, item := range in.GetItems() {
+		if it, ok := k.items[in.GetStorage()+item.GetKey()]; ok {
+			it.Timeout = item.GetTimeout()
+		}
+	}
+
+	return nil
+}
+
+func (k *fakeKV) Delete(in *kvv1.Request, _ *kvv1.Response) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for _, item := range in.GetItems() {
+		delete(k.items, in.GetStorage()+item.GetKey())
+	}
+
+	return nil
+}
+
+func (k *fakeKV) lookup(in *kvv1.Request, out *kvv1.Response, value bool) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for _, item := range in.GetItems() {
+		if it, ok := k.items[in.GetStorage()+item.GetKey()]; ok {
+			found := &kvv1.Item{Key: it.GetKey(), Timeout: it.GetTimeout()}
+			if value {
+				found.Value = it.GetValue()
+			}
+			out.Items = append(out.Items, found)
+		}
+	}
+
+	return nil
+}
+
+func (k *fakeKV) len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return len(k.items)
+}
+
+// serveKV starts the goridge RPC server with the fake kv plugin
+func serveKV(t *testing.T) (*fakeKV, string) {
+	kv := &fakeKV{items: make(map[string]*kvv1.Item)}
+	srv := rpc.NewServer()
+	require.NoError(t, srv.RegisterName("kv", kv))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go srv.ServeCodec(goridgeRpc.NewCodec(conn))
+		}
+	}()
+
+	return kv, l.Addr().String()
+}
+
+func TestParseFlags(t *testing.T) {
+	cfg, err := parseFlags([]string{"-calls", "10", "-value-size", "16-64", "-mix", "set:1,mget,delete:0"})
+	require.NoError(t, err)
+	assert.Equal(t, 16, cfg.valueMin)
+	assert.Equal(t, 64, cfg.valueMax)
+	assert.Equal(t, []string{"set", "mget"}, cfg.mix.Names())
+
+	for _, args := range [][]string{
+		{"-calls", "10", "-mix", "get:1"},
+		{"-calls", "10", "-mix", "set:0"},
+		{"-calls", "10", "-value-size", "64-16"},
+		{"-calls", "10", "-distribution", "normal"},
+		{"-calls", "10", "-keys", "1", "-batch", "2"},
+		{"-calls", "10", "-ttl", "500ms"},
+		{"-storages", ""},
+		{},
+	} {
+		_, err = parseFlags(args)
+		assert.Error(t, err, strings.Join(args, " "))
+	}
+}
+
+func TestRun(t *testing.T) {
+	kv, addr := serveKV(t)
+
+	cfg, err := parseFlags([]string{
+		"-rpc", addr,
+		"-storages", "memory,redis",
+		"-concurrency", "4",
+		"-calls", "400",
+		"-keys", "250",
+		"-batch", "3",
+		"-distribution", "zipf",
+		"-value-size", "8-32",
+		"-mix", "mget:4,has:2,ttl:1,mexpire:1",
+		"-ttl", "1m",
+	})
+	require.NoError(t, err)
+
+	rep, err := run(cfg)
+	require.NoError(t, err)
+
+	// the key space is deleted after the run
+	assert.Equal(t, 0, kv.len())
+	assert.Greater(t, rep.Throughput, float64(0))
+	assert.Equal(t, uint64(800), rep.Methods[mget].Calls+rep.Methods[has].Calls+rep.Methods[ttl].Calls+rep.Methods[mexpire].Calls)
+
+	for _, storage := range []string{"memory", "redis"} {
+		sr := rep.Storages[storage]
+		require.NotNil(t, sr, storage)
+		assert.Equal(t, uint64(400), sr.Calls)
+		// nothing is deleted during the run, all preloaded keys are found
+		assert.Equal(t, float64(1), sr.HitRatio)
+		require.NotNil(t, sr.Preload)
+		assert.Equal(t, uint64(3), sr.Preload.Calls)
+		assert.NotContains(t, sr.Methods, set)
+		assert.Greater(t, sr.Rates[mget], float64(0))
+		for method, op := range sr.Methods {
+			assert.Zero(t, op.Errors, method)
+		}
+	}
+}
+
+func TestRunWithoutPreload(t *testing.T) {
+	kv, addr := serveKV(t)
+
+	cfg, err := parseFlags([]string{"-rpc", addr, "-duration", "200ms", "-keys", "50", "-mix", "set:1,mget:1", "-preload=false", "-cleanup=false"})
+	require.NoError(t, err)
+
+	start := time.Now()
+	rep, err := run(cfg)
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), time.Second*5)
+
+	sr := rep.Storages["memory"]
+	assert.Nil(t, sr.Preload)
+	assert.Less(t, sr.HitRatio, float64(1))
+	assert.Greater(t, kv.len(), 0)
+}
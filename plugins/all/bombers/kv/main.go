@@ -0,0 +1,316 @@
+// Command kv is the load generator for the kv plugin. The workers call kv.Set, kv.MGet, kv.Has, kv.MExpire, kv.TTL
+// and kv.Delete in the configured proportions (-mix) over the shared key space, the keys are picked uniformly or
+// with the zipf distribution, the values are random bytes of the configured size. The key space is set before the
+// run (-preload) so the reads hit, and deleted after it (-cleanup). At the end the JSON report with the throughput,
+// the hit ratio and the latency percentiles per storage and RPC method is written, so the storages can be compared
+// on the same workload.
+package main
+
+import (
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	kvv1 "github.com/roadrunner-server/api/v2/proto/kv/v1"
+	"github.com/roadrunner-server/rr-e2e-tests/plugins/all/bombers/load"
+	"github.com/roadrunner-server/rr-e2e-tests/rpcclient"
+)
+
+const (
+	set     string = "kv.Set"
+	mget    string = "kv.MGet"
+	has     string = "kv.Has"
+	mexpire string = "kv.MExpire"
+	ttl     string = "kv.TTL"
+	del     string = "kv.Delete"
+
+	// keys per call for the preload and the cleanup
+	chunk int = 100
+	// mexpire timeout when -ttl is not set
+	defaultExpire time.Duration = time.Hour
+)
+
+// methods maps the -mix operations to the RPC methods
+var methods = map[string]string{ //nolint:gochecknoglobals
+	"set":     set,
+	"mget":    mget,
+	"has":     has,
+	"mexpire": mexpire,
+	"ttl":     ttl,
+	"delete":  del,
+}
+
+func main() {
+	cfg, err := parseFlags(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rep, err := run(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = load.WriteJSON(cfg.Report, rep)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// bomber runs the workers of a single storage
+type bomber struct {
+	cfg     *config
+	storage string
+	client  *rpcclient.Client
+	rec     *load.Recorder
+	// prefix of the keys of the run
+	prefix string
+
+	// calls started, used to stop after cfg.Calls
+	started uint64
+	calls   uint64
+	// keys requested and found by kv.MGet and kv.Has
+	requested uint64
+	found     uint64
+}
+
+func run(cfg *config) (*Report, error) {
+	rec := load.NewRecorder()
+	preload := load.NewRecorder()
+	prefix := "kv-bomber:" + uuid.NewString() + ":"
+	bombers := make([]*bomber, 0, len(cfg.Storages))
+
+	for i := 0; i < len(cfg.Storages); i++ {
+		client, err := rpcclient.Dial(cfg.RPCAddr)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = client.Close()
+		}()
+
+		bombers = append(bombers, &bomber{cfg: cfg, storage: cfg.Storages[i], client: client, rec: rec, prefix: prefix})
+	}
+
+	if cfg.Preload {
+		for i := 0; i < len(bombers); i++ {
+			bombers[i].preload(preload)
+		}
+	}
+
+	var deadline time.Time
+	if cfg.Duration > 0 {
+		deadline = time.Now().Add(cfg.Duration)
+	}
+
+	stopCh := make(chan struct{})
+	go load.Progress("RATE", func() uint64 {
+		cur := uint64(0)
+		for i := 0; i < len(bombers); i++ {
+			cur += atomic.LoadUint64(&bombers[i].calls)
+		}
+		return cur
+	}, stopCh)
+
+	started := time.Now()
+	wg := &sync.WaitGroup{}
+	for i := 0; i < len(bombers); i++ {
+		for j := 0; j < cfg.Concurrency; j++ {
+			wg.Add(1)
+			go func(b *bomber, seed int64) {
+				defer wg.Done()
+				b.work(deadline, rand.New(rand.NewSource(seed))) //nolint:gosec
+			}(bombers[i], time.Now().UnixNano()+int64(i*cfg.Concurrency+j))
+		}
+	}
+
+	wg.Wait()
+	close(stopCh)
+	finished := time.Now()
+
+	if cfg.Cleanup {
+		for i := 0; i < len(bombers); i++ {
+			bombers[i].cleanup()
+		}
+	}
+
+	elapsed := finished.Sub(started).Seconds()
+	rep := &Report{
+		Config:   cfg,
+		Started:  started,
+		Finished: finished,
+		Elapsed:  elapsed,
+		Storages: make(map[string]*StorageReport, len(bombers)),
+		Methods:  rec.TotalReports(),
+	}
+
+	total := uint64(0)
+	for i := 0; i < len(bombers); i++ {
+		b := bombers[i]
+		calls := atomic.LoadUint64(&b.calls)
+		total += calls
+
+		sr := &StorageReport{
+			Calls:      calls,
+			Throughput: float64(calls) / elapsed,
+			Rates:      make(map[string]float64),
+			Methods:    rec.Reports(b.storage),
+			Preload:    preload.Reports(b.storage)[set],
+		}
+		for method, op := range sr.Methods {
+			sr.Rates[method] = float64(op.Calls) / elapsed
+		}
+		if requested := atomic.LoadUint64(&b.requested); requested > 0 {
+			sr.HitRatio = float64(atomic.LoadUint64(&b.found)) / float64(requested)
+		}
+
+		rep.Storages[b.storage] = sr
+	}
+	rep.Throughput = float64(total) / elapsed
+
+	return rep, nil
+}
+
+// key returns the key of the key space by the index
+func (b *bomber) key(i int) string {
+	return b.prefix + strconv.Itoa(i)
+}
+
+// timeout returns the RFC3339 timeout of the set keys, empty without -ttl
+func (b *bomber) timeout() string {
+	if b.cfg.TTL == 0 {
+		return ""
+	}
+
+	return time.Now().Add(b.cfg.TTL).Format(time.RFC3339)
+}
+
+// preload sets every key of the key space in chunks, the latencies are recorded apart from the run
+func (b *bomber) preload(rec *load.Recorder) {
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano())) //nolint:gosec
+	v := newValues(b.cfg, rnd)
+
+	for lo := 0; lo < b.cfg.Keys; lo += chunk {
+		items := make([]*kvv1.Item, 0, chunk)
+		for i := lo; i < lo+chunk && i < b.cfg.Keys; i++ {
+			items = append(items, &kvv1.Item{Key: b.key(i), Value: v.next(rnd), Timeout: b.timeout()})
+		}
+
+		start := time.Now()
+		err := b.client.KV.Set(b.storage, items...)
+		rec.Record(b.storage, set, time.Since(start), err)
+	}
+}
+
+// cleanup deletes the key space
+func (b *bomber) cleanup() {
+	for lo := 0; lo < b.cfg.Keys; lo += chunk {
+		keys := make([]string, 0, chunk)
+		for i := lo; i < lo+chunk && i < b.cfg.Keys; i++ {
+			keys = append(keys, b.key(i))
+		}
+
+		err := b.client.KV.Delete(b.storage, keys...)
+		if err != nil {
+			log.Printf("cleanup of %s: %v", b.storage, err)
+			return
+		}
+	}
+}
+
+// work runs the calls until the deadline (if set) or until all calls (if set) are started
+func (b *bomber) work(deadline time.Time, rnd *rand.Rand) {
+	v := newValues(b.cfg, rnd)
+
+	var zipf *rand.Zipf
+	if b.cfg.Distribution == distZipf {
+		zipf = rand.NewZipf(rnd, 1.1, 1, uint64(b.cfg.Keys-1))
+	}
+
+	keys := make([]string, b.cfg.Batch)
+	for {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return
+		}
+		if b.cfg.Calls > 0 && atomic.AddUint64(&b.started, 1) > uint64(b.cfg.Calls) {
+			return
+		}
+
+		for i := 0; i < len(keys); i++ {
+			if zipf != nil {
+				keys[i] = b.key(int(zipf.Uint64()))
+				continue
+			}
+			keys[i] = b.key(rnd.Intn(b.cfg.Keys))
+		}
+
+		b.call(methods[b.cfg.mix.Pick(rnd)], keys, v, rnd)
+		atomic.AddUint64(&b.calls, 1)
+	}
+}
+
+// call calls the method with the keys and records the latency and the result
+func (b *bomber) call(method string, keys []string, v *values, rnd *rand.Rand) {
+	var err error
+	var found []*kvv1.Item
+
+	start := time.Now()
+	switch method {
+	case set:
+		items := make([]*kvv1.Item, len(keys))
+		for i := 0; i < len(keys); i++ {
+			items[i] = &kvv1.Item{Key: keys[i], Value: v.next(rnd), Timeout: b.timeout()}
+		}
+		start = time.Now()
+		err = b.client.KV.Set(b.storage, items...)
+	case mexpire:
+		exp := b.cfg.TTL
+		if exp == 0 {
+			exp = defaultExpire
+		}
+		timeout := time.Now().Add(exp).Format(time.RFC3339)
+
+		items := make([]*kvv1.Item, len(keys))
+		for i := 0; i < len(keys); i++ {
+			items[i] = &kvv1.Item{Key: keys[i], Timeout: timeout}
+		}
+		start = time.Now()
+		err = b.client.KV.MExpire(b.storage, items...)
+	case mget:
+		found, err = b.client.KV.MGet(b.storage, keys...)
+	case has:
+		found, err = b.client.KV.Has(b.storage, keys...)
+	case ttl:
+		_, err = b.client.KV.TTL(b.storage, keys...)
+	case del:
+		err = b.client.KV.Delete(b.storage, keys...)
+	}
+	b.rec.Record(b.storage, method, time.Since(start), err)
+
+	if err == nil && (method == mget || method == has) {
+		atomic.AddUint64(&b.requested, uint64(len(keys)))
+		atomic.AddUint64(&b.found, uint64(len(found)))
+	}
+}
+
+// values returns the random values of the configured size, the values share the buffer
+type values struct {
+	buf      []byte
+	min, max int
+}
+
+func newValues(cfg *config, rnd *rand.Rand) *values {
+	v := &values{buf: make([]byte, cfg.valueMax), min: cfg.valueMin, max: cfg.valueMax}
+	_, _ = rnd.Read(v.buf)
+
+	return v
+}
+
+func (v *values) next(rnd *rand.Rand) []byte {
+	return v.buf[:v.min+rnd.Intn(v.max-v.min+1)]
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"time"
+
+	"github.com/roadrunner-server/rr-e2e-tests/plugins/all/bombers/load"
+)
+
+// Report is the machine-readable result of the run
+type Report struct {
+	Config   *config                   `json:"config"`
+	Started  time.Time                 `json:"started"`
+	Finished time.Time                 `json:"finished"`
+	Elapsed  float64                   `json:"elapsed_seconds"`
+	Storages map[string]*StorageReport `json:"storages"`
+	// Throughput is the calls per second for all storages
+	Throughput float64                   `json:"throughput"`
+	Methods    map[string]*load.OpReport `json:"methods"`
+}
+
+// StorageReport is the per storage summary
+type StorageReport struct {
+	Calls      uint64  `json:"calls"`
+	Throughput float64 `json:"throughput"`
+	// Rates are the calls per second per method
+	Rates map[string]float64 `json:"rates"`
+	// HitRatio is the found keys to the requested ones by kv.MGet and kv.Has
+	HitRatio float64                   `json:"hit_ratio"`
+	Preload  *load.OpReport            `json:"preload,omitempty"`
+	Methods  map[string]*load.OpReport `json:"methods"`
+}
@@ -0,0 +1,144 @@
+package load
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+const (
+	// 2048 sub-buckets keep 3 significant digits (the relative error is below 0.1%)
+	subBucketBits  int   = 11
+	subBucketCount int64 = 1 << subBucketBits
+	subBucketHalf  int64 = subBucketCount / 2
+	// the highest trackable latency, the longer ones are recorded as this value
+	highestTrackable time.Duration = time.Hour
+)
+
+// Histogram is the high dynamic range (log-linear) histogram of the latencies in nanoseconds, as in HdrHistogram:
+// the values below subBucketCount are counted exactly, above them every power of two range is split into
+// subBucketHalf buckets. Not safe for concurrent use.
+type Histogram struct {
+	counts []uint64
+	total  uint64
+	sum    time.Duration
+	min    time.Duration
+	max    time.Duration
+}
+
+// NewHistogram creates the empty histogram tracking the latencies up to an hour
+func NewHistogram() *Histogram {
+	return &Histogram{counts: make([]uint64, index(int64(highestTrackable))+1)}
+}
+
+// index returns the bucket index of the value
+func index(v int64) int {
+	shift := bits.Len64(uint64(v)) - subBucketBits
+	if shift <= 0 {
+		return int(v)
+	}
+
+	sub := v >> shift
+	return int(subBucketCount + int64(shift-1)*subBucketHalf + sub - subBucketHalf)
+}
+
+// highest returns the highest value counted in the bucket
+func highest(idx int) int64 {
+	if int64(idx) < subBucketCount {
+		return int64(idx)
+	}
+
+	i := int64(idx) - subBucketCount
+	shift := i/subBucketHalf + 1
+	sub := i%subBucketHalf + subBucketHalf
+
+	return (sub+1)<<shift - 1
+}
+
+// Record records the latency, the negative ones as zero and the ones above an hour as an hour
+func (h *Histogram) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	if d > highestTrackable {
+		d = highestTrackable
+	}
+
+	h.counts[index(int64(d))]++
+	if h.total == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.total++
+	h.sum += d
+}
+
+// Merge adds the values of the other histogram
+func (h *Histogram) Merge(o *Histogram) {
+	if o.total == 0 {
+		return
+	}
+
+	for i := 0; i < len(o.counts); i++ {
+		h.counts[i] += o.counts[i]
+	}
+	if h.total == 0 || o.min < h.min {
+		h.min = o.min
+	}
+	if o.max > h.max {
+		h.max = o.max
+	}
+	h.total += o.total
+	h.sum += o.sum
+}
+
+// Percentile returns the value below or equal to which p percent of the values fall, within the histogram precision
+func (h *Histogram) Percentile(p float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+
+	target := uint64(math.Ceil(p / 100 * float64(h.total)))
+	if target == 0 {
+		target = 1
+	}
+
+	count := uint64(0)
+	for i := 0; i < len(h.counts); i++ {
+		count += h.counts[i]
+		if count >= target {
+			if v := time.Duration(highest(i)); v < h.max {
+				return v
+			}
+			return h.max
+		}
+	}
+
+	return h.max
+}
+
+// Mean returns the average of the recorded values
+func (h *Histogram) Mean() time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+
+	return h.sum / time.Duration(h.total)
+}
+
+// Count returns the number of the recorded values
+func (h *Histogram) Count() uint64 {
+	return h.total
+}
+
+// Max returns the highest recorded value
+func (h *Histogram) Max() time.Duration {
+	return h.max
+}
+
+// Min returns the lowest recorded value
+func (h *Histogram) Min() time.Duration {
+	return h.min
+}
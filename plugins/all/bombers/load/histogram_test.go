@@ -0,0 +1,45 @@
+package load
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogramIndex(t *testing.T) {
+	// every value is within its bucket and the bucket error is below 0.1%
+	for _, v := range []int64{0, 1, 2047, 2048, 2049, 4095, 4096, 1e6, 123456789, int64(time.Hour)} {
+		idx := index(v)
+		assert.GreaterOrEqual(t, highest(idx), v, v)
+		if idx > 0 {
+			assert.Less(t, highest(idx-1), v, v)
+		}
+		assert.LessOrEqual(t, float64(highest(idx)-v), float64(v)/1000+1, v)
+	}
+}
+
+func TestHistogramPercentiles(t *testing.T) {
+	h := NewHistogram()
+	assert.Equal(t, time.Duration(0), h.Percentile(99))
+
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * time.Microsecond)
+	}
+	// the tail stall, 1002 values in total
+	h.Record(time.Second * 3)
+	h.Record(time.Hour * 2)
+
+	assert.InEpsilon(t, float64(501*time.Microsecond), float64(h.Percentile(50)), 0.001)
+	assert.InEpsilon(t, float64(992*time.Microsecond), float64(h.Percentile(99)), 0.001)
+	assert.InEpsilon(t, float64(time.Second*3), float64(h.Percentile(99.9)), 0.001)
+	assert.Equal(t, time.Hour, h.Percentile(100))
+	assert.Equal(t, time.Hour, h.max)
+	assert.Equal(t, time.Microsecond, h.min)
+
+	o := NewHistogram()
+	o.Record(time.Nanosecond)
+	h.Merge(o)
+	assert.Equal(t, uint64(1003), h.total)
+	assert.Equal(t, time.Nanosecond, h.min)
+}
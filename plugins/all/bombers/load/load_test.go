@@ -0,0 +1,196 @@
+package load
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArrival(t *testing.T) {
+	// 10/s for 1s, then 10 -> 30/s for 1s (20 arrivals), then nothing
+	phases := []Phase{Constant(time.Second, 10), {Duration: time.Second, From: 10, To: 30}}
+
+	off, ok := arrival(phases, 1)
+	require.True(t, ok)
+	assert.Equal(t, time.Millisecond*100, off)
+
+	off, ok = arrival(phases, 10)
+	require.True(t, ok)
+	assert.Equal(t, time.Second, off)
+
+	// 10t + 10t^2 = 20 at t = 1
+	off, ok = arrival(phases, 30)
+	require.True(t, ok)
+	assert.InDelta(t, float64(time.Second*2), float64(off), float64(time.Microsecond))
+
+	_, ok = arrival(phases, 31)
+	assert.False(t, ok)
+
+	// the ramp from zero
+	off, ok = arrival([]Phase{{Duration: time.Second * 2, From: 0, To: 10}}, 10)
+	require.True(t, ok)
+	assert.InDelta(t, float64(time.Second*2), float64(off), float64(time.Microsecond))
+
+	assert.Error(t, ValidatePhases(nil))
+	assert.Error(t, ValidatePhases([]Phase{{Duration: time.Second, From: -1}}))
+	assert.NoError(t, ValidatePhases(phases))
+}
+
+func TestPace(t *testing.T) {
+	calls := int64(0)
+	start := time.Now()
+	res := Pace(context.Background(), []Phase{Constant(time.Millisecond*500, 100)}, 10, func(scheduled time.Time) {
+		atomic.AddInt64(&calls, 1)
+		assert.False(t, time.Now().Before(scheduled))
+	})
+
+	assert.Equal(t, uint64(50), res.Started)
+	assert.Equal(t, uint64(0), res.Dropped)
+	assert.Equal(t, int64(50), atomic.LoadInt64(&calls))
+	assert.GreaterOrEqual(t, time.Since(start), time.Millisecond*490)
+}
+
+func TestPaceDrops(t *testing.T) {
+	// the calls are slower than the arrivals, the open loop does not wait for them
+	res := Pace(context.Background(), []Phase{Constant(time.Millisecond*500, 100)}, 2, func(time.Time) {
+		time.Sleep(time.Millisecond * 100)
+	})
+
+	assert.Equal(t, uint64(50), res.Started+res.Dropped)
+	assert.Greater(t, res.Dropped, uint64(30))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	res = Pace(ctx, []Phase{Constant(time.Hour, 10)}, 1, func(time.Time) {})
+	assert.LessOrEqual(t, res.Started, uint64(2))
+}
+
+func TestPaceZeroRate(t *testing.T) {
+	// the phase without the arrivals still takes its duration
+	start := time.Now()
+	res := Pace(context.Background(), []Phase{Constant(time.Millisecond*300, 0)}, 1, func(time.Time) {})
+	assert.Equal(t, uint64(0), res.Started)
+	assert.GreaterOrEqual(t, time.Since(start), time.Millisecond*290)
+
+	// the arrivals start after the idle phase
+	first := time.Time{}
+	start = time.Now()
+	res = Pace(context.Background(), []Phase{Constant(time.Millisecond*300, 0), Constant(time.Millisecond*200, 10)}, 1, func(scheduled time.Time) {
+		if first.IsZero() {
+			first = scheduled
+		}
+	})
+	assert.Equal(t, uint64(2), res.Started)
+	assert.GreaterOrEqual(t, first.Sub(start), time.Millisecond*300)
+	assert.GreaterOrEqual(t, time.Since(start), time.Millisecond*490)
+}
+
+func TestRecorder(t *testing.T) {
+	rec := NewRecorder()
+	for i := 1; i <= 100; i++ {
+		rec.Record("memory", "jobs.Push", time.Duration(i)*time.Millisecond, nil)
+		rec.Record("boltdb", "jobs.Push", time.Duration(i)*time.Millisecond*2, nil)
+	}
+	rec.Record("memory", "jobs.Destroy", time.Millisecond, errors.New("busy"))
+	rec.Record("boltdb", "jobs.Destroy", time.Millisecond, errors.New("busy"))
+	rec.RecordCode("http", "GET", time.Millisecond, "200", nil)
+	rec.RecordCode("http", "GET", time.Millisecond, "503", errors.New("status 503"))
+
+	reps := rec.Reports("memory")
+	require.Contains(t, reps, "jobs.Push")
+	assert.Equal(t, uint64(100), reps["jobs.Push"].Calls)
+	assert.Equal(t, uint64(0), reps["jobs.Push"].Errors)
+	assert.InEpsilon(t, 50.0, reps["jobs.Push"].P50, 0.001)
+	assert.InEpsilon(t, 90.0, reps["jobs.Push"].P90, 0.001)
+	assert.InEpsilon(t, 99.0, reps["jobs.Push"].P99, 0.001)
+	assert.Equal(t, 100.0, reps["jobs.Push"].P999)
+	assert.Equal(t, 100.0, reps["jobs.Push"].Max)
+	assert.Equal(t, 50.5, reps["jobs.Push"].Mean)
+	assert.Equal(t, uint64(101), rec.Calls("memory"))
+
+	total := rec.TotalReports()
+	assert.Equal(t, uint64(200), total["jobs.Push"].Calls)
+	assert.Equal(t, 200.0, total["jobs.Push"].Max)
+	assert.Equal(t, map[string]uint64{"busy": 2}, total["jobs.Destroy"].ErrorCounts)
+	assert.Equal(t, map[string]uint64{"busy": 1}, rec.Reports("boltdb")["jobs.Destroy"].ErrorCounts)
+
+	assert.Equal(t, map[string]uint64{"200": 1, "503": 1}, rec.Reports("http")["GET"].Codes)
+	assert.Equal(t, uint64(1), rec.Reports("http")["GET"].Errors)
+}
+
+func TestRecorderErrorMessages(t *testing.T) {
+	rec := NewRecorder()
+	rec.Record("memory", "jobs.Destroy", time.Millisecond, errors.New("pipeline 5b9a1c3e-0f4d-4a8e-9c2b-7d6e5f4a3b21 is busy"))
+	rec.Record("memory", "jobs.Destroy", time.Millisecond, errors.New("pipeline 0c1d2e3f-4a5b-4c6d-8e7f-9a0b1c2d3e4f is busy"))
+	rec.Record("memory", "jobs.Push", time.Millisecond, errors.New("dial tcp 127.0.0.1:6001: connection refused"))
+
+	// the distinct messages are capped
+	for i := 0; i < maxMessages+10; i++ {
+		rec.Record("memory", "jobs.Pause", time.Millisecond, errors.New("error "+strings.Repeat("x", i)))
+	}
+
+	reps := rec.Reports("memory")
+	assert.Equal(t, map[string]uint64{"pipeline <id> is busy": 2}, reps["jobs.Destroy"].ErrorCounts)
+	assert.Equal(t, map[string]uint64{"dial tcp 127.0.0.1:<n>: connection refused": 1}, reps["jobs.Push"].ErrorCounts)
+
+	pause := reps["jobs.Pause"]
+	assert.Equal(t, uint64(maxMessages+10), pause.Errors)
+	assert.Len(t, pause.ErrorCounts, maxMessages+1)
+	assert.Equal(t, uint64(10), pause.ErrorCounts[otherMessages])
+}
+
+func TestRecorderDrop(t *testing.T) {
+	rec := NewRecorder()
+	rec.Record("http", "GET", time.Millisecond*10, nil)
+	rec.Drop("http", "GET", 5)
+	rec.Drop("http", "GET", 0)
+	rec.Drop("grpc", "Echo", 3)
+
+	rep := rec.Reports("http")["GET"]
+	assert.Equal(t, uint64(1), rep.Calls)
+	assert.Equal(t, uint64(0), rep.Errors)
+	assert.Equal(t, uint64(5), rep.Dropped)
+	assert.Equal(t, 10.0, rep.Max)
+
+	// dropped only
+	rep = rec.Reports("grpc")["Echo"]
+	assert.Equal(t, uint64(0), rep.Calls)
+	assert.Equal(t, uint64(3), rep.Dropped)
+
+	assert.Equal(t, uint64(5), rec.TotalReports()["GET"].Dropped)
+}
+
+func TestParseMix(t *testing.T) {
+	known := func(name string) bool { return name == "a" || name == "b" || name == "c" }
+
+	m, err := ParseMix("a:3, b,c:0", "operation", known)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, m.Names())
+
+	rnd := rand.New(rand.NewSource(1)) //nolint:gosec
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		counts[m.Pick(rnd)]++
+	}
+	assert.InDelta(t, 7500, counts["a"], 300)
+	assert.InDelta(t, 2500, counts["b"], 300)
+
+	_, err = ParseMix("d:1", "operation", known)
+	assert.EqualError(t, err, "unknown operation: d")
+	_, err = ParseMix("a:-1", "method", known)
+	assert.EqualError(t, err, "invalid method weight: a:-1")
+	_, err = ParseMix("a:0,", "method", known)
+	assert.EqualError(t, err, "no methods")
+}
+
+func TestSplitList(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, SplitList(" a,, b ,"))
+	assert.Empty(t, SplitList(""))
+}
@@ -0,0 +1,101 @@
+package load
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// Weights is the weighted random choice of the index, the indexes are in the order of Add
+type Weights struct {
+	weights []int
+	total   int
+}
+
+// Add appends the index with the weight, the weight should be positive
+func (w *Weights) Add(weight int) {
+	w.weights = append(w.weights, weight)
+	w.total += weight
+}
+
+// Total returns the sum of the weights
+func (w *Weights) Total() int {
+	return w.total
+}
+
+// Pick returns the random index according to the weights, there should be at least one index
+func (w *Weights) Pick(rnd *rand.Rand) int {
+	n := rnd.Intn(w.total)
+	for i := 0; i < len(w.weights); i++ {
+		if n < w.weights[i] {
+			return i
+		}
+		n -= w.weights[i]
+	}
+
+	// unreachable, the weights sum up to the total
+	return len(w.weights) - 1
+}
+
+// Mix is the weighted distribution of the names (operations, methods)
+type Mix struct {
+	names   []string
+	weights Weights
+}
+
+// ParseMix parses "set:20,mget:50", the weight defaults to 1 and the zero weight excludes the name. kind is the name
+// of the entries in the errors (operation, method), known reports whether the name is valid.
+func ParseMix(s, kind string, known func(name string) bool) (*Mix, error) {
+	m := &Mix{}
+	for _, entry := range SplitList(s) {
+		name, weight, ok := strings.Cut(entry, ":")
+		w := 1
+		if ok {
+			var err error
+			w, err = strconv.Atoi(weight)
+			if err != nil || w < 0 {
+				return nil, fmt.Errorf("invalid %s weight: %s", kind, entry)
+			}
+		}
+
+		if !known(name) {
+			return nil, fmt.Errorf("unknown %s: %s", kind, name)
+		}
+		if w == 0 {
+			continue
+		}
+
+		m.names = append(m.names, name)
+		m.weights.Add(w)
+	}
+
+	if len(m.names) == 0 {
+		return nil, errors.New("no " + kind + "s")
+	}
+
+	return m, nil
+}
+
+// Names returns the names with the non-zero weight in the order of the mix
+func (m *Mix) Names() []string {
+	return m.names
+}
+
+// Pick returns the random name according to the weights
+func (m *Mix) Pick(rnd *rand.Rand) string {
+	return m.names[m.weights.Pick(rnd)]
+}
+
+// SplitList splits the comma separated list, the empty entries are skipped
+func SplitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+
+	return out
+}
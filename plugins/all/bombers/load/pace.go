@@ -0,0 +1,153 @@
+package load
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// Phase is the part of the run with the arrival rate (per second) changing linearly from From to To
+type Phase struct {
+	Duration time.Duration `json:"duration_ns"`
+	From     float64       `json:"from"`
+	To       float64       `json:"to"`
+}
+
+// Constant returns the phase with the constant rate
+func Constant(d time.Duration, rate float64) Phase {
+	return Phase{Duration: d, From: rate, To: rate}
+}
+
+// RampUp returns the optional ramp-up from zero to the rate and the full rate phases
+func RampUp(rampUp, d time.Duration, rate float64) []Phase {
+	phases := make([]Phase, 0, 2)
+	if rampUp > 0 {
+		phases = append(phases, Phase{Duration: rampUp, From: 0, To: rate})
+	}
+
+	return append(phases, Constant(d, rate))
+}
+
+// ValidatePhases checks the durations and the rates of the phases
+func ValidatePhases(phases []Phase) error {
+	if len(phases) == 0 {
+		return errors.New("no phases")
+	}
+
+	for i := 0; i < len(phases); i++ {
+		if phases[i].Duration <= 0 || phases[i].From < 0 || phases[i].To < 0 {
+			return errors.New("phase duration should be positive and the rates should not be negative")
+		}
+	}
+
+	return nil
+}
+
+// Paced is the result of Pace
+type Paced struct {
+	Started uint64 `json:"started"`
+	// Dropped are the arrivals skipped because maxInFlight calls were running, they have no latency, record them
+	// with Recorder.Drop
+	Dropped uint64 `json:"dropped"`
+}
+
+// Pace calls fn at the arrival rate of the phases (open loop): the calls are scheduled independently of how long the
+// previous ones take, so the slow responses don't reduce the load (no coordinated omission). fn gets the scheduled
+// time, the latency should be measured from it. At most maxInFlight calls run at once, the arrivals which can't be
+// started are dropped and counted in Paced.Dropped. The phases without the arrivals (zero rate) take their duration
+// as well. Pace returns when the phases are over (or ctx is done) and all started calls returned.
+func Pace(ctx context.Context, phases []Phase, maxInFlight int, fn func(scheduled time.Time)) Paced {
+	res := Paced{}
+	sem := make(chan struct{}, maxInFlight)
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+
+	start := time.Now()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+
+	// sleepUntil waits for the time, false if ctx is done
+	sleepUntil := func(at time.Time) bool {
+		d := time.Until(at)
+		if d <= 0 {
+			return ctx.Err() == nil
+		}
+
+		timer.Reset(d)
+		select {
+		case <-timer.C:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for n := 1; ; n++ {
+		off, ok := arrival(phases, float64(n))
+		if !ok {
+			sleepUntil(start.Add(duration(phases)))
+			return res
+		}
+
+		scheduled := start.Add(off)
+		if !sleepUntil(scheduled) {
+			return res
+		}
+
+		select {
+		case sem <- struct{}{}:
+			res.Started++
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				fn(scheduled)
+			}()
+		default:
+			res.Dropped++
+		}
+	}
+}
+
+// duration returns the total duration of the phases
+func duration(phases []Phase) time.Duration {
+	d := time.Duration(0)
+	for i := 0; i < len(phases); i++ {
+		d += phases[i].Duration
+	}
+
+	return d
+}
+
+// arrival returns the offset of the n-th arrival (from 1) from the start, false if it's after the last phase
+func arrival(phases []Phase, n float64) (time.Duration, bool) {
+	offset := time.Duration(0)
+	for i := 0; i < len(phases); i++ {
+		p := phases[i]
+		total := (p.From + p.To) / 2 * p.Duration.Seconds()
+		if n <= total {
+			return offset + time.Duration(solve(p, n)*float64(time.Second)), true
+		}
+
+		n -= total
+		offset += p.Duration
+	}
+
+	return 0, false
+}
+
+// solve returns the time (seconds) of the k-th arrival in the phase: from*t + (to-from)/duration*t^2/2 = k
+func solve(p Phase, k float64) float64 {
+	a := p.From
+	b := (p.To - p.From) / p.Duration.Seconds()
+	if b == 0 {
+		return k / a
+	}
+
+	return (-a + math.Sqrt(math.Max(a*a+2*b*k, 0))) / b
+}
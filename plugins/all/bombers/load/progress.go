@@ -0,0 +1,25 @@
+package load
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Progress prints the increase of the counter every second to stderr as "-- label: n --" until stopCh is closed
+func Progress(label string, counter func() uint64, stopCh chan struct{}) {
+	tt := time.NewTicker(time.Second)
+	defer tt.Stop()
+
+	last := uint64(0)
+	for {
+		select {
+		case <-tt.C:
+			cur := counter()
+			fmt.Fprintf(os.Stderr, "-- %s: %d --\n", label, cur-last)
+			last = cur
+		case <-stopCh:
+			return
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package load
+
+import (
+	"regexp"
+	"sync"
+	"time"
+)
+
+const (
+	// maxMessages is the number of the distinct error messages kept per operation, the rest are counted as otherMessages
+	maxMessages   int    = 100
+	otherMessages string = "other errors"
+)
+
+var (
+	// the IDs (pipelines, jobs) and the numbers (ports, sizes) differ between the otherwise same errors
+	uuidRe   = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	numberRe = regexp.MustCompile(`\d{4,}`)
+)
+
+// Recorder collects the latencies, the errors and the result codes of the operations per group (driver, scenario
+// step, etc.), safe for concurrent use
+type Recorder struct {
+	mu sync.Mutex
+	// group -> operation -> stats
+	groups map[string]map[string]*opStats
+}
+
+type opStats struct {
+	errors    uint64
+	dropped   uint64
+	latencies *Histogram
+	// error messages and the result codes (HTTP status, gRPC code) with the number of occurrences
+	messages map[string]uint64
+	codes    map[string]uint64
+}
+
+func newOpStats() *opStats {
+	return &opStats{
+		latencies: NewHistogram(),
+		messages:  make(map[string]uint64),
+		codes:     make(map[string]uint64),
+	}
+}
+
+// NewRecorder creates the empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{groups: make(map[string]map[string]*opStats)}
+}
+
+// Record records the operation of the group which took d, err is the operation result
+func (r *Recorder) Record(group, op string, d time.Duration, err error) {
+	r.RecordCode(group, op, d, "", err)
+}
+
+// RecordCode is the same as Record with the result code (HTTP status, gRPC code), the empty code is not counted
+func (r *Recorder) RecordCode(group, op string, d time.Duration, code string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	st := r.stats(group, op)
+	st.latencies.Record(d)
+	if code != "" {
+		st.codes[code]++
+	}
+	if err != nil {
+		st.errors++
+		countMessage(st.messages, errorMessage(err), 1)
+	}
+}
+
+// Drop records n arrivals of the operation dropped by Pace, they are reported separately from the calls, the errors
+// and the latencies
+func (r *Recorder) Drop(group, op string, n uint64) {
+	if n == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stats(group, op).dropped += n
+}
+
+// stats returns the stats of the operation of the group, called under the lock
+func (r *Recorder) stats(group, op string) *opStats {
+	ops, ok := r.groups[group]
+	if !ok {
+		ops = make(map[string]*opStats)
+		r.groups[group] = ops
+	}
+
+	st, ok := ops[op]
+	if !ok {
+		st = newOpStats()
+		ops[op] = st
+	}
+
+	return st
+}
+
+// Calls returns the number of the recorded operations of the group
+func (r *Recorder) Calls(group string) uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := uint64(0)
+	for _, st := range r.groups[group] {
+		n += st.latencies.Count()
+	}
+
+	return n
+}
+
+// Reports builds the per operation reports of the group
+func (r *Recorder) Reports(group string) map[string]*OpReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make(map[string]*OpReport, len(r.groups[group]))
+	for name, st := range r.groups[group] {
+		out[name] = st.report()
+	}
+
+	return out
+}
+
+// TotalReports builds the per operation reports of all groups together
+func (r *Recorder) TotalReports() map[string]*OpReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	total := make(map[string]*opStats)
+	for _, ops := range r.groups {
+		for name, st := range ops {
+			t, ok := total[name]
+			if !ok {
+				t = newOpStats()
+				total[name] = t
+			}
+
+			t.errors += st.errors
+			t.dropped += st.dropped
+			t.latencies.Merge(st.latencies)
+			for msg, n := range st.messages {
+				countMessage(t.messages, msg, n)
+			}
+			addCounts(t.codes, st.codes)
+		}
+	}
+
+	out := make(map[string]*OpReport, len(total))
+	for name, st := range total {
+		out[name] = st.report()
+	}
+
+	return out
+}
+
+func (st *opStats) report() *OpReport {
+	h := st.latencies
+	rep := &OpReport{
+		Calls:   h.Count(),
+		Errors:  st.errors,
+		Dropped: st.dropped,
+		Mean:    Ms(h.Mean()),
+		P50:     Ms(h.Percentile(50)),
+		P90:     Ms(h.Percentile(90)),
+		P99:     Ms(h.Percentile(99)),
+		P999:    Ms(h.Percentile(99.9)),
+		Max:     Ms(h.Max()),
+	}
+
+	if len(st.messages) > 0 {
+		rep.ErrorCounts = make(map[string]uint64, len(st.messages))
+		addCounts(rep.ErrorCounts, st.messages)
+	}
+	if len(st.codes) > 0 {
+		rep.Codes = make(map[string]uint64, len(st.codes))
+		addCounts(rep.Codes, st.codes)
+	}
+
+	return rep
+}
+
+func addCounts(dst, src map[string]uint64) {
+	for k, n := range src {
+		dst[k] += n
+	}
+}
+
+// errorMessage returns the error message with the IDs and the numbers replaced, so the same errors are counted together
+func errorMessage(err error) string {
+	msg := uuidRe.ReplaceAllString(err.Error(), "<id>")
+	return numberRe.ReplaceAllString(msg, "<n>")
+}
+
+// countMessage adds n to the message, the messages over maxMessages are counted as otherMessages
+func countMessage(messages map[string]uint64, msg string, n uint64) {
+	if _, ok := messages[msg]; !ok && len(messages) >= maxMessages {
+		msg = otherMessages
+	}
+
+	messages[msg] += n
+}
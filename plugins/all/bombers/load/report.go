@@ -0,0 +1,48 @@
+// Package load is the machinery shared by the bombers: the HDR latency histograms, the recorder of the operations
+// per group, the JSON report and the open-loop pacing of the operations.
+package load
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// OpReport is the per operation summary, the latencies are in milliseconds. ErrorCounts are keyed by the error message
+// with the IDs and the numbers replaced, at most 100 distinct messages, the rest are counted as "other errors".
+type OpReport struct {
+	Calls       uint64            `json:"calls"`
+	Errors      uint64            `json:"errors"`
+	ErrorCounts map[string]uint64 `json:"error_counts,omitempty"`
+	Codes       map[string]uint64 `json:"codes,omitempty"`
+	Mean        float64           `json:"mean_ms"`
+	P50         float64           `json:"p50_ms"`
+	P90         float64           `json:"p90_ms"`
+	P99         float64           `json:"p99_ms"`
+	P999        float64           `json:"p999_ms"`
+	Max         float64           `json:"max_ms"`
+	// Dropped are the arrivals not started by Pace because of the max in flight calls, the open loop would have
+	// queued them, they are not in the calls, the errors and the latencies
+	Dropped uint64 `json:"dropped,omitempty"`
+}
+
+// Ms returns the duration in milliseconds
+func Ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// WriteJSON writes the report as the indented JSON to the path, - for stdout
+func WriteJSON(path string, report interface{}) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o600)
+}
@@ -0,0 +1,132 @@
+// Command mixed is the load generator running the YAML scenario: the steps (HTTP requests, jobs pushes, KV reads
+// and writes, broadcast publishes and gRPC calls) run concurrently, each one at its own open-loop arrival rate with
+// the ramp-up phases. At the end the JSON report with the latency percentiles, the errors and the result codes per
+// step is written, in the same format as the jobs bomber.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/roadrunner-server/rr-e2e-tests/plugins/all/bombers/load"
+	"github.com/roadrunner-server/rr-e2e-tests/rpcclient"
+)
+
+// Report is the machine-readable result of the run
+type Report struct {
+	Scenario *Scenario              `json:"scenario"`
+	Started  time.Time              `json:"started"`
+	Finished time.Time              `json:"finished"`
+	Elapsed  float64                `json:"elapsed_seconds"`
+	Steps    map[string]*StepReport `json:"steps"`
+}
+
+// StepReport is the per step summary, Rate is the achieved operations per second. The operation of the dropped
+// arrivals (Paced.Dropped) is not known, they are not in the Methods.
+type StepReport struct {
+	Kind string `json:"kind"`
+	load.Paced
+	Rate    float64                   `json:"rate"`
+	Methods map[string]*load.OpReport `json:"methods"`
+}
+
+func main() {
+	scenario := flag.String("scenario", "scenario.yaml", "path of the YAML scenario")
+	report := flag.String("report", "mixed-report.json", "path of the JSON report, - for stdout")
+	flag.Parse()
+
+	sc, err := readScenario(*scenario)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the interrupted run still writes the report
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	rep, err := run(ctx, sc)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = load.WriteJSON(*report, rep)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context, sc *Scenario) (*Report, error) {
+	var (
+		client *rpcclient.Client
+		rpcErr error
+		once   sync.Once
+	)
+	rpc := func() (*rpcclient.Client, error) {
+		once.Do(func() {
+			client, rpcErr = rpcclient.Dial(sc.RPC)
+		})
+		return client, rpcErr
+	}
+	defer func() {
+		if client != nil {
+			_ = client.Close()
+		}
+	}()
+
+	runners := make([]runner, len(sc.Steps))
+	for i := 0; i < len(sc.Steps); i++ {
+		r, err := newRunner(sc.Steps[i], rpc)
+		if err != nil {
+			return nil, err
+		}
+		defer r.close()
+		runners[i] = r
+	}
+
+	rec := load.NewRecorder()
+	paced := make([]load.Paced, len(sc.Steps))
+
+	started := time.Now()
+	wg := &sync.WaitGroup{}
+	for i := 0; i < len(sc.Steps); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			st, r := sc.Steps[i], runners[i]
+			paced[i] = load.Pace(ctx, st.phases(), st.MaxInFlight, func(scheduled time.Time) {
+				op, code, err := r.run(ctx)
+				// from the scheduled time, the queueing in the bomber is the part of the latency
+				rec.RecordCode(st.Name, op, time.Since(scheduled), code, err)
+			})
+		}(i)
+	}
+	wg.Wait()
+	finished := time.Now()
+
+	elapsed := finished.Sub(started).Seconds()
+	rep := &Report{
+		Scenario: sc,
+		Started:  started,
+		Finished: finished,
+		Elapsed:  elapsed,
+		Steps:    make(map[string]*StepReport, len(sc.Steps)),
+	}
+
+	for i := 0; i < len(sc.Steps); i++ {
+		st := sc.Steps[i]
+		rep.Steps[st.Name] = &StepReport{
+			Kind:    st.kind(),
+			Paced:   paced[i],
+			Rate:    float64(rec.Calls(st.Name)) / elapsed,
+			Methods: rec.Reports(st.Name),
+		}
+	}
+
+	return rep, nil
+}
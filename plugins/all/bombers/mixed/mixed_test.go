@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/rr-e2e-tests/plugins/all/bombers/load"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestExampleScenario(t *testing.T) {
+	sc, err := readScenario("scenario.yaml")
+	require.NoError(t, err)
+
+	require.Len(t, sc.Steps, 5)
+	kinds := make([]string, 0, len(sc.Steps))
+	for i := 0; i < len(sc.Steps); i++ {
+		kinds = append(kinds, sc.Steps[i].kind())
+	}
+	assert.Equal(t, []string{"http", "jobs", "kv", "broadcast", "grpc"}, kinds)
+
+	assert.Equal(t, []load.Phase{
+		{Duration: time.Second * 30, From: 0, To: 500},
+		load.Constant(time.Minute*2, 500),
+	}, sc.Steps[0].phases())
+	assert.Equal(t, 100, sc.Steps[1].MaxInFlight)
+	assert.Equal(t, time.Second*5, sc.Steps[0].HTTP.Timeout)
+}
+
+func TestInvalidScenario(t *testing.T) {
+	for name, data := range map[string]string{
+		"no steps":   `rpc: 127.0.0.1:6001`,
+		"no kind":    "steps:\n  - name: a\n    phases: [{duration: 1s, rate: 1}]",
+		"two kinds":  "steps:\n  - name: a\n    phases: [{duration: 1s, rate: 1}]\n    http: {url: http://a}\n    jobs: {pipeline: p}",
+		"no phases":  "steps:\n  - name: a\n    http: {url: http://a}",
+		"duplicate":  "steps:\n  - name: a\n    phases: [{duration: 1s, rate: 1}]\n    http: {url: http://a}\n  - name: a\n    phases: [{duration: 1s, rate: 1}]\n    http: {url: http://a}",
+		"read ratio": "steps:\n  - name: a\n    phases: [{duration: 1s, rate: 1}]\n    kv: {storage: memory, keys: 1, read_ratio: 2}",
+	} {
+		path := filepath.Join(t.TempDir(), "scenario.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+		_, err := readScenario(path)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestRunHTTP(t *testing.T) {
+	hits := int64(0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&hits, 1)%2 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	sc := &Scenario{Steps: []*Step{{
+		Name:   "index",
+		Phases: []Phase{{Duration: time.Millisecond * 500, Rate: 40}},
+		HTTP:   &HTTPStep{URL: srv.URL},
+	}}}
+	require.NoError(t, sc.validate())
+
+	rep, err := run(context.Background(), sc)
+	require.NoError(t, err)
+
+	st := rep.Steps["index"]
+	require.NotNil(t, st)
+	assert.Equal(t, "http", st.Kind)
+	assert.Equal(t, uint64(20), st.Started)
+	assert.Equal(t, uint64(20), st.Methods["GET"].Calls)
+	assert.Equal(t, uint64(10), st.Methods["GET"].Errors)
+	assert.Equal(t, map[string]uint64{"200": 10, "503": 10}, st.Methods["GET"].Codes)
+}
+
+func TestRunGRPC(t *testing.T) {
+	ls, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	calls := int64(0)
+	// echoes the raw message back, fails every second call
+	srv := grpc.NewServer(grpc.ForceServerCodec(rawCodec{}), grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		method, _ := grpc.MethodFromServerStream(stream)
+		assert.Equal(t, grpcPing, method)
+
+		var msg []byte
+		err := stream.RecvMsg(&msg)
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, []byte("\x0a\x05hello"), msg)
+
+		if atomic.AddInt64(&calls, 1)%2 == 0 {
+			return status.Error(codes.Unavailable, "busy")
+		}
+		return stream.SendMsg(&msg)
+	}))
+	go func() {
+		_ = srv.Serve(ls)
+	}()
+	defer srv.Stop()
+
+	sc := &Scenario{Steps: []*Step{{
+		Name:   "echo",
+		Phases: []Phase{{Duration: time.Millisecond * 500, Rate: 20}},
+		GRPC:   &GRPCStep{Addr: ls.Addr().String(), Message: "hello"},
+	}}}
+	require.NoError(t, sc.validate())
+
+	rep, err := run(context.Background(), sc)
+	require.NoError(t, err)
+
+	st := rep.Steps["echo"]
+	require.NotNil(t, st)
+	assert.Equal(t, uint64(10), st.Methods[grpcPing].Calls)
+	assert.Equal(t, map[string]uint64{"OK": 5, "Unavailable": 5}, st.Methods[grpcPing].Codes)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/roadrunner-server/rr-e2e-tests/plugins/all/bombers/load"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultRPC         string        = "127.0.0.1:6001"
+	defaultMaxInFlight int           = 100
+	defaultTimeout     time.Duration = time.Second * 10
+)
+
+// Scenario is the YAML scenario: the steps run concurrently, each one at its own arrival rate
+type Scenario struct {
+	// RPC is the RoadRunner RPC address used by the jobs, kv and broadcast steps
+	RPC   string  `yaml:"rpc" json:"rpc"`
+	Steps []*Step `yaml:"steps" json:"steps"`
+}
+
+// Step is the operation repeated at the rate of the phases, exactly one of HTTP, Jobs, KV, Broadcast and GRPC is set
+type Step struct {
+	Name        string  `yaml:"name" json:"name"`
+	MaxInFlight int     `yaml:"max_in_flight" json:"max_in_flight"`
+	Phases      []Phase `yaml:"phases" json:"phases"`
+
+	HTTP      *HTTPStep      `yaml:"http" json:"http,omitempty"`
+	Jobs      *JobsStep      `yaml:"jobs" json:"jobs,omitempty"`
+	KV        *KVStep        `yaml:"kv" json:"kv,omitempty"`
+	Broadcast *BroadcastStep `yaml:"broadcast" json:"broadcast,omitempty"`
+	GRPC      *GRPCStep      `yaml:"grpc" json:"grpc,omitempty"`
+}
+
+// Phase is the ramp from From to To requests per second, Rate is the shortcut for the constant rate
+type Phase struct {
+	Duration time.Duration `yaml:"duration" json:"duration_ns"`
+	Rate     float64       `yaml:"rate" json:"rate,omitempty"`
+	From     float64       `yaml:"from" json:"from,omitempty"`
+	To       float64       `yaml:"to" json:"to,omitempty"`
+}
+
+// HTTPStep sends the request, the statuses >= 400 are counted as the errors
+type HTTPStep struct {
+	Method  string            `yaml:"method" json:"method"`
+	URL     string            `yaml:"url" json:"url"`
+	Headers map[string]string `yaml:"headers" json:"headers,omitempty"`
+	Body    string            `yaml:"body" json:"body,omitempty"`
+	Timeout time.Duration     `yaml:"timeout" json:"timeout_ns"`
+}
+
+// JobsStep pushes the job to the pipeline
+type JobsStep struct {
+	Pipeline    string `yaml:"pipeline" json:"pipeline"`
+	PayloadSize int    `yaml:"payload_size" json:"payload_size"`
+	Priority    int64  `yaml:"priority" json:"priority"`
+	Delay       int64  `yaml:"delay" json:"delay,omitempty"`
+}
+
+// KVStep reads (kv.MGet) or writes (kv.Set) the random key of the key space
+type KVStep struct {
+	Storage   string  `yaml:"storage" json:"storage"`
+	Keys      int     `yaml:"keys" json:"keys"`
+	ValueSize int     `yaml:"value_size" json:"value_size"`
+	ReadRatio float64 `yaml:"read_ratio" json:"read_ratio"`
+}
+
+// BroadcastStep publishes the message to the topics
+type BroadcastStep struct {
+	Topics      []string `yaml:"topics" json:"topics"`
+	PayloadSize int      `yaml:"payload_size" json:"payload_size"`
+	Async       bool     `yaml:"async" json:"async"`
+}
+
+// GRPCStep calls Echo.Ping (plugins/grpc/proto/service) with the message
+type GRPCStep struct {
+	Addr    string        `yaml:"addr" json:"addr"`
+	Message string        `yaml:"message" json:"message,omitempty"`
+	Timeout time.Duration `yaml:"timeout" json:"timeout_ns"`
+}
+
+// readScenario reads, validates and fills the defaults of the scenario file
+func readScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := &Scenario{}
+	err = yaml.Unmarshal(data, sc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sc.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return sc, nil
+}
+
+func (sc *Scenario) validate() error {
+	if sc.RPC == "" {
+		sc.RPC = defaultRPC
+	}
+	if len(sc.Steps) == 0 {
+		return errors.New("no steps")
+	}
+
+	names := make(map[string]struct{}, len(sc.Steps))
+	for i := 0; i < len(sc.Steps); i++ {
+		st := sc.Steps[i]
+		if st.Name == "" {
+			return fmt.Errorf("step %d: no name", i)
+		}
+		if _, ok := names[st.Name]; ok {
+			return fmt.Errorf("duplicate step: %s", st.Name)
+		}
+		names[st.Name] = struct{}{}
+
+		err := st.validate()
+		if err != nil {
+			return fmt.Errorf("step %s: %w", st.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (st *Step) validate() error {
+	if st.MaxInFlight == 0 {
+		st.MaxInFlight = defaultMaxInFlight
+	}
+	if st.MaxInFlight < 0 {
+		return errors.New("max_in_flight should be positive")
+	}
+
+	err := load.ValidatePhases(st.phases())
+	if err != nil {
+		return err
+	}
+
+	kinds := 0
+	for _, set := range []bool{st.HTTP != nil, st.Jobs != nil, st.KV != nil, st.Broadcast != nil, st.GRPC != nil} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return errors.New("exactly one of http, jobs, kv, broadcast and grpc should be set")
+	}
+
+	switch {
+	case st.HTTP != nil:
+		if st.HTTP.URL == "" {
+			return errors.New("no url")
+		}
+		if st.HTTP.Method == "" {
+			st.HTTP.Method = "GET"
+		}
+		if st.HTTP.Timeout == 0 {
+			st.HTTP.Timeout = defaultTimeout
+		}
+	case st.Jobs != nil:
+		if st.Jobs.Pipeline == "" {
+			return errors.New("no pipeline")
+		}
+	case st.KV != nil:
+		if st.KV.Storage == "" || st.KV.Keys <= 0 {
+			return errors.New("storage and positive keys should be set")
+		}
+		if st.KV.ReadRatio < 0 || st.KV.ReadRatio > 1 {
+			return errors.New("read_ratio should be in [0, 1]")
+		}
+	case st.Broadcast != nil:
+		if len(st.Broadcast.Topics) == 0 {
+			return errors.New("no topics")
+		}
+	case st.GRPC != nil:
+		if st.GRPC.Addr == "" {
+			return errors.New("no addr")
+		}
+		if st.GRPC.Timeout == 0 {
+			st.GRPC.Timeout = defaultTimeout
+		}
+	}
+
+	return nil
+}
+
+// phases converts the scenario phases to the load phases
+func (st *Step) phases() []load.Phase {
+	out := make([]load.Phase, len(st.Phases))
+	for i := 0; i < len(st.Phases); i++ {
+		p := st.Phases[i]
+		if p.Rate > 0 {
+			out[i] = load.Constant(p.Duration, p.Rate)
+			continue
+		}
+		out[i] = load.Phase{Duration: p.Duration, From: p.From, To: p.To}
+	}
+
+	return out
+}
+
+// kind returns the name of the step kind
+func (st *Step) kind() string {
+	switch {
+	case st.HTTP != nil:
+		return "http"
+	case st.Jobs != nil:
+		return "jobs"
+	case st.KV != nil:
+		return "kv"
+	case st.Broadcast != nil:
+		return "broadcast"
+	default:
+		return "grpc"
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	jobsv1beta "github.com/roadrunner-server/api/v2/proto/jobs/v1"
+	kvv1 "github.com/roadrunner-server/api/v2/proto/kv/v1"
+	"github.com/roadrunner-server/rr-e2e-tests/rpcclient"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// grpcPing is Echo.Ping of plugins/grpc/proto/service. It's called with the hand encoded message, the generated
+// package registers service.proto which conflicts with the RR API protos used by the RPC client.
+const grpcPing string = "/service.Echo/Ping"
+
+// runner executes a single operation of the step and returns the operation name, the result code (optional) and
+// the error, safe for concurrent use
+type runner interface {
+	run(ctx context.Context) (op, code string, err error)
+	close()
+}
+
+// newRunner creates the runner of the step kind, the RPC client is shared by the jobs, kv and broadcast steps
+func newRunner(st *Step, rpc func() (*rpcclient.Client, error)) (runner, error) {
+	switch {
+	case st.HTTP != nil:
+		return newHTTPRunner(st.HTTP, st.MaxInFlight)
+	case st.GRPC != nil:
+		return newGRPCRunner(st.GRPC)
+	}
+
+	client, err := rpc()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case st.Jobs != nil:
+		return &jobsRunner{cfg: st.Jobs, client: client, payload: strings.Repeat("a", st.Jobs.PayloadSize)}, nil
+	case st.KV != nil:
+		return &kvRunner{cfg: st.KV, client: client, value: []byte(strings.Repeat("a", st.KV.ValueSize)), rnd: newRand()}, nil
+	default:
+		return &broadcastRunner{cfg: st.Broadcast, client: client, payload: []byte(strings.Repeat("a", st.Broadcast.PayloadSize))}, nil
+	}
+}
+
+type httpRunner struct {
+	cfg    *HTTPStep
+	client *http.Client
+}
+
+func newHTTPRunner(cfg *HTTPStep, maxInFlight int) (*httpRunner, error) {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	// keep-alive connections for all requests in flight
+	tr.MaxIdleConns = maxInFlight
+	tr.MaxIdleConnsPerHost = maxInFlight
+
+	return &httpRunner{cfg: cfg, client: &http.Client{Transport: tr, Timeout: cfg.Timeout}}, nil
+}
+
+func (r *httpRunner) run(ctx context.Context) (string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, r.cfg.Method, r.cfg.URL, strings.NewReader(r.cfg.Body))
+	if err != nil {
+		return r.cfg.Method, "", err
+	}
+	for k, v := range r.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return r.cfg.Method, "", err
+	}
+	// read the body to reuse the connection
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	code := strconv.Itoa(resp.StatusCode)
+	if resp.StatusCode >= http.StatusBadRequest {
+		return r.cfg.Method, code, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	return r.cfg.Method, code, nil
+}
+
+func (r *httpRunner) close() {
+	r.client.CloseIdleConnections()
+}
+
+type jobsRunner struct {
+	cfg     *JobsStep
+	client  *rpcclient.Client
+	payload string
+}
+
+func (r *jobsRunner) run(context.Context) (string, string, error) {
+	return "jobs.Push", "", r.client.Jobs.Push(&jobsv1beta.Job{
+		Job:     "Some/Super/PHP/Class",
+		Id:      uuid.NewString(),
+		Payload: r.payload,
+		Headers: map[string]*jobsv1beta.HeaderValue{},
+		Options: &jobsv1beta.Options{
+			Priority: r.cfg.Priority,
+			Pipeline: r.cfg.Pipeline,
+			Delay:    r.cfg.Delay,
+		},
+	})
+}
+
+func (r *jobsRunner) close() {}
+
+type kvRunner struct {
+	cfg    *KVStep
+	client *rpcclient.Client
+	value  []byte
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+func (r *kvRunner) run(context.Context) (string, string, error) {
+	r.mu.Lock()
+	key := "key-" + strconv.Itoa(r.rnd.Intn(r.cfg.Keys))
+	read := r.rnd.Float64() < r.cfg.ReadRatio
+	r.mu.Unlock()
+
+	if read {
+		_, err := r.client.KV.MGet(r.cfg.Storage, key)
+		return "kv.MGet", "", err
+	}
+
+	return "kv.Set", "", r.client.KV.Set(r.cfg.Storage, &kvv1.Item{Key: key, Value: r.value})
+}
+
+func (r *kvRunner) close() {}
+
+type broadcastRunner struct {
+	cfg     *BroadcastStep
+	client  *rpcclient.Client
+	payload []byte
+}
+
+func (r *broadcastRunner) run(context.Context) (string, string, error) {
+	msg := rpcclient.Message(r.payload, r.cfg.Topics...)
+	if r.cfg.Async {
+		ok, err := r.client.Broadcast.PublishAsync(msg)
+		return "broadcast.PublishAsync", "", published(ok, err)
+	}
+
+	ok, err := r.client.Broadcast.Publish(msg)
+	return "broadcast.Publish", "", published(ok, err)
+}
+
+func (r *broadcastRunner) close() {}
+
+func published(ok bool, err error) error {
+	if err == nil && !ok {
+		return errors.New("not published")
+	}
+
+	return err
+}
+
+type grpcRunner struct {
+	cfg  *GRPCStep
+	conn *grpc.ClientConn
+	// the encoded service.Message
+	msg []byte
+}
+
+func newGRPCRunner(cfg *GRPCStep) (*grpcRunner, error) {
+	conn, err := grpc.Dial(cfg.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+
+	msg := protowire.AppendTag(nil, 1, protowire.BytesType)
+	msg = protowire.AppendString(msg, cfg.Message)
+
+	return &grpcRunner{cfg: cfg, conn: conn, msg: msg}, nil
+}
+
+func (r *grpcRunner) run(ctx context.Context) (string, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+
+	in, out := r.msg, []byte(nil)
+	err := r.conn.Invoke(ctx, grpcPing, &in, &out, grpc.ForceCodec(rawCodec{}))
+
+	return grpcPing, status.Code(err).String(), err
+}
+
+func (r *grpcRunner) close() {
+	_ = r.conn.Close()
+}
+
+// rawCodec sends and receives the already encoded protobuf messages (*[]byte)
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type: %T", v)
+	}
+
+	return *b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unexpected message type: %T", v)
+	}
+
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+func newRand() *rand.Rand {
+	return rand.New(rand.NewSource(time.Now().UnixNano())) //nolint:gosec
+}
@@ -1,29 +1,22 @@
 package broadcast
 
 import (
-	"context"
-	"net"
-	"net/rpc"
-	"os"
-	"os/signal"
-	"sync"
-	"syscall"
 	"testing"
 	"time"
 
-	goRedis "github.com/go-redis/redis/v8"
-	websocketsv1 "github.com/roadrunner-server/api/v2/proto/websockets/v1beta"
 	"github.com/roadrunner-server/broadcast/v2"
 	"github.com/roadrunner-server/config/v2"
 	endure "github.com/roadrunner-server/endure/pkg/container"
-	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
 	httpPlugin "github.com/roadrunner-server/http/v2"
 	"github.com/roadrunner-server/logger/v2"
 	"github.com/roadrunner-server/memory/v2"
 	"github.com/roadrunner-server/redis/v2"
 	rpcPlugin "github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/fakeredis"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	mock_logger "github.com/roadrunner-server/rr-e2e-tests/mock"
 	"github.com/roadrunner-server/rr-e2e-tests/plugins/broadcast/plugins"
+	"github.com/roadrunner-server/rr-e2e-tests/rpcclient"
 	"github.com/roadrunner-server/server/v2"
 	"github.com/roadrunner-server/websockets/v2"
 	"github.com/stretchr/testify/assert"
@@ -32,16 +25,10 @@ import (
 )
 
 func TestBroadcastInit(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-broadcast-init.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
+	env.Set("redis", fakeredis.Start(t).Addr())
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-broadcast-init.yaml",
 		&broadcast.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -52,64 +39,17 @@ func TestBroadcastInit(t *testing.T) {
 		&memory.Plugin{},
 	)
 
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	stopCh <- struct{}{}
-
-	wg.Wait()
+	h.Stop()
 }
 
 func TestBroadcastConfigError(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
 	assert.NoError(t, err)
 
 	cfg := &config.Plugin{
-		Path:   "configs/.rr-broadcast-config-error.yaml",
+		Path:   env.Render("configs/.rr-broadcast-config-error.yaml"),
 		Prefix: "rr",
 	}
 
@@ -140,17 +80,9 @@ func TestBroadcastConfigError(t *testing.T) {
 }
 
 func TestBroadcastNoConfig(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-broadcast-no-config.yaml",
-		Prefix: "rr",
-	}
-
 	l, oLogger := mock_logger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	// should be just disabled
+	h := harness.Start(t, "configs/.rr-broadcast-no-config.yaml",
 		&broadcast.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
@@ -160,38 +92,19 @@ func TestBroadcastNoConfig(t *testing.T) {
 		&httpPlugin.Plugin{},
 		&memory.Plugin{},
 	)
-
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	// should be just disabled
-	_, err = cont.Serve()
-	assert.NoError(t, err)
-	_ = cont.Stop()
+	h.Stop()
 
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("http server was started").Len())
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("plugin was started").Len())
 }
 
 func TestBroadcastSameSubscriber(t *testing.T) {
-	t.Run("RedisFlush", redisFlushAll("127.0.0.1:6379"))
-	t.Run("RedisFlush", redisFlushAll("127.0.0.1:6378"))
-
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-broadcast-same-section.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
+	env.Set("redis", fakeredis.Start(t).Addr())
+	env.Set("redis2", fakeredis.Start(t).Addr())
 
 	l, oLogger := mock_logger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-broadcast-same-section.yaml",
 		&broadcast.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
@@ -202,7 +115,7 @@ func TestBroadcastSameSubscriber(t *testing.T) {
 		&memory.Plugin{},
 
 		// test - redis
-		// test2 - redis (port 6378)
+		// test2 - redis (the second server)
 		// test3 - memory
 		// test4 - memory
 		&plugins.Plugin1{}, // foo, foo2, foo3 test
@@ -213,69 +126,18 @@ func TestBroadcastSameSubscriber(t *testing.T) {
 		&plugins.Plugin6{}, // foo, test3
 	)
 
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
 	time.Sleep(time.Second * 2)
 
-	t.Run("PublishHelloFooFoo2Foo3", BroadcastPublishFooFoo2Foo3("6002"))
+	t.Run("PublishHelloFooFoo2Foo3", BroadcastPublishFooFoo2Foo3(h.RPCAddr()))
 	time.Sleep(time.Second)
-	t.Run("PublishHelloFoo2", BroadcastPublishFoo2("6002"))
+	t.Run("PublishHelloFoo2", BroadcastPublishFoo2(h.RPCAddr()))
 	time.Sleep(time.Second)
-	t.Run("PublishHelloFoo3", BroadcastPublishFoo3("6002"))
+	t.Run("PublishHelloFoo3", BroadcastPublishFoo3(h.RPCAddr()))
 	time.Sleep(time.Second)
-	t.Run("PublishAsyncHelloFooFoo2Foo3", BroadcastPublishAsyncFooFoo2Foo3("6002"))
+	t.Run("PublishAsyncHelloFooFoo2Foo3", BroadcastPublishAsyncFooFoo2Foo3(h.RPCAddr()))
 
 	time.Sleep(time.Second * 5)
-	stopCh <- struct{}{}
-	wg.Wait()
-
-	t.Run("RedisFlush", redisFlushAll("127.0.0.1:6379"))
-	t.Run("RedisFlush", redisFlushAll("127.0.0.1:6378"))
+	h.Stop()
 
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("http server was started").Len())
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("plugin was started").Len())
@@ -292,20 +154,12 @@ func TestBroadcastSameSubscriber(t *testing.T) {
 }
 
 func TestBroadcastSameSubscriberGlobal(t *testing.T) {
-	t.Run("RedisFlush", redisFlushAll("127.0.0.1:6379"))
-	t.Run("RedisFlush", redisFlushAll("127.0.0.1:6378"))
-
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-broadcast-global.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
+	env.Set("redis", fakeredis.Start(t).Addr())
+	env.Set("redis2", fakeredis.Start(t).Addr())
 
 	l, oLogger := mock_logger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-broadcast-global.yaml",
 		&broadcast.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
@@ -316,7 +170,7 @@ func TestBroadcastSameSubscriberGlobal(t *testing.T) {
 		&memory.Plugin{},
 
 		// test - redis
-		// test2 - redis (port 6378)
+		// test2 - redis (the second server)
 		// test3 - memory
 		// test4 - memory
 		&plugins.Plugin1{}, // foo, foo2, foo3 test
@@ -327,69 +181,18 @@ func TestBroadcastSameSubscriberGlobal(t *testing.T) {
 		&plugins.Plugin6{}, // foo, test3
 	)
 
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
 	time.Sleep(time.Second * 2)
 
-	t.Run("PublishHelloFooFoo2Foo3", BroadcastPublishFooFoo2Foo3("6003"))
+	t.Run("PublishHelloFooFoo2Foo3", BroadcastPublishFooFoo2Foo3(h.RPCAddr()))
 	time.Sleep(time.Second)
-	t.Run("PublishHelloFoo2", BroadcastPublishFoo2("6003"))
+	t.Run("PublishHelloFoo2", BroadcastPublishFoo2(h.RPCAddr()))
 	time.Sleep(time.Second)
-	t.Run("PublishHelloFoo3", BroadcastPublishFoo3("6003"))
+	t.Run("PublishHelloFoo3", BroadcastPublishFoo3(h.RPCAddr()))
 	time.Sleep(time.Second)
-	t.Run("PublishAsyncHelloFooFoo2Foo3", BroadcastPublishAsyncFooFoo2Foo3("6003"))
+	t.Run("PublishAsyncHelloFooFoo2Foo3", BroadcastPublishAsyncFooFoo2Foo3(h.RPCAddr()))
 
 	time.Sleep(time.Second * 4)
-	stopCh <- struct{}{}
-	wg.Wait()
-
-	t.Run("RedisFlush", redisFlushAll("127.0.0.1:6379"))
-	t.Run("RedisFlush", redisFlushAll("127.0.0.1:6378"))
+	h.Stop()
 
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("http server was started").Len())
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("plugin was started").Len())
@@ -405,96 +208,38 @@ func TestBroadcastSameSubscriberGlobal(t *testing.T) {
 	require.Equal(t, 3, oLogger.FilterMessageSnippet("plugin6: {foo hello}").Len())
 }
 
-func BroadcastPublishFooFoo2Foo3(port string) func(t *testing.T) {
+func BroadcastPublishFooFoo2Foo3(addr string) func(t *testing.T) {
 	return func(t *testing.T) {
-		conn, err := net.Dial("tcp", "127.0.0.1:"+port)
-		if err != nil {
-			t.Fatal(err)
-		}
-
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-		ret := &websocketsv1.Response{}
-		err = client.Call("broadcast.Publish", makeMessage([]byte("hello"), "foo", "foo2", "foo3"), ret)
+		_, err := harness.Dial(t, addr).Broadcast.Publish(rpcclient.Message([]byte("hello"), "foo", "foo2", "foo3"))
 		if err != nil {
 			t.Fatal(err)
 		}
 	}
 }
 
-func BroadcastPublishFoo2(port string) func(t *testing.T) {
+func BroadcastPublishFoo2(addr string) func(t *testing.T) {
 	return func(t *testing.T) {
-		conn, err := net.Dial("tcp", "127.0.0.1:"+port)
-		if err != nil {
-			t.Fatal(err)
-		}
-
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-		ret := &websocketsv1.Response{}
-		err = client.Call("broadcast.Publish", makeMessage([]byte("hello"), "foo"), ret)
+		_, err := harness.Dial(t, addr).Broadcast.Publish(rpcclient.Message([]byte("hello"), "foo"))
 		if err != nil {
 			t.Fatal(err)
 		}
 	}
 }
 
-func BroadcastPublishFoo3(port string) func(t *testing.T) {
+func BroadcastPublishFoo3(addr string) func(t *testing.T) {
 	return func(t *testing.T) {
-		conn, err := net.Dial("tcp", "127.0.0.1:"+port)
-		if err != nil {
-			t.Fatal(err)
-		}
-
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-		ret := &websocketsv1.Response{}
-		err = client.Call("broadcast.Publish", makeMessage([]byte("hello"), "foo3"), ret)
+		_, err := harness.Dial(t, addr).Broadcast.Publish(rpcclient.Message([]byte("hello"), "foo3"))
 		if err != nil {
 			t.Fatal(err)
 		}
 	}
 }
 
-func BroadcastPublishAsyncFooFoo2Foo3(port string) func(t *testing.T) {
+func BroadcastPublishAsyncFooFoo2Foo3(addr string) func(t *testing.T) {
 	return func(t *testing.T) {
-		conn, err := net.Dial("tcp", "127.0.0.1:"+port)
+		_, err := harness.Dial(t, addr).Broadcast.PublishAsync(rpcclient.Message([]byte("hello"), "foo", "foo2", "foo3"))
 		if err != nil {
 			t.Fatal(err)
 		}
-
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-		ret := &websocketsv1.Response{}
-		err = client.Call("broadcast.PublishAsync", makeMessage([]byte("hello"), "foo", "foo2", "foo3"), ret)
-		if err != nil {
-			t.Fatal(err)
-		}
-	}
-}
-
-func makeMessage(payload []byte, topics ...string) *websocketsv1.Request {
-	m := &websocketsv1.Request{
-		Messages: []*websocketsv1.Message{
-			{
-				Topics:  topics,
-				Payload: payload,
-			},
-		},
-	}
-
-	return m
-}
-
-func redisFlushAll(addr string) func(t *testing.T) {
-	return func(t *testing.T) {
-		rdb := goRedis.NewClient(&goRedis.Options{
-			Addr:     addr,
-			Password: "", // no password set
-			DB:       0,  // use default DB
-		})
-
-		rdb.FlushAll(context.Background())
-		_ = rdb.Close()
 	}
 }
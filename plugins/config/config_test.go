@@ -3,8 +3,6 @@ package config
 import (
 	"os"
 	"os/signal"
-	"sync"
-	"syscall"
 	"testing"
 	"time"
 
@@ -15,18 +13,20 @@ import (
 	"github.com/roadrunner-server/jobs/v2"
 	"github.com/roadrunner-server/logger/v2"
 	"github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	"github.com/roadrunner-server/server/v2"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
 )
 
 func TestViperProvider_Init(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	container, err := endure.NewContainer(nil, endure.RetryOnFail(true), endure.SetLogLevel(endure.ErrorLevel))
 	if err != nil {
 		t.Fatal(err)
 	}
 	vp := &configImpl.Plugin{}
-	vp.Path = "configs/.rr.yaml"
+	vp.Path = env.Render("configs/.rr.yaml")
 	vp.Prefix = "rr"
 	vp.Flags = nil
 
@@ -35,7 +35,7 @@ func TestViperProvider_Init(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	err = container.Register(&Foo{})
+	err = container.Register(&Foo{Listen: "tcp://" + env.RPCAddr()})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -75,12 +75,14 @@ func TestViperProvider_Init(t *testing.T) {
 }
 
 func TestConfigOverwriteFail(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	container, err := endure.NewContainer(nil, endure.RetryOnFail(false), endure.SetLogLevel(endure.ErrorLevel))
 	if err != nil {
 		t.Fatal(err)
 	}
 	vp := &configImpl.Plugin{}
-	vp.Path = "configs/.rr.yaml"
+	vp.Path = env.Render("configs/.rr.yaml")
 	vp.Prefix = "rr"
 	vp.Flags = []string{"rpc.listen=tcp//not_exist"}
 
@@ -97,12 +99,14 @@ func TestConfigOverwriteFail(t *testing.T) {
 }
 
 func TestConfigOverwriteFail_2(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	container, err := endure.NewContainer(nil, endure.RetryOnFail(false), endure.SetLogLevel(endure.ErrorLevel))
 	if err != nil {
 		t.Fatal(err)
 	}
 	vp := &configImpl.Plugin{}
-	vp.Path = "configs/.rr.yaml"
+	vp.Path = env.Render("configs/.rr.yaml")
 	vp.Prefix = "rr"
 	vp.Flags = []string{"rpc.listen="}
 
@@ -119,12 +123,14 @@ func TestConfigOverwriteFail_2(t *testing.T) {
 }
 
 func TestConfigOverwriteFail_3(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	container, err := endure.NewContainer(nil, endure.RetryOnFail(false), endure.SetLogLevel(endure.ErrorLevel))
 	if err != nil {
 		t.Fatal(err)
 	}
 	vp := &configImpl.Plugin{}
-	vp.Path = "configs/.rr.yaml"
+	vp.Path = env.Render("configs/.rr.yaml")
 	vp.Prefix = "rr"
 	vp.Flags = []string{"="}
 
@@ -141,122 +147,57 @@ func TestConfigOverwriteFail_3(t *testing.T) {
 }
 
 func TestConfigOverwriteValid(t *testing.T) {
-	container, err := endure.NewContainer(nil, endure.RetryOnFail(false), endure.SetLogLevel(endure.ErrorLevel))
-	if err != nil {
-		t.Fatal(err)
+	env := harness.NewEnv(t)
+
+	vp := &configImpl.Plugin{
+		Path:  "configs/.rr.yaml",
+		Flags: []string{"rpc.listen=tcp://" + env.Addr("overwrite")},
 	}
-	vp := &configImpl.Plugin{}
-	vp.Path = "configs/.rr.yaml"
-	vp.Prefix = "rr"
-	vp.Flags = []string{"rpc.listen=tcp://127.0.0.1:36643"}
 
-	err = container.RegisterAll(
+	h := env.StartConfig(vp,
 		&logger.Plugin{},
 		&rpc.Plugin{},
-		vp,
-		&Foo2{},
+		&Foo2{Listen: "tcp://" + env.Addr("overwrite")},
 	)
-	assert.NoError(t, err)
-
-	err = container.Init()
-	assert.NoError(t, err)
 
-	errCh, err := container.Serve()
-	assert.NoError(t, err)
-
-	// stop by CTRL+C
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-
-	tt := time.NewTicker(time.Second * 3)
-	defer tt.Stop()
-
-	for {
-		select {
-		case e := <-errCh:
-			assert.NoError(t, e.Error)
-			assert.NoError(t, container.Stop())
-			return
-		case <-c:
-			er := container.Stop()
-			assert.NoError(t, er)
-			return
-		case <-tt.C:
-			assert.NoError(t, container.Stop())
-			return
-		}
-	}
+	h.Stop()
 }
 
 func TestConfigEnvVariables(t *testing.T) {
-	container, err := endure.NewContainer(nil, endure.RetryOnFail(false), endure.SetLogLevel(endure.ErrorLevel))
-	if err != nil {
-		t.Fatal(err)
-	}
+	env := harness.NewEnv(t)
 
-	err = os.Setenv("SUPER_RPC_ENV", "tcp://127.0.0.1:36643")
+	err := os.Setenv("SUPER_RPC_ENV", "tcp://"+env.RPCAddr())
 	assert.NoError(t, err)
 
-	vp := &configImpl.Plugin{}
-	vp.Path = "configs/.rr-env.yaml"
-	vp.Prefix = "rr"
-
-	err = container.RegisterAll(
+	h := env.Start("configs/.rr-env.yaml",
 		&logger.Plugin{},
 		&rpc.Plugin{},
-		vp,
-		&Foo2{},
+		&Foo2{Listen: "tcp://" + env.RPCAddr()},
 	)
-	assert.NoError(t, err)
-
-	err = container.Init()
-	assert.NoError(t, err)
-
-	errCh, err := container.Serve()
-	assert.NoError(t, err)
-
-	// stop by CTRL+C
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-
-	tt := time.NewTicker(time.Second * 3)
-	defer tt.Stop()
 
-	for {
-		select {
-		case e := <-errCh:
-			assert.NoError(t, e.Error)
-			assert.NoError(t, container.Stop())
-			return
-		case <-c:
-			er := container.Stop()
-			assert.NoError(t, er)
-			return
-		case <-tt.C:
-			assert.NoError(t, container.Stop())
-			return
-		}
-	}
+	h.Stop()
 }
 
 func TestConfigEnvVariablesFail(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	container, err := endure.NewContainer(nil, endure.RetryOnFail(false), endure.SetLogLevel(endure.ErrorLevel))
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	err = os.Setenv("SUPER_RPC_ENV", "tcp://127.0.0.1:6065")
+	err = os.Setenv("SUPER_RPC_ENV", "tcp://"+env.RPCAddr())
 	assert.NoError(t, err)
 
 	vp := &configImpl.Plugin{}
-	vp.Path = "configs/.rr-env.yaml"
+	vp.Path = env.Render("configs/.rr-env.yaml")
 	vp.Prefix = "rr"
 
 	err = container.RegisterAll(
 		&logger.Plugin{},
 		&rpc.Plugin{},
 		vp,
-		&Foo2{},
+		&Foo2{Listen: "tcp://" + env.Addr("overwrite")},
 	)
 	assert.NoError(t, err)
 
@@ -268,12 +209,14 @@ func TestConfigEnvVariablesFail(t *testing.T) {
 }
 
 func TestConfigProvider_GeneralSection(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	container, err := endure.NewContainer(nil, endure.RetryOnFail(true), endure.SetLogLevel(endure.ErrorLevel))
 	if err != nil {
 		t.Fatal(err)
 	}
 	vp := &configImpl.Plugin{}
-	vp.Path = "configs/.rr.yaml"
+	vp.Path = env.Render("configs/.rr.yaml")
 	vp.Prefix = "rr"
 	vp.Flags = nil
 	vp.Timeout = time.Second * 10
@@ -325,211 +268,64 @@ func TestConfigProvider_GeneralSection(t *testing.T) {
 // VERSIONS
 
 func TestViperProvider_Init_Version(t *testing.T) {
-	container, err := endure.NewContainer(nil, endure.RetryOnFail(true), endure.SetLogLevel(endure.ErrorLevel))
-	if err != nil {
-		t.Fatal(err)
-	}
+	env := harness.NewEnv(t)
 
-	vp := &configImpl.Plugin{}
-	vp.Path = "configs/.rr-init-version.yaml"
-	vp.Prefix = "rr"
-	vp.Flags = nil
-	vp.Version = "2.7.2"
+	vp := &configImpl.Plugin{
+		Path:    "configs/.rr-init-version.yaml",
+		Flags:   nil,
+		Version: "2.7.2",
+	}
 
-	err = container.RegisterAll(
+	h := env.Options(endure.RetryOnFail(true)).StartConfig(vp,
 		&jobs.Plugin{},
 		&amqp.Plugin{},
 		&beanstalk.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
-		vp,
 	)
 
-	require.NoError(t, err)
-
-	err = container.Init()
-	require.NoError(t, err)
-
-	ch, err := container.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = container.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-sig:
-				err = container.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = container.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
 	time.Sleep(time.Second * 2)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestViperProvider_Init_Version27(t *testing.T) {
-	container, err := endure.NewContainer(nil, endure.RetryOnFail(true), endure.SetLogLevel(endure.ErrorLevel))
-	if err != nil {
-		t.Fatal(err)
-	}
+	env := harness.NewEnv(t)
 
-	vp := &configImpl.Plugin{}
-	vp.Path = "configs/.rr-init-version-2.7.yaml"
-	vp.Prefix = "rr"
-	vp.Flags = nil
-	vp.Version = "2.7.0"
+	vp := &configImpl.Plugin{
+		Path:    "configs/.rr-init-version-2.7.yaml",
+		Flags:   nil,
+		Version: "2.7.0",
+	}
 
-	err = container.RegisterAll(
+	h := env.Options(endure.RetryOnFail(true)).StartConfig(vp,
 		&jobs.Plugin{},
 		&amqp.Plugin{},
 		&beanstalk.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
-		vp,
 	)
 
-	require.NoError(t, err)
-
-	err = container.Init()
-	require.NoError(t, err)
-
-	ch, err := container.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = container.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-sig:
-				err = container.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = container.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
 	time.Sleep(time.Second * 2)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestViperProvider_Init_Version28(t *testing.T) {
-	container, err := endure.NewContainer(nil, endure.RetryOnFail(true), endure.SetLogLevel(endure.ErrorLevel))
-	if err != nil {
-		t.Fatal(err)
-	}
+	env := harness.NewEnv(t)
 
-	vp := &configImpl.Plugin{}
-	vp.Path = "configs/.rr-init-version-2.7.yaml"
-	vp.Prefix = "rr"
-	vp.Flags = nil
-	vp.Version = "2.8.0"
+	vp := &configImpl.Plugin{
+		Path:    "configs/.rr-init-version-2.7.yaml",
+		Flags:   nil,
+		Version: "2.8.0",
+	}
 
-	err = container.RegisterAll(
+	h := env.Options(endure.RetryOnFail(true)).StartConfig(vp,
 		&jobs.Plugin{},
 		&amqp.Plugin{},
 		&beanstalk.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
-		vp,
 	)
 
-	require.NoError(t, err)
-
-	err = container.Init()
-	require.NoError(t, err)
-
-	ch, err := container.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = container.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-sig:
-				err = container.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = container.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
 	time.Sleep(time.Second * 2)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
@@ -52,6 +52,9 @@ type ServiceConfig struct {
 }
 
 type Foo struct {
+	// Listen is the expected rpc.listen value
+	Listen string
+
 	configProvider config.Configurer
 }
 
@@ -83,7 +86,7 @@ func (f *Foo) Serve() chan error {
 		return errCh
 	}
 
-	if allCfg.RPC.Listen != "tcp://127.0.0.1:6060" {
+	if allCfg.RPC.Listen != f.Listen {
 		errCh <- errors.E(op, errors.Str("RPC.Listen should be parsed"))
 		return errCh
 	}
@@ -6,6 +6,9 @@ import (
 )
 
 type Foo2 struct {
+	// Listen is the expected (overwritten) rpc.listen value
+	Listen string
+
 	configProvider config.Configurer
 }
 
@@ -37,7 +40,7 @@ func (f *Foo2) Serve() chan error {
 		return errCh
 	}
 
-	if allCfg.RPC.Listen != "tcp://127.0.0.1:36643" {
+	if allCfg.RPC.Listen != f.Listen {
 		errCh <- errors.E(op, errors.Str("RPC.Listen should be overwritten"))
 		return errCh
 	}
@@ -5,94 +5,38 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"os"
-	"os/signal"
 	"runtime"
-	"sync"
-	"syscall"
+	"strconv"
 	"testing"
-	"time"
 
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/health/grpc_health_v1"
 
-	"github.com/roadrunner-server/config/v2"
-	endure "github.com/roadrunner-server/endure/pkg/container"
 	grpcPlugin "github.com/roadrunner-server/grpc/v2"
 	"github.com/roadrunner-server/logger/v2"
 	"github.com/roadrunner-server/resetter/v2"
 	rpcPlugin "github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	"github.com/roadrunner-server/rr-e2e-tests/plugins/grpc/proto/service"
 	"github.com/roadrunner-server/server/v2"
-	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 )
 
 func TestGrpcRqRsGzip(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-grpc-rq.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-grpc-rq.yaml",
 		&grpcPlugin.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
+	harness.WaitForTCP(t, env.Addr("grpc"), harness.DefaultWaitTimeout)
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-
-	conn, err := grpc.Dial("127.0.0.1:9001", grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithDefaultCallOptions(grpc.UseCompressor("gzip")))
+	conn, err := grpc.Dial(env.Addr("grpc"), grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithDefaultCallOptions(grpc.UseCompressor("gzip")))
 	require.NoError(t, err)
 	require.NotNil(t, conn)
 
@@ -101,75 +45,22 @@ func TestGrpcRqRsGzip(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, "TOST", resp.Msg)
 
-	stopCh <- struct{}{}
-
-	wg.Wait()
+	h.Stop()
 }
 
 func TestGrpcRqRsMultipleGzip(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-grpc-rq-multiple.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-grpc-rq-multiple.yaml",
 		&grpcPlugin.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
+	harness.WaitForTCP(t, env.Addr("grpc"), harness.DefaultWaitTimeout)
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-
-	conn, err := grpc.Dial("127.0.0.1:9001", grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithDefaultCallOptions(grpc.UseCompressor("gzip")))
+	conn, err := grpc.Dial(env.Addr("grpc"), grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithDefaultCallOptions(grpc.UseCompressor("gzip")))
 	require.NoError(t, err)
 	require.NotNil(t, conn)
 
@@ -195,78 +86,25 @@ func TestGrpcRqRsMultipleGzip(t *testing.T) {
 	err = watch.CloseSend()
 	require.NoError(t, err)
 
-	stopCh <- struct{}{}
-
-	wg.Wait()
+	h.Stop()
 }
 
 func TestGrpcRqRsTLSGzip(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-grpc-rq-tls.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-grpc-rq-tls.yaml",
 		&grpcPlugin.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
+	harness.WaitForTCP(t, env.Addr("grpc"), harness.DefaultWaitTimeout)
 
 	creds, err := credentials.NewClientTLSFromFile("./configs/test-certs/test.pem", "")
 	require.NoError(t, err)
 
-	conn, err := grpc.Dial("127.0.0.1:9002", grpc.WithTransportCredentials(creds), grpc.WithDefaultCallOptions(grpc.UseCompressor("gzip")))
+	conn, err := grpc.Dial(env.Addr("grpc"), grpc.WithTransportCredentials(creds), grpc.WithDefaultCallOptions(grpc.UseCompressor("gzip")))
 	require.NoError(t, err)
 	require.NotNil(t, conn)
 
@@ -275,76 +113,23 @@ func TestGrpcRqRsTLSGzip(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, "TOST", resp.Msg)
 
-	stopCh <- struct{}{}
-
-	wg.Wait()
+	h.Stop()
 }
 
 func TestGrpcRqRsTLSRootCAGzip(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	if runtime.GOOS == "windows" {
 		t.Skip("root pool is not available on Windows")
 	}
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-grpc-rq-tls-rootca.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-grpc-rq-tls-rootca.yaml",
 		&grpcPlugin.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
+	harness.WaitForTCP(t, env.Addr("grpc"), harness.DefaultWaitTimeout)
 
 	ca, _ := os.ReadFile("./configs/test-certs/ca.cert")
 	require.NotNil(t, ca)
@@ -362,7 +147,7 @@ func TestGrpcRqRsTLSRootCAGzip(t *testing.T) {
 		ClientCAs:          pool,
 	}
 
-	conn, err := grpc.Dial("127.0.0.1:9003", grpc.WithTransportCredentials(credentials.NewTLS(tlscfg)), grpc.WithDefaultCallOptions(grpc.UseCompressor("gzip")))
+	conn, err := grpc.Dial(env.Addr("grpc"), grpc.WithTransportCredentials(credentials.NewTLS(tlscfg)), grpc.WithDefaultCallOptions(grpc.UseCompressor("gzip")))
 	require.NoError(t, err)
 	require.NotNil(t, conn)
 
@@ -371,79 +156,26 @@ func TestGrpcRqRsTLSRootCAGzip(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, "TOST", resp.Msg)
 
-	stopCh <- struct{}{}
-
-	wg.Wait()
+	h.Stop()
 }
 
 func TestGrpcRqRsTLS_WithResetGzip(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-grpc-rq-tls.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-grpc-rq-tls.yaml",
 		&grpcPlugin.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 		&resetter.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
+	harness.WaitForTCP(t, env.Addr("grpc"), harness.DefaultWaitTimeout)
 
 	creds, err := credentials.NewClientTLSFromFile("./configs/test-certs/test.pem", "")
 	require.NoError(t, err)
 
-	conn, err := grpc.Dial("localhost:9002", grpc.WithTransportCredentials(creds), grpc.WithDefaultCallOptions(grpc.UseCompressor("gzip")))
+	conn, err := grpc.Dial("localhost:"+strconv.Itoa(env.Port("grpc")), grpc.WithTransportCredentials(creds), grpc.WithDefaultCallOptions(grpc.UseCompressor("gzip")))
 	require.NoError(t, err)
 	require.NotNil(t, conn)
 
@@ -453,12 +185,11 @@ func TestGrpcRqRsTLS_WithResetGzip(t *testing.T) {
 	require.Equal(t, "TOST", resp.Msg)
 
 	// reset
-	t.Run("SendReset", sendReset)
+	t.Run("SendReset", sendReset(h.RPCAddr()))
 
 	resp2, err2 := client.Ping(context.Background(), &service.Message{Msg: "TOST"})
 	require.NoError(t, err2)
 	require.Equal(t, "TOST", resp2.Msg)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
@@ -5,14 +5,10 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"io"
-	"net"
 	"net/http"
-	"net/rpc"
 	"os"
-	"os/signal"
 	"runtime"
-	"sync"
-	"syscall"
+	"strconv"
 	"testing"
 	"time"
 
@@ -24,11 +20,11 @@ import (
 
 	"github.com/roadrunner-server/config/v2"
 	endure "github.com/roadrunner-server/endure/pkg/container"
-	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
 	grpcPlugin "github.com/roadrunner-server/grpc/v2"
 	"github.com/roadrunner-server/logger/v2"
 	"github.com/roadrunner-server/resetter/v2"
 	rpcPlugin "github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	"github.com/roadrunner-server/rr-e2e-tests/plugins/grpc/proto/service"
 	"github.com/roadrunner-server/server/v2"
 	"github.com/stretchr/testify/assert"
@@ -37,82 +33,28 @@ import (
 	"google.golang.org/grpc/credentials"
 )
 
-const getAddr = "http://127.0.0.1:2112/metrics"
-
 func TestGrpcInit(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-grpc-init.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-grpc-init.yaml",
 		&grpcPlugin.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
 	time.Sleep(time.Second * 1)
-	stopCh <- struct{}{}
-
-	wg.Wait()
+	h.Stop()
 }
 
 // test panics -> https://github.com/grpc/grpc-go/blob/master/server.go#L644
 func TestGrpcInitDuplicate(t *testing.T) {
 	t.Skip("test panics, use locally")
+	env := harness.NewEnv(t)
+
 	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
 	assert.NoError(t, err)
 
 	cfg := &config.Plugin{
-		Path:   "configs/.rr-grpc-init-duplicate.yaml",
+		Path:   env.Render("configs/.rr-grpc-init-duplicate.yaml"),
 		Prefix: "rr",
 	}
 
@@ -134,203 +76,42 @@ func TestGrpcInitDuplicate(t *testing.T) {
 
 // different services, same methods inside
 func TestGrpcInitDup2(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-grpc-init-duplicate-2.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-grpc-init-duplicate-2.yaml",
 		&grpcPlugin.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
 	time.Sleep(time.Second * 2)
-	stopCh <- struct{}{}
-
-	wg.Wait()
+	h.Stop()
 }
 
 func TestGrpcInitMultiple(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-grpc-init-multiple.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-grpc-init-multiple.yaml",
 		&grpcPlugin.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
 	time.Sleep(time.Second * 1)
-	stopCh <- struct{}{}
-
-	wg.Wait()
+	h.Stop()
 }
 
 func TestGrpcRqRs(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-grpc-rq.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-grpc-rq.yaml",
 		&grpcPlugin.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
+	harness.WaitForTCP(t, env.Addr("grpc"), harness.DefaultWaitTimeout)
 
-	conn, err := grpc.Dial("127.0.0.1:9001", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(env.Addr("grpc"), grpc.WithTransportCredentials(insecure.NewCredentials()))
 	require.NoError(t, err)
 	require.NotNil(t, conn)
 
@@ -339,75 +120,22 @@ func TestGrpcRqRs(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, "TOST", resp.Msg)
 
-	stopCh <- struct{}{}
-
-	wg.Wait()
+	h.Stop()
 }
 
 func TestGrpcRqRsException(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-grpc-rq-exception.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-grpc-rq-exception.yaml",
 		&grpcPlugin.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
+	harness.WaitForTCP(t, env.Addr("grpc"), harness.DefaultWaitTimeout)
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-
-	conn, err := grpc.Dial("127.0.0.1:9001", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(env.Addr("grpc"), grpc.WithTransportCredentials(insecure.NewCredentials()))
 	require.NoError(t, err)
 	require.NotNil(t, conn)
 
@@ -417,75 +145,22 @@ func TestGrpcRqRsException(t *testing.T) {
 	require.Equal(t, "rpc error: code = Internal desc = FOOOOOOOOOOOO", err.Error())
 	require.Nil(t, resp)
 
-	stopCh <- struct{}{}
-
-	wg.Wait()
+	h.Stop()
 }
 
 func TestGrpcRqRsMultiple(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-grpc-rq-multiple.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-grpc-rq-multiple.yaml",
 		&grpcPlugin.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
+	harness.WaitForTCP(t, env.Addr("grpc"), harness.DefaultWaitTimeout)
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-
-	conn, err := grpc.Dial("127.0.0.1:9001", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(env.Addr("grpc"), grpc.WithTransportCredentials(insecure.NewCredentials()))
 	require.NoError(t, err)
 	require.NotNil(t, conn)
 
@@ -511,78 +186,25 @@ func TestGrpcRqRsMultiple(t *testing.T) {
 	err = watch.CloseSend()
 	require.NoError(t, err)
 
-	stopCh <- struct{}{}
-
-	wg.Wait()
+	h.Stop()
 }
 
 func TestGrpcRqRsTLS(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-grpc-rq-tls.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-grpc-rq-tls.yaml",
 		&grpcPlugin.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
-	time.Sleep(time.Second * 1)
+	harness.WaitForTCP(t, env.Addr("grpc"), harness.DefaultWaitTimeout)
 
 	creds, err := credentials.NewClientTLSFromFile("./configs/test-certs/test.pem", "")
 	require.NoError(t, err)
 
-	conn, err := grpc.Dial("127.0.0.1:9002", grpc.WithTransportCredentials(creds))
+	conn, err := grpc.Dial(env.Addr("grpc"), grpc.WithTransportCredentials(creds))
 	require.NoError(t, err)
 	require.NotNil(t, conn)
 
@@ -591,76 +213,23 @@ func TestGrpcRqRsTLS(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, "TOST", resp.Msg)
 
-	stopCh <- struct{}{}
-
-	wg.Wait()
+	h.Stop()
 }
 
 func TestGrpcRqRsTLSRootCA(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	if runtime.GOOS == "windows" {
 		t.Skip("root pool is not available on Windows")
 	}
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-grpc-rq-tls-rootca.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-grpc-rq-tls-rootca.yaml",
 		&grpcPlugin.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
-	time.Sleep(time.Second * 1)
+	harness.WaitForTCP(t, env.Addr("grpc"), harness.DefaultWaitTimeout)
 
 	ca, _ := os.ReadFile("./configs/test-certs/ca.cert")
 	require.NotNil(t, ca)
@@ -678,7 +247,7 @@ func TestGrpcRqRsTLSRootCA(t *testing.T) {
 		ClientCAs:          pool,
 	}
 
-	conn, err := grpc.Dial("127.0.0.1:9003", grpc.WithTransportCredentials(credentials.NewTLS(tlscfg)))
+	conn, err := grpc.Dial(env.Addr("grpc"), grpc.WithTransportCredentials(credentials.NewTLS(tlscfg)))
 	require.NoError(t, err)
 	require.NotNil(t, conn)
 
@@ -687,79 +256,26 @@ func TestGrpcRqRsTLSRootCA(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, "TOST", resp.Msg)
 
-	stopCh <- struct{}{}
-
-	wg.Wait()
+	h.Stop()
 }
 
 func TestGrpcRqRsTLS_WithReset(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-grpc-rq-tls.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-grpc-rq-tls.yaml",
 		&grpcPlugin.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 		&resetter.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
+	harness.WaitForTCP(t, env.Addr("grpc"), harness.DefaultWaitTimeout)
 
 	creds, err := credentials.NewClientTLSFromFile("./configs/test-certs/test.pem", "")
 	require.NoError(t, err)
 
-	conn, err := grpc.Dial("localhost:9002", grpc.WithTransportCredentials(creds))
+	conn, err := grpc.Dial("localhost:"+strconv.Itoa(env.Port("grpc")), grpc.WithTransportCredentials(creds))
 	require.NoError(t, err)
 	require.NotNil(t, conn)
 
@@ -769,82 +285,29 @@ func TestGrpcRqRsTLS_WithReset(t *testing.T) {
 	require.Equal(t, "TOST", resp.Msg)
 
 	// reset
-	t.Run("SendReset", sendReset)
+	t.Run("SendReset", sendReset(h.RPCAddr()))
 
 	resp2, err2 := client.Ping(context.Background(), &service.Message{Msg: "TOST"})
 	require.NoError(t, err2)
 	require.Equal(t, "TOST", resp2.Msg)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestGRPCMetrics(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	cfg := &config.Plugin{}
-	cfg.Prefix = "rr"
-	cfg.Path = "configs/.rr-grpc-metrics.yaml"
+	env := harness.NewEnv(t)
 
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-grpc-metrics.yaml",
 		&server.Plugin{},
 		&grpcPlugin.Plugin{},
 		&metrics.Plugin{},
 		l,
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	tt := time.NewTimer(time.Minute * 3)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	go func() {
-		defer tt.Stop()
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-tt.C:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
+	harness.WaitForTCP(t, env.Addr("grpc"), harness.DefaultWaitTimeout)
 
-	time.Sleep(time.Second * 2)
-
-	conn, err := grpc.Dial("127.0.0.1:9005", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(env.Addr("grpc"), grpc.WithTransportCredentials(insecure.NewCredentials()))
 	require.NoError(t, err)
 	require.NotNil(t, conn)
 
@@ -854,41 +317,36 @@ func TestGRPCMetrics(t *testing.T) {
 	require.Equal(t, "TOST", resp.Msg)
 
 	time.Sleep(time.Millisecond * 500)
-	genericOut, err := get()
+	genericOut, err := get(env.Addr("metrics"))
 	assert.NoError(t, err)
 	assert.Contains(t, genericOut, `rr_grpc_workers_memory_bytes`)
 	assert.Contains(t, genericOut, `rr_grpc_worker_state`)
 	assert.Contains(t, genericOut, `rr_grpc_worker_memory_bytes`)
 
-	close(sig)
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("grpc server was started").Len())
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("method was called successfully").Len())
 }
 
-func sendReset(t *testing.T) {
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
-	assert.NoError(t, err)
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-	// WorkerList contains list of workers.
+func sendReset(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
 
-	var ret bool
-	err = client.Call("resetter.Reset", "grpc", &ret)
-	assert.NoError(t, err)
-	assert.True(t, ret)
-	ret = false
+		ret, err := client.Resetter.Reset("grpc")
+		assert.NoError(t, err)
+		assert.True(t, ret)
 
-	var services []string
-	err = client.Call("resetter.List", nil, &services)
-	assert.NotNil(t, services)
-	assert.NoError(t, err)
-	require.Equal(t, []string{"grpc"}, services)
+		services, err := client.Resetter.List()
+		assert.NotNil(t, services)
+		assert.NoError(t, err)
+		require.Equal(t, []string{"grpc"}, services)
+	}
 }
 
 // get request and return body
-func get() (string, error) {
-	r, err := http.Get(getAddr)
+func get(addr string) (string, error) {
+	r, err := http.Get("http://" + addr + "/metrics")
 	if err != nil {
 		return "", err
 	}
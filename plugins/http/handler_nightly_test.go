@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/roadrunner-server/http/v2/handler"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	"github.com/roadrunner-server/sdk/v2/ipc/pipe"
 	"github.com/roadrunner-server/sdk/v2/pool"
 	"github.com/stretchr/testify/assert"
@@ -20,6 +21,8 @@ import (
 var mockLog = zap.NewNop() //nolint:gochecknoglobals
 
 func TestHandler_Error(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "error", "pipes")
@@ -40,7 +43,7 @@ func TestHandler_Error(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":8177", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -54,9 +57,9 @@ func TestHandler_Error(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
-	_, r, err := get("http://127.0.0.1:8177/?hello=world")
+	_, r, err := get("http://" + hs.Addr + "/?hello=world")
 	assert.NoError(t, err)
 	defer func() {
 		_ = r.Body.Close()
@@ -65,6 +68,8 @@ func TestHandler_Error(t *testing.T) {
 }
 
 func TestHandler_Error2(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "error2", "pipes")
@@ -85,7 +90,7 @@ func TestHandler_Error2(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":8178", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -99,9 +104,9 @@ func TestHandler_Error2(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
-	_, r, err := get("http://127.0.0.1:8178/?hello=world")
+	_, r, err := get("http://" + hs.Addr + "/?hello=world")
 	assert.NoError(t, err)
 	defer func() {
 		_ = r.Body.Close()
@@ -110,6 +115,8 @@ func TestHandler_Error2(t *testing.T) {
 }
 
 func TestHandler_ErrorDuration(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "error", "pipes")
@@ -130,7 +137,7 @@ func TestHandler_ErrorDuration(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":8182", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -144,9 +151,9 @@ func TestHandler_ErrorDuration(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
-	_, r, err := get("http://127.0.0.1:8182/?hello=world")
+	_, r, err := get("http://" + hs.Addr + "/?hello=world")
 	assert.NoError(t, err)
 	defer func() {
 		_ = r.Body.Close()
@@ -18,6 +18,7 @@ import (
 
 	"github.com/goccy/go-json"
 	"github.com/roadrunner-server/http/v2/handler"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	"github.com/roadrunner-server/sdk/v2/ipc/pipe"
 	"github.com/roadrunner-server/sdk/v2/pool"
 	"github.com/stretchr/testify/assert"
@@ -28,6 +29,8 @@ import (
 var mockLog = zap.NewNop() //nolint:gochecknoglobals
 
 func TestHandler_Echo(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "echo", "pipes")
@@ -43,7 +46,7 @@ func TestHandler_Echo(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":9177", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -56,9 +59,9 @@ func TestHandler_Echo(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}(hs)
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
-	body, r, err := get("http://127.0.0.1:9177/?hello=world")
+	body, r, err := get("http://" + hs.Addr + "/?hello=world")
 	assert.NoError(t, err)
 	defer func() {
 		_ = r.Body.Close()
@@ -73,6 +76,8 @@ func Test_HandlerErrors(t *testing.T) {
 }
 
 func TestHandler_Headers(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "header", "pipes")
@@ -93,7 +98,7 @@ func TestHandler_Headers(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":8078", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -107,9 +112,9 @@ func TestHandler_Headers(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 100)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
-	req, err := http.NewRequest("GET", "http://127.0.0.1:8078?hello=world", nil)
+	req, err := http.NewRequest("GET", "http://"+hs.Addr+"?hello=world", nil)
 	assert.NoError(t, err)
 
 	req.Header.Add("input", "sample")
@@ -133,6 +138,8 @@ func TestHandler_Headers(t *testing.T) {
 }
 
 func TestHandler_Empty_User_Agent(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "user-agent", "pipes")
@@ -153,7 +160,7 @@ func TestHandler_Empty_User_Agent(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":19658", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -167,9 +174,9 @@ func TestHandler_Empty_User_Agent(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
-	req, err := http.NewRequest("GET", "http://127.0.0.1:19658?hello=world", nil)
+	req, err := http.NewRequest("GET", "http://"+hs.Addr+"?hello=world", nil)
 	assert.NoError(t, err)
 
 	req.Header.Add("user-agent", "")
@@ -192,6 +199,8 @@ func TestHandler_Empty_User_Agent(t *testing.T) {
 }
 
 func TestHandler_User_Agent(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "user-agent", "pipes")
@@ -212,7 +221,7 @@ func TestHandler_User_Agent(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":25688", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -226,9 +235,9 @@ func TestHandler_User_Agent(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
-	req, err := http.NewRequest("GET", "http://127.0.0.1:25688?hello=world", nil)
+	req, err := http.NewRequest("GET", "http://"+hs.Addr+"?hello=world", nil)
 	assert.NoError(t, err)
 
 	req.Header.Add("User-Agent", "go-agent")
@@ -251,6 +260,8 @@ func TestHandler_User_Agent(t *testing.T) {
 }
 
 func TestHandler_Cookies(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "cookie", "pipes")
@@ -271,7 +282,7 @@ func TestHandler_Cookies(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":8079", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -285,9 +296,9 @@ func TestHandler_Cookies(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
-	req, err := http.NewRequest("GET", "http://127.0.0.1:8079", nil)
+	req, err := http.NewRequest("GET", "http://"+hs.Addr, nil)
 	assert.NoError(t, err)
 
 	req.AddCookie(&http.Cookie{Name: "input", Value: "input-value"})
@@ -315,6 +326,8 @@ func TestHandler_Cookies(t *testing.T) {
 }
 
 func TestHandler_JsonPayload_POST(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "payload", "pipes")
@@ -335,7 +348,7 @@ func TestHandler_JsonPayload_POST(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":8090", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -349,11 +362,11 @@ func TestHandler_JsonPayload_POST(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
 	req, err := http.NewRequest(
 		"POST",
-		"http://127.0.0.1"+hs.Addr,
+		"http://"+hs.Addr,
 		bytes.NewBufferString(`{"key":"value"}`),
 	)
 	assert.NoError(t, err)
@@ -378,6 +391,8 @@ func TestHandler_JsonPayload_POST(t *testing.T) {
 }
 
 func TestHandler_JsonPayload_PUT(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "payload", "pipes")
@@ -398,7 +413,7 @@ func TestHandler_JsonPayload_PUT(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":8081", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -412,9 +427,9 @@ func TestHandler_JsonPayload_PUT(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
-	req, err := http.NewRequest("PUT", "http://127.0.0.1"+hs.Addr, bytes.NewBufferString(`{"key":"value"}`))
+	req, err := http.NewRequest("PUT", "http://"+hs.Addr, bytes.NewBufferString(`{"key":"value"}`))
 	assert.NoError(t, err)
 
 	req.Header.Add("Content-Type", "application/json")
@@ -437,6 +452,8 @@ func TestHandler_JsonPayload_PUT(t *testing.T) {
 }
 
 func TestHandler_JsonPayload_PATCH(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "payload", "pipes")
@@ -457,7 +474,7 @@ func TestHandler_JsonPayload_PATCH(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":8082", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -471,9 +488,9 @@ func TestHandler_JsonPayload_PATCH(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
-	req, err := http.NewRequest("PATCH", "http://127.0.0.1"+hs.Addr, bytes.NewBufferString(`{"key":"value"}`))
+	req, err := http.NewRequest("PATCH", "http://"+hs.Addr, bytes.NewBufferString(`{"key":"value"}`))
 	assert.NoError(t, err)
 
 	req.Header.Add("Content-Type", "application/json")
@@ -496,6 +513,8 @@ func TestHandler_JsonPayload_PATCH(t *testing.T) {
 }
 
 func TestHandler_FormData_POST(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "data", "pipes")
@@ -516,7 +535,7 @@ func TestHandler_FormData_POST(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":10084", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -530,7 +549,7 @@ func TestHandler_FormData_POST(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 500)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
 	form := url.Values{}
 
@@ -543,7 +562,7 @@ func TestHandler_FormData_POST(t *testing.T) {
 	form.Add("arr[c]p", "l")
 	form.Add("arr[c]z", "")
 
-	req, err := http.NewRequest("POST", "http://127.0.0.1"+hs.Addr, strings.NewReader(form.Encode()))
+	req, err := http.NewRequest("POST", "http://"+hs.Addr, strings.NewReader(form.Encode()))
 	assert.NoError(t, err)
 
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
@@ -578,6 +597,8 @@ func TestHandler_FormData_POST(t *testing.T) {
 }
 
 func TestHandler_FormData_POST_Overwrite(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "data", "pipes")
@@ -598,7 +619,7 @@ func TestHandler_FormData_POST_Overwrite(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":8083", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -612,7 +633,7 @@ func TestHandler_FormData_POST_Overwrite(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
 	form := url.Values{}
 
@@ -626,7 +647,7 @@ func TestHandler_FormData_POST_Overwrite(t *testing.T) {
 	form.Add("arr[c]p", "l")
 	form.Add("arr[c]z", "")
 
-	req, err := http.NewRequest("POST", "http://127.0.0.1"+hs.Addr, strings.NewReader(form.Encode()))
+	req, err := http.NewRequest("POST", "http://"+hs.Addr, strings.NewReader(form.Encode()))
 	assert.NoError(t, err)
 
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
@@ -661,6 +682,8 @@ func TestHandler_FormData_POST_Overwrite(t *testing.T) {
 }
 
 func TestHandler_FormData_POST_Form_UrlEncoded_Charset(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "data", "pipes")
@@ -681,7 +704,7 @@ func TestHandler_FormData_POST_Form_UrlEncoded_Charset(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":8085", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -695,7 +718,7 @@ func TestHandler_FormData_POST_Form_UrlEncoded_Charset(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
 	form := url.Values{}
 
@@ -708,7 +731,7 @@ func TestHandler_FormData_POST_Form_UrlEncoded_Charset(t *testing.T) {
 	form.Add("arr[c]p", "l")
 	form.Add("arr[c]z", "")
 
-	req, err := http.NewRequest("POST", "http://127.0.0.1"+hs.Addr, strings.NewReader(form.Encode()))
+	req, err := http.NewRequest("POST", "http://"+hs.Addr, strings.NewReader(form.Encode()))
 	assert.NoError(t, err)
 
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded; charset=UTF-8")
@@ -743,6 +766,8 @@ func TestHandler_FormData_POST_Form_UrlEncoded_Charset(t *testing.T) {
 }
 
 func TestHandler_FormData_PUT(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "data", "pipes")
@@ -763,7 +788,7 @@ func TestHandler_FormData_PUT(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":17834", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -777,7 +802,7 @@ func TestHandler_FormData_PUT(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 500)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
 	form := url.Values{}
 
@@ -790,7 +815,7 @@ func TestHandler_FormData_PUT(t *testing.T) {
 	form.Add("arr[c]p", "l")
 	form.Add("arr[c]z", "")
 
-	req, err := http.NewRequest("PUT", "http://127.0.0.1"+hs.Addr, strings.NewReader(form.Encode()))
+	req, err := http.NewRequest("PUT", "http://"+hs.Addr, strings.NewReader(form.Encode()))
 	assert.NoError(t, err)
 
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
@@ -826,6 +851,8 @@ func TestHandler_FormData_PUT(t *testing.T) {
 }
 
 func TestHandler_FormData_PATCH(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "data", "pipes")
@@ -846,7 +873,7 @@ func TestHandler_FormData_PATCH(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":8086", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -860,7 +887,7 @@ func TestHandler_FormData_PATCH(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
 	form := url.Values{}
 
@@ -873,7 +900,7 @@ func TestHandler_FormData_PATCH(t *testing.T) {
 	form.Add("arr[c]p", "l")
 	form.Add("arr[c]z", "")
 
-	req, err := http.NewRequest("PATCH", "http://127.0.0.1"+hs.Addr, strings.NewReader(form.Encode()))
+	req, err := http.NewRequest("PATCH", "http://"+hs.Addr, strings.NewReader(form.Encode()))
 	assert.NoError(t, err)
 
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
@@ -908,6 +935,8 @@ func TestHandler_FormData_PATCH(t *testing.T) {
 }
 
 func TestHandler_Multipart_POST(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "data", "pipes")
@@ -928,7 +957,7 @@ func TestHandler_Multipart_POST(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":8019", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -942,7 +971,7 @@ func TestHandler_Multipart_POST(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
 	var mb bytes.Buffer
 	w := multipart.NewWriter(&mb)
@@ -997,7 +1026,7 @@ func TestHandler_Multipart_POST(t *testing.T) {
 		t.Errorf("error closing the writer: error %v", err)
 	}
 
-	req, err := http.NewRequest("POST", "http://127.0.0.1"+hs.Addr, &mb)
+	req, err := http.NewRequest("POST", "http://"+hs.Addr, &mb)
 	assert.NoError(t, err)
 
 	req.Header.Set("Content-Type", w.FormDataContentType())
@@ -1032,6 +1061,8 @@ func TestHandler_Multipart_POST(t *testing.T) {
 }
 
 func TestHandler_Multipart_PUT(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "data", "pipes")
@@ -1052,7 +1083,7 @@ func TestHandler_Multipart_PUT(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":8020", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -1066,7 +1097,7 @@ func TestHandler_Multipart_PUT(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 500)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
 	var mb bytes.Buffer
 	w := multipart.NewWriter(&mb)
@@ -1121,7 +1152,7 @@ func TestHandler_Multipart_PUT(t *testing.T) {
 		t.Errorf("error closing the writer: error %v", err)
 	}
 
-	req, err := http.NewRequest("PUT", "http://127.0.0.1"+hs.Addr, &mb)
+	req, err := http.NewRequest("PUT", "http://"+hs.Addr, &mb)
 	assert.NoError(t, err)
 
 	req.Header.Set("Content-Type", w.FormDataContentType())
@@ -1156,6 +1187,8 @@ func TestHandler_Multipart_PUT(t *testing.T) {
 }
 
 func TestHandler_Multipart_PATCH(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "data", "pipes")
@@ -1176,7 +1209,7 @@ func TestHandler_Multipart_PATCH(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":8021", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -1191,7 +1224,7 @@ func TestHandler_Multipart_PATCH(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 500)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
 	var mb bytes.Buffer
 	w := multipart.NewWriter(&mb)
@@ -1247,7 +1280,7 @@ func TestHandler_Multipart_PATCH(t *testing.T) {
 		t.Errorf("error closing the writer: error %v", err)
 	}
 
-	req, err := http.NewRequest("PATCH", "http://127.0.0.1"+hs.Addr, &mb)
+	req, err := http.NewRequest("PATCH", "http://"+hs.Addr, &mb)
 	assert.NoError(t, err)
 
 	req.Header.Set("Content-Type", w.FormDataContentType())
@@ -1282,6 +1315,8 @@ func TestHandler_Multipart_PATCH(t *testing.T) {
 }
 
 func TestHandler_Error(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "error", "pipes")
@@ -1302,7 +1337,7 @@ func TestHandler_Error(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":8177", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -1316,9 +1351,9 @@ func TestHandler_Error(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
-	_, r, err := get("http://127.0.0.1:8177/?hello=world")
+	_, r, err := get("http://" + hs.Addr + "/?hello=world")
 	assert.NoError(t, err)
 	defer func() {
 		_ = r.Body.Close()
@@ -1327,6 +1362,8 @@ func TestHandler_Error(t *testing.T) {
 }
 
 func TestHandler_Error2(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "error2", "pipes")
@@ -1347,7 +1384,7 @@ func TestHandler_Error2(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":8178", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -1361,9 +1398,9 @@ func TestHandler_Error2(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
-	_, r, err := get("http://127.0.0.1:8178/?hello=world")
+	_, r, err := get("http://" + hs.Addr + "/?hello=world")
 	assert.NoError(t, err)
 	defer func() {
 		_ = r.Body.Close()
@@ -1372,6 +1409,8 @@ func TestHandler_Error2(t *testing.T) {
 }
 
 func TestHandler_Error3(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "pid", "pipes")
@@ -1392,7 +1431,7 @@ func TestHandler_Error3(t *testing.T) {
 	h, err := handler.NewHandler(1, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":8179", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -1406,14 +1445,14 @@ func TestHandler_Error3(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
 	b2 := &bytes.Buffer{}
 	for i := 0; i < 1024*1024; i++ {
 		b2.Write([]byte("  "))
 	}
 
-	req, err := http.NewRequest("POST", "http://127.0.0.1"+hs.Addr, b2)
+	req, err := http.NewRequest("POST", "http://"+hs.Addr, b2)
 	assert.NoError(t, err)
 
 	r, err := http.DefaultClient.Do(req)
@@ -1430,6 +1469,8 @@ func TestHandler_Error3(t *testing.T) {
 }
 
 func TestHandler_ResponseDuration(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "echo", "pipes")
@@ -1450,7 +1491,7 @@ func TestHandler_ResponseDuration(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":8180", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -1464,9 +1505,9 @@ func TestHandler_ResponseDuration(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
-	body, r, err := get("http://127.0.0.1:8180/?hello=world")
+	body, r, err := get("http://" + hs.Addr + "/?hello=world")
 	assert.NoError(t, err)
 	defer func() {
 		_ = r.Body.Close()
@@ -1477,6 +1518,8 @@ func TestHandler_ResponseDuration(t *testing.T) {
 }
 
 func TestHandler_ResponseDurationDelayed(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "echoDelay", "pipes")
@@ -1497,7 +1540,7 @@ func TestHandler_ResponseDurationDelayed(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":8181", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -1507,6 +1550,8 @@ func TestHandler_ResponseDurationDelayed(t *testing.T) {
 }
 
 func TestHandler_ErrorDuration(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "error", "pipes")
@@ -1527,7 +1572,7 @@ func TestHandler_ErrorDuration(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":8182", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -1541,9 +1586,9 @@ func TestHandler_ErrorDuration(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
-	_, r, err := get("http://127.0.0.1:8182/?hello=world")
+	_, r, err := get("http://" + hs.Addr + "/?hello=world")
 	assert.NoError(t, err)
 	defer func() {
 		_ = r.Body.Close()
@@ -1553,6 +1598,8 @@ func TestHandler_ErrorDuration(t *testing.T) {
 }
 
 func TestHandler_IP(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "ip", "pipes")
@@ -1573,7 +1620,7 @@ func TestHandler_IP(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: "127.0.0.1:8183", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -1587,9 +1634,9 @@ func TestHandler_IP(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
-	body, r, err := get("http://127.0.0.1:8183/")
+	body, r, err := get("http://" + hs.Addr + "/")
 	assert.NoError(t, err)
 	defer func() {
 		_ = r.Body.Close()
@@ -1599,6 +1646,8 @@ func TestHandler_IP(t *testing.T) {
 }
 
 func BenchmarkHandler_Listen_Echo(b *testing.B) {
+	env := harness.NewEnv(b)
+
 	p, err := pool.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "echo", "pipes")
@@ -1619,7 +1668,7 @@ func BenchmarkHandler_Listen_Echo(b *testing.B) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{}, p, mockLog, false)
 	assert.NoError(b, err)
 
-	hs := &http.Server{Addr: ":8188", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -1633,13 +1682,13 @@ func BenchmarkHandler_Listen_Echo(b *testing.B) {
 			b.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(b, hs.Addr, harness.DefaultWaitTimeout)
 
 	b.ResetTimer()
 	b.ReportAllocs()
 	bb := "WORLD"
 	for n := 0; n < b.N; n++ {
-		r, err := http.Get("http://127.0.0.1:8188/?hello=world")
+		r, err := http.Get("http://" + hs.Addr + "/?hello=world")
 		if err != nil {
 			b.Fail()
 		}
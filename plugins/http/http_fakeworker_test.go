@@ -0,0 +1,101 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	httpPlugin "github.com/roadrunner-server/http/v2"
+	"github.com/roadrunner-server/informer/v2"
+	"github.com/roadrunner-server/logger/v2"
+	rpcPlugin "github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/fakeworker"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
+	"github.com/roadrunner-server/server/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPFakeWorker(t *testing.T) {
+	t.Parallel()
+
+	env := harness.NewEnv(t)
+	env.Set("worker", fakeworker.Command("http"))
+	env.Set("record", filepath.Join(env.TempDir(), "records"))
+
+	h := env.Start("configs/.rr-http-fakeworker.yaml",
+		&logger.Plugin{},
+		&server.Plugin{},
+		&httpPlugin.Plugin{},
+		&rpcPlugin.Plugin{},
+		&informer.Plugin{},
+	)
+
+	harness.WaitForWorkers(t, h.RPCAddr(), "http", 2, harness.DefaultWaitTimeout)
+	addr := "http://" + env.Addr("http")
+
+	t.Run("Echo", func(t *testing.T) {
+		body, r, err := get(addr + "/?hello=world")
+		require.NoError(t, err)
+		assert.Equal(t, 201, r.StatusCode)
+		assert.Equal(t, "WORLD", body)
+	})
+
+	t.Run("Header", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, addr+"/?hello=world", nil) //nolint:noctx
+		require.NoError(t, err)
+		req.Header.Set(fakeworker.BehaviourHeader, "header")
+		req.Header.Set("input", "sample")
+
+		r, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		b, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		_ = r.Body.Close()
+
+		assert.Equal(t, 200, r.StatusCode)
+		assert.Equal(t, "world", r.Header.Get("Header"))
+		assert.Equal(t, "SAMPLE", string(b))
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, addr, nil) //nolint:noctx
+		require.NoError(t, err)
+		req.Header.Set(fakeworker.BehaviourHeader, "error")
+
+		r, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = r.Body.Close()
+
+		assert.Equal(t, 500, r.StatusCode)
+	})
+
+	t.Run("RequestContext", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, addr+"/foo/bar?key=value", nil) //nolint:noctx
+		require.NoError(t, err)
+		req.Header.Set(fakeworker.BehaviourHeader, "pid")
+		req.AddCookie(&http.Cookie{Name: "input", Value: "cookie"})
+
+		r, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = r.Body.Close()
+		require.Equal(t, 200, r.StatusCode)
+
+		records, err := fakeworker.ReadRecords(env.Var("record"))
+		require.NoError(t, err)
+		require.NotEmpty(t, records)
+
+		ctx, err := records[len(records)-1].HTTPRequest()
+		require.NoError(t, err)
+
+		assert.Equal(t, "127.0.0.1", ctx.RemoteAddr)
+		assert.Equal(t, "HTTP/1.1", ctx.Protocol)
+		assert.Equal(t, http.MethodGet, ctx.Method)
+		assert.Equal(t, addr+"/foo/bar?key=value", ctx.URI)
+		assert.Equal(t, "key=value", ctx.RawQuery)
+		assert.Equal(t, "pid", ctx.Headers.Get(fakeworker.BehaviourHeader))
+		assert.Equal(t, "cookie", ctx.Cookies["input"])
+		assert.False(t, ctx.Parsed)
+	})
+}
@@ -2,86 +2,29 @@ package http
 
 import (
 	"net/http"
-	"os"
-	"os/signal"
-	"sync"
-	"syscall"
 	"testing"
-	"time"
 
-	"github.com/roadrunner-server/config/v2"
-	endure "github.com/roadrunner-server/endure/pkg/container"
 	httpPlugin "github.com/roadrunner-server/http/v2"
 	"github.com/roadrunner-server/logger/v2"
 	ipparser "github.com/roadrunner-server/proxy_ip_parser/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	"github.com/roadrunner-server/server/v2"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestXFF(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/headers/.rr-http-xff.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/headers/.rr-http-xff.yaml",
 		&logger.Plugin{},
 		&server.Plugin{},
 		&ipparser.Plugin{},
 		&httpPlugin.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 2)
-
-	req, err := http.NewRequest("GET", "http://127.0.0.1:12311?hello=world", nil)
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+
+	req, err := http.NewRequest("GET", "http://"+env.Addr("http")+"?hello=world", nil)
 	assert.NoError(t, err)
 	req.Header.Add("X-Forwarded-For", "127.0.0.1")
 
@@ -94,7 +37,7 @@ func TestXFF(t *testing.T) {
 
 	// ---
 
-	req, err = http.NewRequest("GET", "http://127.0.0.1:12311?hello=world", nil)
+	req, err = http.NewRequest("GET", "http://"+env.Addr("http")+"?hello=world", nil)
 	assert.NoError(t, err)
 	req.Header.Add("X-Forwarded-For", "foo.workstation")
 
@@ -107,7 +50,7 @@ func TestXFF(t *testing.T) {
 
 	// ---
 
-	req, err = http.NewRequest("GET", "http://127.0.0.1:12311?hello=world", nil)
+	req, err = http.NewRequest("GET", "http://"+env.Addr("http")+"?hello=world", nil)
 	assert.NoError(t, err)
 	req.Header.Add("X-Forwarded-For", "9.10.11.12")
 
@@ -118,74 +61,22 @@ func TestXFF(t *testing.T) {
 	err = r.Body.Close()
 	assert.NoError(t, err)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestForwarded(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/headers/.rr-http-f.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/headers/.rr-http-f.yaml",
 		&ipparser.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 2)
-
-	req, err := http.NewRequest("GET", "http://127.0.0.1:12811?hello=world", nil)
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+
+	req, err := http.NewRequest("GET", "http://"+env.Addr("http")+"?hello=world", nil)
 	assert.NoError(t, err)
 	req.Header.Add("Forwarded", "foo.workstation")
 
@@ -198,7 +89,7 @@ func TestForwarded(t *testing.T) {
 
 	// --
 
-	req, err = http.NewRequest("GET", "http://127.0.0.1:12811?hello=world", nil)
+	req, err = http.NewRequest("GET", "http://"+env.Addr("http")+"?hello=world", nil)
 	assert.NoError(t, err)
 	req.Header.Add("Forwarded", "by=foo;for=127.0.0.1;host=foo.workstation;proto=http")
 
@@ -211,7 +102,7 @@ func TestForwarded(t *testing.T) {
 
 	// --
 
-	req, err = http.NewRequest("GET", "http://127.0.0.1:12811?hello=world", nil)
+	req, err = http.NewRequest("GET", "http://"+env.Addr("http")+"?hello=world", nil)
 	assert.NoError(t, err)
 	req.Header.Add("Forwarded", "by=foo;for=127.0.0.1;host=foo.workstation;proto=http")
 
@@ -224,7 +115,7 @@ func TestForwarded(t *testing.T) {
 
 	// --
 
-	req, err = http.NewRequest("GET", "http://127.0.0.1:12811?hello=world", nil)
+	req, err = http.NewRequest("GET", "http://"+env.Addr("http")+"?hello=world", nil)
 	assert.NoError(t, err)
 	req.Header.Add("Forwarded", "by=foo;for=3.11.0.1;host=foo.workstation;proto=http")
 
@@ -235,6 +126,5 @@ func TestForwarded(t *testing.T) {
 	err = r.Body.Close()
 	assert.NoError(t, err)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
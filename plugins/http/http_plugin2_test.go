@@ -6,15 +6,12 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"os/signal"
 	"sync"
-	"syscall"
 	"testing"
 	"time"
 
 	"github.com/goccy/go-json"
 	"github.com/roadrunner-server/cache/v2"
-	"github.com/roadrunner-server/config/v2"
 	endure "github.com/roadrunner-server/endure/pkg/container"
 	"github.com/roadrunner-server/fileserver/v2"
 	"github.com/roadrunner-server/gzip/v2"
@@ -23,367 +20,166 @@ import (
 	"github.com/roadrunner-server/memory/v2"
 	newrelic "github.com/roadrunner-server/new_relic/v2"
 	rpcPlugin "github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	"github.com/roadrunner-server/server/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestHTTPPost(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-post-test.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-post-test.yaml",
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
 
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	t.Run("BombardWithPosts", echoHTTPPost(env.Addr("http")))
 
-	time.Sleep(time.Second * 1)
-	t.Run("BombardWithPosts", echoHTTPPost)
-
-	stopCh <- struct{}{}
-
-	wg.Wait()
+	h.Stop()
 }
 
-func echoHTTPPost(t *testing.T) {
-	body := struct {
-		Name  string `json:"name"`
-		Index int    `json:"index"`
-	}{
-		Name:  "foo",
-		Index: 111,
-	}
-
-	bd, err := json.Marshal(body)
-	require.NoError(t, err)
-
-	rdr := bytes.NewReader(bd)
-
-	resp, err := http.Post("http://127.0.0.1:10084/", "", rdr)
-	assert.NoError(t, err)
-
-	b, err := ioutil.ReadAll(resp.Body)
-	assert.NoError(t, err)
-	assert.Equal(t, 200, resp.StatusCode)
+func echoHTTPPost(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		body := struct {
+			Name  string `json:"name"`
+			Index int    `json:"index"`
+		}{
+			Name:  "foo",
+			Index: 111,
+		}
 
-	require.True(t, bytes.Equal(bd, b))
+		bd, err := json.Marshal(body)
+		require.NoError(t, err)
 
-	_ = resp.Body.Close()
+		rdr := bytes.NewReader(bd)
 
-	for i := 0; i < 20; i++ {
-		rdr = bytes.NewReader(bd)
-		resp, err = http.Post("http://127.0.0.1:10084/", "application/json", rdr)
+		resp, err := http.Post("http://"+addr+"/", "", rdr)
 		assert.NoError(t, err)
 
-		b, err = ioutil.ReadAll(resp.Body)
+		b, err := ioutil.ReadAll(resp.Body)
 		assert.NoError(t, err)
 		assert.Equal(t, 200, resp.StatusCode)
 
 		require.True(t, bytes.Equal(bd, b))
 
 		_ = resp.Body.Close()
+
+		for i := 0; i < 20; i++ {
+			rdr = bytes.NewReader(bd)
+			resp, err = http.Post("http://"+addr+"/", "application/json", rdr)
+			assert.NoError(t, err)
+
+			b, err = ioutil.ReadAll(resp.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, 200, resp.StatusCode)
+
+			require.True(t, bytes.Equal(bd, b))
+
+			_ = resp.Body.Close()
+		}
 	}
 }
 
 func TestSSLNoHTTP(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-ssl-no-http.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-ssl-no-http.yaml",
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
+	harness.WaitForTCP(t, env.Addr("https"), harness.DefaultWaitTimeout)
+	t.Run("SSLEcho", sslEcho2(env.Addr("https")))
 
-	time.Sleep(time.Second * 1)
-	t.Run("SSLEcho", sslEcho2)
-
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 	time.Sleep(time.Second)
 }
 
-func sslEcho2(t *testing.T) {
-	req, err := http.NewRequest("GET", "https://127.0.0.1:4455?hello=world", nil)
-	assert.NoError(t, err)
+func sslEcho2(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		req, err := http.NewRequest("GET", "https://"+addr+"?hello=world", nil)
+		assert.NoError(t, err)
 
-	r, err := sslClient.Do(req)
-	assert.NoError(t, err)
+		r, err := sslClient.Do(req)
+		assert.NoError(t, err)
 
-	b, err := ioutil.ReadAll(r.Body)
-	assert.NoError(t, err)
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
 
-	assert.NoError(t, err)
-	assert.Equal(t, 201, r.StatusCode)
-	assert.Equal(t, "WORLD", string(b))
+		assert.NoError(t, err)
+		assert.Equal(t, 201, r.StatusCode)
+		assert.Equal(t, "WORLD", string(b))
 
-	err2 := r.Body.Close()
-	if err2 != nil {
-		t.Errorf("fail to close the Body: error %v", err2)
+		err2 := r.Body.Close()
+		if err2 != nil {
+			t.Errorf("fail to close the Body: error %v", err2)
+		}
 	}
 }
 
 func TestFileServer(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel), endure.GracefulShutdownTimeout(time.Second*30))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-http-static-new.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Options(endure.GracefulShutdownTimeout(time.Second*30)).Start("configs/.rr-http-static-new.yaml",
 		&logger.Plugin{},
 		&fileserver.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
+	harness.WaitForTCP(t, env.Addr("fileserver"), harness.DefaultWaitTimeout)
+	t.Run("ServeSampleEtag", serveStaticSampleEtag2(env.Addr("fileserver")))
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
+	h.Stop()
+}
 
-	stopCh := make(chan struct{}, 1)
+func serveStaticSampleEtag2(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		// OK 200 response
+		b, r, err := get("http://" + addr + "/foo/sample.txt")
+		assert.NoError(t, err)
+		assert.Contains(t, b, "sample")
+		assert.Equal(t, r.StatusCode, http.StatusOK)
+		etag := r.Header.Get("Etag")
+		_ = r.Body.Close()
 
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
+		// Should be 304 response with same etag
+		c := http.Client{
+			Timeout: time.Second * 5,
 		}
-	}()
-
-	time.Sleep(time.Second)
-	t.Run("ServeSampleEtag", serveStaticSampleEtag2)
 
-	stopCh <- struct{}{}
-	wg.Wait()
-}
+		parsedURL, _ := url.Parse("http://" + addr + "/foo/sample.txt")
 
-func serveStaticSampleEtag2(t *testing.T) {
-	// OK 200 response
-	b, r, err := get("http://127.0.0.1:10101/foo/sample.txt")
-	assert.NoError(t, err)
-	assert.Contains(t, b, "sample")
-	assert.Equal(t, r.StatusCode, http.StatusOK)
-	etag := r.Header.Get("Etag")
-	_ = r.Body.Close()
-
-	// Should be 304 response with same etag
-	c := http.Client{
-		Timeout: time.Second * 5,
-	}
-
-	parsedURL, _ := url.Parse("http://127.0.0.1:10101/foo/sample.txt")
+		req := &http.Request{
+			Method: http.MethodGet,
+			URL:    parsedURL,
+			Header: map[string][]string{"If-None-Match": {etag}},
+		}
 
-	req := &http.Request{
-		Method: http.MethodGet,
-		URL:    parsedURL,
-		Header: map[string][]string{"If-None-Match": {etag}},
+		resp, err := c.Do(req)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+		_ = resp.Body.Close()
 	}
-
-	resp, err := c.Do(req)
-	assert.Nil(t, err)
-	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
-	_ = resp.Body.Close()
 }
 
 func TestHTTPNewRelic(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-http-new-relic.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-http-new-relic.yaml",
 		&newrelic.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-	req, err := http.NewRequest("GET", "http://127.0.0.1:19999", nil)
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	req, err := http.NewRequest("GET", "http://"+env.Addr("http"), nil)
 	assert.NoError(t, err)
 
 	resp, err := http.DefaultClient.Do(req)
@@ -395,75 +191,22 @@ func TestHTTPNewRelic(t *testing.T) {
 	require.Nil(t, resp.Header["Rr_newrelic"])
 	require.Equal(t, []string{"application/json"}, resp.Header["Content-Type"])
 
-	stopCh <- struct{}{}
-
-	wg.Wait()
+	h.Stop()
 }
 
 func TestHTTPNewRelicError(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-http-new-relic-error.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-http-new-relic-error.yaml",
 		&newrelic.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
-	time.Sleep(time.Second * 1)
-	req, err := http.NewRequest("GET", "http://127.0.0.1:22998", nil)
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	req, err := http.NewRequest("GET", "http://"+env.Addr("http"), nil)
 	assert.NoError(t, err)
 
 	resp, err := http.DefaultClient.Do(req)
@@ -476,75 +219,22 @@ func TestHTTPNewRelicError(t *testing.T) {
 	require.Nil(t, resp.Header["Rr_newrelic_error"])
 	require.Equal(t, []string{"application/json"}, resp.Header["Content-Type"])
 
-	stopCh <- struct{}{}
-
-	wg.Wait()
+	h.Stop()
 }
 
 func TestHTTPNewRelicIgnore(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-http-new-relic-ignore.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-http-new-relic-ignore.yaml",
 		&newrelic.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-	req, err := http.NewRequest("GET", "http://127.0.0.1:53493", nil)
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	req, err := http.NewRequest("GET", "http://"+env.Addr("http"), nil)
 	assert.NoError(t, err)
 
 	resp, err := http.DefaultClient.Do(req)
@@ -556,142 +246,35 @@ func TestHTTPNewRelicIgnore(t *testing.T) {
 	require.Nil(t, resp.Header["Rr_newrelic_ignore"])
 	require.Equal(t, []string{"application/json"}, resp.Header["Content-Type"])
 
-	stopCh <- struct{}{}
-
-	wg.Wait()
+	h.Stop()
 }
 
 func TestHTTPCache(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-http-cache.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-http-cache.yaml",
 		&cache.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 		&memory.Plugin{},
 		&httpPlugin.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestHTTPCacheDifferentRqs(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-http-cache.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-http-cache.yaml",
 		&cache.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 		&memory.Plugin{},
 		&httpPlugin.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second)
-
-	req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:53123", nil)
+	req, err := http.NewRequest(http.MethodGet, "http://"+env.Addr("http"), nil)
 	assert.NoError(t, err)
 
 	r, err := http.DefaultClient.Do(req)
@@ -711,7 +294,7 @@ func TestHTTPCacheDifferentRqs(t *testing.T) {
 
 	// -------------------
 
-	req, err = http.NewRequest(http.MethodGet, "http://127.0.0.1:53123", nil)
+	req, err = http.NewRequest(http.MethodGet, "http://"+env.Addr("http"), nil)
 	require.NoError(t, err)
 	// typo
 	req.Header.Set("Cache-Control", "max-age=abc")
@@ -725,7 +308,7 @@ func TestHTTPCacheDifferentRqs(t *testing.T) {
 
 	// -----------------
 
-	req, err = http.NewRequest(http.MethodGet, "http://127.0.0.1:53123", nil)
+	req, err = http.NewRequest(http.MethodGet, "http://"+env.Addr("http"), nil)
 	require.NoError(t, err)
 	// typo
 	req.Header.Set("Cache-Control", "max-age=0")
@@ -751,7 +334,7 @@ func TestHTTPCacheDifferentRqs(t *testing.T) {
 
 	// -----------------
 
-	req, err = http.NewRequest(http.MethodGet, "http://127.0.0.1:53123", nil)
+	req, err = http.NewRequest(http.MethodGet, "http://"+env.Addr("http"), nil)
 	require.NoError(t, err)
 	// typo
 	req.Header.Set("Cache-Control", "max-age=10,public,foo,bar")
@@ -762,79 +345,27 @@ func TestHTTPCacheDifferentRqs(t *testing.T) {
 	require.Equal(t, 200, r.StatusCode)
 	_ = r.Body.Close()
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestHTTPBigResp(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-init-big-resp.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-init-big-resp.yaml",
 		&gzip.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 		&memory.Plugin{},
 		&httpPlugin.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
-	time.Sleep(time.Second * 5)
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
 
 	wg2 := &sync.WaitGroup{}
 	wg2.Add(2)
 	go func() {
 		defer wg2.Done()
-		req, err1 := http.NewRequest(http.MethodGet, "http://127.0.0.1:15399", nil)
+		req, err1 := http.NewRequest(http.MethodGet, "http://"+env.Addr("http"), nil)
 		require.NoError(t, err1)
 
 		r, err1 := http.DefaultClient.Do(req)
@@ -845,7 +376,7 @@ func TestHTTPBigResp(t *testing.T) {
 
 	go func() {
 		defer wg2.Done()
-		req, err2 := http.NewRequest(http.MethodGet, "http://127.0.0.1:15399", nil)
+		req, err2 := http.NewRequest(http.MethodGet, "http://"+env.Addr("http"), nil)
 		require.NoError(t, err2)
 
 		r, err2 := http.DefaultClient.Do(req)
@@ -855,8 +386,7 @@ func TestHTTPBigResp(t *testing.T) {
 	}()
 
 	wg2.Wait()
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	t.Cleanup(func() {
 		_ = os.RemoveAll("well")
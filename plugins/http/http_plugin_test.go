@@ -10,25 +10,25 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
-	"net/rpc"
 	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
 	"sync"
 	"syscall"
 	"testing"
 	"time"
 
-	"github.com/roadrunner-server/api/v2/state/process"
 	"github.com/roadrunner-server/config/v2"
 	endure "github.com/roadrunner-server/endure/pkg/container"
-	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
 	"github.com/roadrunner-server/gzip/v2"
 	httpPlugin "github.com/roadrunner-server/http/v2"
 	"github.com/roadrunner-server/informer/v2"
 	"github.com/roadrunner-server/logger/v2"
 	"github.com/roadrunner-server/resetter/v2"
 	rpcPlugin "github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	mock_logger "github.com/roadrunner-server/rr-e2e-tests/mock"
 	"github.com/roadrunner-server/send/v2"
 	"github.com/roadrunner-server/server/v2"
@@ -49,321 +49,107 @@ var sslClient = &http.Client{ //nolint:gochecknoglobals
 }
 
 func TestHTTPInit(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-http-init.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-http-init.yaml",
 		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
 	time.Sleep(time.Second * 2)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestHTTPAccessLogs(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-http-access-logs.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-http-access-logs.yaml",
 		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
-	time.Sleep(time.Second * 2)
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
 
-	t.Run("AccessLogsEcho", echoAccessLogs)
+	t.Run("AccessLogsEcho", echoAccessLogs(env.Addr("http")))
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
-func echoAccessLogs(t *testing.T) {
-	req, err := http.NewRequest("GET", "http://127.0.0.1:58332", nil)
-	assert.NoError(t, err)
+func echoAccessLogs(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		req, err := http.NewRequest("GET", "http://"+addr, nil)
+		assert.NoError(t, err)
 
-	r, err := http.DefaultClient.Do(req)
-	require.NoError(t, err)
-	require.NotNil(t, r)
-	b, err := io.ReadAll(r.Body)
-	assert.NoError(t, err)
-	assert.Equal(t, 200, r.StatusCode)
-	assert.Equal(t, "hello world", string(b))
+		r, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		b, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, 200, r.StatusCode)
+		assert.Equal(t, "hello world", string(b))
 
-	err = r.Body.Close()
-	assert.NoError(t, err)
+		err = r.Body.Close()
+		assert.NoError(t, err)
+	}
 }
 
 func TestHTTPXSendFile(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-http-sendfile.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-http-sendfile.yaml",
 		&logger.Plugin{},
 		&server.Plugin{},
 		&send.Plugin{},
 		&httpPlugin.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 2)
-	t.Run("X-Sendfile", xsendfile)
-	stopCh <- struct{}{}
-	wg.Wait()
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	t.Run("X-Sendfile", xsendfile(env.Addr("http")))
+	h.Stop()
 }
 
-func xsendfile(t *testing.T) {
-	parsedURL, _ := url.Parse("http://127.0.0.1:44444")
-	client := http.Client{}
-	pwd, _ := os.Getwd()
-	req := &http.Request{
-		Method: http.MethodGet,
-		URL:    parsedURL,
-		Header: map[string][]string{"x-sendfile": {fmt.Sprintf("%s/attributes_test.go", pwd)}},
-	}
+func xsendfile(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		parsedURL, _ := url.Parse("http://" + addr)
+		client := http.Client{}
+		pwd, _ := os.Getwd()
+		req := &http.Request{
+			Method: http.MethodGet,
+			URL:    parsedURL,
+			Header: map[string][]string{"x-sendfile": {fmt.Sprintf("%s/attributes_test.go", pwd)}},
+		}
 
-	resp, err := client.Do(req)
-	require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
 
-	b, err := ioutil.ReadAll(resp.Body)
-	assert.NoError(t, err)
-	require.True(t, len(b) > 0)
-	require.Empty(t, resp.Header.Get("X-Sendfile"))
+		b, err := ioutil.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		require.True(t, len(b) > 0)
+		require.Empty(t, resp.Header.Get("X-Sendfile"))
 
-	file, err := os.ReadFile(fmt.Sprintf("%s/attributes_test.go", pwd))
-	require.NoError(t, err)
-	require.Equal(t, file, b)
-	require.NoError(t, resp.Body.Close())
-	_, _ = io.Discard.Write(file)
+		file, err := os.ReadFile(fmt.Sprintf("%s/attributes_test.go", pwd))
+		require.NoError(t, err)
+		require.Equal(t, file, b)
+		require.NoError(t, resp.Body.Close())
+		_, _ = io.Discard.Write(file)
+	}
 }
 
 func TestHTTPNoConfigSection(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-no-http.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-no-http.yaml",
 		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
 	time.Sleep(time.Second * 2)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestHTTPInformerReset(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-resetter.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-resetter.yaml",
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
@@ -371,1798 +157,581 @@ func TestHTTPInformerReset(t *testing.T) {
 		&informer.Plugin{},
 		&resetter.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("HTTPInformerTest", informerTest(h.RPCAddr()))
+	t.Run("HTTPEchoTestBefore", echoHTTP(env.Addr("http")))
+	t.Run("HTTPResetTest", resetTest(h.RPCAddr()))
+	t.Run("HTTPEchoTestAfter", echoHTTP(env.Addr("http")))
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
+	h.Stop()
+}
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+func TestSSL(t *testing.T) {
+	env := harness.NewEnv(t)
 
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
+	h := env.Start("configs/.rr-ssl.yaml",
+		&rpcPlugin.Plugin{},
+		&logger.Plugin{},
+		&server.Plugin{},
+		&httpPlugin.Plugin{},
+	)
 
-	stopCh := make(chan struct{}, 1)
+	harness.WaitForTCP(t, env.Addr("https"), harness.DefaultWaitTimeout)
+	t.Run("SSLEcho", sslEcho(env.Addr("https")))
+	t.Run("SSLNoRedirect", sslNoRedirect(env.Addr("http")))
+	t.Run("FCGEcho", fcgiEcho(env.Addr("fcgi")))
 
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-	t.Run("HTTPInformerTest", informerTest("127.0.0.1:6008"))
-	t.Run("HTTPEchoTestBefore", echoHTTP)
-	t.Run("HTTPResetTest", resetTest("127.0.0.1:6008"))
-	t.Run("HTTPEchoTestAfter", echoHTTP)
-
-	stopCh <- struct{}{}
-
-	wg.Wait()
+	h.Stop()
 }
 
-func TestSSL(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-ssl.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
-		&rpcPlugin.Plugin{},
-		&logger.Plugin{},
-		&server.Plugin{},
-		&httpPlugin.Plugin{},
-	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
+func sslNoRedirect(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		req, err := http.NewRequest("GET", "http://"+addr+"?hello=world", nil)
+		assert.NoError(t, err)
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
+		r, err := sslClient.Do(req)
+		assert.NoError(t, err)
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+		assert.Nil(t, r.TLS)
 
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
 
-	stopCh := make(chan struct{}, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 201, r.StatusCode)
+		assert.Equal(t, "WORLD", string(b))
 
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
+		err2 := r.Body.Close()
+		if err2 != nil {
+			t.Errorf("fail to close the Body: error %v", err2)
 		}
-	}()
-
-	time.Sleep(time.Second * 1)
-	t.Run("SSLEcho", sslEcho)
-	t.Run("SSLNoRedirect", sslNoRedirect)
-	t.Run("FCGEcho", fcgiEcho)
-
-	stopCh <- struct{}{}
-	wg.Wait()
-}
-
-func sslNoRedirect(t *testing.T) {
-	req, err := http.NewRequest("GET", "http://127.0.0.1:8085?hello=world", nil)
-	assert.NoError(t, err)
-
-	r, err := sslClient.Do(req)
-	assert.NoError(t, err)
-
-	assert.Nil(t, r.TLS)
-
-	b, err := ioutil.ReadAll(r.Body)
-	assert.NoError(t, err)
-
-	assert.NoError(t, err)
-	assert.Equal(t, 201, r.StatusCode)
-	assert.Equal(t, "WORLD", string(b))
-
-	err2 := r.Body.Close()
-	if err2 != nil {
-		t.Errorf("fail to close the Body: error %v", err2)
-	}
-}
-
-func sslEcho(t *testing.T) {
-	req, err := http.NewRequest("GET", "https://127.0.0.1:8893?hello=world", nil)
-	assert.NoError(t, err)
-
-	r, err := sslClient.Do(req)
-	assert.NoError(t, err)
-
-	b, err := ioutil.ReadAll(r.Body)
-	assert.NoError(t, err)
-
-	assert.NoError(t, err)
-	assert.Equal(t, 201, r.StatusCode)
-	assert.Equal(t, "WORLD", string(b))
-
-	err2 := r.Body.Close()
-	if err2 != nil {
-		t.Errorf("fail to close the Body: error %v", err2)
 	}
 }
 
-func fcgiEcho(t *testing.T) {
-	fcgiConnFactory := gofast.SimpleConnFactory("tcp", "127.0.0.1:16920")
-
-	fcgiHandler := gofast.NewHandler(
-		gofast.BasicParamsMap(gofast.BasicSession),
-		gofast.SimpleClientFactory(fcgiConnFactory),
-	)
-
-	w := httptest.NewRecorder()
-	req := httptest.NewRequest("GET", "http://site.local/?hello=world", nil)
-	fcgiHandler.ServeHTTP(w, req)
-
-	body, err := ioutil.ReadAll(w.Result().Body) //nolint:bodyclose
-
-	defer func() {
-		_ = w.Result().Body.Close()
-		w.Body.Reset()
-	}()
-
-	assert.NoError(t, err)
-	assert.Equal(t, 201, w.Result().StatusCode) //nolint:bodyclose
-	assert.Equal(t, "WORLD", string(body))
-}
-
-func TestSSLRedirect(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-ssl-redirect.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
-		&rpcPlugin.Plugin{},
-		&logger.Plugin{},
-		&server.Plugin{},
-		&httpPlugin.Plugin{},
-	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
+func sslEcho(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		req, err := http.NewRequest("GET", "https://"+addr+"?hello=world", nil)
+		assert.NoError(t, err)
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+		r, err := sslClient.Do(req)
+		assert.NoError(t, err)
 
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
 
-	stopCh := make(chan struct{}, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 201, r.StatusCode)
+		assert.Equal(t, "WORLD", string(b))
 
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
+		err2 := r.Body.Close()
+		if err2 != nil {
+			t.Errorf("fail to close the Body: error %v", err2)
 		}
-	}()
-
-	time.Sleep(time.Second * 1)
-	t.Run("SSLRedirect", sslRedirect)
-
-	stopCh <- struct{}{}
-	wg.Wait()
-}
-
-func sslRedirect(t *testing.T) {
-	req, err := http.NewRequest("GET", "http://127.0.0.1:8087?hello=world", nil)
-	assert.NoError(t, err)
-
-	r, err := sslClient.Do(req)
-	assert.NoError(t, err)
-	assert.NotNil(t, r.TLS)
-
-	b, err := ioutil.ReadAll(r.Body)
-	assert.NoError(t, err)
-
-	assert.NoError(t, err)
-	assert.Equal(t, 201, r.StatusCode)
-	assert.Equal(t, "WORLD", string(b))
-
-	err2 := r.Body.Close()
-	if err2 != nil {
-		t.Errorf("fail to close the Body: error %v", err2)
 	}
 }
 
-func TestSSLPushPipes(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-ssl-push.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
-		&rpcPlugin.Plugin{},
-		&logger.Plugin{},
-		&server.Plugin{},
-		&httpPlugin.Plugin{},
-	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-	t.Run("SSLPush", sslPush)
+func fcgiEcho(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		fcgiConnFactory := gofast.SimpleConnFactory("tcp", addr)
 
-	stopCh <- struct{}{}
-	wg.Wait()
-}
+		fcgiHandler := gofast.NewHandler(
+			gofast.BasicParamsMap(gofast.BasicSession),
+			gofast.SimpleClientFactory(fcgiConnFactory),
+		)
 
-func sslPush(t *testing.T) {
-	req, err := http.NewRequest("GET", "https://127.0.0.1:8894?hello=world", nil)
-	assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "http://site.local/?hello=world", nil)
+		fcgiHandler.ServeHTTP(w, req)
 
-	r, err := sslClient.Do(req)
-	assert.NoError(t, err)
+		body, err := ioutil.ReadAll(w.Result().Body) //nolint:bodyclose
 
-	assert.NotNil(t, r.TLS)
+		defer func() {
+			_ = w.Result().Body.Close()
+			w.Body.Reset()
+		}()
 
-	b, err := ioutil.ReadAll(r.Body)
-	assert.NoError(t, err)
-
-	assert.Equal(t, "", r.Header.Get("Http2-Release"))
-
-	assert.NoError(t, err)
-	assert.Equal(t, 201, r.StatusCode)
-	assert.Equal(t, "WORLD", string(b))
-
-	err2 := r.Body.Close()
-	if err2 != nil {
-		t.Errorf("fail to close the Body: error %v", err2)
-	}
-}
-
-func TestFastCGI_Echo(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-fcgi.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
-		&logger.Plugin{},
-		&server.Plugin{},
-		&httpPlugin.Plugin{},
-		&static.Plugin{},
-	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-	t.Run("FastCGIEcho", fcgiEcho1)
-
-	stopCh <- struct{}{}
-	wg.Wait()
-}
-
-func fcgiEcho1(t *testing.T) {
-	time.Sleep(time.Second * 2)
-	fcgiConnFactory := gofast.SimpleConnFactory("tcp", "127.0.0.1:6920")
-
-	fcgiHandler := gofast.NewHandler(
-		gofast.BasicParamsMap(gofast.BasicSession),
-		gofast.SimpleClientFactory(fcgiConnFactory),
-	)
-
-	w := httptest.NewRecorder()
-	req := httptest.NewRequest("GET", "http://site.local/hello-world", nil)
-	fcgiHandler.ServeHTTP(w, req)
-
-	_, err := ioutil.ReadAll(w.Result().Body) //nolint:bodyclose
-	assert.NoError(t, err)
-	assert.Equal(t, 200, w.Result().StatusCode) //nolint:bodyclose
-}
-
-func TestFastCGI_EchoUnix(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-fcgi-unix.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
-		&logger.Plugin{},
-		&server.Plugin{},
-		&httpPlugin.Plugin{},
-		&static.Plugin{},
-	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-	t.Run("FastCGIEcho", fcgiEchoUnix)
-
-	stopCh <- struct{}{}
-	wg.Wait()
-
-	t.Cleanup(func() {
-		_ = os.RemoveAll("rr.sock")
-	})
-}
-
-func fcgiEchoUnix(t *testing.T) {
-	time.Sleep(time.Second * 2)
-	fcgiConnFactory := gofast.SimpleConnFactory("unix", "rr.sock")
-
-	fcgiHandler := gofast.NewHandler(
-		gofast.BasicParamsMap(gofast.BasicSession),
-		gofast.SimpleClientFactory(fcgiConnFactory),
-	)
-
-	w := httptest.NewRecorder()
-	req := httptest.NewRequest("GET", "http://site.local/hello-world", nil)
-	fcgiHandler.ServeHTTP(w, req)
-
-	_, err := ioutil.ReadAll(w.Result().Body) //nolint:bodyclose
-	assert.NoError(t, err)
-	assert.Equal(t, 200, w.Result().StatusCode) //nolint:bodyclose
-}
-
-func TestFastCGI_RequestUri(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-fcgi-reqUri.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
-		&logger.Plugin{},
-		&server.Plugin{},
-		&httpPlugin.Plugin{},
-	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-	t.Run("FastCGIServiceRequestUri", fcgiReqURI)
-
-	stopCh <- struct{}{}
-	wg.Wait()
-}
-
-func fcgiReqURI(t *testing.T) {
-	time.Sleep(time.Second * 2)
-	fcgiConnFactory := gofast.SimpleConnFactory("tcp", "127.0.0.1:6921")
-
-	fcgiHandler := gofast.NewHandler(
-		gofast.BasicParamsMap(gofast.BasicSession),
-		gofast.SimpleClientFactory(fcgiConnFactory),
-	)
-
-	w := httptest.NewRecorder()
-	req := httptest.NewRequest("GET", "http://site.local/hello-world", nil)
-	fcgiHandler.ServeHTTP(w, req)
-
-	body, err := ioutil.ReadAll(w.Result().Body) //nolint:bodyclose
-	assert.NoError(t, err)
-	assert.Equal(t, 200, w.Result().StatusCode) //nolint:bodyclose
-	assert.Contains(t, string(body), "ddddd")
-}
-
-func TestHTTP2Req(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel), endure.GracefulShutdownTimeout(time.Second*5))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-h2-ssl.yaml",
-		Prefix: "rr",
-	}
-
-	l, oLogger := mock_logger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
-		&rpcPlugin.Plugin{},
-		l,
-		&server.Plugin{},
-		&httpPlugin.Plugin{},
-	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-
-	tr := &http2.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec
-	}
-	client := &http.Client{
-		Transport:     tr,
-		CheckRedirect: nil,
-		Jar:           nil,
-		Timeout:       0,
-	}
-
-	req, err := http.NewRequest(http.MethodGet, "https://127.0.0.1:23452?hello=world", nil)
-	require.NoError(t, err)
-
-	r, err := client.Do(req)
-	require.NoError(t, err)
-
-	assert.Equal(t, r.StatusCode, http.StatusCreated)
-	data, err := io.ReadAll(r.Body)
-	require.NoError(t, err)
-	require.Equal(t, data, []byte("WORLD"))
-	require.NoError(t, r.Body.Close())
-
-	stopCh <- struct{}{}
-	wg.Wait()
-
-	require.Equal(t, 1, oLogger.FilterMessageSnippet("http server was started").Len())
-	require.Equal(t, 1, oLogger.FilterMessageSnippet("http log").Len())
-}
-
-func TestH2CUpgrade(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel), endure.GracefulShutdownTimeout(time.Second*5))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-h2c.yaml",
-		Prefix: "rr",
-	}
-
-	l, oLogger := mock_logger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
-		&rpcPlugin.Plugin{},
-		l,
-		&server.Plugin{},
-		&httpPlugin.Plugin{},
-	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-
-	req, err := http.NewRequest("PRI", "http://127.0.0.1:8083?hello=world", nil)
-	require.NoError(t, err)
-
-	req.Header.Add("Upgrade", "h2c")
-	req.Header.Add("Connection", "HTTP2-Settings")
-	req.Header.Add("Connection", "Upgrade")
-	req.Header.Add("HTTP2-Settings", "AAMAAABkAARAAAAAAAIAAAAA")
-
-	r, err := http.DefaultClient.Do(req)
-	require.NoError(t, err)
-
-	assert.Equal(t, "101 Switching Protocols", r.Status)
-	require.NoError(t, r.Body.Close())
-
-	stopCh <- struct{}{}
-	wg.Wait()
-
-	require.Equal(t, 1, oLogger.FilterMessageSnippet("http server was started").Len())
-	require.Equal(t, 1, oLogger.FilterMessageSnippet("hijacked").Len())
-}
-
-func TestH2C(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-h2c.yaml",
-		Prefix: "rr",
-	}
-
-	l, oLogger := mock_logger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
-		&rpcPlugin.Plugin{},
-		l,
-		&server.Plugin{},
-		&httpPlugin.Plugin{},
-	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 2)
-
-	tr := &http2.Transport{
-		AllowHTTP: true,
-		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
-			// use the http dial (w/o tls)
-			return net.Dial(network, addr)
-		},
-	}
-	client := &http.Client{
-		Transport: tr,
-	}
-
-	req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:8083?hello=world", nil)
-	require.NoError(t, err)
-
-	r, err := client.Do(req)
-	require.NoError(t, err)
-
-	assert.Equal(t, "201 Created", r.Status)
-	data, err := io.ReadAll(r.Body)
-	require.NoError(t, err)
-
-	require.Equal(t, []byte("WORLD"), data)
-
-	require.NoError(t, r.Body.Close())
-
-	stopCh <- struct{}{}
-	wg.Wait()
-
-	require.Equal(t, 1, oLogger.FilterMessageSnippet("http server was started").Len())
-	require.Equal(t, 1, oLogger.FilterMessageSnippet("http log").Len())
-}
-
-func TestHttpMiddleware(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-http.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
-		&rpcPlugin.Plugin{},
-		&logger.Plugin{},
-		&server.Plugin{},
-		&httpPlugin.Plugin{},
-		&PluginMiddleware{},
-		&PluginMiddleware2{},
-	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-	t.Run("MiddlewareTest", middleware)
-
-	stopCh <- struct{}{}
-	wg.Wait()
-}
-
-func middleware(t *testing.T) {
-	req, err := http.NewRequest("GET", "http://127.0.0.1:18903?hello=world", nil)
-	assert.NoError(t, err)
-
-	r, err := http.DefaultClient.Do(req)
-	assert.NoError(t, err)
-
-	b, err := ioutil.ReadAll(r.Body)
-	assert.NoError(t, err)
-
-	assert.Equal(t, 201, r.StatusCode)
-	assert.Equal(t, "WORLD", string(b))
-
-	err = r.Body.Close()
-	assert.NoError(t, err)
-
-	req, err = http.NewRequest("GET", "http://127.0.0.1:18903/halt", nil)
-	assert.NoError(t, err)
-
-	r, err = http.DefaultClient.Do(req)
-	assert.NoError(t, err)
-	b, err = ioutil.ReadAll(r.Body)
-	assert.NoError(t, err)
-
-	assert.Equal(t, 500, r.StatusCode)
-	assert.Equal(t, "halted", string(b))
-
-	err = r.Body.Close()
-	assert.NoError(t, err)
-}
-
-func TestHttpEchoErr(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	rIn := `
-rpc:
-  listen: tcp://127.0.0.1:6003
-  disabled: false
-
-server:
-  command: "php ../../php_test_files/http/client.php echoerr pipes"
-  relay: "pipes"
-  relay_timeout: "20s"
-
-http:
-  debug: true
-  address: 127.0.0.1:34999
-  max_request_size: 1024
-  middleware: [ "pluginMiddleware", "pluginMiddleware2" ]
-  uploads:
-    forbid: [ "" ]
-  trusted_subnets: [ "10.0.0.0/8", "127.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "::1/128", "fc00::/7", "fe80::/10" ]
-  pool:
-    num_workers: 2
-    max_jobs: 0
-    allocate_timeout: 60s
-    destroy_timeout: 60s
-
-logs:
-  mode: development
-  level: debug
-`
-
-	cfg := &config.Plugin{
-		Type:      "yaml",
-		ReadInCfg: []byte(rIn),
-	}
-
-	err = cont.RegisterAll(
-		cfg,
-		&logger.Plugin{},
-		&server.Plugin{},
-		&httpPlugin.Plugin{},
-		&PluginMiddleware{},
-		&PluginMiddleware2{},
-	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
-
-	t.Run("HttpEchoError", echoError)
-
-	stopCh <- struct{}{}
-	wg.Wait()
-}
-
-func echoError(t *testing.T) {
-	req, err := http.NewRequest("GET", "http://127.0.0.1:34999?hello=world", nil)
-	assert.NoError(t, err)
-
-	r, err := http.DefaultClient.Do(req)
-	assert.NoError(t, err)
-	require.NotNil(t, r)
-
-	b, err := ioutil.ReadAll(r.Body)
-	assert.NoError(t, err)
-
-	assert.Equal(t, 201, r.StatusCode)
-	assert.Equal(t, "WORLD", string(b))
-	err = r.Body.Close()
-	assert.NoError(t, err)
-}
-
-func TestHttpEnvVariables(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-env.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
-		&logger.Plugin{},
-		&server.Plugin{},
-		&httpPlugin.Plugin{},
-		&PluginMiddleware{},
-		&PluginMiddleware2{},
-	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
+		assert.NoError(t, err)
+		assert.Equal(t, 201, w.Result().StatusCode) //nolint:bodyclose
+		assert.Equal(t, "WORLD", string(body))
 	}
+}
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-	t.Run("EnvVariablesTest", envVarsTest)
+func TestSSLRedirect(t *testing.T) {
+	env := harness.NewEnv(t)
 
-	stopCh <- struct{}{}
-	wg.Wait()
-}
+	h := env.Start("configs/.rr-ssl-redirect.yaml",
+		&rpcPlugin.Plugin{},
+		&logger.Plugin{},
+		&server.Plugin{},
+		&httpPlugin.Plugin{},
+	)
 
-func envVarsTest(t *testing.T) {
-	req, err := http.NewRequest("GET", "http://127.0.0.1:12084", nil)
-	assert.NoError(t, err)
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	t.Run("SSLRedirect", sslRedirect(env.Addr("http")))
 
-	r, err := http.DefaultClient.Do(req)
-	assert.NoError(t, err)
+	h.Stop()
+}
 
-	b, err := ioutil.ReadAll(r.Body)
-	assert.NoError(t, err)
+func sslRedirect(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		req, err := http.NewRequest("GET", "http://"+addr+"?hello=world", nil)
+		assert.NoError(t, err)
 
-	assert.Equal(t, 200, r.StatusCode)
-	assert.Equal(t, "ENV_VALUE", string(b))
+		r, err := sslClient.Do(req)
+		assert.NoError(t, err)
+		assert.NotNil(t, r.TLS)
 
-	err = r.Body.Close()
-	assert.NoError(t, err)
-}
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
 
-func TestHttpBrokenPipes(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+		assert.NoError(t, err)
+		assert.Equal(t, 201, r.StatusCode)
+		assert.Equal(t, "WORLD", string(b))
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-broken-pipes.yaml",
-		Prefix: "rr",
-		Type:   "yaml",
+		err2 := r.Body.Close()
+		if err2 != nil {
+			t.Errorf("fail to close the Body: error %v", err2)
+		}
 	}
+}
 
-	err = cont.RegisterAll(
-		cfg,
+func TestSSLPushPipes(t *testing.T) {
+	env := harness.NewEnv(t)
+
+	h := env.Start("configs/.rr-ssl-push.yaml",
+		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
-		&PluginMiddleware{},
-		&PluginMiddleware2{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	assert.NoError(t, err)
+	harness.WaitForTCP(t, env.Addr("https"), harness.DefaultWaitTimeout)
+	t.Run("SSLPush", sslPush(env.Addr("https")))
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
+	h.Stop()
+}
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+func sslPush(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		req, err := http.NewRequest("GET", "https://"+addr+"?hello=world", nil)
+		assert.NoError(t, err)
 
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
+		r, err := sslClient.Do(req)
+		assert.NoError(t, err)
 
-	stopCh := make(chan struct{}, 1)
+		assert.NotNil(t, r.TLS)
 
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			// should be error from the plugin
-			case e := <-ch:
-				assert.Error(t, e.Error)
-				return
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
 
-	wg.Wait()
-}
+		assert.Equal(t, "", r.Header.Get("Http2-Release"))
 
-func TestHTTPSupervisedPool(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+		assert.NoError(t, err)
+		assert.Equal(t, 201, r.StatusCode)
+		assert.Equal(t, "WORLD", string(b))
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-http-supervised-pool.yaml",
-		Prefix: "rr",
+		err2 := r.Body.Close()
+		if err2 != nil {
+			t.Errorf("fail to close the Body: error %v", err2)
+		}
 	}
+}
 
-	err = cont.RegisterAll(
-		cfg,
-		&rpcPlugin.Plugin{},
+func TestFastCGI_Echo(t *testing.T) {
+	env := harness.NewEnv(t)
+
+	h := env.Start("configs/.rr-fcgi.yaml",
 		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
-		&informer.Plugin{},
+		&static.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
+	harness.WaitForTCP(t, env.Addr("fcgi"), harness.DefaultWaitTimeout)
+	t.Run("FastCGIEcho", fcgiEcho1(env.Addr("fcgi")))
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
+	h.Stop()
+}
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+func fcgiEcho1(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		time.Sleep(time.Second * 2)
+		fcgiConnFactory := gofast.SimpleConnFactory("tcp", addr)
 
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
+		fcgiHandler := gofast.NewHandler(
+			gofast.BasicParamsMap(gofast.BasicSession),
+			gofast.SimpleClientFactory(fcgiConnFactory),
+		)
 
-	stopCh := make(chan struct{}, 1)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "http://site.local/hello-world", nil)
+		fcgiHandler.ServeHTTP(w, req)
 
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
+		_, err := ioutil.ReadAll(w.Result().Body) //nolint:bodyclose
+		assert.NoError(t, err)
+		assert.Equal(t, 200, w.Result().StatusCode) //nolint:bodyclose
+	}
+}
+
+func TestFastCGI_EchoUnix(t *testing.T) {
+	env := harness.NewEnv(t)
+
+	h := env.Start("configs/.rr-fcgi-unix.yaml",
+		&logger.Plugin{},
+		&server.Plugin{},
+		&httpPlugin.Plugin{},
+		&static.Plugin{},
+	)
 
 	time.Sleep(time.Second * 1)
-	t.Run("HTTPEchoRunActivateWorker", echoHTTP2)
-	// bigger timeout to handle idle_ttl on slow systems
-	time.Sleep(time.Second * 10)
-	t.Run("HTTPInformerCompareWorkersTestBefore", informerTestBefore)
-	t.Run("HTTPEchoShouldBeNewWorker", echoHTTP2)
-	// worker should be destructed (idle_ttl)
-	t.Run("HTTPInformerCompareWorkersTestAfter", informerTestAfter)
+	t.Run("FastCGIEcho", fcgiEchoUnix(filepath.Join(env.TempDir(), "rr.sock")))
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
-func echoHTTP2(t *testing.T) {
-	req, err := http.NewRequest("GET", "http://127.0.0.1:18888?hello=world", nil)
-	assert.NoError(t, err)
+func fcgiEchoUnix(sock string) func(t *testing.T) {
+	return func(t *testing.T) {
+		time.Sleep(time.Second * 2)
+		fcgiConnFactory := gofast.SimpleConnFactory("unix", sock)
 
-	r, err := http.DefaultClient.Do(req)
-	assert.NoError(t, err)
-	b, err := ioutil.ReadAll(r.Body)
-	assert.NoError(t, err)
-	assert.Equal(t, 201, r.StatusCode)
-	assert.Equal(t, "WORLD", string(b))
+		fcgiHandler := gofast.NewHandler(
+			gofast.BasicParamsMap(gofast.BasicSession),
+			gofast.SimpleClientFactory(fcgiConnFactory),
+		)
 
-	err = r.Body.Close()
-	assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "http://site.local/hello-world", nil)
+		fcgiHandler.ServeHTTP(w, req)
+
+		_, err := ioutil.ReadAll(w.Result().Body) //nolint:bodyclose
+		assert.NoError(t, err)
+		assert.Equal(t, 200, w.Result().StatusCode) //nolint:bodyclose
+	}
 }
 
-// get worker
-// sleep
-// supervisor destroy worker
-// compare pid's
-var workerPid int = 0 //nolint:gochecknoglobals
+func TestFastCGI_RequestUri(t *testing.T) {
+	env := harness.NewEnv(t)
 
-func informerTestBefore(t *testing.T) {
-	conn, err := net.Dial("tcp", "127.0.0.1:15432")
-	assert.NoError(t, err)
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-	// WorkerList contains list of workers.
-	list := struct {
-		// Workers is list of workers.
-		Workers []process.State `json:"workers"`
-	}{}
-
-	err = client.Call("informer.Workers", "http", &list)
-	assert.NoError(t, err)
-	assert.Len(t, list.Workers, 1)
-	// save the pid
-	workerPid = list.Workers[0].Pid
+	h := env.Start("configs/.rr-fcgi-reqUri.yaml",
+		&logger.Plugin{},
+		&server.Plugin{},
+		&httpPlugin.Plugin{},
+	)
+
+	harness.WaitForTCP(t, env.Addr("fcgi"), harness.DefaultWaitTimeout)
+	t.Run("FastCGIServiceRequestUri", fcgiReqURI(env.Addr("fcgi")))
+
+	h.Stop()
 }
 
-func informerTestAfter(t *testing.T) {
-	conn, err := net.Dial("tcp", "127.0.0.1:15432")
-	assert.NoError(t, err)
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-	// WorkerList contains list of workers.
-	list := struct {
-		// Workers is list of workers.
-		Workers []process.State `json:"workers"`
-	}{}
+func fcgiReqURI(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		time.Sleep(time.Second * 2)
+		fcgiConnFactory := gofast.SimpleConnFactory("tcp", addr)
 
-	assert.NotZero(t, workerPid)
+		fcgiHandler := gofast.NewHandler(
+			gofast.BasicParamsMap(gofast.BasicSession),
+			gofast.SimpleClientFactory(fcgiConnFactory),
+		)
 
-	time.Sleep(time.Second * 5)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "http://site.local/hello-world", nil)
+		fcgiHandler.ServeHTTP(w, req)
 
-	err = client.Call("informer.Workers", "http", &list)
-	assert.NoError(t, err)
-	assert.Len(t, list.Workers, 1)
-	assert.NotEqual(t, workerPid, list.Workers[0].Pid)
+		body, err := ioutil.ReadAll(w.Result().Body) //nolint:bodyclose
+		assert.NoError(t, err)
+		assert.Equal(t, 200, w.Result().StatusCode) //nolint:bodyclose
+		assert.Contains(t, string(body), "ddddd")
+	}
 }
 
-// get request and return body
-func getHeader(url string, h map[string]string) (string, *http.Response, error) {
-	req, err := http.NewRequest("GET", url, bytes.NewBuffer(nil))
-	if err != nil {
-		return "", nil, err
-	}
+func TestHTTP2Req(t *testing.T) {
+	env := harness.NewEnv(t)
 
-	for k, v := range h {
-		req.Header.Set(k, v)
-	}
+	l, oLogger := mock_logger.ZapTestLogger(zap.DebugLevel)
+	h := env.Options(endure.GracefulShutdownTimeout(time.Second*5)).Start("configs/.rr-h2-ssl.yaml",
+		&rpcPlugin.Plugin{},
+		l,
+		&server.Plugin{},
+		&httpPlugin.Plugin{},
+	)
 
-	r, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", nil, err
-	}
+	harness.WaitForTCP(t, env.Addr("https"), harness.DefaultWaitTimeout)
 
-	b, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		return "", nil, err
+	tr := &http2.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec
 	}
-
-	err = r.Body.Close()
-	if err != nil {
-		return "", nil, err
+	client := &http.Client{
+		Transport:     tr,
+		CheckRedirect: nil,
+		Jar:           nil,
+		Timeout:       0,
 	}
-	return string(b), r, err
-}
 
-func TestHTTPBigRequestSize(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, "https://"+env.Addr("https")+"?hello=world", nil)
+	require.NoError(t, err)
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-big-req-size.yaml",
-		Prefix: "rr",
-		Type:   "yaml",
-	}
+	r, err := client.Do(req)
+	require.NoError(t, err)
 
-	err = cont.RegisterAll(
-		cfg,
-		&logger.Plugin{},
+	assert.Equal(t, r.StatusCode, http.StatusCreated)
+	data, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	require.Equal(t, data, []byte("WORLD"))
+	require.NoError(t, r.Body.Close())
+
+	h.Stop()
+
+	require.Equal(t, 1, oLogger.FilterMessageSnippet("http server was started").Len())
+	require.Equal(t, 1, oLogger.FilterMessageSnippet("http log").Len())
+}
+
+func TestH2CUpgrade(t *testing.T) {
+	env := harness.NewEnv(t)
+
+	l, oLogger := mock_logger.ZapTestLogger(zap.DebugLevel)
+	h := env.Options(endure.GracefulShutdownTimeout(time.Second*5)).Start("configs/.rr-h2c.yaml",
+		&rpcPlugin.Plugin{},
+		l,
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+
+	req, err := http.NewRequest("PRI", "http://"+env.Addr("http")+"?hello=world", nil)
+	require.NoError(t, err)
+
+	req.Header.Add("Upgrade", "h2c")
+	req.Header.Add("Connection", "HTTP2-Settings")
+	req.Header.Add("Connection", "Upgrade")
+	req.Header.Add("HTTP2-Settings", "AAMAAABkAARAAAAAAAIAAAAA")
+
+	r, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
+	assert.Equal(t, "101 Switching Protocols", r.Status)
+	require.NoError(t, r.Body.Close())
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	h.Stop()
 
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
+	require.Equal(t, 1, oLogger.FilterMessageSnippet("http server was started").Len())
+	require.Equal(t, 1, oLogger.FilterMessageSnippet("hijacked").Len())
+}
 
-	stopCh := make(chan struct{}, 1)
+func TestH2C(t *testing.T) {
+	env := harness.NewEnv(t)
 
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
+	l, oLogger := mock_logger.ZapTestLogger(zap.DebugLevel)
+	h := env.Start("configs/.rr-h2c.yaml",
+		&rpcPlugin.Plugin{},
+		l,
+		&server.Plugin{},
+		&httpPlugin.Plugin{},
+	)
 
-	time.Sleep(time.Second * 2)
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
 
-	t.Run("HTTPBigEcho10Mb", bigEchoHTTP)
+	tr := &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			// use the http dial (w/o tls)
+			return net.Dial(network, addr)
+		},
+	}
+	client := &http.Client{
+		Transport: tr,
+	}
 
-	stopCh <- struct{}{}
-	wg.Wait()
-}
+	req, err := http.NewRequest(http.MethodGet, "http://"+env.Addr("http")+"?hello=world", nil)
+	require.NoError(t, err)
 
-func bigEchoHTTP(t *testing.T) {
-	buf := make([]byte, 1024*1024*10)
+	r, err := client.Do(req)
+	require.NoError(t, err)
 
-	_, err := rand.Read(buf)
-	assert.NoError(t, err)
+	assert.Equal(t, "201 Created", r.Status)
+	data, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
 
-	bt := bytes.NewBuffer(buf)
+	require.Equal(t, []byte("WORLD"), data)
 
-	req, err := http.NewRequest("GET", "http://127.0.0.1:10085?hello=world", bt)
-	assert.NoError(t, err)
+	require.NoError(t, r.Body.Close())
 
-	r, err := http.DefaultClient.Do(req)
-	assert.NoError(t, err)
-	b, err := ioutil.ReadAll(r.Body)
-	assert.NoError(t, err)
-	assert.Equal(t, 400, r.StatusCode)
-	assert.Equal(t, "http_handler_max_size: request body max size is exceeded\n", string(b))
+	h.Stop()
 
-	err = r.Body.Close()
-	assert.NoError(t, err)
+	require.Equal(t, 1, oLogger.FilterMessageSnippet("http server was started").Len())
+	require.Equal(t, 1, oLogger.FilterMessageSnippet("http log").Len())
 }
 
-func TestStaticEtagPlugin(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-http-static.yaml",
-		Prefix: "rr",
-	}
+func TestHttpMiddleware(t *testing.T) {
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-http.yaml",
+		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
-		&gzip.Plugin{},
-		&static.Plugin{},
+		&PluginMiddleware{},
+		&PluginMiddleware2{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	t.Run("MiddlewareTest", middleware(env.Addr("http")))
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
+	h.Stop()
+}
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+func middleware(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		req, err := http.NewRequest("GET", "http://"+addr+"?hello=world", nil)
+		assert.NoError(t, err)
 
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
+		r, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
 
-	stopCh := make(chan struct{}, 1)
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
 
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
+		assert.Equal(t, 201, r.StatusCode)
+		assert.Equal(t, "WORLD", string(b))
 
-	time.Sleep(time.Second)
-	t.Run("ServeSampleEtag", serveStaticSampleEtag)
-	t.Run("NoStaticHeaders", noStaticHeaders)
+		err = r.Body.Close()
+		assert.NoError(t, err)
 
-	stopCh <- struct{}{}
-	wg.Wait()
-}
+		req, err = http.NewRequest("GET", "http://"+addr+"/halt", nil)
+		assert.NoError(t, err)
 
-func serveStaticSampleEtag(t *testing.T) {
-	// OK 200 response
-	b, r, err := get("http://127.0.0.1:21603/sample.txt")
-	assert.NoError(t, err)
-	assert.Contains(t, b, "sample")
-	assert.Equal(t, r.StatusCode, http.StatusOK)
-	etag := r.Header.Get("Etag")
+		r, err = http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		b, err = ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
 
-	_ = r.Body.Close()
+		assert.Equal(t, 500, r.StatusCode)
+		assert.Equal(t, "halted", string(b))
 
-	// Should be 304 response with same etag
-	c := http.Client{
-		Timeout: time.Second * 5,
+		err = r.Body.Close()
+		assert.NoError(t, err)
 	}
+}
 
-	parsedURL, _ := url.Parse("http://127.0.0.1:21603/sample.txt")
-
-	req := &http.Request{
-		Method: http.MethodGet,
-		URL:    parsedURL,
-		Header: map[string][]string{"If-None-Match": {etag}},
-	}
+func TestHttpEchoErr(t *testing.T) {
+	env := harness.NewEnv(t)
 
-	resp, err := c.Do(req)
-	assert.Nil(t, err)
-	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
-	_ = resp.Body.Close()
-}
+	rIn := fmt.Sprintf(`
+rpc:
+  listen: tcp://%s
+  disabled: false
 
-// regular request should not contain static headers
-func noStaticHeaders(t *testing.T) {
-	// OK 200 response
-	_, r, err := get("http://127.0.0.1:21603")
-	assert.NoError(t, err)
-	assert.NotContains(t, r.Header["input"], "custom-header")  //nolint:staticcheck
-	assert.NotContains(t, r.Header["output"], "output-header") //nolint:staticcheck
-	assert.Equal(t, r.StatusCode, http.StatusOK)
+server:
+  command: "php ../../php_test_files/http/client.php echoerr pipes"
+  relay: "pipes"
+  relay_timeout: "20s"
 
-	_ = r.Body.Close()
-}
+http:
+  debug: true
+  address: %s
+  max_request_size: 1024
+  middleware: [ "pluginMiddleware", "pluginMiddleware2" ]
+  uploads:
+    forbid: [ "" ]
+  trusted_subnets: [ "10.0.0.0/8", "127.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "::1/128", "fc00::/7", "fe80::/10" ]
+  pool:
+    num_workers: 2
+    max_jobs: 0
+    allocate_timeout: 60s
+    destroy_timeout: 60s
 
-func TestStaticPluginSecurity(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+logs:
+  mode: development
+  level: debug
+`, env.RPCAddr(), env.Addr("http"))
 
 	cfg := &config.Plugin{
-		Path:   "configs/.rr-http-static-security.yaml",
-		Prefix: "rr",
+		Type:      "yaml",
+		ReadInCfg: []byte(rIn),
 	}
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.StartConfig(cfg,
 		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
-		&gzip.Plugin{},
-		&static.Plugin{},
+		&PluginMiddleware{},
+		&PluginMiddleware2{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
 
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
 
-	time.Sleep(time.Second)
-	t.Run("ServeSampleNotAllowedPath", serveStaticSampleNotAllowedPath)
+	t.Run("HttpEchoError", echoError(env.Addr("http")))
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
-func serveStaticSampleNotAllowedPath(t *testing.T) {
-	// Should be 304 response with same etag
-	c := http.Client{
-		Timeout: time.Second * 5,
-	}
-
-	parsedURL := &url.URL{
-		Scheme: "http",
-		User:   nil,
-		Host:   "127.0.0.1:21603",
-		Path:   "%2e%2e%/tests/",
-	}
+func echoError(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		req, err := http.NewRequest("GET", "http://"+addr+"?hello=world", nil)
+		assert.NoError(t, err)
 
-	req := &http.Request{
-		Method: http.MethodGet,
-		URL:    parsedURL,
-	}
+		r, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		require.NotNil(t, r)
 
-	resp, err := c.Do(req)
-	assert.Nil(t, err)
-	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
-	_ = resp.Body.Close()
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
 
-	parsedURL = &url.URL{
-		Scheme: "http",
-		User:   nil,
-		Host:   "127.0.0.1:21603",
-		Path:   "%2e%2e%5ctests/",
+		assert.Equal(t, 201, r.StatusCode)
+		assert.Equal(t, "WORLD", string(b))
+		err = r.Body.Close()
+		assert.NoError(t, err)
 	}
+}
 
-	req = &http.Request{
-		Method: http.MethodGet,
-		URL:    parsedURL,
-	}
+func TestHttpEnvVariables(t *testing.T) {
+	env := harness.NewEnv(t)
 
-	resp, err = c.Do(req)
-	assert.Nil(t, err)
-	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
-	_ = resp.Body.Close()
+	h := env.Start("configs/.rr-env.yaml",
+		&logger.Plugin{},
+		&server.Plugin{},
+		&httpPlugin.Plugin{},
+		&PluginMiddleware{},
+		&PluginMiddleware2{},
+	)
 
-	parsedURL = &url.URL{
-		Scheme: "http",
-		User:   nil,
-		Host:   "127.0.0.1:21603",
-		Path:   "..%2ftests/",
-	}
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	t.Run("EnvVariablesTest", envVarsTest(env.Addr("http")))
 
-	req = &http.Request{
-		Method: http.MethodGet,
-		URL:    parsedURL,
-	}
+	h.Stop()
+}
 
-	resp, err = c.Do(req)
-	assert.Nil(t, err)
-	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
-	_ = resp.Body.Close()
+func envVarsTest(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		req, err := http.NewRequest("GET", "http://"+addr, nil)
+		assert.NoError(t, err)
 
-	parsedURL = &url.URL{
-		Scheme: "http",
-		User:   nil,
-		Host:   "127.0.0.1:21603",
-		Path:   "%2e%2e%2ftests/",
-	}
+		r, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
 
-	req = &http.Request{
-		Method: http.MethodGet,
-		URL:    parsedURL,
-	}
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
 
-	resp, err = c.Do(req)
-	assert.Nil(t, err)
-	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
-	_ = resp.Body.Close()
+		assert.Equal(t, 200, r.StatusCode)
+		assert.Equal(t, "ENV_VALUE", string(b))
 
-	_, r, err := get("http://127.0.0.1:21603/../../sample.txt")
-	assert.NoError(t, err)
-	assert.Equal(t, 403, r.StatusCode)
-	_ = r.Body.Close()
+		err = r.Body.Close()
+		assert.NoError(t, err)
+	}
 }
 
-func TestStaticPlugin(t *testing.T) {
+func TestHttpBrokenPipes(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
 	assert.NoError(t, err)
 
 	cfg := &config.Plugin{
-		Path:   "configs/.rr-http-static.yaml",
+		Path:   env.Render("configs/.rr-broken-pipes.yaml"),
 		Prefix: "rr",
+		Type:   "yaml",
 	}
 
 	err = cont.RegisterAll(
@@ -2170,15 +739,13 @@ func TestStaticPlugin(t *testing.T) {
 		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
-		&gzip.Plugin{},
-		&static.Plugin{},
+		&PluginMiddleware{},
+		&PluginMiddleware2{},
 	)
 	assert.NoError(t, err)
 
 	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
+	assert.NoError(t, err)
 
 	ch, err := cont.Serve()
 	assert.NoError(t, err)
@@ -2195,12 +762,10 @@ func TestStaticPlugin(t *testing.T) {
 		defer wg.Done()
 		for {
 			select {
+			// should be error from the plugin
 			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
+				assert.Error(t, e.Error)
+				return
 			case <-sig:
 				err = cont.Stop()
 				if err != nil {
@@ -2218,540 +783,621 @@ func TestStaticPlugin(t *testing.T) {
 		}
 	}()
 
-	time.Sleep(time.Second)
-	t.Run("ServeSample", serveStaticSample)
-	t.Run("StaticNotForbid", staticNotForbid)
-	t.Run("StaticHeaders", staticHeaders)
-
-	stopCh <- struct{}{}
 	wg.Wait()
 }
 
-func staticHeaders(t *testing.T) {
-	req, err := http.NewRequest("GET", "http://127.0.0.1:21603/php_test_files/client.php", nil)
-	if err != nil {
-		t.Fatal(err)
+func TestHTTPSupervisedPool(t *testing.T) {
+	env := harness.NewEnv(t)
+
+	h := env.Start("configs/.rr-http-supervised-pool.yaml",
+		&rpcPlugin.Plugin{},
+		&logger.Plugin{},
+		&server.Plugin{},
+		&httpPlugin.Plugin{},
+		&informer.Plugin{},
+	)
+
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	t.Run("HTTPEchoRunActivateWorker", echoHTTP2(env.Addr("http")))
+	// bigger timeout to handle idle_ttl on slow systems
+	time.Sleep(time.Second * 10)
+	t.Run("HTTPInformerCompareWorkersTestBefore", informerTestBefore(h.RPCAddr()))
+	t.Run("HTTPEchoShouldBeNewWorker", echoHTTP2(env.Addr("http")))
+	// worker should be destructed (idle_ttl)
+	t.Run("HTTPInformerCompareWorkersTestAfter", informerTestAfter(h.RPCAddr()))
+
+	h.Stop()
+}
+
+func echoHTTP2(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		req, err := http.NewRequest("GET", "http://"+addr+"?hello=world", nil)
+		assert.NoError(t, err)
+
+		r, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, 201, r.StatusCode)
+		assert.Equal(t, "WORLD", string(b))
+
+		err = r.Body.Close()
+		assert.NoError(t, err)
 	}
+}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		t.Fatal(err)
+// get worker
+// sleep
+// supervisor destroy worker
+// compare pid's
+var workerPid int = 0 //nolint:gochecknoglobals
+
+func informerTestBefore(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		workers, err := harness.Dial(t, addr).Informer.Workers("http")
+		assert.NoError(t, err)
+		assert.Len(t, workers, 1)
+		// save the pid
+		workerPid = workers[0].Pid
 	}
+}
+
+func informerTestAfter(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
+
+		assert.NotZero(t, workerPid)
 
-	if resp.Header.Get("Output") != "output-header" {
-		t.Fatal("can't find output header in response")
+		harness.Eventually(t, time.Second*10, "the destroyed worker is replaced", func() (bool, error) {
+			workers, err := client.Informer.Workers("http")
+			if err != nil {
+				return false, err
+			}
+
+			return len(workers) == 1 && workers[0].Pid != workerPid, nil
+		})
+
+		workers, err := client.Informer.Workers("http")
+		assert.NoError(t, err)
+		assert.Len(t, workers, 1)
+		assert.NotEqual(t, workerPid, workers[0].Pid)
 	}
+}
 
-	b, err := ioutil.ReadAll(resp.Body)
+// get request and return body
+func getHeader(url string, h map[string]string) (string, *http.Response, error) {
+	req, err := http.NewRequest("GET", url, bytes.NewBuffer(nil))
 	if err != nil {
-		t.Fatal(err)
+		return "", nil, err
 	}
 
-	defer func() {
-		_ = resp.Body.Close()
-	}()
+	for k, v := range h {
+		req.Header.Set(k, v)
+	}
 
-	require.Equal(t, all("../../php_test_files/client.php"), string(b))
-	require.Equal(t, all("../../php_test_files/client.php"), string(b))
-}
+	r, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", nil, err
+	}
 
-func staticNotForbid(t *testing.T) {
-	b, r, err := get("http://127.0.0.1:21603/php_test_files/client.php")
-	require.NoError(t, err)
-	require.Equal(t, all("../../php_test_files/client.php"), b)
-	require.Equal(t, all("../../php_test_files/client.php"), b)
-	_ = r.Body.Close()
-}
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return "", nil, err
+	}
 
-func serveStaticSample(t *testing.T) {
-	b, r, err := get("http://127.0.0.1:21603/sample.txt")
-	require.NoError(t, err)
-	require.Contains(t, b, "sample")
-	_ = r.Body.Close()
+	err = r.Body.Close()
+	if err != nil {
+		return "", nil, err
+	}
+	return string(b), r, err
 }
 
-func TestStaticDisabled_Error(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+func TestHTTPBigRequestSize(t *testing.T) {
+	env := harness.NewEnv(t)
 
 	cfg := &config.Plugin{
-		Path:   "configs/.rr-http-static-disabled.yaml",
-		Prefix: "rr",
+		Path: "configs/.rr-big-req-size.yaml",
+		Type: "yaml",
 	}
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.StartConfig(cfg,
 		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
-		&gzip.Plugin{},
-		&static.Plugin{},
 	)
-	assert.NoError(t, err)
-	assert.Error(t, cont.Init())
+
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+
+	t.Run("HTTPBigEcho10Mb", bigEchoHTTP(env.Addr("http")))
+
+	h.Stop()
 }
 
-func TestStaticFilesDisabled(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+func bigEchoHTTP(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		buf := make([]byte, 1024*1024*10)
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-http-static-files-disable.yaml",
-		Prefix: "rr",
+		_, err := rand.Read(buf)
+		assert.NoError(t, err)
+
+		bt := bytes.NewBuffer(buf)
+
+		req, err := http.NewRequest("GET", "http://"+addr+"?hello=world", bt)
+		assert.NoError(t, err)
+
+		r, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, 400, r.StatusCode)
+		assert.Equal(t, "http_handler_max_size: request body max size is exceeded\n", string(b))
+
+		err = r.Body.Close()
+		assert.NoError(t, err)
 	}
+}
 
-	err = cont.RegisterAll(
-		cfg,
+func TestStaticEtagPlugin(t *testing.T) {
+	env := harness.NewEnv(t)
+
+	h := env.Start("configs/.rr-http-static.yaml",
 		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
 		&gzip.Plugin{},
 		&static.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	t.Run("ServeSampleEtag", serveStaticSampleEtag(env.Addr("http")))
+	t.Run("NoStaticHeaders", noStaticHeaders(env.Addr("http")))
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	h.Stop()
+}
 
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
+func serveStaticSampleEtag(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		// OK 200 response
+		b, r, err := get("http://" + addr + "/sample.txt")
+		assert.NoError(t, err)
+		assert.Contains(t, b, "sample")
+		assert.Equal(t, r.StatusCode, http.StatusOK)
+		etag := r.Header.Get("Etag")
 
-	stopCh := make(chan struct{}, 1)
+		_ = r.Body.Close()
 
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
+		// Should be 304 response with same etag
+		c := http.Client{
+			Timeout: time.Second * 5,
 		}
-	}()
 
-	time.Sleep(time.Second)
-	t.Run("StaticFilesDisabled", staticFilesDisabled)
+		parsedURL, _ := url.Parse("http://" + addr + "/sample.txt")
 
-	stopCh <- struct{}{}
-	wg.Wait()
-}
+		req := &http.Request{
+			Method: http.MethodGet,
+			URL:    parsedURL,
+			Header: map[string][]string{"If-None-Match": {etag}},
+		}
 
-func staticFilesDisabled(t *testing.T) {
-	b, r, err := get("http://127.0.0.1:45877/php_test_files/client.php?hello=world")
-	if err != nil {
-		t.Fatal(err)
+		resp, err := c.Do(req)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+		_ = resp.Body.Close()
 	}
-	assert.Equal(t, "WORLD", b)
-	_ = r.Body.Close()
 }
 
-func TestStaticFilesForbid(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+// regular request should not contain static headers
+func noStaticHeaders(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		// OK 200 response
+		_, r, err := get("http://" + addr)
+		assert.NoError(t, err)
+		assert.NotContains(t, r.Header["input"], "custom-header")  //nolint:staticcheck
+		assert.NotContains(t, r.Header["output"], "output-header") //nolint:staticcheck
+		assert.Equal(t, r.StatusCode, http.StatusOK)
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-http-static-files.yaml",
-		Prefix: "rr",
+		_ = r.Body.Close()
 	}
+}
 
-	l, oLogger := mock_logger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
-		l,
+func TestStaticPluginSecurity(t *testing.T) {
+	env := harness.NewEnv(t)
+
+	h := env.Start("configs/.rr-http-static-security.yaml",
+		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
 		&gzip.Plugin{},
 		&static.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	t.Run("ServeSampleNotAllowedPath", serveStaticSampleNotAllowedPath(env.Addr("http")))
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
+	h.Stop()
+}
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+func serveStaticSampleNotAllowedPath(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		// Should be 304 response with same etag
+		c := http.Client{
+			Timeout: time.Second * 5,
+		}
 
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
+		parsedURL := &url.URL{
+			Scheme: "http",
+			User:   nil,
+			Host:   addr,
+			Path:   "%2e%2e%/tests/",
+		}
 
-	stopCh := make(chan struct{}, 1)
+		req := &http.Request{
+			Method: http.MethodGet,
+			URL:    parsedURL,
+		}
 
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
+		resp, err := c.Do(req)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		_ = resp.Body.Close()
+
+		parsedURL = &url.URL{
+			Scheme: "http",
+			User:   nil,
+			Host:   addr,
+			Path:   "%2e%2e%5ctests/",
 		}
-	}()
 
-	time.Sleep(time.Second)
-	t.Run("StaticTestFilesDir", staticTestFilesDir)
-	t.Run("StaticNotFound", staticNotFound)
-	t.Run("StaticFilesForbid", staticFilesForbid)
+		req = &http.Request{
+			Method: http.MethodGet,
+			URL:    parsedURL,
+		}
 
-	stopCh <- struct{}{}
-	wg.Wait()
-	time.Sleep(time.Second)
+		resp, err = c.Do(req)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		_ = resp.Body.Close()
 
-	o1 := oLogger.FilterMessageSnippet("http server was started")
-	o3 := oLogger.FilterMessageSnippet("http log")
+		parsedURL = &url.URL{
+			Scheme: "http",
+			User:   nil,
+			Host:   addr,
+			Path:   "..%2ftests/",
+		}
 
-	require.Equal(t, 1, o1.Len())
-	require.Equal(t, 3, o3.Len())
-	require.Equal(t, 1, oLogger.FilterMessageSnippet("file extension is forbidden").Len())
-}
+		req = &http.Request{
+			Method: http.MethodGet,
+			URL:    parsedURL,
+		}
 
-func staticTestFilesDir(t *testing.T) {
-	b, r, err := get("http://127.0.0.1:34653/http?hello=world")
-	assert.NoError(t, err)
-	assert.Equal(t, "WORLD", b)
-	_ = r.Body.Close()
-}
+		resp, err = c.Do(req)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		_ = resp.Body.Close()
 
-func staticNotFound(t *testing.T) {
-	b, _, _ := get("http://127.0.0.1:34653/client.XXX?hello=world") //nolint:bodyclose
-	assert.Equal(t, "WORLD", b)
-}
+		parsedURL = &url.URL{
+			Scheme: "http",
+			User:   nil,
+			Host:   addr,
+			Path:   "%2e%2e%2ftests/",
+		}
 
-func staticFilesForbid(t *testing.T) {
-	b, r, err := get("http://127.0.0.1:34653/client.php?hello=world")
-	if err != nil {
-		t.Fatal(err)
-	}
-	assert.Equal(t, "WORLD", b)
-	_ = r.Body.Close()
-}
+		req = &http.Request{
+			Method: http.MethodGet,
+			URL:    parsedURL,
+		}
 
-func TestHTTPIssue659(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+		resp, err = c.Do(req)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		_ = resp.Body.Close()
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-issue659.yaml",
-		Prefix: "rr",
+		_, r, err := get("http://" + addr + "/../../sample.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, 403, r.StatusCode)
+		_ = r.Body.Close()
 	}
+}
+
+func TestStaticPlugin(t *testing.T) {
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
-		&rpcPlugin.Plugin{},
+	h := env.Start("configs/.rr-http-static.yaml",
 		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
+		&gzip.Plugin{},
+		&static.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	t.Run("ServeSample", serveStaticSample(env.Addr("http")))
+	t.Run("StaticNotForbid", staticNotForbid(env.Addr("http")))
+	t.Run("StaticHeaders", staticHeaders(env.Addr("http")))
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
+	h.Stop()
+}
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+func staticHeaders(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		req, err := http.NewRequest("GET", "http://"+addr+"/php_test_files/client.php", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
 
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
 
-	stopCh := make(chan struct{}, 1)
+		if resp.Header.Get("Output") != "output-header" {
+			t.Fatal("can't find output header in response")
+		}
 
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
 		}
-	}()
 
-	time.Sleep(time.Second * 1)
-	t.Run("HTTPIssue659", echoIssue659)
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+
+		require.Equal(t, all("../../php_test_files/client.php"), string(b))
+		require.Equal(t, all("../../php_test_files/client.php"), string(b))
+	}
+}
 
-	stopCh <- struct{}{}
+func staticNotForbid(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		b, r, err := get("http://" + addr + "/php_test_files/client.php")
+		require.NoError(t, err)
+		require.Equal(t, all("../../php_test_files/client.php"), b)
+		require.Equal(t, all("../../php_test_files/client.php"), b)
+		_ = r.Body.Close()
+	}
+}
 
-	wg.Wait()
+func serveStaticSample(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		b, r, err := get("http://" + addr + "/sample.txt")
+		require.NoError(t, err)
+		require.Contains(t, b, "sample")
+		_ = r.Body.Close()
+	}
 }
 
-func TestHTTPIPv6Long(t *testing.T) {
+func TestStaticDisabled_Error(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
 	assert.NoError(t, err)
 
 	cfg := &config.Plugin{
-		Path:   "configs/.rr-http-ipv6.yaml",
+		Path:   env.Render("configs/.rr-http-static-disabled.yaml"),
 		Prefix: "rr",
 	}
 
 	err = cont.RegisterAll(
 		cfg,
-		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
+		&gzip.Plugin{},
+		&static.Plugin{},
 	)
 	assert.NoError(t, err)
+	assert.Error(t, cont.Init())
+}
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
+func TestStaticFilesDisabled(t *testing.T) {
+	env := harness.NewEnv(t)
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	h := env.Start("configs/.rr-http-static-files-disable.yaml",
+		&logger.Plugin{},
+		&server.Plugin{},
+		&httpPlugin.Plugin{},
+		&gzip.Plugin{},
+		&static.Plugin{},
+	)
 
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	t.Run("StaticFilesDisabled", staticFilesDisabled(env.Addr("http")))
 
-	stopCh := make(chan struct{}, 1)
+	h.Stop()
+}
 
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
+func staticFilesDisabled(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		b, r, err := get("http://" + addr + "/php_test_files/client.php?hello=world")
+		if err != nil {
+			t.Fatal(err)
 		}
-	}()
+		assert.Equal(t, "WORLD", b)
+		_ = r.Body.Close()
+	}
+}
 
-	time.Sleep(time.Second * 1)
-	t.Run("HTTPEchoIPv6-long", echoHTTPIPv6Long)
+func TestStaticFilesForbid(t *testing.T) {
+	env := harness.NewEnv(t)
+
+	l, oLogger := mock_logger.ZapTestLogger(zap.DebugLevel)
+	h := env.Start("configs/.rr-http-static-files.yaml",
+		l,
+		&server.Plugin{},
+		&httpPlugin.Plugin{},
+		&gzip.Plugin{},
+		&static.Plugin{},
+	)
 
-	stopCh <- struct{}{}
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	t.Run("StaticTestFilesDir", staticTestFilesDir(env.Addr("http")))
+	t.Run("StaticNotFound", staticNotFound(env.Addr("http")))
+	t.Run("StaticFilesForbid", staticFilesForbid(env.Addr("http")))
 
-	wg.Wait()
+	h.Stop()
+	time.Sleep(time.Second)
+
+	o1 := oLogger.FilterMessageSnippet("http server was started")
+	o3 := oLogger.FilterMessageSnippet("http log")
+
+	require.Equal(t, 1, o1.Len())
+	require.Equal(t, 3, o3.Len())
+	require.Equal(t, 1, oLogger.FilterMessageSnippet("file extension is forbidden").Len())
 }
 
-func TestHTTPIPv6Short(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+func staticTestFilesDir(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		b, r, err := get("http://" + addr + "/http?hello=world")
+		assert.NoError(t, err)
+		assert.Equal(t, "WORLD", b)
+		_ = r.Body.Close()
+	}
+}
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-http-ipv6-2.yaml",
-		Prefix: "rr",
+func staticNotFound(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		b, _, _ := get("http://" + addr + "/client.XXX?hello=world") //nolint:bodyclose
+		assert.Equal(t, "WORLD", b)
 	}
+}
 
-	err = cont.RegisterAll(
-		cfg,
+func staticFilesForbid(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		b, r, err := get("http://" + addr + "/client.php?hello=world")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "WORLD", b)
+		_ = r.Body.Close()
+	}
+}
+
+func TestHTTPIssue659(t *testing.T) {
+	env := harness.NewEnv(t)
+
+	h := env.Start("configs/.rr-issue659.yaml",
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	t.Run("HTTPIssue659", echoIssue659(env.Addr("http")))
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
+	h.Stop()
+}
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+func TestHTTPIPv6Long(t *testing.T) {
+	env := harness.NewEnv(t)
 
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
+	h := env.Start("configs/.rr-http-ipv6.yaml",
+		&rpcPlugin.Plugin{},
+		&logger.Plugin{},
+		&server.Plugin{},
+		&httpPlugin.Plugin{},
+	)
 
-	stopCh := make(chan struct{}, 1)
+	harness.WaitForTCP(t, "[::1]:"+strconv.Itoa(env.Port("http")), harness.DefaultWaitTimeout)
+	t.Run("HTTPEchoIPv6-long", echoHTTPIPv6Long("[0:0:0:0:0:0:0:1]:"+strconv.Itoa(env.Port("http"))))
 
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
+	h.Stop()
+}
 
-	time.Sleep(time.Second * 1)
-	t.Run("HTTPEchoIPv6-short", echoHTTPIPv6Short)
+func TestHTTPIPv6Short(t *testing.T) {
+	env := harness.NewEnv(t)
 
-	stopCh <- struct{}{}
+	h := env.Start("configs/.rr-http-ipv6-2.yaml",
+		&rpcPlugin.Plugin{},
+		&logger.Plugin{},
+		&server.Plugin{},
+		&httpPlugin.Plugin{},
+	)
 
-	wg.Wait()
+	harness.WaitForTCP(t, "[::1]:"+strconv.Itoa(env.Port("http")), harness.DefaultWaitTimeout)
+	t.Run("HTTPEchoIPv6-short", echoHTTPIPv6Short("[::1]:"+strconv.Itoa(env.Port("http"))))
+
+	h.Stop()
 }
 
-func echoIssue659(t *testing.T) {
-	req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:32552", nil)
-	assert.NoError(t, err)
+func echoIssue659(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "http://"+addr, nil)
+		assert.NoError(t, err)
 
-	r, err := http.DefaultClient.Do(req)
-	assert.NoError(t, err)
-	b, err := ioutil.ReadAll(r.Body)
-	assert.NoError(t, err)
-	assert.Empty(t, b)
-	assert.Equal(t, 444, r.StatusCode)
+		r, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Empty(t, b)
+		assert.Equal(t, 444, r.StatusCode)
 
-	err = r.Body.Close()
-	assert.NoError(t, err)
+		err = r.Body.Close()
+		assert.NoError(t, err)
+	}
 }
 
-func echoHTTP(t *testing.T) {
-	req, err := http.NewRequest("GET", "http://127.0.0.1:10084?hello=world", nil)
-	assert.NoError(t, err)
+func echoHTTP(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		req, err := http.NewRequest("GET", "http://"+addr+"?hello=world", nil)
+		assert.NoError(t, err)
 
-	r, err := http.DefaultClient.Do(req)
-	assert.NoError(t, err)
-	b, err := ioutil.ReadAll(r.Body)
-	assert.NoError(t, err)
-	assert.Equal(t, 201, r.StatusCode)
-	assert.Equal(t, "WORLD", string(b))
+		r, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, 201, r.StatusCode)
+		assert.Equal(t, "WORLD", string(b))
 
-	err = r.Body.Close()
-	assert.NoError(t, err)
+		err = r.Body.Close()
+		assert.NoError(t, err)
+	}
 }
 
-func echoHTTPIPv6Long(t *testing.T) {
-	req, err := http.NewRequest("GET", "http://[0:0:0:0:0:0:0:1]:10684?hello=world", nil)
-	assert.NoError(t, err)
+func echoHTTPIPv6Long(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		req, err := http.NewRequest("GET", "http://"+addr+"?hello=world", nil)
+		assert.NoError(t, err)
 
-	r, err := http.DefaultClient.Do(req)
-	assert.NoError(t, err)
-	b, err := ioutil.ReadAll(r.Body)
-	assert.NoError(t, err)
-	assert.Equal(t, 201, r.StatusCode)
-	assert.Equal(t, "WORLD", string(b))
+		r, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, 201, r.StatusCode)
+		assert.Equal(t, "WORLD", string(b))
 
-	err = r.Body.Close()
-	assert.NoError(t, err)
+		err = r.Body.Close()
+		assert.NoError(t, err)
+	}
 }
 
-func echoHTTPIPv6Short(t *testing.T) {
-	req, err := http.NewRequest("GET", "http://[::1]:10784?hello=world", nil)
-	assert.NoError(t, err)
+func echoHTTPIPv6Short(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		req, err := http.NewRequest("GET", "http://"+addr+"?hello=world", nil)
+		assert.NoError(t, err)
 
-	r, err := http.DefaultClient.Do(req)
-	assert.NoError(t, err)
-	b, err := ioutil.ReadAll(r.Body)
-	assert.NoError(t, err)
-	assert.Equal(t, 201, r.StatusCode)
-	assert.Equal(t, "WORLD", string(b))
+		r, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, 201, r.StatusCode)
+		assert.Equal(t, "WORLD", string(b))
 
-	err = r.Body.Close()
-	assert.NoError(t, err)
+		err = r.Body.Close()
+		assert.NoError(t, err)
+	}
 }
 
 func resetTest(address string) func(t *testing.T) {
 	return func(t *testing.T) {
-		conn, err := net.Dial("tcp", address)
-		assert.NoError(t, err)
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-		// WorkerList contains list of workers.
+		client := harness.Dial(t, address)
 
-		var ret bool
-		err = client.Call("resetter.Reset", "http", &ret)
+		ret, err := client.Resetter.Reset("http")
 		assert.NoError(t, err)
 		assert.True(t, ret)
-		ret = false
 
-		var services []string
-		err = client.Call("resetter.List", nil, &services)
+		services, err := client.Resetter.List()
 		assert.NoError(t, err)
 		if services[0] != "http" {
 			t.Fatal("no enough services")
@@ -2761,18 +1407,9 @@ func resetTest(address string) func(t *testing.T) {
 
 func informerTest(address string) func(t *testing.T) {
 	return func(t *testing.T) {
-		conn, err := net.Dial("tcp", address)
-		assert.NoError(t, err)
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-		// WorkerList contains list of workers.
-		list := struct {
-			// Workers is list of workers.
-			Workers []process.State `json:"workers"`
-		}{}
-
-		err = client.Call("informer.Workers", "http", &list)
+		workers, err := harness.Dial(t, address).Informer.Workers("http")
 		assert.NoError(t, err)
-		assert.Len(t, list.Workers, 2)
+		assert.Len(t, workers, 2)
 	}
 }
 
@@ -0,0 +1,14 @@
+package http
+
+import (
+	"os"
+	"testing"
+
+	"github.com/roadrunner-server/rr-e2e-tests/fakeworker"
+)
+
+func TestMain(m *testing.M) {
+	// the test binary is also the worker for the fakeworker tests
+	fakeworker.Main()
+	os.Exit(m.Run())
+}
@@ -17,6 +17,7 @@ import (
 
 	"github.com/goccy/go-json"
 	"github.com/roadrunner-server/http/v2/handler"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	"github.com/roadrunner-server/sdk/v2/ipc/pipe"
 	poolImpl "github.com/roadrunner-server/sdk/v2/pool"
 	"github.com/stretchr/testify/assert"
@@ -25,6 +26,8 @@ import (
 const testFile = "uploads_test.go"
 
 func TestHandler_Upload_File(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	pool, err := poolImpl.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "upload", "pipes")
@@ -42,7 +45,7 @@ func TestHandler_Upload_File(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{".go": {}}, map[string]struct{}{}, pool, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":9021", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -56,7 +59,7 @@ func TestHandler_Upload_File(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", errL)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
 	var mb bytes.Buffer
 	w := multipart.NewWriter(&mb)
@@ -81,7 +84,7 @@ func TestHandler_Upload_File(t *testing.T) {
 		t.Errorf("error closing the file: error %v", err)
 	}
 
-	req, err := http.NewRequest("POST", "http://127.0.0.1"+hs.Addr, &mb)
+	req, err := http.NewRequest("POST", "http://"+hs.Addr, &mb)
 	assert.NoError(t, err)
 
 	req.Header.Set("Content-Type", w.FormDataContentType())
@@ -107,6 +110,8 @@ func TestHandler_Upload_File(t *testing.T) {
 }
 
 func TestHandler_Upload_NestedFile(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	pool, err := poolImpl.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "upload", "pipes")
@@ -125,7 +130,7 @@ func TestHandler_Upload_NestedFile(t *testing.T) {
 
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":9022", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -139,7 +144,7 @@ func TestHandler_Upload_NestedFile(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", errL)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
 	var mb bytes.Buffer
 	w := multipart.NewWriter(&mb)
@@ -164,7 +169,7 @@ func TestHandler_Upload_NestedFile(t *testing.T) {
 		t.Errorf("error closing the file: error %v", err)
 	}
 
-	req, err := http.NewRequest("POST", "http://127.0.0.1"+hs.Addr, &mb)
+	req, err := http.NewRequest("POST", "http://"+hs.Addr, &mb)
 	assert.NoError(t, err)
 
 	req.Header.Set("Content-Type", w.FormDataContentType())
@@ -190,6 +195,8 @@ func TestHandler_Upload_NestedFile(t *testing.T) {
 }
 
 func TestHandler_Upload_File_NoTmpDir(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	pool, err := poolImpl.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "upload", "pipes")
@@ -207,7 +214,7 @@ func TestHandler_Upload_File_NoTmpDir(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, "--------", map[string]struct{}{".go": {}}, map[string]struct{}{}, pool, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":9023", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -221,7 +228,7 @@ func TestHandler_Upload_File_NoTmpDir(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
 	var mb bytes.Buffer
 	w := multipart.NewWriter(&mb)
@@ -246,7 +253,7 @@ func TestHandler_Upload_File_NoTmpDir(t *testing.T) {
 		t.Errorf("error closing the file: error %v", err)
 	}
 
-	req, err := http.NewRequest("POST", "http://127.0.0.1"+hs.Addr, &mb)
+	req, err := http.NewRequest("POST", "http://"+hs.Addr, &mb)
 	assert.NoError(t, err)
 
 	req.Header.Set("Content-Type", w.FormDataContentType())
@@ -272,6 +279,8 @@ func TestHandler_Upload_File_NoTmpDir(t *testing.T) {
 }
 
 func TestHandler_Upload_File_Forbids(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	pool, err := poolImpl.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "upload", "pipes")
@@ -289,7 +298,7 @@ func TestHandler_Upload_File_Forbids(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{}, map[string]struct{}{".go": {}}, pool, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":9024", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -303,7 +312,7 @@ func TestHandler_Upload_File_Forbids(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
 	var mb bytes.Buffer
 	w := multipart.NewWriter(&mb)
@@ -328,7 +337,7 @@ func TestHandler_Upload_File_Forbids(t *testing.T) {
 		t.Errorf("error closing the file: error %v", err)
 	}
 
-	req, err := http.NewRequest("POST", "http://127.0.0.1"+hs.Addr, &mb)
+	req, err := http.NewRequest("POST", "http://"+hs.Addr, &mb)
 	assert.NoError(t, err)
 
 	req.Header.Set("Content-Type", w.FormDataContentType())
@@ -354,6 +363,8 @@ func TestHandler_Upload_File_Forbids(t *testing.T) {
 }
 
 func TestHandler_Upload_File_NotAllowed(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	pool, err := poolImpl.NewStaticPool(context.Background(),
 		func(cmd string) *exec.Cmd {
 			return exec.Command("php", "../../php_test_files/http/client.php", "upload", "pipes")
@@ -371,7 +382,7 @@ func TestHandler_Upload_File_NotAllowed(t *testing.T) {
 	h, err := handler.NewHandler(1024, 500, os.TempDir(), map[string]struct{}{".php": {}}, map[string]struct{}{}, pool, mockLog, false)
 	assert.NoError(t, err)
 
-	hs := &http.Server{Addr: ":9024", Handler: h}
+	hs := &http.Server{Addr: env.Addr("http"), Handler: h}
 	defer func() {
 		errS := hs.Shutdown(context.Background())
 		if errS != nil {
@@ -385,7 +396,7 @@ func TestHandler_Upload_File_NotAllowed(t *testing.T) {
 			t.Errorf("error listening the interface: error %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
+	harness.WaitForTCP(t, hs.Addr, harness.DefaultWaitTimeout)
 
 	var mb bytes.Buffer
 	w := multipart.NewWriter(&mb)
@@ -410,7 +421,7 @@ func TestHandler_Upload_File_NotAllowed(t *testing.T) {
 		t.Errorf("error closing the file: error %v", err)
 	}
 
-	req, err := http.NewRequest("POST", "http://127.0.0.1"+hs.Addr, &mb)
+	req, err := http.NewRequest("POST", "http://"+hs.Addr, &mb)
 	assert.NoError(t, err)
 
 	req.Header.Set("Content-Type", w.FormDataContentType())
@@ -1,24 +1,15 @@
 package informer
 
 import (
-	"net"
-	"net/rpc"
-	"os"
-	"os/signal"
-	"sync"
-	"syscall"
 	"testing"
 	"time"
 
-	"github.com/roadrunner-server/api/v2/state/process"
-	"github.com/roadrunner-server/config/v2"
-	endure "github.com/roadrunner-server/endure/pkg/container"
-	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
 	"github.com/roadrunner-server/http/v2"
 	"github.com/roadrunner-server/informer/v2"
 	"github.com/roadrunner-server/logger/v2"
 	"github.com/roadrunner-server/resetter/v2"
 	rpcPlugin "github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	"github.com/roadrunner-server/server/v2"
 	"github.com/roadrunner-server/status/v2"
 	"github.com/stretchr/testify/assert"
@@ -26,88 +17,24 @@ import (
 )
 
 func TestInformerInit(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-informer.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-informer.yaml",
 		&server.Plugin{},
 		&logger.Plugin{},
 		&informer.Plugin{},
 		&rpcPlugin.Plugin{},
 		&Plugin1{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	stopCh := make(chan struct{}, 1)
 
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("InformerWorkersRpcTest", informerWorkersRPCTest(h.RPCAddr(), "informer.plugin1"))
+	t.Run("InformerListRpcTest", informerListRPCTest(h.RPCAddr()))
+	t.Run("InformerPluginWithoutWorkersRpcTest", informerPluginWOWorkersRPCTest(h.RPCAddr()))
 
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				return
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second)
-	t.Run("InformerWorkersRpcTest", informerWorkersRPCTest("informer.plugin1"))
-	t.Run("InformerListRpcTest", informerListRPCTest)
-	t.Run("InformerPluginWithoutWorkersRpcTest", informerPluginWOWorkersRPCTest)
-
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestInformerEarlyCall(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-informer-early-call.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-informer-early-call.yaml",
 		&server.Plugin{},
 		&logger.Plugin{},
 		&http.Plugin{},
@@ -118,108 +45,43 @@ func TestInformerEarlyCall(t *testing.T) {
 		&Plugin2{},
 	)
 
+	workers, err := h.RPC().Informer.Workers("informer.plugin2")
 	require.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	require.NoError(t, err)
-
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
-	assert.NoError(t, err)
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-	// WorkerList contains list of workers.
-	list := struct {
-		// list of workers.
-		Workers []process.State `json:"workers"`
-	}{}
-
-	err = client.Call("informer.Workers", "informer.plugin2", &list)
-	require.NoError(t, err)
-	require.Len(t, list.Workers, 0)
-
-	sig := make(chan os.Signal, 0)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	stopCh := make(chan struct{}, 1)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				return
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
+	require.Len(t, workers, 0)
 
 	time.Sleep(time.Second)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
-func informerPluginWOWorkersRPCTest(t *testing.T) {
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
-	assert.NoError(t, err)
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-	// WorkerList contains list of workers.
-	list := struct {
-		// Workers is list of workers.
-		Workers []process.State `json:"workers"`
-	}{}
+func informerPluginWOWorkersRPCTest(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
 
-	err = client.Call("informer.Workers", "informer.config", &list)
-	assert.NoError(t, err)
-	assert.Len(t, list.Workers, 0)
+		workers, err := client.Informer.Workers("informer.config")
+		assert.NoError(t, err)
+		assert.Len(t, workers, 0)
+	}
 }
 
-func informerWorkersRPCTest(service string) func(t *testing.T) {
+func informerWorkersRPCTest(addr, service string) func(t *testing.T) {
 	return func(t *testing.T) {
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
-		assert.NoError(t, err)
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-		// WorkerList contains list of workers.
-		list := struct {
-			// Workers is list of workers.
-			Workers []process.State `json:"workers"`
-		}{}
+		client := harness.Dial(t, addr)
 
-		err = client.Call("informer.Workers", service, &list)
+		workers, err := client.Informer.Workers(service)
 		assert.NoError(t, err)
-		assert.Len(t, list.Workers, 10)
+		assert.Len(t, workers, 10)
 	}
 }
 
-func informerListRPCTest(t *testing.T) {
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
-	assert.NoError(t, err)
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-	// WorkerList contains list of workers.
-	list := make([]string, 0, 5)
-	// Plugins which are expected to be in the list
-	expected := []string{"informer.plugin1"}
+func informerListRPCTest(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
+
+		// Plugins which are expected to be in the list
+		expected := []string{"informer.plugin1"}
 
-	err = client.Call("informer.List", true, &list)
-	assert.NoError(t, err)
-	assert.ElementsMatch(t, list, expected)
+		list, err := client.Informer.List()
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, list, expected)
+	}
 }
@@ -1,12 +1,6 @@
 package amqp
 
 import (
-	"net"
-	"net/rpc"
-	"os"
-	"os/signal"
-	"sync"
-	"syscall"
 	"testing"
 	"time"
 
@@ -15,12 +9,12 @@ import (
 	jobsv1beta "github.com/roadrunner-server/api/v2/proto/jobs/v1"
 	"github.com/roadrunner-server/config/v2"
 	endure "github.com/roadrunner-server/endure/pkg/container"
-	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
 	"github.com/roadrunner-server/informer/v2"
 	"github.com/roadrunner-server/jobs/v2"
 	"github.com/roadrunner-server/logger/v2"
 	"github.com/roadrunner-server/resetter/v2"
 	rpcPlugin "github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	mocklogger "github.com/roadrunner-server/rr-e2e-tests/mock"
 	helpers "github.com/roadrunner-server/rr-e2e-tests/plugins/jobs"
 	"github.com/roadrunner-server/server/v2"
@@ -30,17 +24,8 @@ import (
 )
 
 func TestAMQPInit(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-amqp-init.yaml",
-		Prefix: "rr",
-	}
-
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-amqp-init.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&jobs.Plugin{},
@@ -49,60 +34,13 @@ func TestAMQPInit(t *testing.T) {
 		&informer.Plugin{},
 		&amqp.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
-	t.Run("PushToPipeline", helpers.PushToPipe("test-1"))
-	t.Run("PushToPipeline", helpers.PushToPipe("test-2"))
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("PushToPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushToPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 	time.Sleep(time.Second)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("pipeline was started").Len())
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("pipeline was stopped").Len())
@@ -111,23 +49,18 @@ func TestAMQPInit(t *testing.T) {
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("delivery channel was closed, leaving the rabbit listener").Len())
 
 	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-1", "test-2")
+		helpers.DestroyPipelinesAt(h.RPCAddr(), "test-1", "test-2")
 	})
 }
 
 func TestAMQPInitV27(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
 	cfg := &config.Plugin{
 		Path:    "configs/.rr-amqp-init.yaml",
-		Prefix:  "rr",
 		Version: "2.7.0",
 	}
 
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.StartConfig(t, cfg,
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
@@ -136,60 +69,13 @@ func TestAMQPInitV27(t *testing.T) {
 		&informer.Plugin{},
 		&amqp.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
-	t.Run("PushToPipeline", helpers.PushToPipe("test-1"))
-	t.Run("PushToPipeline", helpers.PushToPipe("test-2"))
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("PushToPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushToPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 	time.Sleep(time.Second)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("pipeline was started").Len())
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("pipeline was stopped").Len())
@@ -198,23 +84,18 @@ func TestAMQPInitV27(t *testing.T) {
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("delivery channel was closed, leaving the rabbit listener").Len())
 
 	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-1", "test-2")
+		helpers.DestroyPipelinesAt(h.RPCAddr(), "test-1", "test-2")
 	})
 }
 
 func TestAMQPInitV27RR27(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
 	cfg := &config.Plugin{
 		Path:    "configs/.rr-amqp-init-v27.yaml",
-		Prefix:  "rr",
 		Version: "2.7.0",
 	}
 
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.StartConfig(t, cfg,
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
@@ -223,60 +104,13 @@ func TestAMQPInitV27RR27(t *testing.T) {
 		&informer.Plugin{},
 		&amqp.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
-	t.Run("PushToPipeline", helpers.PushToPipe("test-1"))
-	t.Run("PushToPipeline", helpers.PushToPipe("test-2"))
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("PushToPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushToPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 	time.Sleep(time.Second)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("pipeline was started").Len())
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("pipeline was stopped").Len())
@@ -285,23 +119,18 @@ func TestAMQPInitV27RR27(t *testing.T) {
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("delivery channel was closed, leaving the rabbit listener").Len())
 
 	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-1", "test-2")
+		helpers.DestroyPipelinesAt(h.RPCAddr(), "test-1", "test-2")
 	})
 }
 
 func TestAMQPInitV27RR27Durable(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
 	cfg := &config.Plugin{
 		Path:    "configs/.rr-amqp-init-v27-durable.yaml",
-		Prefix:  "rr",
 		Version: "2.7.0",
 	}
 
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.StartConfig(t, cfg,
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
@@ -310,60 +139,13 @@ func TestAMQPInitV27RR27Durable(t *testing.T) {
 		&informer.Plugin{},
 		&amqp.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
-	t.Run("PushToPipeline", helpers.PushToPipe("test-1"))
-	t.Run("PushToPipeline", helpers.PushToPipe("test-2"))
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("PushToPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushToPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 	time.Sleep(time.Second)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("pipeline was started").Len())
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("pipeline was stopped").Len())
@@ -372,22 +154,13 @@ func TestAMQPInitV27RR27Durable(t *testing.T) {
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("delivery channel was closed, leaving the rabbit listener").Len())
 
 	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-1", "test-2")
+		helpers.DestroyPipelinesAt(h.RPCAddr(), "test-1", "test-2")
 	})
 }
 
 func TestAMQPReset(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-amqp-init.yaml",
-		Prefix: "rr",
-	}
-
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-amqp-init.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
@@ -396,64 +169,17 @@ func TestAMQPReset(t *testing.T) {
 		&informer.Plugin{},
 		&amqp.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
-	t.Run("PushToPipeline", helpers.PushToPipe("test-1"))
-	t.Run("PushToPipeline", helpers.PushToPipe("test-2"))
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("PushToPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushToPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 	time.Sleep(time.Second)
-	reset(t)
-	t.Run("PushToPipeline", helpers.PushToPipe("test-1"))
-	t.Run("PushToPipeline", helpers.PushToPipe("test-2"))
+	reset(t, h.RPCAddr())
+	t.Run("PushToPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushToPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 	time.Sleep(time.Second)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("pipeline was started").Len())
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("pipeline was stopped").Len())
@@ -463,22 +189,13 @@ func TestAMQPReset(t *testing.T) {
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("delivery channel was closed, leaving the rabbit listener").Len())
 
 	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-1", "test-2")
+		helpers.DestroyPipelinesAt(h.RPCAddr(), "test-1", "test-2")
 	})
 }
 
 func TestAMQPDeclare(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-amqp-declare.yaml",
-		Prefix: "rr",
-	}
-
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-amqp-declare.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
@@ -487,65 +204,18 @@ func TestAMQPDeclare(t *testing.T) {
 		&informer.Plugin{},
 		&amqp.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	time.Sleep(time.Second * 3)
-
-	t.Run("DeclareAMQPPipeline", declareAMQPPipe)
-	t.Run("ConsumeAMQPPipeline", helpers.ResumePipes("test-3"))
-	t.Run("PushAMQPPipeline", helpers.PushToPipe("test-3"))
+	t.Run("DeclareAMQPPipeline", declareAMQPPipe(h.RPCAddr()))
+	t.Run("ConsumeAMQPPipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	t.Run("PushAMQPPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second)
-	t.Run("PauseAMQPPipeline", helpers.PausePipelines("test-3"))
+	t.Run("PauseAMQPPipeline", helpers.PausePipelinesAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second)
-	t.Run("DestroyAMQPPipeline", helpers.DestroyPipelines("test-3"))
+	t.Run("DestroyAMQPPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3"))
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("pipeline was resumed").Len())
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("pipeline was stopped").Len())
@@ -555,22 +225,13 @@ func TestAMQPDeclare(t *testing.T) {
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("delivery channel was closed, leaving the rabbit listener").Len())
 
 	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-3")
+		helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3")
 	})
 }
 
 func TestAMQPDeclareDurable(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-amqp-declare.yaml",
-		Prefix: "rr",
-	}
-
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-amqp-declare.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
@@ -579,65 +240,18 @@ func TestAMQPDeclareDurable(t *testing.T) {
 		&informer.Plugin{},
 		&amqp.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
-
-	t.Run("DeclareAMQPPipeline", declareAMQPPipeDurable)
-	t.Run("ConsumeAMQPPipeline", helpers.ResumePipes("test-3"))
-	t.Run("PushAMQPPipeline", helpers.PushToPipe("test-3"))
+	t.Run("DeclareAMQPPipeline", declareAMQPPipeDurable(h.RPCAddr()))
+	t.Run("ConsumeAMQPPipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	t.Run("PushAMQPPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second)
-	t.Run("PauseAMQPPipeline", helpers.PausePipelines("test-3"))
+	t.Run("PauseAMQPPipeline", helpers.PausePipelinesAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second)
-	t.Run("DestroyAMQPPipeline", helpers.DestroyPipelines("test-3"))
+	t.Run("DestroyAMQPPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3"))
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("pipeline was resumed").Len())
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("pipeline was stopped").Len())
@@ -647,22 +261,13 @@ func TestAMQPDeclareDurable(t *testing.T) {
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("delivery channel was closed, leaving the rabbit listener").Len())
 
 	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-3")
+		helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3")
 	})
 }
 
 func TestAMQPJobsError(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-amqp-jobs-err.yaml",
-		Prefix: "rr",
-	}
-
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-amqp-jobs-err.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
@@ -671,64 +276,17 @@ func TestAMQPJobsError(t *testing.T) {
 		&informer.Plugin{},
 		&amqp.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
-
-	t.Run("DeclareAMQPPipeline", declareAMQPPipe)
-	t.Run("ConsumeAMQPPipeline", helpers.ResumePipes("test-3"))
-	t.Run("PushAMQPPipeline", helpers.PushToPipe("test-3"))
+	t.Run("DeclareAMQPPipeline", declareAMQPPipe(h.RPCAddr()))
+	t.Run("ConsumeAMQPPipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	t.Run("PushAMQPPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second * 25)
-	t.Run("PauseAMQPPipeline", helpers.PausePipelines("test-3"))
-	t.Run("DestroyAMQPPipeline", helpers.DestroyPipelines("test-3"))
+	t.Run("PauseAMQPPipeline", helpers.PausePipelinesAt(h.RPCAddr(), "test-3"))
+	t.Run("DestroyAMQPPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3"))
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("job was pushed successfully").Len())
 	require.Equal(t, 4, oLogger.FilterMessageSnippet("job processing was started").Len())
@@ -740,16 +298,18 @@ func TestAMQPJobsError(t *testing.T) {
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("delivery channel was closed, leaving the rabbit listener").Len())
 
 	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-3")
+		helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3")
 	})
 }
 
 func TestAMQPNoGlobalSection(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
 	assert.NoError(t, err)
 
 	cfg := &config.Plugin{
-		Path:   "configs/.rr-no-global.yaml",
+		Path:   env.Render("configs/.rr-no-global.yaml"),
 		Prefix: "rr",
 	}
 
@@ -776,17 +336,8 @@ func TestAMQPNoGlobalSection(t *testing.T) {
 }
 
 func TestAMQPStats(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-amqp-declare.yaml",
-		Prefix: "rr",
-	}
-
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-amqp-declare.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
@@ -795,66 +346,20 @@ func TestAMQPStats(t *testing.T) {
 		&informer.Plugin{},
 		&amqp.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
-
-	t.Run("DeclareAMQPPipeline", declareAMQPPipe)
-	t.Run("ConsumeAMQPPipeline", helpers.ResumePipes("test-3"))
-	t.Run("PushAMQPPipeline", helpers.PushToPipe("test-3"))
+	t.Run("DeclareAMQPPipeline", declareAMQPPipe(h.RPCAddr()))
+	t.Run("ConsumeAMQPPipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	t.Run("PushAMQPPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second * 2)
-	t.Run("PauseAMQPPipeline", helpers.PausePipelines("test-3"))
+	t.Run("PauseAMQPPipeline", helpers.PausePipelinesAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second * 2)
-	t.Run("PushAMQPPipeline", helpers.PushToPipe("test-3"))
-	t.Run("PushPipelineDelayed", helpers.PushToPipeDelayed("test-3", 5))
+	t.Run("PushAMQPPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
+	t.Run("PushPipelineDelayed", helpers.PushToPipeDelayedAt(h.RPCAddr(), "test-3", 5))
 
 	out := &jobState.State{}
-	t.Run("Stats", helpers.Stats(out))
+	t.Run("Stats", helpers.StatsAt(h.RPCAddr(), out))
 
 	assert.Equal(t, out.Pipeline, "test-3")
 	assert.Equal(t, out.Driver, "amqp")
@@ -866,11 +371,13 @@ func TestAMQPStats(t *testing.T) {
 	assert.Equal(t, false, out.Ready)
 
 	time.Sleep(time.Second)
-	t.Run("ResumePipeline", helpers.ResumePipes("test-3"))
-	time.Sleep(time.Second * 7)
+	t.Run("ResumePipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	harness.WaitForJobsStat(t, h.RPCAddr(), time.Second*15, harness.PipelineStat("test-3", func(st *jobsv1beta.Stat) bool {
+		return st.GetActive() == 0 && st.GetDelayed() == 0 && st.GetReserved() == 0 && st.GetReady()
+	}))
 
 	out = &jobState.State{}
-	t.Run("Stats", helpers.Stats(out))
+	t.Run("Stats", helpers.StatsAt(h.RPCAddr(), out))
 
 	assert.Equal(t, out.Pipeline, "test-3")
 	assert.Equal(t, out.Driver, "amqp")
@@ -882,10 +389,9 @@ func TestAMQPStats(t *testing.T) {
 	assert.Equal(t, true, out.Ready)
 
 	time.Sleep(time.Second)
-	t.Run("DestroyAMQPPipeline", helpers.DestroyPipelines("test-3"))
+	t.Run("DestroyAMQPPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3"))
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 3, oLogger.FilterMessageSnippet("job was pushed successfully").Len())
 	require.Equal(t, 3, oLogger.FilterMessageSnippet("job processing was started").Len())
@@ -896,22 +402,13 @@ func TestAMQPStats(t *testing.T) {
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("delivery channel was closed, leaving the rabbit listener").Len())
 
 	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-3")
+		helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3")
 	})
 }
 
 func TestAMQPRespondOk(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-amqp-resp-jobs-ok.yaml",
-		Prefix: "rr",
-	}
-
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-amqp-resp-jobs-ok.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
@@ -920,64 +417,17 @@ func TestAMQPRespondOk(t *testing.T) {
 		&informer.Plugin{},
 		&amqp.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
-
-	t.Run("DeclareAMQPPipeline", declareAMQPPipe)
-	t.Run("ConsumeAMQPPipeline", helpers.ResumePipes("test-3"))
-	t.Run("PushAMQPPipeline", helpers.PushToPipe("test-3"))
+	t.Run("DeclareAMQPPipeline", declareAMQPPipe(h.RPCAddr()))
+	t.Run("ConsumeAMQPPipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	t.Run("PushAMQPPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second * 5)
-	t.Run("DestroyAMQPPipeline", helpers.DestroyPipelines("test-3"))
-	t.Run("DestroyAMQPPipeline", helpers.DestroyPipelines("test-1"))
+	t.Run("DestroyAMQPPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3"))
+	t.Run("DestroyAMQPPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-1"))
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("job was pushed successfully").Len())
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("job processing was started").Len())
@@ -988,22 +438,13 @@ func TestAMQPRespondOk(t *testing.T) {
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("delivery channel was closed, leaving the rabbit listener").Len())
 
 	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-3", "test-1")
+		helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3", "test-1")
 	})
 }
 
 func TestAMQPBadResp(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-amqp-init-br.yaml",
-		Prefix: "rr",
-	}
-
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-amqp-init-br.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&jobs.Plugin{},
@@ -1012,60 +453,13 @@ func TestAMQPBadResp(t *testing.T) {
 		&informer.Plugin{},
 		&amqp.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
-	time.Sleep(time.Second * 3)
-	t.Run("PushToPipeline", helpers.PushToPipe("test-1"))
-	t.Run("PushToPipeline", helpers.PushToPipe("test-2"))
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("PushToPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushToPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 	time.Sleep(time.Second)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("pipeline was started").Len())
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("pipeline was stopped").Len())
@@ -1075,68 +469,63 @@ func TestAMQPBadResp(t *testing.T) {
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("delivery channel was closed, leaving the rabbit listener").Len())
 
 	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-1", "test-2")
+		helpers.DestroyPipelinesAt(h.RPCAddr(), "test-1", "test-2")
 	})
 }
 
-func declareAMQPPipe(t *testing.T) {
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
-	assert.NoError(t, err)
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-	pipe := &jobsv1beta.DeclareRequest{Pipeline: map[string]string{
-		"driver":               "amqp",
-		"name":                 "test-3",
-		"routing_key":          "test-3",
-		"queue":                "default",
-		"exchange_type":        "direct",
-		"exchange":             "amqp.default",
-		"prefetch":             "100",
-		"delete_queue_on_stop": "true",
-		"priority":             "3",
-		"exclusive":            "true",
-		"multiple_ask":         "true",
-		"requeue_on_fail":      "true",
-	}}
-
-	er := &jobsv1beta.Empty{}
-	err = client.Call("jobs.Declare", pipe, er)
-	assert.NoError(t, err)
+func declareAMQPPipe(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
+
+		pipe := map[string]string{
+			"driver":               "amqp",
+			"name":                 "test-3",
+			"routing_key":          "test-3",
+			"queue":                "default",
+			"exchange_type":        "direct",
+			"exchange":             "amqp.default",
+			"prefetch":             "100",
+			"delete_queue_on_stop": "true",
+			"priority":             "3",
+			"exclusive":            "true",
+			"multiple_ask":         "true",
+			"requeue_on_fail":      "true",
+		}
+
+		err := client.Jobs.Declare(pipe)
+		assert.NoError(t, err)
+	}
 }
 
-func declareAMQPPipeDurable(t *testing.T) {
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
-	assert.NoError(t, err)
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-	pipe := &jobsv1beta.DeclareRequest{Pipeline: map[string]string{
-		"driver":               "amqp",
-		"name":                 "test-3",
-		"routing_key":          "test-3",
-		"queue":                "default",
-		"exchange_type":        "direct",
-		"exchange":             "amqp.default",
-		"delete_queue_on_stop": "true",
-		"prefetch":             "100",
-		"durable":              "true",
-		"priority":             "3",
-		"exclusive":            "true",
-		"multiple_ask":         "true",
-		"requeue_on_fail":      "true",
-	}}
-
-	er := &jobsv1beta.Empty{}
-	err = client.Call("jobs.Declare", pipe, er)
-	assert.NoError(t, err)
+func declareAMQPPipeDurable(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
+
+		pipe := map[string]string{
+			"driver":               "amqp",
+			"name":                 "test-3",
+			"routing_key":          "test-3",
+			"queue":                "default",
+			"exchange_type":        "direct",
+			"exchange":             "amqp.default",
+			"delete_queue_on_stop": "true",
+			"prefetch":             "100",
+			"durable":              "true",
+			"priority":             "3",
+			"exclusive":            "true",
+			"multiple_ask":         "true",
+			"requeue_on_fail":      "true",
+		}
+
+		err := client.Jobs.Declare(pipe)
+		assert.NoError(t, err)
+	}
 }
 
-func reset(t *testing.T) {
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
-	assert.NoError(t, err)
-	c := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
+func reset(t *testing.T, addr string) {
+	c := harness.Dial(t, addr)
 
-	var ret bool
-	err = c.Call("resetter.Reset", "jobs", &ret)
+	ret, err := c.Resetter.Reset("jobs")
 	assert.NoError(t, err)
 	require.True(t, ret)
 }
@@ -1,12 +1,6 @@
 package beanstalk
 
 import (
-	"net"
-	"net/rpc"
-	"os"
-	"os/signal"
-	"sync"
-	"syscall"
 	"testing"
 	"time"
 
@@ -16,12 +10,13 @@ import (
 	"github.com/roadrunner-server/beanstalk/v2"
 	"github.com/roadrunner-server/config/v2"
 	endure "github.com/roadrunner-server/endure/pkg/container"
-	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
 	"github.com/roadrunner-server/informer/v2"
 	"github.com/roadrunner-server/jobs/v2"
 	"github.com/roadrunner-server/logger/v2"
 	"github.com/roadrunner-server/resetter/v2"
 	rpcPlugin "github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/fakebeanstalk"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	mocklogger "github.com/roadrunner-server/rr-e2e-tests/mock"
 	helpers "github.com/roadrunner-server/rr-e2e-tests/plugins/jobs"
 	"github.com/roadrunner-server/server/v2"
@@ -31,17 +26,12 @@ import (
 )
 
 func TestBeanstalkInit(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel), endure.GracefulShutdownTimeout(time.Second*60))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-beanstalk-init.yaml",
-		Prefix: "rr",
-	}
+	env.Set("beanstalk", fakebeanstalk.Start(t).Addr())
 
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Options(endure.GracefulShutdownTimeout(time.Second*60)).Start("configs/.rr-beanstalk-init.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
@@ -50,79 +40,31 @@ func TestBeanstalkInit(t *testing.T) {
 		&informer.Plugin{},
 		&beanstalk.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
-	stopCh <- struct{}{}
-	wg.Wait()
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	h.Stop()
 
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("pipeline was started").Len())
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("pipeline was stopped").Len())
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("beanstalk listener stopped").Len())
 
 	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-1", "test-2")
+		helpers.DestroyPipelinesAt(h.RPCAddr(), "test-1", "test-2")
 	})
 }
 
 func TestBeanstalkInitV27(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel), endure.GracefulShutdownTimeout(time.Second*60))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
+
+	env.Set("beanstalk", fakebeanstalk.Start(t).Addr())
 
 	cfg := &config.Plugin{
 		Path:    "configs/.rr-beanstalk-init-v27.yaml",
-		Prefix:  "rr",
 		Version: "2.7.0",
 	}
 
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Options(endure.GracefulShutdownTimeout(time.Second*60)).StartConfig(cfg,
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
@@ -131,83 +73,30 @@ func TestBeanstalkInitV27(t *testing.T) {
 		&informer.Plugin{},
 		&beanstalk.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	time.Sleep(time.Second * 3)
-
-	t.Run("PushPipeline", helpers.PushToPipe("test-1"))
-	t.Run("PushPipeline", helpers.PushToPipe("test-2"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 
 	time.Sleep(time.Second)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("pipeline was started").Len())
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("pipeline was stopped").Len())
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("beanstalk listener stopped").Len())
 
 	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-1", "test-2")
+		helpers.DestroyPipelinesAt(h.RPCAddr(), "test-1", "test-2")
 	})
 }
 
 func TestBeanstalkStats(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel), endure.GracefulShutdownTimeout(time.Second*60))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-beanstalk-declare.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	env.Set("beanstalk", fakebeanstalk.Start(t).Addr())
+	h := env.Options(endure.GracefulShutdownTimeout(time.Second*60)).Start("configs/.rr-beanstalk-declare.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -216,85 +105,44 @@ func TestBeanstalkStats(t *testing.T) {
 		&informer.Plugin{},
 		&beanstalk.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	time.Sleep(time.Second * 3)
-
-	t.Run("DeclarePipeline", declareBeanstalkPipe)
-	t.Run("ConsumePipeline", helpers.ResumePipes("test-3"))
-	t.Run("PushPipeline", helpers.PushToPipe("test-3"))
+	t.Run("DeclarePipeline", declareBeanstalkPipe(h.RPCAddr()))
+	t.Run("ConsumePipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second * 2)
-	t.Run("PausePipeline", helpers.PausePipelines("test-3"))
+	t.Run("PausePipeline", helpers.PausePipelinesAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second * 3)
-	t.Run("PushPipelineDelayed", helpers.PushToPipeDelayed("test-3", 8))
-	t.Run("PushPipeline", helpers.PushToPipe("test-3"))
-	time.Sleep(time.Second)
+	t.Run("PushPipelineDelayed", helpers.PushToPipeDelayedAt(h.RPCAddr(), "test-3", 8))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
+
+	harness.WaitForJobsStat(t, h.RPCAddr(), time.Second*5, harness.PipelineStat("test-3", func(st *jobsv1beta.Stat) bool {
+		return st.GetActive() == 1 && st.GetDelayed() == 1
+	}))
 
 	out := &jobState.State{}
-	t.Run("Stats", helpers.Stats(out))
+	t.Run("Stats", helpers.StatsAt(h.RPCAddr(), out))
 
 	assert.Equal(t, out.Pipeline, "test-3")
 	assert.Equal(t, out.Driver, "beanstalk")
 	assert.NotEmpty(t, out.Queue)
 
 	out = &jobState.State{}
-	t.Run("Stats", helpers.Stats(out))
+	t.Run("Stats", helpers.StatsAt(h.RPCAddr(), out))
 
 	assert.Equal(t, int64(1), out.Active)
 	assert.Equal(t, int64(1), out.Delayed)
 	assert.Equal(t, int64(0), out.Reserved)
 
 	time.Sleep(time.Second)
-	t.Run("ResumePipeline", helpers.ResumePipes("test-3"))
-	time.Sleep(time.Second * 15)
+	t.Run("ResumePipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	harness.WaitForJobsStat(t, h.RPCAddr(), time.Second*30, harness.PipelineStat("test-3", func(st *jobsv1beta.Stat) bool {
+		return st.GetActive() == 0 && st.GetDelayed() == 0 && st.GetReserved() == 0
+	}))
 
 	out = &jobState.State{}
-	t.Run("Stats", helpers.Stats(out))
+	t.Run("Stats", helpers.StatsAt(h.RPCAddr(), out))
 
 	assert.Equal(t, out.Pipeline, "test-3")
 	assert.Equal(t, out.Driver, "beanstalk")
@@ -305,28 +153,21 @@ func TestBeanstalkStats(t *testing.T) {
 	assert.Equal(t, int64(0), out.Reserved)
 
 	time.Sleep(time.Second)
-	t.Run("DestroyPipeline", helpers.DestroyPipelines("test-3"))
+	t.Run("DestroyPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3"))
 
 	time.Sleep(time.Second)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-3")
+		helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3")
 	})
 }
 
 func TestBeanstalkDeclare(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel), endure.GracefulShutdownTimeout(time.Second*60))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-beanstalk-declare.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	env.Set("beanstalk", fakebeanstalk.Start(t).Addr())
+	h := env.Options(endure.GracefulShutdownTimeout(time.Second*60)).Start("configs/.rr-beanstalk-declare.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -335,82 +176,29 @@ func TestBeanstalkDeclare(t *testing.T) {
 		&informer.Plugin{},
 		&beanstalk.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	time.Sleep(time.Second * 3)
-
-	t.Run("DeclareBeanstalkPipeline", declareBeanstalkPipe)
-	t.Run("ConsumeBeanstalkPipeline", helpers.ResumePipes("test-3"))
-	t.Run("PushBeanstalkPipeline", helpers.PushToPipe("test-3"))
-	t.Run("PauseBeanstalkPipeline", helpers.PausePipelines("test-3"))
+	t.Run("DeclareBeanstalkPipeline", declareBeanstalkPipe(h.RPCAddr()))
+	t.Run("ConsumeBeanstalkPipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	t.Run("PushBeanstalkPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
+	t.Run("PauseBeanstalkPipeline", helpers.PausePipelinesAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second * 5)
-	t.Run("DestroyBeanstalkPipeline", helpers.DestroyPipelines("test-3"))
+	t.Run("DestroyBeanstalkPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3"))
 
 	time.Sleep(time.Second * 5)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-3")
+		helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3")
 	})
 }
 
 func TestBeanstalkJobsError(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel), endure.GracefulShutdownTimeout(time.Second*60))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-beanstalk-jobs-err.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	env.Set("beanstalk", fakebeanstalk.Start(t).Addr())
+	h := env.Options(endure.GracefulShutdownTimeout(time.Second*60)).Start("configs/.rr-beanstalk-jobs-err.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -419,78 +207,33 @@ func TestBeanstalkJobsError(t *testing.T) {
 		&informer.Plugin{},
 		&beanstalk.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	time.Sleep(time.Second * 3)
-
-	t.Run("DeclareBeanstalkPipeline", declareBeanstalkPipe)
-	t.Run("ConsumeBeanstalkPipeline", helpers.ResumePipes("test-3"))
-	t.Run("PushBeanstalkPipeline", helpers.PushToPipe("test-3"))
+	t.Run("DeclareBeanstalkPipeline", declareBeanstalkPipe(h.RPCAddr()))
+	t.Run("ConsumeBeanstalkPipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	t.Run("PushBeanstalkPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second * 25)
-	t.Run("PauseBeanstalkPipeline", helpers.PausePipelines("test-3"))
+	t.Run("PauseBeanstalkPipeline", helpers.PausePipelinesAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second)
-	t.Run("DestroyBeanstalkPipeline", helpers.DestroyPipelines("test-3"))
+	t.Run("DestroyBeanstalkPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3"))
 
 	time.Sleep(time.Second * 5)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-3")
+		helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3")
 	})
 }
 
 func TestBeanstalkNoGlobalSection(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel), endure.GracefulShutdownTimeout(time.Second*60))
 	assert.NoError(t, err)
 
 	cfg := &config.Plugin{
-		Path:   "configs/.rr-no-global.yaml",
+		Path:   env.Render("configs/.rr-no-global.yaml"),
 		Prefix: "rr",
 	}
 
@@ -516,16 +259,10 @@ func TestBeanstalkNoGlobalSection(t *testing.T) {
 }
 
 func TestBeanstalkRespond(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel), endure.GracefulShutdownTimeout(time.Second*60))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-beanstalk-respond.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	env.Set("beanstalk", fakebeanstalk.Start(t).Addr())
+	h := env.Options(endure.GracefulShutdownTimeout(time.Second*60)).Start("configs/.rr-beanstalk-respond.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -534,85 +271,37 @@ func TestBeanstalkRespond(t *testing.T) {
 		&informer.Plugin{},
 		&beanstalk.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
+	t.Run("DeclareBeanstalkPipeline", declareBeanstalkPipe(h.RPCAddr()))
+	t.Run("ConsumeBeanstalkPipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	t.Run("PushBeanstalkPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second * 3)
-
-	t.Run("DeclareBeanstalkPipeline", declareBeanstalkPipe)
-	t.Run("ConsumeBeanstalkPipeline", helpers.ResumePipes("test-3"))
-	t.Run("PushBeanstalkPipeline", helpers.PushToPipe("test-3"))
-	time.Sleep(time.Second * 3)
-	t.Run("DestroyBeanstalkPipeline", helpers.DestroyPipelines("test-3"))
-	t.Run("DestroyBeanstalkPipeline", helpers.DestroyPipelines("test-1"))
+	t.Run("DestroyBeanstalkPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3"))
+	t.Run("DestroyBeanstalkPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-1"))
 
 	time.Sleep(time.Second * 5)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 	time.Sleep(time.Second)
 
 	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-1", "test-3")
+		helpers.DestroyPipelinesAt(h.RPCAddr(), "test-1", "test-3")
 	})
 }
 
 func TestBeanstalkInitV27BadResp(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel), endure.GracefulShutdownTimeout(time.Second*60))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
+
+	env.Set("beanstalk", fakebeanstalk.Start(t).Addr())
 
 	cfg := &config.Plugin{
 		Path:    "configs/.rr-beanstalk-init-br.yaml",
-		Prefix:  "rr",
 		Version: "2.7.0",
 	}
 
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Options(endure.GracefulShutdownTimeout(time.Second*60)).StartConfig(cfg,
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
@@ -621,62 +310,15 @@ func TestBeanstalkInitV27BadResp(t *testing.T) {
 		&informer.Plugin{},
 		&beanstalk.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	time.Sleep(time.Second * 3)
-
-	t.Run("PushPipeline", helpers.PushToPipe("test-1"))
-	t.Run("PushPipeline", helpers.PushToPipe("test-2"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 
 	time.Sleep(time.Second)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("pipeline was started").Len())
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("pipeline was stopped").Len())
@@ -684,25 +326,24 @@ func TestBeanstalkInitV27BadResp(t *testing.T) {
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("beanstalk listener stopped").Len())
 
 	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-1", "test-2")
+		helpers.DestroyPipelinesAt(h.RPCAddr(), "test-1", "test-2")
 	})
 }
 
-func declareBeanstalkPipe(t *testing.T) {
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
-	require.NoError(t, err)
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-	pipe := &jobsv1beta.DeclareRequest{Pipeline: map[string]string{
-		"driver":          "beanstalk",
-		"name":            "test-3",
-		"tube":            uuid.NewString(),
-		"reserve_timeout": "60s",
-		"priority":        "3",
-		"tube_priority":   "10",
-	}}
-
-	er := &jobsv1beta.Empty{}
-	err = client.Call("jobs.Declare", pipe, er)
-	require.NoError(t, err)
+func declareBeanstalkPipe(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
+
+		pipe := map[string]string{
+			"driver":          "beanstalk",
+			"name":            "test-3",
+			"tube":            uuid.NewString(),
+			"reserve_timeout": "60s",
+			"priority":        "3",
+			"tube_priority":   "10",
+		}
+
+		err := client.Jobs.Declare(pipe)
+		require.NoError(t, err)
+	}
 }
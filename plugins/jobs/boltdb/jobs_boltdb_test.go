@@ -1,12 +1,7 @@
 package boltdb
 
 import (
-	"net"
-	"net/rpc"
 	"os"
-	"os/signal"
-	"sync"
-	"syscall"
 	"testing"
 	"time"
 
@@ -15,12 +10,12 @@ import (
 	"github.com/roadrunner-server/boltdb/v2"
 	"github.com/roadrunner-server/config/v2"
 	endure "github.com/roadrunner-server/endure/pkg/container"
-	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
 	"github.com/roadrunner-server/informer/v2"
 	"github.com/roadrunner-server/jobs/v2"
 	"github.com/roadrunner-server/logger/v2"
 	"github.com/roadrunner-server/resetter/v2"
 	rpcPlugin "github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	mocklogger "github.com/roadrunner-server/rr-e2e-tests/mock"
 	helpers "github.com/roadrunner-server/rr-e2e-tests/plugins/jobs"
 	"github.com/roadrunner-server/server/v2"
@@ -35,16 +30,7 @@ const (
 )
 
 func TestBoltDBInit(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-boltdb-init.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-boltdb-init.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -53,76 +39,20 @@ func TestBoltDBInit(t *testing.T) {
 		&informer.Plugin{},
 		&boltdb.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
-	stopCh <- struct{}{}
-	wg.Wait()
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	h.Stop()
 
 	assert.NoError(t, os.Remove(rr1db))
 	assert.NoError(t, os.Remove(rr2db))
 
 	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-1", "test-2")
+		helpers.DestroyPipelinesAt(h.RPCAddr(), "test-1", "test-2")
 	})
 }
 
 func TestBoltDBInitV27(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-boltdb-init-v27.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-boltdb-init-v27.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -131,80 +61,24 @@ func TestBoltDBInitV27(t *testing.T) {
 		&informer.Plugin{},
 		&boltdb.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
-	t.Run("PushPipeline", helpers.PushToPipe("test-1"))
-	t.Run("PushPipeline", helpers.PushToPipe("test-2"))
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 	time.Sleep(time.Second)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	assert.NoError(t, os.Remove(rr1db))
 	assert.NoError(t, os.Remove(rr2db))
 
 	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-1", "test-2")
+		helpers.DestroyPipelinesAt(h.RPCAddr(), "test-1", "test-2")
 	})
 }
 
 func TestBoltDBInitV27BadResp(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-boltdb-init-v27-br.yaml",
-		Prefix: "rr",
-	}
-
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-boltdb-init-v27-br.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
@@ -213,59 +87,12 @@ func TestBoltDBInitV27BadResp(t *testing.T) {
 		&informer.Plugin{},
 		&boltdb.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
-	t.Run("PushPipeline", helpers.PushToPipe("test-1"))
-	t.Run("PushPipeline", helpers.PushToPipe("test-2"))
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 	time.Sleep(time.Second)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("response handler error").Len())
 
@@ -273,21 +100,12 @@ func TestBoltDBInitV27BadResp(t *testing.T) {
 	assert.NoError(t, os.Remove(rr2db))
 
 	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-1", "test-2")
+		helpers.DestroyPipelinesAt(h.RPCAddr(), "test-1", "test-2")
 	})
 }
 
 func TestBoltDBDeclare(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-boltdb-declare.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-boltdb-declare.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -296,84 +114,28 @@ func TestBoltDBDeclare(t *testing.T) {
 		&informer.Plugin{},
 		&boltdb.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
-	time.Sleep(time.Second * 3)
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	t.Run("DeclarePipeline", declareBoltDBPipe(rr1db))
-	t.Run("ConsumePipeline", helpers.ResumePipes("test-3"))
-	t.Run("PushPipeline", helpers.PushToPipe("test-3"))
+	t.Run("DeclarePipeline", declareBoltDBPipe(h.RPCAddr(), rr1db))
+	t.Run("ConsumePipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second)
-	t.Run("PausePipeline", helpers.PausePipelines("test-3"))
+	t.Run("PausePipeline", helpers.PausePipelinesAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second)
-	t.Run("DestroyPipeline", helpers.DestroyPipelines("test-3"))
+	t.Run("DestroyPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3"))
 
 	time.Sleep(time.Second * 5)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 	assert.NoError(t, os.Remove(rr1db))
 
 	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-3")
+		helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3")
 	})
 }
 
 func TestBoltDBJobsError(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-boltdb-jobs-err.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-boltdb-jobs-err.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -382,78 +144,33 @@ func TestBoltDBJobsError(t *testing.T) {
 		&informer.Plugin{},
 		&boltdb.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
-	time.Sleep(time.Second * 3)
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	t.Run("DeclarePipeline", declareBoltDBPipe(rr1db))
-	t.Run("ConsumePipeline", helpers.ResumePipes("test-3"))
-	t.Run("PushPipeline", helpers.PushToPipe("test-3"))
+	t.Run("DeclarePipeline", declareBoltDBPipe(h.RPCAddr(), rr1db))
+	t.Run("ConsumePipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second * 25)
-	t.Run("PausePipeline", helpers.PausePipelines("test-3"))
-	t.Run("DestroyPipeline", helpers.DestroyPipelines("test-3"))
+	t.Run("PausePipeline", helpers.PausePipelinesAt(h.RPCAddr(), "test-3"))
+	t.Run("DestroyPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3"))
 
 	time.Sleep(time.Second * 5)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 	assert.NoError(t, os.Remove(rr1db))
 
 	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-3")
+		helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3")
 	})
 }
 
 func TestBoltDBNoGlobalSection(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
 	assert.NoError(t, err)
 
 	cfg := &config.Plugin{
-		Path:   "configs/.rr-no-global.yaml",
+		Path:   env.Render("configs/.rr-no-global.yaml"),
 		Prefix: "rr",
 	}
 
@@ -479,16 +196,7 @@ func TestBoltDBNoGlobalSection(t *testing.T) {
 }
 
 func TestBoltDBStats(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-boltdb-declare.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-boltdb-declare.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -497,66 +205,20 @@ func TestBoltDBStats(t *testing.T) {
 		&informer.Plugin{},
 		&boltdb.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	t.Run("DeclarePipeline", declareBoltDBPipe(rr1db))
-	t.Run("ConsumePipeline", helpers.ResumePipes("test-3"))
-	t.Run("PushPipeline", helpers.PushToPipe("test-3"))
+	t.Run("DeclarePipeline", declareBoltDBPipe(h.RPCAddr(), rr1db))
+	t.Run("ConsumePipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second * 2)
-	t.Run("PausePipeline", helpers.PausePipelines("test-3"))
+	t.Run("PausePipeline", helpers.PausePipelinesAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second * 2)
-	t.Run("PushPipeline", helpers.PushToPipe("test-3"))
-	t.Run("PushPipelineDelayed", helpers.PushToPipeDelayed("test-3", 5))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
+	t.Run("PushPipelineDelayed", helpers.PushToPipeDelayedAt(h.RPCAddr(), "test-3", 5))
 
 	out := &jobState.State{}
-	t.Run("Stats", helpers.Stats(out))
+	t.Run("Stats", helpers.StatsAt(h.RPCAddr(), out))
 
 	assert.Equal(t, "test-3", out.Pipeline)
 	assert.Equal(t, "boltdb", out.Driver)
@@ -568,11 +230,13 @@ func TestBoltDBStats(t *testing.T) {
 	assert.Equal(t, false, out.Ready)
 
 	time.Sleep(time.Second)
-	t.Run("ResumePipeline", helpers.ResumePipes("test-3"))
-	time.Sleep(time.Second * 7)
+	t.Run("ResumePipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	harness.WaitForJobsStat(t, h.RPCAddr(), time.Second*15, harness.PipelineStat("test-3", func(st *jobsv1beta.Stat) bool {
+		return st.GetActive() == 0 && st.GetDelayed() == 0 && st.GetReserved() == 0 && st.GetReady()
+	}))
 
 	out = &jobState.State{}
-	t.Run("Stats", helpers.Stats(out))
+	t.Run("Stats", helpers.StatsAt(h.RPCAddr(), out))
 
 	assert.Equal(t, "test-3", out.Pipeline)
 	assert.Equal(t, "boltdb", out.Driver)
@@ -584,34 +248,30 @@ func TestBoltDBStats(t *testing.T) {
 	assert.Equal(t, true, out.Ready)
 
 	time.Sleep(time.Second)
-	t.Run("DestroyPipeline", helpers.DestroyPipelines("test-3"))
+	t.Run("DestroyPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3"))
 
 	time.Sleep(time.Second * 5)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 	assert.NoError(t, os.Remove(rr1db))
 
 	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-3")
+		helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3")
 	})
 }
 
-func declareBoltDBPipe(file string) func(t *testing.T) {
+func declareBoltDBPipe(addr, file string) func(t *testing.T) {
 	return func(t *testing.T) {
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
-		assert.NoError(t, err)
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
+		client := harness.Dial(t, addr)
 
-		pipe := &jobsv1beta.DeclareRequest{Pipeline: map[string]string{
+		pipe := map[string]string{
 			"driver":   "boltdb",
 			"name":     "test-3",
 			"prefetch": "100",
 			"priority": "3",
 			"file":     file,
-		}}
+		}
 
-		er := &jobsv1beta.Empty{}
-		err = client.Call("jobs.Declare", pipe, er)
+		err := client.Jobs.Declare(pipe)
 		assert.NoError(t, err)
 	}
 }
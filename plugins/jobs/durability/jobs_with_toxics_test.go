@@ -1,17 +1,11 @@
 package durability
 
 import (
-	"os"
-	"os/signal"
-	"sync"
-	"syscall"
 	"testing"
 	"time"
 
-	toxiproxy "github.com/Shopify/toxiproxy/client"
 	"github.com/roadrunner-server/amqp/v2"
 	"github.com/roadrunner-server/beanstalk/v2"
-	"github.com/roadrunner-server/config/v2"
 	endure "github.com/roadrunner-server/endure/pkg/container"
 	"github.com/roadrunner-server/informer/v2"
 	"github.com/roadrunner-server/jobs/v2"
@@ -19,6 +13,11 @@ import (
 	"github.com/roadrunner-server/nats/v2"
 	"github.com/roadrunner-server/resetter/v2"
 	rpcPlugin "github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/fakebeanstalk"
+	"github.com/roadrunner-server/rr-e2e-tests/fakesqs"
+	"github.com/roadrunner-server/rr-e2e-tests/faultproxy"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
+	"github.com/roadrunner-server/rr-e2e-tests/natsserver"
 	helpers "github.com/roadrunner-server/rr-e2e-tests/plugins/jobs"
 	"github.com/roadrunner-server/server/v2"
 	"github.com/roadrunner-server/sqs/v2"
@@ -27,22 +26,11 @@ import (
 )
 
 func TestDurabilityAMQP(t *testing.T) {
-	client := toxiproxy.NewClient("127.0.0.1:8474")
+	proxy := faultproxy.Start(t, "127.0.0.1:5672")
+	env := harness.NewEnv(t)
+	env.Set("amqp", proxy.Addr())
 
-	_, err := client.CreateProxy("redial", "127.0.0.1:23679", "127.0.0.1:5672")
-	require.NoError(t, err)
-	defer helpers.DeleteProxy("redial", t)
-
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	require.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-amqp-durability-redial.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-amqp-durability-redial.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -51,96 +39,36 @@ func TestDurabilityAMQP(t *testing.T) {
 		&informer.Plugin{},
 		&amqp.Plugin{},
 	)
-	require.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
 
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
-	helpers.DisableProxy("redial", t)
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	require.NoError(t, proxy.Disable())
 	time.Sleep(time.Second * 3)
 
 	go func() {
 		time.Sleep(time.Second * 5)
-		helpers.EnableProxy("redial", t)
+		assert.NoError(t, proxy.Enable())
 	}()
 
-	t.Run("PushPipelineWhileRedialing-1", helpers.PushToPipeErr("test-1"))
-	t.Run("PushPipelineWhileRedialing-2", helpers.PushToPipeErr("test-2"))
+	t.Run("PushPipelineWhileRedialing-1", helpers.PushToPipeErrAt(h.RPCAddr(), "test-1"))
+	t.Run("PushPipelineWhileRedialing-2", helpers.PushToPipeErrAt(h.RPCAddr(), "test-2"))
 
 	time.Sleep(time.Second * 15)
-	t.Run("PushPipelineWhileRedialing-1", helpers.PushToPipe("test-1"))
-	t.Run("PushPipelineWhileRedialing-2", helpers.PushToPipe("test-2"))
+	t.Run("PushPipelineWhileRedialing-1", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushPipelineWhileRedialing-2", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 
 	time.Sleep(time.Second * 5)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	t.Run("DestroyPipelines", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-1", "test-2"))
 
-	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-1", "test-2")
-	})
+	h.Stop()
 }
 
 func TestDurabilitySQS(t *testing.T) {
-	client := toxiproxy.NewClient("127.0.0.1:8474")
-
-	_, err := client.CreateProxy("redial", "127.0.0.1:19324", "127.0.0.1:9324")
-	require.NoError(t, err)
-	defer helpers.DeleteProxy("redial", t)
+	proxy := faultproxy.Start(t, fakesqs.Start(t).Addr())
+	env := harness.NewEnv(t)
+	env.Set("sqs", proxy.Addr())
 
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	require.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-sqs-durability-redial.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-sqs-durability-redial.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -149,97 +77,37 @@ func TestDurabilitySQS(t *testing.T) {
 		&informer.Plugin{},
 		&sqs.Plugin{},
 	)
-	require.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
 
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
-	helpers.DisableProxy("redial", t)
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	require.NoError(t, proxy.Disable())
 
 	go func() {
 		time.Sleep(time.Second)
-		t.Run("PushPipelineWhileRedialing-1", helpers.PushToPipe("test-1"))
+		t.Run("PushPipelineWhileRedialing-1", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
 		time.Sleep(time.Second)
-		t.Run("PushPipelineWhileRedialing-2", helpers.PushToPipe("test-2"))
+		t.Run("PushPipelineWhileRedialing-2", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 	}()
 
 	time.Sleep(time.Second * 5)
-	helpers.EnableProxy("redial", t)
+	require.NoError(t, proxy.Enable())
 	time.Sleep(time.Second * 5)
 
-	t.Run("PushPipelineWhileRedialing-3", helpers.PushToPipe("test-1"))
-	t.Run("PushPipelineWhileRedialing-4", helpers.PushToPipe("test-2"))
+	t.Run("PushPipelineWhileRedialing-3", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushPipelineWhileRedialing-4", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 
 	time.Sleep(time.Second * 10)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	t.Run("DestroyPipelines", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-1", "test-2"))
 
-	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-1", "test-2")
-	})
+	h.Stop()
 }
 
 func TestDurabilityBeanstalk(t *testing.T) {
-	client := toxiproxy.NewClient("127.0.0.1:8474")
-
-	_, err := client.CreateProxy("redial", "127.0.0.1:11400", "127.0.0.1:11300")
-	require.NoError(t, err)
-	defer helpers.DeleteProxy("redial", t)
+	srv := fakebeanstalk.Start(t)
+	env := harness.NewEnv(t)
+	env.Set("beanstalk", srv.Addr())
 
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel), endure.GracefulShutdownTimeout(time.Second*60))
-	require.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-beanstalk-durability-redial.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Options(endure.GracefulShutdownTimeout(time.Second*60)).Start("configs/.rr-beanstalk-durability-redial.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -248,175 +116,262 @@ func TestDurabilityBeanstalk(t *testing.T) {
 		&informer.Plugin{},
 		&beanstalk.Plugin{},
 	)
-	require.NoError(t, err)
+	h.SetStopTimeout(time.Second * 60)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
+	time.Sleep(time.Second * 3)
+	// the connections are dropped and refused until Up
+	require.NoError(t, srv.Down())
 
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
+	go func() {
+		time.Sleep(time.Second * 2)
+		t.Run("PushPipelineWhileRedialing-1", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+		t.Run("PushPipelineWhileRedialing-2", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
+	}()
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	time.Sleep(time.Second * 5)
+	require.NoError(t, srv.Up())
+	time.Sleep(time.Second * 2)
 
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
+	t.Run("PushPipelineWhileRedialing-1", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushPipelineWhileRedialing-2", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 
-	stopCh := make(chan struct{}, 1)
+	time.Sleep(time.Second * 10)
 
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
+	t.Run("DestroyPipelines", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-1", "test-2"))
+
+	h.Stop()
+}
 
+func TestDurabilityNATS(t *testing.T) {
+	proxy := faultproxy.Start(t, natsserver.Start(t).Addr())
+	env := harness.NewEnv(t)
+	env.Set("nats", proxy.Addr())
+
+	h := env.Start("configs/.rr-nats-durability-redial.yaml",
+		&server.Plugin{},
+		&rpcPlugin.Plugin{},
+		&logger.Plugin{},
+		&jobs.Plugin{},
+		&resetter.Plugin{},
+		&informer.Plugin{},
+		&nats.Plugin{},
+	)
+
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	require.NoError(t, proxy.Disable())
 	time.Sleep(time.Second * 3)
-	helpers.DisableProxy("redial", t)
 
 	go func() {
-		time.Sleep(time.Second * 2)
-		t.Run("PushPipelineWhileRedialing-1", helpers.PushToPipe("test-1"))
-		t.Run("PushPipelineWhileRedialing-2", helpers.PushToPipe("test-2"))
+		time.Sleep(time.Second)
+		t.Run("PushPipelineWhileRedialing-1", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+		time.Sleep(time.Second)
+		t.Run("PushPipelineWhileRedialing-2", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 	}()
 
 	time.Sleep(time.Second * 5)
-	helpers.EnableProxy("redial", t)
+	require.NoError(t, proxy.Enable())
 	time.Sleep(time.Second * 2)
 
-	t.Run("PushPipelineWhileRedialing-1", helpers.PushToPipe("test-1"))
-	t.Run("PushPipelineWhileRedialing-2", helpers.PushToPipe("test-2"))
+	t.Run("PushPipelineWhileRedialing-3", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushPipelineWhileRedialing-4", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 
-	time.Sleep(time.Second * 10)
+	time.Sleep(time.Second * 2)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	t.Run("DestroyPipelines", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-1", "test-2"))
+
+	h.Stop()
+}
 
-	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-1", "test-2")
+// drained waits until the fake SQS queues have no messages left
+func drained(t *testing.T, srv *fakesqs.Server, queues ...string) {
+	harness.Eventually(t, time.Second*30, "sqs queues drained", func() (bool, error) {
+		for i := 0; i < len(queues); i++ {
+			msgs, ok := srv.Messages(queues[i])
+			if !ok || len(msgs) > 0 {
+				return false, nil
+			}
+		}
+		return true, nil
 	})
 }
 
-func TestDurabilityNATS(t *testing.T) {
-	client := toxiproxy.NewClient("127.0.0.1:8474")
+// streamsDrained waits until the nats streams have no messages left (delete_after_ack)
+func streamsDrained(t *testing.T, srv *natsserver.Server, streams ...string) {
+	harness.Eventually(t, time.Second*30, "nats streams drained", func() (bool, error) {
+		st, err := srv.Streams()
+		if err != nil {
+			return false, err
+		}
+		for i := 0; i < len(streams); i++ {
+			if n, ok := st[streams[i]]; !ok || n > 0 {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+}
+
+func TestDurabilitySQSSlowBroker(t *testing.T) {
+	srv := fakesqs.Start(t)
+	proxy := faultproxy.Start(t, srv.Addr())
+	env := harness.NewEnv(t)
+	env.Set("sqs", proxy.Addr())
 
-	_, err := client.CreateProxy("redial", "127.0.0.1:19224", "127.0.0.1:4222")
-	require.NoError(t, err)
-	defer helpers.DeleteProxy("redial", t)
+	h := env.Start("configs/.rr-sqs-durability-redial.yaml",
+		&server.Plugin{},
+		&rpcPlugin.Plugin{},
+		&logger.Plugin{},
+		&jobs.Plugin{},
+		&resetter.Plugin{},
+		&informer.Plugin{},
+		&sqs.Plugin{},
+	)
 
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	require.NoError(t, err)
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	// slow in both directions, the responses are also throttled
+	helpers.AddLatency(proxy, "latency-up", faultproxy.Upstream, time.Millisecond*300, time.Millisecond*100, t)
+	helpers.AddLatency(proxy, "latency-down", faultproxy.Downstream, time.Millisecond*300, time.Millisecond*100, t)
+	helpers.AddBandwidth(proxy, "bandwidth", faultproxy.Downstream, 16, t)
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-nats-durability-redial.yaml",
-		Prefix: "rr",
+	for i := 0; i < 5; i++ {
+		t.Run("PushPipelineSlowBroker-1", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+		t.Run("PushPipelineSlowBroker-2", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 	}
 
-	err = cont.RegisterAll(
-		cfg,
+	drained(t, srv, "default", "default-2")
+
+	// even slower, the pushes still succeed
+	helpers.UpdateToxic(proxy, "latency-up", faultproxy.Latency{Latency: time.Second}, t)
+	t.Run("PushPipelineSlowerBroker-1", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushPipelineSlowerBroker-2", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
+
+	proxy.RemoveToxics()
+	drained(t, srv, "default", "default-2")
+
+	t.Run("DestroyPipelines", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-1", "test-2"))
+
+	h.Stop()
+}
+
+func TestDurabilitySQSSlicedResponses(t *testing.T) {
+	srv := fakesqs.Start(t)
+	proxy := faultproxy.Start(t, srv.Addr())
+	env := harness.NewEnv(t)
+	env.Set("sqs", proxy.Addr())
+
+	h := env.Start("configs/.rr-sqs-durability-redial.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&jobs.Plugin{},
 		&resetter.Plugin{},
 		&informer.Plugin{},
-		&nats.Plugin{},
+		&sqs.Plugin{},
 	)
-	require.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	// the responses arrive in the small pieces, the requests in the bigger ones
+	helpers.AddSlicer(proxy, "slicer-down", faultproxy.Downstream, 64, 32, time.Millisecond*5, t)
+	helpers.AddSlicer(proxy, "slicer-up", faultproxy.Upstream, 512, 128, time.Millisecond, t)
 
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
+	for i := 0; i < 5; i++ {
+		t.Run("PushPipelineSliced-1", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+		t.Run("PushPipelineSliced-2", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 	}
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	drained(t, srv, "default", "default-2")
 
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
+	// byte by byte
+	helpers.UpdateToxic(proxy, "slicer-down", faultproxy.Slicer{AverageSize: 1, Delay: time.Microsecond * 100}, t)
+	t.Run("PushPipelineByteSliced-1", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushPipelineByteSliced-2", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 
-	stopCh := make(chan struct{}, 1)
+	drained(t, srv, "default", "default-2")
 
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
+	t.Run("DestroyPipelines", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-1", "test-2"))
 
-	time.Sleep(time.Second * 3)
-	helpers.DisableProxy("redial", t)
-	time.Sleep(time.Second * 3)
+	h.Stop()
+}
 
-	go func() {
-		time.Sleep(time.Second)
-		t.Run("PushPipelineWhileRedialing-1", helpers.PushToPipe("test-1"))
-		time.Sleep(time.Second)
-		t.Run("PushPipelineWhileRedialing-2", helpers.PushToPipe("test-2"))
-	}()
+func TestDurabilityNATSHalfOpen(t *testing.T) {
+	ns := natsserver.Start(t)
+	proxy := faultproxy.Start(t, ns.Addr())
+	env := harness.NewEnv(t)
+	env.Set("nats", proxy.Addr())
 
-	time.Sleep(time.Second * 5)
-	helpers.EnableProxy("redial", t)
-	time.Sleep(time.Second * 2)
+	h := env.Start("configs/.rr-nats-durability-redial.yaml",
+		&server.Plugin{},
+		&rpcPlugin.Plugin{},
+		&logger.Plugin{},
+		&jobs.Plugin{},
+		&resetter.Plugin{},
+		&informer.Plugin{},
+		&nats.Plugin{},
+	)
 
-	t.Run("PushPipelineWhileRedialing-3", helpers.PushToPipe("test-1"))
-	t.Run("PushPipelineWhileRedialing-4", helpers.PushToPipe("test-2"))
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	// the connection stays open but no data flows in either direction, no FIN/RST is sent
+	helpers.AddTimeout(proxy, "blackhole-up", faultproxy.Upstream, 0, t)
+	helpers.AddTimeout(proxy, "blackhole-down", faultproxy.Downstream, 0, t)
+	time.Sleep(time.Second)
 
+	// the broker doesn't ack, the push times out or fails
+	t.Run("PushPipelineWhileHalfOpen-1", helpers.PushToPipeStalledAt(h.RPCAddr(), "test-1", time.Second*10))
+	t.Run("PushPipelineWhileHalfOpen-2", helpers.PushToPipeStalledAt(h.RPCAddr(), "test-2", time.Second*10))
+
+	// the stalled data flows again
+	helpers.RemoveToxic(proxy, "blackhole-up", t)
+	helpers.RemoveToxic(proxy, "blackhole-down", t)
 	time.Sleep(time.Second * 2)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	t.Run("PushPipelineAfterHalfOpen-1", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushPipelineAfterHalfOpen-2", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
+	streamsDrained(t, ns, "foo", "foo2")
 
-	t.Cleanup(func() {
-		helpers.DestroyPipelines("test-1", "test-2")
-	})
+	// half-open and then closed by the broker side, the driver should redial
+	helpers.AddTimeout(proxy, "timeout", faultproxy.Downstream, time.Second*3, t)
+	t.Run("PushPipelineWhileTimeout-1", helpers.PushToPipeStalledAt(h.RPCAddr(), "test-1", time.Second*5))
+	time.Sleep(time.Second * 5)
+	helpers.RemoveToxic(proxy, "timeout", t)
+	time.Sleep(time.Second * 5)
+
+	t.Run("PushPipelineAfterTimeout-1", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushPipelineAfterTimeout-2", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
+	streamsDrained(t, ns, "foo", "foo2")
+
+	t.Run("DestroyPipelines", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-1", "test-2"))
+
+	h.Stop()
+}
+
+func TestDurabilityNATSResetPeer(t *testing.T) {
+	ns := natsserver.Start(t)
+	proxy := faultproxy.Start(t, ns.Addr())
+	env := harness.NewEnv(t)
+	env.Set("nats", proxy.Addr())
+
+	h := env.Start("configs/.rr-nats-durability-redial.yaml",
+		&server.Plugin{},
+		&rpcPlugin.Plugin{},
+		&logger.Plugin{},
+		&jobs.Plugin{},
+		&resetter.Plugin{},
+		&informer.Plugin{},
+		&nats.Plugin{},
+	)
+
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	// the current and the redialed connections are reset by the peer
+	helpers.AddResetPeer(proxy, "reset", faultproxy.Downstream, time.Second, t)
+	time.Sleep(time.Second * 5)
+	helpers.RemoveToxic(proxy, "reset", t)
+	time.Sleep(time.Second * 5)
+
+	t.Run("PushPipelineAfterReset-1", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushPipelineAfterReset-2", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
+	streamsDrained(t, ns, "foo", "foo2")
+
+	t.Run("DestroyPipelines", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-1", "test-2"))
+
+	h.Stop()
 }
@@ -2,21 +2,11 @@ package general
 
 import (
 	"io/ioutil"
-	"net"
 	"net/http"
-	"net/rpc"
-	"os"
-	"os/signal"
-	"sync"
-	"syscall"
 	"testing"
 	"time"
 
 	"github.com/roadrunner-server/amqp/v2"
-	jobsv1beta "github.com/roadrunner-server/api/v2/proto/jobs/v1"
-	"github.com/roadrunner-server/config/v2"
-	endure "github.com/roadrunner-server/endure/pkg/container"
-	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
 	"github.com/roadrunner-server/informer/v2"
 	"github.com/roadrunner-server/jobs/v2"
 	"github.com/roadrunner-server/logger/v2"
@@ -24,22 +14,14 @@ import (
 	"github.com/roadrunner-server/metrics/v2"
 	"github.com/roadrunner-server/resetter/v2"
 	rpcPlugin "github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	helpers "github.com/roadrunner-server/rr-e2e-tests/plugins/jobs"
 	"github.com/roadrunner-server/server/v2"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestJobsInit(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-jobs-init.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-jobs-init.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -49,70 +31,15 @@ func TestJobsInit(t *testing.T) {
 		&memory.Plugin{},
 		&amqp.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
 	time.Sleep(time.Second * 5)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestJOBSMetrics(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	if err != nil {
-		t.Fatal(err)
-	}
+	env := harness.NewEnv(t)
 
-	cfg := &config.Plugin{}
-	cfg.Prefix = "rr"
-	cfg.Path = "configs/.rr-jobs-metrics.yaml"
-
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-jobs-metrics.yaml",
 		&rpcPlugin.Plugin{},
 		&server.Plugin{},
 		&jobs.Plugin{},
@@ -120,63 +47,19 @@ func TestJOBSMetrics(t *testing.T) {
 		&metrics.Plugin{},
 		&memory.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
+	harness.WaitForTCP(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	tt := time.NewTimer(time.Minute * 3)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	go func() {
-		defer tt.Stop()
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-tt.C:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 2)
-
-	t.Run("DeclareEphemeralPipeline", declareMemoryPipe)
-	t.Run("ConsumeEphemeralPipeline", consumeMemoryPipe)
-	t.Run("PushEphemeralPipeline", helpers.PushToPipe("test-3"))
+	t.Run("DeclareEphemeralPipeline", declareMemoryPipe(h.RPCAddr()))
+	t.Run("ConsumeEphemeralPipeline", consumeMemoryPipe(h.RPCAddr()))
+	t.Run("PushEphemeralPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second)
-	t.Run("PushEphemeralPipeline", helpers.PushToPipeDelayed("test-3", 5))
+	t.Run("PushEphemeralPipeline", helpers.PushToPipeDelayedAt(h.RPCAddr(), "test-3", 5))
 	time.Sleep(time.Second)
-	t.Run("PushEphemeralPipeline", helpers.PushToPipe("test-3"))
+	t.Run("PushEphemeralPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second * 5)
 
-	genericOut, err := get()
+	genericOut, err := get(env.Addr("metrics"))
 	assert.NoError(t, err)
 
 	assert.Contains(t, genericOut, `rr_jobs_jobs_err 0`)
@@ -188,16 +71,12 @@ func TestJOBSMetrics(t *testing.T) {
 	assert.Contains(t, genericOut, `{pid=`)
 	assert.Contains(t, genericOut, `rr_jobs_total_workers 1`)
 
-	close(sig)
-
-	wg.Wait()
+	h.Stop()
 }
 
-const getAddr = "http://127.0.0.1:2112/metrics"
-
 // get request and return body
-func get() (string, error) {
-	r, err := http.Get(getAddr)
+func get(addr string) (string, error) {
+	r, err := http.Get("http://" + addr + "/metrics")
 	if err != nil {
 		return "", err
 	}
@@ -215,31 +94,26 @@ func get() (string, error) {
 	return string(b), err
 }
 
-func declareMemoryPipe(t *testing.T) {
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
-	assert.NoError(t, err)
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
+func declareMemoryPipe(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
 
-	pipe := &jobsv1beta.DeclareRequest{Pipeline: map[string]string{
-		"driver":   "memory",
-		"name":     "test-3",
-		"prefetch": "10000",
-	}}
+		pipe := map[string]string{
+			"driver":   "memory",
+			"name":     "test-3",
+			"prefetch": "10000",
+		}
 
-	er := &jobsv1beta.Empty{}
-	err = client.Call("jobs.Declare", pipe, er)
-	assert.NoError(t, err)
+		err := client.Jobs.Declare(pipe)
+		assert.NoError(t, err)
+	}
 }
 
-func consumeMemoryPipe(t *testing.T) {
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
-	assert.NoError(t, err)
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-	pipe := &jobsv1beta.Pipelines{Pipelines: make([]string, 1)}
-	pipe.GetPipelines()[0] = "test-3"
+func consumeMemoryPipe(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
 
-	er := &jobsv1beta.Empty{}
-	err = client.Call("jobs.Resume", pipe, er)
-	assert.NoError(t, err)
+		err := client.Jobs.Resume("test-3")
+		assert.NoError(t, err)
+	}
 }
@@ -1,54 +1,30 @@
 package jobs
 
 import (
-	"bytes"
-	"net"
-	"net/http"
-	"net/rpc"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	jobState "github.com/roadrunner-server/api/v2/plugins/jobs"
 	jobsv1beta "github.com/roadrunner-server/api/v2/proto/jobs/v1"
-	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
+	"github.com/roadrunner-server/rr-e2e-tests/faultproxy"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-const (
-	push    string = "jobs.Push"
-	pause   string = "jobs.Pause"
-	destroy string = "jobs.Destroy"
-	resume  string = "jobs.Resume"
-	stat    string = "jobs.Stat"
-)
-
-func ResumePipes(pipes ...string) func(t *testing.T) {
+// ResumePipesAt resumes the pipelines via the RPC plugin listening on the addr
+func ResumePipesAt(addr string, pipes ...string) func(t *testing.T) {
 	return func(t *testing.T) {
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
-		require.NoError(t, err)
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-		pipe := &jobsv1beta.Pipelines{Pipelines: make([]string, len(pipes))}
-
-		for i := 0; i < len(pipes); i++ {
-			pipe.GetPipelines()[i] = pipes[i]
-		}
-
-		er := &jobsv1beta.Empty{}
-		err = client.Call(resume, pipe, er)
+		err := harness.Dial(t, addr).Jobs.Resume(pipes...)
 		require.NoError(t, err)
 	}
 }
 
-func PushToDisabledPipe(pipeline string) func(t *testing.T) {
+// PushToDisabledPipeAt pushes the job to the paused pipeline via the RPC plugin listening on the addr
+func PushToDisabledPipeAt(addr, pipeline string) func(t *testing.T) {
 	return func(t *testing.T) {
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
-		require.NoError(t, err)
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-		req := &jobsv1beta.PushRequest{Job: &jobsv1beta.Job{
+		err := harness.Dial(t, addr).Jobs.Push(&jobsv1beta.Job{
 			Job:     "some/php/namespace",
 			Id:      "1",
 			Payload: `{"hello":"world"}`,
@@ -57,21 +33,15 @@ func PushToDisabledPipe(pipeline string) func(t *testing.T) {
 				Priority: 1,
 				Pipeline: pipeline,
 			},
-		}}
-
-		er := &jobsv1beta.Empty{}
-		err = client.Call(push, req, er)
+		})
 		require.NoError(t, err)
 	}
 }
 
-func PushToPipe(pipeline string) func(t *testing.T) {
+// PushToPipeAt pushes the job to the pipeline via the RPC plugin listening on the addr
+func PushToPipeAt(addr, pipeline string) func(t *testing.T) {
 	return func(t *testing.T) {
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
-		require.NoError(t, err)
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-		req := &jobsv1beta.PushRequest{Job: &jobsv1beta.Job{
+		err := harness.Dial(t, addr).Jobs.Push(&jobsv1beta.Job{
 			Job:     "some/php/namespace",
 			Id:      uuid.NewString(),
 			Payload: `{"hello":"world"}`,
@@ -81,21 +51,15 @@ func PushToPipe(pipeline string) func(t *testing.T) {
 				Pipeline: pipeline,
 				Delay:    0,
 			},
-		}}
-
-		er := &jobsv1beta.Empty{}
-		err = client.Call(push, req, er)
+		})
 		require.NoError(t, err)
 	}
 }
 
-func PushToPipeDelayed(pipeline string, delay int64) func(t *testing.T) {
+// PushToPipeDelayedAt pushes the job with the delay (seconds) to the pipeline via the RPC plugin listening on the addr
+func PushToPipeDelayedAt(addr, pipeline string, delay int64) func(t *testing.T) {
 	return func(t *testing.T) {
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
-		assert.NoError(t, err)
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-		req := &jobsv1beta.PushRequest{Job: &jobsv1beta.Job{
+		err := harness.Dial(t, addr).Jobs.Push(&jobsv1beta.Job{
 			Job:     "some/php/namespace",
 			Id:      uuid.NewString(),
 			Payload: `{"hello":"world"}`,
@@ -105,21 +69,15 @@ func PushToPipeDelayed(pipeline string, delay int64) func(t *testing.T) {
 				Pipeline: pipeline,
 				Delay:    delay,
 			},
-		}}
-
-		er := &jobsv1beta.Empty{}
-		err = client.Call(push, req, er)
+		})
 		assert.NoError(t, err)
 	}
 }
 
-func PushToPipeErr(pipeline string) func(t *testing.T) {
+// PushToPipeErrAt pushes the job to the pipeline, the push must fail
+func PushToPipeErrAt(addr, pipeline string) func(t *testing.T) {
 	return func(t *testing.T) {
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
-		require.NoError(t, err)
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-		req := &jobsv1beta.PushRequest{Job: &jobsv1beta.Job{
+		err := harness.Dial(t, addr).Jobs.Push(&jobsv1beta.Job{
 			Job:     "some/php/namespace",
 			Id:      "1",
 			Payload: `{"hello":"world"}`,
@@ -129,46 +87,56 @@ func PushToPipeErr(pipeline string) func(t *testing.T) {
 				Pipeline: pipeline,
 				Delay:    0,
 			},
-		}}
-
-		er := &jobsv1beta.Empty{}
-		err = client.Call(push, req, er)
+		})
 		require.Error(t, err)
 	}
 }
-func PausePipelines(pipes ...string) func(t *testing.T) {
-	return func(t *testing.T) {
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
-		assert.NoError(t, err)
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
 
-		pipe := &jobsv1beta.Pipelines{Pipelines: make([]string, len(pipes))}
-
-		for i := 0; i < len(pipes); i++ {
-			pipe.GetPipelines()[i] = pipes[i]
+// PushToPipeStalledAt pushes the job to the pipeline with the stalled broker, the push must fail or not finish in
+// the timeout
+func PushToPipeStalledAt(addr, pipeline string, timeout time.Duration) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- client.Jobs.Push(&jobsv1beta.Job{
+				Job:     "some/php/namespace",
+				Id:      uuid.NewString(),
+				Payload: `{"hello":"world"}`,
+				Headers: map[string]*jobsv1beta.HeaderValue{"test": {Value: []string{"test2"}}},
+				Options: &jobsv1beta.Options{
+					Priority: 1,
+					Pipeline: pipeline,
+				},
+			})
+		}()
+
+		select {
+		case err := <-errCh:
+			require.Error(t, err)
+		case <-time.After(timeout):
+			// still waiting for the broker, the call is canceled with the connection close
 		}
-
-		er := &jobsv1beta.Empty{}
-		err = client.Call(pause, pipe, er)
-		assert.NoError(t, err)
 	}
 }
 
-func DestroyPipelines(pipes ...string) func(t *testing.T) {
+// PausePipelinesAt pauses the pipelines via the RPC plugin listening on the addr
+func PausePipelinesAt(addr string, pipes ...string) func(t *testing.T) {
 	return func(t *testing.T) {
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
+		err := harness.Dial(t, addr).Jobs.Pause(pipes...)
 		assert.NoError(t, err)
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-		pipe := &jobsv1beta.Pipelines{Pipelines: make([]string, len(pipes))}
+	}
+}
 
-		for i := 0; i < len(pipes); i++ {
-			pipe.GetPipelines()[i] = pipes[i]
-		}
+// DestroyPipelinesAt destroys the pipelines via the RPC plugin listening on the addr, retries up to 10 times
+func DestroyPipelinesAt(addr string, pipes ...string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
 
+		var err error
 		for i := 0; i < 10; i++ {
-			er := &jobsv1beta.Empty{}
-			err = client.Call(destroy, pipe, er)
+			err = client.Jobs.Destroy(pipes...)
 			if err != nil {
 				time.Sleep(time.Second)
 				continue
@@ -179,65 +147,54 @@ func DestroyPipelines(pipes ...string) func(t *testing.T) {
 	}
 }
 
-func EnableProxy(name string, t *testing.T) {
-	buf := new(bytes.Buffer)
-	buf.WriteString(`{"enabled":true}`)
-
-	resp, err := http.Post("http://127.0.0.1:8474/proxies/"+name, "application/json", buf) //nolint:noctx
-	require.NoError(t, err)
-	require.Equal(t, 200, resp.StatusCode)
-	if resp.Body != nil {
-		_ = resp.Body.Close()
-	}
+// AddLatency delays the data in the direction by latency +- jitter
+func AddLatency(p *faultproxy.Proxy, name string, dir faultproxy.Direction, latency, jitter time.Duration, t *testing.T) {
+	require.NoError(t, p.AddToxic(name, dir, faultproxy.Latency{Latency: latency, Jitter: jitter}))
 }
 
-func DisableProxy(name string, t *testing.T) {
-	buf := new(bytes.Buffer)
-	buf.WriteString(`{"enabled":false}`)
+// AddBandwidth limits the data in the direction to rate KB/s
+func AddBandwidth(p *faultproxy.Proxy, name string, dir faultproxy.Direction, rate int64, t *testing.T) {
+	require.NoError(t, p.AddToxic(name, dir, faultproxy.Bandwidth{Rate: rate}))
+}
 
-	resp, err := http.Post("http://127.0.0.1:8474/proxies/"+name, "application/json", buf) //nolint:noctx
-	require.NoError(t, err)
-	require.Equal(t, 200, resp.StatusCode)
-	if resp.Body != nil {
-		_ = resp.Body.Close()
-	}
+// AddTimeout stops the data in the direction and closes the connection after the timeout, zero keeps it half-open
+func AddTimeout(p *faultproxy.Proxy, name string, dir faultproxy.Direction, timeout time.Duration, t *testing.T) {
+	require.NoError(t, p.AddToxic(name, dir, faultproxy.Timeout{Timeout: timeout}))
 }
 
-func DeleteProxy(name string, t *testing.T) {
-	client := &http.Client{}
+// AddSlicer slices the data in the direction into the pieces of avg +- variation bytes with the delay between them
+func AddSlicer(p *faultproxy.Proxy, name string, dir faultproxy.Direction, avg, variation int, delay time.Duration, t *testing.T) {
+	require.NoError(t, p.AddToxic(name, dir, faultproxy.Slicer{AverageSize: avg, SizeVariation: variation, Delay: delay}))
+}
 
-	req, err := http.NewRequest(http.MethodDelete, "http://127.0.0.1:8474/proxies/"+name, nil) //nolint:noctx
-	require.NoError(t, err)
+// AddResetPeer resets the connections after the timeout
+func AddResetPeer(p *faultproxy.Proxy, name string, dir faultproxy.Direction, timeout time.Duration, t *testing.T) {
+	require.NoError(t, p.AddToxic(name, dir, faultproxy.ResetPeer{Timeout: timeout}))
+}
 
-	resp, err := client.Do(req)
-	require.NoError(t, err)
+// UpdateToxic replaces the toxic with the name keeping its direction
+func UpdateToxic(p *faultproxy.Proxy, name string, toxic faultproxy.Toxic, t *testing.T) {
+	require.NoError(t, p.UpdateToxic(name, toxic))
+}
 
-	require.NoError(t, err)
-	require.Equal(t, 204, resp.StatusCode)
-	if resp.Body != nil {
-		_ = resp.Body.Close()
-	}
+// RemoveToxic removes the toxic with the name from the proxy
+func RemoveToxic(p *faultproxy.Proxy, name string, t *testing.T) {
+	require.NoError(t, p.RemoveToxic(name))
 }
 
-func Stats(state *jobState.State) func(t *testing.T) {
+// StatsAt reads the stats of the first pipeline into the state
+func StatsAt(addr string, state *jobState.State) func(t *testing.T) {
 	return func(t *testing.T) {
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
-		require.NoError(t, err)
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-		st := &jobsv1beta.Stats{}
-		er := &jobsv1beta.Empty{}
-
-		err = client.Call(stat, er, st)
+		st, err := harness.Dial(t, addr).Jobs.Stat()
 		require.NoError(t, err)
-		require.NotNil(t, st)
-
-		state.Queue = st.Stats[0].Queue
-		state.Pipeline = st.Stats[0].Pipeline
-		state.Driver = st.Stats[0].Driver
-		state.Active = st.Stats[0].Active
-		state.Delayed = st.Stats[0].Delayed
-		state.Reserved = st.Stats[0].Reserved
-		state.Ready = st.Stats[0].Ready
+		require.NotEmpty(t, st)
+
+		state.Queue = st[0].Queue
+		state.Pipeline = st[0].Pipeline
+		state.Driver = st[0].Driver
+		state.Active = st[0].Active
+		state.Delayed = st[0].Delayed
+		state.Reserved = st[0].Reserved
+		state.Ready = st[0].Ready
 	}
 }
@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	jobsv1beta "github.com/roadrunner-server/api/v2/proto/jobs/v1"
+	"github.com/roadrunner-server/informer/v2"
+	"github.com/roadrunner-server/jobs/v2"
+	"github.com/roadrunner-server/memory/v2"
+	"github.com/roadrunner-server/resetter/v2"
+	rpcPlugin "github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/fakeworker"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
+	mocklogger "github.com/roadrunner-server/rr-e2e-tests/mock"
+	"github.com/roadrunner-server/rr-e2e-tests/rpcclient"
+	"github.com/roadrunner-server/server/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestMemoryFakeWorker(t *testing.T) {
+	t.Parallel()
+
+	env := harness.NewEnv(t)
+	env.Set("worker", fakeworker.Command("jobs"))
+	env.Set("record", filepath.Join(env.TempDir(), "records"))
+
+	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
+	h := env.Start("configs/.rr-memory-fakeworker.yaml",
+		&server.Plugin{},
+		&rpcPlugin.Plugin{},
+		l,
+		&jobs.Plugin{},
+		&resetter.Plugin{},
+		&informer.Plugin{},
+		&memory.Plugin{},
+	)
+
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+
+	client, err := rpcclient.Dial(h.RPCAddr())
+	require.NoError(t, err)
+	defer func() {
+		_ = client.Close()
+	}()
+
+	push := func(id string, headers map[string]string) {
+		job := &jobsv1beta.Job{
+			Job:     "some/php/namespace",
+			Id:      id,
+			Payload: `{"hello":"world"}`,
+			Headers: make(map[string]*jobsv1beta.HeaderValue, len(headers)),
+			Options: &jobsv1beta.Options{Pipeline: "test-1"},
+		}
+		for k, v := range headers {
+			job.Headers[k] = &jobsv1beta.HeaderValue{Value: []string{v}}
+		}
+
+		require.NoError(t, client.Jobs.Push(job))
+	}
+
+	push("ack", map[string]string{fakeworker.HeaderAction: fakeworker.ActionAck})
+	push("nack", map[string]string{fakeworker.HeaderAction: fakeworker.ActionNack})
+	push("requeue", map[string]string{
+		fakeworker.HeaderAction:      fakeworker.ActionRequeue,
+		fakeworker.HeaderMaxAttempts: "2",
+	})
+
+	// ack + nack + requeue, requeue, ack
+	var records []fakeworker.Record
+	harness.Eventually(t, harness.DefaultWaitTimeout, "5 processed jobs", func() (bool, error) {
+		records, err = fakeworker.ReadRecords(env.Var("record"))
+		if err != nil {
+			return false, err
+		}
+
+		return len(records) == 5, fmt.Errorf("%d jobs were processed", len(records))
+	})
+
+	attempts := make([]string, 0, 3)
+	for i := 0; i < len(records); i++ {
+		job, errJ := records[i].JobRequest()
+		require.NoError(t, errJ)
+
+		assert.Equal(t, "some/php/namespace", job.Job)
+		assert.Equal(t, "test-1", job.Pipeline)
+		assert.Equal(t, "memory", job.Driver)
+		assert.Equal(t, `{"hello":"world"}`, string(job.Payload))
+
+		if job.ID == "requeue" {
+			attempts = append(attempts, fmt.Sprint(job.Headers["attempts"]))
+		}
+	}
+
+	assert.Equal(t, []string{"[]", "[1]", "[2]"}, attempts)
+
+	h.Stop()
+
+	require.Equal(t, 5, oLogger.FilterMessageSnippet("job processing was started").Len())
+	require.Equal(t, 2, oLogger.FilterMessageSnippet("job was processed successfully").Len())
+}
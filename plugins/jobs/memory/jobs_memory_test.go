@@ -1,26 +1,19 @@
 package memory
 
 import (
-	"net"
-	"net/rpc"
-	"os"
-	"os/signal"
-	"sync"
-	"syscall"
 	"testing"
 	"time"
 
 	jobState "github.com/roadrunner-server/api/v2/plugins/jobs"
 	jobsv1beta "github.com/roadrunner-server/api/v2/proto/jobs/v1"
 	"github.com/roadrunner-server/config/v2"
-	endure "github.com/roadrunner-server/endure/pkg/container"
-	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
 	"github.com/roadrunner-server/informer/v2"
 	"github.com/roadrunner-server/jobs/v2"
 	"github.com/roadrunner-server/logger/v2"
 	"github.com/roadrunner-server/memory/v2"
 	"github.com/roadrunner-server/resetter/v2"
 	rpcPlugin "github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	mocklogger "github.com/roadrunner-server/rr-e2e-tests/mock"
 	helpers "github.com/roadrunner-server/rr-e2e-tests/plugins/jobs"
 	"github.com/roadrunner-server/server/v2"
@@ -30,17 +23,10 @@ import (
 )
 
 func TestMemoryInit(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-memory-init.yaml",
-		Prefix: "rr",
-	}
+	t.Parallel()
 
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-memory-init.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
@@ -49,73 +35,21 @@ func TestMemoryInit(t *testing.T) {
 		&informer.Plugin{},
 		&memory.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
 	time.Sleep(time.Second * 1)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("plugin was started").Len())
 }
 
 func TestMemoryInitV27(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
 	cfg := &config.Plugin{
 		Path:    "configs/.rr-memory-init-v27.yaml",
-		Prefix:  "rr",
 		Version: "2.7.0",
 	}
 
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.StartConfig(t, cfg,
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
@@ -124,60 +58,13 @@ func TestMemoryInitV27(t *testing.T) {
 		&informer.Plugin{},
 		&memory.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
+	require.NoError(t, oLogger.WaitForCount(mocklogger.MessageSnippet("job processing was started"), 2, time.Second*10))
 
-	time.Sleep(time.Second * 1)
-	t.Run("PushPipeline", helpers.PushToPipe("test-1"))
-	t.Run("PushPipeline", helpers.PushToPipe("test-2"))
-	time.Sleep(time.Second * 1)
-
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("pipeline was started").Len())
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("pipeline was stopped").Len())
@@ -186,17 +73,8 @@ func TestMemoryInitV27(t *testing.T) {
 }
 
 func TestMemoryInitV27BadResp(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-memory-init-v27-br.yaml",
-		Prefix: "rr",
-	}
-
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-memory-init-v27-br.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
@@ -205,76 +83,20 @@ func TestMemoryInitV27BadResp(t *testing.T) {
 		&informer.Plugin{},
 		&memory.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
-	time.Sleep(time.Second * 1)
-	t.Run("PushPipeline", helpers.PushToPipe("test-1"))
-	t.Run("PushPipeline", helpers.PushToPipe("test-2"))
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 	time.Sleep(time.Second * 1)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("response handler error").Len())
 }
 
 func TestMemoryCreate(t *testing.T) {
 	t.Skip("not for the CI")
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-memory-create.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-memory-create.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -283,71 +105,17 @@ func TestMemoryCreate(t *testing.T) {
 		&informer.Plugin{},
 		&memory.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 5)
-	t.Run("PushPipeline", helpers.PushToPipe("example"))
-	stopCh <- struct{}{}
-	wg.Wait()
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "example"))
+	h.Stop()
 }
 
 func TestMemoryDeclare(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-memory-declare.yaml",
-		Prefix: "rr",
-	}
+	t.Parallel()
 
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-memory-declare.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
@@ -356,65 +124,18 @@ func TestMemoryDeclare(t *testing.T) {
 		&informer.Plugin{},
 		&memory.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	t.Run("DeclarePipeline", declareMemoryPipe)
-	t.Run("ConsumePipeline", consumeMemoryPipe)
-	t.Run("PushPipeline", helpers.PushToPipe("test-3"))
+	t.Run("DeclarePipeline", declareMemoryPipe(h.RPCAddr()))
+	t.Run("ConsumePipeline", consumeMemoryPipe(h.RPCAddr()))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second)
-	t.Run("PausePipeline", helpers.PausePipelines("test-3"))
+	t.Run("PausePipeline", helpers.PausePipelinesAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second)
-	t.Run("DestroyPipeline", helpers.DestroyPipelines("test-3"))
+	t.Run("DestroyPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3"))
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("pipeline was resumed").Len())
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("pipeline was stopped").Len())
@@ -424,17 +145,8 @@ func TestMemoryDeclare(t *testing.T) {
 }
 
 func TestMemoryPauseResume(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-memory-pause-resume.yaml",
-		Prefix: "rr",
-	}
-
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-memory-pause-resume.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
@@ -444,63 +156,15 @@ func TestMemoryPauseResume(t *testing.T) {
 		&memory.Plugin{},
 	)
 
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	t.Run("Pause", helpers.PausePipelines("test-local"))
-	t.Run("pushToDisabledPipe", helpers.PushToDisabledPipe("test-local"))
-	t.Run("Resume", helpers.ResumePipes("test-local"))
-	t.Run("pushToEnabledPipe", helpers.PushToPipe("test-local"))
+	t.Run("Pause", helpers.PausePipelinesAt(h.RPCAddr(), "test-local"))
+	t.Run("pushToDisabledPipe", helpers.PushToDisabledPipeAt(h.RPCAddr(), "test-local"))
+	t.Run("Resume", helpers.ResumePipesAt(h.RPCAddr(), "test-local"))
+	t.Run("pushToEnabledPipe", helpers.PushToPipeAt(h.RPCAddr(), "test-local"))
 	time.Sleep(time.Second * 1)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("pipeline was resumed").Len())
 	require.Equal(t, 3, oLogger.FilterMessageSnippet("pipeline was stopped").Len())
@@ -510,17 +174,8 @@ func TestMemoryPauseResume(t *testing.T) {
 }
 
 func TestMemoryJobsError(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-memory-jobs-err.yaml",
-		Prefix: "rr",
-	}
-
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-memory-jobs-err.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
@@ -529,65 +184,18 @@ func TestMemoryJobsError(t *testing.T) {
 		&informer.Plugin{},
 		&memory.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
-
-	t.Run("DeclarePipeline", declareMemoryPipe)
-	t.Run("ConsumePipeline", helpers.ResumePipes("test-3"))
-	t.Run("PushPipeline", helpers.PushToPipe("test-3"))
+	t.Run("DeclarePipeline", declareMemoryPipe(h.RPCAddr()))
+	t.Run("ConsumePipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second * 25)
-	t.Run("PausePipeline", helpers.PausePipelines("test-3"))
+	t.Run("PausePipeline", helpers.PausePipelinesAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second)
-	t.Run("DestroyPipeline", helpers.DestroyPipelines("test-3"))
+	t.Run("DestroyPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3"))
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("job was pushed successfully").Len())
 	require.Equal(t, 4, oLogger.FilterMessageSnippet("job processing was started").Len())
@@ -599,17 +207,10 @@ func TestMemoryJobsError(t *testing.T) {
 }
 
 func TestMemoryStats(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-memory-declare.yaml",
-		Prefix: "rr",
-	}
+	t.Parallel()
 
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-memory-declare.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
@@ -618,68 +219,25 @@ func TestMemoryStats(t *testing.T) {
 		&informer.Plugin{},
 		&memory.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
-
-	t.Run("DeclarePipeline", declareMemoryPipe)
-	t.Run("ConsumePipeline", consumeMemoryPipe)
-	t.Run("PushPipeline", helpers.PushToPipe("test-3"))
+	t.Run("DeclarePipeline", declareMemoryPipe(h.RPCAddr()))
+	t.Run("ConsumePipeline", consumeMemoryPipe(h.RPCAddr()))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second)
-	t.Run("PausePipeline", helpers.PausePipelines("test-3"))
+	t.Run("PausePipeline", helpers.PausePipelinesAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second)
 
-	t.Run("PushPipeline", helpers.PushToPipeDelayed("test-3", 5))
-	t.Run("PushPipeline", helpers.PushToPipe("test-3"))
+	t.Run("PushPipeline", helpers.PushToPipeDelayedAt(h.RPCAddr(), "test-3", 5))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
+
+	harness.WaitForJobsStat(t, h.RPCAddr(), time.Second*5, harness.PipelineStat("test-3", func(st *jobsv1beta.Stat) bool {
+		return st.GetActive() == 1 && st.GetDelayed() == 1
+	}))
 
-	time.Sleep(time.Second)
 	out := &jobState.State{}
-	t.Run("Stats", helpers.Stats(out))
+	t.Run("Stats", helpers.StatsAt(h.RPCAddr(), out))
 
 	assert.Equal(t, out.Pipeline, "test-3")
 	assert.Equal(t, out.Driver, "memory")
@@ -690,11 +248,14 @@ func TestMemoryStats(t *testing.T) {
 	assert.Equal(t, out.Reserved, int64(0))
 
 	time.Sleep(time.Second)
-	t.Run("ConsumePipeline", consumeMemoryPipe)
-	time.Sleep(time.Second * 7)
+	t.Run("ConsumePipeline", consumeMemoryPipe(h.RPCAddr()))
+
+	harness.WaitForJobsStat(t, h.RPCAddr(), time.Second*15, harness.PipelineStat("test-3", func(st *jobsv1beta.Stat) bool {
+		return st.GetActive() == 0 && st.GetDelayed() == 0
+	}))
 
 	out = &jobState.State{}
-	t.Run("Stats", helpers.Stats(out))
+	t.Run("Stats", helpers.StatsAt(h.RPCAddr(), out))
 
 	assert.Equal(t, out.Pipeline, "test-3")
 	assert.Equal(t, out.Driver, "memory")
@@ -704,10 +265,9 @@ func TestMemoryStats(t *testing.T) {
 	assert.Equal(t, out.Delayed, int64(0))
 	assert.Equal(t, out.Reserved, int64(0))
 
-	t.Run("DestroyEphemeralPipeline", helpers.DestroyPipelines("test-3"))
+	t.Run("DestroyEphemeralPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3"))
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 3, oLogger.FilterMessageSnippet("job was pushed successfully").Len())
 	require.Equal(t, 3, oLogger.FilterMessageSnippet("job processing was started").Len())
@@ -715,33 +275,35 @@ func TestMemoryStats(t *testing.T) {
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("pipeline was paused").Len())
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("pipeline was resumed").Len())
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("pipeline was stopped").Len())
+
+	require.NoError(t, oLogger.Sequence(
+		mocklogger.Entry("pipeline was resumed"),
+		mocklogger.Entry("pipeline was paused"),
+		mocklogger.Entry("pipeline was resumed"),
+		mocklogger.Entry("pipeline was stopped"),
+	))
 }
 
-func declareMemoryPipe(t *testing.T) {
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
-	assert.NoError(t, err)
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
+func declareMemoryPipe(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
 
-	pipe := &jobsv1beta.DeclareRequest{Pipeline: map[string]string{
-		"driver":   "memory",
-		"name":     "test-3",
-		"prefetch": "10000",
-	}}
+		pipe := map[string]string{
+			"driver":   "memory",
+			"name":     "test-3",
+			"prefetch": "10000",
+		}
 
-	er := &jobsv1beta.Empty{}
-	err = client.Call("jobs.Declare", pipe, er)
-	assert.NoError(t, err)
+		err := client.Jobs.Declare(pipe)
+		assert.NoError(t, err)
+	}
 }
 
-func consumeMemoryPipe(t *testing.T) {
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
-	assert.NoError(t, err)
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
+func consumeMemoryPipe(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
 
-	pipe := &jobsv1beta.Pipelines{Pipelines: make([]string, 1)}
-	pipe.GetPipelines()[0] = "test-3"
-
-	er := &jobsv1beta.Empty{}
-	err = client.Call("jobs.Resume", pipe, er)
-	assert.NoError(t, err)
+		err := client.Jobs.Resume("test-3")
+		assert.NoError(t, err)
+	}
 }
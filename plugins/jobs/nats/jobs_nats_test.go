@@ -1,12 +1,6 @@
 package nats
 
 import (
-	"net"
-	"net/rpc"
-	"os"
-	"os/signal"
-	"sync"
-	"syscall"
 	"testing"
 	"time"
 
@@ -14,14 +8,15 @@ import (
 	jobsv1beta "github.com/roadrunner-server/api/v2/proto/jobs/v1"
 	"github.com/roadrunner-server/config/v2"
 	endure "github.com/roadrunner-server/endure/pkg/container"
-	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
 	"github.com/roadrunner-server/informer/v2"
 	"github.com/roadrunner-server/jobs/v2"
 	"github.com/roadrunner-server/logger/v2"
 	"github.com/roadrunner-server/nats/v2"
 	"github.com/roadrunner-server/resetter/v2"
 	rpcPlugin "github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	mocklogger "github.com/roadrunner-server/rr-e2e-tests/mock"
+	"github.com/roadrunner-server/rr-e2e-tests/natsserver"
 	helpers "github.com/roadrunner-server/rr-e2e-tests/plugins/jobs"
 	"github.com/roadrunner-server/server/v2"
 	"github.com/stretchr/testify/assert"
@@ -30,16 +25,10 @@ import (
 )
 
 func TestNATSInit(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
+	env.Set("nats", natsserver.Start(t).URL())
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-nats-init.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-nats-init.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -48,70 +37,21 @@ func TestNATSInit(t *testing.T) {
 		&informer.Plugin{},
 		&nats.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
 	time.Sleep(time.Second * 3)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestNATSInitV27(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
+	env.Set("nats", natsserver.Start(t).URL())
 
 	cfg := &config.Plugin{
 		Path:    "configs/.rr-nats-init-v27.yaml",
-		Prefix:  "rr",
 		Version: "2.7.0",
 	}
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.StartConfig(cfg,
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -120,75 +60,26 @@ func TestNATSInitV27(t *testing.T) {
 		&informer.Plugin{},
 		&nats.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
-	time.Sleep(time.Second * 3)
-	t.Run("PushPipeline", helpers.PushToPipe("test-1"))
-	t.Run("PushPipeline", helpers.PushToPipe("test-2"))
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 	time.Sleep(time.Second)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestNATSInitV27BadResp(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
+	env.Set("nats", natsserver.Start(t).URL())
 
+	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
 	cfg := &config.Plugin{
 		Path:    "configs/.rr-nats-init-v27-br.yaml",
-		Prefix:  "rr",
 		Version: "2.7.0",
 	}
 
-	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := env.StartConfig(cfg,
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
@@ -197,75 +88,22 @@ func TestNATSInitV27BadResp(t *testing.T) {
 		&informer.Plugin{},
 		&nats.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
-	time.Sleep(time.Second * 3)
-	t.Run("PushPipeline", helpers.PushToPipe("test-1"))
-	t.Run("PushPipeline", helpers.PushToPipe("test-2"))
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 	time.Sleep(time.Second * 2)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("response handler error").Len())
 }
 
 func TestNATSDeclare(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
+	env.Set("nats", natsserver.Start(t).URL())
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-nats-declare.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-nats-declare.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -274,78 +112,25 @@ func TestNATSDeclare(t *testing.T) {
 		&informer.Plugin{},
 		&nats.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	t.Run("DeclarePipeline", declareNATSPipe)
-	t.Run("ConsumePipeline", helpers.ResumePipes("test-3"))
-	t.Run("PushPipeline", helpers.PushToPipe("test-3"))
+	t.Run("DeclarePipeline", declareNATSPipe(h.RPCAddr()))
+	t.Run("ConsumePipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second)
-	t.Run("PausePipeline", helpers.PausePipelines("test-3"))
+	t.Run("PausePipeline", helpers.PausePipelinesAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second)
-	t.Run("DestroyPipeline", helpers.DestroyPipelines("test-3"))
+	t.Run("DestroyPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3"))
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestNATSJobsError(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-nats-jobs-err.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
+	env.Set("nats", natsserver.Start(t).URL())
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-nats-jobs-err.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -354,78 +139,25 @@ func TestNATSJobsError(t *testing.T) {
 		&informer.Plugin{},
 		&nats.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
-
-	t.Run("DeclarePipeline", declareNATSPipe)
-	t.Run("ConsumePipeline", helpers.ResumePipes("test-3"))
-	t.Run("PushPipeline", helpers.PushToPipe("test-3"))
+	t.Run("DeclarePipeline", declareNATSPipe(h.RPCAddr()))
+	t.Run("ConsumePipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second * 25)
-	t.Run("PausePipeline", helpers.PausePipelines("test-3"))
-	t.Run("DestroyPipeline", helpers.DestroyPipelines("test-3"))
+	t.Run("PausePipeline", helpers.PausePipelinesAt(h.RPCAddr(), "test-3"))
+	t.Run("DestroyPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3"))
 
 	time.Sleep(time.Second * 5)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestNATSRespond(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-nats-respond.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
+	env.Set("nats", natsserver.Start(t).URL())
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-nats-respond.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -434,71 +166,26 @@ func TestNATSRespond(t *testing.T) {
 		&informer.Plugin{},
 		&nats.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
-	time.Sleep(time.Second * 3)
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	t.Run("DeclarePipeline", declareNATSPipe)
-	t.Run("ConsumePipeline", helpers.ResumePipes("test-3"))
-	t.Run("PushPipeline", helpers.PushToPipe("test-3"))
+	t.Run("DeclarePipeline", declareNATSPipe(h.RPCAddr()))
+	t.Run("ConsumePipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second)
-	t.Run("DestroyPipeline", helpers.DestroyPipelines("test-3"))
+	t.Run("DestroyPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3"))
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestNATSNoGlobalSection(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
 	assert.NoError(t, err)
 
 	cfg := &config.Plugin{
-		Path:   "configs/.rr-no-global.yaml",
+		Path:   env.Render("configs/.rr-no-global.yaml"),
 		Prefix: "rr",
 	}
 
@@ -524,16 +211,10 @@ func TestNATSNoGlobalSection(t *testing.T) {
 }
 
 func TestNATSStats(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-nats-stat.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
+	env.Set("nats", natsserver.Start(t).URL())
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-nats-stat.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -542,65 +223,19 @@ func TestNATSStats(t *testing.T) {
 		&informer.Plugin{},
 		&nats.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	time.Sleep(time.Second * 3)
-
-	t.Run("DeclarePipeline", declareNATSPipe)
-	t.Run("ConsumePipeline", helpers.ResumePipes("test-3"))
-	t.Run("PushPipeline", helpers.PushToPipe("test-3"))
+	t.Run("DeclarePipeline", declareNATSPipe(h.RPCAddr()))
+	t.Run("ConsumePipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second * 2)
-	t.Run("PausePipeline", helpers.PausePipelines("test-3"))
+	t.Run("PausePipeline", helpers.PausePipelinesAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second * 2)
-	t.Run("PushPipeline", helpers.PushToPipe("test-3"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
 
 	out := &jobState.State{}
-	t.Run("Stats", helpers.Stats(out))
+	t.Run("Stats", helpers.StatsAt(h.RPCAddr(), out))
 
 	assert.Equal(t, "test-3", out.Pipeline)
 	assert.Equal(t, "nats", out.Driver)
@@ -612,11 +247,13 @@ func TestNATSStats(t *testing.T) {
 	assert.Equal(t, false, out.Ready)
 
 	time.Sleep(time.Second)
-	t.Run("ResumePipeline", helpers.ResumePipes("test-3"))
-	time.Sleep(time.Second * 7)
+	t.Run("ResumePipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	harness.WaitForJobsStat(t, h.RPCAddr(), time.Second*15, harness.PipelineStat("test-3", func(st *jobsv1beta.Stat) bool {
+		return st.GetActive() == 0 && st.GetDelayed() == 0 && st.GetReserved() == 0 && st.GetReady()
+	}))
 
 	out = &jobState.State{}
-	t.Run("Stats", helpers.Stats(out))
+	t.Run("Stats", helpers.StatsAt(h.RPCAddr(), out))
 
 	assert.Equal(t, out.Pipeline, "test-3")
 	assert.Equal(t, out.Driver, "nats")
@@ -628,29 +265,27 @@ func TestNATSStats(t *testing.T) {
 	assert.Equal(t, true, out.Ready)
 
 	time.Sleep(time.Second)
-	t.Run("DestroyPipeline", helpers.DestroyPipelines("test-3"))
+	t.Run("DestroyPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3"))
 
 	time.Sleep(time.Second * 5)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
-func declareNATSPipe(t *testing.T) {
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
-	require.NoError(t, err)
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-	pipe := &jobsv1beta.DeclareRequest{Pipeline: map[string]string{
-		"driver":      "nats",
-		"name":        "test-3",
-		"subject":     "default",
-		"stream":      "foo",
-		"deliver_new": "true",
-		"prefetch":    "100",
-		"priority":    "3",
-	}}
-
-	er := &jobsv1beta.Empty{}
-	err = client.Call("jobs.Declare", pipe, er)
-	require.NoError(t, err)
+func declareNATSPipe(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
+
+		pipe := map[string]string{
+			"driver":      "nats",
+			"name":        "test-3",
+			"subject":     "default",
+			"stream":      "foo",
+			"deliver_new": "true",
+			"prefetch":    "100",
+			"priority":    "3",
+		}
+
+		err := client.Jobs.Declare(pipe)
+		require.NoError(t, err)
+	}
 }
@@ -1,12 +1,6 @@
 package sqs
 
 import (
-	"net"
-	"net/rpc"
-	"os"
-	"os/signal"
-	"sync"
-	"syscall"
 	"testing"
 	"time"
 
@@ -14,12 +8,13 @@ import (
 	jobsv1beta "github.com/roadrunner-server/api/v2/proto/jobs/v1"
 	"github.com/roadrunner-server/config/v2"
 	endure "github.com/roadrunner-server/endure/pkg/container"
-	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
 	"github.com/roadrunner-server/informer/v2"
 	"github.com/roadrunner-server/jobs/v2"
 	"github.com/roadrunner-server/logger/v2"
 	"github.com/roadrunner-server/resetter/v2"
 	rpcPlugin "github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/fakesqs"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	mocklogger "github.com/roadrunner-server/rr-e2e-tests/mock"
 	helpers "github.com/roadrunner-server/rr-e2e-tests/plugins/jobs"
 	"github.com/roadrunner-server/server/v2"
@@ -30,16 +25,11 @@ import (
 )
 
 func TestSQSInit(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-sqs-init.yaml",
-		Prefix: "rr",
-	}
+	srv := fakesqs.Start(t)
+	env := harness.NewEnv(t)
+	env.Set("sqs", srv.URL())
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-sqs-init.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -48,73 +38,26 @@ func TestSQSInit(t *testing.T) {
 		&informer.Plugin{},
 		&sqs.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
-	time.Sleep(time.Second * 3)
-	t.Run("PushPipeline", helpers.PushToPipe("test-1"))
-	t.Run("PushPipeline", helpers.PushToPipe("test-2"))
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 	time.Sleep(time.Second * 2)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
+
+	assert.Equal(t, []string{"default", "default-2"}, srv.Queues())
 }
 
 func TestSQSInitV27(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
+	env.Set("sqs", fakesqs.Start(t).URL())
 
 	cfg := &config.Plugin{
 		Path:    "configs/.rr-sqs-init-v27.yaml",
-		Prefix:  "rr",
 		Version: "2.7.0",
 	}
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.StartConfig(cfg,
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -123,74 +66,25 @@ func TestSQSInitV27(t *testing.T) {
 		&informer.Plugin{},
 		&sqs.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
-	t.Run("PushPipeline", helpers.PushToPipe("test-1"))
-	t.Run("PushPipeline", helpers.PushToPipe("test-2"))
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 	time.Sleep(time.Second)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestSQSInitV27Attributes(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
+	env.Set("sqs", fakesqs.Start(t).URL())
 
 	cfg := &config.Plugin{
 		Path:    "configs/.rr-sqs-attr.yaml",
-		Prefix:  "rr",
 		Version: "2.7.6",
 	}
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.StartConfig(cfg,
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -199,75 +93,26 @@ func TestSQSInitV27Attributes(t *testing.T) {
 		&informer.Plugin{},
 		&sqs.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
-	t.Run("PushPipeline", helpers.PushToPipe("test-1"))
-	t.Run("PushPipeline", helpers.PushToPipe("test-1"))
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
 	time.Sleep(time.Second)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestSQSInitV27BadResp(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
+	env.Set("sqs", fakesqs.Start(t).URL())
 
 	cfg := &config.Plugin{
 		Path:    "configs/.rr-sqs-init-v27-br.yaml",
-		Prefix:  "rr",
 		Version: "2.7.0",
 	}
 
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := env.StartConfig(cfg,
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
@@ -276,75 +121,22 @@ func TestSQSInitV27BadResp(t *testing.T) {
 		&informer.Plugin{},
 		&sqs.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
-	t.Run("PushPipeline", helpers.PushToPipe("test-1"))
-	t.Run("PushPipeline", helpers.PushToPipe("test-2"))
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-1"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-2"))
 	time.Sleep(time.Second)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.GreaterOrEqual(t, oLogger.FilterMessageSnippet("response handler error").Len(), 2)
 }
 
 func TestSQSDeclare(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
+	env.Set("sqs", fakesqs.Start(t).URL())
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-sqs-declare.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-sqs-declare.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -353,79 +145,26 @@ func TestSQSDeclare(t *testing.T) {
 		&informer.Plugin{},
 		&sqs.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	time.Sleep(time.Second * 3)
-
-	t.Run("DeclarePipeline", declareSQSPipe("default"))
-	t.Run("ConsumePipeline", helpers.ResumePipes("test-3"))
-	t.Run("PushPipeline", helpers.PushToPipe("test-3"))
+	t.Run("DeclarePipeline", declareSQSPipe(h.RPCAddr(), "default"))
+	t.Run("ConsumePipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second)
-	t.Run("PausePipeline", helpers.PausePipelines("test-3"))
+	t.Run("PausePipeline", helpers.PausePipelinesAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second)
-	t.Run("DestroyPipeline", helpers.DestroyPipelines("test-3"))
+	t.Run("DestroyPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3"))
 
 	time.Sleep(time.Second * 5)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestSQSJobsError(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-sqs-jobs-err.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
+	env.Set("sqs", fakesqs.Start(t).URL())
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-sqs-jobs-err.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -434,76 +173,31 @@ func TestSQSJobsError(t *testing.T) {
 		&informer.Plugin{},
 		&sqs.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	time.Sleep(time.Second * 3)
-
-	t.Run("DeclarePipeline", declareSQSPipe("default"))
-	t.Run("ConsumePipeline", helpers.ResumePipes("test-3"))
-	t.Run("PushPipeline", helpers.PushToPipe("test-3"))
+	t.Run("DeclarePipeline", declareSQSPipe(h.RPCAddr(), "default"))
+	t.Run("ConsumePipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second * 25)
-	t.Run("PausePipeline", helpers.PausePipelines("test-3"))
+	t.Run("PausePipeline", helpers.PausePipelinesAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second)
-	t.Run("DestroyPipeline", helpers.DestroyPipelines("test-3"))
+	t.Run("DestroyPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3"))
 
 	time.Sleep(time.Second * 5)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	time.Sleep(time.Second * 5)
 }
 
 func TestSQSNoGlobalSection(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
 	assert.NoError(t, err)
 
 	cfg := &config.Plugin{
-		Path:   "configs/.rr-no-global.yaml",
+		Path:   env.Render("configs/.rr-no-global.yaml"),
 		Prefix: "rr",
 	}
 
@@ -529,16 +223,11 @@ func TestSQSNoGlobalSection(t *testing.T) {
 }
 
 func TestSQSStat(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	srv := fakesqs.Start(t)
+	env := harness.NewEnv(t)
+	env.Set("sqs", srv.URL())
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-sqs-declare.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-sqs-declare.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -547,110 +236,62 @@ func TestSQSStat(t *testing.T) {
 		&informer.Plugin{},
 		&sqs.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
-	time.Sleep(time.Second * 3)
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	t.Run("DeclarePipeline", declareSQSPipe("default-stat"))
-	t.Run("ConsumePipeline", helpers.ResumePipes("test-3"))
-	t.Run("PushPipeline", helpers.PushToPipe("test-3"))
+	t.Run("DeclarePipeline", declareSQSPipe(h.RPCAddr(), "default-stat"))
+	t.Run("ConsumePipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second)
-	t.Run("PausePipeline", helpers.PausePipelines("test-3"))
+	t.Run("PausePipeline", helpers.PausePipelinesAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second)
 
-	t.Run("PushPipelineDelayed", helpers.PushToPipeDelayed("test-3", 5))
-	t.Run("PushPipeline", helpers.PushToPipe("test-3"))
-	time.Sleep(time.Second)
+	t.Run("PushPipelineDelayed", helpers.PushToPipeDelayedAt(h.RPCAddr(), "test-3", 5))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
+
+	harness.WaitForJobsStat(t, h.RPCAddr(), time.Second*5, harness.PipelineStat("test-3", func(st *jobsv1beta.Stat) bool {
+		return st.GetActive() > 0 && st.GetDelayed() > 0
+	}))
 
 	out := &jobState.State{}
-	t.Run("Stats", helpers.Stats(out))
+	t.Run("Stats", helpers.StatsAt(h.RPCAddr(), out))
 
 	assert.Equal(t, out.Pipeline, "test-3")
 	assert.Equal(t, out.Driver, "sqs")
-	assert.Equal(t, out.Queue, "https://sqs.us-east-1.amazonaws.com/588160034479/default-stat")
+	assert.Equal(t, out.Queue, srv.QueueURL("default-stat"))
 
 	assert.Greater(t, out.Active, int64(0))
 	assert.Greater(t, out.Delayed, int64(0))
 	assert.Equal(t, int64(0), out.Reserved)
 
 	time.Sleep(time.Second)
-	t.Run("ResumePipeline", helpers.ResumePipes("test-3"))
-	time.Sleep(time.Second * 7)
+	t.Run("ResumePipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	harness.WaitForJobsStat(t, h.RPCAddr(), time.Second*15, harness.PipelineStat("test-3", func(st *jobsv1beta.Stat) bool {
+		return st.GetReserved() == 0
+	}))
 
 	out = &jobState.State{}
-	t.Run("Stats", helpers.Stats(out))
+	t.Run("Stats", helpers.StatsAt(h.RPCAddr(), out))
 
 	assert.Equal(t, out.Pipeline, "test-3")
 	assert.Equal(t, out.Driver, "sqs")
-	assert.Equal(t, out.Queue, "https://sqs.us-east-1.amazonaws.com/588160034479/default-stat")
+	assert.Equal(t, out.Queue, srv.QueueURL("default-stat"))
 
 	assert.GreaterOrEqual(t, out.Active, int64(0))
 	assert.GreaterOrEqual(t, out.Delayed, int64(0))
 	assert.Equal(t, int64(0), out.Reserved)
 
-	t.Run("DestroyPipeline", helpers.DestroyPipelines("test-3"))
+	t.Run("DestroyPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3"))
 
 	time.Sleep(time.Second * 5)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestSQSRespond(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
+	env.Set("sqs", fakesqs.Start(t).URL())
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-sqs-respond.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-sqs-respond.yaml",
 		&server.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
@@ -659,74 +300,25 @@ func TestSQSRespond(t *testing.T) {
 		&informer.Plugin{},
 		&sqs.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 3)
-
-	t.Run("DeclarePipeline", declareSQSPipe("default"))
-	t.Run("ConsumePipeline", helpers.ResumePipes("test-3"))
-	t.Run("PushPipeline", helpers.PushToPipe("test-3"))
+	t.Run("DeclarePipeline", declareSQSPipe(h.RPCAddr(), "default"))
+	t.Run("ConsumePipeline", helpers.ResumePipesAt(h.RPCAddr(), "test-3"))
+	t.Run("PushPipeline", helpers.PushToPipeAt(h.RPCAddr(), "test-3"))
 	time.Sleep(time.Second)
-	t.Run("DestroyPipeline", helpers.DestroyPipelines("test-3"))
-	t.Run("DestroyPipeline", helpers.DestroyPipelines("test-1"))
+	t.Run("DestroyPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-3"))
+	t.Run("DestroyPipeline", helpers.DestroyPipelinesAt(h.RPCAddr(), "test-1"))
 
 	time.Sleep(time.Second * 5)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
-func declareSQSPipe(queue string) func(t *testing.T) {
+func declareSQSPipe(addr, queue string) func(t *testing.T) {
 	return func(t *testing.T) {
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
-		assert.NoError(t, err)
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
+		client := harness.Dial(t, addr)
 
-		pipe := &jobsv1beta.DeclareRequest{Pipeline: map[string]string{
+		pipe := map[string]string{
 			"driver":             "sqs",
 			"name":               "test-3",
 			"queue":              queue,
@@ -735,21 +327,18 @@ func declareSQSPipe(queue string) func(t *testing.T) {
 			"visibility_timeout": "0",
 			"wait_time_seconds":  "3",
 			"tags":               `{"key":"value"}`,
-		}}
+		}
 
-		er := &jobsv1beta.Empty{}
-		err = client.Call("jobs.Declare", pipe, er)
+		err := client.Jobs.Declare(pipe)
 		assert.NoError(t, err)
 	}
 }
 
-func declareSQSPipeFifo(queue string) func(t *testing.T) {
+func declareSQSPipeFifo(addr, queue string) func(t *testing.T) {
 	return func(t *testing.T) {
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
-		assert.NoError(t, err)
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
+		client := harness.Dial(t, addr)
 
-		pipe := &jobsv1beta.DeclareRequest{Pipeline: map[string]string{
+		pipe := map[string]string{
 			"driver":             "sqs",
 			"name":               "test-3",
 			"queue":              queue,
@@ -760,10 +349,9 @@ func declareSQSPipeFifo(queue string) func(t *testing.T) {
 			"wait_time_seconds":  "3",
 			"attributes":         `{"FifoQueue":"true"}`,
 			"tags":               `{"key":"value"}`,
-		}}
+		}
 
-		er := &jobsv1beta.Empty{}
-		err = client.Call("jobs.Declare", pipe, er)
+		err := client.Jobs.Declare(pipe)
 		assert.NoError(t, err)
 	}
 }
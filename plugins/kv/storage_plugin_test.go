@@ -1,26 +1,22 @@
 package kv
 
 import (
-	"net"
-	"net/rpc"
 	"os"
-	"os/signal"
-	"sync"
-	"syscall"
 	"testing"
 	"time"
 
 	payload "github.com/roadrunner-server/api/v2/proto/kv/v1"
 	"github.com/roadrunner-server/boltdb/v2"
-	"github.com/roadrunner-server/config/v2"
-	endure "github.com/roadrunner-server/endure/pkg/container"
-	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
+	"github.com/roadrunner-server/informer/v2"
 	"github.com/roadrunner-server/kv/v2"
 	"github.com/roadrunner-server/logger/v2"
 	"github.com/roadrunner-server/memcached/v2"
 	"github.com/roadrunner-server/memory/v2"
 	"github.com/roadrunner-server/redis/v2"
 	rpcPlugin "github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/fakememcached"
+	"github.com/roadrunner-server/rr-e2e-tests/fakeredis"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	mock_logger "github.com/roadrunner-server/rr-e2e-tests/mock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -28,16 +24,11 @@ import (
 )
 
 func TestKVInit(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-kv-init.yaml",
-		Prefix: "rr",
-	}
+	env.Set("memcached", fakememcached.Start(t).Addr())
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-kv-init.yaml",
 		&memory.Plugin{},
 		&boltdb.Plugin{},
 		&memcached.Plugin{},
@@ -46,1474 +37,459 @@ func TestKVInit(t *testing.T) {
 		&logger.Plugin{},
 		&kv.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
+	harness.WaitForTCP(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("KvSetTest", kvSetTest(h.RPCAddr()))
+	t.Run("KvHasTest", kvHasTest(h.RPCAddr()))
 
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-	t.Run("KvSetTest", kvSetTest)
-	t.Run("KvHasTest", kvHasTest)
-
-	stopCh <- struct{}{}
-
-	wg.Wait()
+	h.Stop()
 
 	_ = os.RemoveAll("rr.db")
 	_ = os.RemoveAll("africa.db")
 }
 
 func TestKVNoInterval(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-kv-bolt-no-interval.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-kv-bolt-no-interval.yaml",
 		&boltdb.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&kv.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-	t.Run("KvSetTest", kvSetTest)
-	t.Run("KvHasTest", kvHasTest)
 
-	stopCh <- struct{}{}
+	harness.WaitForTCP(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("KvSetTest", kvSetTest(h.RPCAddr()))
+	t.Run("KvHasTest", kvHasTest(h.RPCAddr()))
 
-	wg.Wait()
+	h.Stop()
 
 	_ = os.RemoveAll("rr.db")
 	_ = os.RemoveAll("africa.db")
 }
 
 func TestKVCreateToReopenWithPerms(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-kv-bolt-perms.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-kv-bolt-perms.yaml",
 		&boltdb.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&kv.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
 	time.Sleep(time.Second * 1)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestKVCreateToReopenWithPerms2(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-kv-bolt-perms.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-kv-bolt-perms.yaml",
 		&boltdb.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&kv.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-	t.Run("KvSetTest", kvSetTest)
-	t.Run("KvHasTest", kvHasTest)
 
-	stopCh <- struct{}{}
+	harness.WaitForTCP(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("KvSetTest", kvSetTest(h.RPCAddr()))
+	t.Run("KvHasTest", kvHasTest(h.RPCAddr()))
 
-	wg.Wait()
+	h.Stop()
 
 	_ = os.RemoveAll("rr.db")
 	_ = os.RemoveAll("africa.db")
 }
 
-func kvSetTest(t *testing.T) {
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
-	assert.NoError(t, err)
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-	// WorkerList contains list of workers.
-	p := &payload.Request{
-		Storage: "boltdb-south",
-		Items: []*payload.Item{
-			{
-				Key:   "key",
-				Value: []byte("val"),
-			},
-		},
+func kvSetTest(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		err := harness.Dial(t, addr).KV.Set("boltdb-south", &payload.Item{
+			Key:   "key",
+			Value: []byte("val"),
+		})
+		assert.NoError(t, err)
 	}
-
-	resp := &payload.Response{}
-	err = client.Call("kv.Set", p, resp)
-	assert.NoError(t, err)
 }
-
-func kvHasTest(t *testing.T) {
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
-	assert.NoError(t, err)
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-	// WorkerList contains list of workers.
-	p := &payload.Request{
-		Storage: "boltdb-south",
-		Items: []*payload.Item{
-			{
-				Key:   "key",
-				Value: []byte("val"),
-			},
-		},
+func kvHasTest(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		items, err := harness.Dial(t, addr).KV.Has("boltdb-south", "key")
+		assert.NoError(t, err)
+		assert.Len(t, items, 1)
 	}
-
-	ret := &payload.Response{}
-	err = client.Call("kv.Has", p, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 1)
 }
-
 func TestBoltDb(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-boltdb.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-boltdb.yaml",
 		&kv.Plugin{},
 		&boltdb.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&memory.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-	t.Run("BOLTDB", testRPCMethods)
-	stopCh <- struct{}{}
-	wg.Wait()
+	harness.WaitForTCP(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("BOLTDB", testRPCMethods(h.RPCAddr(), "boltdb-rr"))
+	h.Stop()
 
 	_ = os.Remove("rr.db")
 }
 
-func testRPCMethods(t *testing.T) {
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
-	assert.NoError(t, err)
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-	// add 5 second ttl
-	tt := time.Now().Add(time.Second * 5).Format(time.RFC3339)
-	keys := &payload.Request{
-		Storage: "boltdb-rr",
-		Items: []*payload.Item{
-			{
-				Key: "a",
-			},
-			{
-				Key: "b",
-			},
-			{
-				Key: "c",
-			},
-		},
+func testRPCMethods(rpcAddr, storage string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, rpcAddr)
+
+		// add 5 second ttl
+		tt := time.Now().Add(time.Second * 5).Format(time.RFC3339)
+
+		// Register 3 keys with values
+		err := client.KV.Set(storage,
+			&payload.Item{Key: "a", Value: []byte("aa")},
+			&payload.Item{Key: "b", Value: []byte("bb")},
+			&payload.Item{Key: "c", Value: []byte("cc"), Timeout: tt},
+			&payload.Item{Key: "d", Value: []byte("dd")},
+			&payload.Item{Key: "e", Value: []byte("ee")},
+		)
+		assert.NoError(t, err)
+
+		items, err := client.KV.Has(storage, "a", "b", "c")
+		assert.NoError(t, err)
+		assert.Len(t, items, 3) // should be 3
+
+		// key "c" should be deleted
+		time.Sleep(time.Second * 7)
+
+		items, err = client.KV.Has(storage, "a", "b", "c")
+		assert.NoError(t, err)
+		assert.Len(t, items, 2) // should be 2
+
+		items, err = client.KV.MGet(storage, "a", "b", "c")
+		assert.NoError(t, err)
+		assert.Len(t, items, 2) // c is expired
+
+		tt2 := time.Now().Add(time.Second * 10).Format(time.RFC3339)
+
+		// MEXPIRE
+		err = client.KV.MExpire(storage,
+			&payload.Item{Key: "a", Timeout: tt2},
+			&payload.Item{Key: "b", Timeout: tt2},
+			&payload.Item{Key: "d", Timeout: tt2},
+		)
+		assert.NoError(t, err)
+
+		// TTL
+		items, err = client.KV.TTL(storage, "a", "b", "d")
+		assert.NoError(t, err)
+		assert.Len(t, items, 3)
+
+		// HAS AFTER TTL
+		time.Sleep(time.Second * 15)
+		items, err = client.KV.Has(storage, "a", "b", "d")
+		assert.NoError(t, err)
+		assert.Len(t, items, 0)
+
+		// DELETE
+		err = client.KV.Delete(storage, "e")
+		assert.NoError(t, err)
+
+		// HAS AFTER DELETE
+		items, err = client.KV.Has(storage, "e")
+		assert.NoError(t, err)
+		assert.Len(t, items, 0)
+
+		// Register 5 keys with values
+		err = client.KV.Set(storage,
+			&payload.Item{Key: "a", Value: []byte("aa")},
+			&payload.Item{Key: "b", Value: []byte("bb")},
+			&payload.Item{Key: "c", Value: []byte("cc")},
+			&payload.Item{Key: "d", Value: []byte("dd")},
+			&payload.Item{Key: "e", Value: []byte("ee")},
+		)
+		assert.NoError(t, err)
+
+		items, err = client.KV.Has(storage, "a", "b", "c", "d", "e")
+		assert.NoError(t, err)
+		assert.Len(t, items, 5) // should be 5
+
+		err = client.KV.Clear(storage)
+		assert.NoError(t, err)
+
+		items, err = client.KV.Has(storage, "a", "b", "c", "d", "e")
+		assert.NoError(t, err)
+		assert.Len(t, items, 0) // should be 0
 	}
-
-	data := &payload.Request{
-		Storage: "boltdb-rr",
-		Items: []*payload.Item{
-			{
-				Key:   "a",
-				Value: []byte("aa"),
-			},
-			{
-				Key:   "b",
-				Value: []byte("bb"),
-			},
-			{
-				Key:     "c",
-				Value:   []byte("cc"),
-				Timeout: tt,
-			},
-			{
-				Key:   "d",
-				Value: []byte("dd"),
-			},
-			{
-				Key:   "e",
-				Value: []byte("ee"),
-			},
-		},
-	}
-
-	ret := &payload.Response{}
-	// Register 3 keys with values
-	err = client.Call("kv.Set", data, ret)
-	assert.NoError(t, err)
-
-	ret = &payload.Response{}
-	err = client.Call("kv.Has", keys, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 3) // should be 3
-
-	// key "c" should be deleted
-	time.Sleep(time.Second * 7)
-
-	ret = &payload.Response{}
-	err = client.Call("kv.Has", keys, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 2) // should be 2
-
-	ret = &payload.Response{}
-	err = client.Call("kv.MGet", keys, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 2) // c is expired
-
-	tt2 := time.Now().Add(time.Second * 10).Format(time.RFC3339)
-
-	data2 := &payload.Request{
-		Storage: "boltdb-rr",
-		Items: []*payload.Item{
-			{
-				Key:     "a",
-				Timeout: tt2,
-			},
-			{
-				Key:     "b",
-				Timeout: tt2,
-			},
-			{
-				Key:     "d",
-				Timeout: tt2,
-			},
-		},
-	}
-
-	// MEXPIRE
-	ret = &payload.Response{}
-	err = client.Call("kv.MExpire", data2, ret)
-	assert.NoError(t, err)
-
-	// TTL
-	keys2 := &payload.Request{
-		Storage: "boltdb-rr",
-		Items: []*payload.Item{
-			{
-				Key: "a",
-			},
-			{
-				Key: "b",
-			},
-			{
-				Key: "d",
-			},
-		},
-	}
-
-	ret = &payload.Response{}
-	err = client.Call("kv.TTL", keys2, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 3)
-
-	// HAS AFTER TTL
-	time.Sleep(time.Second * 15)
-	ret = &payload.Response{}
-	err = client.Call("kv.Has", keys2, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 0)
-
-	// DELETE
-	keysDel := &payload.Request{
-		Storage: "boltdb-rr",
-		Items: []*payload.Item{
-			{
-				Key: "e",
-			},
-		},
-	}
-
-	ret = &payload.Response{}
-	err = client.Call("kv.Delete", keysDel, ret)
-	assert.NoError(t, err)
-
-	// HAS AFTER DELETE
-	ret = &payload.Response{}
-	err = client.Call("kv.Has", keysDel, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 0)
-
-	dataClear := &payload.Request{
-		Storage: "boltdb-rr",
-		Items: []*payload.Item{
-			{
-				Key:   "a",
-				Value: []byte("aa"),
-			},
-			{
-				Key:   "b",
-				Value: []byte("bb"),
-			},
-			{
-				Key:   "c",
-				Value: []byte("cc"),
-			},
-			{
-				Key:   "d",
-				Value: []byte("dd"),
-			},
-			{
-				Key:   "e",
-				Value: []byte("ee"),
-			},
-		},
-	}
-
-	clear := &payload.Request{Storage: "boltdb-rr"}
-
-	ret = &payload.Response{}
-	// Register 3 keys with values
-	err = client.Call("kv.Set", dataClear, ret)
-	assert.NoError(t, err)
-
-	ret = &payload.Response{}
-	err = client.Call("kv.Has", dataClear, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 5) // should be 5
-
-	ret = &payload.Response{}
-	err = client.Call("kv.Clear", clear, ret)
-	assert.NoError(t, err)
-
-	ret = &payload.Response{}
-	err = client.Call("kv.Has", dataClear, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 0) // should be 5
 }
-
 func TestMemcached(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	srv := fakememcached.Start(t)
+	env := harness.NewEnv(t)
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-memcached.yaml",
-		Prefix: "rr",
-	}
+	env.Set("memcached", srv.Addr())
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-memcached.yaml",
 		&kv.Plugin{},
 		&memcached.Plugin{},
 		&rpcPlugin.Plugin{},
+		&informer.Plugin{},
 		&logger.Plugin{},
 		&memory.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
-	time.Sleep(time.Second * 1)
-	t.Run("MEMCACHED", testRPCMethodsMemcached)
-	stopCh <- struct{}{}
-	wg.Wait()
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("MEMCACHED", testRPCMethodsMemcached(h.RPCAddr(), srv))
+	assert.Empty(t, srv.Keys())
 }
 
-func testRPCMethodsMemcached(t *testing.T) {
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
-	assert.NoError(t, err)
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-	// add 5 second ttl
-	tt := time.Now().Add(time.Second * 5).Format(time.RFC3339)
-
-	keys := &payload.Request{
-		Storage: "memcached-rr",
-		Items: []*payload.Item{
-			{
-				Key: "a",
-			},
-			{
-				Key: "b",
-			},
-			{
-				Key: "c",
-			},
-		},
-	}
-
-	data := &payload.Request{
-		Storage: "memcached-rr",
-		Items: []*payload.Item{
-			{
-				Key:   "a",
-				Value: []byte("aa"),
-			},
-			{
-				Key:   "b",
-				Value: []byte("bb"),
-			},
-			{
-				Key:     "c",
-				Value:   []byte("cc"),
-				Timeout: tt,
-			},
-			{
-				Key:   "d",
-				Value: []byte("dd"),
-			},
-			{
-				Key:   "e",
-				Value: []byte("ee"),
-			},
-		},
+func testRPCMethodsMemcached(rpcAddr string, srv *fakememcached.Server) func(t *testing.T) {
+	return func(t *testing.T) {
+		const storage = "memcached-rr"
+
+		client := harness.Dial(t, rpcAddr)
+
+		// add 5 second ttl
+		tt := time.Now().Add(time.Second * 5).Format(time.RFC3339)
+
+		// Register 3 keys with values
+		err := client.KV.Set(storage,
+			&payload.Item{Key: "a", Value: []byte("aa")},
+			&payload.Item{Key: "b", Value: []byte("bb")},
+			&payload.Item{Key: "c", Value: []byte("cc"), Timeout: tt},
+			&payload.Item{Key: "d", Value: []byte("dd")},
+			&payload.Item{Key: "e", Value: []byte("ee")},
+		)
+		assert.NoError(t, err)
+
+		items, err := client.KV.Has(storage, "a", "b", "c")
+		assert.NoError(t, err)
+		assert.Len(t, items, 3) // should be 3
+
+		v, ok := srv.Get("a")
+		assert.True(t, ok)
+		assert.Equal(t, []byte("aa"), v)
+
+		ttl, ok := srv.TTL("c")
+		assert.True(t, ok)
+		assert.Greater(t, ttl, time.Duration(0))
+		assert.LessOrEqual(t, ttl, time.Second*5)
+
+		// key "c" should be deleted
+		time.Sleep(time.Second * 7)
+
+		items, err = client.KV.Has(storage, "a", "b", "c")
+		assert.NoError(t, err)
+		assert.Len(t, items, 2) // should be 2
+
+		items, err = client.KV.MGet(storage, "a", "b", "c")
+		assert.NoError(t, err)
+		assert.Len(t, items, 2) // c is expired
+		assert.Equal(t, []string{"a", "b", "d", "e"}, srv.Keys())
+
+		tt2 := time.Now().Add(time.Second * 10).Format(time.RFC3339)
+
+		// MEXPIRE
+		err = client.KV.MExpire(storage,
+			&payload.Item{Key: "a", Timeout: tt2},
+			&payload.Item{Key: "b", Timeout: tt2},
+			&payload.Item{Key: "d", Timeout: tt2},
+		)
+		assert.NoError(t, err)
+
+		// the driver does not support TTL, but the stored expiration can be checked directly
+		ttl, ok = srv.TTL("a")
+		assert.True(t, ok)
+		assert.Greater(t, ttl, time.Second*5)
+
+		// TTL call is not supported for the memcached driver
+		items, err = client.KV.TTL(storage, "a", "b", "d")
+		assert.Error(t, err)
+		assert.Len(t, items, 0)
+
+		// HAS AFTER TTL
+		time.Sleep(time.Second * 15)
+		items, err = client.KV.Has(storage, "a", "b", "d")
+		assert.NoError(t, err)
+		assert.Len(t, items, 0)
+
+		// DELETE
+		err = client.KV.Delete(storage, "e")
+		assert.NoError(t, err)
+
+		// HAS AFTER DELETE
+		items, err = client.KV.Has(storage, "e")
+		assert.NoError(t, err)
+		assert.Len(t, items, 0)
+
+		// Register 5 keys with values
+		err = client.KV.Set(storage,
+			&payload.Item{Key: "a", Value: []byte("aa")},
+			&payload.Item{Key: "b", Value: []byte("bb")},
+			&payload.Item{Key: "c", Value: []byte("cc")},
+			&payload.Item{Key: "d", Value: []byte("dd")},
+			&payload.Item{Key: "e", Value: []byte("ee")},
+		)
+		assert.NoError(t, err)
+
+		items, err = client.KV.Has(storage, "a", "b", "c", "d", "e")
+		assert.NoError(t, err)
+		assert.Len(t, items, 5) // should be 5
+
+		err = client.KV.Clear(storage)
+		assert.NoError(t, err)
+
+		time.Sleep(time.Second * 2)
+		items, err = client.KV.Has(storage, "a", "b", "c", "d", "e")
+		assert.NoError(t, err)
+		assert.Len(t, items, 0) // should be 0
+		assert.Empty(t, srv.Keys())
 	}
-
-	ret := &payload.Response{}
-	// Register 3 keys with values
-	err = client.Call("kv.Set", data, ret)
-	assert.NoError(t, err)
-
-	ret = &payload.Response{}
-	err = client.Call("kv.Has", keys, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 3) // should be 3
-
-	// key "c" should be deleted
-	time.Sleep(time.Second * 7)
-
-	ret = &payload.Response{}
-	err = client.Call("kv.Has", keys, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 2) // should be 2
-
-	ret = &payload.Response{}
-	err = client.Call("kv.MGet", keys, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 2) // c is expired
-
-	tt2 := time.Now().Add(time.Second * 10).Format(time.RFC3339)
-
-	data2 := &payload.Request{
-		Storage: "memcached-rr",
-		Items: []*payload.Item{
-			{
-				Key:     "a",
-				Timeout: tt2,
-			},
-			{
-				Key:     "b",
-				Timeout: tt2,
-			},
-			{
-				Key:     "d",
-				Timeout: tt2,
-			},
-		},
-	}
-
-	// MEXPIRE
-	ret = &payload.Response{}
-	err = client.Call("kv.MExpire", data2, ret)
-	assert.NoError(t, err)
-
-	// TTL call is not supported for the memcached driver
-	keys2 := &payload.Request{
-		Storage: "memcached-rr",
-		Items: []*payload.Item{
-			{
-				Key: "a",
-			},
-			{
-				Key: "b",
-			},
-			{
-				Key: "d",
-			},
-		},
-	}
-
-	ret = &payload.Response{}
-	err = client.Call("kv.TTL", keys2, ret)
-	assert.Error(t, err)
-	assert.Len(t, ret.GetItems(), 0)
-
-	// HAS AFTER TTL
-	time.Sleep(time.Second * 15)
-	ret = &payload.Response{}
-	err = client.Call("kv.Has", keys2, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 0)
-
-	// DELETE
-	keysDel := &payload.Request{
-		Storage: "memcached-rr",
-		Items: []*payload.Item{
-			{
-				Key: "e",
-			},
-		},
-	}
-
-	ret = &payload.Response{}
-	err = client.Call("kv.Delete", keysDel, ret)
-	assert.NoError(t, err)
-
-	// HAS AFTER DELETE
-	ret = &payload.Response{}
-	err = client.Call("kv.Has", keysDel, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 0)
-
-	dataClear := &payload.Request{
-		Storage: "memcached-rr",
-		Items: []*payload.Item{
-			{
-				Key:   "a",
-				Value: []byte("aa"),
-			},
-			{
-				Key:   "b",
-				Value: []byte("bb"),
-			},
-			{
-				Key:   "c",
-				Value: []byte("cc"),
-			},
-			{
-				Key:   "d",
-				Value: []byte("dd"),
-			},
-			{
-				Key:   "e",
-				Value: []byte("ee"),
-			},
-		},
-	}
-
-	clear := &payload.Request{Storage: "memcached-rr"}
-
-	ret = &payload.Response{}
-	// Register 3 keys with values
-	err = client.Call("kv.Set", dataClear, ret)
-	assert.NoError(t, err)
-
-	ret = &payload.Response{}
-	err = client.Call("kv.Has", dataClear, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 5) // should be 5
-
-	ret = &payload.Response{}
-	err = client.Call("kv.Clear", clear, ret)
-	assert.NoError(t, err)
-
-	time.Sleep(time.Second * 2)
-	ret = &payload.Response{}
-	err = client.Call("kv.Has", dataClear, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 0) // should be 5
 }
-
 func TestInMemory(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-in-memory.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-in-memory.yaml",
 		&kv.Plugin{},
 		&memory.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-	t.Run("INMEMORY", testRPCMethodsInMemory)
-	stopCh <- struct{}{}
-	wg.Wait()
-}
-
-func testRPCMethodsInMemory(t *testing.T) {
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
-	assert.NoError(t, err)
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-	// add 5 second ttl
-
-	tt := time.Now().Add(time.Second * 5).Format(time.RFC3339)
-	keys := &payload.Request{
-		Storage: "memory-rr",
-		Items: []*payload.Item{
-			{
-				Key: "a",
-			},
-			{
-				Key: "b",
-			},
-			{
-				Key: "c",
-			},
-		},
-	}
-
-	data := &payload.Request{
-		Storage: "memory-rr",
-		Items: []*payload.Item{
-			{
-				Key:   "a",
-				Value: []byte("aa"),
-			},
-			{
-				Key:   "b",
-				Value: []byte("bb"),
-			},
-			{
-				Key:     "c",
-				Value:   []byte("cc"),
-				Timeout: tt,
-			},
-			{
-				Key:   "d",
-				Value: []byte("dd"),
-			},
-			{
-				Key:   "e",
-				Value: []byte("ee"),
-			},
-		},
-	}
-
-	ret := &payload.Response{}
-	// Register 3 keys with values
-	err = client.Call("kv.Set", data, ret)
-	assert.NoError(t, err)
-
-	ret = &payload.Response{}
-	err = client.Call("kv.Has", keys, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 3) // should be 3
-
-	// key "c" should be deleted
-	time.Sleep(time.Second * 7)
-
-	ret = &payload.Response{}
-	err = client.Call("kv.Has", keys, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 2) // should be 2
-
-	ret = &payload.Response{}
-	err = client.Call("kv.MGet", keys, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 2) // c is expired
-
-	tt2 := time.Now().Add(time.Second * 10).Format(time.RFC3339)
-
-	data2 := &payload.Request{
-		Storage: "memory-rr",
-		Items: []*payload.Item{
-			{
-				Key:     "a",
-				Timeout: tt2,
-			},
-			{
-				Key:     "b",
-				Timeout: tt2,
-			},
-			{
-				Key:     "d",
-				Timeout: tt2,
-			},
-		},
-	}
-
-	// MEXPIRE
-	ret = &payload.Response{}
-	err = client.Call("kv.MExpire", data2, ret)
-	assert.NoError(t, err)
-
-	// TTL
-	keys2 := &payload.Request{
-		Storage: "memory-rr",
-		Items: []*payload.Item{
-			{
-				Key: "a",
-			},
-			{
-				Key: "b",
-			},
-			{
-				Key: "d",
-			},
-		},
-	}
-
-	ret = &payload.Response{}
-	err = client.Call("kv.TTL", keys2, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 3)
-
-	// HAS AFTER TTL
-	time.Sleep(time.Second * 15)
-	ret = &payload.Response{}
-	err = client.Call("kv.Has", keys2, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 0)
-
-	// DELETE
-	keysDel := &payload.Request{
-		Storage: "memory-rr",
-		Items: []*payload.Item{
-			{
-				Key: "e",
-			},
-		},
-	}
-
-	ret = &payload.Response{}
-	err = client.Call("kv.Delete", keysDel, ret)
-	assert.NoError(t, err)
-
-	// HAS AFTER DELETE
-	ret = &payload.Response{}
-	err = client.Call("kv.Has", keysDel, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 0)
-
-	dataClear := &payload.Request{
-		Storage: "memory-rr",
-		Items: []*payload.Item{
-			{
-				Key:   "a",
-				Value: []byte("aa"),
-			},
-			{
-				Key:   "b",
-				Value: []byte("bb"),
-			},
-			{
-				Key:   "c",
-				Value: []byte("cc"),
-			},
-			{
-				Key:   "d",
-				Value: []byte("dd"),
-			},
-			{
-				Key:   "e",
-				Value: []byte("ee"),
-			},
-		},
-	}
-
-	clear := &payload.Request{Storage: "memory-rr"}
 
-	ret = &payload.Response{}
-	// Register 3 keys with values
-	err = client.Call("kv.Set", dataClear, ret)
-	assert.NoError(t, err)
-
-	ret = &payload.Response{}
-	err = client.Call("kv.Has", dataClear, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 5) // should be 5
-
-	ret = &payload.Response{}
-	err = client.Call("kv.Clear", clear, ret)
-	assert.NoError(t, err)
-
-	ret = &payload.Response{}
-	err = client.Call("kv.Has", dataClear, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 0) // should be 5
+	harness.WaitForTCP(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("INMEMORY", testRPCMethods(h.RPCAddr(), "memory-rr"))
+	h.Stop()
 }
-
 func TestRedis(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	srv := fakeredis.Start(t)
+	env := harness.NewEnv(t)
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-redis.yaml",
-		Prefix: "rr",
-	}
+	env.Set("redis", srv.Addr())
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-redis.yaml",
 		&kv.Plugin{},
 		&redis.Plugin{},
 		&rpcPlugin.Plugin{},
+		&informer.Plugin{},
 		&logger.Plugin{},
 		&memory.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-	t.Run("REDIS", testRPCMethodsRedis)
-	stopCh <- struct{}{}
-	wg.Wait()
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("REDIS", testRPCMethodsRedis(h.RPCAddr()))
+	assert.Empty(t, srv.Keys())
 }
 
 func TestRedisGlobalSection(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	srv := fakeredis.Start(t)
+	env := harness.NewEnv(t)
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-redis-global.yaml",
-		Prefix: "rr",
-	}
+	env.Set("redis", srv.Addr())
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-redis-global.yaml",
 		&kv.Plugin{},
 		&redis.Plugin{},
 		&rpcPlugin.Plugin{},
+		&informer.Plugin{},
 		&logger.Plugin{},
 		&memory.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-	t.Run("REDIS", testRPCMethodsRedis)
-	stopCh <- struct{}{}
-	wg.Wait()
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("REDIS", testRPCMethodsRedis(h.RPCAddr()))
+	assert.Empty(t, srv.Keys())
 }
 
 func TestRedisNoConfig(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-redis-no-config.yaml", // should be used default
-		Prefix: "rr",
-	}
-
 	l, oLogger := mock_logger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	// should be used default
+	h := harness.Start(t, "configs/.rr-redis-no-config.yaml",
 		l,
 		&kv.Plugin{},
 		&redis.Plugin{},
 		&rpcPlugin.Plugin{},
 		&memory.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	_, err = cont.Serve()
-	assert.NoError(t, err)
-	_ = cont.Stop()
+	h.Stop()
 
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("plugin was started").Len())
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("can't find local or global configuration, this section will be skipped").Len())
 }
 
-func testRPCMethodsRedis(t *testing.T) {
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
-	assert.NoError(t, err)
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-	// add 5 second ttl
-	tt := time.Now().Add(time.Second * 5).Format(time.RFC3339)
-	keys := &payload.Request{
-		Storage: "redis-rr",
-		Items: []*payload.Item{
-			{
-				Key: "a",
-			},
-			{
-				Key: "b",
-			},
-			{
-				Key: "c",
-			},
-		},
+func testRPCMethodsRedis(rpcAddr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		const storage = "redis-rr"
+
+		client := harness.Dial(t, rpcAddr)
+
+		// add 5 second ttl
+		tt := time.Now().Add(time.Second * 5).Format(time.RFC3339)
+
+		// Register 3 keys with values
+		err := client.KV.Set(storage,
+			&payload.Item{Key: "a", Value: []byte("aa")},
+			&payload.Item{Key: "b", Value: []byte("bb")},
+			&payload.Item{Key: "c", Value: []byte("cc"), Timeout: tt},
+			&payload.Item{Key: "d", Value: []byte("dd")},
+			&payload.Item{Key: "e", Value: []byte("ee")},
+		)
+		assert.NoError(t, err)
+
+		items, err := client.KV.Has(storage, "a", "b", "c")
+		assert.NoError(t, err)
+		assert.Len(t, items, 3) // should be 3
+
+		// key "c" should be deleted
+		time.Sleep(time.Second * 7)
+
+		items, err = client.KV.Has(storage, "a", "b", "c")
+		assert.NoError(t, err)
+		assert.Len(t, items, 2) // should be 2
+
+		items, err = client.KV.MGet(storage, "a", "b", "c")
+		assert.NoError(t, err)
+		assert.Len(t, items, 2) // c is expired
+
+		tt2 := time.Now().Add(time.Second * 10).Format(time.RFC3339)
+
+		// MEXPIRE
+		err = client.KV.MExpire(storage,
+			&payload.Item{Key: "a", Timeout: tt2},
+			&payload.Item{Key: "b", Timeout: tt2},
+			&payload.Item{Key: "d", Timeout: tt2},
+		)
+		assert.NoError(t, err)
+
+		// TTL
+		items, err = client.KV.TTL(storage, "a", "b", "d")
+		assert.NoError(t, err)
+		assert.Len(t, items, 3)
+
+		// HAS AFTER TTL
+		time.Sleep(time.Second * 15)
+		items, err = client.KV.Has(storage, "a", "b", "d")
+		assert.NoError(t, err)
+		assert.Len(t, items, 0)
+
+		items, err = client.KV.TTL(storage, "a", "b", "d")
+		assert.NoError(t, err)
+		assert.Len(t, items, 0)
+
+		// DELETE
+		err = client.KV.Delete(storage, "e")
+		assert.NoError(t, err)
+
+		// HAS AFTER DELETE
+		items, err = client.KV.Has(storage, "e")
+		assert.NoError(t, err)
+		assert.Len(t, items, 0)
+
+		// Register 5 keys with values
+		err = client.KV.Set(storage,
+			&payload.Item{Key: "a", Value: []byte("aa")},
+			&payload.Item{Key: "b", Value: []byte("bb")},
+			&payload.Item{Key: "c", Value: []byte("cc")},
+			&payload.Item{Key: "d", Value: []byte("dd")},
+			&payload.Item{Key: "e", Value: []byte("ee")},
+		)
+		assert.NoError(t, err)
+
+		items, err = client.KV.Has(storage, "a", "b", "c", "d", "e")
+		assert.NoError(t, err)
+		assert.Len(t, items, 5) // should be 5
+
+		err = client.KV.Clear(storage)
+		assert.NoError(t, err)
+
+		items, err = client.KV.Has(storage, "a", "b", "c", "d", "e")
+		assert.NoError(t, err)
+		assert.Len(t, items, 0) // should be 0
 	}
-
-	data := &payload.Request{
-		Storage: "redis-rr",
-		Items: []*payload.Item{
-			{
-				Key:   "a",
-				Value: []byte("aa"),
-			},
-			{
-				Key:   "b",
-				Value: []byte("bb"),
-			},
-			{
-				Key:     "c",
-				Value:   []byte("cc"),
-				Timeout: tt,
-			},
-			{
-				Key:   "d",
-				Value: []byte("dd"),
-			},
-			{
-				Key:   "e",
-				Value: []byte("ee"),
-			},
-		},
-	}
-
-	ret := &payload.Response{}
-	// Register 3 keys with values
-	err = client.Call("kv.Set", data, ret)
-	assert.NoError(t, err)
-
-	ret = &payload.Response{}
-	err = client.Call("kv.Has", keys, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 3) // should be 3
-
-	// key "c" should be deleted
-	time.Sleep(time.Second * 7)
-
-	ret = &payload.Response{}
-	err = client.Call("kv.Has", keys, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 2) // should be 2
-
-	ret = &payload.Response{}
-	err = client.Call("kv.MGet", keys, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 2) // c is expired
-
-	tt2 := time.Now().Add(time.Second * 10).Format(time.RFC3339)
-
-	data2 := &payload.Request{
-		Storage: "redis-rr",
-		Items: []*payload.Item{
-			{
-				Key:     "a",
-				Timeout: tt2,
-			},
-			{
-				Key:     "b",
-				Timeout: tt2,
-			},
-			{
-				Key:     "d",
-				Timeout: tt2,
-			},
-		},
-	}
-
-	// MEXPIRE
-	ret = &payload.Response{}
-	err = client.Call("kv.MExpire", data2, ret)
-	assert.NoError(t, err)
-
-	// TTL
-	keys2 := &payload.Request{
-		Storage: "redis-rr",
-		Items: []*payload.Item{
-			{
-				Key: "a",
-			},
-			{
-				Key: "b",
-			},
-			{
-				Key: "d",
-			},
-		},
-	}
-
-	ret = &payload.Response{}
-	err = client.Call("kv.TTL", keys2, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 3)
-
-	// HAS AFTER TTL
-	time.Sleep(time.Second * 15)
-	ret = &payload.Response{}
-	err = client.Call("kv.Has", keys2, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 0)
-
-	ret = &payload.Response{}
-	err = client.Call("kv.TTL", keys2, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 0)
-
-	// DELETE
-	keysDel := &payload.Request{
-		Storage: "redis-rr",
-		Items: []*payload.Item{
-			{
-				Key: "e",
-			},
-		},
-	}
-
-	ret = &payload.Response{}
-	err = client.Call("kv.Delete", keysDel, ret)
-	assert.NoError(t, err)
-
-	// HAS AFTER DELETE
-	ret = &payload.Response{}
-	err = client.Call("kv.Has", keysDel, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 0)
-
-	dataClear := &payload.Request{
-		Storage: "redis-rr",
-		Items: []*payload.Item{
-			{
-				Key:   "a",
-				Value: []byte("aa"),
-			},
-			{
-				Key:   "b",
-				Value: []byte("bb"),
-			},
-			{
-				Key:   "c",
-				Value: []byte("cc"),
-			},
-			{
-				Key:   "d",
-				Value: []byte("dd"),
-			},
-			{
-				Key:   "e",
-				Value: []byte("ee"),
-			},
-		},
-	}
-
-	clear := &payload.Request{Storage: "redis-rr"}
-
-	ret = &payload.Response{}
-	// Register 3 keys with values
-	err = client.Call("kv.Set", dataClear, ret)
-	assert.NoError(t, err)
-
-	ret = &payload.Response{}
-	err = client.Call("kv.Has", dataClear, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 5) // should be 5
-
-	ret = &payload.Response{}
-	err = client.Call("kv.Clear", clear, ret)
-	assert.NoError(t, err)
-
-	ret = &payload.Response{}
-	err = client.Call("kv.Has", dataClear, ret)
-	assert.NoError(t, err)
-	assert.Len(t, ret.GetItems(), 0) // should be 5
 }
@@ -3,325 +3,75 @@ package logger
 import (
 	"net/http"
 	"os"
-	"os/signal"
 	"strings"
-	"sync"
-	"syscall"
 	"testing"
 	"time"
 
-	"github.com/roadrunner-server/config/v2"
 	endure "github.com/roadrunner-server/endure/pkg/container"
 	httpPlugin "github.com/roadrunner-server/http/v2"
 	"github.com/roadrunner-server/logger/v2"
 	"github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	"github.com/roadrunner-server/server/v2"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestLogger(t *testing.T) {
-	container, err := endure.NewContainer(nil, endure.RetryOnFail(false), endure.SetLogLevel(endure.ErrorLevel))
-	if err != nil {
-		t.Fatal(err)
-	}
-	// config plugin
-	vp := &config.Plugin{}
-	vp.Path = "configs/.rr.yaml"
-	vp.Prefix = "rr"
-
-	err = container.RegisterAll(
-		vp,
+	h := harness.Start(t, "configs/.rr.yaml",
 		&TestPlugin{},
 		&logger.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = container.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	errCh, err := container.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	// stop by CTRL+C
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-
-	stopCh := make(chan struct{}, 1)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-errCh:
-				assert.NoError(t, e.Error)
-				assert.NoError(t, container.Stop())
-				return
-			case <-c:
-				err = container.Stop()
-				assert.NoError(t, err)
-				return
-			case <-stopCh:
-				assert.NoError(t, container.Stop())
-				return
-			}
-		}
-	}()
-
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestLoggerRawErr(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	// config plugin
-	cfg := &config.Plugin{}
-	cfg.Path = "configs/.rr-raw-mode.yaml"
-	cfg.Prefix = "rr"
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-raw-mode.yaml",
 		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestLoggerNoConfig(t *testing.T) {
-	container, err := endure.NewContainer(nil, endure.RetryOnFail(true), endure.SetLogLevel(endure.ErrorLevel))
-	if err != nil {
-		t.Fatal(err)
-	}
-	// config plugin
-	vp := &config.Plugin{}
-	vp.Path = "configs/.rr-no-logger.yaml"
-	vp.Prefix = "rr"
+	env := harness.NewEnv(t)
 
-	err = container.RegisterAll(
-		vp,
+	h := env.Options(endure.RetryOnFail(true)).Start("configs/.rr-no-logger.yaml",
 		&TestPlugin{},
 		&logger.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = container.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	errCh, err := container.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	// stop by CTRL+C
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-
-	stopCh := make(chan struct{}, 1)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-errCh:
-				assert.NoError(t, e.Error)
-				assert.NoError(t, container.Stop())
-				return
-			case <-c:
-				err = container.Stop()
-				assert.NoError(t, err)
-				return
-			case <-stopCh:
-				assert.NoError(t, container.Stop())
-				return
-			}
-		}
-	}()
-
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 // Should no panic
 func TestLoggerNoConfig2(t *testing.T) {
-	container, err := endure.NewContainer(nil, endure.RetryOnFail(true), endure.SetLogLevel(endure.ErrorLevel))
-	if err != nil {
-		t.Fatal(err)
-	}
-	// config plugin
-	vp := &config.Plugin{}
-	vp.Path = "configs/.rr-no-logger2.yaml"
-	vp.Prefix = "rr"
+	env := harness.NewEnv(t)
 
-	err = container.RegisterAll(
-		vp,
+	h := env.Options(endure.RetryOnFail(true)).Start("configs/.rr-no-logger2.yaml",
 		&rpc.Plugin{},
 		&logger.Plugin{},
 		&httpPlugin.Plugin{},
 		&server.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = container.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	errCh, err := container.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	// stop by CTRL+C
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-
-	stopCh := make(chan struct{}, 1)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-errCh:
-				assert.NoError(t, e.Error)
-				assert.NoError(t, container.Stop())
-				return
-			case <-c:
-				err = container.Stop()
-				assert.NoError(t, err)
-				return
-			case <-stopCh:
-				assert.NoError(t, container.Stop())
-				return
-			}
-		}
-	}()
-
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestFileLogger(t *testing.T) {
-	container, err := endure.NewContainer(nil, endure.RetryOnFail(true), endure.SetLogLevel(endure.ErrorLevel))
-	if err != nil {
-		t.Fatal(err)
-	}
-	// config plugin
-	vp := &config.Plugin{}
-	vp.Path = "configs/.rr-file-logger.yaml"
-	vp.Prefix = "rr"
+	env := harness.NewEnv(t)
 
-	err = container.RegisterAll(
-		vp,
+	h := env.Options(endure.RetryOnFail(true)).Start("configs/.rr-file-logger.yaml",
 		&rpc.Plugin{},
 		&logger.Plugin{},
 		&httpPlugin.Plugin{},
 		&server.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = container.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	errCh, err := container.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	// stop by CTRL+C
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-
-	stopCh := make(chan struct{}, 1)
 
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-errCh:
-				assert.NoError(t, e.Error)
-				assert.NoError(t, container.Stop())
-				return
-			case <-c:
-				err = container.Stop()
-				assert.NoError(t, err)
-				return
-			case <-stopCh:
-				assert.NoError(t, container.Stop())
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 2)
-	t.Run("HTTPEchoReq", httpEcho)
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	t.Run("HTTPEchoReq", httpEcho(env.Addr("http")))
 
 	f, err := os.ReadFile("test.log")
 	if err != nil {
@@ -333,76 +83,30 @@ func TestFileLogger(t *testing.T) {
 
 	_ = os.Remove("test.log")
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
-func httpEcho(t *testing.T) {
-	req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:54224?hello=world", nil)
-	assert.NoError(t, err)
+func httpEcho(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "http://"+addr+"?hello=world", nil)
+		assert.NoError(t, err)
 
-	r, err := http.DefaultClient.Do(req)
-	assert.NoError(t, err)
-	assert.Equal(t, http.StatusCreated, r.StatusCode)
+		r, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, r.StatusCode)
 
-	err = r.Body.Close()
-	assert.NoError(t, err)
+		err = r.Body.Close()
+		assert.NoError(t, err)
+	}
 }
 
 func TestMarshalObjectLogging(t *testing.T) {
-	container, err := endure.NewContainer(nil, endure.RetryOnFail(true), endure.SetLogLevel(endure.ErrorLevel))
-	if err != nil {
-		t.Fatal(err)
-	}
-	// config plugin
-	vp := &config.Plugin{}
-	vp.Path = "configs/.rr-file-logger.yaml"
-	vp.Prefix = "rr"
+	env := harness.NewEnv(t)
 
-	err = container.RegisterAll(
-		vp,
+	h := env.Options(endure.RetryOnFail(true)).Start("configs/.rr-file-logger.yaml",
 		&TestPlugin{},
 		&logger.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = container.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	errCh, err := container.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	// stop by CTRL+C
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-
-	stopCh := make(chan struct{}, 1)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-errCh:
-				assert.NoError(t, e.Error)
-				assert.NoError(t, container.Stop())
-				return
-			case <-c:
-				err = container.Stop()
-				assert.NoError(t, err)
-				return
-			case <-stopCh:
-				assert.NoError(t, container.Stop())
-				return
-			}
-		}
-	}()
 
 	time.Sleep(time.Second * 2)
 
@@ -414,7 +118,6 @@ func TestMarshalObjectLogging(t *testing.T) {
 	assert.Contains(t, string(f), "Example field error")
 	assert.Equal(t, 4, strings.Count(string(f), "Example field error"))
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 	_ = os.Remove("test.log")
 }
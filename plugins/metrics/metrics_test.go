@@ -2,24 +2,18 @@ package metrics
 
 import (
 	"io/ioutil"
-	"net"
 	"net/http"
-	"net/rpc"
-	"os"
-	"os/signal"
-	"sync"
-	"syscall"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
-	"github.com/roadrunner-server/config/v2"
-	endure "github.com/roadrunner-server/endure/pkg/container"
-	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
 	httpPlugin "github.com/roadrunner-server/http/v2"
 	"github.com/roadrunner-server/logger/v2"
 	"github.com/roadrunner-server/metrics/v2"
 	"github.com/roadrunner-server/prometheus/v2"
 	rpcPlugin "github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	mocklogger "github.com/roadrunner-server/rr-e2e-tests/mock"
 	"github.com/roadrunner-server/server/v2"
 	"github.com/stretchr/testify/assert"
@@ -27,155 +21,51 @@ import (
 	"go.uber.org/zap"
 )
 
-const dialAddr = "127.0.0.1:6001"
-const dialNetwork = "tcp"
-const getAddr = "http://127.0.0.1:2112/metrics"
-const getAddr2 = "http://127.0.0.1:2113/metrics"
-const getIPV6Addr = "http://[::1]:2112/metrics"
-
 func TestMetricsInit(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	cfg := &config.Plugin{}
-	cfg.Prefix = "rr"
-	cfg.Path = "configs/.rr-test.yaml"
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-test.yaml",
 		&metrics.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&Plugin1{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	tt := time.NewTimer(time.Second * 5)
-	defer tt.Stop()
-
-	time.Sleep(time.Second * 2)
-	out, err := getIPV6()
+	harness.WaitForTCP(t, "[::1]:"+strconv.Itoa(env.Port("metrics")), harness.DefaultWaitTimeout)
+	out, err := getIPV6(env.Port("metrics"))
 	assert.NoError(t, err)
 
 	assert.Contains(t, out, "go_gc_duration_seconds")
 	assert.Contains(t, out, "app_metric_counter")
 
-	for {
-		select {
-		case e := <-ch:
-			assert.Fail(t, "error", e.Error.Error())
-			err = cont.Stop()
-			if err != nil {
-				assert.FailNow(t, "error", err.Error())
-			}
-		case <-sig:
-			err = cont.Stop()
-			if err != nil {
-				assert.FailNow(t, "error", err.Error())
-			}
-			return
-		case <-tt.C:
-			// timeout
-			err = cont.Stop()
-			if err != nil {
-				assert.FailNow(t, "error", err.Error())
-			}
-			return
-		}
-	}
+	h.Stop()
 }
 
 func TestMetricsIssue571(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	cfg := &config.Plugin{}
-	cfg.Prefix = "rr"
-	cfg.Path = "configs/.rr-issue-571.yaml"
+	env := harness.NewEnv(t)
 
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-issue-571.yaml",
 		&metrics.Plugin{},
 		&rpcPlugin.Plugin{},
 		&server.Plugin{},
 		l,
 		&httpPlugin.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
 	// give some time to wait http
 	time.Sleep(time.Second)
-	_, err = issue571Http()
+	_, err := issue571Http(env.Addr("http"))
 	assert.NoError(t, err)
 
-	out, err := issue571Metrics()
+	out, err := issue571Metrics(env.Addr("metrics"))
 	assert.NoError(t, err)
 
 	assert.Contains(t, out, "HELP test Test counter")
 	assert.Contains(t, out, "TYPE test counter")
 
-	stopCh := make(chan struct{}, 1)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
 	time.Sleep(time.Second)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("http server was started").Len())
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("http log").Len())
@@ -188,8 +78,8 @@ func TestMetricsIssue571(t *testing.T) {
 }
 
 // get request and return body
-func issue571Http() (string, error) {
-	r, err := http.Get("http://127.0.0.1:56444")
+func issue571Http(addr string) (string, error) {
+	r, err := http.Get("http://" + addr)
 	if err != nil {
 		return "", err
 	}
@@ -208,8 +98,8 @@ func issue571Http() (string, error) {
 }
 
 // get request and return body
-func issue571Metrics() (string, error) {
-	r, err := http.Get("http://127.0.0.1:23557")
+func issue571Metrics(addr string) (string, error) {
+	r, err := http.Get("http://" + addr)
 	if err != nil {
 		return "", err
 	}
@@ -228,173 +118,85 @@ func issue571Metrics() (string, error) {
 }
 
 func TestMetricsGaugeCollector(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	cfg := &config.Plugin{}
-	cfg.Prefix = "rr"
-	cfg.Path = "configs/.rr-test.yaml"
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-test.yaml",
 		&metrics.Plugin{},
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&Plugin1{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
+	harness.WaitForTCP(t, "[::1]:"+strconv.Itoa(env.Port("metrics")), harness.DefaultWaitTimeout)
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	harness.Eventually(t, time.Second*10, "the gauge is collected", func() (bool, error) {
+		out, err := getIPV6(env.Port("metrics"))
+		if err != nil {
+			return false, err
+		}
 
-	time.Sleep(time.Second)
-	tt := time.NewTimer(time.Second * 5)
-	defer tt.Stop()
+		return strings.Contains(out, "my_gauge 100"), nil
+	})
 
-	time.Sleep(time.Second * 2)
-	out, err := getIPV6()
+	out, err := getIPV6(env.Port("metrics"))
 	assert.NoError(t, err)
 	assert.Contains(t, out, "my_gauge 100")
 	assert.Contains(t, out, "my_gauge2 100")
 
-	out, err = getIPV6()
+	out, err = getIPV6(env.Port("metrics"))
 	assert.NoError(t, err)
 	assert.Contains(t, out, "go_gc_duration_seconds")
 
-	for {
-		select {
-		case e := <-ch:
-			assert.Fail(t, "error", e.Error.Error())
-			err = cont.Stop()
-			if err != nil {
-				assert.FailNow(t, "error", err.Error())
-			}
-		case <-sig:
-			err = cont.Stop()
-			if err != nil {
-				assert.FailNow(t, "error", err.Error())
-			}
-			return
-		case <-tt.C:
-			// timeout
-			err = cont.Stop()
-			if err != nil {
-				assert.FailNow(t, "error", err.Error())
-			}
-			return
-		}
-	}
+	h.Stop()
 }
 
 func TestMetricsDifferentRPCCalls(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	cfg := &config.Plugin{}
-	cfg.Prefix = "rr"
-	cfg.Path = "configs/.rr-test.yaml"
+	env := harness.NewEnv(t)
 
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-test.yaml",
 		&metrics.Plugin{},
 		&rpcPlugin.Plugin{},
 		l,
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	tt := time.NewTimer(time.Minute * 3)
-	defer tt.Stop()
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-tt.C:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 2)
-	t.Run("DeclareMetric", declareMetricsTest)
-	genericOut, err := getIPV6()
+	harness.WaitForTCP(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("DeclareMetric", declareMetricsTest(h.RPCAddr()))
+	genericOut, err := getIPV6(env.Port("metrics"))
 	assert.NoError(t, err)
 	assert.Contains(t, genericOut, "test_metrics_named_collector")
 
-	t.Run("AddMetric", addMetricsTest)
-	genericOut, err = getIPV6()
+	t.Run("AddMetric", addMetricsTest(h.RPCAddr()))
+	genericOut, err = getIPV6(env.Port("metrics"))
 	assert.NoError(t, err)
 	assert.Contains(t, genericOut, "test_metrics_named_collector 10000")
 
-	t.Run("SetMetric", setMetric)
-	genericOut, err = getIPV6()
+	t.Run("SetMetric", setMetric(h.RPCAddr()))
+	genericOut, err = getIPV6(env.Port("metrics"))
 	assert.NoError(t, err)
 	assert.Contains(t, genericOut, "user_gauge_collector 100")
 
-	t.Run("VectorMetric", vectorMetric)
-	genericOut, err = getIPV6()
+	t.Run("VectorMetric", vectorMetric(h.RPCAddr()))
+	genericOut, err = getIPV6(env.Port("metrics"))
 	assert.NoError(t, err)
 	assert.Contains(t, genericOut, "gauge_2_collector{section=\"first\",type=\"core\"} 100")
 
-	t.Run("MissingSection", missingSection)
-	t.Run("SetWithoutLabels", setWithoutLabels)
-	t.Run("SetOnHistogram", setOnHistogram)
-	t.Run("MetricSub", subMetric)
-	genericOut, err = getIPV6()
+	t.Run("MissingSection", missingSection(h.RPCAddr()))
+	t.Run("SetWithoutLabels", setWithoutLabels(h.RPCAddr()))
+	t.Run("SetOnHistogram", setOnHistogram(h.RPCAddr()))
+	t.Run("MetricSub", subMetric(h.RPCAddr()))
+	genericOut, err = getIPV6(env.Port("metrics"))
 	assert.NoError(t, err)
 	assert.Contains(t, genericOut, "sub_gauge_subMetric 1")
 
-	t.Run("SubVector", subVector)
-	genericOut, err = getIPV6()
+	t.Run("SubVector", subVector(h.RPCAddr()))
+	genericOut, err = getIPV6(env.Port("metrics"))
 	assert.NoError(t, err)
 	assert.Contains(t, genericOut, "sub_gauge_subVector{section=\"first\",type=\"core\"} 1")
 
-	t.Run("RegisterHistogram", registerHistogram)
+	t.Run("RegisterHistogram", registerHistogram(h.RPCAddr()))
 
-	genericOut, err = getIPV6()
+	genericOut, err = getIPV6(env.Port("metrics"))
 	assert.NoError(t, err)
 	assert.Contains(t, genericOut, `TYPE histogram_registerHistogram`)
 
@@ -406,27 +208,26 @@ func TestMetricsDifferentRPCCalls(t *testing.T) {
 	assert.Contains(t, genericOut, `histogram_registerHistogram_sum 0`)
 	assert.Contains(t, genericOut, `histogram_registerHistogram_count 0`)
 
-	t.Run("CounterMetric", counterMetric)
-	genericOut, err = getIPV6()
+	t.Run("CounterMetric", counterMetric(h.RPCAddr()))
+	genericOut, err = getIPV6(env.Port("metrics"))
 	assert.NoError(t, err)
 	assert.Contains(t, genericOut, "HELP default_default_counter_CounterMetric test_counter")
 	assert.Contains(t, genericOut, `default_default_counter_CounterMetric{section="section2",type="type2"}`)
 
-	t.Run("ObserveMetric", observeMetric)
-	genericOut, err = getIPV6()
+	t.Run("ObserveMetric", observeMetric(h.RPCAddr()))
+	genericOut, err = getIPV6(env.Port("metrics"))
 	assert.NoError(t, err)
 	assert.Contains(t, genericOut, "observe_observeMetric")
 
-	t.Run("ObserveMetricNotEnoughLabels", observeMetricNotEnoughLabels)
+	t.Run("ObserveMetricNotEnoughLabels", observeMetricNotEnoughLabels(h.RPCAddr()))
 
-	t.Run("ConfiguredCounterMetric", configuredCounterMetric)
-	genericOut, err = getIPV6()
+	t.Run("ConfiguredCounterMetric", configuredCounterMetric(h.RPCAddr()))
+	genericOut, err = getIPV6(env.Port("metrics"))
 	assert.NoError(t, err)
 	assert.Contains(t, genericOut, "HELP app_metric_counter Custom application counter.")
 	assert.Contains(t, genericOut, `app_metric_counter 100`)
 
-	close(sig)
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 0, oLogger.FilterMessageSnippet("http server was started").Len())
 	require.Equal(t, 0, oLogger.FilterMessageSnippet("http log").Len())
@@ -445,538 +246,414 @@ func TestMetricsDifferentRPCCalls(t *testing.T) {
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("failed to get metrics with label values").Len())
 }
 
-func configuredCounterMetric(t *testing.T) {
-	conn, err := net.Dial(dialNetwork, dialAddr)
-	assert.NoError(t, err)
-	defer func() {
-		_ = conn.Close()
-	}()
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-	var ret bool
-
-	assert.NoError(t, client.Call("metrics.Add", metrics.Metric{
-		Name:  "app_metric_counter",
-		Value: 100.0,
-	}, &ret))
-	assert.True(t, ret)
-}
+func configuredCounterMetric(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
 
-func observeMetricNotEnoughLabels(t *testing.T) {
-	conn, err := net.Dial(dialNetwork, dialAddr)
-	assert.NoError(t, err)
-	defer func() {
-		_ = conn.Close()
-	}()
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-	var ret bool
-
-	nc := metrics.NamedCollector{
-		Name: "observe_observeMetricNotEnoughLabels",
-		Collector: metrics.Collector{
-			Namespace: "default",
-			Subsystem: "default",
-			Help:      "test_observe",
-			Type:      metrics.Histogram,
-			Labels:    []string{"type", "section"},
-		},
+		ret, err := client.Metrics.Add(metrics.Metric{
+			Name:  "app_metric_counter",
+			Value: 100.0,
+		})
+		assert.NoError(t, err)
+		assert.True(t, ret)
 	}
-
-	err = client.Call("metrics.Declare", nc, &ret)
-	assert.NoError(t, err)
-	assert.True(t, ret)
-	ret = false
-
-	assert.Error(t, client.Call("metrics.Observe", metrics.Metric{
-		Name:   "observe_observeMetric",
-		Value:  100.0,
-		Labels: []string{"test"},
-	}, &ret))
-	assert.False(t, ret)
 }
 
-func observeMetric(t *testing.T) {
-	conn, err := net.Dial(dialNetwork, dialAddr)
-	assert.NoError(t, err)
-	defer func() {
-		_ = conn.Close()
-	}()
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-	var ret bool
-
-	nc := metrics.NamedCollector{
-		Name: "observe_observeMetric",
-		Collector: metrics.Collector{
-			Namespace: "default",
-			Subsystem: "default",
-			Help:      "test_observe",
-			Type:      metrics.Histogram,
-			Labels:    []string{"type", "section"},
-		},
-	}
+func observeMetricNotEnoughLabels(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
+
+		nc := metrics.NamedCollector{
+			Name: "observe_observeMetricNotEnoughLabels",
+			Collector: metrics.Collector{
+				Namespace: "default",
+				Subsystem: "default",
+				Help:      "test_observe",
+				Type:      metrics.Histogram,
+				Labels:    []string{"type", "section"},
+			},
+		}
 
-	err = client.Call("metrics.Declare", nc, &ret)
-	assert.NoError(t, err)
-	assert.True(t, ret)
-	ret = false
-
-	assert.NoError(t, client.Call("metrics.Observe", metrics.Metric{
-		Name:   "observe_observeMetric",
-		Value:  100.0,
-		Labels: []string{"test", "test2"},
-	}, &ret))
-	assert.True(t, ret)
-}
+		ret, err := client.Metrics.Declare(nc)
+		assert.NoError(t, err)
+		assert.True(t, ret)
 
-func counterMetric(t *testing.T) {
-	conn, err := net.Dial(dialNetwork, dialAddr)
-	assert.NoError(t, err)
-	defer func() {
-		_ = conn.Close()
-	}()
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-	var ret bool
-
-	nc := metrics.NamedCollector{
-		Name: "counter_CounterMetric",
-		Collector: metrics.Collector{
-			Namespace: "default",
-			Subsystem: "default",
-			Help:      "test_counter",
-			Type:      metrics.Counter,
-			Labels:    []string{"type", "section"},
-		},
+		ret, err = client.Metrics.Observe(metrics.Metric{
+			Name:   "observe_observeMetric",
+			Value:  100.0,
+			Labels: []string{"test"},
+		})
+		assert.Error(t, err)
+		assert.False(t, ret)
 	}
+}
 
-	err = client.Call("metrics.Declare", nc, &ret)
-	assert.NoError(t, err)
-	assert.True(t, ret)
+func observeMetric(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
+
+		nc := metrics.NamedCollector{
+			Name: "observe_observeMetric",
+			Collector: metrics.Collector{
+				Namespace: "default",
+				Subsystem: "default",
+				Help:      "test_observe",
+				Type:      metrics.Histogram,
+				Labels:    []string{"type", "section"},
+			},
+		}
 
-	ret = false
+		ret, err := client.Metrics.Declare(nc)
+		assert.NoError(t, err)
+		assert.True(t, ret)
 
-	assert.NoError(t, client.Call("metrics.Add", metrics.Metric{
-		Name:   "counter_CounterMetric",
-		Value:  100.0,
-		Labels: []string{"type2", "section2"},
-	}, &ret))
-	assert.True(t, ret)
+		ret, err = client.Metrics.Observe(metrics.Metric{
+			Name:   "observe_observeMetric",
+			Value:  100.0,
+			Labels: []string{"test", "test2"},
+		})
+		assert.NoError(t, err)
+		assert.True(t, ret)
+	}
 }
 
-func registerHistogram(t *testing.T) {
-	conn, err := net.Dial(dialNetwork, dialAddr)
-	assert.NoError(t, err)
-	defer func() {
-		_ = conn.Close()
-	}()
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-	var ret bool
-
-	nc := metrics.NamedCollector{
-		Name: "histogram_registerHistogram",
-		Collector: metrics.Collector{
-			Help:    "test_histogram",
-			Type:    metrics.Histogram,
-			Buckets: []float64{0.1, 0.2, 0.5},
-		},
+func counterMetric(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
+
+		nc := metrics.NamedCollector{
+			Name: "counter_CounterMetric",
+			Collector: metrics.Collector{
+				Namespace: "default",
+				Subsystem: "default",
+				Help:      "test_counter",
+				Type:      metrics.Counter,
+				Labels:    []string{"type", "section"},
+			},
+		}
+
+		ret, err := client.Metrics.Declare(nc)
+		assert.NoError(t, err)
+		assert.True(t, ret)
+
+		ret, err = client.Metrics.Add(metrics.Metric{
+			Name:   "counter_CounterMetric",
+			Value:  100.0,
+			Labels: []string{"type2", "section2"},
+		})
+		assert.NoError(t, err)
+		assert.True(t, ret)
 	}
+}
 
-	err = client.Call("metrics.Declare", nc, &ret)
-	assert.NoError(t, err)
-	assert.True(t, ret)
+func registerHistogram(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
+
+		nc := metrics.NamedCollector{
+			Name: "histogram_registerHistogram",
+			Collector: metrics.Collector{
+				Help:    "test_histogram",
+				Type:    metrics.Histogram,
+				Buckets: []float64{0.1, 0.2, 0.5},
+			},
+		}
 
-	ret = false
+		ret, err := client.Metrics.Declare(nc)
+		assert.NoError(t, err)
+		assert.True(t, ret)
 
-	m := metrics.Metric{
-		Name:   "histogram_registerHistogram",
-		Value:  10000,
-		Labels: nil,
-	}
+		m := metrics.Metric{
+			Name:   "histogram_registerHistogram",
+			Value:  10000,
+			Labels: nil,
+		}
 
-	err = client.Call("metrics.Add", m, &ret)
-	assert.Error(t, err)
-	assert.False(t, ret)
+		ret, err = client.Metrics.Add(m)
+		assert.Error(t, err)
+		assert.False(t, ret)
+	}
 }
 
-func subVector(t *testing.T) {
-	conn, err := net.Dial(dialNetwork, dialAddr)
-	assert.NoError(t, err)
-	defer func() {
-		_ = conn.Close()
-	}()
+func subVector(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
+
+		nc := metrics.NamedCollector{
+			Name: "sub_gauge_subVector",
+			Collector: metrics.Collector{
+				Namespace: "default",
+				Subsystem: "default",
+				Type:      metrics.Gauge,
+				Labels:    []string{"type", "section"},
+			},
+		}
 
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-	var ret bool
-
-	nc := metrics.NamedCollector{
-		Name: "sub_gauge_subVector",
-		Collector: metrics.Collector{
-			Namespace: "default",
-			Subsystem: "default",
-			Type:      metrics.Gauge,
-			Labels:    []string{"type", "section"},
-		},
-	}
+		ret, err := client.Metrics.Declare(nc)
+		assert.NoError(t, err)
+		assert.True(t, ret)
 
-	err = client.Call("metrics.Declare", nc, &ret)
-	assert.NoError(t, err)
-	assert.True(t, ret)
-	ret = false
+		m := metrics.Metric{
+			Name:   "sub_gauge_subVector",
+			Value:  100000,
+			Labels: []string{"core", "first"},
+		}
 
-	m := metrics.Metric{
-		Name:   "sub_gauge_subVector",
-		Value:  100000,
-		Labels: []string{"core", "first"},
-	}
+		ret, err = client.Metrics.Add(m)
+		assert.NoError(t, err)
+		assert.True(t, ret)
 
-	err = client.Call("metrics.Add", m, &ret)
-	assert.NoError(t, err)
-	assert.True(t, ret)
-	ret = false
+		m = metrics.Metric{
+			Name:   "sub_gauge_subVector",
+			Value:  99999,
+			Labels: []string{"core", "first"},
+		}
 
-	m = metrics.Metric{
-		Name:   "sub_gauge_subVector",
-		Value:  99999,
-		Labels: []string{"core", "first"},
+		ret, err = client.Metrics.Sub(m)
+		assert.NoError(t, err)
+		assert.True(t, ret)
 	}
-
-	err = client.Call("metrics.Sub", m, &ret)
-	assert.NoError(t, err)
-	assert.True(t, ret)
 }
 
-func subMetric(t *testing.T) {
-	conn, err := net.Dial(dialNetwork, dialAddr)
-	assert.NoError(t, err)
-	defer func() {
-		_ = conn.Close()
-	}()
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-	var ret bool
-
-	nc := metrics.NamedCollector{
-		Name: "sub_gauge_subMetric",
-		Collector: metrics.Collector{
-			Namespace: "default",
-			Subsystem: "default",
-			Type:      metrics.Gauge,
-		},
-	}
+func subMetric(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
+
+		nc := metrics.NamedCollector{
+			Name: "sub_gauge_subMetric",
+			Collector: metrics.Collector{
+				Namespace: "default",
+				Subsystem: "default",
+				Type:      metrics.Gauge,
+			},
+		}
 
-	err = client.Call("metrics.Declare", nc, &ret)
-	assert.NoError(t, err)
-	assert.True(t, ret)
-	ret = false
+		ret, err := client.Metrics.Declare(nc)
+		assert.NoError(t, err)
+		assert.True(t, ret)
 
-	m := metrics.Metric{
-		Name:  "sub_gauge_subMetric",
-		Value: 100000,
-	}
+		m := metrics.Metric{
+			Name:  "sub_gauge_subMetric",
+			Value: 100000,
+		}
 
-	err = client.Call("metrics.Add", m, &ret)
-	assert.NoError(t, err)
-	assert.True(t, ret)
-	ret = false
+		ret, err = client.Metrics.Add(m)
+		assert.NoError(t, err)
+		assert.True(t, ret)
 
-	m = metrics.Metric{
-		Name:  "sub_gauge_subMetric",
-		Value: 99999,
-	}
+		m = metrics.Metric{
+			Name:  "sub_gauge_subMetric",
+			Value: 99999,
+		}
 
-	err = client.Call("metrics.Sub", m, &ret)
-	assert.NoError(t, err)
-	assert.True(t, ret)
+		ret, err = client.Metrics.Sub(m)
+		assert.NoError(t, err)
+		assert.True(t, ret)
+	}
 }
 
-func setOnHistogram(t *testing.T) {
-	conn, err := net.Dial(dialNetwork, dialAddr)
-	assert.NoError(t, err)
-	defer func() {
-		_ = conn.Close()
-	}()
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-	var ret bool
-
-	nc := metrics.NamedCollector{
-		Name: "histogram_setOnHistogram",
-		Collector: metrics.Collector{
-			Namespace: "default",
-			Subsystem: "default",
-			Type:      metrics.Histogram,
-			Labels:    []string{"type", "section"},
-		},
-	}
+func setOnHistogram(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
+
+		nc := metrics.NamedCollector{
+			Name: "histogram_setOnHistogram",
+			Collector: metrics.Collector{
+				Namespace: "default",
+				Subsystem: "default",
+				Type:      metrics.Histogram,
+				Labels:    []string{"type", "section"},
+			},
+		}
 
-	err = client.Call("metrics.Declare", nc, &ret)
-	assert.NoError(t, err)
-	assert.True(t, ret)
+		ret, err := client.Metrics.Declare(nc)
+		assert.NoError(t, err)
+		assert.True(t, ret)
 
-	ret = false
+		m := metrics.Metric{
+			Name:  "gauge_setOnHistogram",
+			Value: 100.0,
+		}
 
-	m := metrics.Metric{
-		Name:  "gauge_setOnHistogram",
-		Value: 100.0,
+		ret, err = client.Metrics.Set(m) // expected 2 label values but got 1 in []string{"missing"}
+		assert.Error(t, err)
+		assert.False(t, ret)
 	}
-
-	err = client.Call("metrics.Set", m, &ret) // expected 2 label values but got 1 in []string{"missing"}
-	assert.Error(t, err)
-	assert.False(t, ret)
 }
 
-func setWithoutLabels(t *testing.T) {
-	conn, err := net.Dial(dialNetwork, dialAddr)
-	assert.NoError(t, err)
-	defer func() {
-		_ = conn.Close()
-	}()
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-	var ret bool
-
-	nc := metrics.NamedCollector{
-		Name: "gauge_setWithoutLabels",
-		Collector: metrics.Collector{
-			Namespace: "default",
-			Subsystem: "default",
-			Type:      metrics.Gauge,
-			Labels:    []string{"type", "section"},
-		},
-	}
+func setWithoutLabels(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
+
+		nc := metrics.NamedCollector{
+			Name: "gauge_setWithoutLabels",
+			Collector: metrics.Collector{
+				Namespace: "default",
+				Subsystem: "default",
+				Type:      metrics.Gauge,
+				Labels:    []string{"type", "section"},
+			},
+		}
 
-	err = client.Call("metrics.Declare", nc, &ret)
-	assert.NoError(t, err)
-	assert.True(t, ret)
+		ret, err := client.Metrics.Declare(nc)
+		assert.NoError(t, err)
+		assert.True(t, ret)
 
-	ret = false
+		m := metrics.Metric{
+			Name:  "gauge_setWithoutLabels",
+			Value: 100.0,
+		}
 
-	m := metrics.Metric{
-		Name:  "gauge_setWithoutLabels",
-		Value: 100.0,
+		ret, err = client.Metrics.Set(m) // expected 2 label values but got 1 in []string{"missing"}
+		assert.Error(t, err)
+		assert.False(t, ret)
 	}
-
-	err = client.Call("metrics.Set", m, &ret) // expected 2 label values but got 1 in []string{"missing"}
-	assert.Error(t, err)
-	assert.False(t, ret)
 }
 
-func missingSection(t *testing.T) {
-	conn, err := net.Dial(dialNetwork, dialAddr)
-	assert.NoError(t, err)
-	defer func() {
-		_ = conn.Close()
-	}()
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-	var ret bool
-
-	nc := metrics.NamedCollector{
-		Name: "gauge_missing_section_collector",
-		Collector: metrics.Collector{
-			Namespace: "default",
-			Subsystem: "default",
-			Type:      metrics.Gauge,
-			Labels:    []string{"type", "section"},
-		},
-	}
+func missingSection(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
+
+		nc := metrics.NamedCollector{
+			Name: "gauge_missing_section_collector",
+			Collector: metrics.Collector{
+				Namespace: "default",
+				Subsystem: "default",
+				Type:      metrics.Gauge,
+				Labels:    []string{"type", "section"},
+			},
+		}
 
-	err = client.Call("metrics.Declare", nc, &ret)
-	assert.NoError(t, err)
-	assert.True(t, ret)
+		ret, err := client.Metrics.Declare(nc)
+		assert.NoError(t, err)
+		assert.True(t, ret)
 
-	ret = false
+		m := metrics.Metric{
+			Name:   "gauge_missing_section_collector",
+			Value:  100.0,
+			Labels: []string{"missing"},
+		}
 
-	m := metrics.Metric{
-		Name:   "gauge_missing_section_collector",
-		Value:  100.0,
-		Labels: []string{"missing"},
+		ret, err = client.Metrics.Set(m) // expected 2 label values but got 1 in []string{"missing"}
+		assert.Error(t, err)
+		assert.False(t, ret)
 	}
-
-	err = client.Call("metrics.Set", m, &ret) // expected 2 label values but got 1 in []string{"missing"}
-	assert.Error(t, err)
-	assert.False(t, ret)
 }
 
-func vectorMetric(t *testing.T) {
-	conn, err := net.Dial(dialNetwork, dialAddr)
-	assert.NoError(t, err)
-	defer func() {
-		_ = conn.Close()
-	}()
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-	var ret bool
-
-	nc := metrics.NamedCollector{
-		Name: "gauge_2_collector",
-		Collector: metrics.Collector{
-			Namespace: "default",
-			Subsystem: "default",
-			Type:      metrics.Gauge,
-			Labels:    []string{"type", "section"},
-		},
-	}
+func vectorMetric(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
+
+		nc := metrics.NamedCollector{
+			Name: "gauge_2_collector",
+			Collector: metrics.Collector{
+				Namespace: "default",
+				Subsystem: "default",
+				Type:      metrics.Gauge,
+				Labels:    []string{"type", "section"},
+			},
+		}
 
-	err = client.Call("metrics.Declare", nc, &ret)
-	assert.NoError(t, err)
-	assert.True(t, ret)
+		ret, err := client.Metrics.Declare(nc)
+		assert.NoError(t, err)
+		assert.True(t, ret)
 
-	ret = false
+		m := metrics.Metric{
+			Name:   "gauge_2_collector",
+			Value:  100.0,
+			Labels: []string{"core", "first"},
+		}
 
-	m := metrics.Metric{
-		Name:   "gauge_2_collector",
-		Value:  100.0,
-		Labels: []string{"core", "first"},
+		ret, err = client.Metrics.Set(m)
+		assert.NoError(t, err)
+		assert.True(t, ret)
 	}
-
-	err = client.Call("metrics.Set", m, &ret)
-	assert.NoError(t, err)
-	assert.True(t, ret)
 }
 
-func setMetric(t *testing.T) {
-	conn, err := net.Dial(dialNetwork, dialAddr)
-	assert.NoError(t, err)
-	defer func() {
-		_ = conn.Close()
-	}()
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-	var ret bool
-
-	nc := metrics.NamedCollector{
-		Name: "user_gauge_collector",
-		Collector: metrics.Collector{
-			Namespace: "default",
-			Subsystem: "default",
-			Type:      metrics.Gauge,
-		},
-	}
+func setMetric(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
+
+		nc := metrics.NamedCollector{
+			Name: "user_gauge_collector",
+			Collector: metrics.Collector{
+				Namespace: "default",
+				Subsystem: "default",
+				Type:      metrics.Gauge,
+			},
+		}
 
-	err = client.Call("metrics.Declare", nc, &ret)
-	assert.NoError(t, err)
-	assert.True(t, ret)
-	ret = false
+		ret, err := client.Metrics.Declare(nc)
+		assert.NoError(t, err)
+		assert.True(t, ret)
 
-	m := metrics.Metric{
-		Name:  "user_gauge_collector",
-		Value: 100.0,
-	}
+		m := metrics.Metric{
+			Name:  "user_gauge_collector",
+			Value: 100.0,
+		}
 
-	err = client.Call("metrics.Set", m, &ret)
-	assert.NoError(t, err)
-	assert.True(t, ret)
+		ret, err = client.Metrics.Set(m)
+		assert.NoError(t, err)
+		assert.True(t, ret)
+	}
 }
 
-func addMetricsTest(t *testing.T) {
-	conn, err := net.Dial(dialNetwork, dialAddr)
-	assert.NoError(t, err)
-	defer func() {
-		_ = conn.Close()
-	}()
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-	var ret bool
+func addMetricsTest(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
 
-	m := metrics.Metric{
-		Name:   "test_metrics_named_collector",
-		Value:  10000,
-		Labels: nil,
-	}
+		m := metrics.Metric{
+			Name:   "test_metrics_named_collector",
+			Value:  10000,
+			Labels: nil,
+		}
 
-	err = client.Call("metrics.Add", m, &ret)
-	assert.NoError(t, err)
-	assert.True(t, ret)
+		ret, err := client.Metrics.Add(m)
+		assert.NoError(t, err)
+		assert.True(t, ret)
+	}
 }
 
-func declareMetricsTest(t *testing.T) {
-	conn, err := net.Dial(dialNetwork, dialAddr)
-	assert.NoError(t, err)
-	defer func() {
-		_ = conn.Close()
-	}()
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-	var ret bool
-
-	nc := metrics.NamedCollector{
-		Name: "test_metrics_named_collector",
-		Collector: metrics.Collector{
-			Namespace: "default",
-			Subsystem: "default",
-			Type:      metrics.Counter,
-			Help:      "NO HELP!",
-			Labels:    nil,
-			Buckets:   nil,
-		},
-	}
+func declareMetricsTest(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
+
+		nc := metrics.NamedCollector{
+			Name: "test_metrics_named_collector",
+			Collector: metrics.Collector{
+				Namespace: "default",
+				Subsystem: "default",
+				Type:      metrics.Counter,
+				Help:      "NO HELP!",
+				Labels:    nil,
+				Buckets:   nil,
+			},
+		}
 
-	err = client.Call("metrics.Declare", nc, &ret)
-	assert.NoError(t, err)
-	assert.True(t, ret)
+		ret, err := client.Metrics.Declare(nc)
+		assert.NoError(t, err)
+		assert.True(t, ret)
+	}
 }
 
 func TestHTTPMetrics(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	cfg := &config.Plugin{}
-	cfg.Prefix = "rr"
-	cfg.Path = "configs/.rr-http-metrics.yaml"
+	env := harness.NewEnv(t)
 
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-http-metrics.yaml",
 		&metrics.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
 		l,
 		&prometheus.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	tt := time.NewTimer(time.Minute * 3)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	go func() {
-		defer tt.Stop()
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-tt.C:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 2)
-	t.Run("req1", echoHTTP("13223"))
-	t.Run("req2", echoHTTP("13223"))
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	t.Run("req1", echoHTTP(strconv.Itoa(env.Port("http"))))
+	t.Run("req2", echoHTTP(strconv.Itoa(env.Port("http"))))
 
 	time.Sleep(time.Millisecond * 500)
-	genericOut, err := get()
+	genericOut, err := get(env.Addr("metrics"))
 	assert.NoError(t, err)
 	assert.Contains(t, genericOut, `rr_http_request_duration_seconds_bucket`)
 	assert.Contains(t, genericOut, `rr_http_request_duration_seconds_sum{status="200"}`)
@@ -987,8 +664,7 @@ func TestHTTPMetrics(t *testing.T) {
 	assert.Contains(t, genericOut, `{pid=`)
 	assert.Contains(t, genericOut, `rr_http_total_workers 10`)
 
-	close(sig)
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 2, oLogger.FilterMessageSnippet("http log").Len())
 }
@@ -1009,103 +685,34 @@ func echoHTTP(port string) func(t *testing.T) {
 }
 
 func TestHTTPMetricsNoFreeWorkers(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	cfg := &config.Plugin{}
-	cfg.Prefix = "rr"
-	cfg.Path = "configs/.rr-http-metrics-no-free-workers.yaml"
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-http-metrics-no-free-workers.yaml",
 		&metrics.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
 		&logger.Plugin{},
 		&prometheus.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	tt := time.NewTimer(time.Minute * 3)
 
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
 	go func() {
-		defer tt.Stop()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-tt.C:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 2)
-	go func() {
-		t.Run("req_slow", echoHTTP("15442"))
+		t.Run("req_slow", echoHTTP(strconv.Itoa(env.Port("http"))))
 	}()
 	time.Sleep(time.Second * 2)
-	t.Run("req2", echoHTTP("15442"))
+	t.Run("req2", echoHTTP(strconv.Itoa(env.Port("http"))))
 
-	genericOut, err := get2()
+	genericOut, err := get(env.Addr("metrics"))
 	assert.NoError(t, err)
 	assert.Contains(t, genericOut, `rr_http_requests_queue`)
 	assert.Contains(t, genericOut, `rr_http_no_free_workers_total 1`)
 
-	close(sig)
-}
-
-// get request and return body
-func get() (string, error) {
-	r, err := http.Get(getAddr)
-	if err != nil {
-		return "", err
-	}
-
-	b, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		return "", err
-	}
-
-	err = r.Body.Close()
-	if err != nil {
-		return "", err
-	}
-	// unsafe
-	return string(b), err
+	h.Stop()
 }
 
 // get request and return body
-func get2() (string, error) {
-	r, err := http.Get(getAddr2)
+func get(addr string) (string, error) {
+	r, err := http.Get("http://" + addr + "/metrics")
 	if err != nil {
 		return "", err
 	}
@@ -1124,8 +731,8 @@ func get2() (string, error) {
 }
 
 // get request and return body
-func getIPV6() (string, error) {
-	r, err := http.Get(getIPV6Addr)
+func getIPV6(port int) (string, error) {
+	r, err := http.Get("http://[::1]:" + strconv.Itoa(port) + "/metrics")
 	if err != nil {
 		return "", err
 	}
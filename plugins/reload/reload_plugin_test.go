@@ -20,6 +20,7 @@ import (
 	"github.com/roadrunner-server/logger/v2"
 	"github.com/roadrunner-server/reload/v2"
 	"github.com/roadrunner-server/resetter/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	mocklogger "github.com/roadrunner-server/rr-e2e-tests/mock"
 	"github.com/roadrunner-server/server/v2"
 	"github.com/stretchr/testify/assert"
@@ -33,76 +34,24 @@ const dir1 string = "dir1"
 const hugeNumberOfFiles uint = 500
 
 func TestReloadInit(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-reload.yaml",
-		Prefix: "rr",
-	}
-
 	// try to remove, skip error
 	assert.NoError(t, freeResources(testDir))
-	err = os.Mkdir(testDir, 0755)
+	err := os.Mkdir(testDir, 0755)
 	assert.NoError(t, err)
 
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-reload.yaml",
 		l,
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
 		&reload.Plugin{},
 		&resetter.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	assert.NoError(t, err)
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
 	time.Sleep(time.Second * 3)
 	t.Run("ReloadTestInit", reloadTestInit)
 	time.Sleep(time.Second * 3)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("http server was started").Len())
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("file was created").Len())
@@ -121,11 +70,13 @@ func reloadTestInit(t *testing.T) {
 }
 
 func TestReloadBadWorker(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
 	assert.NoError(t, err)
 
 	cfg := &config.Plugin{
-		Path:   "configs/.rr-reload-bad-worker.yaml",
+		Path:   env.Render("configs/.rr-reload-bad-worker.yaml"),
 		Prefix: "rr",
 	}
 
@@ -215,14 +166,6 @@ func TestReloadBadWorker(t *testing.T) {
 }
 
 func TestReloadHugeNumberOfFiles(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-reload.yaml",
-		Prefix: "rr",
-	}
-
 	// try to remove, skip error
 	assert.NoError(t, freeResources(testDir))
 	assert.NoError(t, freeResources(testCopyToDir))
@@ -231,64 +174,20 @@ func TestReloadHugeNumberOfFiles(t *testing.T) {
 	assert.NoError(t, os.Mkdir(testCopyToDir, 0755))
 
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-reload.yaml",
 		l,
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
 		&reload.Plugin{},
 		&resetter.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	assert.NoError(t, err)
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
 	time.Sleep(time.Second * 3)
 	t.Run("ReloadTestHugeNumberOfFiles", reloadHugeNumberOfFiles)
 	t.Run("ReloadRandomlyChangeFile", randomlyChangeFile)
 	time.Sleep(time.Second * 10)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.GreaterOrEqual(t, oLogger.Len(), 500)
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("http server was started").Len())
@@ -325,68 +224,16 @@ func reloadHugeNumberOfFiles(t *testing.T) {
 
 // Should be events only about creating files with txt ext
 func TestReloadFilterFileExt(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-reload-2.yaml",
-		Prefix: "rr",
-	}
-
 	// try to remove, skip error
 	assert.NoError(t, freeResources(testDir))
 	assert.NoError(t, os.Mkdir(testDir, 0755))
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-reload-2.yaml",
 		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
 		&reload.Plugin{},
 		&resetter.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	assert.NoError(t, err)
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
 	time.Sleep(time.Second * 3)
 	t.Run("ReloadMakeFiles", reloadMakeFiles)
@@ -394,8 +241,7 @@ func TestReloadFilterFileExt(t *testing.T) {
 	t.Run("ReloadFilteredExt", reloadFilteredExt)
 	time.Sleep(time.Second * 10)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	t.Cleanup(func() {
 		_ = os.RemoveAll(testDir)
@@ -438,14 +284,6 @@ func reloadFilteredExt(t *testing.T) {
 
 // Should be events only about creating files with txt ext
 func TestReloadCopy100(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-reload-3.yaml",
-		Prefix: "rr",
-	}
-
 	// try to remove, skip error
 	assert.NoError(t, freeResources(testDir))
 	assert.NoError(t, freeResources(testCopyToDir))
@@ -456,57 +294,13 @@ func TestReloadCopy100(t *testing.T) {
 	assert.NoError(t, os.Mkdir(dir1, 0755))
 
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-reload-3.yaml",
 		l,
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
 		&reload.Plugin{},
 		&resetter.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	assert.NoError(t, err)
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
 	// Scenario
 	// 1
@@ -541,8 +335,7 @@ func TestReloadCopy100(t *testing.T) {
 
 	time.Sleep(time.Second * 5)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Greater(t, oLogger.FilterMessageSnippet("file was updated").Len(), 0)
 	require.Greater(t, oLogger.FilterMessageSnippet("file added to the list of removed files").Len(), 0)
@@ -720,14 +513,6 @@ func reloadMake100Files(t *testing.T) {
 }
 
 func TestReloadNoRecursion(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-reload-4.yaml",
-		Prefix: "rr",
-	}
-
 	// try to remove, skip error
 	assert.NoError(t, freeResources(testDir))
 	assert.NoError(t, freeResources(testCopyToDir))
@@ -736,57 +521,13 @@ func TestReloadNoRecursion(t *testing.T) {
 	assert.NoError(t, os.Mkdir(testDir, 0755))
 	assert.NoError(t, os.Mkdir(dir1, 0755))
 	assert.NoError(t, os.Mkdir(testCopyToDir, 0755))
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-reload-4.yaml",
 		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
 		&reload.Plugin{},
 		&resetter.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	assert.NoError(t, err)
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
 	time.Sleep(time.Second * 3)
 	t.Run("ReloadMakeFiles", reloadMakeFiles) // make files in the testDir
@@ -796,8 +537,7 @@ func TestReloadNoRecursion(t *testing.T) {
 	assert.NoError(t, freeResources(testDir))
 	time.Sleep(time.Second * 10)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	t.Cleanup(func() {
 		_ = os.RemoveAll(testCopyToDir)
@@ -1,20 +1,11 @@
 package resetter
 
 import (
-	"net"
-	"net/rpc"
-	"os"
-	"os/signal"
-	"sync"
-	"syscall"
 	"testing"
-	"time"
 
-	"github.com/roadrunner-server/config/v2"
-	endure "github.com/roadrunner-server/endure/pkg/container"
-	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
 	"github.com/roadrunner-server/resetter/v2"
 	rpcPlugin "github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	mocklogger "github.com/roadrunner-server/rr-e2e-tests/mock"
 	"github.com/roadrunner-server/server/v2"
 	"github.com/stretchr/testify/assert"
@@ -23,96 +14,36 @@ import (
 )
 
 func TestResetterInit(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	cfg := &config.Plugin{
-		Path:   ".rr-resetter.yaml",
-		Prefix: "rr",
-	}
-
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, ".rr-resetter.yaml",
 		&server.Plugin{},
 		l,
 		&resetter.Plugin{},
 		&rpcPlugin.Plugin{},
 		&Plugin1{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	harness.WaitForTCP(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
-	stopCh := make(chan struct{}, 1)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second)
-
-	t.Run("ResetterRpcTest", resetterRPCTest)
-	stopCh <- struct{}{}
-	wg.Wait()
+	t.Run("ResetterRpcTest", resetterRPCTest(h.RPCAddr()))
+	h.Stop()
 
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("plugin was started").Len())
 }
 
-func resetterRPCTest(t *testing.T) {
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
-	assert.NoError(t, err)
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-	// WorkerList contains list of workers.
+func resetterRPCTest(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client := harness.Dial(t, addr)
 
-	var ret bool
-	err = client.Call("resetter.Reset", "resetter.plugin1", &ret)
-	assert.NoError(t, err)
-	assert.True(t, ret)
-	ret = false
+		ret, err := client.Resetter.Reset("resetter.plugin1")
+		assert.NoError(t, err)
+		assert.True(t, ret)
 
-	var services []string
-	err = client.Call("resetter.List", nil, &services)
-	assert.NotNil(t, services)
-	assert.NoError(t, err)
-	if services[0] != "resetter.plugin1" {
-		t.Fatal("no enough services")
+		services, err := client.Resetter.List()
+		assert.NotNil(t, services)
+		assert.NoError(t, err)
+		if services[0] != "resetter.plugin1" {
+			t.Fatal("no enough services")
+		}
 	}
 }
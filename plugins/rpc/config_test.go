@@ -2,14 +2,17 @@ package rpc
 
 import (
 	"runtime"
+	"strconv"
 	"testing"
 
 	"github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestConfig_Listener(t *testing.T) {
-	cfg := &rpc.Config{Listen: "tcp://:18001"}
+	port := strconv.Itoa(harness.NewEnv(t).Port("rpc"))
+	cfg := &rpc.Config{Listen: "tcp://:" + port}
 
 	ln, err := cfg.Listener()
 	assert.NoError(t, err)
@@ -23,14 +26,15 @@ func TestConfig_Listener(t *testing.T) {
 
 	assert.Equal(t, "tcp", ln.Addr().Network())
 	if runtime.GOOS == "windows" {
-		assert.Equal(t, "[::]:18001", ln.Addr().String())
+		assert.Equal(t, "[::]:"+port, ln.Addr().String())
 	} else {
-		assert.Equal(t, "0.0.0.0:18001", ln.Addr().String())
+		assert.Equal(t, "0.0.0.0:"+port, ln.Addr().String())
 	}
 }
 
 func TestConfig_Listener2(t *testing.T) {
-	cfg := &rpc.Config{Listen: ":18001"}
+	port := strconv.Itoa(harness.NewEnv(t).Port("rpc"))
+	cfg := &rpc.Config{Listen: ":" + port}
 
 	ln, err := cfg.Listener()
 	assert.NoError(t, err)
@@ -44,14 +48,15 @@ func TestConfig_Listener2(t *testing.T) {
 
 	assert.Equal(t, "tcp", ln.Addr().Network())
 	if runtime.GOOS == "windows" {
-		assert.Equal(t, "[::]:18001", ln.Addr().String())
+		assert.Equal(t, "[::]:"+port, ln.Addr().String())
 	} else {
-		assert.Equal(t, "0.0.0.0:18001", ln.Addr().String())
+		assert.Equal(t, "0.0.0.0:"+port, ln.Addr().String())
 	}
 }
 
 func TestConfig_ListenerIPV6(t *testing.T) {
-	cfg := &rpc.Config{Listen: "tcp://[::]:18001"}
+	port := strconv.Itoa(harness.NewEnv(t).Port("rpc"))
+	cfg := &rpc.Config{Listen: "tcp://[::]:" + port}
 
 	ln, err := cfg.Listener()
 	assert.NoError(t, err)
@@ -64,7 +69,7 @@ func TestConfig_ListenerIPV6(t *testing.T) {
 	}()
 
 	assert.Equal(t, "tcp", ln.Addr().Network())
-	assert.Equal(t, "[::]:18001", ln.Addr().String())
+	assert.Equal(t, "[::]:"+port, ln.Addr().String())
 }
 
 func TestConfig_ListenerUnix(t *testing.T) {
@@ -100,7 +105,8 @@ func Test_Config_ErrorMethod(t *testing.T) {
 }
 
 func TestConfig_Dialer(t *testing.T) {
-	cfg := &rpc.Config{Listen: "tcp://:18001"}
+	port := strconv.Itoa(harness.NewEnv(t).Port("rpc"))
+	cfg := &rpc.Config{Listen: "tcp://:" + port}
 
 	ln, _ := cfg.Listener()
 	defer func() {
@@ -121,7 +127,7 @@ func TestConfig_Dialer(t *testing.T) {
 	}()
 
 	assert.Equal(t, "tcp", conn.RemoteAddr().Network())
-	assert.Equal(t, "127.0.0.1:18001", conn.RemoteAddr().String())
+	assert.Equal(t, "127.0.0.1:"+port, conn.RemoteAddr().String())
 }
 
 func TestConfig_DialerUnix(t *testing.T) {
@@ -1,21 +1,32 @@
 package rpc
 
 import (
-	"net"
-	"net/rpc"
+	"strings"
 	"time"
 
+	"github.com/roadrunner-server/api/v2/plugins/config"
 	"github.com/roadrunner-server/errors"
-	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
+	"github.com/roadrunner-server/rr-e2e-tests/rpcclient"
 )
 
 // Plugin2 makes a call to the plugin1 via RPC
 // this is just a simulation of external call FOR TEST
 // you don't need to do such things :)
 type Plugin2 struct {
+	addr string
 }
 
-func (p2 *Plugin2) Init() error {
+func (p2 *Plugin2) Init(cfg config.Configurer) error {
+	// the address is rendered into the config by the test, empty when the rpc is disabled (the dial fails)
+	rpcCfg := &struct {
+		Listen string `mapstructure:"listen"`
+	}{}
+	err := cfg.UnmarshalKey("rpc", rpcCfg)
+	if err != nil {
+		return errors.E(errors.Init, err)
+	}
+
+	p2.addr = strings.TrimPrefix(rpcCfg.Listen, "tcp://")
 	return nil
 }
 
@@ -25,12 +36,15 @@ func (p2 *Plugin2) Serve() chan error {
 	go func() {
 		time.Sleep(time.Second * 3)
 
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
+		client, err := rpcclient.Dial(p2.addr)
 		if err != nil {
 			errCh <- errors.E(errors.Serve, err)
 			return
 		}
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
+		defer func() {
+			_ = client.Close()
+		}()
+
 		var ret string
 		err = client.Call("rpc_test.plugin1.Hello", "Valery", &ret)
 		if err != nil {
@@ -13,11 +13,14 @@ import (
 	"github.com/roadrunner-server/errors"
 	"github.com/roadrunner-server/logger/v2"
 	"github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	"github.com/stretchr/testify/assert"
 )
 
 // graph https://bit.ly/3ensdNb
 func TestRpcInit(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
 	if err != nil {
 		t.Fatal(err)
@@ -34,7 +37,7 @@ func TestRpcInit(t *testing.T) {
 	}
 
 	v := &config.Plugin{}
-	v.Path = "configs/.rr.yaml"
+	v.Path = env.Render("configs/.rr.yaml")
 	v.Prefix = "rr"
 	err = cont.Register(v)
 	if err != nil {
@@ -1,193 +1,51 @@
 package server
 
 import (
-	"os"
-	"os/signal"
-	"sync"
-	"syscall"
 	"testing"
 	"time"
 
 	"github.com/roadrunner-server/config/v2"
 	endure "github.com/roadrunner-server/endure/pkg/container"
 	"github.com/roadrunner-server/logger/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	mock_logger "github.com/roadrunner-server/rr-e2e-tests/mock"
 	"github.com/roadrunner-server/server/v2"
-	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
 
 func TestAppPipes(t *testing.T) {
-	container, err := endure.NewContainer(nil, endure.RetryOnFail(true), endure.SetLogLevel(endure.ErrorLevel))
-	require.NoError(t, err)
-
-	// config plugin
-	vp := &config.Plugin{}
-	vp.Path = "configs/.rr.yaml"
-	vp.Prefix = "rr"
-
-	err = container.RegisterAll(
-		vp,
+	h := harness.NewEnv(t).Options(endure.RetryOnFail(true)).Start("configs/.rr.yaml",
 		&server.Plugin{},
 		&Foo{},
 		&logger.Plugin{},
 	)
-	require.NoError(t, err)
-
-	err = container.Init()
-	require.NoError(t, err)
 
-	errCh, err := container.Serve()
-	require.NoError(t, err)
-
-	// stop by CTRL+C
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-
-	tt := time.NewTimer(time.Second * 10)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	go func() {
-		defer wg.Done()
-		defer tt.Stop()
-		for {
-			select {
-			case e := <-errCh:
-				assert.NoError(t, e.Error)
-				assert.NoError(t, container.Stop())
-				return
-			case <-c:
-				er := container.Stop()
-				assert.NoError(t, er)
-				return
-			case <-tt.C:
-				assert.NoError(t, container.Stop())
-				return
-			}
-		}
-	}()
-
-	wg.Wait()
+	time.Sleep(time.Second * 10)
+	h.Stop()
 }
 
 func TestAppSockets(t *testing.T) {
-	container, err := endure.NewContainer(nil, endure.RetryOnFail(true), endure.SetLogLevel(endure.ErrorLevel))
-	require.NoError(t, err)
-
-	// config plugin
-	vp := &config.Plugin{}
-	vp.Path = "configs/.rr-sockets.yaml"
-	vp.Prefix = "rr"
-
-	err = container.RegisterAll(
-		vp,
+	h := harness.NewEnv(t).Options(endure.RetryOnFail(true)).Start("configs/.rr-sockets.yaml",
 		&server.Plugin{},
 		&Foo2{},
 		&logger.Plugin{},
 	)
-	require.NoError(t, err)
 
-	err = container.Init()
-	require.NoError(t, err)
-
-	errCh, err := container.Serve()
-	require.NoError(t, err)
-
-	// stop by CTRL+C
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-
-	// stop after 10 seconds
-	tt := time.NewTicker(time.Second * 10)
-
-	for {
-		select {
-		case e := <-errCh:
-			assert.NoError(t, e.Error)
-			assert.NoError(t, container.Stop())
-			tt.Stop()
-			return
-		case <-c:
-			er := container.Stop()
-			tt.Stop()
-			if er != nil {
-				panic(er)
-			}
-			return
-		case <-tt.C:
-			tt.Stop()
-			assert.NoError(t, container.Stop())
-			return
-		}
-	}
+	time.Sleep(time.Second * 10)
+	h.Stop()
 }
 
 func TestAppTCPOnInit(t *testing.T) {
-	container, err := endure.NewContainer(nil, endure.RetryOnFail(true), endure.SetLogLevel(endure.ErrorLevel))
-	require.NoError(t, err)
-
-	// config plugin
-	vp := &config.Plugin{}
-	vp.Path = "configs/.rr-tcp-on-init.yaml"
-	vp.Prefix = "rr"
-	err = container.Register(vp)
-	require.NoError(t, err)
-
 	l, oLogger := mock_logger.ZapTestLogger(zap.DebugLevel)
-	err = container.RegisterAll(
+	h := harness.NewEnv(t).Options(endure.RetryOnFail(true)).Start("configs/.rr-tcp-on-init.yaml",
 		l,
 		&server.Plugin{},
 		&Foo2{},
 	)
-	require.NoError(t, err)
-
-	err = container.Init()
-	require.NoError(t, err)
-
-	ch, err := container.Serve()
-	require.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = container.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-sig:
-				err = container.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = container.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
 	time.Sleep(time.Second * 10)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("The number is: 0").Len())
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("The number is: 1").Len())
@@ -198,70 +56,15 @@ func TestAppTCPOnInit(t *testing.T) {
 }
 
 func TestAppSocketsOnInit(t *testing.T) {
-	container, err := endure.NewContainer(nil, endure.RetryOnFail(true), endure.SetLogLevel(endure.ErrorLevel))
-	if err != nil {
-		t.Fatal(err)
-	}
-	// config plugin
-	vp := &config.Plugin{}
-	vp.Path = "configs/.rr-sockets-on-init.yaml"
-	vp.Prefix = "rr"
-	err = container.Register(vp)
-	require.NoError(t, err)
-
 	l, oLogger := mock_logger.ZapTestLogger(zap.DebugLevel)
-	err = container.RegisterAll(
+	h := harness.NewEnv(t).Options(endure.RetryOnFail(true)).Start("configs/.rr-sockets-on-init.yaml",
 		l,
 		&server.Plugin{},
 		&Foo2{},
 	)
-	require.NoError(t, err)
-
-	err = container.Init()
-	require.NoError(t, err)
-
-	ch, err := container.Serve()
-	require.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = container.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-sig:
-				err = container.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = container.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
 	time.Sleep(time.Second * 10)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("The number is: 0\n").Len())
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("The number is: 1\n").Len())
@@ -272,122 +75,28 @@ func TestAppSocketsOnInit(t *testing.T) {
 }
 
 func TestAppSocketsOnInitFastClose(t *testing.T) {
-	container, err := endure.NewContainer(nil, endure.RetryOnFail(true), endure.SetLogLevel(endure.ErrorLevel))
-	if err != nil {
-		t.Fatal(err)
-	}
-	// config plugin
-	vp := &config.Plugin{}
-	vp.Path = "configs/.rr-sockets-on-init-fast-close.yaml"
-	vp.Prefix = "rr"
-	err = container.Register(vp)
-	require.NoError(t, err)
-
 	l, oLogger := mock_logger.ZapTestLogger(zap.DebugLevel)
-	err = container.RegisterAll(
+	h := harness.NewEnv(t).Options(endure.RetryOnFail(true)).Start("configs/.rr-sockets-on-init-fast-close.yaml",
 		l,
 		&server.Plugin{},
 		&Foo2{},
 	)
-	require.NoError(t, err)
-
-	err = container.Init()
-	require.NoError(t, err)
-
-	ch, err := container.Serve()
-	require.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = container.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-sig:
-				err = container.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = container.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
 	time.Sleep(time.Second * 10)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 
 	require.Equal(t, 1, oLogger.FilterMessageSnippet("process wait").Len())
 }
 
 func TestAppTCP(t *testing.T) {
-	container, err := endure.NewContainer(nil, endure.RetryOnFail(true), endure.SetLogLevel(endure.ErrorLevel))
-	require.NoError(t, err)
-
-	// config plugin
-	vp := &config.Plugin{}
-	vp.Path = "configs/.rr-tcp.yaml"
-	vp.Prefix = "rr"
-
-	err = container.RegisterAll(
-		vp,
+	h := harness.NewEnv(t).Options(endure.RetryOnFail(true)).Start("configs/.rr-tcp.yaml",
 		&server.Plugin{},
 		&Foo3{},
 		&logger.Plugin{},
 	)
-	require.NoError(t, err)
-
-	err = container.Init()
-	require.NoError(t, err)
 
-	errCh, err := container.Serve()
-	require.NoError(t, err)
-
-	// stop by CTRL+C
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-
-	// stop after 10 seconds
-	tt := time.NewTicker(time.Second * 10)
-
-	for {
-		select {
-		case e := <-errCh:
-			assert.NoError(t, e.Error)
-			assert.NoError(t, container.Stop())
-			return
-		case <-c:
-			er := container.Stop()
-			if er != nil {
-				panic(er)
-			}
-			return
-		case <-tt.C:
-			tt.Stop()
-			assert.NoError(t, container.Stop())
-			return
-		}
-	}
+	time.Sleep(time.Second * 10)
+	h.Stop()
 }
 
 func TestAppWrongConfig(t *testing.T) {
@@ -8,11 +8,7 @@ use Spiral\RoadRunner;
 
 require dirname(__DIR__) . "/../php_test_files/vendor/autoload.php";
 
-$relay = new Goridge\SocketRelay(
-            "unix.sock",
-            null,
-            Goridge\SocketRelay::SOCK_UNIX
-        );
+$relay = Goridge\Relay::create(RoadRunner\Environment::fromGlobals()->getRelayAddress());
 
 $rr = new RoadRunner\Worker($relay);
 
@@ -8,7 +8,7 @@ use Spiral\RoadRunner;
 
 require dirname(__DIR__) . "/../php_test_files/vendor/autoload.php";
 
-$relay = new Goridge\SocketRelay("127.0.0.1", 10118);
+$relay = Goridge\Relay::create(RoadRunner\Environment::fromGlobals()->getRelayAddress());
 $rr = new RoadRunner\Worker($relay);
 
 while ($in = $rr->waitPayload()) {
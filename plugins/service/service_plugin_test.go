@@ -3,485 +3,95 @@
 package service
 
 import (
-	"net"
-	"net/rpc"
-	"os"
-	"os/signal"
-	"sync"
-	"syscall"
 	"testing"
 	"time"
 
 	serviceV1 "github.com/roadrunner-server/api/v2/proto/service/v1"
-	"github.com/roadrunner-server/api/v2/state/process"
-	"github.com/roadrunner-server/config/v2"
-	endure "github.com/roadrunner-server/endure/pkg/container"
-	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
 	"github.com/roadrunner-server/informer/v2"
 	"github.com/roadrunner-server/logger/v2"
 	rpcPlugin "github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	mocklogger "github.com/roadrunner-server/rr-e2e-tests/mock"
 	"github.com/roadrunner-server/service/v2"
-	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestServiceInit(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-service-init.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-service-init.yaml",
 		&logger.Plugin{},
 		&service.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
 	time.Sleep(time.Second * 10)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestServiceWorkers(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-service-workers.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-service-workers.yaml",
 		&logger.Plugin{},
 		&service.Plugin{},
 		&informer.Plugin{},
 		&rpcPlugin.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
+	harness.WaitForRPC(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("workers", workers(h.RPCAddr(), "service"))
 	time.Sleep(time.Second)
-	t.Run("workers", workers("service"))
-	time.Sleep(time.Second)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestServiceInitStdout(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-service-init-stdout.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-service-init-stdout.yaml",
 		&logger.Plugin{},
 		&service.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
 	time.Sleep(time.Second * 5)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestServiceEnv(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-service-env.yaml",
-		Prefix: "rr",
-	}
-
 	l, oLogger := mocklogger.ZapTestLogger(zap.DebugLevel)
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-service-env.yaml",
 		l,
 		&service.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
 	time.Sleep(time.Second * 5)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 	require.Equal(t, 0, oLogger.FilterMessageSnippet("faillll").Len())
 }
 
 func TestServiceError(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-service-error.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-service-error.yaml",
 		&logger.Plugin{},
 		&service.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	assert.NoError(t, err)
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
 	time.Sleep(time.Second * 10)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestServiceRestarts(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-service-restarts.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-service-restarts.yaml",
 		&logger.Plugin{},
 		&service.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
 	time.Sleep(time.Second * 10)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestServiceCreate(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-service-create.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-service-create.yaml",
 		&logger.Plugin{},
 		&service.Plugin{},
 		&rpcPlugin.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second)
+	harness.WaitForTCP(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
 	in := &serviceV1.Create{
 		Name:            "foo",
@@ -496,79 +106,24 @@ func TestServiceCreate(t *testing.T) {
 
 	out := &serviceV1.Response{}
 
-	t.Run("create", create(in, out))
+	t.Run("create", create(h.RPCAddr(), in, out))
 
 	time.Sleep(time.Second * 2)
 
 	out = &serviceV1.Response{}
-	t.Run("terminate", terminate(&serviceV1.Service{Name: "foo"}, out))
+	t.Run("terminate", terminate(h.RPCAddr(), &serviceV1.Service{Name: "foo"}, out))
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestServiceCreateEmptyConfig(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-service-create-empty.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-service-create-empty.yaml",
 		&logger.Plugin{},
 		&service.Plugin{},
 		&rpcPlugin.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
 
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second)
+	harness.WaitForTCP(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
 	in := &serviceV1.Create{
 		Name:            "foo",
@@ -583,11 +138,11 @@ func TestServiceCreateEmptyConfig(t *testing.T) {
 
 	out := &serviceV1.Response{}
 
-	t.Run("create", create(in, out))
+	t.Run("create", create(h.RPCAddr(), in, out))
 
 	time.Sleep(time.Second * 3)
 	l := &serviceV1.List{}
-	t.Run("list", list(&serviceV1.Service{}, l))
+	t.Run("list", list(h.RPCAddr(), &serviceV1.Service{}, l))
 
 	for i := 0; i < len(l.GetServices()); i++ {
 		cmd := &serviceV1.Service{
@@ -596,76 +151,21 @@ func TestServiceCreateEmptyConfig(t *testing.T) {
 
 		out = &serviceV1.Response{}
 
-		t.Run("terminate", terminate(cmd, out))
+		t.Run("terminate", terminate(h.RPCAddr(), cmd, out))
 	}
 
 	time.Sleep(time.Second * 2)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestServiceRestart(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-service-create-empty.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-service-create-empty.yaml",
 		&logger.Plugin{},
 		&service.Plugin{},
 		&rpcPlugin.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
-	time.Sleep(time.Second)
+	harness.WaitForTCP(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
 	in := &serviceV1.Create{
 		Name:            "foo",
@@ -680,11 +180,11 @@ func TestServiceRestart(t *testing.T) {
 
 	out := &serviceV1.Response{}
 
-	t.Run("create", create(in, out))
+	t.Run("create", create(h.RPCAddr(), in, out))
 
 	time.Sleep(time.Second * 3)
 	l := &serviceV1.List{}
-	t.Run("list", list(&serviceV1.Service{}, l))
+	t.Run("list", list(h.RPCAddr(), &serviceV1.Service{}, l))
 
 	for i := 0; i < len(l.GetServices()); i++ {
 		cmd := &serviceV1.Service{
@@ -693,80 +193,25 @@ func TestServiceRestart(t *testing.T) {
 
 		out = &serviceV1.Response{}
 
-		t.Run("restart", restart(cmd, out))
+		t.Run("restart", restart(h.RPCAddr(), cmd, out))
 
 		time.Sleep(time.Second * 5)
 	}
 
 	time.Sleep(time.Second * 2)
 	out = &serviceV1.Response{}
-	t.Run("terminate", terminate(&serviceV1.Service{Name: "foo"}, out))
-	stopCh <- struct{}{}
-	wg.Wait()
+	t.Run("terminate", terminate(h.RPCAddr(), &serviceV1.Service{Name: "foo"}, out))
+	h.Stop()
 }
 
 func TestServiceRestartConcurrent(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-service-create-empty.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-service-create-empty.yaml",
 		&logger.Plugin{},
 		&service.Plugin{},
 		&rpcPlugin.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
-	time.Sleep(time.Second)
+	harness.WaitForTCP(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
 	in := &serviceV1.Create{
 		Name:            "foo",
@@ -781,12 +226,12 @@ func TestServiceRestartConcurrent(t *testing.T) {
 
 	out := &serviceV1.Response{}
 
-	t.Run("create", create(in, out))
+	t.Run("create", create(h.RPCAddr(), in, out))
 
 	time.Sleep(time.Second)
 
 	l := &serviceV1.List{}
-	t.Run("list", list(nil, l))
+	t.Run("list", list(h.RPCAddr(), nil, l))
 
 	for jj := 0; jj < 100; jj++ {
 		go func() {
@@ -797,7 +242,7 @@ func TestServiceRestartConcurrent(t *testing.T) {
 
 				out1 := &serviceV1.Response{}
 
-				t.Run("restart", restart(cmd, out1))
+				t.Run("restart", restart(h.RPCAddr(), cmd, out1))
 
 				time.Sleep(time.Second)
 			}
@@ -811,7 +256,7 @@ func TestServiceRestartConcurrent(t *testing.T) {
 
 				out2 := &serviceV1.Response{}
 
-				t.Run("restart", restart(cmd, out2))
+				t.Run("restart", restart(h.RPCAddr(), cmd, out2))
 
 				time.Sleep(time.Second)
 			}
@@ -820,73 +265,18 @@ func TestServiceRestartConcurrent(t *testing.T) {
 
 	time.Sleep(time.Second * 10)
 	out = &serviceV1.Response{}
-	t.Run("terminate", terminate(&serviceV1.Service{Name: "foo"}, out))
-	stopCh <- struct{}{}
-	wg.Wait()
+	t.Run("terminate", terminate(h.RPCAddr(), &serviceV1.Service{Name: "foo"}, out))
+	h.Stop()
 }
 
 func TestServiceListConcurrent(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-service-create-empty.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-service-create-empty.yaml",
 		&logger.Plugin{},
 		&service.Plugin{},
 		&rpcPlugin.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
 
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second)
+	harness.WaitForTCP(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
 	in := &serviceV1.Create{
 		Name:            "foo",
@@ -901,12 +291,12 @@ func TestServiceListConcurrent(t *testing.T) {
 
 	out := &serviceV1.Response{}
 
-	t.Run("create", create(in, out))
+	t.Run("create", create(h.RPCAddr(), in, out))
 
 	time.Sleep(time.Second)
 
 	l := &serviceV1.List{}
-	t.Run("list", list(nil, l))
+	t.Run("list", list(h.RPCAddr(), nil, l))
 
 	for jj := 0; jj < 100; jj++ {
 		go func() {
@@ -917,9 +307,9 @@ func TestServiceListConcurrent(t *testing.T) {
 
 				out1 := &serviceV1.Response{}
 
-				t.Run("restart", restart(cmd, out1))
+				t.Run("restart", restart(h.RPCAddr(), cmd, out1))
 				ll := &serviceV1.List{}
-				t.Run("list", list(nil, ll))
+				t.Run("list", list(h.RPCAddr(), nil, ll))
 				require.Len(t, ll.GetServices(), 1)
 
 				time.Sleep(time.Second)
@@ -933,9 +323,9 @@ func TestServiceListConcurrent(t *testing.T) {
 				}
 
 				out2 := &serviceV1.Response{}
-				t.Run("restart", restart(cmd, out2))
+				t.Run("restart", restart(h.RPCAddr(), cmd, out2))
 				ll := &serviceV1.List{}
-				t.Run("list", list(nil, ll))
+				t.Run("list", list(h.RPCAddr(), nil, ll))
 				require.Len(t, ll.GetServices(), 1)
 
 				time.Sleep(time.Second)
@@ -945,73 +335,18 @@ func TestServiceListConcurrent(t *testing.T) {
 
 	time.Sleep(time.Second * 10)
 	out = &serviceV1.Response{}
-	t.Run("terminate", terminate(&serviceV1.Service{Name: "foo"}, out))
-	stopCh <- struct{}{}
-	wg.Wait()
+	t.Run("terminate", terminate(h.RPCAddr(), &serviceV1.Service{Name: "foo"}, out))
+	h.Stop()
 }
 
 func TestServiceStatus(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-service-create-empty.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-service-create-empty.yaml",
 		&logger.Plugin{},
 		&service.Plugin{},
 		&rpcPlugin.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second)
+	harness.WaitForTCP(t, h.RPCAddr(), harness.DefaultWaitTimeout)
 
 	in := &serviceV1.Create{
 		Name:            "foo",
@@ -1026,12 +361,12 @@ func TestServiceStatus(t *testing.T) {
 
 	out := &serviceV1.Response{}
 
-	t.Run("create", create(in, out))
+	t.Run("create", create(h.RPCAddr(), in, out))
 
 	time.Sleep(time.Second)
 
 	l := &serviceV1.List{}
-	t.Run("list", list(nil, l))
+	t.Run("list", list(h.RPCAddr(), nil, l))
 	require.Len(t, l.GetServices(), 1)
 
 	inStat := &serviceV1.Service{
@@ -1039,152 +374,78 @@ func TestServiceStatus(t *testing.T) {
 	}
 
 	outStat := &serviceV1.Status{}
-	t.Run("stats", status(inStat, outStat))
+	t.Run("stats", status(h.RPCAddr(), inStat, outStat))
 	require.NotEmpty(t, outStat.GetCommand())
 	require.NotZero(t, outStat.GetMemoryUsage())
 	require.NotZero(t, outStat.GetPid())
 
 	out = &serviceV1.Response{}
-	t.Run("terminate", terminate(&serviceV1.Service{Name: l.GetServices()[0]}, out))
+	t.Run("terminate", terminate(h.RPCAddr(), &serviceV1.Service{Name: l.GetServices()[0]}, out))
 
 	time.Sleep(time.Second * 2)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestServiceInitRemain(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-service-init-remain.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-service-init-remain.yaml",
 		&logger.Plugin{},
 		&service.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
 	time.Sleep(time.Second * 10)
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
-func create(in *serviceV1.Create, out *serviceV1.Response) func(t *testing.T) {
+func create(addr string, in *serviceV1.Create, out *serviceV1.Response) func(t *testing.T) {
 	return func(t *testing.T) {
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
-		require.NoError(t, err)
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-		err = client.Call("service.Create", in, out)
+		resp, err := harness.Dial(t, addr).Service.Create(in)
 		require.NoError(t, err)
+		copyMsg(out, resp)
 	}
 }
 
-func terminate(in *serviceV1.Service, out *serviceV1.Response) func(t *testing.T) {
+func terminate(addr string, in *serviceV1.Service, out *serviceV1.Response) func(t *testing.T) {
 	return func(t *testing.T) {
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
-		require.NoError(t, err)
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-		err = client.Call("service.Terminate", in, out)
+		resp, err := harness.Dial(t, addr).Service.Terminate(in.GetName())
 		require.NoError(t, err)
+		copyMsg(out, resp)
 	}
 }
 
-func restart(in *serviceV1.Service, out *serviceV1.Response) func(t *testing.T) {
+func restart(addr string, in *serviceV1.Service, out *serviceV1.Response) func(t *testing.T) {
 	return func(t *testing.T) {
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
-		require.NoError(t, err)
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-		err = client.Call("service.Restart", in, out)
+		resp, err := harness.Dial(t, addr).Service.Restart(in.GetName())
 		require.NoError(t, err)
+		copyMsg(out, resp)
 	}
 }
 
-func status(in *serviceV1.Service, out *serviceV1.Status) func(t *testing.T) {
+func status(addr string, in *serviceV1.Service, out *serviceV1.Status) func(t *testing.T) {
 	return func(t *testing.T) {
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
-		require.NoError(t, err)
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-		err = client.Call("service.Status", in, out)
+		resp, err := harness.Dial(t, addr).Service.Status(in.GetName())
 		require.NoError(t, err)
+		copyMsg(out, resp)
 	}
 }
 
-func list(in *serviceV1.Service, out *serviceV1.List) func(t *testing.T) {
+func list(addr string, _ *serviceV1.Service, out *serviceV1.List) func(t *testing.T) {
 	return func(t *testing.T) {
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
-		require.NoError(t, err)
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-		err = client.Call("service.List", in, out)
+		services, err := harness.Dial(t, addr).Service.List()
 		require.NoError(t, err)
+		copyMsg(out, &serviceV1.List{Services: services})
 	}
 }
 
-func workers(service string) func(t *testing.T) {
+func workers(addr, service string) func(t *testing.T) {
 	return func(t *testing.T) {
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
+		wrks, err := harness.Dial(t, addr).Informer.Workers(service)
 		require.NoError(t, err)
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-		// WorkerList contains list of workers.
-		lst := struct {
-			// Workers is list of workers.
-			Workers []process.State `json:"workers"`
-		}{}
-
-		err = client.Call("informer.Workers", service, &lst)
-		require.NoError(t, err)
-		require.Len(t, lst.Workers, 2)
+		require.Len(t, wrks, 2)
 	}
 }
+
+// copyMsg replaces the content of dst with src, tests read the responses via the pointers passed to the helpers
+func copyMsg(dst, src proto.Message) {
+	proto.Reset(dst)
+	proto.Merge(dst, src)
+}
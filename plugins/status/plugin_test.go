@@ -2,9 +2,7 @@ package status
 
 import (
 	"io/ioutil"
-	"net"
 	"net/http"
-	"net/rpc"
 	"os"
 	"os/signal"
 	"sync"
@@ -12,276 +10,131 @@ import (
 	"testing"
 	"time"
 
-	statusImpl "github.com/roadrunner-server/api/v2/plugins/status"
 	"github.com/roadrunner-server/config/v2"
 	endure "github.com/roadrunner-server/endure/pkg/container"
-	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
 	httpPlugin "github.com/roadrunner-server/http/v2"
 	"github.com/roadrunner-server/logger/v2"
 	rpcPlugin "github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
+	"github.com/roadrunner-server/rr-e2e-tests/rpcclient"
 	"github.com/roadrunner-server/server/v2"
 	"github.com/roadrunner-server/status/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestStatusHttp(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-status-init.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-status-init.yaml",
 		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
 		&status.Plugin{},
 	)
-	assert.NoError(t, err)
 
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
+	// the http server is started after its workers pool, the status reports the pool
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	harness.WaitForTCP(t, env.Addr("status"), harness.DefaultWaitTimeout)
+	t.Run("CheckerGetStatus", checkHTTPStatus(env.Addr("status")))
 
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second)
-	t.Run("CheckerGetStatus", checkHTTPStatus)
-
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 const resp = `Service: http: Status: 200
 Service: rpc not found`
 
-func checkHTTPStatus(t *testing.T) {
-	req, err := http.NewRequest("GET", "http://127.0.0.1:34333/health?plugin=http&plugin=rpc", nil)
-	assert.NoError(t, err)
+func checkHTTPStatus(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		req, err := http.NewRequest("GET", "http://"+addr+"/health?plugin=http&plugin=rpc", nil)
+		assert.NoError(t, err)
 
-	r, err := http.DefaultClient.Do(req)
-	assert.NoError(t, err)
-	b, err := ioutil.ReadAll(r.Body)
-	assert.NoError(t, err)
-	assert.Equal(t, 200, r.StatusCode)
-	assert.Equal(t, resp, string(b))
+		r, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, 200, r.StatusCode)
+		assert.Equal(t, resp, string(b))
 
-	err = r.Body.Close()
-	assert.NoError(t, err)
+		err = r.Body.Close()
+		assert.NoError(t, err)
+	}
 }
 
 func TestStatusRPC(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-status-init.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := harness.Start(t, "configs/.rr-status-init.yaml",
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
 		&status.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
-	time.Sleep(time.Second)
-	t.Run("CheckerGetStatusRpc", checkRPCStatus)
-	stopCh <- struct{}{}
-	wg.Wait()
+	harness.WaitForTCP(t, h.Env().Addr("http"), harness.DefaultWaitTimeout)
+	harness.WaitForTCP(t, h.RPCAddr(), harness.DefaultWaitTimeout)
+	t.Run("CheckerGetStatusRpc", checkRPCStatus(h.RPCAddr()))
+	h.Stop()
 }
 
-func checkRPCStatus(t *testing.T) {
-	conn, err := net.Dial("tcp", "127.0.0.1:6005")
-	assert.NoError(t, err)
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-	st := &statusImpl.Status{}
+func checkRPCStatus(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client, err := rpcclient.Dial(addr)
+		require.NoError(t, err)
+		defer func() {
+			_ = client.Close()
+		}()
 
-	err = client.Call("status.Status", "http", &st)
-	assert.NoError(t, err)
-	assert.Equal(t, st.Code, 200)
+		st, err := client.Status.Status("http")
+		assert.NoError(t, err)
+		assert.Equal(t, st.Code, 200)
+	}
 }
 
 func TestReadyHttp(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-status-init.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-status-init.yaml",
 		&logger.Plugin{},
 		&server.Plugin{},
 		&httpPlugin.Plugin{},
 		&status.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
-	time.Sleep(time.Second)
-	t.Run("CheckerGetReadiness", checkHTTPReadiness)
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	harness.WaitForTCP(t, env.Addr("status"), harness.DefaultWaitTimeout)
+	t.Run("CheckerGetReadiness", checkHTTPReadiness(env.Addr("status")))
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 const resp2 = `Service: http: Status: 204
 Service: rpc not found`
 
-func checkHTTPReadiness(t *testing.T) {
-	req, err := http.NewRequest("GET", "http://127.0.0.1:34333/ready?plugin=http&plugin=rpc", nil)
-	assert.NoError(t, err)
+func checkHTTPReadiness(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		req, err := http.NewRequest("GET", "http://"+addr+"/ready?plugin=http&plugin=rpc", nil)
+		assert.NoError(t, err)
 
-	r, err := http.DefaultClient.Do(req)
-	assert.NoError(t, err)
-	b, err := ioutil.ReadAll(r.Body)
-	assert.NoError(t, err)
-	assert.Equal(t, 200, r.StatusCode)
-	assert.Equal(t, resp, string(b))
+		r, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, 200, r.StatusCode)
+		assert.Equal(t, resp, string(b))
 
-	err = r.Body.Close()
-	assert.NoError(t, err)
+		err = r.Body.Close()
+		assert.NoError(t, err)
+	}
 }
 
 func TestReadinessRPCWorkerNotReady(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel), endure.GracefulShutdownTimeout(time.Second*2))
 	assert.NoError(t, err)
 
 	cfg := &config.Plugin{
-		Path:   "configs/.rr-ready-init.yaml",
+		Path:   env.Render("configs/.rr-ready-init.yaml"),
 		Prefix: "rr",
 	}
 
@@ -336,54 +189,60 @@ func TestReadinessRPCWorkerNotReady(t *testing.T) {
 	}()
 
 	time.Sleep(time.Second)
-	t.Run("DoHttpReq", doHTTPReq)
+	t.Run("DoHttpReq", doHTTPReq(env.Addr("http")))
 	time.Sleep(time.Second * 5)
-	t.Run("CheckerGetReadiness2", checkHTTPReadiness2)
-	t.Run("CheckerGetRpcReadiness", checkRPCReadiness)
+	t.Run("CheckerGetReadiness2", checkHTTPReadiness2(env.Addr("status")))
+	t.Run("CheckerGetRpcReadiness", checkRPCReadiness(env.RPCAddr()))
 	stopCh <- struct{}{}
 	wg.Wait()
 }
 
-func doHTTPReq(t *testing.T) {
-	go func() {
-		req, err := http.NewRequest("GET", "http://127.0.0.1:11933", nil)
+func doHTTPReq(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		go func() {
+			req, err := http.NewRequest("GET", "http://"+addr, nil)
+			assert.NoError(t, err)
+
+			r, err := http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+			b, err := ioutil.ReadAll(r.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, 200, r.StatusCode)
+			assert.Equal(t, resp2, string(b))
+
+			err = r.Body.Close()
+			assert.NoError(t, err)
+		}()
+	}
+}
+
+func checkHTTPReadiness2(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		req, err := http.NewRequest("GET", "http://"+addr+"/ready?plugin=http&plugin=rpc", nil)
 		assert.NoError(t, err)
 
 		r, err := http.DefaultClient.Do(req)
 		assert.NoError(t, err)
 		b, err := ioutil.ReadAll(r.Body)
 		assert.NoError(t, err)
-		assert.Equal(t, 200, r.StatusCode)
-		assert.Equal(t, resp2, string(b))
+		assert.Equal(t, 503, r.StatusCode)
+		assert.Equal(t, "", string(b))
 
 		err = r.Body.Close()
 		assert.NoError(t, err)
-	}()
-}
-
-func checkHTTPReadiness2(t *testing.T) {
-	req, err := http.NewRequest("GET", "http://127.0.0.1:34334/ready?plugin=http&plugin=rpc", nil)
-	assert.NoError(t, err)
-
-	r, err := http.DefaultClient.Do(req)
-	assert.NoError(t, err)
-	b, err := ioutil.ReadAll(r.Body)
-	assert.NoError(t, err)
-	assert.Equal(t, 503, r.StatusCode)
-	assert.Equal(t, "", string(b))
-
-	err = r.Body.Close()
-	assert.NoError(t, err)
+	}
 }
 
-func checkRPCReadiness(t *testing.T) {
-	conn, err := net.Dial("tcp", "127.0.0.1:6007")
-	assert.NoError(t, err)
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-	st := &statusImpl.Status{}
+func checkRPCReadiness(addr string) func(t *testing.T) {
+	return func(t *testing.T) {
+		client, err := rpcclient.Dial(addr)
+		require.NoError(t, err)
+		defer func() {
+			_ = client.Close()
+		}()
 
-	err = client.Call("status.Ready", "http", &st)
-	assert.NoError(t, err)
-	assert.Equal(t, st.Code, 503)
+		st, err := client.Status.Ready("http")
+		assert.NoError(t, err)
+		assert.Equal(t, st.Code, 503)
+	}
 }
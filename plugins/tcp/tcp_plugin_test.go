@@ -4,87 +4,27 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
-	"net/rpc"
-	"os"
-	"os/signal"
-	"sync"
-	"syscall"
 	"testing"
-	"time"
 
-	"github.com/roadrunner-server/config/v2"
-	endure "github.com/roadrunner-server/endure/pkg/container"
-	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
 	"github.com/roadrunner-server/logger/v2"
 	rpcPlugin "github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	"github.com/roadrunner-server/server/v2"
 	"github.com/roadrunner-server/tcp/v2"
-	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestTCPInit(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-tcp-init.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-tcp-init.yaml",
 		&logger.Plugin{},
 		&server.Plugin{},
 		&tcp.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
 
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-	c, err := net.Dial("tcp", "127.0.0.1:7777")
+	harness.WaitForTCP(t, env.Addr("server1"), harness.DefaultWaitTimeout)
+	c, err := net.Dial("tcp", env.Addr("server1"))
 	require.NoError(t, err)
 	_, err = c.Write([]byte("wuzaaaa\n\r\n"))
 	require.NoError(t, err)
@@ -102,8 +42,8 @@ func TestTCPInit(t *testing.T) {
 
 	// ---
 
-	time.Sleep(time.Second * 1)
-	c, err = net.Dial("tcp", "127.0.0.1:8889")
+	harness.WaitForTCP(t, env.Addr("server2"), harness.DefaultWaitTimeout)
+	c, err = net.Dial("tcp", env.Addr("server2"))
 	require.NoError(t, err)
 	_, err = c.Write([]byte("helooooo\r\n"))
 	require.NoError(t, err)
@@ -121,8 +61,8 @@ func TestTCPInit(t *testing.T) {
 
 	// ---
 
-	time.Sleep(time.Second * 1)
-	c, err = net.Dial("tcp", "127.0.0.1:8810")
+	harness.WaitForTCP(t, env.Addr("server3"), harness.DefaultWaitTimeout)
+	c, err = net.Dial("tcp", env.Addr("server3"))
 	require.NoError(t, err)
 	_, err = c.Write([]byte("HEEEEEEEEEEEEEYYYYYYYYYYYYY\r\n"))
 	require.NoError(t, err)
@@ -138,72 +78,20 @@ func TestTCPInit(t *testing.T) {
 
 	require.Equal(t, d3["remote_addr"].(string), c.LocalAddr().String())
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestTCPEmptySend(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-tcp-empty.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-tcp-empty.yaml",
 		&logger.Plugin{},
 		&server.Plugin{},
 		&tcp.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
 
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-	c, err := net.Dial("tcp", "127.0.0.1:7779")
+	harness.WaitForTCP(t, env.Addr("tcp_access_point_1"), harness.DefaultWaitTimeout)
+	c, err := net.Dial("tcp", env.Addr("tcp_access_point_1"))
 	require.NoError(t, err)
 	_, err = c.Write([]byte(""))
 	require.NoError(t, err)
@@ -220,73 +108,21 @@ func TestTCPEmptySend(t *testing.T) {
 
 	// ---
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestTCPConnClose(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-tcp-close.yaml",
-		Prefix: "rr",
-	}
-
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-tcp-close.yaml",
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
 		&tcp.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
 
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-	c, err := net.Dial("tcp", "127.0.0.1:7788")
+	harness.WaitForTCP(t, env.Addr("server1"), harness.DefaultWaitTimeout)
+	c, err := net.Dial("tcp", env.Addr("server1"))
 	require.NoError(t, err)
 	_, err = c.Write([]byte("hello \r\n"))
 	require.NoError(t, err)
@@ -301,78 +137,26 @@ func TestTCPConnClose(t *testing.T) {
 
 	require.NotEmpty(t, d["uuid"].(string))
 
-	t.Run("CloseConnection", closeConn(d["uuid"].(string)))
+	t.Run("CloseConnection", closeConn(h.RPCAddr(), d["uuid"].(string)))
 	// ---
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
 func TestTCPFull(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-tcp-full.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-tcp-full.yaml",
 		&logger.Plugin{},
 		&server.Plugin{},
 		&tcp.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	assert.NoError(t, err)
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
 
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
+	harness.WaitForTCP(t, env.Addr("server1"), harness.DefaultWaitTimeout)
 	waitCh := make(chan struct{}, 3)
 
 	go func() {
-		c, err := net.Dial("tcp", "127.0.0.1:7778")
+		c, err := net.Dial("tcp", env.Addr("server1"))
 		require.NoError(t, err)
 
 		buf := make([]byte, 1024)
@@ -399,7 +183,7 @@ func TestTCPFull(t *testing.T) {
 	}()
 
 	go func() {
-		c, err := net.Dial("tcp", "127.0.0.1:8811")
+		c, err := net.Dial("tcp", env.Addr("server2"))
 		require.NoError(t, err)
 
 		buf := make([]byte, 1024)
@@ -426,7 +210,7 @@ func TestTCPFull(t *testing.T) {
 	}()
 
 	go func() {
-		c, err := net.Dial("tcp", "127.0.0.1:8812")
+		c, err := net.Dial("tcp", env.Addr("server3"))
 		require.NoError(t, err)
 
 		buf := make([]byte, 1024)
@@ -457,17 +241,12 @@ func TestTCPFull(t *testing.T) {
 	<-waitCh
 	<-waitCh
 	<-waitCh
-	stopCh <- struct{}{}
-	wg.Wait()
+	h.Stop()
 }
 
-func closeConn(uuid string) func(t *testing.T) {
+func closeConn(addr, uuid string) func(t *testing.T) {
 	return func(t *testing.T) {
-		conn, err := net.Dial("tcp", "127.0.0.1:6001")
-		require.NoError(t, err)
-		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-		var ret bool
-		err = client.Call("tcp.Close", uuid, &ret)
+		ret, err := harness.Dial(t, addr).TCP.Close(uuid)
 		require.NoError(t, err)
 		require.True(t, ret)
 	}
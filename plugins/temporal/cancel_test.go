@@ -2,7 +2,6 @@ package tests
 
 import (
 	"context"
-	"sync"
 	"testing"
 	"time"
 
@@ -14,10 +13,7 @@ import (
 )
 
 func Test_SimpleWorkflowCancelProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -38,15 +34,11 @@ func Test_SimpleWorkflowCancelProto(t *testing.T) {
 	assert.NoError(t, err)
 
 	assert.Equal(t, "Canceled", we.WorkflowExecutionInfo.Status.String())
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_CancellableWorkflowScopeProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -69,15 +61,11 @@ func Test_CancellableWorkflowScopeProto(t *testing.T) {
 	s.AssertNotContainsEvent(t, w, func(event *history.HistoryEvent) bool {
 		return event.EventType == enums.EVENT_TYPE_ACTIVITY_TASK_SCHEDULED
 	})
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_CanceledWorkflowProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -96,15 +84,11 @@ func Test_CanceledWorkflowProto(t *testing.T) {
 	var result interface{}
 	assert.NoError(t, w.Get(context.Background(), &result))
 	assert.Equal(t, "CANCELED", result)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_CanceledWithCompensationWorkflowProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -144,15 +128,11 @@ func Test_CanceledWithCompensationWorkflowProto(t *testing.T) {
 		},
 		trace,
 	)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_CanceledNestedWorkflowProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -191,15 +171,11 @@ func Test_CanceledNestedWorkflowProto(t *testing.T) {
 		},
 		trace,
 	)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_CanceledNSingleScopeWorkflowProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -235,15 +211,11 @@ func Test_CanceledNSingleScopeWorkflowProto(t *testing.T) {
 		},
 		trace,
 	)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_CanceledMidflightWorkflowProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -280,15 +252,11 @@ func Test_CanceledMidflightWorkflowProto(t *testing.T) {
 	s.AssertNotContainsEvent(t, w, func(event *history.HistoryEvent) bool {
 		return event.EventType == enums.EVENT_TYPE_ACTIVITY_TASK_SCHEDULED
 	})
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_CancelSignaledChildWorkflowProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -323,6 +291,5 @@ func Test_CancelSignaledChildWorkflowProto(t *testing.T) {
 	s.AssertContainsEvent(t, w, func(event *history.HistoryEvent) bool {
 		return event.EventType == enums.EVENT_TYPE_REQUEST_CANCEL_EXTERNAL_WORKFLOW_EXECUTION_INITIATED
 	})
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
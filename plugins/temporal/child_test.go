@@ -2,7 +2,6 @@ package tests
 
 import (
 	"context"
-	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -10,10 +9,7 @@ import (
 )
 
 func Test_ExecuteChildWorkflowProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -28,15 +24,11 @@ func Test_ExecuteChildWorkflowProto(t *testing.T) {
 	var result string
 	assert.NoError(t, w.Get(context.Background(), &result))
 	assert.Equal(t, "Child: CHILD HELLO WORLD", result)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_ExecuteChildStubWorkflowProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -51,15 +43,11 @@ func Test_ExecuteChildStubWorkflowProto(t *testing.T) {
 	var result string
 	assert.NoError(t, w.Get(context.Background(), &result))
 	assert.Equal(t, "Child: CHILD HELLO WORLD", result)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_ExecuteChildStubWorkflow_02Proto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -74,15 +62,11 @@ func Test_ExecuteChildStubWorkflow_02Proto(t *testing.T) {
 	var result []string
 	assert.NoError(t, w.Get(context.Background(), &result))
 	assert.Equal(t, []string{"HELLO WORLD", "UNTYPED"}, result)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_SignalChildViaStubWorkflowProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -96,6 +80,5 @@ func Test_SignalChildViaStubWorkflowProto(t *testing.T) {
 	var result int
 	assert.NoError(t, w.Get(context.Background(), &result))
 	assert.Equal(t, 8, result)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
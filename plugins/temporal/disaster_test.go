@@ -2,27 +2,21 @@ package tests
 
 import (
 	"context"
-	"net"
-	"net/rpc"
 	"os"
-	"sync"
 	"testing"
 	"time"
 
 	"github.com/roadrunner-server/api/v2/state/process"
-	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.temporal.io/sdk/client"
 )
 
 func Test_WorkerError_DisasterRecovery(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
-	workers := getWorkers(t)
+	workers := getWorkers(t, s.rpcAddr)
 	require.Len(t, workers, 5)
 
 	p, err := os.FindProcess(workers[0].Pid)
@@ -46,15 +40,11 @@ func Test_WorkerError_DisasterRecovery(t *testing.T) {
 	var result string
 	assert.NoError(t, w.Get(context.Background(), &result))
 	assert.Equal(t, "hello world", result)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_ResetAll(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -71,7 +61,7 @@ func Test_ResetAll(t *testing.T) {
 	assert.NoError(t, w.Get(context.Background(), &result))
 	assert.Equal(t, "hello world", result)
 
-	reset(t)
+	reset(t, s.rpcAddr)
 
 	w, err = s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -87,8 +77,7 @@ func Test_ResetAll(t *testing.T) {
 	assert.NoError(t, w.Get(context.Background(), &result))
 	assert.Equal(t, "hello world", result)
 
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 // TODO find a solution
@@ -135,10 +124,7 @@ func Test_ResetAll(t *testing.T) {
 // }
 
 func Test_ActivityError_DisasterRecovery(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	defer func() {
 		// always restore script
@@ -150,7 +136,7 @@ func Test_ActivityError_DisasterRecovery(t *testing.T) {
 
 	// destroys all workers in activities
 
-	workers := getWorkers(t)
+	workers := getWorkers(t, s.rpcAddr)
 	require.Len(t, workers, 5)
 
 	for i := 1; i < len(workers); i++ {
@@ -178,17 +164,13 @@ func Test_ActivityError_DisasterRecovery(t *testing.T) {
 	var result string
 	assert.NoError(t, w.Get(context.Background(), &result))
 	assert.Equal(t, "HELLO WORLD", result)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_WorkerError_DisasterRecoveryProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
-	workers := getWorkers(t)
+	workers := getWorkers(t, s.rpcAddr)
 	require.Len(t, workers, 5)
 
 	p, err := os.FindProcess(workers[0].Pid)
@@ -212,8 +194,7 @@ func Test_WorkerError_DisasterRecoveryProto(t *testing.T) {
 	var result string
 	assert.NoError(t, w.Get(context.Background(), &result))
 	assert.Equal(t, "hello world", result)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 // TODO find a solution
@@ -260,10 +241,7 @@ func Test_WorkerError_DisasterRecoveryProto(t *testing.T) {
 // }
 
 func Test_ActivityError_DisasterRecoveryProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	defer func() {
 		// always restore script
@@ -274,7 +252,7 @@ func Test_ActivityError_DisasterRecoveryProto(t *testing.T) {
 	_ = os.Rename("worker.php", "worker.bak")
 
 	// destroys all workers in activities
-	workers := getWorkers(t)
+	workers := getWorkers(t, s.rpcAddr)
 	require.Len(t, workers, 5)
 
 	for i := 1; i < len(workers); i++ {
@@ -302,34 +280,21 @@ func Test_ActivityError_DisasterRecoveryProto(t *testing.T) {
 	var result string
 	assert.NoError(t, w.Get(context.Background(), &result))
 	assert.Equal(t, "HELLO WORLD", result)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
-func getWorkers(t *testing.T) []*process.State {
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
+func getWorkers(t *testing.T, addr string) []*process.State {
+	workers, err := harness.Dial(t, addr).Informer.Workers("temporal")
 	assert.NoError(t, err)
-	c := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-	// WorkerList contains list of workers.
-	list := struct {
-		// Workers is list of workers.
-		Workers []*process.State `json:"workers"`
-	}{}
-
-	err = c.Call("informer.Workers", "temporal", &list)
-	assert.NoError(t, err)
-	assert.Len(t, list.Workers, 5)
+	assert.Len(t, workers, 5)
 
-	return list.Workers
+	return workers
 }
 
-func reset(t *testing.T) {
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
-	assert.NoError(t, err)
-	c := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
+func reset(t *testing.T, addr string) {
+	c := harness.Dial(t, addr)
 
-	var ret bool
-	err = c.Call("resetter.Reset", "temporal", &ret)
+	ret, err := c.Resetter.Reset("temporal")
 	assert.NoError(t, err)
 	require.True(t, ret)
 }
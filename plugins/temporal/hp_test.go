@@ -5,17 +5,14 @@ import (
 	"crypto/rand"
 	"crypto/sha512"
 	"fmt"
-	"net"
-	"net/rpc"
-	"sync"
 	"testing"
 	"time"
 
-	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
 	"github.com/stretchr/testify/require"
 	"go.temporal.io/api/common/v1"
 
 	"github.com/fatih/color"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
 	"github.com/stretchr/testify/assert"
 	"go.temporal.io/api/enums/v1"
 	"go.temporal.io/api/history/v1"
@@ -32,14 +29,11 @@ type User struct {
 }
 
 func Test_VerifyRegistrationProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
 
-	_ = NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
-	activities := getActivities(t)
-	workflows := getWorkflows(t)
+	activities := getActivities(t, s.rpcAddr)
+	workflows := getWorkflows(t, s.rpcAddr)
 
 	assert.Contains(t, workflows, "SimpleWorkflow")
 
@@ -47,15 +41,11 @@ func Test_VerifyRegistrationProto(t *testing.T) {
 	assert.Contains(t, activities, "HeartBeatActivity.doSomething")
 
 	assert.Contains(t, activities, "SimpleActivity.lower")
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_ExecuteSimpleWorkflow_1Proto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -70,15 +60,11 @@ func Test_ExecuteSimpleWorkflow_1Proto(t *testing.T) {
 	var result string
 	assert.NoError(t, w.Get(context.Background(), &result))
 	assert.Equal(t, "HELLO WORLD", result)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_ExecuteSimpleDTOWorkflowProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -96,15 +82,11 @@ func Test_ExecuteSimpleDTOWorkflowProto(t *testing.T) {
 	var result struct{ Message string }
 	assert.NoError(t, w.Get(context.Background(), &result))
 	assert.Equal(t, "Hello Antony <email@world.net>", result.Message)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_ExecuteSimpleWorkflowWithSequenceInBatchProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -119,15 +101,11 @@ func Test_ExecuteSimpleWorkflowWithSequenceInBatchProto(t *testing.T) {
 	var result string
 	assert.NoError(t, w.Get(context.Background(), &result))
 	assert.Equal(t, "OK", result)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_MultipleWorkflowsInSingleWorkerProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -155,15 +133,11 @@ func Test_MultipleWorkflowsInSingleWorkerProto(t *testing.T) {
 
 	assert.NoError(t, w2.Get(context.Background(), &result))
 	assert.Equal(t, "hello world", result)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_ExecuteWorkflowWithParallelScopesProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -178,15 +152,11 @@ func Test_ExecuteWorkflowWithParallelScopesProto(t *testing.T) {
 	var result string
 	assert.NoError(t, w.Get(context.Background(), &result))
 	assert.Equal(t, "HELLO WORLD|Hello World|hello world", result)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_TimerProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	start := time.Now()
 	w, err := s.Client().ExecuteWorkflow(
@@ -207,15 +177,11 @@ func Test_TimerProto(t *testing.T) {
 	s.AssertContainsEvent(t, w, func(event *history.HistoryEvent) bool {
 		return event.EventType == enums.EVENT_TYPE_TIMER_STARTED
 	})
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_SideEffectProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -234,15 +200,11 @@ func Test_SideEffectProto(t *testing.T) {
 	s.AssertContainsEvent(t, w, func(event *history.HistoryEvent) bool {
 		return event.EventType == enums.EVENT_TYPE_MARKER_RECORDED
 	})
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_EmptyWorkflowProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -257,15 +219,11 @@ func Test_EmptyWorkflowProto(t *testing.T) {
 	var result int
 	assert.NoError(t, w.Get(context.Background(), &result))
 	assert.Equal(t, 42, result)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_PromiseChainingProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -280,15 +238,11 @@ func Test_PromiseChainingProto(t *testing.T) {
 	var result string
 	assert.NoError(t, w.Get(context.Background(), &result))
 	assert.Equal(t, "result:hello world", result)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_ActivityHeartbeatProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -314,15 +268,11 @@ func Test_ActivityHeartbeatProto(t *testing.T) {
 	var result string
 	assert.NoError(t, w.Get(context.Background(), &result))
 	assert.Equal(t, "OK", result)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_BinaryPayloadProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	rnd := make([]byte, 2500)
 
@@ -343,15 +293,11 @@ func Test_BinaryPayloadProto(t *testing.T) {
 	assert.NoError(t, w.Get(context.Background(), &result))
 
 	assert.Equal(t, fmt.Sprintf("%x", sha512.Sum512(rnd)), result)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_ContinueAsNewProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -376,15 +322,11 @@ func Test_ContinueAsNewProto(t *testing.T) {
 	var result string
 	assert.NoError(t, w.Get(context.Background(), &result))
 	assert.Equal(t, "OK6", result)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_ActivityStubWorkflowProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -404,15 +346,11 @@ func Test_ActivityStubWorkflowProto(t *testing.T) {
 		"invalid method call",
 		"UNTYPED",
 	}, result)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_ExecuteProtoWorkflowProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -427,15 +365,11 @@ func Test_ExecuteProtoWorkflowProto(t *testing.T) {
 	assert.NoError(t, w.Get(context.Background(), &result))
 	assert.Equal(t, "updated", result.RunId)
 	assert.Equal(t, "workflow id", result.WorkflowId)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_SagaWorkflowProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -448,31 +382,18 @@ func Test_SagaWorkflowProto(t *testing.T) {
 
 	var result string
 	assert.Error(t, w.Get(context.Background(), &result))
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
-func getActivities(t *testing.T) []string {
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
-	assert.NoError(t, err)
-	c := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-	res := make([]string, 0, 10)
-
-	err = c.Call("temporal.GetActivityNames", true, &res)
+func getActivities(t *testing.T, addr string) []string {
+	res, err := harness.Dial(t, addr).Temporal.ActivityNames()
 	assert.NoError(t, err)
 
 	return res
 }
 
-func getWorkflows(t *testing.T) []string {
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
-	assert.NoError(t, err)
-	c := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-	res := make([]string, 0, 10)
-
-	err = c.Call("temporal.GetWorkflowNames", true, &res)
+func getWorkflows(t *testing.T, addr string) []string {
+	res, err := harness.Dial(t, addr).Temporal.WorkflowNames()
 	assert.NoError(t, err)
 
 	return res
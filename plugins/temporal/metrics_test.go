@@ -4,7 +4,6 @@ import (
 	"context"
 	"io/ioutil"
 	"net/http"
-	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -12,10 +11,7 @@ import (
 )
 
 func Test_SimpleWorkflowCancelMetrics(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServerWithMetrics(t, stopCh, wg)
+	s := NewTestServerWithMetrics(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -34,7 +30,7 @@ func Test_SimpleWorkflowCancelMetrics(t *testing.T) {
 	we, err := s.Client().DescribeWorkflowExecution(context.Background(), w.GetID(), w.GetRunID())
 	assert.NoError(t, err)
 
-	metrics, err := get()
+	metrics, err := get(s.metricsAddr)
 	assert.NoError(t, err)
 
 	assert.Contains(t, metrics, "request_attempt")
@@ -59,13 +55,12 @@ func Test_SimpleWorkflowCancelMetrics(t *testing.T) {
 	assert.Contains(t, metrics, "workflow_task_schedule_to_start_latency")
 
 	assert.Equal(t, "Completed", we.WorkflowExecutionInfo.Status.String())
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 // get request and return body
-func get() (string, error) {
-	r, err := http.Get("http://127.0.0.1:9095/metrics")
+func get(addr string) (string, error) {
+	r, err := http.Get("http://" + addr + "/metrics")
 	if err != nil {
 		return "", err
 	}
@@ -2,7 +2,6 @@ package tests
 
 import (
 	"context"
-	"sync"
 	"testing"
 	"time"
 
@@ -11,10 +10,7 @@ import (
 )
 
 func Test_ListQueriesProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -37,15 +33,11 @@ func Test_ListQueriesProto(t *testing.T) {
 	var r int
 	assert.NoError(t, w.Get(context.Background(), &r))
 	assert.Equal(t, 0, r)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_GetQueryProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -70,6 +62,5 @@ func Test_GetQueryProto(t *testing.T) {
 
 	assert.NoError(t, w.Get(context.Background(), &r))
 	assert.Equal(t, 88, r)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
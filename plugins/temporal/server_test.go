@@ -3,19 +3,19 @@ package tests
 import (
 	"context"
 	"fmt"
-	"sync"
+	"os"
 	"testing"
 	"time"
 
-	"github.com/roadrunner-server/api/v2/plugins/config"
 	configImpl "github.com/roadrunner-server/config/v2"
 	endure "github.com/roadrunner-server/endure/pkg/container"
 	"github.com/roadrunner-server/informer/v2"
 	"github.com/roadrunner-server/logger/v2"
 	"github.com/roadrunner-server/resetter/v2"
 	"github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
+	"github.com/roadrunner-server/rr-e2e-tests/temporalserver"
 	"github.com/roadrunner-server/server/v2"
-	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	roadrunnerTemporal "github.com/temporalio/roadrunner-temporal"
 	"github.com/temporalio/roadrunner-temporal/data_converter"
@@ -27,8 +27,26 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
+// envTemporalAddr points the suite at the external temporal cluster (docker-compose-temporal.yaml) instead of the
+// embedded server
+const envTemporalAddr string = "TEMPORAL_ADDRESS"
+
 type TestServer struct {
-	client temporalClient.Client
+	h           *harness.Harness
+	client      temporalClient.Client
+	addr        string
+	rpcAddr     string
+	metricsAddr string
+}
+
+// temporalAddr starts the temporal dev server (skipping the test without temporalite) unless the external one is set
+// via TEMPORAL_ADDRESS
+func temporalAddr(t *testing.T) string {
+	if addr := os.Getenv(envTemporalAddr); addr != "" {
+		return addr
+	}
+
+	return temporalserver.Start(t).Addr()
 }
 
 type log struct {
@@ -79,13 +97,17 @@ func (l *log) fields(keyvals []interface{}) []zap.Field {
 	return zf
 }
 
-func NewTestServerWithMetrics(t *testing.T, stopCh chan struct{}, wg *sync.WaitGroup) *TestServer {
-	container, err := endure.NewContainer(initLogger(), endure.RetryOnFail(false))
-	assert.NoError(t, err)
+func NewTestServerWithMetrics(t *testing.T) *TestServer {
+	env := harness.NewEnv(t)
+	env.Set("temporal", temporalAddr(t))
 
-	err = container.RegisterAll(
+	cfg := &configImpl.Plugin{
+		Path:    "configs/.rr-metrics.yaml",
+		Timeout: time.Second * 30,
+	}
+
+	h := env.StartConfig(cfg,
 		&roadrunnerTemporal.Plugin{},
-		initConfigProtoWithMetrics(),
 		&logger.Plugin{},
 		&resetter.Plugin{},
 		&informer.Plugin{},
@@ -93,32 +115,9 @@ func NewTestServerWithMetrics(t *testing.T, stopCh chan struct{}, wg *sync.WaitG
 		&rpc.Plugin{},
 	)
 
-	assert.NoError(t, err)
-	assert.NoError(t, container.Init())
-
-	errCh, err := container.Serve()
-	if err != nil {
-		panic(err)
-	}
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case er := <-errCh:
-				assert.Fail(t, fmt.Sprintf("got error from vertex: %s, error: %v", er.VertexID, er.Error))
-				assert.NoError(t, container.Stop())
-				return
-			case <-stopCh:
-				assert.NoError(t, container.Stop())
-				return
-			}
-		}
-	}()
-
 	dc := data_converter.NewDataConverter(converter.GetDefaultDataConverter())
 	client, err := temporalClient.NewClient(temporalClient.Options{
-		HostPort:      "127.0.0.1:7233",
+		HostPort:      env.Var("temporal"),
 		Namespace:     "default",
 		Logger:        newZapAdapter(initLogger()),
 		DataConverter: dc,
@@ -126,22 +125,24 @@ func NewTestServerWithMetrics(t *testing.T, stopCh chan struct{}, wg *sync.WaitG
 	require.NoError(t, err)
 
 	return &TestServer{
-		client: client,
+		h:           h,
+		client:      client,
+		addr:        env.Var("temporal"),
+		rpcAddr:     h.RPCAddr(),
+		metricsAddr: env.Addr("metrics"),
 	}
 }
 
-func NewTestServer(t *testing.T, stopCh chan struct{}, wg *sync.WaitGroup) *TestServer {
-	container, err := endure.NewContainer(initLogger(), endure.RetryOnFail(false), endure.GracefulShutdownTimeout(time.Second*30))
-	assert.NoError(t, err)
+func NewTestServer(t *testing.T) *TestServer {
+	env := harness.NewEnv(t)
+	env.Set("temporal", temporalAddr(t))
 
 	cfg := &configImpl.Plugin{
+		Path:    "configs/.rr-proto.yaml",
 		Timeout: time.Second * 30,
 	}
-	cfg.Path = "configs/.rr-proto.yaml"
-	cfg.Prefix = "rr"
 
-	err = container.RegisterAll(
-		cfg,
+	h := env.Options(endure.GracefulShutdownTimeout(time.Second*30)).StartConfig(cfg,
 		&roadrunnerTemporal.Plugin{},
 		&logger.Plugin{},
 		&resetter.Plugin{},
@@ -150,54 +151,26 @@ func NewTestServer(t *testing.T, stopCh chan struct{}, wg *sync.WaitGroup) *Test
 		&rpc.Plugin{},
 	)
 
-	assert.NoError(t, err)
-	assert.NoError(t, container.Init())
-
-	errCh, err := container.Serve()
-	if err != nil {
-		panic(err)
-	}
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case er := <-errCh:
-				assert.Fail(t, fmt.Sprintf("got error from vertex: %s, error: %v", er.VertexID, er.Error))
-				assert.NoError(t, container.Stop())
-				return
-			case <-stopCh:
-				assert.NoError(t, container.Stop())
-				return
-			}
-		}
-	}()
-
 	dc := data_converter.NewDataConverter(converter.GetDefaultDataConverter())
 	client, err := temporalClient.NewClient(temporalClient.Options{
-		HostPort:      "127.0.0.1:7233",
+		HostPort:      env.Var("temporal"),
 		Namespace:     "default",
 		DataConverter: dc,
 		Logger:        newZapAdapter(initLogger()),
 	})
-	if err != nil {
-		panic(err)
-	}
 	require.NoError(t, err)
 
 	return &TestServer{
-		client: client,
+		h:       h,
+		client:  client,
+		addr:    env.Var("temporal"),
+		rpcAddr: h.RPCAddr(),
 	}
 }
 
-func initConfigProtoWithMetrics() config.Configurer {
-	cfg := &configImpl.Plugin{
-		Timeout: time.Second * 30,
-	}
-	cfg.Path = "configs/.rr-metrics.yaml"
-	cfg.Prefix = "rr"
-
-	return cfg
+// Stop stops the RR container of the server
+func (s *TestServer) Stop() {
+	s.h.Stop()
 }
 
 func initLogger() *zap.Logger {
@@ -230,7 +203,7 @@ func initLogger() *zap.Logger {
 func (s *TestServer) AssertContainsEvent(t *testing.T, w temporalClient.WorkflowRun, assert func(*history.HistoryEvent) bool) {
 	dc := data_converter.NewDataConverter(converter.GetDefaultDataConverter())
 	client, err := temporalClient.NewClient(temporalClient.Options{
-		HostPort:      "127.0.0.1:7233",
+		HostPort:      s.addr,
 		Namespace:     "default",
 		Logger:        newZapAdapter(initLogger()),
 		DataConverter: dc,
@@ -266,7 +239,7 @@ func (s *TestServer) AssertContainsEvent(t *testing.T, w temporalClient.Workflow
 func (s *TestServer) AssertNotContainsEvent(t *testing.T, w temporalClient.WorkflowRun, assert func(*history.HistoryEvent) bool) {
 	dc := data_converter.NewDataConverter(converter.GetDefaultDataConverter())
 	client, err := temporalClient.NewClient(temporalClient.Options{
-		HostPort:           "",
+		HostPort:           s.addr,
 		Namespace:          "default",
 		Logger:             nil,
 		MetricsHandler:     nil,
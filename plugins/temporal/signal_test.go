@@ -2,7 +2,6 @@ package tests
 
 import (
 	"context"
-	"sync"
 	"testing"
 	"time"
 
@@ -14,10 +13,7 @@ import (
 )
 
 func Test_SignalsWithoutSignalsProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -32,15 +28,11 @@ func Test_SignalsWithoutSignalsProto(t *testing.T) {
 	var result int
 	assert.NoError(t, w.Get(context.Background(), &result))
 	assert.Equal(t, 0, result)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_SendSignalDuringTimerProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().SignalWithStartWorkflow(
 		context.Background(),
@@ -69,15 +61,11 @@ func Test_SendSignalDuringTimerProto(t *testing.T) {
 
 		return false
 	})
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_SendSignalBeforeCompletingWorkflowProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -106,15 +94,11 @@ func Test_SendSignalBeforeCompletingWorkflowProto(t *testing.T) {
 
 		return false
 	})
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_RuntimeSignalProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().SignalWithStartWorkflow(
 		context.Background(),
@@ -140,15 +124,11 @@ func Test_RuntimeSignalProto(t *testing.T) {
 
 		return false
 	})
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
 
 func Test_SignalStepsProto(t *testing.T) {
-	stopCh := make(chan struct{}, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	s := NewTestServer(t, stopCh, wg)
+	s := NewTestServer(t)
 
 	w, err := s.Client().ExecuteWorkflow(
 		context.Background(),
@@ -186,6 +166,5 @@ func Test_SignalStepsProto(t *testing.T) {
 
 	// 3 ticks
 	assert.Equal(t, 3, result)
-	stopCh <- struct{}{}
-	wg.Wait()
+	s.Stop()
 }
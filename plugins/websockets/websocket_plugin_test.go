@@ -2,13 +2,8 @@ package websockets
 
 import (
 	"context"
-	"net"
-	"net/rpc"
 	"net/url"
-	"os"
-	"os/signal"
-	"sync"
-	"syscall"
+	"strconv"
 	"testing"
 	"time"
 
@@ -26,24 +21,19 @@ import (
 	"github.com/goccy/go-json"
 	websocketsv1 "github.com/roadrunner-server/api/v2/proto/websockets/v1beta"
 	endure "github.com/roadrunner-server/endure/pkg/container"
-	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
 	httpPlugin "github.com/roadrunner-server/http/v2"
 	rpcPlugin "github.com/roadrunner-server/rpc/v2"
+	"github.com/roadrunner-server/rr-e2e-tests/fakeredis"
+	"github.com/roadrunner-server/rr-e2e-tests/harness"
+	"github.com/roadrunner-server/rr-e2e-tests/rpcclient"
 	"github.com/roadrunner-server/sdk/v2/utils"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestWebsocketsInit(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-websockets-init.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-websockets-init.yaml",
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
@@ -54,74 +44,20 @@ func TestWebsocketsInit(t *testing.T) {
 		&broadcast.Plugin{},
 	)
 
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-	t.Run("TestWSInit", wsInit)
-	t.Run("RPCWsMemoryPubAsync", RPCWsPubAsync("11111"))
-	t.Run("RPCWsMemory", RPCWsPub("11111"))
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	t.Run("TestWSInit", wsInit(strconv.Itoa(env.Port("http"))))
+	t.Run("RPCWsMemoryPubAsync", RPCWsPubAsync(h.RPCAddr(), strconv.Itoa(env.Port("http"))))
+	t.Run("RPCWsMemory", RPCWsPub(h.RPCAddr(), strconv.Itoa(env.Port("http"))))
 
-	stopCh <- struct{}{}
-
-	wg.Wait()
+	h.Stop()
 }
 
 func TestWSRedis(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-websockets-redis.yaml",
-		Prefix: "rr",
-	}
+	env.Set("redis", fakeredis.Start(t).Addr())
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-websockets-redis.yaml",
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
@@ -130,68 +66,22 @@ func TestWSRedis(t *testing.T) {
 		&httpPlugin.Plugin{},
 		&broadcast.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-	t.Run("RPCWsRedisPubAsync", RPCWsPubAsync("13235"))
-	t.Run("RPCWsRedisPub", RPCWsPub("13235"))
 
-	stopCh <- struct{}{}
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	t.Run("RPCWsRedisPubAsync", RPCWsPubAsync(h.RPCAddr(), strconv.Itoa(env.Port("http"))))
+	t.Run("RPCWsRedisPub", RPCWsPub(h.RPCAddr(), strconv.Itoa(env.Port("http"))))
 
-	wg.Wait()
+	h.Stop()
 }
 
 func TestWSRedisNoSection(t *testing.T) {
+	env := harness.NewEnv(t)
+
 	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
 	assert.NoError(t, err)
 
 	cfg := &config.Plugin{
-		Path:   "configs/.rr-websockets-broker-no-section.yaml",
+		Path:   env.Render("configs/.rr-websockets-broker-no-section.yaml"),
 		Prefix: "rr",
 	}
 
@@ -217,16 +107,9 @@ func TestWSRedisNoSection(t *testing.T) {
 }
 
 func TestWSDeny(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-websockets-deny.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-websockets-deny.yaml",
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
@@ -235,72 +118,19 @@ func TestWSDeny(t *testing.T) {
 		&memory.Plugin{},
 		&broadcast.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
-	time.Sleep(time.Second * 1)
-	t.Run("RPCWsMemoryDeny", RPCWsDeny("15587"))
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	t.Run("RPCWsMemoryDeny", RPCWsDeny(strconv.Itoa(env.Port("http"))))
 
-	stopCh <- struct{}{}
-
-	wg.Wait()
+	h.Stop()
 }
 
 func TestWSDeny2(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-websockets-deny2.yaml",
-		Prefix: "rr",
-	}
+	env.Set("redis", fakeredis.Start(t).Addr())
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-websockets-deny2.yaml",
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
@@ -309,72 +139,17 @@ func TestWSDeny2(t *testing.T) {
 		&redis.Plugin{},
 		&broadcast.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
-	time.Sleep(time.Second * 1)
-	t.Run("RPCWsRedisDeny", RPCWsDeny("15588"))
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	t.Run("RPCWsRedisDeny", RPCWsDeny(strconv.Itoa(env.Port("http"))))
 
-	stopCh <- struct{}{}
-
-	wg.Wait()
+	h.Stop()
 }
 
 func TestWSStop(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
-
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-websockets-stop.yaml",
-		Prefix: "rr",
-	}
+	env := harness.NewEnv(t)
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-websockets-stop.yaml",
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
@@ -384,59 +159,11 @@ func TestWSStop(t *testing.T) {
 		&memory.Plugin{},
 		&broadcast.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
-	time.Sleep(time.Second * 1)
-	t.Run("RPCWsStop", RPCWsMemoryStop("11114"))
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	t.Run("RPCWsStop", RPCWsMemoryStop(strconv.Itoa(env.Port("http"))))
 
-	stopCh <- struct{}{}
-
-	wg.Wait()
+	h.Stop()
 }
 
 func RPCWsMemoryStop(port string) func(t *testing.T) {
@@ -452,16 +179,11 @@ func RPCWsMemoryStop(port string) func(t *testing.T) {
 }
 
 func TestWSAllow(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-websockets-allow.yaml",
-		Prefix: "rr",
-	}
+	env.Set("redis", fakeredis.Start(t).Addr())
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-websockets-allow.yaml",
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
@@ -471,72 +193,19 @@ func TestWSAllow(t *testing.T) {
 		&memory.Plugin{},
 		&broadcast.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
 
-	time.Sleep(time.Second * 1)
-	t.Run("RPCWsMemoryAllow", RPCWsPub("41278"))
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	t.Run("RPCWsMemoryAllow", RPCWsPub(h.RPCAddr(), strconv.Itoa(env.Port("http"))))
 
-	stopCh <- struct{}{}
-
-	wg.Wait()
+	h.Stop()
 }
 
 func TestWSAllow2(t *testing.T) {
-	cont, err := endure.NewContainer(nil, endure.SetLogLevel(endure.ErrorLevel))
-	assert.NoError(t, err)
+	env := harness.NewEnv(t)
 
-	cfg := &config.Plugin{
-		Path:   "configs/.rr-websockets-allow2.yaml",
-		Prefix: "rr",
-	}
+	env.Set("redis", fakeredis.Start(t).Addr())
 
-	err = cont.RegisterAll(
-		cfg,
+	h := env.Start("configs/.rr-websockets-allow2.yaml",
 		&rpcPlugin.Plugin{},
 		&logger.Plugin{},
 		&server.Plugin{},
@@ -546,89 +215,43 @@ func TestWSAllow2(t *testing.T) {
 		&memory.Plugin{},
 		&broadcast.Plugin{},
 	)
-	assert.NoError(t, err)
-
-	err = cont.Init()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	ch, err := cont.Serve()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	stopCh := make(chan struct{}, 1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			select {
-			case e := <-ch:
-				assert.Fail(t, "error", e.Error.Error())
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-			case <-sig:
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			case <-stopCh:
-				// timeout
-				err = cont.Stop()
-				if err != nil {
-					assert.FailNow(t, "error", err.Error())
-				}
-				return
-			}
-		}
-	}()
-
-	time.Sleep(time.Second * 1)
-	t.Run("RPCWsMemoryAllow", RPCWsPub("41270"))
 
-	stopCh <- struct{}{}
+	harness.WaitForTCP(t, env.Addr("http"), harness.DefaultWaitTimeout)
+	t.Run("RPCWsMemoryAllow", RPCWsPub(h.RPCAddr(), strconv.Itoa(env.Port("http"))))
 
-	wg.Wait()
+	h.Stop()
 }
 
-func wsInit(t *testing.T) {
-	connURL := url.URL{Scheme: "ws", Host: "127.0.0.1:11111", Path: "/ws"}
-	dialer := ws.Dialer{
-		Header: ws.HandshakeHeaderHTTP{"Origin": []string{"127.0.0.1"}},
-	}
+func wsInit(port string) func(t *testing.T) {
+	return func(t *testing.T) {
+		connURL := url.URL{Scheme: "ws", Host: "127.0.0.1:" + port, Path: "/ws"}
+		dialer := ws.Dialer{
+			Header: ws.HandshakeHeaderHTTP{"Origin": []string{"127.0.0.1"}},
+		}
 
-	conn, _, _, err := dialer.Dial(context.Background(), connURL.String())
-	require.NoError(t, err)
+		conn, _, _, err := dialer.Dial(context.Background(), connURL.String())
+		require.NoError(t, err)
 
-	d, err := json.Marshal(messageWS("join", []byte("hello websockets"), "foo", "foo2"))
-	require.NoError(t, err)
+		d, err := json.Marshal(messageWS("join", []byte("hello websockets"), "foo", "foo2"))
+		require.NoError(t, err)
 
-	err = wsutil.WriteClientText(conn, d)
-	assert.NoError(t, err)
+		err = wsutil.WriteClientText(conn, d)
+		assert.NoError(t, err)
 
-	msg, err := wsutil.ReadServerText(conn)
-	require.NoError(t, err)
-	_ = msg
-	retMsg := utils.AsString(msg)
+		msg, err := wsutil.ReadServerText(conn)
+		require.NoError(t, err)
+		_ = msg
+		retMsg := utils.AsString(msg)
 
-	// subscription done
-	assert.Equal(t, `{"topic":"@join","payload":["foo","foo2"]}`, retMsg)
+		// subscription done
+		assert.Equal(t, `{"topic":"@join","payload":["foo","foo2"]}`, retMsg)
 
-	err = conn.Close()
-	require.NoError(t, err)
+		err = conn.Close()
+		require.NoError(t, err)
+	}
 }
 
-func RPCWsPubAsync(port string) func(t *testing.T) {
+func RPCWsPubAsync(addr, port string) func(t *testing.T) {
 	return func(t *testing.T) {
 		connURL := url.URL{Scheme: "ws", Host: "127.0.0.1:" + port, Path: "/ws"}
 		conn, _, _, err := ws.Dial(context.Background(), connURL.String())
@@ -663,7 +286,7 @@ func RPCWsPubAsync(port string) func(t *testing.T) {
 
 		time.Sleep(time.Second)
 
-		publishAsync(t, "foo")
+		publishAsync(t, addr, "foo")
 
 		time.Sleep(time.Second)
 
@@ -679,14 +302,14 @@ func RPCWsPubAsync(port string) func(t *testing.T) {
 		time.Sleep(time.Second)
 
 		// TRY TO PUBLISH TO UNSUBSCRIBED TOPIC
-		publishAsync(t, "foo")
+		publishAsync(t, addr, "foo")
 
 		err = conn.Close()
 		assert.NoError(t, err)
 	}
 }
 
-func RPCWsPub(port string) func(t *testing.T) {
+func RPCWsPub(addr, port string) func(t *testing.T) {
 	return func(t *testing.T) {
 		connURL := url.URL{Scheme: "ws", Host: "127.0.0.1:" + port, Path: "/ws"}
 
@@ -724,7 +347,7 @@ func RPCWsPub(port string) func(t *testing.T) {
 
 		time.Sleep(time.Second)
 
-		publish("", "foo")
+		publish(addr, "", "foo")
 
 		time.Sleep(time.Second)
 
@@ -740,7 +363,7 @@ func RPCWsPub(port string) func(t *testing.T) {
 		time.Sleep(time.Second)
 
 		// TRY TO PUBLISH TO UNSUBSCRIBED TOPIC
-		publish("", "foo")
+		publish(addr, "", "foo")
 
 		err = conn.Close()
 		assert.NoError(t, err)
@@ -791,33 +414,25 @@ func RPCWsDeny(port string) func(t *testing.T) {
 
 // ---------------------------------------------------------------------------------------------------
 
-func publish(topics ...string) {
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
+func publish(addr string, topics ...string) {
+	client, err := rpcclient.Dial(addr)
 	if err != nil {
 		panic(err)
 	}
+	defer func() {
+		_ = client.Close()
+	}()
 
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-	ret := &websocketsv1.Response{}
-	err = client.Call("broadcast.Publish", makeMessage([]byte("hello, PHP"), topics...), ret)
+	_, err = client.Broadcast.Publish(rpcclient.Message([]byte("hello, PHP"), topics...))
 	if err != nil {
 		panic(err)
 	}
 }
 
-func publishAsync(t *testing.T, topics ...string) {
-	conn, err := net.Dial("tcp", "127.0.0.1:6001")
-	if err != nil {
-		panic(err)
-	}
-
-	client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
-
-	ret := &websocketsv1.Response{}
-	err = client.Call("broadcast.PublishAsync", makeMessage([]byte("hello, PHP"), topics...), ret)
+func publishAsync(t *testing.T, addr string, topics ...string) {
+	ok, err := harness.Dial(t, addr).Broadcast.PublishAsync(rpcclient.Message([]byte("hello, PHP"), topics...))
 	assert.NoError(t, err)
-	assert.True(t, ret.Ok)
+	assert.True(t, ok)
 }
 
 func messageWS(command string, payload []byte, topics ...string) *websocketsv1.Message {
@@ -827,16 +442,3 @@ func messageWS(command string, payload []byte, topics ...string) *websocketsv1.M
 		Payload: payload,
 	}
 }
-
-func makeMessage(payload []byte, topics ...string) *websocketsv1.Request {
-	m := &websocketsv1.Request{
-		Messages: []*websocketsv1.Message{
-			{
-				Topics:  topics,
-				Payload: payload,
-			},
-		},
-	}
-
-	return m
-}
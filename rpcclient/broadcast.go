@@ -0,0 +1,45 @@
+package rpcclient
+
+import (
+	"net/rpc"
+
+	websocketsv1 "github.com/roadrunner-server/api/v2/proto/websockets/v1beta"
+)
+
+const (
+	broadcastPublish      string = "broadcast.Publish"
+	broadcastPublishAsync string = "broadcast.PublishAsync"
+)
+
+// Broadcast is the client for the broadcast plugin
+type Broadcast struct {
+	c *rpc.Client
+}
+
+// Publish publishes the messages and waits for the brokers
+func (b *Broadcast) Publish(messages ...*websocketsv1.Message) (bool, error) {
+	return b.call(broadcastPublish, messages)
+}
+
+// PublishAsync publishes the messages without waiting for the brokers
+func (b *Broadcast) PublishAsync(messages ...*websocketsv1.Message) (bool, error) {
+	return b.call(broadcastPublishAsync, messages)
+}
+
+// Message is a shortcut to create the message for the topics
+func Message(payload []byte, topics ...string) *websocketsv1.Message {
+	return &websocketsv1.Message{
+		Topics:  topics,
+		Payload: payload,
+	}
+}
+
+func (b *Broadcast) call(method string, messages []*websocketsv1.Message) (bool, error) {
+	resp := &websocketsv1.Response{}
+	err := b.c.Call(method, &websocketsv1.Request{Messages: messages}, resp)
+	if err != nil {
+		return false, err
+	}
+
+	return resp.GetOk(), nil
+}
@@ -0,0 +1,68 @@
+// Package rpcclient provides typed clients for the RPC API of the RoadRunner plugins.
+package rpcclient
+
+import (
+	"net"
+	"net/rpc"
+	"time"
+
+	goridgeRpc "github.com/roadrunner-server/goridge/v3/pkg/rpc"
+)
+
+const dialTimeout time.Duration = time.Second * 5
+
+// Client holds a single goridge connection shared by all namespaces. Safe for concurrent use.
+type Client struct {
+	rpc *rpc.Client
+
+	Jobs      *Jobs
+	KV        *KV
+	Metrics   *Metrics
+	Informer  *Informer
+	Resetter  *Resetter
+	Service   *Service
+	Status    *Status
+	Broadcast *Broadcast
+	TCP       *TCP
+	Temporal  *Temporal
+}
+
+// Dial connects to the RPC plugin listening on the addr (tcp)
+func Dial(addr string) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(conn), nil
+}
+
+// New creates the client over the already established connection, the connection is closed by Close
+func New(conn net.Conn) *Client {
+	c := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
+
+	return &Client{
+		rpc: c,
+
+		Jobs:      &Jobs{c: c},
+		KV:        &KV{c: c},
+		Metrics:   &Metrics{c: c},
+		Informer:  &Informer{c: c},
+		Resetter:  &Resetter{c: c},
+		Service:   &Service{c: c},
+		Status:    &Status{c: c},
+		Broadcast: &Broadcast{c: c},
+		TCP:       &TCP{c: c},
+		Temporal:  &Temporal{c: c},
+	}
+}
+
+// Call is an escape hatch for the methods not covered by the typed namespaces
+func (c *Client) Call(method string, args, reply interface{}) error {
+	return c.rpc.Call(method, args, reply)
+}
+
+// Close closes the underlying connection
+func (c *Client) Close() error {
+	return c.rpc.Close()
+}
@@ -0,0 +1,56 @@
+package rpcclient
+
+import (
+	"net/rpc"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/roadrunner-server/api/v2/state/process"
+)
+
+const (
+	informerWorkers string = "informer.Workers"
+	informerList    string = "informer.List"
+	informerJobs    string = "informer.Jobs"
+)
+
+// Informer is the client for the informer plugin
+type Informer struct {
+	c *rpc.Client
+}
+
+// Workers returns the workers of the plugin
+func (i *Informer) Workers(plugin string) ([]*process.State, error) {
+	list := struct {
+		// Workers is list of workers.
+		Workers []*process.State `json:"workers"`
+	}{}
+
+	err := i.c.Call(informerWorkers, plugin, &list)
+	if err != nil {
+		return nil, err
+	}
+
+	return list.Workers, nil
+}
+
+// List returns the plugins which have workers
+func (i *Informer) List() ([]string, error) {
+	list := make([]string, 0, 5)
+	err := i.c.Call(informerList, true, &list)
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// Jobs returns the state of the pipelines of the plugin (jobs)
+func (i *Informer) Jobs(plugin string) ([]*jobs.State, error) {
+	var list []*jobs.State
+	err := i.c.Call(informerJobs, plugin, &list)
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
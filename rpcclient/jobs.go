@@ -0,0 +1,75 @@
+package rpcclient
+
+import (
+	"net/rpc"
+
+	jobsv1beta "github.com/roadrunner-server/api/v2/proto/jobs/v1"
+)
+
+const (
+	jobsPush      string = "jobs.Push"
+	jobsPushBatch string = "jobs.PushBatch"
+	jobsPause     string = "jobs.Pause"
+	jobsResume    string = "jobs.Resume"
+	jobsList      string = "jobs.List"
+	jobsDeclare   string = "jobs.Declare"
+	jobsDestroy   string = "jobs.Destroy"
+	jobsStat      string = "jobs.Stat"
+)
+
+// Jobs is the client for the jobs plugin
+type Jobs struct {
+	c *rpc.Client
+}
+
+// Push pushes the job to the pipeline set in the job options
+func (j *Jobs) Push(job *jobsv1beta.Job) error {
+	return j.c.Call(jobsPush, &jobsv1beta.PushRequest{Job: job}, &jobsv1beta.Empty{})
+}
+
+// PushBatch pushes the jobs at once
+func (j *Jobs) PushBatch(jobs ...*jobsv1beta.Job) error {
+	return j.c.Call(jobsPushBatch, &jobsv1beta.PushBatchRequest{Jobs: jobs}, &jobsv1beta.Empty{})
+}
+
+// Pause stops consuming the pipelines
+func (j *Jobs) Pause(pipelines ...string) error {
+	return j.c.Call(jobsPause, &jobsv1beta.Pipelines{Pipelines: pipelines}, &jobsv1beta.Empty{})
+}
+
+// Resume starts consuming the pipelines
+func (j *Jobs) Resume(pipelines ...string) error {
+	return j.c.Call(jobsResume, &jobsv1beta.Pipelines{Pipelines: pipelines}, &jobsv1beta.Empty{})
+}
+
+// List returns the names of the registered pipelines
+func (j *Jobs) List() ([]string, error) {
+	resp := &jobsv1beta.Pipelines{}
+	err := j.c.Call(jobsList, &jobsv1beta.Empty{}, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.GetPipelines(), nil
+}
+
+// Declare declares the new pipeline, pipeline is the pipeline config (driver, name, priority, etc)
+func (j *Jobs) Declare(pipeline map[string]string) error {
+	return j.c.Call(jobsDeclare, &jobsv1beta.DeclareRequest{Pipeline: pipeline}, &jobsv1beta.Empty{})
+}
+
+// Destroy stops and removes the pipelines
+func (j *Jobs) Destroy(pipelines ...string) error {
+	return j.c.Call(jobsDestroy, &jobsv1beta.Pipelines{Pipelines: pipelines}, &jobsv1beta.Empty{})
+}
+
+// Stat returns the state of all pipelines
+func (j *Jobs) Stat() ([]*jobsv1beta.Stat, error) {
+	resp := &jobsv1beta.Stats{}
+	err := j.c.Call(jobsStat, &jobsv1beta.Empty{}, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.GetStats(), nil
+}
@@ -0,0 +1,80 @@
+package rpcclient
+
+import (
+	"net/rpc"
+
+	kvv1 "github.com/roadrunner-server/api/v2/proto/kv/v1"
+)
+
+const (
+	kvHas     string = "kv.Has"
+	kvSet     string = "kv.Set"
+	kvMGet    string = "kv.MGet"
+	kvMExpire string = "kv.MExpire"
+	kvTTL     string = "kv.TTL"
+	kvDelete  string = "kv.Delete"
+	kvClear   string = "kv.Clear"
+)
+
+// KV is the client for the kv plugin
+type KV struct {
+	c *rpc.Client
+}
+
+// Has returns the items which exist in the storage
+func (k *KV) Has(storage string, keys ...string) ([]*kvv1.Item, error) {
+	return k.call(kvHas, &kvv1.Request{Storage: storage, Items: keyItems(keys)})
+}
+
+// Set stores the items, Item.Timeout (RFC3339) is optional
+func (k *KV) Set(storage string, items ...*kvv1.Item) error {
+	_, err := k.call(kvSet, &kvv1.Request{Storage: storage, Items: items})
+	return err
+}
+
+// MGet returns the items with values, missing keys are omitted
+func (k *KV) MGet(storage string, keys ...string) ([]*kvv1.Item, error) {
+	return k.call(kvMGet, &kvv1.Request{Storage: storage, Items: keyItems(keys)})
+}
+
+// MExpire sets Item.Timeout (RFC3339) for the items
+func (k *KV) MExpire(storage string, items ...*kvv1.Item) error {
+	_, err := k.call(kvMExpire, &kvv1.Request{Storage: storage, Items: items})
+	return err
+}
+
+// TTL returns the items with the timeouts
+func (k *KV) TTL(storage string, keys ...string) ([]*kvv1.Item, error) {
+	return k.call(kvTTL, &kvv1.Request{Storage: storage, Items: keyItems(keys)})
+}
+
+// Delete removes the keys
+func (k *KV) Delete(storage string, keys ...string) error {
+	_, err := k.call(kvDelete, &kvv1.Request{Storage: storage, Items: keyItems(keys)})
+	return err
+}
+
+// Clear removes all keys from the storage
+func (k *KV) Clear(storage string) error {
+	_, err := k.call(kvClear, &kvv1.Request{Storage: storage})
+	return err
+}
+
+func (k *KV) call(method string, req *kvv1.Request) ([]*kvv1.Item, error) {
+	resp := &kvv1.Response{}
+	err := k.c.Call(method, req, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.GetItems(), nil
+}
+
+func keyItems(keys []string) []*kvv1.Item {
+	items := make([]*kvv1.Item, len(keys))
+	for i := 0; i < len(keys); i++ {
+		items[i] = &kvv1.Item{Key: keys[i]}
+	}
+
+	return items
+}
@@ -0,0 +1,57 @@
+package rpcclient
+
+import (
+	"net/rpc"
+
+	"github.com/roadrunner-server/metrics/v2"
+)
+
+const (
+	metricsAdd        string = "metrics.Add"
+	metricsSub        string = "metrics.Sub"
+	metricsObserve    string = "metrics.Observe"
+	metricsSet        string = "metrics.Set"
+	metricsDeclare    string = "metrics.Declare"
+	metricsUnregister string = "metrics.Unregister"
+)
+
+// Metrics is the client for the metrics plugin
+type Metrics struct {
+	c *rpc.Client
+}
+
+// Add adds the value to the counter or gauge
+func (m *Metrics) Add(metric metrics.Metric) (bool, error) {
+	return m.call(metricsAdd, metric)
+}
+
+// Sub subtracts the value from the gauge
+func (m *Metrics) Sub(metric metrics.Metric) (bool, error) {
+	return m.call(metricsSub, metric)
+}
+
+// Observe adds the value to the histogram or summary
+func (m *Metrics) Observe(metric metrics.Metric) (bool, error) {
+	return m.call(metricsObserve, metric)
+}
+
+// Set sets the value of the gauge
+func (m *Metrics) Set(metric metrics.Metric) (bool, error) {
+	return m.call(metricsSet, metric)
+}
+
+// Declare registers the new collector
+func (m *Metrics) Declare(nc metrics.NamedCollector) (bool, error) {
+	return m.call(metricsDeclare, nc)
+}
+
+// Unregister removes the collector by name
+func (m *Metrics) Unregister(name string) (bool, error) {
+	return m.call(metricsUnregister, name)
+}
+
+func (m *Metrics) call(method string, args interface{}) (bool, error) {
+	var ok bool
+	err := m.c.Call(method, args, &ok)
+	return ok, err
+}
@@ -0,0 +1,33 @@
+package rpcclient
+
+import (
+	"net/rpc"
+)
+
+const (
+	resetterReset string = "resetter.Reset"
+	resetterList  string = "resetter.List"
+)
+
+// Resetter is the client for the resetter plugin
+type Resetter struct {
+	c *rpc.Client
+}
+
+// Reset resets (reloads workers of) the plugin
+func (r *Resetter) Reset(plugin string) (bool, error) {
+	var ok bool
+	err := r.c.Call(resetterReset, plugin, &ok)
+	return ok, err
+}
+
+// List returns the plugins which might be reset
+func (r *Resetter) List() ([]string, error) {
+	var services []string
+	err := r.c.Call(resetterList, nil, &services)
+	if err != nil {
+		return nil, err
+	}
+
+	return services, nil
+}
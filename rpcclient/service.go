@@ -0,0 +1,59 @@
+package rpcclient
+
+import (
+	"net/rpc"
+
+	serviceV1 "github.com/roadrunner-server/api/v2/proto/service/v1"
+)
+
+const (
+	serviceCreate    string = "service.Create"
+	serviceTerminate string = "service.Terminate"
+	serviceRestart   string = "service.Restart"
+	serviceStatus    string = "service.Status"
+	serviceList      string = "service.List"
+)
+
+// Service is the client for the service plugin
+type Service struct {
+	c *rpc.Client
+}
+
+// Create creates and starts the new service
+func (s *Service) Create(in *serviceV1.Create) (*serviceV1.Response, error) {
+	out := &serviceV1.Response{}
+	err := s.c.Call(serviceCreate, in, out)
+	return out, err
+}
+
+// Terminate stops and removes the service
+func (s *Service) Terminate(name string) (*serviceV1.Response, error) {
+	out := &serviceV1.Response{}
+	err := s.c.Call(serviceTerminate, &serviceV1.Service{Name: name}, out)
+	return out, err
+}
+
+// Restart restarts the service processes
+func (s *Service) Restart(name string) (*serviceV1.Response, error) {
+	out := &serviceV1.Response{}
+	err := s.c.Call(serviceRestart, &serviceV1.Service{Name: name}, out)
+	return out, err
+}
+
+// Status returns the status of the service process
+func (s *Service) Status(name string) (*serviceV1.Status, error) {
+	out := &serviceV1.Status{}
+	err := s.c.Call(serviceStatus, &serviceV1.Service{Name: name}, out)
+	return out, err
+}
+
+// List returns the names of the services
+func (s *Service) List() ([]string, error) {
+	out := &serviceV1.List{}
+	err := s.c.Call(serviceList, &serviceV1.Service{}, out)
+	if err != nil {
+		return nil, err
+	}
+
+	return out.GetServices(), nil
+}
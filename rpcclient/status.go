@@ -0,0 +1,31 @@
+package rpcclient
+
+import (
+	"net/rpc"
+
+	statusImpl "github.com/roadrunner-server/api/v2/plugins/status"
+)
+
+const (
+	statusStatus string = "status.Status"
+	statusReady  string = "status.Ready"
+)
+
+// Status is the client for the status plugin
+type Status struct {
+	c *rpc.Client
+}
+
+// Status returns the health status of the plugin
+func (s *Status) Status(plugin string) (*statusImpl.Status, error) {
+	st := &statusImpl.Status{}
+	err := s.c.Call(statusStatus, plugin, &st)
+	return st, err
+}
+
+// Ready returns the readiness status of the plugin
+func (s *Status) Ready(plugin string) (*statusImpl.Status, error) {
+	st := &statusImpl.Status{}
+	err := s.c.Call(statusReady, plugin, &st)
+	return st, err
+}
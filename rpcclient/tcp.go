@@ -0,0 +1,19 @@
+package rpcclient
+
+import (
+	"net/rpc"
+)
+
+const tcpClose string = "tcp.Close"
+
+// TCP is the client for the tcp plugin
+type TCP struct {
+	c *rpc.Client
+}
+
+// Close closes the connection by its uuid
+func (t *TCP) Close(uuid string) (bool, error) {
+	var ok bool
+	err := t.c.Call(tcpClose, uuid, &ok)
+	return ok, err
+}
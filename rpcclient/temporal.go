@@ -0,0 +1,35 @@
+package rpcclient
+
+import (
+	"net/rpc"
+)
+
+const (
+	temporalWorkflowNames string = "temporal.GetWorkflowNames"
+	temporalActivityNames string = "temporal.GetActivityNames"
+)
+
+// Temporal is the client for the temporal plugin
+type Temporal struct {
+	c *rpc.Client
+}
+
+// WorkflowNames returns the names of the registered workflows
+func (t *Temporal) WorkflowNames() ([]string, error) {
+	return t.names(temporalWorkflowNames)
+}
+
+// ActivityNames returns the names of the registered activities
+func (t *Temporal) ActivityNames() ([]string, error) {
+	return t.names(temporalActivityNames)
+}
+
+func (t *Temporal) names(method string) ([]string, error) {
+	res := make([]string, 0, 10)
+	err := t.c.Call(method, true, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,239 @@
+// Package temporalserver runs the Temporal dev server (the temporalite binary) with the SQLite persistence, so the
+// temporal suite doesn't need the temporal cluster containers. The binary is taken from TEMPORALITE_BIN or PATH:
+//
+//	go install -tags headless github.com/temporalio/temporalite/cmd/temporalite@v0.1.1
+//
+// The server runs as the subprocess instead of being embedded in the test binary: temporalite requires
+// go.temporal.io/server v1.17 and go.temporal.io/sdk v1.15, importing it would upgrade the sdk and the api of the
+// whole module and the roadrunner-temporal plugin would no longer be tested with the versions it pins (sdk v1.14).
+// The tests using Start are skipped when the binary is not installed.
+package temporalserver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/client"
+)
+
+const (
+	// Namespace is registered on start
+	Namespace string = "default"
+	// EnvBin is the path of the temporalite binary, PATH is searched when empty
+	EnvBin string = "TEMPORALITE_BIN"
+
+	bin string = "temporalite"
+	// time to wait for the frontend to serve the namespace
+	readyTimeout time.Duration = time.Second * 30
+	stopTimeout  time.Duration = time.Second * 10
+	dbFile       string        = "temporal.db"
+)
+
+// Server is the Temporal dev server process
+type Server struct {
+	cmd  *exec.Cmd
+	addr string
+	dir  string
+	out  *buffer
+
+	exitCh chan struct{}
+	err    error
+	once   sync.Once
+}
+
+// Binary returns the path of the temporalite binary
+func Binary() (string, error) {
+	if path := os.Getenv(EnvBin); path != "" {
+		return exec.LookPath(path)
+	}
+
+	return exec.LookPath(bin)
+}
+
+// Listen starts the server with the frontend on the addr (host:port, port 0 for the random one) and the SQLite
+// database in the dir. The other services take the ports next to the frontend (frontend+1..3, +100..103, +200, +201).
+func Listen(addr, dir string) (*Server, error) {
+	path, err := Binary()
+	if err != nil {
+		return nil, err
+	}
+
+	host, p, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if port == 0 {
+		port, err = freePort(host)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s := &Server{
+		addr:   net.JoinHostPort(host, strconv.Itoa(port)),
+		dir:    dir,
+		out:    &buffer{},
+		exitCh: make(chan struct{}),
+	}
+
+	s.cmd = exec.Command(path, "start", //nolint:gosec
+		"--headless",
+		"--namespace", Namespace,
+		"--filename", s.DBPath(),
+		"--ip", host,
+		"--port", strconv.Itoa(port),
+		"--log-format", "json",
+		"--log-level", "error",
+	)
+	s.cmd.Stdout = s.out
+	s.cmd.Stderr = s.out
+
+	err = s.cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		s.err = s.cmd.Wait()
+		close(s.exitCh)
+	}()
+
+	err = s.waitReady()
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Start starts the server on the random ports with the database in the temporary dir, the server is stopped when
+// the test finishes. The test is skipped when the temporalite binary is not found.
+func Start(t testing.TB) *Server {
+	t.Helper()
+
+	_, err := Binary()
+	if err != nil {
+		t.Skipf("temporalite is not installed (set %s or see the package doc): %v", EnvBin, err)
+	}
+
+	s, err := Listen("127.0.0.1:0", t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to start the temporal server: %v", err)
+	}
+
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// Addr returns host:port of the frontend
+func (s *Server) Addr() string {
+	return s.addr
+}
+
+// DBPath returns the path of the SQLite database
+func (s *Server) DBPath() string {
+	return filepath.Join(s.dir, dbFile)
+}
+
+// Close stops the server, killing it if it doesn't stop in time
+func (s *Server) Close() {
+	s.once.Do(func() {
+		_ = s.cmd.Process.Signal(os.Interrupt)
+
+		select {
+		case <-s.exitCh:
+		case <-time.After(stopTimeout):
+			_ = s.cmd.Process.Kill()
+			<-s.exitCh
+		}
+	})
+}
+
+// waitReady waits until the frontend serves the namespace
+func (s *Server) waitReady() error {
+	ctx, cancel := context.WithTimeout(context.Background(), readyTimeout)
+	defer cancel()
+
+	var lastErr error
+	for {
+		lastErr = s.describe(ctx)
+		if lastErr == nil {
+			return nil
+		}
+
+		select {
+		case <-s.exitCh:
+			return fmt.Errorf("temporal server exited: %v: %s", s.err, s.out.String())
+		case <-ctx.Done():
+			return fmt.Errorf("temporal server is not ready: %w", lastErr)
+		case <-time.After(time.Millisecond * 100):
+		}
+	}
+}
+
+func (s *Server) describe(ctx context.Context) error {
+	nc, err := client.NewNamespaceClient(client.Options{HostPort: s.Addr()})
+	if err != nil {
+		return err
+	}
+	defer nc.Close()
+
+	_, err = nc.Describe(ctx, Namespace)
+	return err
+}
+
+// freePort returns the port free at the moment of the call
+func freePort(host string) (int, error) {
+	l, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = l.Close()
+	}()
+
+	addr, ok := l.Addr().(*net.TCPAddr)
+	if !ok {
+		return 0, errors.New("not a tcp address")
+	}
+
+	return addr.Port, nil
+}
+
+// buffer is the output of the process, safe for concurrent use
+type buffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *buffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *buffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
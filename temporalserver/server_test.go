@@ -0,0 +1,23 @@
+package temporalserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/client"
+)
+
+func TestNamespace(t *testing.T) {
+	s := Start(t)
+
+	nc, err := client.NewNamespaceClient(client.Options{HostPort: s.Addr()})
+	require.NoError(t, err)
+	defer nc.Close()
+
+	resp, err := nc.Describe(context.Background(), Namespace)
+	require.NoError(t, err)
+	assert.Equal(t, Namespace, resp.GetNamespaceInfo().GetName())
+	assert.FileExists(t, s.DBPath())
+}